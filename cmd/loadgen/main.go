@@ -0,0 +1,306 @@
+// Command loadgen drives a running OpenVDO stack with concurrent API requests and reports
+// their latencies along with the saturation of the server's connection pool. Given budgets,
+// it exits with an error when the run exceeds them, so CI can catch regressions of the
+// request path and of acquiring tenant connections.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultTargets go through the database middleware, RLS context setup and tenant queries
+var defaultTargets = []string{
+	"GET /api/v1/organizations",
+	"GET /api/v1/videos",
+	"GET /api/v1/sessions",
+}
+
+type options struct {
+	baseURL     string
+	metricsURL  string
+	userID      string
+	orgID       string
+	targets     []string
+	duration    time.Duration
+	warmup      time.Duration
+	concurrency int
+	rate        int
+	timeout     time.Duration
+
+	budgetP99        time.Duration
+	budgetAcquireP99 time.Duration
+	budgetContextP99 time.Duration
+	budgetErrorRate  float64
+}
+
+// target is one endpoint requests are spread over
+type target struct {
+	method string
+	path   string
+}
+
+func (t target) String() string {
+	return t.method + " " + t.path
+}
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	opts := options{}
+	cmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Load an OpenVDO API and check latencies and pool saturation against budgets",
+		Long: `Load an OpenVDO API and check latencies and pool saturation against budgets.
+Requests are spread evenly over the targets and sent as the given user, so each goes through the
+database middleware, the RLS context setup and the tenant queries of its handler. The server's
+/stats/db and /metrics are read during the run for the saturation of its connection pool and the
+latency of acquiring tenant connections.`,
+		Example: `  loadgen --user 6f1c... --duration 1m --concurrency 32
+  loadgen --user 6f1c... --target "GET /api/v1/videos?limit=50" --budget-p99 200ms --budget-acquire-p99 5ms`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd.Context(), opts)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&opts.baseURL, "url", "http://localhost:8080", "base URL of the API")
+	f.StringVar(&opts.metricsURL, "metrics-url", "", "base URL of /metrics and /stats/db when the server has METRICS_LISTEN_ADDR; --url when omitted")
+	f.StringVar(&opts.userID, "user", "", "ID of the user the requests act as, sent as X-User-ID")
+	f.StringVar(&opts.orgID, "org", "", "organization the requests act in, sent as X-Org-ID; the user's current one when omitted")
+	f.StringArrayVar(&opts.targets, "target", defaultTargets, `endpoint to request as "METHOD /path", repeatable`)
+	f.DurationVar(&opts.duration, "duration", 30*time.Second, "how long requests are measured")
+	f.DurationVar(&opts.warmup, "warmup", 5*time.Second, "how long requests run before measuring starts")
+	f.IntVar(&opts.concurrency, "concurrency", 16, "requests in flight at once")
+	f.IntVar(&opts.rate, "rate", 0, "requests per second over all targets; 0 sends as fast as the concurrency allows")
+	f.DurationVar(&opts.timeout, "timeout", 10*time.Second, "timeout of one request")
+	f.DurationVar(&opts.budgetP99, "budget-p99", 0, "fail when the p99 latency of a target exceeds this; 0 disables it")
+	f.DurationVar(&opts.budgetAcquireP99, "budget-acquire-p99", 0, "fail when the p99 of acquiring a tenant connection exceeds this; 0 disables it")
+	f.DurationVar(&opts.budgetContextP99, "budget-context-p99", 0, "fail when the p99 of setting up the RLS context exceeds this; 0 disables it")
+	f.Float64Var(&opts.budgetErrorRate, "budget-error-rate", 0.01, "fail when this share of the requests of a target fails; 1 disables it")
+	cmd.MarkFlagRequired("user")
+	return cmd
+}
+
+func run(ctx context.Context, opts options) error {
+	if opts.concurrency < 1 {
+		return fmt.Errorf("--concurrency must be positive")
+	}
+	var targets []target
+	for _, t := range opts.targets {
+		method, path, ok := strings.Cut(strings.TrimSpace(t), " ")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return fmt.Errorf(`invalid target %q, expected "METHOD /path"`, t)
+		}
+		targets = append(targets, target{method: strings.ToUpper(method), path: strings.TrimSpace(path)})
+	}
+	opts.baseURL = strings.TrimSuffix(opts.baseURL, "/")
+	if opts.metricsURL == "" {
+		opts.metricsURL = opts.baseURL
+	}
+	opts.metricsURL = strings.TrimSuffix(opts.metricsURL, "/")
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := &http.Client{
+		Timeout:   opts.timeout,
+		Transport: &http.Transport{MaxIdleConnsPerHost: opts.concurrency, MaxConnsPerHost: opts.concurrency},
+	}
+	server := &serverStats{client: client, baseURL: opts.metricsURL}
+
+	fmt.Printf("Warming up for %v\n", opts.warmup)
+	load(ctx, client, opts, targets, opts.warmup, nil)
+
+	before, err := server.histograms(ctx)
+	if err != nil {
+		return err
+	}
+	pool := server.samplePool(ctx)
+	fmt.Printf("Measuring %d targets for %v with %d requests in flight\n", len(targets), opts.duration, opts.concurrency)
+	results := make([]*result, len(targets))
+	for i := range results {
+		results[i] = &result{}
+	}
+	elapsed := load(ctx, client, opts, targets, opts.duration, results)
+	saturation := pool.stop()
+	after, err := server.histograms(ctx)
+	if err != nil {
+		return err
+	}
+
+	var violations []string
+	fmt.Printf("\n%-40s %9s %8s %9s %10s %10s %10s %10s\n", "TARGET", "REQUESTS", "ERRORS", "REQ/S", "P50", "P95", "P99", "MAX")
+	for i, t := range targets {
+		r := results[i]
+		r.sort()
+		fmt.Printf("%-40s %9d %8d %9.1f %10v %10v %10v %10v\n", t, len(r.latencies), r.errors,
+			float64(len(r.latencies))/elapsed.Seconds(), r.percentile(50), r.percentile(95), r.percentile(99), r.percentile(100))
+		if len(r.latencies) == 0 {
+			violations = append(violations, fmt.Sprintf("%s: no requests completed", t))
+			continue
+		}
+		if opts.budgetP99 > 0 && r.percentile(99) > opts.budgetP99 {
+			violations = append(violations, fmt.Sprintf("%s: p99 of %v exceeds the budget of %v", t, r.percentile(99), opts.budgetP99))
+		}
+		if rate := float64(r.errors) / float64(len(r.latencies)); rate > opts.budgetErrorRate {
+			violations = append(violations, fmt.Sprintf("%s: %.1f%% of requests failed (first: %s), over the budget of %.1f%%",
+				t, rate*100, r.firstError, opts.budgetErrorRate*100))
+		}
+	}
+
+	fmt.Printf("\nConnection pool: peak %d of %d connections in use, %d waits for a connection totalling %v\n",
+		saturation.peakInUse, saturation.maxOpen, saturation.waits, saturation.waitDuration.Round(time.Millisecond))
+	for _, h := range []struct {
+		name, metric string
+		budget       time.Duration
+	}{
+		{"Tenant connection acquisition", acquireMetric, opts.budgetAcquireP99},
+		{"RLS context setup", contextMetric, opts.budgetContextP99},
+	} {
+		window := after[h.metric].since(before[h.metric])
+		if window.count == 0 {
+			fmt.Printf("%s: not observed\n", h.name)
+			continue
+		}
+		p99 := window.quantile(0.99)
+		fmt.Printf("%s: %.0f observations, p50 %v, p95 %v, p99 %v\n", h.name, window.count,
+			window.quantile(0.50), window.quantile(0.95), p99)
+		if h.budget > 0 && p99 > h.budget {
+			violations = append(violations, fmt.Sprintf("%s: p99 of %v exceeds the budget of %v", h.name, p99, h.budget))
+		}
+	}
+
+	if len(violations) > 0 {
+		fmt.Println("\nBudgets exceeded:")
+		for _, v := range violations {
+			fmt.Println("  " + v)
+		}
+		return fmt.Errorf("%d budgets exceeded", len(violations))
+	}
+	fmt.Println("\nAll budgets met")
+	return nil
+}
+
+// result collects the latencies of one target
+type result struct {
+	mu         sync.Mutex
+	latencies  []time.Duration
+	errors     int
+	firstError string
+}
+
+func (r *result) add(latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies = append(r.latencies, latency)
+	if err != nil {
+		if r.errors == 0 {
+			r.firstError = err.Error()
+		}
+		r.errors++
+	}
+}
+
+func (r *result) sort() {
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+}
+
+func (r *result) percentile(p int) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	return r.latencies[(len(r.latencies)-1)*p/100].Round(time.Microsecond)
+}
+
+// load sends requests over the targets in turn for duration and returns how long it ran.
+// With results nil, as while warming up, nothing is recorded.
+func load(ctx context.Context, client *http.Client, opts options, targets []target, duration time.Duration, results []*result) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		var tick <-chan time.Time
+		if opts.rate > 0 {
+			ticker := time.NewTicker(time.Second / time.Duration(opts.rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for i := 0; ; i++ {
+			if tick != nil {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case next <- i % len(targets):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w := 0; w < opts.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				begun := time.Now()
+				err := send(ctx, client, opts, targets[i])
+				if ctx.Err() != nil {
+					// Requests cut off by the end of the run say nothing about the server
+					return
+				}
+				if results != nil {
+					results[i].add(time.Since(begun), err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+func send(ctx context.Context, client *http.Client, opts options, t target) error {
+	req, err := http.NewRequestWithContext(ctx, t.method, opts.baseURL+t.path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-User-ID", opts.userID)
+	if opts.orgID != "" {
+		req.Header.Set("X-Org-ID", opts.orgID)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Histograms of the tenant connection path the server exports
+const (
+	acquireMetric = "openvdo_db_connection_acquire_seconds"
+	contextMetric = "openvdo_db_rls_context_setup_seconds"
+)
+
+// serverStats reads the server's own view of the run
+type serverStats struct {
+	client  *http.Client
+	baseURL string
+}
+
+// histogram is the cumulative bucket counts of a Prometheus histogram
+type histogram struct {
+	bounds []float64
+	counts []float64
+	count  float64
+}
+
+// since returns the observations made between an earlier reading and this one
+func (h histogram) since(earlier histogram) histogram {
+	window := histogram{bounds: h.bounds, counts: make([]float64, len(h.counts)), count: h.count - earlier.count}
+	for i := range h.counts {
+		window.counts[i] = h.counts[i]
+		if i < len(earlier.counts) {
+			window.counts[i] -= earlier.counts[i]
+		}
+	}
+	return window
+}
+
+// quantile interpolates within the bucket holding the rank, as histogram_quantile does
+func (h histogram) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := q * h.count
+	lower, previous := 0.0, 0.0
+	for i, bound := range h.bounds {
+		if h.counts[i] >= rank {
+			if math.IsInf(bound, 1) {
+				return seconds(lower)
+			}
+			return seconds(lower + (bound-lower)*(rank-previous)/(h.counts[i]-previous))
+		}
+		lower, previous = bound, h.counts[i]
+	}
+	return seconds(lower)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Microsecond)
+}
+
+// histograms reads the tenant connection histograms from /metrics
+func (s *serverStats) histograms(ctx context.Context) (map[string]histogram, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/metrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the server's metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read the server's metrics: status %d", resp.StatusCode)
+	}
+
+	type bucket struct{ bound, count float64 }
+	buckets := map[string][]bucket{}
+	totals := map[string]float64{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, metric := range []string{acquireMetric, contextMetric} {
+			if rest, ok := strings.CutPrefix(line, metric+`_bucket{le="`); ok {
+				bound, value, ok := strings.Cut(rest, `"} `)
+				if !ok {
+					continue
+				}
+				b, err1 := strconv.ParseFloat(bound, 64)
+				v, err2 := strconv.ParseFloat(value, 64)
+				if err1 == nil && err2 == nil {
+					buckets[metric] = append(buckets[metric], bucket{b, v})
+				}
+			} else if rest, ok := strings.CutPrefix(line, metric+"_count "); ok {
+				totals[metric], _ = strconv.ParseFloat(rest, 64)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the server's metrics: %w", err)
+	}
+
+	histograms := map[string]histogram{}
+	for metric, bs := range buckets {
+		sort.Slice(bs, func(i, j int) bool { return bs[i].bound < bs[j].bound })
+		h := histogram{count: totals[metric]}
+		for _, b := range bs {
+			h.bounds = append(h.bounds, b.bound)
+			h.counts = append(h.counts, b.count)
+		}
+		histograms[metric] = h
+	}
+	return histograms, nil
+}
+
+// saturation is how close the server's connection pool came to running out
+type saturation struct {
+	peakInUse    int64
+	maxOpen      int
+	waits        int64
+	waitDuration time.Duration
+}
+
+// poolSampler polls /stats/db while requests run
+type poolSampler struct {
+	done   chan struct{}
+	result chan saturation
+}
+
+// poolStats is the part of /stats/db the sampler reads
+type poolStats struct {
+	Data struct {
+		ActiveConnections int64         `json:"active_connections"`
+		WaitCount         int64         `json:"wait_count"`
+		WaitDuration      time.Duration `json:"wait_duration"`
+		Sizing            struct {
+			MaxOpenConns int `json:"max_open_conns"`
+		} `json:"sizing"`
+	} `json:"data"`
+}
+
+func (s *serverStats) pool(ctx context.Context) (poolStats, error) {
+	var stats poolStats
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/stats/db", nil)
+	if err != nil {
+		return stats, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return stats, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return stats, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return stats, json.NewDecoder(resp.Body).Decode(&stats)
+}
+
+// samplePool polls the pool every 250ms until stop is called
+func (s *serverStats) samplePool(ctx context.Context) *poolSampler {
+	p := &poolSampler{done: make(chan struct{}), result: make(chan saturation, 1)}
+	go func() {
+		var sat saturation
+		var first *poolStats
+		observe := func() {
+			stats, err := s.pool(ctx)
+			if err != nil {
+				return
+			}
+			if first == nil {
+				first = &stats
+			}
+			sat.peakInUse = max(sat.peakInUse, stats.Data.ActiveConnections)
+			sat.maxOpen = stats.Data.Sizing.MaxOpenConns
+			sat.waits = stats.Data.WaitCount - first.Data.WaitCount
+			sat.waitDuration = stats.Data.WaitDuration - first.Data.WaitDuration
+		}
+
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		for observe(); ; observe() {
+			select {
+			case <-p.done:
+				p.result <- sat
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return p
+}
+
+func (p *poolSampler) stop() saturation {
+	close(p.done)
+	return <-p.result
+}
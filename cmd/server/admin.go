@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"openvdo/internal/bootstrap"
+	"openvdo/internal/maintenance"
+	"openvdo/internal/models"
+	"openvdo/internal/openapi"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func newAdminCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Administrative tasks that run directly against the database or generate configuration",
+	}
+	cmd.AddCommand(newCreateUserCmd(), newGrantRoleCmd(), newGenerateVAPIDKeysCmd(), newMaintenanceCmd(), newBenchServingCmd(), newOpenAPICmd(), newVerifySchemaCmd())
+	return cmd
+}
+
+func newCreateUserCmd() *cobra.Command {
+	var email, name, password, org, role string
+	var createOrg bool
+
+	cmd := &cobra.Command{
+		Use:   "create-user",
+		Short: "Create a user, optionally as a member of an organization",
+		Example: `  openvdo admin create-user --email admin@example.com --name Admin --org Acme --create-org
+  echo "$PASSWORD" | openvdo admin create-user --email dev@example.com --password-stdin --org Acme --role developer`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if org == "" && createOrg {
+				return fmt.Errorf("--create-org requires --org")
+			}
+			if org != "" && !models.ValidRole(role) {
+				return fmt.Errorf("invalid role %q (valid: %s)", role, strings.Join(models.Roles, ", "))
+			}
+
+			if password == "" {
+				var err error
+				if password, err = readPassword(cmd); err != nil {
+					return err
+				}
+			}
+			if len(password) < 8 {
+				return fmt.Errorf("password must be at least 8 characters")
+			}
+
+			return withAdminTx(func(ctx context.Context, tx *sql.Tx) error {
+				userID, err := queries.New(tx).CreateUser(ctx, queries.CreateUserParams{
+					Email: email, Password: password, Name: name,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create user: %w", err)
+				}
+				fmt.Printf("Created user %s (%s)\n", email, userID)
+
+				if org == "" {
+					return nil
+				}
+
+				orgID, err := resolveOrganization(ctx, tx, org, createOrg)
+				if err != nil {
+					return err
+				}
+				if err := grantRole(ctx, tx, userID, orgID, role); err != nil {
+					return err
+				}
+				fmt.Printf("Granted %s on organization %s (%s)\n", role, org, orgID)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&email, "email", "", "email address (required)")
+	cmd.Flags().StringVar(&name, "name", "", "display name")
+	cmd.Flags().StringVar(&password, "password", "", "password; prompted for when omitted")
+	cmd.Flags().Bool("password-stdin", false, "read the password from standard input")
+	cmd.Flags().StringVar(&org, "org", "", "organization name or ID to add the user to")
+	cmd.Flags().BoolVar(&createOrg, "create-org", false, "create the organization if it does not exist")
+	cmd.Flags().StringVar(&role, "role", models.RoleOwner, "role in the organization")
+	cmd.MarkFlagRequired("email")
+	cmd.MarkFlagsMutuallyExclusive("password", "password-stdin")
+	return cmd
+}
+
+func newGenerateVAPIDKeysCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "generate-vapid-keys",
+		Short:   "Print a new VAPID key pair for Web Push notifications",
+		Long:    "Print a new VAPID key pair for Web Push notifications. Browsers subscribe with the public key, so\nreplacing the pair invalidates every existing Web Push subscription.",
+		Example: `  openvdo admin generate-vapid-keys >> .env`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			public, private, err := services.GenerateVAPIDKeys()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("PUSH_VAPID_PUBLIC_KEY=%s\nPUSH_VAPID_PRIVATE_KEY=%s\n", public, private)
+			return nil
+		},
+	}
+}
+
+func newOpenAPICmd() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:     "openapi",
+		Short:   "Print the API description as OpenAPI 3.1",
+		Long:    "Print the API description as OpenAPI 3.1, converted from the Swagger 2.0 document generated into docs.\nThe running server publishes the same document at /openapi.json.",
+		Example: `  openvdo admin openapi --output docs/openapi.json`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doc, err := openapi.Document()
+			if err != nil {
+				return err
+			}
+			doc = append(doc, '\n')
+			if output == "" {
+				_, err = os.Stdout.Write(doc)
+				return err
+			}
+			return os.WriteFile(output, doc, 0o644)
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write instead of standard output")
+	return cmd
+}
+
+func newVerifySchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-schema",
+		Short: "Run the startup checks of the database, including the queries of the core tables",
+		Long: `Run the checks the server runs at startup: required extensions, the migration version, row level
+security, and the generated queries of users, organizations, videos and roles, each prepared against
+the schema, with the columns of the core tables compared to their generated models. Run it after
+migrating a scratch database in CI to catch a query a migration broke before deploying.`,
+		Example: `  openvdo migrate up && openvdo admin verify-schema`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Bootstrap.Timeout)
+			defer cancel()
+
+			db, err := bootstrap.WaitForPostgres(ctx, cfg.Database)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			if err := bootstrap.Verify(ctx, db, cfg.Bootstrap.RequiredExtensions); err != nil {
+				return err
+			}
+			fmt.Println("Database schema verified")
+			return nil
+		},
+	}
+}
+
+func newMaintenanceCmd() *cobra.Command {
+	var message string
+	var retryAfter time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "maintenance [on|off|status]",
+		Short: "Turn the fleet-wide maintenance mode on or off, or show it",
+		Long: `Turn the fleet-wide maintenance mode on or off, or show it. While it is on, API instances answer
+requests that change data with 503 and Retry-After, and keep serving reads and playback. Instances pick
+the change up within MAINTENANCE_POLL_INTERVAL.`,
+		Example: `  openvdo admin maintenance on --message "Upgrading the database" --retry-after 5m
+  openvdo migrate up
+  openvdo admin maintenance off`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"on", "off", "status"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			action := args[0]
+			if action != "on" && action != "off" && action != "status" {
+				return fmt.Errorf("unknown action %q (valid: on, off, status)", action)
+			}
+
+			return withAdminTx(func(ctx context.Context, tx *sql.Tx) error {
+				state, err := maintenance.Load(ctx, tx)
+				if err != nil {
+					return fmt.Errorf("failed to read maintenance mode: %w", err)
+				}
+				if action != "status" {
+					if state, err = maintenance.Save(ctx, tx, action == "on", message, int(retryAfter.Seconds())); err != nil {
+						return fmt.Errorf("failed to save maintenance mode: %w", err)
+					}
+				}
+
+				if !state.Enabled {
+					fmt.Println("Maintenance mode is off")
+					return nil
+				}
+				fmt.Printf("Maintenance mode is on since %s (Retry-After %ds)\n", state.StartedAt.Format(time.RFC3339), state.RetryAfter)
+				if state.Message != "" {
+					fmt.Printf("Message: %s\n", state.Message)
+				}
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&message, "message", "", "message returned to refused requests")
+	cmd.Flags().DurationVar(&retryAfter, "retry-after", time.Minute, "how long refused clients are told to wait")
+	return cmd
+}
+
+func newGrantRoleCmd() *cobra.Command {
+	var email, org, role string
+
+	cmd := &cobra.Command{
+		Use:     "grant-role",
+		Short:   "Grant a user a role in an organization, replacing any existing role",
+		Example: `  openvdo admin grant-role --email dev@example.com --org Acme --role admin`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !models.ValidRole(role) {
+				return fmt.Errorf("invalid role %q (valid: %s)", role, strings.Join(models.Roles, ", "))
+			}
+
+			return withAdminTx(func(ctx context.Context, tx *sql.Tx) error {
+				userID, err := queries.New(tx).FindUserByEmail(ctx, email)
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("user %s not found", email)
+				}
+				if err != nil {
+					return err
+				}
+
+				orgID, err := resolveOrganization(ctx, tx, org, false)
+				if err != nil {
+					return err
+				}
+				if err := grantRole(ctx, tx, userID, orgID, role); err != nil {
+					return err
+				}
+				fmt.Printf("Granted %s on organization %s (%s) to %s\n", role, org, orgID, email)
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&email, "email", "", "email address of the user (required)")
+	cmd.Flags().StringVar(&org, "org", "", "organization name or ID (required)")
+	cmd.Flags().StringVar(&role, "role", "", "role to grant (required)")
+	cmd.MarkFlagRequired("email")
+	cmd.MarkFlagRequired("org")
+	cmd.MarkFlagRequired("role")
+	return cmd
+}
+
+// withAdminTx runs fn in a transaction on the primary database. Admin commands connect as
+// the configured database user like the migrations do, so they are not subject to RLS.
+func withAdminTx(fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Bootstrap.Timeout)
+	defer cancel()
+
+	db, err := bootstrap.WaitForPostgres(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// resolveOrganization looks an organization up by ID or name, creating it by name if requested
+func resolveOrganization(ctx context.Context, tx *sql.Tx, org string, create bool) (uuid.UUID, error) {
+	qtx := queries.New(tx)
+	orgID, err := qtx.FindOrganization(ctx, org)
+	if err == nil {
+		return orgID, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, err
+	}
+	if !create {
+		return uuid.Nil, fmt.Errorf("organization %s not found", org)
+	}
+
+	orgID, err = qtx.CreateNamedOrganization(ctx, org)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	fmt.Printf("Created organization %s (%s)\n", org, orgID)
+	return orgID, nil
+}
+
+func grantRole(ctx context.Context, tx *sql.Tx, userID, orgID uuid.UUID, role string) error {
+	err := queries.New(tx).GrantRole(ctx, queries.GrantRoleParams{UserID: userID, OrganizationID: orgID, Role: role})
+	if err != nil {
+		return fmt.Errorf("failed to grant role: %w", err)
+	}
+	return nil
+}
+
+// readPassword reads the password from stdin, prompting without echo on a terminal
+func readPassword(cmd *cobra.Command) (string, error) {
+	fromStdin, _ := cmd.Flags().GetBool("password-stdin")
+	fd := int(os.Stdin.Fd())
+
+	if !fromStdin && term.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		return string(password), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "time"
+
+// processCPUTime is not measured outside Unix
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -1,56 +1,87 @@
 package main
 
 import (
-	"log"
 	"os"
+	_ "time/tzdata" // time zones of user profiles, for images without zoneinfo
 
+	"openvdo/internal/buildinfo"
 	"openvdo/internal/config"
-	"openvdo/internal/database"
-	"openvdo/internal/routes"
 	"openvdo/pkg/logger"
 
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
 	_ "openvdo/docs" // swagger docs
 )
 
 // @title OpenVDO API
 // @version 1.0
 // @description A high-performance video streaming backend built with Go, Gin, PostgreSQL, and Redis.
+// @description
+// @description Every route is served under /api/v1 and /api/v2. The schemas below describe /api/v1, where successes
+// @description are {"status": "success", "message": ..., "data": ...} and failures {"error": ...}. Under /api/v2 each
+// @description JSON body is wrapped in one envelope instead: {"data": ..., "pagination": {...}, "meta": {"request_id",
+// @description "message", "api_version"}} on success, and {"error": {"code", "message", "details"}, "meta": {...}} on
+// @description failure, where code is derived from the HTTP status (bad_request, not_found, conflict, ...).
 
 // @host localhost:8080
 
-func main() {
-	if err := godotenv.Load(); err != nil {
-		logger.Info("No .env file found, using environment variables")
-	}
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-User-ID
+// @description The ID of the user making the request
 
-	cfg := config.Load()
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description "Bearer " followed by ADMIN_TOKEN
 
-	// Initialize the stateless connection pool manager
-	if err := database.InitPoolManager(cfg.Database, cfg.Redis); err != nil {
-		log.Fatal("Failed to initialize stateless pool manager:", err)
-	}
-	defer database.ClosePoolManager()
+// cfg is loaded once before any subcommand runs
+var cfg *config.Config
 
-	
-	if gin.Mode() == gin.ReleaseMode {
-		gin.SetMode(gin.ReleaseMode)
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
 	}
+}
 
-	r := gin.New()
+func newRootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "openvdo",
+		Short:        "OpenVDO video platform server and operations tool",
+		Version:      buildinfo.Get().String(),
+		SilenceUsage: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if err := godotenv.Load(); err != nil {
+				logger.Info("No .env file found, using environment variables")
+			}
+			cfg = config.Load()
+		},
+		// Running the binary without a subcommand keeps starting the API server
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe(true)
+		},
+	}
 
-	// Get pool manager for routes
-	poolManager := database.GetPoolManager()
-	routes.Setup(r, poolManager, nil) // Redis is managed by pool manager
+	root.AddCommand(
+		newServeCmd(),
+		newWorkerCmd(),
+		newMigrateCmd(),
+		newAdminCmd(),
+	)
+	return root
+}
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// useRegion points cfg at the database and storage of the region given with --region, so an
+// instance runs next to that region's data
+func useRegion(region string) error {
+	if region == "" {
+		return nil
 	}
-
-	logger.Info("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	regional, err := cfg.InRegion(region)
+	if err != nil {
+		return err
 	}
-}
\ No newline at end of file
+	cfg = regional
+	logger.Info("Running in region %s", cfg.Regions.LocalRegion())
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"openvdo/internal/bootstrap"
+	"openvdo/migrations"
+	"openvdo/pkg/logger"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or roll back the embedded database migrations",
+	}
+
+	var all bool
+	down := &cobra.Command{
+		Use:   "down [N]",
+		Short: "Roll back the last N migrations (or all with --all)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !all {
+				return fmt.Errorf("specify the number of migrations to roll back or --all")
+			}
+			return withMigrator(func(m *migrate.Migrate) error {
+				if all {
+					return m.Down()
+				}
+				n, err := parseSteps(args[0])
+				if err != nil {
+					return err
+				}
+				return m.Steps(-n)
+			})
+		},
+	}
+	down.Flags().BoolVar(&all, "all", false, "roll back every migration")
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up [N]",
+			Short: "Apply all pending migrations, or the next N",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(func(m *migrate.Migrate) error {
+					if len(args) == 0 {
+						return m.Up()
+					}
+					n, err := parseSteps(args[0])
+					if err != nil {
+						return err
+					}
+					return m.Steps(n)
+				})
+			},
+		},
+		down,
+		&cobra.Command{
+			Use:   "version",
+			Short: "Print the applied migration version",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withMigrator(func(m *migrate.Migrate) error {
+					version, dirty, err := m.Version()
+					if errors.Is(err, migrate.ErrNilVersion) {
+						fmt.Printf("no migrations applied (latest available: %d)\n", migrations.LatestVersion())
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					fmt.Printf("version %d (latest available: %d, dirty: %t)\n", version, migrations.LatestVersion(), dirty)
+					return nil
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "force VERSION",
+			Short: "Set the migration version without running migrations, clearing the dirty flag",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				version, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid version %q", args[0])
+				}
+				return withMigrator(func(m *migrate.Migrate) error {
+					return m.Force(version)
+				})
+			},
+		},
+	)
+	return cmd
+}
+
+// withMigrator waits for the primary database and runs fn with a migrator over the embedded files
+func withMigrator(fn func(m *migrate.Migrate) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Bootstrap.Timeout)
+	defer cancel()
+
+	db, err := bootstrap.WaitForPostgres(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	err = fn(m)
+	if errors.Is(err, migrate.ErrNoChange) {
+		logger.Info("No migrations to apply")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if version, dirty, err := m.Version(); err == nil {
+		logger.Info("Database at migration version %d (dirty: %t)", version, dirty)
+	}
+	return nil
+}
+
+func newMigrator(db *sql.DB) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize migration driver: %w", err)
+	}
+
+	return migrate.NewWithInstance("iofs", source, "postgres", driver)
+}
+
+func parseSteps(arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", arg)
+	}
+	return n, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"openvdo/internal/app"
+	"openvdo/internal/bootstrap"
+	"openvdo/internal/buildinfo"
+	"openvdo/internal/httpserver"
+	"openvdo/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd() *cobra.Command {
+	var workers bool
+	var region string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start the HTTP API server",
+		Long: `Start the HTTP API server.
+
+By default the server also runs the background workers (storage lifecycle policies, the
+startup scan for unhashed uploads and the job queue). Pass --workers=false when they run in a separate
+"openvdo worker" process.
+
+On SIGINT or SIGTERM the server drains: /readyz fails while requests are still served for
+DRAIN_DELAY, so load balancers stop routing to it, and long-lived responses are ended over
+STREAM_DRAIN_GRACE. It then stops accepting connections and waits up to SHUTDOWN_TIMEOUT for
+requests in flight before exiting. A second signal skips the rest of the delay. GET /drainz
+starts the same drain from a Kubernetes preStop hook.
+
+With --region the server uses that region's database and storage, as configured with REGIONS,
+so embeds and workers of organizations pinned to it run next to their data.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := useRegion(region); err != nil {
+				return err
+			}
+			return runServe(workers)
+		},
+	}
+
+	cmd.Flags().BoolVar(&workers, "workers", true, "run background workers in this process")
+	cmd.Flags().StringVar(&region, "region", "", "run in this region instead of the home region")
+	return cmd
+}
+
+func runServe(workers bool) error {
+	// Wait for dependencies and refuse to start on a database missing extensions, migrations or RLS
+	if err := bootstrap.Run(cfg); err != nil {
+		return fmt.Errorf("bootstrap failed: %w", err)
+	}
+
+	serverTLS, err := httpserver.NewTLS(cfg.TLS)
+	if err != nil {
+		return err
+	}
+
+	a, err := app.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	if workers {
+		a.StartWorkers()
+	}
+	a.Start()
+
+	ln, where, err := httpserver.Listen(cfg.Server)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	srv := &http.Server{Handler: a.Router, Protocols: httpserver.Protocols(cfg.TLS)}
+	servers := []*http.Server{srv}
+	// One slot for each server: public, challenges, admin and metrics
+	serveErr := make(chan error, 4)
+	if serverTLS != nil {
+		srv.TLSConfig = serverTLS.Config
+		go func() {
+			logger.Info("Server %s starting on %s with TLS", buildinfo.Get(), where)
+			serveErr <- srv.ServeTLS(ln, "", "")
+		}()
+		if serverTLS.Challenges != nil {
+			challenges := &http.Server{Addr: cfg.TLS.AutocertHTTPAddr, Handler: serverTLS.Challenges, ReadHeaderTimeout: 10 * time.Second}
+			servers = append(servers, challenges)
+			go func() {
+				logger.Info("Answering ACME challenges and redirecting to HTTPS on %s", cfg.TLS.AutocertHTTPAddr)
+				serveErr <- challenges.ListenAndServe()
+			}()
+		}
+	} else {
+		go func() {
+			logger.Info("Server %s starting on %s", buildinfo.Get(), where)
+			serveErr <- srv.Serve(ln)
+		}()
+	}
+
+	// The admin API and metrics on ports of their own can be kept off the internet by a
+	// firewall. The admin API keeps TLS, which client certificates need.
+	if a.AdminRouter != nil {
+		admin := &http.Server{Handler: a.AdminRouter, Protocols: srv.Protocols, ReadHeaderTimeout: 10 * time.Second}
+		if serverTLS != nil {
+			admin.TLSConfig = serverTLS.Config
+		}
+		if err := serveInternal(admin, "admin API", cfg.Server.AdminAddr, serveErr); err != nil {
+			return err
+		}
+		servers = append(servers, admin)
+	}
+	if a.MetricsRouter != nil {
+		metricsSrv := &http.Server{Handler: a.MetricsRouter, ReadHeaderTimeout: 10 * time.Second}
+		if err := serveInternal(metricsSrv, "metrics", cfg.Server.MetricsAddr, serveErr); err != nil {
+			return err
+		}
+		servers = append(servers, metricsSrv)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("failed to start server: %w", err)
+	case sig := <-stop:
+		a.Drain.Start(sig.String())
+	}
+
+	// A preStop hook may already have waited out the delay, in which case this returns at once
+	waitCtx, cancelWait := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	a.Drain.Wait(waitCtx)
+	cancelWait()
+	logger.Info("Server shutting down, waiting up to %v for requests in flight", cfg.Server.ShutdownTimeout)
+
+	// New connections are refused at once; requests in flight, such as uploads, get the timeout
+	// to finish before the workers and connections are closed by a.Close
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	// The public listener closes first, so metrics are scraped until the end
+	for _, s := range servers {
+		if err := s.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to shut down gracefully: %w", err)
+		}
+	}
+	return nil
+}
+
+// serveInternal serves srv on the TCP address addr, with TLS when srv has a TLS config
+func serveInternal(srv *http.Server, name, addr string, serveErr chan<- error) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for the %s: %w", name, err)
+	}
+	go func() {
+		if srv.TLSConfig != nil {
+			logger.Info("Serving the %s on %s with TLS", name, addr)
+			serveErr <- srv.ServeTLS(ln, "", "")
+			return
+		}
+		logger.Info("Serving the %s on %s", name, addr)
+		serveErr <- srv.Serve(ln)
+	}()
+	return nil
+}
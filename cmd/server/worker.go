@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"openvdo/internal/app"
+	"openvdo/internal/bootstrap"
+	"openvdo/internal/buildinfo"
+	"openvdo/pkg/logger"
+
+	"github.com/spf13/cobra"
+)
+
+func newWorkerCmd() *cobra.Command {
+	var region string
+
+	cmd := &cobra.Command{
+		Use:   "worker",
+		Short: "Run background workers without the HTTP API",
+		Long: `Run the storage lifecycle policies, content hashing and queued jobs such as URL
+imports without serving HTTP.
+Use together with "openvdo serve --workers=false" to scale API and workers separately.
+
+Workers only process the jobs and objects of their own region's database; run one with
+--region for every region in REGIONS.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := useRegion(region); err != nil {
+				return err
+			}
+			return runWorker()
+		},
+	}
+
+	cmd.Flags().StringVar(&region, "region", "", "work on this region instead of the home region")
+	return cmd
+}
+
+func runWorker() error {
+	if err := bootstrap.Run(cfg); err != nil {
+		return fmt.Errorf("bootstrap failed: %w", err)
+	}
+
+	a, err := app.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	a.StartWorkers()
+
+	logger.Info("Worker %s started", buildinfo.Get())
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stop
+
+	logger.Info("Worker shutting down (%v)", sig)
+	return nil
+}
@@ -0,0 +1,308 @@
+// Package accesslog writes one structured entry per HTTP request. Client addresses are
+// truncated or hashed before they are written, requests can be sampled per route, and
+// entries can be shipped to syslog or Loki besides standard output.
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"openvdo/internal/buildinfo"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/metrics"
+	"openvdo/internal/middleware"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of requests, as counted by entriesTotal
+const (
+	OutcomeLogged     = "logged"
+	OutcomeSampledOut = "sampled_out"
+	OutcomeShipped    = "shipped"
+	OutcomeDropped    = "dropped"
+)
+
+var entriesTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "access_log",
+	Name:      "entries_total",
+	Help:      "Access log entries, by outcome",
+}, []string{"outcome"})
+
+// Entry is the access log line of one request. The query string and the path of the
+// referrer are left out since they can carry tokens, and the client address is anonymized.
+type Entry struct {
+	Time           time.Time  `json:"time"`
+	RequestID      string     `json:"request_id,omitempty"`
+	Method         string     `json:"method"`
+	Route          string     `json:"route,omitempty"`
+	Path           string     `json:"path"`
+	Protocol       string     `json:"protocol"`
+	Status         int        `json:"status"`
+	LatencyMS      float64    `json:"latency_ms"`
+	BytesIn        int64      `json:"bytes_in,omitempty"`
+	BytesOut       int64      `json:"bytes_out"`
+	DBQueries      int64      `json:"db_queries,omitempty"`
+	ClientIP       string     `json:"client_ip,omitempty"`
+	UserAgent      string     `json:"user_agent,omitempty"`
+	Referrer       string     `json:"referrer,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	APIKeyID       *uuid.UUID `json:"api_key_id,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	Version        string     `json:"version"`
+}
+
+// routeRate is the sample rate of a route, or of the routes it prefixes
+type routeRate struct {
+	route  string
+	prefix bool
+	rate   float64
+}
+
+// Logger writes entries to standard output as they are made and ships them to its sink in
+// batches. When the buffer is full, because the sink is slow or down, entries are dropped
+// from shipping rather than holding up requests.
+type Logger struct {
+	config     config.AccessLog
+	anonymizer *anonymizer
+	routes     []routeRate
+	sink       Sink
+
+	mu  sync.Mutex
+	out io.Writer
+
+	queue  chan Entry
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a logger from the configuration, which it checks
+func New(cfg config.AccessLog) (*Logger, error) {
+	anonymizer, err := newAnonymizer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := parseRouteRates(cfg.RouteSampleRates)
+	if err != nil {
+		return nil, err
+	}
+	sink, err := newSink(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Logger{
+		config:     cfg,
+		anonymizer: anonymizer,
+		routes:     routes,
+		sink:       sink,
+		out:        os.Stdout,
+		queue:      make(chan Entry, cfg.BufferSize),
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+// parseRouteRates reads route=rate entries. Longer routes come first, so the most specific
+// prefix wins.
+func parseRouteRates(items []string) ([]routeRate, error) {
+	routes := make([]routeRate, 0, len(items))
+	for _, item := range items {
+		route, value, ok := strings.Cut(item, "=")
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		route = strings.TrimSpace(route)
+		if !ok || route == "" || err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid route sample rate %q, expected route=rate with a rate from 0 to 1", item)
+		}
+		r := routeRate{route: route, rate: rate}
+		if strings.HasSuffix(route, "*") {
+			r.route, r.prefix = strings.TrimSuffix(route, "*"), true
+		}
+		routes = append(routes, r)
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return len(routes[i].route) > len(routes[j].route) })
+	return routes, nil
+}
+
+// sampleRate returns the share of requests of a route that are logged
+func (l *Logger) sampleRate(route string) float64 {
+	for _, r := range l.routes {
+		if route == r.route || (r.prefix && strings.HasPrefix(route, r.route)) {
+			return r.rate
+		}
+	}
+	return l.config.SampleRate
+}
+
+// Middleware logs each request once it has been handled. Routes are matched by their
+// template, such as /api/v1/videos/:id, and requests that matched none by an empty route.
+func (l *Logger) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.config.Enabled {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+
+		route := c.FullPath()
+		status := c.Writer.Status()
+		if rate := l.sampleRate(route); status < 500 && (rate <= 0 || (rate < 1 && rand.Float64() >= rate)) {
+			entriesTotal.WithLabelValues(OutcomeSampledOut).Inc()
+			return
+		}
+
+		entry := Entry{
+			Time:      start.UTC(),
+			RequestID: middleware.GetRequestID(c),
+			Method:    c.Request.Method,
+			Route:     route,
+			Path:      path,
+			Protocol:  c.Request.Proto,
+			Status:    status,
+			LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			BytesOut:  int64(max(c.Writer.Size(), 0)),
+			DBQueries: middleware.GetQueryCount(c),
+			ClientIP:  l.anonymizer.anonymize(c.ClientIP()),
+			UserAgent: c.Request.UserAgent(),
+			Referrer:  referrerOrigin(c.Request.Referer()),
+			Error:     c.Errors.ByType(gin.ErrorTypePrivate).String(),
+			Version:   buildinfo.Version(),
+		}
+		if c.Request.ContentLength > 0 {
+			entry.BytesIn = c.Request.ContentLength
+		}
+		if value, _ := c.Get(string(database.OrgIDKey)); value != nil {
+			if id, ok := value.(uuid.UUID); ok && id != uuid.Nil {
+				entry.OrganizationID = &id
+			}
+		}
+		if value, _ := c.Get(string(database.APIKeyIDKey)); value != nil {
+			if id, ok := value.(uuid.UUID); ok && id != uuid.Nil {
+				entry.APIKeyID = &id
+			}
+		}
+		l.log(entry)
+	}
+}
+
+// referrerOrigin keeps the scheme and host of a referrer
+func referrerOrigin(referrer string) string {
+	u, err := url.Parse(referrer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+func (l *Logger) log(entry Entry) {
+	entriesTotal.WithLabelValues(OutcomeLogged).Inc()
+	if l.config.Stdout {
+		line, err := json.Marshal(entry)
+		if err == nil {
+			l.mu.Lock()
+			l.out.Write(append(line, '\n'))
+			l.mu.Unlock()
+		}
+	}
+	if l.sink == nil {
+		return
+	}
+	select {
+	case l.queue <- entry:
+	default:
+		entriesTotal.WithLabelValues(OutcomeDropped).Inc()
+	}
+}
+
+// Start ships queued entries every FlushInterval, or as soon as BatchSize are waiting
+func (l *Logger) Start() {
+	if l.sink == nil {
+		return
+	}
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		ticker := time.NewTicker(l.config.FlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]Entry, 0, l.config.BatchSize)
+		for {
+			select {
+			case <-l.ctx.Done():
+				l.drain(batch)
+				return
+			case e := <-l.queue:
+				batch = append(batch, e)
+				if len(batch) >= l.config.BatchSize {
+					l.ship(batch)
+					batch = batch[:0]
+				}
+			case <-ticker.C:
+				l.ship(batch)
+				batch = batch[:0]
+			}
+		}
+	}()
+}
+
+// Stop ships the entries still queued and closes the sink
+func (l *Logger) Stop() {
+	l.cancel()
+	l.wg.Wait()
+	if l.sink != nil {
+		l.sink.Close()
+	}
+}
+
+// drain ships the batch being built and whatever is left in the queue
+func (l *Logger) drain(batch []Entry) {
+	for {
+		select {
+		case e := <-l.queue:
+			batch = append(batch, e)
+			if len(batch) >= l.config.BatchSize {
+				l.ship(batch)
+				batch = batch[:0]
+			}
+		default:
+			l.ship(batch)
+			return
+		}
+	}
+}
+
+func (l *Logger) ship(batch []Entry) {
+	if len(batch) == 0 {
+		return
+	}
+	// The logger's own context is already cancelled while draining on shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), l.config.WriteTimeout)
+	defer cancel()
+
+	if err := l.sink.Write(ctx, batch); err != nil {
+		logger.Error("Failed to ship %d access log entries to %s: %v", len(batch), l.config.Sink, err)
+		entriesTotal.WithLabelValues(OutcomeDropped).Add(float64(len(batch)))
+		return
+	}
+	entriesTotal.WithLabelValues(OutcomeShipped).Add(float64(len(batch)))
+}
@@ -0,0 +1,83 @@
+package accesslog
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+
+	"openvdo/internal/config"
+)
+
+// Modes of client address logging
+const (
+	IPModeTruncate = "truncate"
+	IPModeHash     = "hash"
+	IPModeFull     = "full"
+	IPModeNone     = "none"
+)
+
+// anonymizer turns client addresses into what the access log may keep. Truncated addresses
+// keep only their network prefix, enough to tell regions and providers apart but not
+// subscribers; hashes let requests of one address be correlated without revealing it.
+type anonymizer struct {
+	mode    string
+	v4Bits  int
+	v6Bits  int
+	hashKey []byte
+}
+
+func newAnonymizer(cfg config.AccessLog) (*anonymizer, error) {
+	a := &anonymizer{mode: cfg.IPMode, v4Bits: cfg.IPv4PrefixBits, v6Bits: cfg.IPv6PrefixBits}
+	switch cfg.IPMode {
+	case IPModeTruncate:
+		if a.v4Bits < 0 || a.v4Bits > 32 || a.v6Bits < 0 || a.v6Bits > 128 {
+			return nil, fmt.Errorf("IPv4 prefixes must be 0 to 32 bits and IPv6 prefixes 0 to 128 bits")
+		}
+	case IPModeHash:
+		a.hashKey = []byte(cfg.IPHashKey)
+		if len(a.hashKey) == 0 {
+			a.hashKey = make([]byte, 32)
+			if _, err := rand.Read(a.hashKey); err != nil {
+				return nil, err
+			}
+		}
+	case IPModeFull, IPModeNone:
+	default:
+		return nil, fmt.Errorf("unknown IP mode %q, expected truncate, hash, full or none", cfg.IPMode)
+	}
+	return a, nil
+}
+
+// anonymize returns the address to log for ip, or "" when none is
+func (a *anonymizer) anonymize(ip string) string {
+	if ip == "" || a.mode == IPModeNone {
+		return ""
+	}
+	switch a.mode {
+	case IPModeFull:
+		return ip
+	case IPModeHash:
+		mac := hmac.New(sha256.New, a.hashKey)
+		mac.Write([]byte(ip))
+		return hex.EncodeToString(mac.Sum(nil)[:16])
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		// Never log what could not be truncated
+		return ""
+	}
+	addr = addr.Unmap().WithZone("")
+	bits := a.v6Bits
+	if addr.Is4() {
+		bits = a.v4Bits
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.Addr().String()
+}
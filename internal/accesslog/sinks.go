@@ -0,0 +1,212 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+)
+
+// Sink receives batches of entries shipped away from the instance
+type Sink interface {
+	Write(ctx context.Context, entries []Entry) error
+	Close() error
+}
+
+// newSink returns the configured sink, or nil when entries are not shipped
+func newSink(cfg config.AccessLog) (Sink, error) {
+	switch cfg.Sink {
+	case "":
+		return nil, nil
+	case "syslog":
+		return newSyslogSink(cfg.SyslogAddress, cfg.SyslogTag)
+	case "loki":
+		return newLokiSink(cfg)
+	default:
+		return nil, fmt.Errorf("unknown sink %q, expected syslog or loki", cfg.Sink)
+	}
+}
+
+// syslogSink sends entries as RFC 5424 messages over UDP, one per datagram, or over TCP with
+// octet-counting framing (RFC 6587). The connection is dialed on first use and again after a
+// failed write.
+type syslogSink struct {
+	network  string
+	address  string
+	tag      string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogSink(address, tag string) (*syslogSink, error) {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Host == "" {
+		return nil, fmt.Errorf("invalid syslog address %q, expected udp://host:port or tcp://host:port", address)
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	if tag == "" {
+		tag = "-"
+	}
+	return &syslogSink{network: u.Scheme, address: u.Host, tag: tag, hostname: hostname}, nil
+}
+
+// Severities of RFC 5424 with the local0 facility
+const (
+	syslogInfo  = 16*8 + 6
+	syslogError = 16*8 + 3
+)
+
+func (s *syslogSink) Write(ctx context.Context, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, s.network, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	pid := strconv.Itoa(os.Getpid())
+	var buf bytes.Buffer
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		priority := syslogInfo
+		if e.Status >= 500 {
+			priority = syslogError
+		}
+		msg := fmt.Sprintf("<%d>1 %s %s %s %s access - %s", priority, e.Time.UTC().Format(time.RFC3339Nano),
+			s.hostname, s.tag, pid, line)
+
+		if s.network == "udp" {
+			if _, err := io.WriteString(s.conn, msg); err != nil {
+				s.reset()
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(&buf, "%d %s", len(msg), msg)
+	}
+	if buf.Len() > 0 {
+		if _, err := s.conn.Write(buf.Bytes()); err != nil {
+			s.reset()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) reset() {
+	s.conn.Close()
+	s.conn = nil
+}
+
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// lokiSink pushes entries to Loki's HTTP push API as one stream with the configured labels
+type lokiSink struct {
+	config config.AccessLog
+	labels map[string]string
+	client *http.Client
+}
+
+func newLokiSink(cfg config.AccessLog) (*lokiSink, error) {
+	u, err := url.Parse(cfg.LokiURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("ACCESS_LOG_LOKI_URL must be the http or https URL of Loki's push API")
+	}
+	labels := map[string]string{}
+	for _, label := range cfg.LokiLabels {
+		name, value, ok := strings.Cut(label, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid Loki label %q, expected name=value", label)
+		}
+		labels[name] = value
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("Loki streams need at least one label")
+	}
+	return &lokiSink{config: cfg, labels: labels, client: &http.Client{Timeout: cfg.WriteTimeout}}, nil
+}
+
+func (s *lokiSink) Write(ctx context.Context, entries []Entry) error {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	values := make([][2]string, 0, len(sorted))
+	for _, e := range sorted {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		values = append(values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), string(line)})
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"streams": []map[string]interface{}{{"stream": s.labels, "values": values}},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.LokiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.config.LokiTenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.config.LokiTenantID)
+	}
+	if s.config.LokiUsername != "" || s.config.LokiPassword != "" {
+		req.SetBasicAuth(s.config.LokiUsername, s.config.LokiPassword)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("loki answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (s *lokiSink) Close() error {
+	return nil
+}
@@ -0,0 +1,336 @@
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	"openvdo/internal/config"
+
+	"github.com/google/uuid"
+)
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ClickHouseStore keeps events in a ClickHouse MergeTree table through the HTTP interface.
+// Inserts use ClickHouse's asynchronous inserts, so the server also batches the writes of
+// every API instance before creating parts.
+type ClickHouseStore struct {
+	config config.ClickHouse
+	client *http.Client
+	table  string
+}
+
+// NewClickHouseStore creates a store for the configured server. The table is created by
+// EnsureSchema.
+func NewClickHouseStore(cfg config.ClickHouse) (*ClickHouseStore, error) {
+	if !identifierPattern.MatchString(cfg.Database) || !identifierPattern.MatchString(cfg.Table) {
+		return nil, fmt.Errorf("invalid ClickHouse database or table name %q.%q", cfg.Database, cfg.Table)
+	}
+	return &ClickHouseStore{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		table:  cfg.Database + "." + cfg.Table,
+	}, nil
+}
+
+// Name returns "clickhouse"
+func (s *ClickHouseStore) Name() string {
+	return "clickhouse"
+}
+
+// EnsureSchema creates the events table when it does not exist. Columns mirror Postgres'
+// playback_events; rows older than CLICKHOUSE_RETENTION are dropped by a TTL.
+func (s *ClickHouseStore) EnsureSchema(ctx context.Context) error {
+	ttl := ""
+	if days := int(s.config.Retention.Hours() / 24); days > 0 {
+		ttl = fmt.Sprintf("\nTTL toDateTime(occurred_at) + INTERVAL %d DAY", days)
+	}
+	query := `CREATE TABLE IF NOT EXISTS ` + s.table + ` (
+	organization_id UUID,
+	video_id UUID,
+	session_id String,
+	type LowCardinality(String),
+	occurred_at DateTime64(3, 'UTC'),
+	received_at DateTime64(3, 'UTC'),
+	position_seconds Nullable(Float64),
+	startup_ms Nullable(UInt32),
+	rebuffer_count Nullable(UInt32),
+	rebuffer_ms Nullable(UInt32),
+	bitrate_kbps Nullable(UInt32),
+	previous_bitrate_kbps Nullable(UInt32),
+	error_code String,
+	error_message String,
+	player LowCardinality(String),
+	user_agent String,
+	sample_rate Float32,
+	share_link_id Nullable(UUID)
+)
+ENGINE = MergeTree
+PARTITION BY toYYYYMM(occurred_at)
+ORDER BY (organization_id, occurred_at, video_id)` + ttl
+
+	body, err := s.exec(ctx, query, nil, nil)
+	if err != nil {
+		return err
+	}
+	if err := body.Close(); err != nil {
+		return err
+	}
+	// Tables created before share links lack their column
+	body, err = s.exec(ctx, `ALTER TABLE `+s.table+` ADD COLUMN IF NOT EXISTS share_link_id Nullable(UUID)`, nil, nil)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// clickHouseRow is an event as a JSONEachRow line
+type clickHouseRow struct {
+	OrganizationID      string   `json:"organization_id"`
+	VideoID             string   `json:"video_id"`
+	SessionID           string   `json:"session_id"`
+	Type                string   `json:"type"`
+	OccurredAt          string   `json:"occurred_at"`
+	ReceivedAt          string   `json:"received_at"`
+	Position            *float64 `json:"position_seconds"`
+	StartupMs           *int     `json:"startup_ms"`
+	RebufferCount       *int     `json:"rebuffer_count"`
+	RebufferMs          *int     `json:"rebuffer_ms"`
+	BitrateKbps         *int     `json:"bitrate_kbps"`
+	PreviousBitrateKbps *int     `json:"previous_bitrate_kbps"`
+	ErrorCode           string   `json:"error_code"`
+	ErrorMessage        string   `json:"error_message"`
+	Player              string   `json:"player"`
+	UserAgent           string   `json:"user_agent"`
+	SampleRate          float64  `json:"sample_rate"`
+	ShareLinkID         *string  `json:"share_link_id"`
+}
+
+// clickHouseTime formats a timestamp the way DateTime64(3) parses it by default
+func clickHouseTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05.000")
+}
+
+// Write inserts the events in one request
+func (s *ClickHouseStore) Write(ctx context.Context, events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		var shareLinkID *string
+		if e.ShareLinkID != nil {
+			id := e.ShareLinkID.String()
+			shareLinkID = &id
+		}
+		if err := enc.Encode(clickHouseRow{
+			OrganizationID: e.OrganizationID.String(), VideoID: e.VideoID.String(),
+			SessionID: e.SessionID, Type: e.Type,
+			OccurredAt: clickHouseTime(e.OccurredAt), ReceivedAt: clickHouseTime(e.ReceivedAt),
+			Position: e.Position, StartupMs: e.StartupMs, RebufferCount: e.RebufferCount, RebufferMs: e.RebufferMs,
+			BitrateKbps: e.BitrateKbps, PreviousBitrateKbps: e.PreviousBitrateKbps,
+			ErrorCode: e.ErrorCode, ErrorMessage: e.ErrorMessage, Player: e.Player, UserAgent: e.UserAgent,
+			SampleRate: e.SampleRate, ShareLinkID: shareLinkID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	settings := url.Values{
+		"async_insert":          {"1"},
+		"wait_for_async_insert": {"1"},
+	}
+	body, err := s.exec(ctx, `INSERT INTO `+s.table+` FORMAT JSONEachRow`, settings, &buf)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+const clickHouseAggregates = `
+	uniqExactIf(session_id, type = 'startup') AS sampled_sessions,
+	sumIf(1 / sample_rate, type = 'startup') AS estimated_sessions,
+	quantileExactIf(0.5)(startup_ms, type = 'startup') AS startup_p50_ms,
+	quantileExactIf(0.95)(startup_ms, type = 'startup') AS startup_p95_ms,
+	uniqExactIf(session_id, type = 'rebuffer') AS rebuffer_sessions,
+	sumIf(rebuffer_ms, type = 'rebuffer') AS rebuffer_ms,
+	countIf(type = 'error') AS errors,
+	uniqExactIf(session_id, type = 'error') AS error_sessions,
+	avgIf(bitrate_kbps, type = 'bitrate_switch') AS avg_bitrate_kbps,
+	countIf(type = 'bitrate_switch') AS bitrate_switches,
+	countIf(type = 'download') AS downloads`
+
+// filter builds the WHERE clause of a query and its parameters
+func (s *ClickHouseStore) filter(q Query) (string, url.Values) {
+	where := ` WHERE organization_id = {org:UUID}
+		AND occurred_at >= fromUnixTimestamp64Milli({from:Int64}) AND occurred_at < fromUnixTimestamp64Milli({to:Int64})`
+	params := url.Values{
+		"param_org":  {q.OrganizationID.String()},
+		"param_from": {strconv.FormatInt(q.From.UnixMilli(), 10)},
+		"param_to":   {strconv.FormatInt(q.To.UnixMilli(), 10)},
+	}
+	if q.VideoID != nil {
+		where += ` AND video_id = {video:UUID}`
+		params.Set("param_video", q.VideoID.String())
+	}
+	if q.ShareLinkID != nil {
+		where += ` AND share_link_id = {share_link:UUID}`
+		params.Set("param_share_link", q.ShareLinkID.String())
+	}
+	return where, params
+}
+
+// Summary aggregates the events a query selects
+func (s *ClickHouseStore) Summary(ctx context.Context, q Query) (*Summary, error) {
+	where, params := s.filter(q)
+	var summary *Summary
+	err := s.query(ctx, `SELECT `+clickHouseAggregates+` FROM `+s.table+where, params, func(line []byte) error {
+		var a aggregate
+		if err := json.Unmarshal(line, &a); err != nil {
+			return err
+		}
+		result := a.summary()
+		summary = &result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if summary == nil {
+		return &Summary{}, nil
+	}
+	return summary, nil
+}
+
+// Timeseries aggregates the events a query selects per interval, aligned to UTC. Intervals
+// without events are left out.
+func (s *ClickHouseStore) Timeseries(ctx context.Context, q Query) ([]Bucket, error) {
+	where, params := s.filter(q)
+	params.Set("param_interval", strconv.FormatInt(int64(q.Interval.Seconds()), 10))
+
+	buckets := []Bucket{}
+	err := s.query(ctx, `
+		SELECT toUnixTimestamp(toStartOfInterval(occurred_at, INTERVAL {interval:UInt32} SECOND)) AS bucket, `+
+		clickHouseAggregates+` FROM `+s.table+where+` GROUP BY bucket ORDER BY bucket`, params,
+		func(line []byte) error {
+			var row struct {
+				Bucket int64 `json:"bucket"`
+				aggregate
+			}
+			if err := json.Unmarshal(line, &row); err != nil {
+				return err
+			}
+			buckets = append(buckets, Bucket{Start: time.Unix(row.Bucket, 0).UTC(), Summary: row.aggregate.summary()})
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// Plays sums the startup events of each video in the range, weighted by their sample rate
+func (s *ClickHouseStore) Plays(ctx context.Context, from, to time.Time) ([]VideoPlays, error) {
+	params := url.Values{
+		"param_from": {strconv.FormatInt(from.UnixMilli(), 10)},
+		"param_to":   {strconv.FormatInt(to.UnixMilli(), 10)},
+	}
+	plays := []VideoPlays{}
+	err := s.query(ctx, `
+		SELECT video_id, sum(1 / sample_rate) AS sessions FROM `+s.table+`
+		WHERE type = 'startup'
+			AND occurred_at >= fromUnixTimestamp64Milli({from:Int64}) AND occurred_at < fromUnixTimestamp64Milli({to:Int64})
+		GROUP BY video_id`, params,
+		func(line []byte) error {
+			var row struct {
+				VideoID  uuid.UUID `json:"video_id"`
+				Sessions float64   `json:"sessions"`
+			}
+			if err := json.Unmarshal(line, &row); err != nil {
+				return err
+			}
+			plays = append(plays, VideoPlays{VideoID: row.VideoID, Sessions: row.Sessions})
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return plays, nil
+}
+
+// DeleteOrganization deletes the events of an organization with a lightweight delete, which
+// hides the rows at once and removes them as parts are merged
+func (s *ClickHouseStore) DeleteOrganization(ctx context.Context, orgID uuid.UUID) error {
+	body, err := s.exec(ctx, `DELETE FROM `+s.table+` WHERE organization_id = {org:UUID}`,
+		url.Values{"param_org": {orgID.String()}}, nil)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// query runs a SELECT and hands each JSONEachRow line to fn. Empty aggregates come back as
+// null rather than NaN, and 64-bit integers unquoted.
+func (s *ClickHouseStore) query(ctx context.Context, query string, params url.Values, fn func(line []byte) error) error {
+	params.Set("output_format_json_quote_64bit_integers", "0")
+	params.Set("output_format_json_quote_denormals", "0")
+	body, err := s.exec(ctx, query+` FORMAT JSONEachRow`, params, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// exec sends a statement with URL parameters, and data after the statement for inserts. The
+// caller closes the returned body.
+func (s *ClickHouseStore) exec(ctx context.Context, query string, params url.Values, data io.Reader) (io.ReadCloser, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("database", s.config.Database)
+
+	var body io.Reader = bytes.NewBufferString(query)
+	if data != nil {
+		// The statement goes in the URL so the body carries only rows
+		params.Set("query", query)
+		body = data
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL+"/?"+params.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.User != "" {
+		req.Header.Set("X-ClickHouse-User", s.config.User)
+		req.Header.Set("X-ClickHouse-Key", s.config.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("clickhouse: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,165 @@
+// Package analytics ingests the quality-of-experience events players report through the
+// beacon endpoint: startup time, rebuffering, bitrate switches and errors. Events are
+// validated and sampled on arrival, buffered in memory and written to a sink in batches, so a
+// burst of beacons costs one insert instead of one per event.
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types players report
+const (
+	EventStartup       = "startup"
+	EventRebuffer      = "rebuffer"
+	EventBitrateSwitch = "bitrate_switch"
+	EventError         = "error"
+	EventHeartbeat     = "heartbeat"
+	EventEnd           = "end"
+)
+
+// EventDownload is recorded by the server when a member fetches a download. Players cannot
+// report it, so it is not a valid beacon event.
+const EventDownload = "download"
+
+// Limits on reported values; anything beyond them is a broken or hostile player
+const (
+	maxSessionIDLength = 64
+	maxDurationMs      = 24 * 60 * 60 * 1000
+	maxBitrateKbps     = 1000000
+	maxErrorCodeLength = 100
+	maxErrorMessage    = 500
+	maxPlayerLength    = 100
+	maxUserAgentLength = 500
+
+	// Players may buffer events for a while, and clocks drift
+	maxEventAge  = 24 * time.Hour
+	maxClockSkew = 5 * time.Minute
+)
+
+// Event is one player report. The measurements that apply depend on Type.
+type Event struct {
+	VideoID   uuid.UUID `json:"video_id"`
+	SessionID string    `json:"session_id"`
+	Type      string    `json:"type"`
+	// OccurredAt is the player's clock; it defaults to the time the beacon arrived
+	OccurredAt          time.Time `json:"occurred_at"`
+	Position            *float64  `json:"position,omitempty"`
+	StartupMs           *int      `json:"startup_ms,omitempty"`
+	RebufferCount       *int      `json:"rebuffer_count,omitempty"`
+	RebufferMs          *int      `json:"rebuffer_ms,omitempty"`
+	BitrateKbps         *int      `json:"bitrate_kbps,omitempty"`
+	PreviousBitrateKbps *int      `json:"previous_bitrate_kbps,omitempty"`
+	ErrorCode           string    `json:"error_code,omitempty"`
+	ErrorMessage        string    `json:"error_message,omitempty"`
+	// ShareLinkID is the share link the video was opened through, if any
+	ShareLinkID *uuid.UUID `json:"share_link_id,omitempty"`
+
+	// Set by the server
+	OrganizationID uuid.UUID `json:"-"`
+	Player         string    `json:"-"`
+	UserAgent      string    `json:"-"`
+	SampleRate     float64   `json:"-"`
+	ReceivedAt     time.Time `json:"-"`
+}
+
+// Validate checks the event against the beacon schema, defaulting OccurredAt to now
+func (e *Event) Validate(now time.Time) error {
+	if e.VideoID == uuid.Nil {
+		return errors.New("video_id is required")
+	}
+	if e.SessionID == "" || len(e.SessionID) > maxSessionIDLength {
+		return fmt.Errorf("session_id must be 1 to %d characters", maxSessionIDLength)
+	}
+
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = now
+	}
+	if e.OccurredAt.Before(now.Add(-maxEventAge)) || e.OccurredAt.After(now.Add(maxClockSkew)) {
+		return errors.New("occurred_at is out of range")
+	}
+	if e.Position != nil && (*e.Position < 0 || *e.Position > float64(maxDurationMs)/1000) {
+		return errors.New("position is out of range")
+	}
+
+	switch e.Type {
+	case EventStartup:
+		if e.StartupMs == nil {
+			return errors.New("startup_ms is required for startup events")
+		}
+	case EventRebuffer:
+		if e.RebufferMs == nil {
+			return errors.New("rebuffer_ms is required for rebuffer events")
+		}
+	case EventBitrateSwitch:
+		if e.BitrateKbps == nil {
+			return errors.New("bitrate_kbps is required for bitrate_switch events")
+		}
+	case EventError:
+		if e.ErrorCode == "" {
+			return errors.New("error_code is required for error events")
+		}
+	case EventHeartbeat, EventEnd:
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+
+	for _, v := range []struct {
+		name  string
+		value *int
+		max   int
+	}{
+		{"startup_ms", e.StartupMs, maxDurationMs},
+		{"rebuffer_count", e.RebufferCount, maxDurationMs},
+		{"rebuffer_ms", e.RebufferMs, maxDurationMs},
+		{"bitrate_kbps", e.BitrateKbps, maxBitrateKbps},
+		{"previous_bitrate_kbps", e.PreviousBitrateKbps, maxBitrateKbps},
+	} {
+		if v.value != nil && (*v.value < 0 || *v.value > v.max) {
+			return fmt.Errorf("%s is out of range", v.name)
+		}
+	}
+
+	if len(e.ErrorCode) > maxErrorCodeLength {
+		return fmt.Errorf("error_code must be at most %d characters", maxErrorCodeLength)
+	}
+	// Messages are informational, so long ones are cut rather than refused
+	e.ErrorMessage = truncate(e.ErrorMessage, maxErrorMessage)
+	return nil
+}
+
+// Sampled reports whether a session is kept at the given rate. The decision depends only on
+// the session, so a playback's events are kept or dropped together and dashboards see whole
+// sessions.
+func Sampled(sessionID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(sessionID))
+	return float64(h.Sum32()%10000) < rate*10000
+}
+
+// NormalizeClient cuts the player name and user agent to the stored lengths
+func NormalizeClient(player, userAgent string) (string, string) {
+	return truncate(player, maxPlayerLength), truncate(userAgent, maxUserAgentLength)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	// Back up to a rune boundary so the result stays valid UTF-8
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,143 @@
+package analytics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/metrics"
+	"openvdo/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of beacon events, as counted by eventsTotal
+const (
+	OutcomeStored     = "stored"
+	OutcomeRejected   = "rejected"
+	OutcomeSampledOut = "sampled_out"
+	OutcomeUnknown    = "unknown_video"
+	OutcomeDropped    = "dropped"
+)
+
+var eventsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "beacon",
+	Name:      "events_total",
+	Help:      "Player events received through the beacon, by outcome",
+}, []string{"outcome"})
+
+// Count records events that did not reach the ingester, such as rejected or sampled out ones
+func Count(outcome string, n int) {
+	if n > 0 {
+		eventsTotal.WithLabelValues(outcome).Add(float64(n))
+	}
+}
+
+// Ingester buffers events in memory and writes them to its sink in batches. When the buffer
+// is full, because the sink is slow or down, new events are dropped rather than slowing the
+// beacon down; analytics are best effort.
+type Ingester struct {
+	sink   Sink
+	config config.Beacon
+	queue  chan Event
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewIngester creates an ingester that writes to sink once started
+func NewIngester(sink Sink, cfg config.Beacon) *Ingester {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Ingester{
+		sink:   sink,
+		config: cfg,
+		queue:  make(chan Event, cfg.BufferSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Submit queues events without blocking and returns how many were queued
+func (in *Ingester) Submit(events []Event) int {
+	queued := 0
+	for _, e := range events {
+		select {
+		case in.queue <- e:
+			queued++
+		default:
+		}
+	}
+	Count(OutcomeDropped, len(events)-queued)
+	return queued
+}
+
+// Start writes queued events every FlushInterval, or as soon as BatchSize are waiting
+func (in *Ingester) Start() {
+	in.wg.Add(1)
+	go func() {
+		defer in.wg.Done()
+		ticker := time.NewTicker(in.config.FlushInterval)
+		defer ticker.Stop()
+
+		batch := make([]Event, 0, in.config.BatchSize)
+		for {
+			select {
+			case <-in.ctx.Done():
+				in.drain(batch)
+				return
+			case e := <-in.queue:
+				batch = append(batch, e)
+				if len(batch) >= in.config.BatchSize {
+					in.write(batch)
+					batch = batch[:0]
+				}
+			case <-ticker.C:
+				in.write(batch)
+				batch = batch[:0]
+			}
+		}
+	}()
+}
+
+// Stop writes the events still queued and stops the ingester
+func (in *Ingester) Stop() {
+	in.cancel()
+	in.wg.Wait()
+}
+
+// drain writes the batch being built and whatever is left in the queue
+func (in *Ingester) drain(batch []Event) {
+	for {
+		select {
+		case e := <-in.queue:
+			batch = append(batch, e)
+			if len(batch) >= in.config.BatchSize {
+				in.write(batch)
+				batch = batch[:0]
+			}
+		default:
+			in.write(batch)
+			return
+		}
+	}
+}
+
+func (in *Ingester) write(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+	// The ingester's own context is already cancelled while draining on shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), in.config.WriteTimeout)
+	defer cancel()
+
+	if err := in.sink.Write(ctx, batch); err != nil {
+		logger.Error("Failed to write %d playback events: %v", len(batch), err)
+		Count(OutcomeDropped, len(batch))
+		return
+	}
+	Count(OutcomeStored, len(batch))
+}
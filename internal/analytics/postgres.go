@@ -0,0 +1,149 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"openvdo/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// playbackEventColumns are the columns Write copies events into
+var playbackEventColumns = []string{"organization_id", "video_id", "session_id", "type", "occurred_at",
+	"received_at", "position_seconds", "startup_ms", "rebuffer_count", "rebuffer_ms", "bitrate_kbps",
+	"previous_bitrate_kbps", "error_code", "error_message", "player", "user_agent", "sample_rate", "share_link_id"}
+
+// PostgresStore keeps events in the playback_events table
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a store on a connection without tenant context, since events of
+// every organization are written together. Queries filter by organization themselves.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+// Name returns "postgres"
+func (s *PostgresStore) Name() string {
+	return "postgres"
+}
+
+// Write copies the events into the table in one transaction
+func (s *PostgresStore) Write(ctx context.Context, events []Event) error {
+	insert := database.NewBulkInsert("playback_events", playbackEventColumns...)
+	for _, e := range events {
+		insert.Add(e.OrganizationID, e.VideoID, e.SessionID, e.Type, e.OccurredAt,
+			e.ReceivedAt, e.Position, e.StartupMs, e.RebufferCount, e.RebufferMs, e.BitrateKbps, e.PreviousBitrateKbps,
+			nullString(e.ErrorCode), nullString(e.ErrorMessage), nullString(e.Player), nullString(e.UserAgent), e.SampleRate,
+			e.ShareLinkID)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := insert.Copy(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// postgresAggregates computes an aggregate over the rows a query selects
+const postgresAggregates = `
+	COUNT(DISTINCT session_id) FILTER (WHERE type = 'startup'),
+	COALESCE(SUM(1 / sample_rate) FILTER (WHERE type = 'startup'), 0),
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY startup_ms) FILTER (WHERE type = 'startup'),
+	percentile_cont(0.95) WITHIN GROUP (ORDER BY startup_ms) FILTER (WHERE type = 'startup'),
+	COUNT(DISTINCT session_id) FILTER (WHERE type = 'rebuffer'),
+	COALESCE(SUM(rebuffer_ms) FILTER (WHERE type = 'rebuffer'), 0),
+	COUNT(*) FILTER (WHERE type = 'error'),
+	COUNT(DISTINCT session_id) FILTER (WHERE type = 'error'),
+	AVG(bitrate_kbps) FILTER (WHERE type = 'bitrate_switch'),
+	COUNT(*) FILTER (WHERE type = 'bitrate_switch'),
+	COUNT(*) FILTER (WHERE type = 'download')`
+
+const postgresFilter = `
+	WHERE organization_id = $1 AND occurred_at >= $2 AND occurred_at < $3
+		AND ($4::uuid IS NULL OR video_id = $4) AND ($5::uuid IS NULL OR share_link_id = $5)`
+
+func scanAggregate(row interface{ Scan(...interface{}) error }, dest ...interface{}) (aggregate, error) {
+	var a aggregate
+	err := row.Scan(append(dest, &a.SampledSessions, &a.EstimatedSessions, &a.StartupP50Ms, &a.StartupP95Ms,
+		&a.RebufferSessions, &a.RebufferMs, &a.Errors, &a.ErrorSessions, &a.AvgBitrateKbps, &a.BitrateSwitches, &a.Downloads)...)
+	return a, err
+}
+
+// Summary aggregates the events a query selects
+func (s *PostgresStore) Summary(ctx context.Context, q Query) (*Summary, error) {
+	a, err := scanAggregate(s.db.QueryRowContext(ctx,
+		`SELECT `+postgresAggregates+` FROM playback_events`+postgresFilter,
+		q.OrganizationID, q.From, q.To, q.VideoID, q.ShareLinkID))
+	if err != nil {
+		return nil, err
+	}
+	summary := a.summary()
+	return &summary, nil
+}
+
+// Timeseries aggregates the events a query selects per interval, aligned to UTC. Intervals
+// without events are left out.
+func (s *PostgresStore) Timeseries(ctx context.Context, q Query) ([]Bucket, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT to_timestamp(floor(extract(epoch FROM occurred_at) / $6) * $6) AS bucket, `+postgresAggregates+`
+		FROM playback_events`+postgresFilter+`
+		GROUP BY bucket
+		ORDER BY bucket`,
+		q.OrganizationID, q.From, q.To, q.VideoID, q.ShareLinkID, int64(q.Interval.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := []Bucket{}
+	for rows.Next() {
+		var start time.Time
+		a, err := scanAggregate(rows, &start)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, Bucket{Start: start.UTC(), Summary: a.summary()})
+	}
+	return buckets, rows.Err()
+}
+
+// Plays sums the startup events of each video in the range, weighted by their sample rate
+func (s *PostgresStore) Plays(ctx context.Context, from, to time.Time) ([]VideoPlays, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT video_id, SUM(1 / sample_rate) FROM playback_events
+		WHERE type = 'startup' AND occurred_at >= $1 AND occurred_at < $2
+		GROUP BY video_id
+	`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plays := []VideoPlays{}
+	for rows.Next() {
+		var p VideoPlays
+		if err := rows.Scan(&p.VideoID, &p.Sessions); err != nil {
+			return nil, err
+		}
+		plays = append(plays, p)
+	}
+	return plays, rows.Err()
+}
+
+// DeleteOrganization deletes the events of an organization
+func (s *PostgresStore) DeleteOrganization(ctx context.Context, orgID uuid.UUID) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM playback_events WHERE organization_id = $1`, orgID)
+	return err
+}
@@ -0,0 +1,137 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sink stores batches of validated events
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+// Store is where events are written and quality-of-experience aggregates are read from:
+// the playback_events table in Postgres, or ClickHouse for high volumes
+type Store interface {
+	Sink
+	// Name identifies the backend in responses and logs
+	Name() string
+	Summary(ctx context.Context, q Query) (*Summary, error)
+	Timeseries(ctx context.Context, q Query) ([]Bucket, error)
+	// Plays estimates the sessions started per video in [from, to), across organizations
+	Plays(ctx context.Context, from, to time.Time) ([]VideoPlays, error)
+	// DeleteOrganization deletes the events of an organization
+	DeleteOrganization(ctx context.Context, orgID uuid.UUID) error
+}
+
+// VideoPlays is the number of sessions of a video, estimated from the sampled ones
+type VideoPlays struct {
+	VideoID  uuid.UUID
+	Sessions float64
+}
+
+// Aggregation intervals of Timeseries
+const (
+	IntervalHour = time.Hour
+	IntervalDay  = 24 * time.Hour
+)
+
+// Limits on the range a query may cover, so one request cannot scan the whole history
+const (
+	MaxRange   = 90 * 24 * time.Hour
+	MaxBuckets = 1000
+)
+
+// ErrInvalidQuery is returned for ranges and intervals outside the limits
+var ErrInvalidQuery = errors.New("invalid analytics query")
+
+// Query selects the events of one organization, optionally of one video or share link, in
+// [From, To)
+type Query struct {
+	OrganizationID uuid.UUID
+	VideoID        *uuid.UUID
+	ShareLinkID    *uuid.UUID
+	From           time.Time
+	To             time.Time
+	// Interval is the bucket width of Timeseries
+	Interval time.Duration
+}
+
+// Validate checks the range and, for time series, the number of buckets
+func (q Query) Validate() error {
+	if !q.From.Before(q.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidQuery)
+	}
+	if q.To.Sub(q.From) > MaxRange {
+		return fmt.Errorf("%w: range must not exceed 90 days", ErrInvalidQuery)
+	}
+	if q.Interval > 0 && q.To.Sub(q.From)/q.Interval > MaxBuckets {
+		return fmt.Errorf("%w: too many buckets; use a wider interval", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// Summary aggregates the quality of experience of a set of playbacks. Sessions are estimated
+// from the sampled ones by their sample rate; rates and percentiles are taken over the sampled
+// sessions, which sampling leaves unbiased. Error events are never sampled, so Errors is exact.
+type Summary struct {
+	Sessions     int64    `json:"sessions"`
+	StartupP50Ms *float64 `json:"startup_p50_ms"`
+	StartupP95Ms *float64 `json:"startup_p95_ms"`
+	// RebufferRate is the share of sessions that rebuffered at least once
+	RebufferRate              float64  `json:"rebuffer_rate"`
+	RebufferMsPerSession      float64  `json:"rebuffer_ms_per_session"`
+	Errors                    int64    `json:"errors"`
+	ErrorRate                 float64  `json:"error_rate"`
+	AvgBitrateKbps            *float64 `json:"avg_bitrate_kbps"`
+	BitrateSwitchesPerSession float64  `json:"bitrate_switches_per_session"`
+	// Downloads counts the fetches of download files, which are recorded by the server
+	Downloads int64 `json:"downloads"`
+}
+
+// Bucket is the summary of one interval of a time series
+type Bucket struct {
+	Start time.Time `json:"start"`
+	Summary
+}
+
+// aggregate holds the sums both backends compute, from which Summary derives its rates
+type aggregate struct {
+	SampledSessions   int64    `json:"sampled_sessions"`
+	EstimatedSessions float64  `json:"estimated_sessions"`
+	StartupP50Ms      *float64 `json:"startup_p50_ms"`
+	StartupP95Ms      *float64 `json:"startup_p95_ms"`
+	RebufferSessions  int64    `json:"rebuffer_sessions"`
+	RebufferMs        int64    `json:"rebuffer_ms"`
+	Errors            int64    `json:"errors"`
+	ErrorSessions     int64    `json:"error_sessions"`
+	AvgBitrateKbps    *float64 `json:"avg_bitrate_kbps"`
+	BitrateSwitches   int64    `json:"bitrate_switches"`
+	Downloads         int64    `json:"downloads"`
+}
+
+func (a aggregate) summary() Summary {
+	s := Summary{
+		Sessions:       int64(math.Round(a.EstimatedSessions)),
+		StartupP50Ms:   a.StartupP50Ms,
+		StartupP95Ms:   a.StartupP95Ms,
+		Errors:         a.Errors,
+		AvgBitrateKbps: a.AvgBitrateKbps,
+		Downloads:      a.Downloads,
+	}
+	if a.SampledSessions > 0 {
+		sampled := float64(a.SampledSessions)
+		s.RebufferRate = float64(a.RebufferSessions) / sampled
+		s.RebufferMsPerSession = float64(a.RebufferMs) / sampled
+		s.BitrateSwitchesPerSession = float64(a.BitrateSwitches) / sampled
+	}
+	if a.EstimatedSessions > 0 {
+		s.ErrorRate = math.Min(float64(a.ErrorSessions)/a.EstimatedSessions, 1)
+	}
+	return s
+}
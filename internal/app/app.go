@@ -0,0 +1,415 @@
+// Package app wires configuration, database pools, storage, services and routes into one
+// instance. Apart from the process-wide Prometheus metrics nothing is kept in package
+// globals, so several instances can run side by side in one process.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"openvdo/internal/accesslog"
+	"openvdo/internal/analytics"
+	"openvdo/internal/auth"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/errortracking"
+	"openvdo/internal/faults"
+	"openvdo/internal/flags"
+	"openvdo/internal/flightrecorder"
+	"openvdo/internal/health"
+	"openvdo/internal/images"
+	"openvdo/internal/ipaccess"
+	"openvdo/internal/jobs"
+	"openvdo/internal/leader"
+	"openvdo/internal/maintenance"
+	"openvdo/internal/middleware"
+	"openvdo/internal/moderation"
+	"openvdo/internal/openapi"
+	"openvdo/internal/outbox"
+	"openvdo/internal/ratelimit"
+	"openvdo/internal/regions"
+	"openvdo/internal/routes"
+	"openvdo/internal/scheduler"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/internal/transcode"
+	"openvdo/internal/usage"
+	"openvdo/migrations"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// App is a fully wired OpenVDO instance
+type App struct {
+	Config        *config.Config
+	Pools         *database.StatelessPoolManager
+	Regions       *regions.Router
+	Storage       storage.Storage
+	Lifecycle     *services.LifecycleManager
+	Hasher        *services.ContentHasher
+	Jobs          *jobs.Queue
+	Leader        *leader.Elector
+	Scheduler     *scheduler.Scheduler
+	Importer      *services.Importer
+	Bulk          *services.BulkImporter
+	Purger        *services.CachePurger
+	Feeds         *services.FeedGenerator
+	Playlists     *services.SmartPlaylists
+	Teardowns     *services.OrganizationTeardowns
+	Prober        *services.MediaProber
+	Downloads     *services.Downloader
+	Images        *images.Processor
+	Notifications *services.NotificationDispatcher
+	Push          *services.PushNotifier
+	Webhooks      *services.WebhookDispatcher
+	Outbox        *outbox.Relay
+	Flags         *flags.Store
+	Maintenance   *maintenance.Mode
+	Analytics     analytics.Store
+	Beacon        *analytics.Ingester
+	Usage         *usage.Recorder
+	Moderator     *moderation.Moderator
+	Scanner       *moderation.Scanner
+	Transcode     *transcode.Manager
+	Checks        *health.Registry
+	Drain         *health.Drain
+	AccessLog     *accesslog.Logger
+	Errors        *errortracking.Tracker
+	Recorder      *flightrecorder.Recorder
+	Sessions      *auth.Sessions
+	IPAccess      *ipaccess.Guard
+	// Contract is the OpenAPI document responses are checked against, when CONTRACT_CHECK is on
+	Contract *openapi.Contract
+	Faults   *faults.Injector
+	Router   *gin.Engine
+	// AdminRouter and MetricsRouter are set when ADMIN_LISTEN_ADDR and METRICS_LISTEN_ADDR give
+	// the admin API and the metrics listeners of their own
+	AdminRouter   *gin.Engine
+	MetricsRouter *gin.Engine
+}
+
+// New connects to the database and storage and builds the services and router.
+// Background work only begins with Start and StartWorkers.
+func New(cfg *config.Config) (*App, error) {
+	if cfg.Server.Pprof && cfg.Server.MetricsAddr == "" {
+		return nil, fmt.Errorf("PPROF_ENABLED needs METRICS_LISTEN_ADDR, as the profiler is not served with the public API")
+	}
+	accessLog, err := accesslog.New(cfg.AccessLog)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize access log: %w", err)
+	}
+	errorTracker, err := errortracking.New(cfg.Errors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize error tracking: %w", err)
+	}
+	var contract *openapi.Contract
+	if cfg.Contract.Check != middleware.ContractCheckOff {
+		doc, err := openapi.Document()
+		if err == nil {
+			contract, err = openapi.NewContract(doc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the API contract: %w", err)
+		}
+	}
+
+	// Redis commands fail from the first one on when fault injection drops them
+	injector := faults.New(cfg.Faults)
+	redisClient := database.ConnectRedis(cfg.Redis)
+	if injector.Enabled() {
+		redisClient.AddHook(injector.RedisHook())
+	}
+	limiter, err := ratelimit.New(redisClient, cfg.Routes.RateLimits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize rate limits: %w", err)
+	}
+	pools, err := database.NewStatelessPoolManager(cfg.Database, redisClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize stateless pool manager: %w", err)
+	}
+
+	localStore, err := storage.New(cfg.Storage)
+	if err != nil {
+		pools.Close()
+		return nil, fmt.Errorf("failed to initialize storage backend: %w", err)
+	}
+
+	// Objects of organizations pinned to other regions go to those regions' storage
+	regionRouter, err := regions.NewRouter(cfg.Regions, pools, localStore, pools.GetRedisClient())
+	if err != nil {
+		pools.Close()
+		return nil, fmt.Errorf("failed to initialize regions: %w", err)
+	}
+	store := regionRouter.Storage()
+
+	masterDB := pools.GetMasterConnection()
+	analyticsStore, err := newAnalyticsStore(cfg, masterDB)
+	if err != nil {
+		regionRouter.Close()
+		pools.Close()
+		return nil, err
+	}
+
+	elector := leader.NewElector(masterDB, cfg.Leader)
+	a := &App{
+		Config:      cfg,
+		Pools:       pools,
+		Regions:     regionRouter,
+		Storage:     store,
+		Lifecycle:   services.NewLifecycleManager(masterDB, store, cfg.Storage),
+		Hasher:      services.NewContentHasher(masterDB, store, cfg.Storage),
+		Jobs:        jobs.NewQueue(masterDB, cfg.Jobs, elector),
+		Leader:      elector,
+		Scheduler:   scheduler.New(masterDB, cfg.Scheduler, elector),
+		Images:      images.NewProcessor(cfg.Images),
+		Flags:       flags.NewStore(masterDB, pools.GetRedisClient(), cfg.Flags.CacheTTL),
+		Maintenance: maintenance.NewMode(masterDB, cfg.Maintenance),
+		Analytics:   analyticsStore,
+		Beacon:      analytics.NewIngester(analyticsStore, cfg.Beacon),
+		Usage:       usage.NewRecorder(masterDB, pools.GetRedisClient(), cfg.Usage),
+		Moderator:   moderation.NewModerator(masterDB, cfg.Moderation),
+		Checks:      health.NewRegistry(cfg.Health.Interval, cfg.Health.Timeout),
+		Drain:       health.NewDrain(cfg.Server.DrainDelay, cfg.Server.StreamDrainGrace),
+		AccessLog:   accessLog,
+		Errors:      errorTracker,
+		Recorder:    flightrecorder.New(cfg.Recorder),
+		Sessions:    auth.NewSessions(masterDB, pools.GetRedisClient(), cfg.Auth),
+		IPAccess:    ipaccess.NewGuard(masterDB, pools.GetRedisClient(), cfg.IPAccess.CacheTTL),
+		Contract:    contract,
+		Faults:      injector,
+		Router:      gin.New(),
+	}
+
+	a.Importer = services.NewImporter(masterDB, store, a.Hasher, cfg.Import, cfg.Storage.MaxUploadSize)
+	a.Bulk = services.NewBulkImporter(masterDB, a.Importer, cfg.Import)
+	a.Jobs.Register(services.JobKindVideoImport, a.Importer.Handle)
+	a.Jobs.Register(services.JobKindBulkImport, a.Bulk.Handle)
+	a.Purger = services.NewCachePurger(cfg.CDN, cfg.Playback.PublicURL)
+	a.Jobs.Register(services.JobKindCDNPurge, a.Purger.Handle)
+
+	a.Notifications = services.NewNotificationDispatcher(masterDB)
+	emailNotifier, err := services.NewEmailNotifier(cfg.Email)
+	if err != nil {
+		regionRouter.Close()
+		pools.Close()
+		return nil, err
+	}
+	if emailNotifier != nil {
+		a.Notifications.Register(emailNotifier)
+	}
+	pushProviders, err := services.NewPushProviders(cfg.Push)
+	if err != nil {
+		regionRouter.Close()
+		pools.Close()
+		return nil, err
+	}
+	a.Push = services.NewPushNotifier(masterDB, cfg.Push.TTL, pushProviders...)
+	if len(pushProviders) > 0 {
+		a.Notifications.Register(a.Push)
+	}
+	a.Jobs.Register(services.JobKindNotificationDeliver, a.Notifications.Handle)
+
+	// Webhook and scan jobs are queued in the relay's transaction, so they go before publishers
+	// that send elsewhere
+	a.Webhooks = services.NewWebhookDispatcher(masterDB, cfg.Events)
+	a.Jobs.Register(services.JobKindWebhookDeliver, a.Webhooks.Handle)
+	a.Outbox = outbox.NewRelay(masterDB, cfg.Events, a.Leader)
+	a.Outbox.Register(a.Webhooks)
+	a.Feeds = services.NewFeedGenerator(masterDB, cfg.Feeds, cfg.Playback.PublicURL, a.Purger)
+	a.Jobs.Register(services.JobKindFeedGenerate, a.Feeds.Handle)
+	a.Outbox.Register(a.Feeds)
+	a.Playlists = services.NewSmartPlaylists(masterDB, cfg.Feeds, cfg.Playback.PublicURL)
+	a.Teardowns = services.NewOrganizationTeardowns(masterDB, store, analyticsStore)
+	a.Jobs.Register(services.JobKindOrganizationTeardown, a.Teardowns.Handle)
+	classifier, err := moderation.NewClassifier(cfg.Moderation)
+	if err != nil {
+		regionRouter.Close()
+		pools.Close()
+		return nil, err
+	}
+	if classifier != nil {
+		if a.Scanner, err = moderation.NewScanner(a.Moderator, store, classifier, cfg.Moderation); err != nil {
+			regionRouter.Close()
+			pools.Close()
+			return nil, err
+		}
+		a.Jobs.Register(moderation.JobKindVideoScan, a.Scanner.Handle)
+		a.Outbox.Register(a.Scanner)
+	}
+	transcoders, err := transcode.NewTranscoders(cfg.Transcode)
+	if err != nil {
+		regionRouter.Close()
+		pools.Close()
+		return nil, err
+	}
+	profiles, err := transcode.ParseProfiles(cfg.Transcode)
+	if err != nil {
+		regionRouter.Close()
+		pools.Close()
+		return nil, err
+	}
+	if a.Prober, err = services.NewMediaProber(masterDB, store, cfg.Probe); err != nil {
+		logger.Info("Sources are not probed, so HDR sources are transcoded as SDR: %v", err)
+		a.Prober = nil
+	} else {
+		a.Jobs.Register(services.JobKindVideoProbe, a.Prober.Handle)
+		a.Outbox.Register(a.Prober)
+	}
+	if a.Downloads, err = services.NewDownloader(masterDB, store, cfg.Downloads); err != nil {
+		logger.Info("Video downloads disabled: %v", err)
+		a.Downloads = nil
+	} else {
+		a.Jobs.Register(services.JobKindVideoDownload, a.Downloads.Handle)
+	}
+	a.Transcode = transcode.NewManager(masterDB, store, transcoders, profiles, a.Prober, cfg.Transcode)
+	a.Jobs.Register(transcode.JobKindTranscode, a.Transcode.Handle)
+	a.Outbox.Register(a.Transcode)
+	if redisClient := pools.GetRedisClient(); redisClient != nil && cfg.Events.RedisChannel != "" {
+		a.Outbox.Register(outbox.NewRedisPublisher(redisClient, cfg.Events.RedisChannel))
+	}
+	a.Outbox.Register(outbox.NewCountInvalidator(pools.Counts()))
+	a.registerTasks(masterDB)
+
+	// Readiness checks, refreshed in the background and served from cache by /readyz
+	a.Checks.Register("database", 0, health.Database(masterDB))
+	a.Checks.Register("migrations", 0, health.Migrations(masterDB, migrations.LatestVersion()))
+	a.Checks.Register("jobs", 0, health.JobQueue(masterDB))
+	if redisClient := pools.GetRedisClient(); redisClient != nil {
+		a.Checks.Register("redis", 0, health.Redis(redisClient))
+	}
+	a.Checks.Register("storage", cfg.Health.StorageTimeout, health.Storage(localStore))
+	for name, regionalStore := range regionRouter.Stores() {
+		regionalPool, _ := regionRouter.Pool(name)
+		a.Checks.Register("database:"+name, 0, health.Database(regionalPool.GetMasterConnection()))
+		a.Checks.Register("storage:"+name, cfg.Health.StorageTimeout, health.Storage(regionalStore))
+	}
+
+	if cfg.Server.AdminAddr != "" {
+		a.AdminRouter = gin.New()
+	}
+	if cfg.Server.MetricsAddr != "" {
+		a.MetricsRouter = gin.New()
+	}
+	routes.Setup(a.Router, routes.Dependencies{
+		Config:        cfg,
+		PoolManager:   pools,
+		Regions:       regionRouter,
+		Storage:       store,
+		Lifecycle:     a.Lifecycle,
+		Hasher:        a.Hasher,
+		Jobs:          a.Jobs,
+		Scheduler:     a.Scheduler,
+		Importer:      a.Importer,
+		BulkImports:   a.Bulk,
+		Purger:        a.Purger,
+		Feeds:         a.Feeds,
+		Playlists:     a.Playlists,
+		Teardowns:     a.Teardowns,
+		Downloads:     a.Downloads,
+		Images:        a.Images,
+		Push:          a.Push,
+		Flags:         a.Flags,
+		Maintenance:   a.Maintenance,
+		Beacon:        a.Beacon,
+		Analytics:     a.Analytics,
+		Usage:         a.Usage,
+		Moderator:     a.Moderator,
+		Scanner:       a.Scanner,
+		Transcode:     a.Transcode,
+		Checks:        a.Checks,
+		Drain:         a.Drain,
+		AccessLog:     a.AccessLog,
+		Errors:        a.Errors,
+		Recorder:      a.Recorder,
+		Sessions:      a.Sessions,
+		IPAccess:      a.IPAccess,
+		Contract:      a.Contract,
+		Faults:        a.Faults,
+		Policies:      routes.DefaultPolicies(cfg),
+		Limiter:       limiter,
+		AdminRouter:   a.AdminRouter,
+		MetricsRouter: a.MetricsRouter,
+	})
+
+	return a, nil
+}
+
+// newAnalyticsStore returns the ClickHouse store when CLICKHOUSE_URL is set, creating its
+// table, and the Postgres one otherwise
+func newAnalyticsStore(cfg *config.Config, db *sql.DB) (analytics.Store, error) {
+	if cfg.ClickHouse.URL == "" {
+		return analytics.NewPostgresStore(db), nil
+	}
+
+	store, err := analytics.NewClickHouseStore(cfg.ClickHouse)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ClickHouse.Timeout)
+	defer cancel()
+	if err := store.EnsureSchema(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create ClickHouse analytics table: %w", err)
+	}
+	logger.Info("Playback analytics are stored in ClickHouse")
+	return store, nil
+}
+
+// Start begins the background readiness checks, the polling of the maintenance mode, the
+// listening for IP access rule changes, the writing of beacon events, the flushing of API
+// usage counters, the shipping of access logs and the reporting of errors
+func (a *App) Start() {
+	a.Checks.Start()
+	a.Maintenance.Start()
+	a.IPAccess.Start()
+	a.Beacon.Start()
+	a.Usage.Start()
+	a.AccessLog.Start()
+	a.Errors.Start()
+	if a.Errors.Enabled() {
+		logger.SetHook(a.Errors.LogHook())
+	}
+}
+
+// StartWorkers begins the campaign for leadership, the scheduled maintenance tasks, the scan
+// for unhashed uploads, the job queue workers and the outbox relay
+func (a *App) StartWorkers() {
+	a.Leader.Start()
+	a.Scheduler.Start()
+	a.Hasher.Start()
+	a.Jobs.Start()
+	a.Outbox.Start()
+}
+
+// Close stops background work and releases the database and Redis connections
+func (a *App) Close() error {
+	a.Checks.Stop()
+	a.Maintenance.Stop()
+	a.IPAccess.Stop()
+	// Writes the beacon events still buffered
+	a.Beacon.Stop()
+	a.Usage.Stop()
+	// Ships the access log entries still buffered
+	a.AccessLog.Stop()
+	// Runs interrupted by the shutdown are due again for the next leader
+	a.Scheduler.Stop()
+	// Events claimed by the relay are published again by the next one
+	a.Outbox.Stop()
+	// Interrupted jobs go back to the queue for another worker
+	a.Jobs.Stop()
+	// Another worker process takes over the periodic routines
+	a.Leader.Stop()
+	// Waits for hashes of uploads completed through the API, which run even without workers
+	a.Hasher.Stop()
+
+	err := errors.Join(a.Regions.Close(), a.Pools.Close())
+	// Sends the errors of the shutdown as well
+	if a.Errors.Enabled() {
+		logger.SetHook(nil)
+	}
+	a.Errors.Stop()
+	logger.Info("Application stopped")
+	return err
+}
@@ -0,0 +1,51 @@
+//go:build integration
+
+package app_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// BenchmarkOrganizationsList serves GET /api/v1/organizations in process, each request taking a
+// tenant connection and running the generated list query as the API does, with and without the
+// prepared statement cache. Run it with make bench-db against the test database.
+func BenchmarkOrganizationsList(b *testing.B) {
+	for _, size := range []int{0, 100} {
+		b.Run(fmt.Sprintf("statement_cache=%d", size), func(b *testing.B) {
+			cfg := testConfig(b)
+			if cfg.Database.PgBouncerMode {
+				b.Skip("the statement cache is off in PgBouncer mode")
+			}
+			cfg.Database.StatementCacheSize = size
+			a := startApp(b, cfg)
+			f := seedMember(b, a.Pools.GetMasterConnection())
+
+			request := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/organizations?limit=10", nil)
+				req.Header.Set("X-User-ID", f.UserID.String())
+				req.Header.Set("X-Org-ID", f.OrgID.String())
+				w := httptest.NewRecorder()
+				a.Router.ServeHTTP(w, req)
+				return w
+			}
+			// The first request prepares the statements and fills the count cache
+			if w := request(); w.Code != http.StatusOK {
+				b.Fatalf("listing organizations: %d %s", w.Code, w.Body)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if w := request(); w.Code != http.StatusOK {
+						b.Errorf("listing organizations: %d %s", w.Code, w.Body)
+						return
+					}
+				}
+			})
+		})
+	}
+}
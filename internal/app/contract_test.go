@@ -0,0 +1,279 @@
+//go:build integration
+
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"openvdo/internal/openapi"
+
+	"github.com/google/uuid"
+)
+
+// methodOrder runs reads before writes and deletions last, so the fixtures exist for as many
+// operations as possible
+var methodOrder = map[string]int{"GET": 0, "HEAD": 1, "POST": 2, "PUT": 3, "PATCH": 4, "DELETE": 5}
+
+// runLast are operations that take the fixtures or the instance away from the operations after them
+var runLast = map[string]bool{
+	"POST /api/v1/organizations/{id}/teardown": true,
+	"POST /api/v1/organizations/{id}/deletion": true,
+	"PUT /admin/v1/maintenance":                true,
+}
+
+// operation is a documented method and path, with its OpenAPI 3.1 description
+type operation struct {
+	method, path string
+	spec         map[string]interface{}
+}
+
+// TestResponsesMatchContract drives every documented operation through the router with the
+// fixtures of seedMember and checks each response against the OpenAPI document. Operations get
+// the fixtures' IDs where their path names videos, organizations or users, unknown IDs elsewhere,
+// and bodies holding the required fields of their schemas, so both the success and the error
+// responses of the API are checked.
+func TestResponsesMatchContract(t *testing.T) {
+	cfg := testConfig(t)
+	a := startApp(t, cfg)
+	f := seedMember(t, a.Pools.GetMasterConnection())
+
+	doc, err := openapi.Document()
+	if err != nil {
+		t.Fatal(err)
+	}
+	contract, err := openapi.NewContract(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(doc, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	samples := sampler{schemas: parsed.Components.Schemas}
+
+	routed := map[string]bool{}
+	for _, route := range a.Router.Routes() {
+		routed[route.Method+" "+templatePath(route.Path)] = true
+	}
+
+	var operations []operation
+	for path, item := range parsed.Paths {
+		for method, spec := range item {
+			operations = append(operations, operation{method: strings.ToUpper(method), path: path, spec: spec})
+		}
+	}
+	sort.Slice(operations, func(i, j int) bool {
+		oi, oj := operations[i], operations[j]
+		if li, lj := runLast[oi.method+" "+oi.path], runLast[oj.method+" "+oj.path]; li != lj {
+			return lj
+		}
+		if methodOrder[oi.method] != methodOrder[oj.method] {
+			return methodOrder[oi.method] < methodOrder[oj.method]
+		}
+		return oi.path < oj.path
+	})
+
+	for _, op := range operations {
+		t.Run(op.method+" "+op.path, func(t *testing.T) {
+			if !routed[op.method+" "+op.path] {
+				t.Fatal("the operation is documented but no route serves it")
+			}
+
+			req := buildRequest(t, op, f, samples)
+			switch {
+			case hasSecurity(op.spec, "AdminToken"):
+				req.Header.Set("Authorization", "Bearer "+cfg.Admin.APIToken)
+			case hasSecurity(op.spec, "ApiKeyAuth"):
+				req.Header.Set("X-User-ID", f.UserID.String())
+				req.Header.Set("X-Org-ID", f.OrgID.String())
+			}
+			w := httptest.NewRecorder()
+			a.Router.ServeHTTP(w, req)
+
+			body := w.Body.Bytes()
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+				body = nil
+			}
+			for _, v := range contract.CheckResponse(op.method, op.path, w.Code, body) {
+				t.Errorf("answered %d: %s", w.Code, v)
+			}
+		})
+	}
+}
+
+// templatePath rewrites a Gin route into the path templates of the document
+func templatePath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func hasSecurity(spec map[string]interface{}, scheme string) bool {
+	requirements, _ := spec["security"].([]interface{})
+	for _, r := range requirements {
+		if _, ok := r.(map[string]interface{})[scheme]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// buildRequest fills in the path and required query parameters of the operation and sends a
+// body of the required fields of its schema
+func buildRequest(t *testing.T, op operation, f fixtures, samples sampler) *http.Request {
+	t.Helper()
+	path := op.path
+	query := url.Values{}
+	parameters, _ := op.spec["parameters"].([]interface{})
+	for _, p := range parameters {
+		param, _ := p.(map[string]interface{})
+		name, _ := param["name"].(string)
+		schema, _ := param["schema"].(map[string]interface{})
+		switch param["in"] {
+		case "path":
+			path = strings.Replace(path, "{"+name+"}", pathValue(op.path, name, schema, f, samples), 1)
+		case "query":
+			if required, _ := param["required"].(bool); required {
+				query.Set(name, stringValue(samples.value(schema, 0)))
+			}
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var body []byte
+	contentType := ""
+	if requestBody, ok := op.spec["requestBody"].(map[string]interface{}); ok {
+		content, _ := requestBody["content"].(map[string]interface{})
+		if media, ok := content["application/json"].(map[string]interface{}); ok {
+			schema, _ := media["schema"].(map[string]interface{})
+			var err error
+			if body, err = json.Marshal(samples.value(schema, 0)); err != nil {
+				t.Fatal(err)
+			}
+			contentType = "application/json"
+		} else {
+			for mediaType := range content {
+				contentType = mediaType
+				break
+			}
+		}
+	}
+
+	req := httptest.NewRequest(op.method, path, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+// pathValue picks the fixture a path parameter names, or an ID nothing has
+func pathValue(path, name string, schema map[string]interface{}, f fixtures, samples sampler) string {
+	if name == "id" {
+		switch {
+		case strings.Contains(path, "/videos/{id}"), strings.HasPrefix(path, "/embed/{id}"):
+			return f.VideoID.String()
+		case strings.Contains(path, "/organizations/{id}"):
+			return f.OrgID.String()
+		case strings.Contains(path, "/users/{id}"):
+			return f.UserID.String()
+		}
+	}
+	if name == "org_id" {
+		return f.OrgID.String()
+	}
+	if format, _ := schema["format"].(string); format == "uuid" || name == "id" || strings.HasSuffix(name, "_id") {
+		return uuid.NewString()
+	}
+	return stringValue(samples.value(schema, 0))
+}
+
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case nil:
+		return "contract"
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// sampler builds values of the schemas of a document from their examples, enums and types
+type sampler struct {
+	schemas map[string]map[string]interface{}
+}
+
+func (s sampler) value(schema map[string]interface{}, depth int) interface{} {
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth > 8 {
+			return nil
+		}
+		return s.value(s.schemas[strings.TrimPrefix(ref, "#/components/schemas/")], depth+1)
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok && len(allOf) > 0 {
+		first, _ := allOf[0].(map[string]interface{})
+		return s.value(first, depth+1)
+	}
+	if example, ok := schema["example"]; ok {
+		return example
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	if def, ok := schema["default"]; ok {
+		return def
+	}
+
+	typ, _ := schema["type"].(string)
+	if types, ok := schema["type"].([]interface{}); ok && len(types) > 0 {
+		typ, _ = types[0].(string)
+	}
+	switch typ {
+	case "object":
+		value := map[string]interface{}{}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			property, _ := properties[name.(string)].(map[string]interface{})
+			value[name.(string)] = s.value(property, depth+1)
+		}
+		return value
+	case "array":
+		return []interface{}{}
+	case "integer", "number":
+		return 1
+	case "boolean":
+		return false
+	}
+	switch schema["format"] {
+	case "uuid":
+		return uuid.NewString()
+	case "date-time":
+		return time.Now().UTC().Format(time.RFC3339)
+	case "email":
+		return "contract@example.com"
+	case "uri":
+		return "https://example.com/contract"
+	}
+	return "contract"
+}
@@ -0,0 +1,160 @@
+//go:build integration
+
+package app_test
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"openvdo/internal/app"
+	"openvdo/internal/config"
+	"openvdo/internal/middleware"
+	"openvdo/internal/models"
+	"openvdo/migrations"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// The integration tests run the whole application in process against the database of
+// OPENVDO_TEST_DATABASE_URL, such as postgres://postgres@localhost:5432/openvdo_test?sslmode=disable,
+// and the Redis of REDIS_HOST and REDIS_PORT. They migrate the database and add their own rows to
+// it, so point them at a scratch database. Without the URL they are skipped.
+const databaseURLEnv = "OPENVDO_TEST_DATABASE_URL"
+
+// testPassword is the password of the users seedMember creates
+const testPassword = "integration-test-password"
+
+var migrateOnce = sync.OnceValue(func() error {
+	db, err := sql.Open("postgres", os.Getenv(databaseURLEnv))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return err
+	}
+	driver, err := postgres.WithInstance(db, &postgres.Config{})
+	if err != nil {
+		return err
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return err
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+})
+
+// testConfig returns the configuration of an instance over the test database, migrated to the
+// latest version, or skips the test when there is none
+func testConfig(t testing.TB) *config.Config {
+	t.Helper()
+	url := os.Getenv(databaseURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", databaseURLEnv)
+	}
+	if err := migrateOnce(); err != nil {
+		t.Fatalf("failed to migrate the test database: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	cfg := config.Load()
+	cfg.Database.PrimaryDSNs = []string{url}
+	cfg.Storage.Backend = "local"
+	cfg.Storage.LocalPath = t.TempDir()
+	cfg.Admin.APIToken = "integration-test-admin-token"
+	cfg.Playback.SigningKey = "integration-test-signing-key"
+	// The admin API and metrics are served by the router under test
+	cfg.Server.AdminAddr = ""
+	cfg.Server.MetricsAddr = ""
+	cfg.Server.Pprof = false
+	cfg.Contract.Check = middleware.ContractCheckOff
+	return cfg
+}
+
+// startApp builds an instance with its background checks running, closed with the test
+func startApp(t testing.TB, cfg *config.Config) *app.App {
+	t.Helper()
+	a, err := app.New(cfg)
+	if err != nil {
+		t.Fatalf("failed to start the application: %v", err)
+	}
+	a.Start()
+	t.Cleanup(func() {
+		if err := a.Close(); err != nil {
+			t.Errorf("failed to stop the application: %v", err)
+		}
+	})
+	return a
+}
+
+// fixtures are the rows seedMember adds
+type fixtures struct {
+	UserID  uuid.UUID
+	Email   string
+	OrgID   uuid.UUID
+	VideoID uuid.UUID
+}
+
+// seedMember adds a user owning a new organization with one ready video, signing in with
+// testPassword
+func seedMember(t testing.TB, db *sql.DB) fixtures {
+	t.Helper()
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	f := fixtures{Email: "integration-" + hex.EncodeToString(suffix) + "@example.com"}
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	steps := []struct {
+		query string
+		args  []interface{}
+		into  *uuid.UUID
+	}{
+		{`INSERT INTO users (email, password_hash, name, email_verified)
+			VALUES ($1, crypt($2, gen_salt('bf')), 'Integration Test', TRUE) RETURNING id`,
+			[]interface{}{f.Email, testPassword}, &f.UserID},
+		{`INSERT INTO organizations (name) VALUES ($1) RETURNING id`,
+			[]interface{}{"Integration " + hex.EncodeToString(suffix)}, &f.OrgID},
+	}
+	for _, step := range steps {
+		if err := tx.QueryRowContext(ctx, step.query, step.args...).Scan(step.into); err != nil {
+			t.Fatalf("failed to seed the test database: %v", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO user_org_roles (user_id, organization_id, role) VALUES ($1, $2, $3)`,
+		f.UserID, f.OrgID, models.RoleOwner); err != nil {
+		t.Fatalf("failed to seed the test database: %v", err)
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO videos (organization_id, title, status, source_key, created_by)
+		VALUES ($1, 'Integration test video', $2, 'integration/source.mp4', $3) RETURNING id
+	`, f.OrgID, models.VideoStatusReady, f.UserID).Scan(&f.VideoID)
+	if err != nil {
+		t.Fatalf("failed to seed the test database: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
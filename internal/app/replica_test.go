@@ -0,0 +1,244 @@
+//go:build integration
+
+package app_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The replica tests check that OpenVDO behaves as one service when requests are spread over
+// several instances. Two instances run in process over the same Postgres database and Redis,
+// and every step of a scenario goes to the other instance than the step before. A scenario
+// that fails here but passes against a single instance points at state kept in process memory
+// instead of in Postgres or Redis.
+
+// replicas are instances sharing their database, Redis and storage
+type replicas struct {
+	urls []string
+	f    fixtures
+	// loginMaxFailures is the instances' AUTH_LOGIN_MAX_FAILURES
+	loginMaxFailures int
+}
+
+func startReplicas(t *testing.T) *replicas {
+	t.Helper()
+	cfg := testConfig(t)
+	r := &replicas{loginMaxFailures: cfg.Auth.LoginMaxFailures}
+	for i := 0; i < 2; i++ {
+		instance := *cfg
+		a := startApp(t, &instance)
+		server := httptest.NewServer(a.Router)
+		t.Cleanup(server.Close)
+		r.urls = append(r.urls, server.URL)
+		if i == 0 {
+			r.f = seedMember(t, a.Pools.GetMasterConnection())
+		}
+	}
+	return r
+}
+
+// client sends the steps of one scenario to the instances in turn
+type client struct {
+	t    *testing.T
+	r    *replicas
+	next int
+}
+
+func (r *replicas) client(t *testing.T) *client {
+	return &client{t: t, r: r}
+}
+
+// request configures a step. Steps act as the seeded user unless they carry a bearer token.
+type request struct {
+	method, path string
+	body         interface{}
+	bearer       string
+	// anonymous steps carry neither the user nor a token
+	anonymous bool
+}
+
+// response is an answered step
+type response struct {
+	status int
+	body   []byte
+}
+
+// data decodes the data of a success response
+func (r *response) data(t *testing.T, out interface{}) {
+	t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(r.body, &envelope); err != nil {
+		t.Fatalf("failed to decode %s: %v", r.body, err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		t.Fatalf("failed to decode %s: %v", envelope.Data, err)
+	}
+}
+
+// do sends a step to the next instance and returns its response, whatever its status
+func (c *client) do(r request) *response {
+	c.t.Helper()
+	instance := c.next
+	c.next = (c.next + 1) % len(c.r.urls)
+
+	var body io.Reader
+	if r.body != nil {
+		payload, err := json.Marshal(r.body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(r.method, c.r.urls[instance]+r.path, body)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case r.bearer != "":
+		req.Header.Set("Authorization", "Bearer "+r.bearer)
+	case !r.anonymous:
+		req.Header.Set("X-User-ID", c.r.f.UserID.String())
+		req.Header.Set("X-Org-ID", c.r.f.OrgID.String())
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s on instance %d: %v", r.method, r.path, instance, err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return &response{status: resp.StatusCode, body: respBody}
+}
+
+// expect sends a step and fails the test unless it got one of the expected statuses
+func (c *client) expect(r request, expected ...int) *response {
+	c.t.Helper()
+	instance := c.next
+	resp := c.do(r)
+	for _, status := range expected {
+		if resp.status == status {
+			return resp
+		}
+	}
+	c.t.Fatalf("%s %s on instance %d: expected %v, got %d: %s", r.method, r.path, instance, expected,
+		resp.status, strings.TrimSpace(string(resp.body)))
+	return nil
+}
+
+// TestReplicaSeries creates a series on one instance and reads, updates and deletes it on the
+// other. A stale version must be refused wherever the update lands.
+func TestReplicaSeries(t *testing.T) {
+	c := startReplicas(t).client(t)
+
+	var series struct {
+		ID      string `json:"id"`
+		Version int64  `json:"version"`
+	}
+	c.expect(request{method: http.MethodPost, path: "/api/v1/series",
+		body: map[string]string{"title": "replica series " + randomSuffix()}}, http.StatusCreated).data(t, &series)
+	path := "/api/v1/series/" + series.ID
+	// The series goes away even when a later step fails
+	t.Cleanup(func() { c.do(request{method: http.MethodDelete, path: path}) })
+
+	c.expect(request{method: http.MethodGet, path: path}, http.StatusOK)
+	title := "replica series renamed"
+	c.expect(request{method: http.MethodPatch, path: path,
+		body: map[string]interface{}{"version": series.Version, "title": title}}, http.StatusOK)
+	c.expect(request{method: http.MethodPatch, path: path,
+		body: map[string]interface{}{"version": series.Version, "title": "replica series stale"}}, http.StatusConflict)
+
+	var current struct {
+		Title string `json:"title"`
+	}
+	c.expect(request{method: http.MethodGet, path: path}, http.StatusOK).data(t, &current)
+	if current.Title != title {
+		t.Errorf("read %q after renaming the series to %q", current.Title, title)
+	}
+	c.expect(request{method: http.MethodDelete, path: path}, http.StatusOK)
+	c.expect(request{method: http.MethodGet, path: path}, http.StatusNotFound)
+}
+
+// TestReplicaSignIn signs in on one instance, uses the session on the other and revokes it on
+// the next step; the revoked token must be refused right away wherever it lands
+func TestReplicaSignIn(t *testing.T) {
+	r := startReplicas(t)
+	c := r.client(t)
+
+	var signIn struct {
+		Token   string `json:"token"`
+		Session struct {
+			ID string `json:"id"`
+		} `json:"session"`
+	}
+	c.expect(request{method: http.MethodPost, path: "/api/v1/auth/login", anonymous: true,
+		body: map[string]string{"email": r.f.Email, "password": testPassword}}, http.StatusCreated).data(t, &signIn)
+
+	c.expect(request{method: http.MethodGet, path: "/api/v1/me/sessions", bearer: signIn.Token}, http.StatusOK)
+	c.expect(request{method: http.MethodDelete, path: "/api/v1/me/sessions/" + signIn.Session.ID,
+		bearer: signIn.Token}, http.StatusOK)
+	for range r.urls {
+		c.expect(request{method: http.MethodGet, path: "/api/v1/me/sessions", bearer: signIn.Token},
+			http.StatusUnauthorized)
+	}
+}
+
+// TestReplicaThrottling fails sign-ins to an unknown account on the instances in turn; the
+// account must be locked once the failures add up to the limit, not the limit per instance
+func TestReplicaThrottling(t *testing.T) {
+	r := startReplicas(t)
+	if r.loginMaxFailures <= 0 {
+		t.Skip("sign-ins are not throttled")
+	}
+	c := r.client(t)
+
+	attempt := request{method: http.MethodPost, path: "/api/v1/auth/login", anonymous: true,
+		body: map[string]string{"email": "replica-" + randomSuffix() + "@example.invalid", "password": "wrong password"}}
+	for i := 0; i < r.loginMaxFailures; i++ {
+		c.expect(attempt, http.StatusUnauthorized)
+	}
+	c.expect(attempt, http.StatusTooManyRequests)
+}
+
+// TestReplicaPlaybackSession starts a playback session on one instance, keeps it alive on the
+// other and ends it on the next step; heartbeats after that must be refused wherever they land
+func TestReplicaPlaybackSession(t *testing.T) {
+	r := startReplicas(t)
+	c := r.client(t)
+
+	var started struct {
+		Session struct {
+			ID string `json:"id"`
+		} `json:"session"`
+	}
+	c.expect(request{method: http.MethodPost, path: "/api/v1/videos/" + r.f.VideoID.String() + "/playback-sessions"},
+		http.StatusCreated).data(t, &started)
+	path := "/api/v1/playback-sessions/" + started.Session.ID
+	t.Cleanup(func() { c.do(request{method: http.MethodDelete, path: path}) })
+
+	c.expect(request{method: http.MethodPost, path: path + "/heartbeat"}, http.StatusOK)
+	c.expect(request{method: http.MethodDelete, path: path}, http.StatusOK)
+	for range r.urls {
+		c.expect(request{method: http.MethodPost, path: path + "/heartbeat"}, http.StatusGone)
+	}
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"openvdo/internal/jobs"
+	"openvdo/internal/scheduler"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+)
+
+// registerTasks adds the recurring maintenance tasks to the scheduler
+func (a *App) registerTasks(db *sql.DB) {
+	a.Scheduler.Register(scheduler.Task{
+		Name:        "session-cleanup",
+		Description: "Deletes expired sign-in sessions",
+		Schedule:    "@hourly",
+		Run: func(ctx context.Context) (string, error) {
+			n, err := a.Sessions.DeleteExpired(ctx)
+			return fmt.Sprintf("deleted %d sessions", n), err
+		},
+	})
+
+	a.Scheduler.Register(scheduler.Task{
+		Name:        "trending",
+		Description: "Recomputes the trending scores of videos from their plays",
+		Schedule:    "*/15 * * * *",
+		Run: func(ctx context.Context) (string, error) {
+			n, err := services.RecomputeTrending(ctx, db, a.Analytics)
+			return fmt.Sprintf("scored %d videos", n), err
+		},
+	})
+
+	a.Scheduler.Register(scheduler.Task{
+		Name:        "download-cleanup",
+		Description: "Deletes expired video downloads and their files",
+		Schedule:    "@hourly",
+		Run: func(ctx context.Context) (string, error) {
+			n, err := services.DeleteExpiredDownloads(ctx, db, a.Storage)
+			return fmt.Sprintf("deleted %d downloads", n), err
+		},
+	})
+
+	a.Scheduler.Register(scheduler.Task{
+		Name:        "source-version-cleanup",
+		Description: "Deletes replaced video sources past their retention and their renditions",
+		Schedule:    "@hourly",
+		Run: func(ctx context.Context) (string, error) {
+			n, err := services.DeleteExpiredSourceVersions(ctx, db, a.Storage, a.Config.Storage.SourceVersionsMax)
+			return fmt.Sprintf("deleted %d source versions", n), err
+		},
+	})
+
+	if a.Lifecycle.Enabled() && a.Config.Storage.LifecycleInterval > 0 {
+		a.Scheduler.Register(scheduler.Task{
+			Name:        "storage-lifecycle",
+			Description: "Archives inactive objects to cold storage and tracks restores",
+			Schedule:    "@every " + a.Config.Storage.LifecycleInterval.String(),
+			Run: func(ctx context.Context) (string, error) {
+				return "", a.Lifecycle.RunOnce(ctx)
+			},
+		})
+	} else {
+		logger.Info("Storage lifecycle disabled: backend does not support archival")
+	}
+
+	// Endpoints that were down for a while get the deliveries they missed, a few times at most
+	a.Scheduler.Register(scheduler.Task{
+		Name:        "webhook-retry-sweep",
+		Description: "Retries webhook deliveries that failed in the last day",
+		Schedule:    "0 */6 * * *",
+		Run: func(ctx context.Context) (string, error) {
+			filter := jobs.Filter{
+				Kinds:            []string{services.JobKindWebhookDeliver},
+				FailedSince:      time.Now().Add(-24 * time.Hour),
+				RetriedFewerThan: 3,
+			}
+			total := 0
+			for {
+				n, err := jobs.RetryFailed(ctx, db, filter)
+				total += n
+				if err != nil || n == 0 {
+					return fmt.Sprintf("retried %d deliveries", total), err
+				}
+			}
+		},
+	})
+}
@@ -0,0 +1,94 @@
+// Package audit records security-relevant actions in organizations, and the requests refused
+// on their behalf, for their owners and admins to review
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Entry describes an action to record
+type Entry struct {
+	OrganizationID uuid.UUID
+	// UserID is uuid.Nil for requests without a known user
+	UserID    uuid.UUID
+	Action    string
+	IPAddress string
+	// Details is marshaled to JSON, e.g. the rules an update set
+	Details interface{}
+}
+
+// Record adds an entry. Through a tenant connection the caller must be an owner or admin of
+// the organization; passing the transaction of the change keeps the two consistent.
+func Record(ctx context.Context, q database.Querier, e Entry) error {
+	details := []byte("{}")
+	if e.Details != nil {
+		var err error
+		if details, err = json.Marshal(e.Details); err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+	}
+	var userID *uuid.UUID
+	if e.UserID != uuid.Nil {
+		userID = &e.UserID
+	}
+
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO audit_log (organization_id, user_id, action, ip_address, details)
+		VALUES ($1, $2, $3, $4, $5)
+	`, e.OrganizationID, userID, e.Action, e.IPAddress, details); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// Columns is the column list matching Scan
+const Columns = `id, organization_id, user_id, action, ip_address, details, created_at`
+
+// Scan scans a row selected with Columns
+func Scan(row interface{ Scan(...interface{}) error }) (*models.AuditEntry, error) {
+	var e models.AuditEntry
+	var details []byte
+	if err := row.Scan(&e.ID, &e.OrganizationID, &e.UserID, &e.Action, &e.IPAddress, &details, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	e.Details = details
+	return &e, nil
+}
+
+// List returns an organization's entries newest first, those of one action when action is
+// not empty, created before the given time when it is not zero
+func List(ctx context.Context, q database.Querier, orgID uuid.UUID, action string, before time.Time, limit int) ([]models.AuditEntry, error) {
+	var beforeArg interface{}
+	if !before.IsZero() {
+		beforeArg = before
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+Columns+` FROM audit_log
+		WHERE organization_id = $1 AND ($2 = '' OR action = $2)
+			AND ($3::timestamptz IS NULL OR created_at < $3)
+		ORDER BY created_at DESC
+		LIMIT $4
+	`, orgID, action, beforeArg, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		e, err := Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,319 @@
+// Package auth signs users in with their password and authenticates their requests with the
+// bearer token of the resulting session. Sessions are stored in Postgres and cached in Redis,
+// and revoking one takes effect on the next request made with its token.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// TokenPrefix starts every session token, so they are told apart from other bearer tokens
+// and recognized when they leak
+const TokenPrefix = "ovs_"
+
+const sessionIDKey = "auth_session_id"
+
+// revokedMarker is cached in place of a revoked session, so a request that read the session
+// just before it was revoked cannot cache it again
+const revokedMarker = "revoked"
+
+var (
+	// ErrInvalidCredentials is returned for an unknown email address or a wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrSessionNotFound is returned for tokens and IDs of sessions that expired, were revoked
+	// or never existed
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+// SessionColumns is the column list matching ScanSession
+const SessionColumns = `id, user_id, ip_address, user_agent, created_at, last_seen_at, expires_at`
+
+// ScanSession scans a row selected with SessionColumns
+func ScanSession(row interface{ Scan(...interface{}) error }) (*models.AuthSession, error) {
+	var s models.AuthSession
+	if err := row.Scan(&s.ID, &s.UserID, &s.IPAddress, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Session is what a token authenticates
+type Session struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// Sessions signs users in and authenticates their tokens. db is the master connection, as
+// tokens are checked before a request has a tenant connection.
+type Sessions struct {
+	db    *sql.DB
+	redis *redis.Client
+	cfg   config.Auth
+}
+
+// NewSessions creates a session store. Without Redis every request reads its session from
+// Postgres.
+func NewSessions(db *sql.DB, redisClient *redis.Client, cfg config.Auth) *Sessions {
+	return &Sessions{db: db, redis: redisClient, cfg: cfg}
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+func cacheKey(hash []byte) string {
+	return "auth:sessions:" + hex.EncodeToString(hash)
+}
+
+// SignIn checks a user's password and opens a session for the client at ip. The token it
+// returns is not stored and cannot be retrieved again. Repeated failures hold up further
+// sign-ins and then refuse them with a *ThrottledError.
+func (s *Sessions) SignIn(ctx context.Context, email, password, ip, userAgent string) (string, *models.AuthSession, error) {
+	keys := newThrottleKeys(email, ip)
+	if err := s.checkThrottle(ctx, keys); err != nil {
+		return "", nil, err
+	}
+
+	token, session, err := s.signIn(ctx, email, password, ip, userAgent)
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		// Counted even when the client gave up waiting for the answer
+		s.recordFailure(context.WithoutCancel(ctx), keys, email)
+	case err == nil:
+		s.clearFailures(ctx, keys)
+	}
+	return token, session, err
+}
+
+func (s *Sessions) signIn(ctx context.Context, email, password, ip, userAgent string) (string, *models.AuthSession, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+
+	userID, err := queries.New(tx).SignIn(ctx, queries.SignInParams{Email: strings.TrimSpace(email), Password: password})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to check credentials: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_sessions WHERE user_id = $1 AND expires_at <= NOW()`, userID); err != nil {
+		return "", nil, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	session, err := ScanSession(tx.QueryRowContext(ctx, `
+		INSERT INTO user_sessions (user_id, token_hash, ip_address, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + make_interval(secs => $5))
+		RETURNING `+SessionColumns,
+		userID, hashToken(token), ip, truncate(userAgent, 512), s.cfg.SessionTTL.Seconds()))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", nil, err
+	}
+	session.Current = true
+	return token, session, nil
+}
+
+// Authenticate returns the session of a token and records that it was seen from ip
+func (s *Sessions) Authenticate(ctx context.Context, token, ip, userAgent string) (Session, error) {
+	hash := hashToken(token)
+	key := cacheKey(hash)
+
+	if s.redis != nil {
+		cached, err := s.redis.Get(ctx, key).Result()
+		if err == nil {
+			if cached == revokedMarker {
+				return Session{}, ErrSessionNotFound
+			}
+			if session, ok := parseCachedSession(cached); ok {
+				s.touch(ctx, session, ip, userAgent)
+				return session, nil
+			}
+		}
+	}
+
+	var session Session
+	var expiresAt time.Time
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, user_id, expires_at FROM user_sessions WHERE token_hash = $1 AND expires_at > NOW()`, hash,
+	).Scan(&session.ID, &session.UserID, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Session{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	if s.redis != nil && s.cfg.SessionCacheTTL > 0 {
+		ttl := min(s.cfg.SessionCacheTTL, time.Until(expiresAt))
+		// SetNX keeps a revocation marker written since the lookup
+		if err := s.redis.SetNX(ctx, key, session.ID.String()+":"+session.UserID.String(), ttl).Err(); err != nil {
+			log.Printf("WARN: Failed to cache session: %v", err)
+		}
+	}
+	s.touch(ctx, session, ip, userAgent)
+	return session, nil
+}
+
+func parseCachedSession(cached string) (Session, bool) {
+	id, user, ok := strings.Cut(cached, ":")
+	if !ok {
+		return Session{}, false
+	}
+	sessionID, err1 := uuid.Parse(id)
+	userID, err2 := uuid.Parse(user)
+	return Session{ID: sessionID, UserID: userID}, err1 == nil && err2 == nil
+}
+
+// touch updates when and where a session was last seen, at most once per touch interval
+func (s *Sessions) touch(ctx context.Context, session Session, ip, userAgent string) {
+	if s.redis != nil {
+		fresh, err := s.redis.SetNX(ctx, "auth:sessions:seen:"+session.ID.String(), 1, s.cfg.SessionTouchInterval).Result()
+		if err == nil && !fresh {
+			return
+		}
+	}
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE user_sessions SET last_seen_at = NOW(), ip_address = $2, user_agent = $3
+		WHERE id = $1 AND last_seen_at < NOW() - make_interval(secs => $4)
+	`, session.ID, ip, truncate(userAgent, 512), s.cfg.SessionTouchInterval.Seconds())
+	if err != nil {
+		log.Printf("WARN: Failed to record session activity: %v", err)
+	}
+}
+
+// List returns the user's active sessions, most recently seen first, marking the current one.
+// q is the user's tenant connection.
+func (s *Sessions) List(ctx context.Context, q database.Querier, userID, currentID uuid.UUID) ([]models.AuthSession, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+SessionColumns+` FROM user_sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.AuthSession{}
+	for rows.Next() {
+		session, err := ScanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		session.Current = session.ID == currentID
+		sessions = append(sessions, *session)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke ends one of the user's sessions. Requests made with its token fail from then on, on
+// every instance.
+func (s *Sessions) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	var hash []byte
+	err := s.db.QueryRowContext(ctx,
+		`DELETE FROM user_sessions WHERE id = $1 AND user_id = $2 RETURNING token_hash`, id, userID,
+	).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if s.redis != nil {
+		if err := s.redis.Set(ctx, cacheKey(hash), revokedMarker, max(s.cfg.SessionCacheTTL, time.Second)).Err(); err != nil {
+			// The cached session outlives the revocation by up to the cache TTL
+			logger.Error("Failed to mark session %s revoked in Redis: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// DeleteExpired deletes the sessions of every user that have expired. Sign-in removes the
+// expired sessions of the user signing in; this catches those of users who never return.
+func (s *Sessions) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Middleware authenticates requests bearing a session token, for StatelessDatabaseMiddleware
+// to act as the session's user. Requests without one are left to the other ways of
+// identifying users; requests with an invalid one are refused.
+func (s *Sessions) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || !strings.HasPrefix(token, TokenPrefix) {
+			c.Next()
+			return
+		}
+
+		session, err := s.Authenticate(c.Request.Context(), token, c.ClientIP(), c.Request.UserAgent())
+		if errors.Is(err, ErrSessionNotFound) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked session"})
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to authenticate a session: %v", err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to check the session"})
+			return
+		}
+
+		c.Set(string(database.AuthUserIDKey), session.UserID)
+		c.Set(sessionIDKey, session.ID)
+		c.Next()
+	}
+}
+
+// GetSessionID returns the session a request authenticated with, if it used one
+func GetSessionID(c *gin.Context) (uuid.UUID, bool) {
+	id, ok := c.Get(sessionIDKey)
+	if !ok {
+		return uuid.Nil, false
+	}
+	sessionID, ok := id.(uuid.UUID)
+	return sessionID, ok
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"openvdo/internal/metrics"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Reasons sign-ins are refused before the password is checked
+const (
+	ReasonAccountLocked = "account_locked"
+	ReasonIPBlocked     = "ip_blocked"
+)
+
+var (
+	blockedLogins = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "auth",
+		Name:      "login_blocked_total",
+		Help:      "Sign-ins refused before checking the password, by reason (account_locked or ip_blocked)",
+	}, []string{"reason"})
+	failedLogins = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "auth",
+		Name:      "login_failures_total",
+		Help:      "Sign-ins with an unknown email address or a wrong password",
+	})
+	lockouts = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "auth",
+		Name:      "lockouts_total",
+		Help:      "Accounts locked after repeated failed sign-ins",
+	})
+)
+
+// ThrottledError refuses a sign-in without checking its password
+type ThrottledError struct {
+	// Reason is ReasonAccountLocked or ReasonIPBlocked
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("sign-in blocked (%s), retry in %v", e.Reason, e.RetryAfter)
+}
+
+// throttleKeys are the Redis keys counting the failed sign-ins of an account and an IP
+// address. Email addresses are hashed so they do not sit in Redis in the clear.
+type throttleKeys struct {
+	account, locked, ip string
+}
+
+func newThrottleKeys(email, ip string) throttleKeys {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	account := hex.EncodeToString(sum[:])
+	return throttleKeys{
+		account: "auth:login:failures:account:" + account,
+		locked:  "auth:login:locked:" + account,
+		ip:      "auth:login:failures:ip:" + ip,
+	}
+}
+
+// checkThrottle refuses sign-ins to locked accounts and from blocked addresses, and holds up
+// the others in proportion to the failures before them. Without Redis, or when it fails,
+// sign-ins are not throttled.
+func (s *Sessions) checkThrottle(ctx context.Context, keys throttleKeys) error {
+	if s.redis == nil {
+		return nil
+	}
+
+	pipe := s.redis.Pipeline()
+	lockTTL := pipe.PTTL(ctx, keys.locked)
+	accountFailures := pipe.Get(ctx, keys.account)
+	ipFailures := pipe.Get(ctx, keys.ip)
+	ipTTL := pipe.PTTL(ctx, keys.ip)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("WARN: Failed to read sign-in failures, not throttling: %v", err)
+		return nil
+	}
+
+	if ttl := lockTTL.Val(); ttl > 0 {
+		blockedLogins.WithLabelValues(ReasonAccountLocked).Inc()
+		return &ThrottledError{Reason: ReasonAccountLocked, RetryAfter: ttl}
+	}
+	byIP, _ := ipFailures.Int()
+	if s.cfg.LoginMaxIPFailures > 0 && byIP >= s.cfg.LoginMaxIPFailures {
+		blockedLogins.WithLabelValues(ReasonIPBlocked).Inc()
+		return &ThrottledError{Reason: ReasonIPBlocked, RetryAfter: max(ipTTL.Val(), time.Second)}
+	}
+
+	byAccount, _ := accountFailures.Int()
+	if failures := max(byAccount, byIP); failures > 0 && s.cfg.LoginDelay > 0 {
+		delay := s.cfg.LoginMaxDelay
+		if failures < 32 {
+			delay = min(s.cfg.LoginDelay<<(failures-1), s.cfg.LoginMaxDelay)
+		}
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// recordFailure counts a failed sign-in against the account and the address, locking the
+// account when it reaches the limit. The owner of a locked account is notified, as someone
+// may be guessing their password.
+func (s *Sessions) recordFailure(ctx context.Context, keys throttleKeys, email string) {
+	failedLogins.Inc()
+	if s.redis == nil {
+		return
+	}
+
+	pipe := s.redis.TxPipeline()
+	accountFailures := pipe.Incr(ctx, keys.account)
+	pipe.Expire(ctx, keys.account, s.cfg.LoginFailureWindow)
+	pipe.Incr(ctx, keys.ip)
+	pipe.Expire(ctx, keys.ip, s.cfg.LoginFailureWindow)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("WARN: Failed to count a failed sign-in: %v", err)
+		return
+	}
+	if s.cfg.LoginMaxFailures <= 0 || accountFailures.Val() < int64(s.cfg.LoginMaxFailures) {
+		return
+	}
+
+	locked, err := s.redis.SetNX(ctx, keys.locked, 1, s.cfg.LoginLockout).Result()
+	if err != nil {
+		log.Printf("WARN: Failed to lock an account after repeated failed sign-ins: %v", err)
+		return
+	}
+	s.redis.Del(ctx, keys.account)
+	if !locked {
+		return
+	}
+	lockouts.Inc()
+
+	// Unknown addresses are locked all the same, so lockouts do not tell which accounts exist
+	userID, err := queries.New(s.db).FindUserByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err == nil {
+		_, err = services.Notify(ctx, s.db, services.NewNotification{
+			UserID: userID,
+			Type:   models.NotificationAccountLocked,
+			Title:  "Your account was locked",
+			Body: fmt.Sprintf("Signing in to your account failed %d times in a row, so it is locked for %v. "+
+				"If this was not you, someone may be guessing your password; consider changing it.",
+				s.cfg.LoginMaxFailures, s.cfg.LoginLockout),
+			Data: map[string]interface{}{"locked_until": time.Now().Add(s.cfg.LoginLockout).UTC()},
+		})
+	}
+	if err != nil {
+		logger.Error("Failed to notify the owner of a locked account: %v", err)
+	}
+}
+
+// clearFailures forgets the failures of an account once it signs in. Those of the address
+// stay, as it may be trying other accounts.
+func (s *Sessions) clearFailures(ctx context.Context, keys throttleKeys) {
+	if s.redis == nil {
+		return
+	}
+	if err := s.redis.Del(ctx, keys.account).Err(); err != nil {
+		log.Printf("WARN: Failed to reset sign-in failures: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+// Package bootstrap waits for the server's dependencies at startup and verifies that the
+// database is set up the way the API relies on, so a half-provisioned environment fails
+// fast instead of serving broken endpoints.
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/health"
+	"openvdo/migrations"
+	"openvdo/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+	_ "github.com/lib/pq"
+)
+
+const (
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// rlsExemptTables have an organization_id column but are intentionally not protected by RLS
+// policies: the policies of every other table query user_org_roles to resolve membership, and
+// feature flag overrides are platform data only the master connection reads.
+var rlsExemptTables = map[string]bool{
+	"user_org_roles":         true,
+	"feature_flag_overrides": true,
+}
+
+// Run waits for Postgres (and Redis when required) until the configured deadline, then
+// verifies extensions, migrations, RLS policies and queries
+func Run(cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Bootstrap.Timeout)
+	defer cancel()
+
+	db, err := WaitForPostgres(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := WaitForRedis(ctx, cfg.Redis); err != nil {
+		if cfg.Bootstrap.RequireRedis {
+			return err
+		}
+		logger.Error("Continuing without Redis: %v", err)
+	}
+
+	if err := Verify(ctx, db, cfg.Bootstrap.RequiredExtensions); err != nil {
+		return err
+	}
+
+	logger.Info("Bootstrap checks passed")
+	return nil
+}
+
+// WaitForPostgres retries until one of the candidate primaries accepts connections and writes
+func WaitForPostgres(ctx context.Context, cfg config.Database) (*sql.DB, error) {
+	var db *sql.DB
+	err := retry(ctx, "Postgres", func(ctx context.Context) error {
+		var errs []string
+		for _, dsn := range cfg.PrimaryDSNList() {
+			candidate, err := sql.Open("postgres", dsn)
+			if err != nil {
+				return err
+			}
+			var inRecovery bool
+			if err := candidate.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+				candidate.Close()
+				errs = append(errs, err.Error())
+				continue
+			}
+			if inRecovery {
+				candidate.Close()
+				errs = append(errs, "server is a standby")
+				continue
+			}
+			db = candidate
+			return nil
+		}
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	})
+	return db, err
+}
+
+// WaitForRedis retries until Redis answers a ping
+func WaitForRedis(ctx context.Context, cfg config.Redis) error {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Address(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	defer client.Close()
+
+	return retry(ctx, "Redis", func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
+
+// Verify checks required extensions, the migration version, RLS coverage and the generated
+// queries of the core tables
+func Verify(ctx context.Context, db *sql.DB, extensions []string) error {
+	if err := verifyExtensions(ctx, db, extensions); err != nil {
+		return err
+	}
+	if err := health.Migrations(db, migrations.LatestVersion())(ctx); err != nil {
+		return fmt.Errorf("database schema is not up to date: %w", err)
+	}
+	if err := verifyRLS(ctx, db); err != nil {
+		return err
+	}
+	return verifyQueries(ctx, db)
+}
+
+// verifyExtensions checks that each required extension is installed
+func verifyExtensions(ctx context.Context, db *sql.DB, extensions []string) error {
+	var missing []string
+	for _, name := range extensions {
+		var exists bool
+		err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = $1)`, name).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check extension %s: %w", name, err)
+		}
+		if !exists {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("required PostgreSQL extensions not installed: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// verifyRLS checks that every tenant table (one with an organization_id column) has row
+// level security enabled and at least one policy
+func verifyRLS(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.relname, c.relrowsecurity,
+			(SELECT COUNT(*) FROM pg_policies p WHERE p.schemaname = n.nspname AND p.tablename = c.relname)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN information_schema.columns col ON col.table_schema = n.nspname AND col.table_name = c.relname
+		WHERE n.nspname = current_schema() AND c.relkind = 'r' AND col.column_name = 'organization_id'
+		ORDER BY c.relname
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to inspect RLS policies: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var table string
+		var enabled bool
+		var policies int
+		if err := rows.Scan(&table, &enabled, &policies); err != nil {
+			return err
+		}
+		if rlsExemptTables[table] {
+			continue
+		}
+		if !enabled {
+			problems = append(problems, table+" (RLS disabled)")
+		} else if policies == 0 {
+			problems = append(problems, table+" (no policies)")
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("tenant tables without row level security: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// retry calls fn with exponential backoff and jitter until it succeeds or ctx expires
+func retry(ctx context.Context, name string, fn func(context.Context) error) error {
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, maxBackoff)
+		err := fn(attemptCtx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				logger.Info("%s available after %d attempts", name, attempt)
+			}
+			return nil
+		}
+
+		logger.Info("Waiting for %s (attempt %d): %v", name, attempt, err)
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not available before the bootstrap deadline: %w", name, err)
+		case <-time.After(wait):
+		}
+
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
@@ -0,0 +1,70 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"openvdo/internal/queries"
+)
+
+// tableColumns pairs a core table with the column list its generated model is read with. Statements
+// selecting or returning * read a row in the order of the table's columns, which must still be
+// the order the model was generated from.
+var tableColumns = []struct {
+	table   string
+	columns string
+}{
+	{"organizations", queries.Columns[queries.Organization]()},
+	{"videos", queries.Columns[queries.Video]()},
+	{"user_org_roles", queries.Columns[queries.UserOrgRole]()},
+}
+
+// verifyQueries prepares every generated statement without running it, and compares the
+// columns of the core tables with those of their generated models
+func verifyQueries(ctx context.Context, db *sql.DB) error {
+	var problems []string
+	names := make([]string, 0, len(queries.Statements))
+	for name := range queries.Statements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stmt, err := db.PrepareContext(ctx, queries.Statements[name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%v)", name, err))
+			continue
+		}
+		stmt.Close()
+	}
+
+	for _, t := range tableColumns {
+		columns, err := selectedColumns(ctx, db, `SELECT * FROM `+t.table)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%v)", t.table, err))
+		} else if columns != t.columns {
+			problems = append(problems, fmt.Sprintf("%s (columns %s, generated for %s)", t.table, columns, t.columns))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("queries do not match the database schema: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// selectedColumns returns the names of the columns query selects, without reading any row
+func selectedColumns(ctx context.Context, db *sql.DB, query string) (string, error) {
+	rows, err := db.QueryContext(ctx, query+` LIMIT 0`)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(columns, ", "), rows.Err()
+}
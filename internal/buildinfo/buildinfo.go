@@ -0,0 +1,101 @@
+// Package buildinfo tells which build of OpenVDO is running. Release builds set the
+// variables with the linker:
+//
+//	go build -ldflags "-X openvdo/internal/buildinfo.version=v1.4.0 \
+//	  -X openvdo/internal/buildinfo.commit=$(git rev-parse HEAD) \
+//	  -X openvdo/internal/buildinfo.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/server
+//
+// Builds without them fall back to the module version and VCS stamp Go embeds.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"openvdo/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// Info describes a build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build of the running binary
+func Get() Info {
+	once.Do(func() {
+		info = Info{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+				info.Version = bi.Main.Version
+			}
+			for _, s := range bi.Settings {
+				switch s.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = s.Value
+					}
+				case "vcs.time":
+					if info.Date == "" {
+						info.Date = s.Value
+					}
+				case "vcs.modified":
+					info.Modified = s.Value == "true" && commit == ""
+				}
+			}
+		}
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+	})
+	return info
+}
+
+// Version returns the version of the running binary, "dev" for untagged builds
+func Version() string {
+	return Get().Version
+}
+
+// String returns the version with the short commit, as shown by openvdo --version
+func (i Info) String() string {
+	s := i.Version
+	if i.Commit != "" {
+		short := i.Commit
+		if len(short) > 12 {
+			short = short[:12]
+		}
+		s += " (" + short
+		if i.Modified {
+			s += ", modified"
+		}
+		s += ")"
+	}
+	return s
+}
+
+// buildInfo exposes the build as a gauge of 1 labelled with it, so dashboards can mark
+// deployments
+var buildInfo = promauto.With(metrics.Registry).NewGaugeFunc(prometheus.GaugeOpts{
+	Namespace:   metrics.Namespace,
+	Name:        "build_info",
+	Help:        "Build of the running binary; always 1",
+	ConstLabels: prometheus.Labels{"version": Get().Version, "commit": Get().Commit, "go_version": Get().GoVersion},
+}, func() float64 { return 1 })
@@ -0,0 +1,129 @@
+// Package cachecontrol decides how long browsers and CDNs keep each kind of response. Handlers
+// apply a policy of Policies instead of writing Cache-Control themselves, so the rules live in
+// one place and follow the configuration.
+package cachecontrol
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Policy is how a response may be cached. The zero Policy sets no headers.
+type Policy struct {
+	// Public lets shared caches store the response; otherwise only the viewer's browser may
+	Public bool
+	// MaxAge is how long browsers keep the response
+	MaxAge time.Duration
+	// SharedMaxAge is how long CDNs keep a public response; zero keeps it as long as MaxAge
+	SharedMaxAge time.Duration
+	// Immutable responses are not revalidated while fresh, even on reload
+	Immutable bool
+	// Revalidate has caches check with the server before every use
+	Revalidate bool
+	// NoStore keeps the response out of every cache
+	NoStore bool
+}
+
+// Private is p for a response only its viewer may see, such as one for a private video
+func (p Policy) Private() Policy {
+	p.Public = false
+	p.SharedMaxAge = 0
+	return p
+}
+
+// CacheControl is the Cache-Control header of p, empty for the zero Policy
+func (p Policy) CacheControl() string {
+	if p == (Policy{}) {
+		return ""
+	}
+	if p.NoStore {
+		return "private, no-store"
+	}
+	directives := []string{"private"}
+	if p.Public {
+		directives[0] = "public"
+	}
+	if p.Revalidate {
+		directives = append(directives, "no-cache")
+	} else {
+		directives = append(directives, "max-age="+seconds(p.MaxAge))
+		if p.Public && p.SharedMaxAge > 0 {
+			directives = append(directives, "s-maxage="+seconds(p.SharedMaxAge))
+		}
+	}
+	if p.Immutable {
+		directives = append(directives, "immutable")
+	}
+	return strings.Join(directives, ", ")
+}
+
+// SurrogateControl is the Surrogate-Control header of p for CDNs that read it, which then drop
+// it before the response reaches browsers. It is empty when CDNs must not keep the response.
+func (p Policy) SurrogateControl() string {
+	if !p.Public || p.NoStore || p.Revalidate {
+		return ""
+	}
+	age := p.MaxAge
+	if p.SharedMaxAge > 0 {
+		age = p.SharedMaxAge
+	}
+	return "max-age=" + seconds(age)
+}
+
+// Apply sets the caching headers of p on the response, replacing those of a policy applied
+// before. The zero Policy leaves them as they are.
+func (p Policy) Apply(c *gin.Context) {
+	cc := p.CacheControl()
+	if cc == "" {
+		return
+	}
+	c.Header("Cache-Control", cc)
+	// c.Header deletes the header when given an empty value
+	c.Header("Surrogate-Control", p.SurrogateControl())
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(d.Seconds()))
+}
+
+// Policies are the policies of each kind of content served
+type Policies struct {
+	// Immutable is for content that never changes at its URL, such as segments and images
+	// linked with their version
+	Immutable Policy
+	// Playlist is for HLS playlists, which live streams update every few seconds
+	Playlist Policy
+	// Image is for images linked without their version, which change when replaced
+	Image Policy
+	// Feed is for catalog feeds, kept long by CDNs as they are purged when they change
+	Feed Policy
+	// PlaylistFeed is for feeds of smart playlists, which are computed when read and never
+	// purged, so caches keep them only as long as the playlist's videos are reused
+	PlaylistFeed Policy
+	// Revalidate is for content checked with the server on every use, such as the player page
+	// and sources it serves with an ETag
+	Revalidate Policy
+	// Metadata is for API responses, which are per user
+	Metadata Policy
+	// NoStore is for responses that must not be reused, such as presigned URLs, which expire
+	NoStore Policy
+}
+
+// New builds the policies from the configuration
+func New(cfg *config.Config) Policies {
+	return Policies{
+		Immutable:    Policy{Public: true, MaxAge: cfg.Cache.ImmutableMaxAge, Immutable: true},
+		Playlist:     Policy{Public: true, MaxAge: cfg.Cache.PlaylistMaxAge},
+		Image:        Policy{Public: true, MaxAge: cfg.Cache.ImageMaxAge},
+		Feed:         Policy{Public: true, MaxAge: cfg.Feeds.MaxAge, SharedMaxAge: cfg.Feeds.CDNMaxAge},
+		PlaylistFeed: Policy{Public: true, MaxAge: cfg.Feeds.PlaylistCacheTTL},
+		Revalidate:   Policy{Public: true, Revalidate: true},
+		Metadata:     Policy{NoStore: true},
+		NoStore:      Policy{NoStore: true},
+	}
+}
@@ -0,0 +1,75 @@
+// Package coalesce merges concurrent reads of the same key into one fetch, so a crowd of
+// players opening a popular video at once, such as at a premiere, costs one database query or
+// storage read rather than one per player.
+package coalesce
+
+import (
+	"context"
+
+	"openvdo/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+// Metrics are labelled by group rather than key: keys are video IDs and storage keys, which
+// would make a series per video
+var (
+	fetches = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Name:      "coalesce_fetches_total",
+		Help:      "Fetches run for reads that may be coalesced, by group",
+	}, []string{"group"})
+	coalesced = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Name:      "coalesced_requests_total",
+		Help:      "Reads answered by a fetch another request started, by group",
+	}, []string{"group"})
+)
+
+// Group coalesces the fetches of one kind of data, such as video rows
+type Group struct {
+	name   string
+	flight singleflight.Group
+}
+
+// NewGroup creates a group; name labels its metrics
+func NewGroup(name string) *Group {
+	fetches.WithLabelValues(name)
+	coalesced.WithLabelValues(name)
+	return &Group{name: name}
+}
+
+// Do returns the result of fn for key, running fn once for all the callers asking for the key
+// while it runs. The result is shared between them and must not be modified.
+//
+// fn gets the deadline and values of the caller that started it, but is not canceled when that
+// caller goes away, as others may be waiting for it. A caller whose ctx ends stops waiting.
+func Do[T any](ctx context.Context, g *Group, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	// Only written by the fetch this caller started, which happens before its result is received
+	started := false
+	ch := g.flight.DoChan(key, func() (interface{}, error) {
+		started = true
+		fetches.WithLabelValues(g.name).Inc()
+		fetchCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			fetchCtx, cancel = context.WithDeadline(fetchCtx, deadline)
+			defer cancel()
+		}
+		return fn(fetchCtx)
+	})
+
+	select {
+	case res := <-ch:
+		if !started {
+			coalesced.WithLabelValues(g.name).Inc()
+		}
+		v, _ := res.Val.(T)
+		return v, res.Err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/knadh/koanf/parsers/yaml"
@@ -26,6 +28,32 @@ type Database struct {
 
 	MaxTenantPools  int           `default:"50"`
 	PoolIdleTimeout time.Duration `default:"10m"`
+
+	WarmupConns      int           `default:"5"`
+	AdaptivePool     bool          `default:"true"`
+	AdaptiveInterval time.Duration `default:"30s"`
+	MinIdleConns     int           `default:"2"`
+
+	// PrimaryDSNs lists candidate primaries in priority order; empty means the single DSN built from Host, Port, etc.
+	PrimaryDSNs           []string
+	FailoverCheckInterval time.Duration `default:"5s"`
+	FailoverThreshold     int           `default:"3"`
+
+	// PgBouncerMode scopes the RLS context to transactions for PgBouncer's transaction pooling
+	PgBouncerMode bool `default:"false"`
+
+	// SlowQueryThreshold logs tenant statements taking at least this long; 0 disables it
+	SlowQueryThreshold time.Duration `default:"500ms"`
+
+	// StatementCacheSize bounds the prepared statements each connection keeps; 0 disables the
+	// cache, which is always off in PgBouncer mode
+	StatementCacheSize int `default:"100"`
+
+	// CountCacheTTL is how long row counts of list endpoints are cached in Redis; 0 disables it
+	CountCacheTTL time.Duration `default:"60s"`
+	// CountEstimateThreshold makes counts the planner expects to reach it estimates instead of
+	// COUNT(*); 0 always counts
+	CountEstimateThreshold int64 `default:"0"`
 }
 
 type Redis struct {
@@ -35,9 +63,626 @@ type Redis struct {
 	DB       int
 }
 
-type Config struct {
+type Storage struct {
+	Backend   string
+	LocalPath string
+
+	S3Bucket          string
+	S3Region          string
+	S3Endpoint        string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3ForcePathStyle  bool
+
+	PresignExpiry     time.Duration `default:"1h"`
+	MultipartPartSize int64         `default:"67108864"`
+	MaxUploadSize     int64         `default:"107374182400"`
+
+	ArchiveStorageClass    string        `default:"GLACIER"`
+	ArchiveSourceAfter     time.Duration `default:"720h"`
+	ArchiveRenditionsAfter time.Duration `default:"2160h"`
+	RestoreDays            int           `default:"7"`
+	RestoreTier            string        `default:"Standard"`
+	LifecycleInterval      time.Duration `default:"1h"`
+
+	DuplicatePolicy string `default:"warn"`
+	HashWorkers     int    `default:"4"`
+
+	// SourceVersionRetention is how long replaced sources and their renditions are kept for
+	// rollback; 0 deletes them when they are replaced
+	SourceVersionRetention time.Duration `default:"720h"`
+	SourceVersionsMax      int           `default:"5"`
+}
+
+type Health struct {
+	Interval       time.Duration `default:"10s"`
+	Timeout        time.Duration `default:"2s"`
+	StorageTimeout time.Duration `default:"5s"`
+}
+
+type Compression struct {
+	Enabled      bool     `default:"true"`
+	MinSize      int      `default:"1024"`
+	ExcludePaths []string `default:"/metrics"`
+}
+
+type CORS struct {
+	// AllowedOrigins may contain "*"; organizations' playback domains are allowed in addition, read-only
+	AllowedOrigins     []string
+	AllowedMethods     []string      `default:"GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS"`
+	AllowedHeaders     []string      `default:"Origin,Content-Type,Authorization,X-User-ID,X-Org-ID,X-Timezone,If-Match,If-None-Match"`
+	ExposedHeaders     []string      `default:"Content-Length,ETag,X-Request-ID"`
+	AllowCredentials   bool          `default:"false"`
+	MaxAge             time.Duration `default:"12h"`
+	PlaybackDomainsTTL time.Duration `default:"1m"`
+}
+
+type Security struct {
+	Headers               bool          `default:"true"`
+	HSTSMaxAge            time.Duration `default:"8760h"`
+	HSTSIncludeSubdomains bool          `default:"true"`
+}
+
+type Playback struct {
+	// SigningKey signs playback tokens for private videos; without it only public and unlisted videos play
+	SigningKey string
+	TokenTTL   time.Duration `default:"1h"`
+	// PublicURL is the externally visible base URL used in embed and oEmbed links; empty means the request's host
+	PublicURL    string
+	ProviderName string `default:"OpenVDO"`
+	HLSJSURL     string `default:"https://cdn.jsdelivr.net/npm/hls.js@1/dist/hls.min.js"`
+	// SessionTokenTTL is the lifetime of the tokens of playback sessions, which heartbeats renew
+	SessionTokenTTL   time.Duration `default:"2m"`
+	HeartbeatInterval time.Duration `default:"30s"`
+	// SessionTimeout ends sessions that sent no heartbeat for this long
+	SessionTimeout time.Duration `default:"90s"`
+	// MaxConcurrentStreams bounds the active sessions of a user in an organization; 0 is unlimited
+	MaxConcurrentStreams int `default:"0"`
+	// ProxyMedia streams sources through the server rather than redirecting players to
+	// presigned storage URLs, for buckets players cannot reach
+	ProxyMedia bool `default:"false"`
+}
+
+type CDN struct {
+	// PurgeURL receives a POST listing the paths to invalidate when a video's source is replaced
+	// or a feed changes; empty disables purging
+	PurgeURL   string
+	PurgeToken string
+	Timeout    time.Duration `default:"10s"`
+}
+
+// Cache sets how long browsers and CDNs keep content; feeds have their own ages in Feeds
+type Cache struct {
+	// ImmutableMaxAge is for content that never changes at its URL, such as versioned images
+	ImmutableMaxAge time.Duration `default:"8760h"`
+	// PlaylistMaxAge is for HLS playlists, short enough for live playlists to advance
+	PlaylistMaxAge time.Duration `default:"2s"`
+	// ImageMaxAge is for images linked without their version
+	ImageMaxAge time.Duration `default:"5m"`
+}
+
+type Feeds struct {
+	// MaxItems bounds the videos of a feed, newest first
+	MaxItems int `default:"100"`
+	// CDNMaxAge is how long shared caches keep a feed; feeds are purged when they change
+	CDNMaxAge time.Duration `default:"24h"`
+	// MaxAge is how long feed readers keep a feed before revalidating it
+	MaxAge time.Duration `default:"5m"`
+	// PlaylistCacheTTL is how long the videos a smart playlist lists are reused before its
+	// criteria are evaluated again, and how long caches keep the playlist's feeds
+	PlaylistCacheTTL time.Duration `default:"1m"`
+}
+
+type Email struct {
+	// SMTPHost is the relay notification emails are sent through; empty disables email delivery
+	SMTPHost     string
+	SMTPPort     int `default:"587"`
+	SMTPUsername string
+	SMTPPassword string
+	From         string        `default:"OpenVDO <no-reply@localhost>"`
+	Timeout      time.Duration `default:"30s"`
+}
+
+type Push struct {
+	// VAPIDPublicKey and VAPIDPrivateKey are the base64url P-256 key pair that Web Push
+	// subscriptions are bound to; without them Web Push is disabled
+	VAPIDPublicKey  string
+	VAPIDPrivateKey string
+	// VAPIDSubject is the mailto: or https: contact push services reach the operator at
+	VAPIDSubject string
+	// FCMCredentialsFile is a Google service account key; without it FCM is disabled
+	FCMCredentialsFile string
+	// FCMProjectID defaults to the project of the service account
+	FCMProjectID string
+	// APNsKeyFile is the .p8 token signing key; without it APNs is disabled
+	APNsKeyFile string
+	APNsKeyID   string
+	APNsTeamID  string
+	// APNsTopic is the bundle ID of the iOS app
+	APNsTopic   string
+	APNsSandbox bool `default:"false"`
+	// TTL is how long push services keep a notification for an offline device
+	TTL     time.Duration `default:"24h"`
+	Timeout time.Duration `default:"10s"`
+}
+
+type Events struct {
+	// RelayInterval is how often the relay looks for events to publish
+	RelayInterval time.Duration `default:"1s"`
+	BatchSize     int           `default:"100"`
+	// RetryDelay is the first delay before publishing a failed event again; it doubles per attempt
+	RetryDelay time.Duration `default:"5s"`
+	// Retention is how long published events are kept
+	Retention time.Duration `default:"168h"`
+	// RedisChannel is the pub/sub channel events are published on; empty disables publishing to Redis
+	RedisChannel       string        `default:"openvdo:events"`
+	WebhookTimeout     time.Duration `default:"10s"`
+	WebhookMaxAttempts int           `default:"8"`
+}
+
+type Admin struct {
+	// APIToken authorizes the operator endpoints under /admin/v1; empty disables them
+	APIToken string
+}
+
+type Flags struct {
+	// CacheTTL is how long an instance evaluates flags from its cached copy, and so how long a
+	// change takes to reach every instance
+	CacheTTL time.Duration `default:"10s"`
+}
+
+type Maintenance struct {
+	// Enabled forces maintenance mode on for this instance, whatever the database says
+	Enabled bool `default:"false"`
+	// RetryAfter is the Retry-After given to refused requests when none is stored
+	RetryAfter time.Duration `default:"60s"`
+	// PollInterval is how often instances read the maintenance mode from the database
+	PollInterval time.Duration `default:"5s"`
+}
+
+type Beacon struct {
+	// Enabled accepts player events on /api/v1/beacon and has the embedded player send them
+	Enabled bool `default:"true"`
+	// SampleRate is the share of playback sessions whose events are stored, from 0 to 1
+	SampleRate    float64       `default:"1"`
+	MaxEvents     int           `default:"100"`
+	MaxBodySize   int64         `default:"65536"`
+	BufferSize    int           `default:"10000"`
+	BatchSize     int           `default:"500"`
+	FlushInterval time.Duration `default:"2s"`
+	WriteTimeout  time.Duration `default:"10s"`
+}
+
+type AccessLog struct {
+	// Enabled logs each request as one JSON line, replacing Gin's request logger
+	Enabled bool `default:"true"`
+	// IPMode is how client addresses are logged: truncate, hash, full or none
+	IPMode         string `default:"truncate"`
+	IPv4PrefixBits int    `default:"24"`
+	IPv6PrefixBits int    `default:"48"`
+	// IPHashKey keys the address hashes; without one each process uses a random key, so
+	// hashes only match within one run
+	IPHashKey string
+	// SampleRate is the share of requests logged, from 0 to 1; server errors are always logged
+	SampleRate float64 `default:"1"`
+	// RouteSampleRates overrides SampleRate per route as route=rate entries; a route ending
+	// in * matches every route it prefixes
+	RouteSampleRates []string
+	// Stdout writes entries to standard output
+	Stdout bool `default:"true"`
+	// Sink also ships entries to syslog or loki; empty ships nowhere
+	Sink          string
+	SyslogAddress string `default:"udp://localhost:514"`
+	SyslogTag     string `default:"openvdo"`
+	LokiURL       string
+	LokiLabels    []string `default:"job=openvdo"`
+	LokiTenantID  string
+	LokiUsername  string
+	LokiPassword  string
+	BufferSize    int           `default:"10000"`
+	BatchSize     int           `default:"500"`
+	FlushInterval time.Duration `default:"1s"`
+	WriteTimeout  time.Duration `default:"10s"`
+}
+
+type ErrorTracking struct {
+	// SentryDSN sends panics and logged errors to a Sentry project
+	SentryDSN string
+	// WebhookURL posts the same events as Sentry event JSON to another collector
+	WebhookURL  string
+	Environment string `default:"production"`
+	// Release tags events; empty uses the version of the build
+	Release string
+	// SampleRate is the share of logged errors sent, from 0 to 1; panics are always sent
+	SampleRate float64       `default:"1"`
+	BufferSize int           `default:"100"`
+	Timeout    time.Duration `default:"5s"`
+}
+
+type FlightRecorder struct {
+	// Enabled records from startup; the admin API turns recording on and off at runtime
+	Enabled bool `default:"false"`
+	// Capacity is how many of the most recent requests are kept
+	Capacity    int `default:"200"`
+	MaxBodySize int `default:"4096"`
+	MaxQueries  int `default:"100"`
+	// MaxDuration bounds how long recording turned on through the admin API lasts
+	MaxDuration time.Duration `default:"1h"`
+}
+
+type Usage struct {
+	// Enabled counts API requests per organization and API key
+	Enabled bool `default:"true"`
+	// FlushInterval is how often counters are moved from Redis into Postgres
+	FlushInterval time.Duration `default:"1m"`
+}
+
+type Moderation struct {
+	// ReportThreshold unpublishes a video until review once this many reporters flagged it; 0
+	// never unpublishes automatically
+	ReportThreshold int `default:"3"`
+	// CounterNoticeWait is how long after a DMCA counter-notice a video may be restored
+	CounterNoticeWait time.Duration `default:"336h"`
+	// ScanProvider classifies sampled frames and audio of videos once they are ready: "http"
+	// posts the samples to a moderation API, "nsfw" posts each frame to an image classifier.
+	// Empty turns scanning off.
+	ScanProvider string
+	ScanURL      string
+	// ScanToken is sent as a bearer token when set
+	ScanToken        string
+	ScanFrames       int `default:"8"`
+	ScanAudioSeconds int `default:"30"`
+	// ScanThreshold flags a video for review once a category scores at least this much
+	ScanThreshold float64 `default:"0.8"`
+	// ScanRestrict unpublishes flagged videos until review rather than only queueing them
+	ScanRestrict bool          `default:"false"`
+	ScanTimeout  time.Duration `default:"5m"`
+	FFmpegPath   string        `default:"ffmpeg"`
+	FFprobePath  string        `default:"ffprobe"`
+}
+
+// ClickHouse stores playback analytics instead of Postgres when URL is set
+type ClickHouse struct {
+	// URL is the HTTP interface, e.g. http://clickhouse:8123
+	URL      string
+	Database string `default:"default"`
+	Table    string `default:"playback_events"`
+	User     string
+	Password string
+	Timeout  time.Duration `default:"10s"`
+	// Retention drops events older than this; 0 keeps them forever
+	Retention time.Duration `default:"8760h"`
+}
+
+type Regions struct {
+	// Home is the region of the main database and storage, where organizations without a
+	// region live
+	Home string `default:"default"`
+	// Local is the region this instance runs in, which is Home unless it was started with
+	// --region
+	Local string
+	// Clusters are the databases and storage of the other regions, keyed by region name
+	Clusters map[string]RegionCluster
+	// LookupTTL is how long an instance caches the region of an organization
+	LookupTTL time.Duration `default:"1m"`
+}
+
+// RegionCluster is the database and storage of one region. Settings a region does not
+// override are those of the home region.
+type RegionCluster struct {
 	Database Database
-	Redis    Redis
+	Storage  Storage
+}
+
+type Server struct {
+	// Listen is how the server is reached: tcp on Port, unix on UnixSocket, or systemd for the
+	// socket systemd passes in
+	Listen string `default:"tcp"`
+	Port   string `default:"8080"`
+	// UnixSocket is the path of the socket file, created with UnixSocketMode and, when set,
+	// owned by UnixSocketGroup
+	UnixSocket      string
+	UnixSocketMode  string `default:"0660"`
+	UnixSocketGroup string
+	// AdminAddr serves the admin API and /drainz on their own TCP address, such as
+	// 127.0.0.1:8081, instead of with the public API
+	AdminAddr string
+	// MetricsAddr serves /metrics, the database statistics and, with Pprof, the profiler on their
+	// own TCP address
+	MetricsAddr string
+	Pprof       bool `default:"false"`
+	// ShutdownTimeout is how long in-flight requests may take to finish after SIGTERM
+	ShutdownTimeout time.Duration `default:"30s"`
+	// DrainDelay is how long a draining instance keeps serving after failing readiness, so load
+	// balancers stop routing to it before it stops accepting connections
+	DrainDelay time.Duration `default:"5s"`
+	// StreamDrainGrace spreads the ending of long-lived responses over this long once draining
+	// starts; it should stay below ShutdownTimeout
+	StreamDrainGrace time.Duration `default:"20s"`
+}
+
+// TLS configures how the server terminates TLS. It serves plain HTTP unless a certificate
+// and key or autocert hosts are given.
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// AutocertHosts are the host names certificates are requested from Let's Encrypt for
+	AutocertHosts []string
+	AutocertEmail string
+	// AutocertCacheDir keeps the issued certificates across restarts
+	AutocertCacheDir string `default:"autocert"`
+	// AutocertHTTPAddr answers HTTP-01 challenges and redirects to HTTPS; empty relies on
+	// TLS-ALPN-01 challenges on the TLS port
+	AutocertHTTPAddr string
+	// ClientCAFile makes the admin API require client certificates signed by these CAs
+	ClientCAFile string
+	// HTTP2 is offered to TLS clients
+	HTTP2 bool `default:"true"`
+	// HTTP2Cleartext accepts HTTP/2 without TLS (h2c), for proxies terminating TLS in front
+	HTTP2Cleartext bool `default:"false"`
+}
+
+// Enabled reports whether the server terminates TLS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertHosts) > 0
+}
+
+// Routes sets what requests may take unless the route policy table says otherwise
+type Routes struct {
+	// Timeout bounds how long a request may take
+	Timeout time.Duration `default:"60s"`
+	// MaxBodySize bounds request bodies, in bytes
+	MaxBodySize int64 `default:"1048576"`
+	// UploadTimeout bounds routes taking files, which check their size themselves
+	UploadTimeout time.Duration `default:"2h"`
+	// RateLimits limits the requests of each caller per tier, as tier=requests/period entries
+	// such as api=600/1m; tiers without an entry are not limited
+	RateLimits []string
+}
+
+// Faults injects failures for resilience testing; never enable it in production
+type Faults struct {
+	Enabled bool `default:"false"`
+	// ErrorRate is the share of requests answered with ErrorStatus before their handler runs
+	ErrorRate   float64 `default:"0"`
+	ErrorStatus int     `default:"503"`
+	// RetryAfter is sent with injected 503 and 429 answers
+	RetryAfter time.Duration `default:"1s"`
+	// DBLatency holds up a request for this long after DBLatencyRate of its tenant statements
+	DBLatency     time.Duration `default:"0"`
+	DBLatencyRate float64       `default:"0"`
+	// RedisDropRate is the share of Redis commands failed as if the connection dropped
+	RedisDropRate float64 `default:"0"`
+	// Paths limits failed requests and slow statements to requests under these prefixes
+	Paths []string `default:"/api/"`
+}
+
+// Auth configures the sessions users sign in to with their password
+type Auth struct {
+	// SessionTTL is how long a session lasts after sign-in
+	SessionTTL time.Duration `default:"720h"`
+	// SessionCacheTTL is how long a session is cached in Redis before Postgres is read again
+	SessionCacheTTL time.Duration `default:"5m"`
+	// SessionTouchInterval limits how often the last-seen time of a session is written
+	SessionTouchInterval time.Duration `default:"1m"`
+	// LoginMaxFailures failed sign-ins lock an account for LoginLockout; failures are
+	// forgotten LoginFailureWindow after the last one
+	LoginMaxFailures   int           `default:"5"`
+	LoginLockout       time.Duration `default:"15m"`
+	LoginFailureWindow time.Duration `default:"15m"`
+	// LoginMaxIPFailures failed sign-ins from one IP address, for any accounts, block it
+	// until LoginFailureWindow passes without another
+	LoginMaxIPFailures int `default:"50"`
+	// LoginDelay holds up sign-ins after a failure, doubling with each further one up to
+	// LoginMaxDelay
+	LoginDelay    time.Duration `default:"250ms"`
+	LoginMaxDelay time.Duration `default:"5s"`
+}
+
+// IPAccess enforces the address ranges organizations restrict their API to
+type IPAccess struct {
+	// CacheTTL is how long an instance keeps an organization's rules before reading them again,
+	// which bounds how long changes take to reach other instances
+	CacheTTL time.Duration `default:"30s"`
+}
+
+// Egress shapes the bandwidth each organization's media is served at, per instance
+type Egress struct {
+	// DefaultLimit is the bytes per second of organizations without a limit of their own; 0 is
+	// unlimited
+	DefaultLimit int64 `default:"0"`
+	// Burst is how many bytes an organization may send at once after sending less than its limit
+	Burst int `default:"1048576"`
+	// CacheTTL is how long an instance keeps an organization's limit before reading it again
+	CacheTTL time.Duration `default:"30s"`
+}
+
+// Encoders receives notifications from external encoders
+type Encoders struct {
+	// WebhookTolerance is how far a notification's signature time may be from now, which
+	// bounds how long a captured notification can be replayed
+	WebhookTolerance   time.Duration `default:"5m"`
+	WebhookMaxBodySize int64         `default:"1048576"`
+}
+
+// Transcode sends sources to an external transcoder, such as AWS MediaConvert, or hands them to
+// a streaming provider, such as Mux, instead of playing them as uploaded
+type Transcode struct {
+	// Provider is the transcoder of organizations without one of their own: mediaconvert, mux,
+	// cloudflare, or empty to leave sources as they are
+	Provider string
+	// PollInterval is how often submitted jobs are checked when no callback arrives first
+	PollInterval time.Duration `default:"30s"`
+	// CallbackToken authorizes MediaConvert's callbacks as a bearer token; empty disables them
+	CallbackToken string
+	// SourceURLExpiry is how long the storage URLs providers fetch sources from stay valid
+	SourceURLExpiry time.Duration `default:"6h"`
+	// Profile is the transcode profile of organizations without one of their own
+	Profile string `default:"standard"`
+	// Profiles name the codecs transcoders encode to besides H.264, as name=codec+codec entries
+	// of h264, hevc and av1. The standard profile, H.264 alone, always exists.
+	Profiles []string `default:"efficient=h264+hevc,modern=h264+hevc+av1"`
+
+	MediaConvertRegion string `default:"us-east-1"`
+	// MediaConvertEndpoint overrides the regional endpoint, e.g. for an account-specific one
+	MediaConvertEndpoint        string
+	MediaConvertAccessKeyID     string
+	MediaConvertSecretAccessKey string
+	// MediaConvertRoleARN is the IAM role MediaConvert assumes to read sources and write outputs
+	MediaConvertRoleARN string
+	MediaConvertQueue   string
+	// MediaConvertInputBucket holds the sources; it defaults to S3_BUCKET
+	MediaConvertInputBucket  string
+	MediaConvertOutputBucket string
+	MediaConvertOutputPrefix string `default:"transcodes/"`
+	// MediaConvertOutputBaseURL is where the output bucket is served from, e.g. a CDN in front of it
+	MediaConvertOutputBaseURL string
+	// MediaConvertHeights are the heights of the HLS ladder
+	MediaConvertHeights []int `default:"1080,720,480,360"`
+
+	MuxTokenID     string
+	MuxTokenSecret string
+	// MuxWebhookSecret is the signing secret of the Mux webhook; empty disables Mux callbacks
+	MuxWebhookSecret string
+
+	CloudflareStreamAccountID string
+	CloudflareStreamAPIToken  string
+	// CloudflareStreamWebhookSecret is the secret Cloudflare returned when the webhook URL was
+	// set; empty disables Cloudflare Stream callbacks
+	CloudflareStreamWebhookSecret string
+}
+
+// Probe inspects video sources with ffprobe for their color signaling, so HDR sources keep
+// their dynamic range through transcoding
+type Probe struct {
+	// FFprobePath is the ffprobe command; probing is off when it is empty or cannot be found
+	FFprobePath string        `default:"ffprobe"`
+	Timeout     time.Duration `default:"2m"`
+}
+
+// Downloads renders the files members download from videos, with ffmpeg
+type Downloads struct {
+	// FFmpegPath is the ffmpeg command; downloads are off when it is empty or cannot be found
+	FFmpegPath string        `default:"ffmpeg"`
+	Timeout    time.Duration `default:"30m"`
+	// LinkTTL is how long a download link stays valid
+	LinkTTL time.Duration `default:"1h"`
+	// Retention is how long a rendered file is kept, and links to it can be issued
+	Retention time.Duration `default:"168h"`
+}
+
+// Contract checks responses against the OpenAPI document in docs
+type Contract struct {
+	// Check is off, log to log and count violations, or enforce to also answer them with a
+	// 500, which is meant for CI and staging
+	Check string `default:"off"`
+}
+
+type Images struct {
+	MaxUploadSize int64 `default:"10485760"`
+	// MaxPixels rejects images whose decoded size would exhaust memory
+	MaxPixels int `default:"40000000"`
+	Quality   int `default:"82"`
+	// Formats are produced besides JPEG (PNG for transparent images); webp needs cwebp and avif needs avifenc
+	Formats         []string      `default:"webp,avif"`
+	CWebPPath       string        `default:"cwebp"`
+	AVIFEncPath     string        `default:"avifenc"`
+	EncodeTimeout   time.Duration `default:"30s"`
+	ThumbnailWidths []int         `default:"320,640,1280"`
+	// AvatarSizes are the edge lengths of the square avatar variants
+	AvatarSizes  []int `default:"64,128,256,512"`
+	BannerWidths []int `default:"640,1280,1920"`
+	// ArtworkWidths are the widths of 16:9 series artwork
+	ArtworkWidths []int `default:"640,1280,1920"`
+}
+
+// Leader configures the election of the worker process running periodic routines
+type Leader struct {
+	// CheckInterval is how often followers try to take over and the leader checks its lock
+	CheckInterval time.Duration `default:"10s"`
+}
+
+// Scheduler configures the recurring maintenance tasks the leader runs
+type Scheduler struct {
+	// CheckInterval is how often the leader looks for due tasks, and so how late a task may start
+	CheckInterval time.Duration `default:"15s"`
+	// TaskTimeout bounds a run of tasks that set no timeout of their own
+	TaskTimeout time.Duration `default:"30m"`
+}
+
+type Jobs struct {
+	Workers      int           `default:"4"`
+	PollInterval time.Duration `default:"1s"`
+	// Lease is how long a claimed job stays reserved without a heartbeat before another worker may take it
+	Lease      time.Duration `default:"2m"`
+	RetryDelay time.Duration `default:"30s"`
+	// MaxRetryDelay caps the backoff between attempts
+	MaxRetryDelay time.Duration `default:"1h"`
+	// QuarantineAfter is how many failed runs, across manual retries, quarantine a job; 0 never does
+	QuarantineAfter int `default:"10"`
+}
+
+type Import struct {
+	// AllowHTTP also accepts plain http:// source URLs
+	AllowHTTP    bool          `default:"false"`
+	Timeout      time.Duration `default:"2h"`
+	TempDir      string
+	MaxRedirects int `default:"5"`
+	// BulkMaxInFlight caps the queued and running imports a bulk import keeps at once
+	BulkMaxInFlight     int   `default:"10"`
+	BulkManifestMaxSize int64 `default:"10485760"`
+}
+
+type Bootstrap struct {
+	Timeout            time.Duration `default:"60s"`
+	RequireRedis       bool          `default:"true"`
+	RequiredExtensions []string      `default:"pgcrypto"`
+}
+
+type Config struct {
+	Database    Database
+	Redis       Redis
+	Storage     Storage
+	Health      Health
+	Bootstrap   Bootstrap
+	Compression Compression
+	CORS        CORS
+	Security    Security
+	Playback    Playback
+	CDN         CDN
+	Cache       Cache
+	Feeds       Feeds
+	Email       Email
+	Push        Push
+	Events      Events
+	Admin       Admin
+	Flags       Flags
+	Maintenance Maintenance
+	Beacon      Beacon
+	ClickHouse  ClickHouse
+	AccessLog   AccessLog
+	Errors      ErrorTracking
+	Recorder    FlightRecorder
+	Usage       Usage
+	Moderation  Moderation
+	Regions     Regions
+	Server      Server
+	TLS         TLS
+	Routes      Routes
+	Contract    Contract
+	Faults      Faults
+	Auth        Auth
+	IPAccess    IPAccess
+	Egress      Egress
+	Encoders    Encoders
+	Transcode   Transcode
+	Probe       Probe
+	Downloads   Downloads
+	Images      Images
+	Jobs        Jobs
+	Leader      Leader
+	Scheduler   Scheduler
+	Import      Import
 }
 
 func Load() *Config {
@@ -53,7 +698,7 @@ func Load() *Config {
 		fmt.Printf("Warning: Could not load environment variables: %v\n", err)
 	}
 
-	return &Config{
+	cfg := &Config{
 		Database: Database{
 			Host:     getEnvWithKoanf(k, "DB_HOST", "DB_HOST", "localhost"),
 			Port:     getEnvWithKoanf(k, "DB_PORT", "DB_PORT", "5432"),
@@ -69,6 +714,23 @@ func Load() *Config {
 
 			MaxTenantPools:  getIntWithKoanf(k, "DB_MAX_TENANT_POOLS", "DB_MAX_TENANT_POOLS", 50),
 			PoolIdleTimeout: getDurationWithKoanf(k, "DB_POOL_IDLE_TIMEOUT", "DB_POOL_IDLE_TIMEOUT", 10*time.Minute),
+
+			WarmupConns:      getIntWithKoanf(k, "DB_WARMUP_CONNS", "DB_WARMUP_CONNS", 5),
+			AdaptivePool:     getBoolWithKoanf(k, "DB_ADAPTIVE_POOL", "DB_ADAPTIVE_POOL", true),
+			AdaptiveInterval: getDurationWithKoanf(k, "DB_ADAPTIVE_INTERVAL", "DB_ADAPTIVE_INTERVAL", 30*time.Second),
+			MinIdleConns:     getIntWithKoanf(k, "DB_MIN_IDLE_CONNS", "DB_MIN_IDLE_CONNS", 2),
+
+			PrimaryDSNs:           getListWithKoanf(k, "DB_PRIMARY_DSNS", "DB_PRIMARY_DSNS"),
+			FailoverCheckInterval: getDurationWithKoanf(k, "DB_FAILOVER_CHECK_INTERVAL", "DB_FAILOVER_CHECK_INTERVAL", 5*time.Second),
+			FailoverThreshold:     getIntWithKoanf(k, "DB_FAILOVER_THRESHOLD", "DB_FAILOVER_THRESHOLD", 3),
+
+			PgBouncerMode: getBoolWithKoanf(k, "DB_PGBOUNCER_MODE", "DB_PGBOUNCER_MODE", false),
+
+			SlowQueryThreshold: getDurationWithKoanf(k, "DB_SLOW_QUERY_THRESHOLD", "DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+			StatementCacheSize: getIntWithKoanf(k, "DB_STATEMENT_CACHE_SIZE", "DB_STATEMENT_CACHE_SIZE", 100),
+
+			CountCacheTTL:          getDurationWithKoanf(k, "DB_COUNT_CACHE_TTL", "DB_COUNT_CACHE_TTL", time.Minute),
+			CountEstimateThreshold: getInt64WithKoanf(k, "DB_COUNT_ESTIMATE_THRESHOLD", "DB_COUNT_ESTIMATE_THRESHOLD", 0),
 		},
 		Redis: Redis{
 			Host:     getEnvWithKoanf(k, "REDIS_HOST", "REDIS_HOST", "localhost"),
@@ -76,7 +738,437 @@ func Load() *Config {
 			Password: getEnvWithKoanf(k, "REDIS_PASSWORD", "REDIS_PASSWORD", ""),
 			DB:       getIntWithKoanf(k, "REDIS_DB", "REDIS_DB", 0),
 		},
+		Storage: Storage{
+			Backend:   getEnvWithKoanf(k, "STORAGE_BACKEND", "STORAGE_BACKEND", "local"),
+			LocalPath: getEnvWithKoanf(k, "STORAGE_LOCAL_PATH", "STORAGE_LOCAL_PATH", "./data/storage"),
+
+			S3Bucket:          getEnvWithKoanf(k, "S3_BUCKET", "S3_BUCKET", ""),
+			S3Region:          getEnvWithKoanf(k, "S3_REGION", "S3_REGION", "us-east-1"),
+			S3Endpoint:        getEnvWithKoanf(k, "S3_ENDPOINT", "S3_ENDPOINT", ""),
+			S3AccessKeyID:     getEnvWithKoanf(k, "S3_ACCESS_KEY_ID", "S3_ACCESS_KEY_ID", ""),
+			S3SecretAccessKey: getEnvWithKoanf(k, "S3_SECRET_ACCESS_KEY", "S3_SECRET_ACCESS_KEY", ""),
+			S3ForcePathStyle:  getBoolWithKoanf(k, "S3_FORCE_PATH_STYLE", "S3_FORCE_PATH_STYLE", false),
+
+			PresignExpiry:     getDurationWithKoanf(k, "STORAGE_PRESIGN_EXPIRY", "STORAGE_PRESIGN_EXPIRY", time.Hour),
+			MultipartPartSize: getInt64WithKoanf(k, "STORAGE_MULTIPART_PART_SIZE", "STORAGE_MULTIPART_PART_SIZE", 64<<20),
+			MaxUploadSize:     getInt64WithKoanf(k, "STORAGE_MAX_UPLOAD_SIZE", "STORAGE_MAX_UPLOAD_SIZE", 100<<30),
+
+			ArchiveStorageClass:    getEnvWithKoanf(k, "STORAGE_ARCHIVE_CLASS", "STORAGE_ARCHIVE_CLASS", "GLACIER"),
+			ArchiveSourceAfter:     getDurationWithKoanf(k, "STORAGE_ARCHIVE_SOURCE_AFTER", "STORAGE_ARCHIVE_SOURCE_AFTER", 30*24*time.Hour),
+			ArchiveRenditionsAfter: getDurationWithKoanf(k, "STORAGE_ARCHIVE_RENDITIONS_AFTER", "STORAGE_ARCHIVE_RENDITIONS_AFTER", 90*24*time.Hour),
+			RestoreDays:            getIntWithKoanf(k, "STORAGE_RESTORE_DAYS", "STORAGE_RESTORE_DAYS", 7),
+			RestoreTier:            getEnvWithKoanf(k, "STORAGE_RESTORE_TIER", "STORAGE_RESTORE_TIER", "Standard"),
+			LifecycleInterval:      getDurationWithKoanf(k, "STORAGE_LIFECYCLE_INTERVAL", "STORAGE_LIFECYCLE_INTERVAL", time.Hour),
+
+			DuplicatePolicy: getEnvWithKoanf(k, "STORAGE_DUPLICATE_POLICY", "STORAGE_DUPLICATE_POLICY", "warn"),
+			HashWorkers:     getIntWithKoanf(k, "STORAGE_HASH_WORKERS", "STORAGE_HASH_WORKERS", 4),
+
+			SourceVersionRetention: getDurationWithKoanf(k, "STORAGE_SOURCE_VERSION_RETENTION", "STORAGE_SOURCE_VERSION_RETENTION", 30*24*time.Hour),
+			SourceVersionsMax:      getIntWithKoanf(k, "STORAGE_SOURCE_VERSIONS_MAX", "STORAGE_SOURCE_VERSIONS_MAX", 5),
+		},
+		Health: Health{
+			Interval:       getDurationWithKoanf(k, "HEALTH_CHECK_INTERVAL", "HEALTH_CHECK_INTERVAL", 10*time.Second),
+			Timeout:        getDurationWithKoanf(k, "HEALTH_CHECK_TIMEOUT", "HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			StorageTimeout: getDurationWithKoanf(k, "HEALTH_STORAGE_TIMEOUT", "HEALTH_STORAGE_TIMEOUT", 5*time.Second),
+		},
+		Bootstrap: Bootstrap{
+			Timeout:            getDurationWithKoanf(k, "BOOTSTRAP_TIMEOUT", "BOOTSTRAP_TIMEOUT", time.Minute),
+			RequireRedis:       getBoolWithKoanf(k, "BOOTSTRAP_REQUIRE_REDIS", "BOOTSTRAP_REQUIRE_REDIS", true),
+			RequiredExtensions: requiredExtensions(k),
+		},
+		Compression: Compression{
+			Enabled:      getBoolWithKoanf(k, "COMPRESSION_ENABLED", "COMPRESSION_ENABLED", true),
+			MinSize:      getIntWithKoanf(k, "COMPRESSION_MIN_SIZE", "COMPRESSION_MIN_SIZE", 1024),
+			ExcludePaths: compressionExcludePaths(k),
+		},
+		CORS: CORS{
+			AllowedOrigins:     getListWithKoanf(k, "CORS_ALLOWED_ORIGINS", "CORS_ALLOWED_ORIGINS"),
+			AllowedMethods:     getListWithDefault(k, "CORS_ALLOWED_METHODS", "CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}),
+			AllowedHeaders:     getListWithDefault(k, "CORS_ALLOWED_HEADERS", "CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Authorization", "X-User-ID", "X-Org-ID", "X-Timezone", "If-Match", "If-None-Match"}),
+			ExposedHeaders:     getListWithDefault(k, "CORS_EXPOSED_HEADERS", "CORS_EXPOSED_HEADERS", []string{"Content-Length", "ETag", "X-Request-ID"}),
+			AllowCredentials:   getBoolWithKoanf(k, "CORS_ALLOW_CREDENTIALS", "CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:             getDurationWithKoanf(k, "CORS_MAX_AGE", "CORS_MAX_AGE", 12*time.Hour),
+			PlaybackDomainsTTL: getDurationWithKoanf(k, "CORS_PLAYBACK_DOMAINS_TTL", "CORS_PLAYBACK_DOMAINS_TTL", time.Minute),
+		},
+		Security: Security{
+			Headers:               getBoolWithKoanf(k, "SECURITY_HEADERS_ENABLED", "SECURITY_HEADERS_ENABLED", true),
+			HSTSMaxAge:            getDurationWithKoanf(k, "SECURITY_HSTS_MAX_AGE", "SECURITY_HSTS_MAX_AGE", 365*24*time.Hour),
+			HSTSIncludeSubdomains: getBoolWithKoanf(k, "SECURITY_HSTS_INCLUDE_SUBDOMAINS", "SECURITY_HSTS_INCLUDE_SUBDOMAINS", true),
+		},
+		Playback: Playback{
+			SigningKey:           getEnvWithKoanf(k, "PLAYBACK_SIGNING_KEY", "PLAYBACK_SIGNING_KEY", ""),
+			TokenTTL:             getDurationWithKoanf(k, "PLAYBACK_TOKEN_TTL", "PLAYBACK_TOKEN_TTL", time.Hour),
+			PublicURL:            strings.TrimSuffix(getEnvWithKoanf(k, "PUBLIC_URL", "PUBLIC_URL", ""), "/"),
+			ProviderName:         getEnvWithKoanf(k, "PLAYBACK_PROVIDER_NAME", "PLAYBACK_PROVIDER_NAME", "OpenVDO"),
+			HLSJSURL:             getEnvWithKoanf(k, "PLAYBACK_HLSJS_URL", "PLAYBACK_HLSJS_URL", "https://cdn.jsdelivr.net/npm/hls.js@1/dist/hls.min.js"),
+			SessionTokenTTL:      getDurationWithKoanf(k, "PLAYBACK_SESSION_TOKEN_TTL", "PLAYBACK_SESSION_TOKEN_TTL", 2*time.Minute),
+			HeartbeatInterval:    getDurationWithKoanf(k, "PLAYBACK_HEARTBEAT_INTERVAL", "PLAYBACK_HEARTBEAT_INTERVAL", 30*time.Second),
+			SessionTimeout:       getDurationWithKoanf(k, "PLAYBACK_SESSION_TIMEOUT", "PLAYBACK_SESSION_TIMEOUT", 90*time.Second),
+			MaxConcurrentStreams: getIntWithKoanf(k, "PLAYBACK_MAX_CONCURRENT_STREAMS", "PLAYBACK_MAX_CONCURRENT_STREAMS", 0),
+			ProxyMedia:           getBoolWithKoanf(k, "PLAYBACK_PROXY_MEDIA", "PLAYBACK_PROXY_MEDIA", false),
+		},
+		CDN: CDN{
+			PurgeURL:   getEnvWithKoanf(k, "CDN_PURGE_URL", "CDN_PURGE_URL", ""),
+			PurgeToken: getEnvWithKoanf(k, "CDN_PURGE_TOKEN", "CDN_PURGE_TOKEN", ""),
+			Timeout:    getDurationWithKoanf(k, "CDN_PURGE_TIMEOUT", "CDN_PURGE_TIMEOUT", 10*time.Second),
+		},
+		Cache: Cache{
+			ImmutableMaxAge: getDurationWithKoanf(k, "CACHE_IMMUTABLE_MAX_AGE", "CACHE_IMMUTABLE_MAX_AGE", 365*24*time.Hour),
+			PlaylistMaxAge:  getDurationWithKoanf(k, "CACHE_PLAYLIST_MAX_AGE", "CACHE_PLAYLIST_MAX_AGE", 2*time.Second),
+			ImageMaxAge:     getDurationWithKoanf(k, "CACHE_IMAGE_MAX_AGE", "CACHE_IMAGE_MAX_AGE", 5*time.Minute),
+		},
+		Feeds: Feeds{
+			MaxItems:         getIntWithKoanf(k, "FEEDS_MAX_ITEMS", "FEEDS_MAX_ITEMS", 100),
+			CDNMaxAge:        getDurationWithKoanf(k, "FEEDS_CDN_MAX_AGE", "FEEDS_CDN_MAX_AGE", 24*time.Hour),
+			MaxAge:           getDurationWithKoanf(k, "FEEDS_MAX_AGE", "FEEDS_MAX_AGE", 5*time.Minute),
+			PlaylistCacheTTL: getDurationWithKoanf(k, "FEEDS_PLAYLIST_CACHE_TTL", "FEEDS_PLAYLIST_CACHE_TTL", time.Minute),
+		},
+		Email: Email{
+			SMTPHost:     getEnvWithKoanf(k, "SMTP_HOST", "SMTP_HOST", ""),
+			SMTPPort:     getIntWithKoanf(k, "SMTP_PORT", "SMTP_PORT", 587),
+			SMTPUsername: getEnvWithKoanf(k, "SMTP_USERNAME", "SMTP_USERNAME", ""),
+			SMTPPassword: getEnvWithKoanf(k, "SMTP_PASSWORD", "SMTP_PASSWORD", ""),
+			From:         getEnvWithKoanf(k, "EMAIL_FROM", "EMAIL_FROM", "OpenVDO <no-reply@localhost>"),
+			Timeout:      getDurationWithKoanf(k, "SMTP_TIMEOUT", "SMTP_TIMEOUT", 30*time.Second),
+		},
+		Push: Push{
+			VAPIDPublicKey:     getEnvWithKoanf(k, "PUSH_VAPID_PUBLIC_KEY", "PUSH_VAPID_PUBLIC_KEY", ""),
+			VAPIDPrivateKey:    getEnvWithKoanf(k, "PUSH_VAPID_PRIVATE_KEY", "PUSH_VAPID_PRIVATE_KEY", ""),
+			VAPIDSubject:       getEnvWithKoanf(k, "PUSH_VAPID_SUBJECT", "PUSH_VAPID_SUBJECT", ""),
+			FCMCredentialsFile: getEnvWithKoanf(k, "PUSH_FCM_CREDENTIALS_FILE", "PUSH_FCM_CREDENTIALS_FILE", ""),
+			FCMProjectID:       getEnvWithKoanf(k, "PUSH_FCM_PROJECT_ID", "PUSH_FCM_PROJECT_ID", ""),
+			APNsKeyFile:        getEnvWithKoanf(k, "PUSH_APNS_KEY_FILE", "PUSH_APNS_KEY_FILE", ""),
+			APNsKeyID:          getEnvWithKoanf(k, "PUSH_APNS_KEY_ID", "PUSH_APNS_KEY_ID", ""),
+			APNsTeamID:         getEnvWithKoanf(k, "PUSH_APNS_TEAM_ID", "PUSH_APNS_TEAM_ID", ""),
+			APNsTopic:          getEnvWithKoanf(k, "PUSH_APNS_TOPIC", "PUSH_APNS_TOPIC", ""),
+			APNsSandbox:        getBoolWithKoanf(k, "PUSH_APNS_SANDBOX", "PUSH_APNS_SANDBOX", false),
+			TTL:                getDurationWithKoanf(k, "PUSH_TTL", "PUSH_TTL", 24*time.Hour),
+			Timeout:            getDurationWithKoanf(k, "PUSH_TIMEOUT", "PUSH_TIMEOUT", 10*time.Second),
+		},
+		Events: Events{
+			RelayInterval:      getDurationWithKoanf(k, "EVENTS_RELAY_INTERVAL", "EVENTS_RELAY_INTERVAL", time.Second),
+			BatchSize:          getIntWithKoanf(k, "EVENTS_BATCH_SIZE", "EVENTS_BATCH_SIZE", 100),
+			RetryDelay:         getDurationWithKoanf(k, "EVENTS_RETRY_DELAY", "EVENTS_RETRY_DELAY", 5*time.Second),
+			Retention:          getDurationWithKoanf(k, "EVENTS_RETENTION", "EVENTS_RETENTION", 7*24*time.Hour),
+			RedisChannel:       getEnvWithKoanf(k, "EVENTS_REDIS_CHANNEL", "EVENTS_REDIS_CHANNEL", "openvdo:events"),
+			WebhookTimeout:     getDurationWithKoanf(k, "WEBHOOK_TIMEOUT", "WEBHOOK_TIMEOUT", 10*time.Second),
+			WebhookMaxAttempts: getIntWithKoanf(k, "WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_MAX_ATTEMPTS", 8),
+		},
+		Admin: Admin{
+			APIToken: getEnvWithKoanf(k, "ADMIN_API_TOKEN", "ADMIN_API_TOKEN", ""),
+		},
+		Flags: Flags{
+			CacheTTL: getDurationWithKoanf(k, "FLAGS_CACHE_TTL", "FLAGS_CACHE_TTL", 10*time.Second),
+		},
+		Maintenance: Maintenance{
+			Enabled:      getBoolWithKoanf(k, "MAINTENANCE_MODE", "MAINTENANCE_MODE", false),
+			RetryAfter:   getDurationWithKoanf(k, "MAINTENANCE_RETRY_AFTER", "MAINTENANCE_RETRY_AFTER", time.Minute),
+			PollInterval: getDurationWithKoanf(k, "MAINTENANCE_POLL_INTERVAL", "MAINTENANCE_POLL_INTERVAL", 5*time.Second),
+		},
+		Beacon: Beacon{
+			Enabled:       getBoolWithKoanf(k, "BEACON_ENABLED", "BEACON_ENABLED", true),
+			SampleRate:    getFractionWithKoanf(k, "BEACON_SAMPLE_RATE", "BEACON_SAMPLE_RATE", 1),
+			MaxEvents:     getIntWithKoanf(k, "BEACON_MAX_EVENTS", "BEACON_MAX_EVENTS", 100),
+			MaxBodySize:   getInt64WithKoanf(k, "BEACON_MAX_BODY_SIZE", "BEACON_MAX_BODY_SIZE", 64<<10),
+			BufferSize:    getIntWithKoanf(k, "BEACON_BUFFER_SIZE", "BEACON_BUFFER_SIZE", 10000),
+			BatchSize:     getIntWithKoanf(k, "BEACON_BATCH_SIZE", "BEACON_BATCH_SIZE", 500),
+			FlushInterval: getDurationWithKoanf(k, "BEACON_FLUSH_INTERVAL", "BEACON_FLUSH_INTERVAL", 2*time.Second),
+			WriteTimeout:  getDurationWithKoanf(k, "BEACON_WRITE_TIMEOUT", "BEACON_WRITE_TIMEOUT", 10*time.Second),
+		},
+		ClickHouse: ClickHouse{
+			URL:       strings.TrimSuffix(getEnvWithKoanf(k, "CLICKHOUSE_URL", "CLICKHOUSE_URL", ""), "/"),
+			Database:  getEnvWithKoanf(k, "CLICKHOUSE_DATABASE", "CLICKHOUSE_DATABASE", "default"),
+			Table:     getEnvWithKoanf(k, "CLICKHOUSE_TABLE", "CLICKHOUSE_TABLE", "playback_events"),
+			User:      getEnvWithKoanf(k, "CLICKHOUSE_USER", "CLICKHOUSE_USER", ""),
+			Password:  getEnvWithKoanf(k, "CLICKHOUSE_PASSWORD", "CLICKHOUSE_PASSWORD", ""),
+			Timeout:   getDurationWithKoanf(k, "CLICKHOUSE_TIMEOUT", "CLICKHOUSE_TIMEOUT", 10*time.Second),
+			Retention: getDurationWithKoanf(k, "CLICKHOUSE_RETENTION", "CLICKHOUSE_RETENTION", 365*24*time.Hour),
+		},
+		AccessLog: AccessLog{
+			Enabled:          getBoolWithKoanf(k, "ACCESS_LOG_ENABLED", "ACCESS_LOG_ENABLED", true),
+			IPMode:           getEnvWithKoanf(k, "ACCESS_LOG_IP_MODE", "ACCESS_LOG_IP_MODE", "truncate"),
+			IPv4PrefixBits:   getIntWithKoanf(k, "ACCESS_LOG_IPV4_PREFIX_BITS", "ACCESS_LOG_IPV4_PREFIX_BITS", 24),
+			IPv6PrefixBits:   getIntWithKoanf(k, "ACCESS_LOG_IPV6_PREFIX_BITS", "ACCESS_LOG_IPV6_PREFIX_BITS", 48),
+			IPHashKey:        getEnvWithKoanf(k, "ACCESS_LOG_IP_HASH_KEY", "ACCESS_LOG_IP_HASH_KEY", ""),
+			SampleRate:       getFractionWithKoanf(k, "ACCESS_LOG_SAMPLE_RATE", "ACCESS_LOG_SAMPLE_RATE", 1),
+			RouteSampleRates: getListWithKoanf(k, "ACCESS_LOG_ROUTE_SAMPLE_RATES", "ACCESS_LOG_ROUTE_SAMPLE_RATES"),
+			Stdout:           getBoolWithKoanf(k, "ACCESS_LOG_STDOUT", "ACCESS_LOG_STDOUT", true),
+			Sink:             getEnvWithKoanf(k, "ACCESS_LOG_SINK", "ACCESS_LOG_SINK", ""),
+			SyslogAddress:    getEnvWithKoanf(k, "ACCESS_LOG_SYSLOG_ADDRESS", "ACCESS_LOG_SYSLOG_ADDRESS", "udp://localhost:514"),
+			SyslogTag:        getEnvWithKoanf(k, "ACCESS_LOG_SYSLOG_TAG", "ACCESS_LOG_SYSLOG_TAG", "openvdo"),
+			LokiURL:          getEnvWithKoanf(k, "ACCESS_LOG_LOKI_URL", "ACCESS_LOG_LOKI_URL", ""),
+			LokiLabels:       getListWithDefault(k, "ACCESS_LOG_LOKI_LABELS", "ACCESS_LOG_LOKI_LABELS", []string{"job=openvdo"}),
+			LokiTenantID:     getEnvWithKoanf(k, "ACCESS_LOG_LOKI_TENANT_ID", "ACCESS_LOG_LOKI_TENANT_ID", ""),
+			LokiUsername:     getEnvWithKoanf(k, "ACCESS_LOG_LOKI_USERNAME", "ACCESS_LOG_LOKI_USERNAME", ""),
+			LokiPassword:     getEnvWithKoanf(k, "ACCESS_LOG_LOKI_PASSWORD", "ACCESS_LOG_LOKI_PASSWORD", ""),
+			BufferSize:       getIntWithKoanf(k, "ACCESS_LOG_BUFFER_SIZE", "ACCESS_LOG_BUFFER_SIZE", 10000),
+			BatchSize:        getIntWithKoanf(k, "ACCESS_LOG_BATCH_SIZE", "ACCESS_LOG_BATCH_SIZE", 500),
+			FlushInterval:    getDurationWithKoanf(k, "ACCESS_LOG_FLUSH_INTERVAL", "ACCESS_LOG_FLUSH_INTERVAL", time.Second),
+			WriteTimeout:     getDurationWithKoanf(k, "ACCESS_LOG_WRITE_TIMEOUT", "ACCESS_LOG_WRITE_TIMEOUT", 10*time.Second),
+		},
+		Errors: ErrorTracking{
+			SentryDSN:   getEnvWithKoanf(k, "SENTRY_DSN", "SENTRY_DSN", ""),
+			WebhookURL:  getEnvWithKoanf(k, "ERROR_TRACKING_WEBHOOK_URL", "ERROR_TRACKING_WEBHOOK_URL", ""),
+			Environment: getEnvWithKoanf(k, "ERROR_TRACKING_ENVIRONMENT", "ERROR_TRACKING_ENVIRONMENT", "production"),
+			Release:     getEnvWithKoanf(k, "ERROR_TRACKING_RELEASE", "ERROR_TRACKING_RELEASE", ""),
+			SampleRate:  getFractionWithKoanf(k, "ERROR_TRACKING_SAMPLE_RATE", "ERROR_TRACKING_SAMPLE_RATE", 1),
+			BufferSize:  getIntWithKoanf(k, "ERROR_TRACKING_BUFFER_SIZE", "ERROR_TRACKING_BUFFER_SIZE", 100),
+			Timeout:     getDurationWithKoanf(k, "ERROR_TRACKING_TIMEOUT", "ERROR_TRACKING_TIMEOUT", 5*time.Second),
+		},
+		Recorder: FlightRecorder{
+			Enabled:     getBoolWithKoanf(k, "FLIGHT_RECORDER_ENABLED", "FLIGHT_RECORDER_ENABLED", false),
+			Capacity:    getIntWithKoanf(k, "FLIGHT_RECORDER_CAPACITY", "FLIGHT_RECORDER_CAPACITY", 200),
+			MaxBodySize: getIntWithKoanf(k, "FLIGHT_RECORDER_MAX_BODY_SIZE", "FLIGHT_RECORDER_MAX_BODY_SIZE", 4096),
+			MaxQueries:  getIntWithKoanf(k, "FLIGHT_RECORDER_MAX_QUERIES", "FLIGHT_RECORDER_MAX_QUERIES", 100),
+			MaxDuration: getDurationWithKoanf(k, "FLIGHT_RECORDER_MAX_DURATION", "FLIGHT_RECORDER_MAX_DURATION", time.Hour),
+		},
+		Usage: Usage{
+			Enabled:       getBoolWithKoanf(k, "USAGE_ENABLED", "USAGE_ENABLED", true),
+			FlushInterval: getDurationWithKoanf(k, "USAGE_FLUSH_INTERVAL", "USAGE_FLUSH_INTERVAL", time.Minute),
+		},
+		Moderation: Moderation{
+			ReportThreshold:   getIntWithKoanf(k, "MODERATION_REPORT_THRESHOLD", "MODERATION_REPORT_THRESHOLD", 3),
+			CounterNoticeWait: getDurationWithKoanf(k, "MODERATION_COUNTER_NOTICE_WAIT", "MODERATION_COUNTER_NOTICE_WAIT", 14*24*time.Hour),
+			ScanProvider:      getEnvWithKoanf(k, "MODERATION_SCAN_PROVIDER", "MODERATION_SCAN_PROVIDER", ""),
+			ScanURL:           getEnvWithKoanf(k, "MODERATION_SCAN_URL", "MODERATION_SCAN_URL", ""),
+			ScanToken:         getEnvWithKoanf(k, "MODERATION_SCAN_TOKEN", "MODERATION_SCAN_TOKEN", ""),
+			ScanFrames:        getIntWithKoanf(k, "MODERATION_SCAN_FRAMES", "MODERATION_SCAN_FRAMES", 8),
+			ScanAudioSeconds:  getIntWithKoanf(k, "MODERATION_SCAN_AUDIO_SECONDS", "MODERATION_SCAN_AUDIO_SECONDS", 30),
+			ScanThreshold:     getFractionWithKoanf(k, "MODERATION_SCAN_THRESHOLD", "MODERATION_SCAN_THRESHOLD", 0.8),
+			ScanRestrict:      getBoolWithKoanf(k, "MODERATION_SCAN_RESTRICT", "MODERATION_SCAN_RESTRICT", false),
+			ScanTimeout:       getDurationWithKoanf(k, "MODERATION_SCAN_TIMEOUT", "MODERATION_SCAN_TIMEOUT", 5*time.Minute),
+			FFmpegPath:        getEnvWithKoanf(k, "MODERATION_FFMPEG_PATH", "MODERATION_FFMPEG_PATH", "ffmpeg"),
+			FFprobePath:       getEnvWithKoanf(k, "MODERATION_FFPROBE_PATH", "MODERATION_FFPROBE_PATH", "ffprobe"),
+		},
+		TLS: TLS{
+			CertFile:         getEnvWithKoanf(k, "TLS_CERT_FILE", "TLS_CERT_FILE", ""),
+			KeyFile:          getEnvWithKoanf(k, "TLS_KEY_FILE", "TLS_KEY_FILE", ""),
+			AutocertHosts:    getListWithKoanf(k, "TLS_AUTOCERT_HOSTS", "TLS_AUTOCERT_HOSTS"),
+			AutocertEmail:    getEnvWithKoanf(k, "TLS_AUTOCERT_EMAIL", "TLS_AUTOCERT_EMAIL", ""),
+			AutocertCacheDir: getEnvWithKoanf(k, "TLS_AUTOCERT_CACHE_DIR", "TLS_AUTOCERT_CACHE_DIR", "autocert"),
+			AutocertHTTPAddr: getEnvWithKoanf(k, "TLS_AUTOCERT_HTTP_ADDR", "TLS_AUTOCERT_HTTP_ADDR", ""),
+			ClientCAFile:     getEnvWithKoanf(k, "TLS_CLIENT_CA_FILE", "TLS_CLIENT_CA_FILE", ""),
+			HTTP2:            getBoolWithKoanf(k, "HTTP2_ENABLED", "HTTP2_ENABLED", true),
+			HTTP2Cleartext:   getBoolWithKoanf(k, "HTTP2_CLEARTEXT", "HTTP2_CLEARTEXT", false),
+		},
+		Routes: Routes{
+			Timeout:       getDurationWithKoanf(k, "ROUTE_TIMEOUT", "ROUTE_TIMEOUT", time.Minute),
+			MaxBodySize:   getInt64WithKoanf(k, "ROUTE_MAX_BODY_SIZE", "ROUTE_MAX_BODY_SIZE", 1<<20),
+			UploadTimeout: getDurationWithKoanf(k, "ROUTE_UPLOAD_TIMEOUT", "ROUTE_UPLOAD_TIMEOUT", 2*time.Hour),
+			RateLimits:    getListWithKoanf(k, "RATE_LIMITS", "RATE_LIMITS"),
+		},
+		Server: Server{
+			Listen:           getEnvWithKoanf(k, "SERVER_LISTEN", "SERVER_LISTEN", "tcp"),
+			Port:             getEnvWithKoanf(k, "PORT", "PORT", "8080"),
+			UnixSocket:       getEnvWithKoanf(k, "UNIX_SOCKET", "UNIX_SOCKET", ""),
+			UnixSocketMode:   getEnvWithKoanf(k, "UNIX_SOCKET_MODE", "UNIX_SOCKET_MODE", "0660"),
+			UnixSocketGroup:  getEnvWithKoanf(k, "UNIX_SOCKET_GROUP", "UNIX_SOCKET_GROUP", ""),
+			AdminAddr:        getEnvWithKoanf(k, "ADMIN_LISTEN_ADDR", "ADMIN_LISTEN_ADDR", ""),
+			MetricsAddr:      getEnvWithKoanf(k, "METRICS_LISTEN_ADDR", "METRICS_LISTEN_ADDR", ""),
+			Pprof:            getBoolWithKoanf(k, "PPROF_ENABLED", "PPROF_ENABLED", false),
+			ShutdownTimeout:  getDurationWithKoanf(k, "SHUTDOWN_TIMEOUT", "SHUTDOWN_TIMEOUT", 30*time.Second),
+			DrainDelay:       getDurationWithKoanf(k, "DRAIN_DELAY", "DRAIN_DELAY", 5*time.Second),
+			StreamDrainGrace: getDurationWithKoanf(k, "STREAM_DRAIN_GRACE", "STREAM_DRAIN_GRACE", 20*time.Second),
+		},
+		Contract: Contract{
+			Check: getEnvWithKoanf(k, "CONTRACT_CHECK", "CONTRACT_CHECK", "off"),
+		},
+		Faults: Faults{
+			Enabled:       getBoolWithKoanf(k, "FAULTS_ENABLED", "FAULTS_ENABLED", false),
+			ErrorRate:     getFractionWithKoanf(k, "FAULTS_ERROR_RATE", "FAULTS_ERROR_RATE", 0),
+			ErrorStatus:   getIntWithKoanf(k, "FAULTS_ERROR_STATUS", "FAULTS_ERROR_STATUS", 503),
+			RetryAfter:    getDurationWithKoanf(k, "FAULTS_RETRY_AFTER", "FAULTS_RETRY_AFTER", time.Second),
+			DBLatency:     getDurationWithKoanf(k, "FAULTS_DB_LATENCY", "FAULTS_DB_LATENCY", 0),
+			DBLatencyRate: getFractionWithKoanf(k, "FAULTS_DB_LATENCY_RATE", "FAULTS_DB_LATENCY_RATE", 0),
+			RedisDropRate: getFractionWithKoanf(k, "FAULTS_REDIS_DROP_RATE", "FAULTS_REDIS_DROP_RATE", 0),
+			Paths:         getListWithDefault(k, "FAULTS_PATHS", "FAULTS_PATHS", []string{"/api/"}),
+		},
+		Auth: Auth{
+			SessionTTL:           getDurationWithKoanf(k, "AUTH_SESSION_TTL", "AUTH_SESSION_TTL", 720*time.Hour),
+			SessionCacheTTL:      getDurationWithKoanf(k, "AUTH_SESSION_CACHE_TTL", "AUTH_SESSION_CACHE_TTL", 5*time.Minute),
+			SessionTouchInterval: getDurationWithKoanf(k, "AUTH_SESSION_TOUCH_INTERVAL", "AUTH_SESSION_TOUCH_INTERVAL", time.Minute),
+			LoginMaxFailures:     getIntWithKoanf(k, "AUTH_LOGIN_MAX_FAILURES", "AUTH_LOGIN_MAX_FAILURES", 5),
+			LoginLockout:         getDurationWithKoanf(k, "AUTH_LOGIN_LOCKOUT", "AUTH_LOGIN_LOCKOUT", 15*time.Minute),
+			LoginFailureWindow:   getDurationWithKoanf(k, "AUTH_LOGIN_FAILURE_WINDOW", "AUTH_LOGIN_FAILURE_WINDOW", 15*time.Minute),
+			LoginMaxIPFailures:   getIntWithKoanf(k, "AUTH_LOGIN_MAX_IP_FAILURES", "AUTH_LOGIN_MAX_IP_FAILURES", 50),
+			LoginDelay:           getDurationWithKoanf(k, "AUTH_LOGIN_DELAY", "AUTH_LOGIN_DELAY", 250*time.Millisecond),
+			LoginMaxDelay:        getDurationWithKoanf(k, "AUTH_LOGIN_MAX_DELAY", "AUTH_LOGIN_MAX_DELAY", 5*time.Second),
+		},
+		IPAccess: IPAccess{
+			CacheTTL: getDurationWithKoanf(k, "IP_ACCESS_CACHE_TTL", "IP_ACCESS_CACHE_TTL", 30*time.Second),
+		},
+		Egress: Egress{
+			DefaultLimit: getInt64WithKoanf(k, "EGRESS_DEFAULT_LIMIT", "EGRESS_DEFAULT_LIMIT", 0),
+			Burst:        getIntWithKoanf(k, "EGRESS_BURST", "EGRESS_BURST", 1<<20),
+			CacheTTL:     getDurationWithKoanf(k, "EGRESS_CACHE_TTL", "EGRESS_CACHE_TTL", 30*time.Second),
+		},
+		Encoders: Encoders{
+			WebhookTolerance:   getDurationWithKoanf(k, "ENCODER_WEBHOOK_TOLERANCE", "ENCODER_WEBHOOK_TOLERANCE", 5*time.Minute),
+			WebhookMaxBodySize: getInt64WithKoanf(k, "ENCODER_WEBHOOK_MAX_BODY_SIZE", "ENCODER_WEBHOOK_MAX_BODY_SIZE", 1<<20),
+		},
+		Transcode: Transcode{
+			Provider:                      getEnvWithKoanf(k, "TRANSCODE_PROVIDER", "TRANSCODE_PROVIDER", ""),
+			PollInterval:                  getDurationWithKoanf(k, "TRANSCODE_POLL_INTERVAL", "TRANSCODE_POLL_INTERVAL", 30*time.Second),
+			CallbackToken:                 getEnvWithKoanf(k, "TRANSCODE_CALLBACK_TOKEN", "TRANSCODE_CALLBACK_TOKEN", ""),
+			SourceURLExpiry:               getDurationWithKoanf(k, "TRANSCODE_SOURCE_URL_EXPIRY", "TRANSCODE_SOURCE_URL_EXPIRY", 6*time.Hour),
+			Profile:                       getEnvWithKoanf(k, "TRANSCODE_PROFILE", "TRANSCODE_PROFILE", "standard"),
+			Profiles:                      getListWithDefault(k, "TRANSCODE_PROFILES", "TRANSCODE_PROFILES", []string{"efficient=h264+hevc", "modern=h264+hevc+av1"}),
+			MediaConvertRegion:            getEnvWithKoanf(k, "MEDIACONVERT_REGION", "MEDIACONVERT_REGION", "us-east-1"),
+			MediaConvertEndpoint:          getEnvWithKoanf(k, "MEDIACONVERT_ENDPOINT", "MEDIACONVERT_ENDPOINT", ""),
+			MediaConvertAccessKeyID:       getEnvWithKoanf(k, "MEDIACONVERT_ACCESS_KEY_ID", "MEDIACONVERT_ACCESS_KEY_ID", ""),
+			MediaConvertSecretAccessKey:   getEnvWithKoanf(k, "MEDIACONVERT_SECRET_ACCESS_KEY", "MEDIACONVERT_SECRET_ACCESS_KEY", ""),
+			MediaConvertRoleARN:           getEnvWithKoanf(k, "MEDIACONVERT_ROLE_ARN", "MEDIACONVERT_ROLE_ARN", ""),
+			MediaConvertQueue:             getEnvWithKoanf(k, "MEDIACONVERT_QUEUE", "MEDIACONVERT_QUEUE", ""),
+			MediaConvertInputBucket:       getEnvWithKoanf(k, "MEDIACONVERT_INPUT_BUCKET", "MEDIACONVERT_INPUT_BUCKET", getEnvWithKoanf(k, "S3_BUCKET", "S3_BUCKET", "")),
+			MediaConvertOutputBucket:      getEnvWithKoanf(k, "MEDIACONVERT_OUTPUT_BUCKET", "MEDIACONVERT_OUTPUT_BUCKET", ""),
+			MediaConvertOutputPrefix:      getEnvWithKoanf(k, "MEDIACONVERT_OUTPUT_PREFIX", "MEDIACONVERT_OUTPUT_PREFIX", "transcodes/"),
+			MediaConvertOutputBaseURL:     getEnvWithKoanf(k, "MEDIACONVERT_OUTPUT_BASE_URL", "MEDIACONVERT_OUTPUT_BASE_URL", ""),
+			MediaConvertHeights:           getIntListWithDefault(k, "MEDIACONVERT_HEIGHTS", "MEDIACONVERT_HEIGHTS", []int{1080, 720, 480, 360}),
+			MuxTokenID:                    getEnvWithKoanf(k, "MUX_TOKEN_ID", "MUX_TOKEN_ID", ""),
+			MuxTokenSecret:                getEnvWithKoanf(k, "MUX_TOKEN_SECRET", "MUX_TOKEN_SECRET", ""),
+			MuxWebhookSecret:              getEnvWithKoanf(k, "MUX_WEBHOOK_SECRET", "MUX_WEBHOOK_SECRET", ""),
+			CloudflareStreamAccountID:     getEnvWithKoanf(k, "CLOUDFLARE_STREAM_ACCOUNT_ID", "CLOUDFLARE_STREAM_ACCOUNT_ID", ""),
+			CloudflareStreamAPIToken:      getEnvWithKoanf(k, "CLOUDFLARE_STREAM_API_TOKEN", "CLOUDFLARE_STREAM_API_TOKEN", ""),
+			CloudflareStreamWebhookSecret: getEnvWithKoanf(k, "CLOUDFLARE_STREAM_WEBHOOK_SECRET", "CLOUDFLARE_STREAM_WEBHOOK_SECRET", ""),
+		},
+		Probe: Probe{
+			FFprobePath: getEnvWithKoanf(k, "PROBE_FFPROBE_PATH", "PROBE_FFPROBE_PATH", "ffprobe"),
+			Timeout:     getDurationWithKoanf(k, "PROBE_TIMEOUT", "PROBE_TIMEOUT", 2*time.Minute),
+		},
+		Downloads: Downloads{
+			FFmpegPath: getEnvWithKoanf(k, "DOWNLOADS_FFMPEG_PATH", "DOWNLOADS_FFMPEG_PATH", "ffmpeg"),
+			Timeout:    getDurationWithKoanf(k, "DOWNLOADS_TIMEOUT", "DOWNLOADS_TIMEOUT", 30*time.Minute),
+			LinkTTL:    getDurationWithKoanf(k, "DOWNLOADS_LINK_TTL", "DOWNLOADS_LINK_TTL", time.Hour),
+			Retention:  getDurationWithKoanf(k, "DOWNLOADS_RETENTION", "DOWNLOADS_RETENTION", 7*24*time.Hour),
+		},
+		Images: Images{
+			MaxUploadSize:   getInt64WithKoanf(k, "IMAGES_MAX_UPLOAD_SIZE", "IMAGES_MAX_UPLOAD_SIZE", 10<<20),
+			MaxPixels:       getIntWithKoanf(k, "IMAGES_MAX_PIXELS", "IMAGES_MAX_PIXELS", 40000000),
+			Quality:         getIntWithKoanf(k, "IMAGES_QUALITY", "IMAGES_QUALITY", 82),
+			Formats:         getListWithDefault(k, "IMAGES_FORMATS", "IMAGES_FORMATS", []string{"webp", "avif"}),
+			CWebPPath:       getEnvWithKoanf(k, "IMAGES_CWEBP_PATH", "IMAGES_CWEBP_PATH", "cwebp"),
+			AVIFEncPath:     getEnvWithKoanf(k, "IMAGES_AVIFENC_PATH", "IMAGES_AVIFENC_PATH", "avifenc"),
+			EncodeTimeout:   getDurationWithKoanf(k, "IMAGES_ENCODE_TIMEOUT", "IMAGES_ENCODE_TIMEOUT", 30*time.Second),
+			ThumbnailWidths: getIntListWithDefault(k, "IMAGES_THUMBNAIL_WIDTHS", "IMAGES_THUMBNAIL_WIDTHS", []int{320, 640, 1280}),
+			AvatarSizes:     getIntListWithDefault(k, "IMAGES_AVATAR_SIZES", "IMAGES_AVATAR_SIZES", []int{64, 128, 256, 512}),
+			BannerWidths:    getIntListWithDefault(k, "IMAGES_BANNER_WIDTHS", "IMAGES_BANNER_WIDTHS", []int{640, 1280, 1920}),
+			ArtworkWidths:   getIntListWithDefault(k, "IMAGES_ARTWORK_WIDTHS", "IMAGES_ARTWORK_WIDTHS", []int{640, 1280, 1920}),
+		},
+		Jobs: Jobs{
+			Workers:         getIntWithKoanf(k, "JOBS_WORKERS", "JOBS_WORKERS", 4),
+			PollInterval:    getDurationWithKoanf(k, "JOBS_POLL_INTERVAL", "JOBS_POLL_INTERVAL", time.Second),
+			Lease:           getDurationWithKoanf(k, "JOBS_LEASE", "JOBS_LEASE", 2*time.Minute),
+			RetryDelay:      getDurationWithKoanf(k, "JOBS_RETRY_DELAY", "JOBS_RETRY_DELAY", 30*time.Second),
+			MaxRetryDelay:   getDurationWithKoanf(k, "JOBS_MAX_RETRY_DELAY", "JOBS_MAX_RETRY_DELAY", time.Hour),
+			QuarantineAfter: getIntWithKoanf(k, "JOBS_QUARANTINE_AFTER", "JOBS_QUARANTINE_AFTER", 10),
+		},
+		Leader: Leader{
+			CheckInterval: getDurationWithKoanf(k, "LEADER_CHECK_INTERVAL", "LEADER_CHECK_INTERVAL", 10*time.Second),
+		},
+		Scheduler: Scheduler{
+			CheckInterval: getDurationWithKoanf(k, "SCHEDULER_CHECK_INTERVAL", "SCHEDULER_CHECK_INTERVAL", 15*time.Second),
+			TaskTimeout:   getDurationWithKoanf(k, "SCHEDULER_TASK_TIMEOUT", "SCHEDULER_TASK_TIMEOUT", 30*time.Minute),
+		},
+		Import: Import{
+			AllowHTTP:           getBoolWithKoanf(k, "IMPORT_ALLOW_HTTP", "IMPORT_ALLOW_HTTP", false),
+			Timeout:             getDurationWithKoanf(k, "IMPORT_TIMEOUT", "IMPORT_TIMEOUT", 2*time.Hour),
+			TempDir:             getEnvWithKoanf(k, "IMPORT_TEMP_DIR", "IMPORT_TEMP_DIR", ""),
+			MaxRedirects:        getIntWithKoanf(k, "IMPORT_MAX_REDIRECTS", "IMPORT_MAX_REDIRECTS", 5),
+			BulkMaxInFlight:     getIntWithKoanf(k, "IMPORT_BULK_MAX_IN_FLIGHT", "IMPORT_BULK_MAX_IN_FLIGHT", 10),
+			BulkManifestMaxSize: getInt64WithKoanf(k, "IMPORT_BULK_MANIFEST_MAX_SIZE", "IMPORT_BULK_MANIFEST_MAX_SIZE", 10<<20),
+		},
+	}
+	cfg.Regions = loadRegions(k, cfg.Database, cfg.Storage)
+	return cfg
+}
+
+// loadRegions reads the clusters of the regions listed in REGIONS. Each region overrides the
+// connection settings of the home database and storage with REGION_<NAME>_-prefixed variables,
+// such as REGION_EU_DB_HOST or REGION_EU_S3_BUCKET.
+func loadRegions(k *koanf.Koanf, db Database, store Storage) Regions {
+	regions := Regions{
+		Home:      strings.ToLower(getEnvWithKoanf(k, "REGION", "REGION", "default")),
+		Clusters:  make(map[string]RegionCluster),
+		LookupTTL: getDurationWithKoanf(k, "REGION_LOOKUP_TTL", "REGION_LOOKUP_TTL", time.Minute),
+	}
+
+	for _, name := range getListWithKoanf(k, "REGIONS", "REGIONS") {
+		name = strings.ToLower(name)
+		if name == regions.Home {
+			continue
+		}
+		prefix := "REGION_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		get := func(key, defaultValue string) string {
+			return getEnvWithKoanf(k, prefix+key, prefix+key, defaultValue)
+		}
+
+		cluster := RegionCluster{Database: db, Storage: store}
+		cluster.Database.Host = get("DB_HOST", db.Host)
+		cluster.Database.Port = get("DB_PORT", db.Port)
+		cluster.Database.User = get("DB_USER", db.User)
+		cluster.Database.Password = get("DB_PASSWORD", db.Password)
+		cluster.Database.Name = get("DB_NAME", db.Name)
+		cluster.Database.SSLMode = get("DB_SSLMODE", db.SSLMode)
+		// The home primaries are never candidates for another region
+		cluster.Database.PrimaryDSNs = getListWithKoanf(k, prefix+"DB_PRIMARY_DSNS", prefix+"DB_PRIMARY_DSNS")
+
+		cluster.Storage.Backend = get("STORAGE_BACKEND", store.Backend)
+		cluster.Storage.LocalPath = get("STORAGE_LOCAL_PATH", store.LocalPath)
+		cluster.Storage.S3Bucket = get("S3_BUCKET", store.S3Bucket)
+		cluster.Storage.S3Region = get("S3_REGION", store.S3Region)
+		cluster.Storage.S3Endpoint = get("S3_ENDPOINT", store.S3Endpoint)
+		cluster.Storage.S3AccessKeyID = get("S3_ACCESS_KEY_ID", store.S3AccessKeyID)
+		cluster.Storage.S3SecretAccessKey = get("S3_SECRET_ACCESS_KEY", store.S3SecretAccessKey)
+
+		regions.Clusters[name] = cluster
+	}
+	return regions
+}
+
+// InRegion returns a copy of the configuration running in the given region: its database and
+// storage become the main ones and the home region becomes one of the clusters, so an instance
+// can be run next to a region's data
+func (c *Config) InRegion(name string) (*Config, error) {
+	name = strings.ToLower(name)
+	if name == c.Regions.LocalRegion() {
+		return c, nil
+	}
+	cluster, ok := c.Regions.Clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown region %q", name)
+	}
+
+	regional := *c
+	regional.Database = cluster.Database
+	regional.Storage = cluster.Storage
+	regional.Regions.Local = name
+	regional.Regions.Clusters = map[string]RegionCluster{
+		c.Regions.LocalRegion(): {Database: c.Database, Storage: c.Storage},
+	}
+	for other, otherCluster := range c.Regions.Clusters {
+		if other != name {
+			regional.Regions.Clusters[other] = otherCluster
+		}
+	}
+	return &regional, nil
+}
+
+// compressionExcludePaths always includes /metrics, which promhttp compresses itself
+func compressionExcludePaths(k *koanf.Koanf) []string {
+	return append([]string{"/metrics"}, getListWithKoanf(k, "COMPRESSION_EXCLUDE_PATHS", "COMPRESSION_EXCLUDE_PATHS")...)
+}
+
+// requiredExtensions defaults to pgcrypto, which the migrations use for gen_random_uuid
+func requiredExtensions(k *koanf.Koanf) []string {
+	return getListWithDefault(k, "BOOTSTRAP_REQUIRED_EXTENSIONS", "BOOTSTRAP_REQUIRED_EXTENSIONS", []string{"pgcrypto"})
+}
+
+// LocalRegion returns the region this instance runs in
+func (r *Regions) LocalRegion() string {
+	if r.Local != "" {
+		return r.Local
 	}
+	return r.Home
 }
 
 func (d *Database) DSN() string {
@@ -84,6 +1176,14 @@ func (d *Database) DSN() string {
 		d.Host, d.Port, d.User, d.Password, d.Name, d.SSLMode)
 }
 
+// PrimaryDSNList returns the candidate primary DSNs in priority order
+func (d *Database) PrimaryDSNList() []string {
+	if len(d.PrimaryDSNs) > 0 {
+		return d.PrimaryDSNs
+	}
+	return []string{d.DSN()}
+}
+
 func (r *Redis) Address() string {
 	return fmt.Sprintf("%s:%s", r.Host, r.Port)
 }
@@ -113,6 +1213,24 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil && intValue > 0 {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvWithKoanf(k *koanf.Koanf, envKey, koanfKey, defaultValue string) string {
 	if value := k.String(koanfKey); value != "" {
 		return value
@@ -120,6 +1238,46 @@ func getEnvWithKoanf(k *koanf.Koanf, envKey, koanfKey, defaultValue string) stri
 	return getEnv(envKey, defaultValue)
 }
 
+// getListWithKoanf reads a comma-separated list, ignoring empty entries
+func getListWithKoanf(k *koanf.Koanf, envKey, koanfKey string) []string {
+	if values := k.Strings(koanfKey); len(values) > 0 {
+		return values
+	}
+
+	var list []string
+	for _, item := range strings.Split(getEnvWithKoanf(k, envKey, koanfKey, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getListWithDefault(k *koanf.Koanf, envKey, koanfKey string, defaultValue []string) []string {
+	if list := getListWithKoanf(k, envKey, koanfKey); len(list) > 0 {
+		return list
+	}
+	return defaultValue
+}
+
+// getIntListWithDefault reads a comma-separated list of positive integers; an invalid entry
+// falls back to the default list
+func getIntListWithDefault(k *koanf.Koanf, envKey, koanfKey string, defaultValue []int) []int {
+	items := getListWithKoanf(k, envKey, koanfKey)
+	if len(items) == 0 {
+		return defaultValue
+	}
+	list := make([]int, 0, len(items))
+	for _, item := range items {
+		n, err := strconv.Atoi(item)
+		if err != nil || n <= 0 {
+			return defaultValue
+		}
+		list = append(list, n)
+	}
+	return list
+}
+
 func getIntWithKoanf(k *koanf.Koanf, envKey, koanfKey string, defaultValue int) int {
 	if value := k.Int(koanfKey); value != 0 {
 		return value
@@ -134,6 +1292,31 @@ func getDurationWithKoanf(k *koanf.Koanf, envKey, koanfKey string, defaultValue
 	return getEnvAsDuration(envKey, defaultValue)
 }
 
+func getBoolWithKoanf(k *koanf.Koanf, envKey, koanfKey string, defaultValue bool) bool {
+	if k.Exists(koanfKey) {
+		if value, err := strconv.ParseBool(k.String(koanfKey)); err == nil {
+			return value
+		}
+	}
+	return getEnvAsBool(envKey, defaultValue)
+}
+
+func getInt64WithKoanf(k *koanf.Koanf, envKey, koanfKey string, defaultValue int64) int64 {
+	if value := k.Int64(koanfKey); value != 0 {
+		return value
+	}
+	return getEnvAsInt64(envKey, defaultValue)
+}
+
+// getFractionWithKoanf reads a number from 0 to 1; anything else falls back to the default
+func getFractionWithKoanf(k *koanf.Koanf, envKey, koanfKey string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnvWithKoanf(k, envKey, koanfKey, ""), 64)
+	if err != nil || value < 0 || value > 1 {
+		return defaultValue
+	}
+	return value
+}
+
 func parseInt(s string) int {
 	var result int
 	for _, char := range s {
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// maxBulkRows bounds the rows of one multi-row INSERT, whose bind parameters must also stay
+// within Postgres' limit of 65535
+const (
+	maxBulkRows       = 1024
+	maxBindParameters = 65535
+)
+
+// BulkInsert collects rows for one table and writes them with a few statements instead of one
+// round trip per row. Exec and Query may run several statements, so callers wanting all rows
+// or none run them in a transaction.
+type BulkInsert struct {
+	table   string
+	columns []string
+	suffix  string
+	rows    [][]interface{}
+	err     error
+}
+
+// NewBulkInsert starts a bulk insert into the columns of table
+func NewBulkInsert(table string, columns ...string) *BulkInsert {
+	return &BulkInsert{table: table, columns: columns}
+}
+
+// Suffix sets a clause following the VALUES list, such as an ON CONFLICT clause. Copy ignores it.
+func (b *BulkInsert) Suffix(clause string) *BulkInsert {
+	b.suffix = clause
+	return b
+}
+
+// Add appends a row with one value per column
+func (b *BulkInsert) Add(values ...interface{}) {
+	if len(values) != len(b.columns) && b.err == nil {
+		b.err = fmt.Errorf("bulk insert into %s: row %d has %d values for %d columns", b.table, len(b.rows)+1,
+			len(values), len(b.columns))
+	}
+	b.rows = append(b.rows, values)
+}
+
+// Len returns the number of rows added
+func (b *BulkInsert) Len() int {
+	return len(b.rows)
+}
+
+// Exec inserts the rows with multi-row INSERT statements and returns how many were inserted
+func (b *BulkInsert) Exec(ctx context.Context, q Querier) (int64, error) {
+	var inserted int64
+	err := b.each(func(query string, args []interface{}) error {
+		result, err := q.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		inserted += n
+		return err
+	})
+	return inserted, err
+}
+
+// Query inserts the rows like Exec, returning the given columns of each inserted row to scan
+func (b *BulkInsert) Query(ctx context.Context, q Querier, returning string, scan func(*sql.Rows) error) error {
+	return b.each(func(query string, args []interface{}) error {
+		rows, err := q.QueryContext(ctx, query+" RETURNING "+returning, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// each calls fn with statements inserting the rows. Statements hold a power of two of rows,
+// so the SQL of a table comes in a few sizes that connections keep prepared, rather than one
+// per batch size.
+func (b *BulkInsert) each(fn func(query string, args []interface{}) error) error {
+	if b.err != nil {
+		return b.err
+	}
+	limit := maxBulkRows
+	for limit*len(b.columns) > maxBindParameters {
+		limit /= 2
+	}
+
+	for rows := b.rows; len(rows) > 0; {
+		n := limit
+		for n > len(rows) {
+			n /= 2
+		}
+		args := make([]interface{}, 0, n*len(b.columns))
+		for _, row := range rows[:n] {
+			args = append(args, row...)
+		}
+		if err := fn(b.statement(n), args); err != nil {
+			return fmt.Errorf("bulk insert into %s: %w", b.table, err)
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+func (b *BulkInsert) statement(rows int) string {
+	var query strings.Builder
+	fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES ", b.table, strings.Join(b.columns, ", "))
+	param := 1
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for c := range b.columns {
+			if c > 0 {
+				query.WriteString(", ")
+			}
+			fmt.Fprintf(&query, "$%d", param)
+			param++
+		}
+		query.WriteString(")")
+	}
+	if b.suffix != "" {
+		query.WriteString(" " + b.suffix)
+	}
+	return query.String()
+}
+
+// Copy streams the rows with COPY FROM STDIN, which is faster than INSERT for large
+// append-only loads but cannot resolve conflicts or return rows. COPY needs a transaction.
+func (b *BulkInsert) Copy(ctx context.Context, tx *sql.Tx) (int64, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(b.table, b.columns...))
+	if err != nil {
+		return 0, fmt.Errorf("copy into %s: %w", b.table, err)
+	}
+	defer stmt.Close()
+
+	for _, row := range b.rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return 0, fmt.Errorf("copy into %s: %w", b.table, err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return 0, fmt.Errorf("copy into %s: %w", b.table, err)
+	}
+	return int64(len(b.rows)), nil
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/metrics"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Sources of counts, as counted by countLookups
+const (
+	CountSourceCache    = "cache"
+	CountSourceEstimate = "estimate"
+	CountSourceQuery    = "query"
+)
+
+var countLookups = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "db",
+	Name:      "count_lookups_total",
+	Help:      "Row counts of list endpoints, by table and where they came from (cache, estimate or query)",
+}, []string{"table", "source"})
+
+// CountScope names a count: the rows of Table a tenant connection sees, which depend on Key,
+// such as the organization for its videos or the user for their organizations. The same
+// scope must always be counted under the same RLS context.
+type CountScope struct {
+	Table string
+	Key   uuid.UUID
+}
+
+// VideoCount is the scope of an organization's videos
+func VideoCount(orgID uuid.UUID) CountScope {
+	return CountScope{Table: "videos", Key: orgID}
+}
+
+// OrganizationCount is the scope of the organizations a user belongs to
+func OrganizationCount(userID uuid.UUID) CountScope {
+	return CountScope{Table: "organizations", Key: userID}
+}
+
+func (s CountScope) cacheKey() string {
+	return "counts:" + s.Table + ":" + s.Key.String()
+}
+
+// Count is the number of rows in a scope. Estimated counts come from the query planner and
+// may be off by a few percent.
+type Count struct {
+	Total     int64
+	Estimated bool
+}
+
+// CountProvider counts the rows list endpoints paginate, so their pagination metadata need
+// not come from COUNT(*) on every request
+type CountProvider interface {
+	// Count returns the rows of the scope q sees
+	Count(ctx context.Context, q Querier, scope CountScope) (Count, error)
+	// Invalidate forgets a cached count after rows of the scope were added or removed
+	Invalidate(ctx context.Context, scope CountScope) error
+}
+
+// cachedCounts keeps counts in Redis for ttl, when Redis is available, and estimates
+// counts the planner expects to reach estimateAbove rather than counting them
+type cachedCounts struct {
+	redis         *redis.Client
+	ttl           time.Duration
+	estimateAbove int64
+}
+
+// NewCountProvider returns a provider caching counts in redis for ttl and estimating ones of
+// at least estimateAbove rows. A nil client or zero ttl disables caching, and a zero
+// estimateAbove disables estimates.
+func NewCountProvider(redisClient *redis.Client, ttl time.Duration, estimateAbove int64) CountProvider {
+	if ttl <= 0 {
+		redisClient = nil
+	}
+	return &cachedCounts{redis: redisClient, ttl: ttl, estimateAbove: estimateAbove}
+}
+
+func (cc *cachedCounts) Count(ctx context.Context, q Querier, scope CountScope) (Count, error) {
+	if cc.redis != nil {
+		if cached, err := cc.redis.Get(ctx, scope.cacheKey()).Result(); err == nil {
+			if count, ok := parseCachedCount(cached); ok {
+				countLookups.WithLabelValues(scope.Table, CountSourceCache).Inc()
+				return count, nil
+			}
+		}
+	}
+
+	count, source, err := cc.count(ctx, q, scope.Table)
+	if err != nil {
+		return Count{}, err
+	}
+	countLookups.WithLabelValues(scope.Table, source).Inc()
+
+	if cc.redis != nil {
+		cached := strconv.FormatInt(count.Total, 10)
+		if count.Estimated {
+			cached = "~" + cached
+		}
+		if err := cc.redis.Set(ctx, scope.cacheKey(), cached, cc.ttl).Err(); err != nil {
+			log.Printf("WARN: Failed to cache count of %s: %v", scope.Table, err)
+		}
+	}
+	return count, nil
+}
+
+func (cc *cachedCounts) count(ctx context.Context, q Querier, table string) (Count, string, error) {
+	if cc.estimateAbove > 0 {
+		estimate, err := estimateRows(ctx, q, table)
+		if err != nil {
+			return Count{}, "", err
+		}
+		if estimate >= cc.estimateAbove {
+			return Count{Total: estimate, Estimated: true}, CountSourceEstimate, nil
+		}
+	}
+
+	var total int64
+	if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&total); err != nil {
+		return Count{}, "", fmt.Errorf("failed to count %s: %w", table, err)
+	}
+	return Count{Total: total}, CountSourceQuery, nil
+}
+
+func (cc *cachedCounts) Invalidate(ctx context.Context, scope CountScope) error {
+	if cc.redis == nil {
+		return nil
+	}
+	return cc.redis.Del(ctx, scope.cacheKey()).Err()
+}
+
+// estimateRows asks the planner how many rows of table q sees. Unlike pg_class.reltuples the
+// estimate takes the RLS policies of a tenant connection into account.
+func estimateRows(ctx context.Context, q Querier, table string) (int64, error) {
+	var plan []byte
+	if err := q.QueryRowContext(ctx, `EXPLAIN (FORMAT JSON) SELECT 1 FROM `+table).Scan(&plan); err != nil {
+		return 0, fmt.Errorf("failed to estimate %s: %w", table, err)
+	}
+	var explained []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(plan, &explained); err != nil {
+		return 0, fmt.Errorf("failed to read the plan estimating %s: %w", table, err)
+	}
+	if len(explained) == 0 {
+		return 0, fmt.Errorf("empty plan estimating %s", table)
+	}
+	return int64(explained[0].Plan.Rows), nil
+}
+
+func parseCachedCount(cached string) (Count, bool) {
+	estimated := strings.HasPrefix(cached, "~")
+	total, err := strconv.ParseInt(strings.TrimPrefix(cached, "~"), 10, 64)
+	return Count{Total: total, Estimated: estimated}, err == nil
+}
+
+// Count returns the rows of the scope this connection sees, from the pool's count cache when
+// it can
+func (t *StatelessTenantDB) Count(ctx context.Context, scope CountScope) (Count, error) {
+	if t.released {
+		return Count{}, fmt.Errorf("connection has been released")
+	}
+	return t.pool.counts.Count(ctx, t, scope)
+}
+
+// InvalidateCount forgets the cached count of a scope. Call it after the transaction adding or
+// removing rows committed, or a request in between may cache the old count again.
+func (t *StatelessTenantDB) InvalidateCount(ctx context.Context, scope CountScope) {
+	if err := t.pool.counts.Invalidate(ctx, scope); err != nil {
+		log.Printf("WARN: Failed to invalidate count of %s: %v", scope.Table, err)
+	}
+}
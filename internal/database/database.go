@@ -10,32 +10,9 @@ import (
 	"openvdo/pkg/logger"
 
 	"github.com/go-redis/redis/v8"
-	"github.com/google/uuid"
 	_ "github.com/lib/pq"
 )
 
-var PoolManagerInstance *StatelessPoolManager
-
-func InitPoolManager(dbConfig config.Database, redisConfig config.Redis) error {
-	pm, err := NewStatelessPoolManager(dbConfig, ConnectRedis(redisConfig))
-	if err != nil {
-		return fmt.Errorf("failed to initialize stateless pool manager: %w", err)
-	}
-	PoolManagerInstance = pm
-	return nil
-}
-
-func ClosePoolManager() error {
-	if PoolManagerInstance != nil {
-		return PoolManagerInstance.Close()
-	}
-	return nil
-}
-
-func GetPoolManager() *StatelessPoolManager {
-	return PoolManagerInstance
-}
-
 func Connect(cfg config.Database) (*sql.DB, error) {
 	dsn := cfg.DSN()
 
@@ -95,20 +72,3 @@ func CloseRedis(client *redis.Client) {
 		}
 	}
 }
-
-func GetTenantDB(ctx context.Context, userID string) (*StatelessTenantDB, error) {
-	if PoolManagerInstance == nil {
-		return nil, fmt.Errorf("pool manager not initialized")
-	}
-
-	userUUID, err := parseUUID(userID)
-	if err != nil {
-		return nil, fmt.Errorf("invalid user ID: %w", err)
-	}
-
-	return PoolManagerInstance.NewTenantDB(ctx, userUUID)
-}
-
-func parseUUID(s string) (uuid.UUID, error) {
-	return uuid.Parse(s)
-}
@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// pqConn is the set of driver interfaces implemented by lib/pq connections. Wrapped
+// connections embed it so database/sql still sees every optional interface.
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// FailoverEvent records a switch from one primary to another
+type FailoverEvent struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// FailoverStatus describes the active primary and recent failover activity
+type FailoverStatus struct {
+	Enabled             bool           `json:"enabled"`
+	ActivePrimary       string         `json:"active_primary"`
+	ActiveIndex         int            `json:"active_index"`
+	Candidates          int            `json:"candidates"`
+	ConsecutiveFailures int            `json:"consecutive_failures"`
+	Failovers           int64          `json:"failovers"`
+	LastEvent           *FailoverEvent `json:"last_event,omitempty"`
+}
+
+// failoverConnector dials whichever DSN of a prioritized list is the active primary.
+// Connections remember the generation they were opened in and report themselves invalid
+// once a failover bumps it, so database/sql drops them instead of reusing them.
+type failoverConnector struct {
+	dsns       []string
+	connectors []*pq.Connector
+	active     atomic.Int32
+	generation atomic.Int64
+
+	// statementCacheSize bounds the statements each connection keeps prepared; 0 disables it
+	statementCacheSize int
+}
+
+type failoverConn struct {
+	pqConn
+	connector  *failoverConnector
+	generation int64
+	stmts      *statementCache
+}
+
+// IsValid reports whether the connection still points at the active primary
+func (c *failoverConn) IsValid() bool {
+	return c.generation == c.connector.generation.Load() && c.pqConn.IsValid()
+}
+
+func newFailoverConnector(dsns []string) (*failoverConnector, error) {
+	if len(dsns) == 0 {
+		return nil, fmt.Errorf("no database DSNs configured")
+	}
+
+	fc := &failoverConnector{dsns: dsns}
+	for i, dsn := range dsns {
+		connector, err := pq.NewConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid database DSN #%d (%s): %w", i+1, dsnHost(dsn), err)
+		}
+		fc.connectors = append(fc.connectors, connector)
+	}
+	return fc, nil
+}
+
+// Connect implements driver.Connector
+func (fc *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	generation := fc.generation.Load()
+	conn, err := fc.connectors[fc.active.Load()].Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pc, ok := conn.(pqConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected PostgreSQL driver connection type %T", conn)
+	}
+	fconn := &failoverConn{pqConn: pc, connector: fc, generation: generation}
+	if fc.statementCacheSize > 0 {
+		fconn.stmts = newStatementCache(fc.statementCacheSize)
+	}
+	return fconn, nil
+}
+
+// Driver implements driver.Connector
+func (fc *failoverConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// activeHost returns the host of the active primary without credentials
+func (fc *failoverConnector) activeHost() string {
+	return dsnHost(fc.dsns[fc.active.Load()])
+}
+
+// switchTo makes the DSN at index the active primary and invalidates existing connections
+func (fc *failoverConnector) switchTo(index int) {
+	fc.active.Store(int32(index))
+	fc.generation.Add(1)
+}
+
+// probe checks that the DSN at index is reachable and accepts writes
+func (fc *failoverConnector) probe(ctx context.Context, index int) error {
+	db := sql.OpenDB(fc.connectors[index])
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	var inRecovery bool
+	if err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+		return err
+	}
+	if inRecovery {
+		return fmt.Errorf("server is a standby")
+	}
+	return nil
+}
+
+// elect activates the first reachable, writable DSN in priority order
+func (fc *failoverConnector) elect(ctx context.Context) (int, error) {
+	var errs []string
+	for i := range fc.dsns {
+		err := fc.probe(ctx, i)
+		if err == nil {
+			fc.switchTo(i)
+			return i, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", dsnHost(fc.dsns[i]), err))
+	}
+	return -1, fmt.Errorf("no writable primary available (%s)", strings.Join(errs, "; "))
+}
+
+// failoverMonitor watches the active primary and promotes the next candidate when it fails
+type failoverMonitor struct {
+	db        *sql.DB
+	connector *failoverConnector
+	config    config.Database
+	idleConns func() int
+	mu        sync.RWMutex
+	failures  int
+	failovers int64
+	lastEvent *FailoverEvent
+	ticker    *time.Ticker
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newFailoverMonitor(db *sql.DB, connector *failoverConnector, cfg config.Database, idleConns func() int) *failoverMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &failoverMonitor{
+		db:        db,
+		connector: connector,
+		config:    cfg,
+		idleConns: idleConns,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start checks the active primary periodically. Monitoring is skipped with a single DSN
+// since there is nothing to fail over to.
+func (fm *failoverMonitor) Start() {
+	if len(fm.connector.dsns) < 2 {
+		return
+	}
+
+	fm.ticker = time.NewTicker(fm.config.FailoverCheckInterval)
+	go func() {
+		for {
+			select {
+			case <-fm.ctx.Done():
+				return
+			case <-fm.ticker.C:
+				fm.check()
+			}
+		}
+	}()
+
+	logger.Info("Database failover monitor started with %d candidate primaries (interval %v)",
+		len(fm.connector.dsns), fm.config.FailoverCheckInterval)
+}
+
+// Stop stops the monitor
+func (fm *failoverMonitor) Stop() {
+	fm.cancel()
+	if fm.ticker != nil {
+		fm.ticker.Stop()
+	}
+}
+
+// check verifies the active primary and fails over after enough consecutive failures
+func (fm *failoverMonitor) check() {
+	ctx, cancel := context.WithTimeout(fm.ctx, fm.config.FailoverCheckInterval)
+	defer cancel()
+
+	var inRecovery bool
+	err := fm.db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&inRecovery)
+	if err == nil && inRecovery {
+		err = fmt.Errorf("primary was demoted to standby")
+	}
+
+	fm.mu.Lock()
+	if err == nil {
+		fm.failures = 0
+		fm.mu.Unlock()
+		return
+	}
+	fm.failures++
+	failures := fm.failures
+	fm.mu.Unlock()
+
+	logger.Error("Database primary %s check failed (%d/%d): %v",
+		fm.connector.activeHost(), failures, fm.config.FailoverThreshold, err)
+	if failures >= fm.config.FailoverThreshold {
+		fm.failover(err)
+	}
+}
+
+// failover promotes the highest-priority healthy candidate other than the current primary
+func (fm *failoverMonitor) failover(cause error) {
+	current := int(fm.connector.active.Load())
+	for i := range fm.connector.dsns {
+		if i == current {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(fm.ctx, fm.config.FailoverCheckInterval)
+		err := fm.connector.probe(ctx, i)
+		cancel()
+		if err != nil {
+			logger.Debug("Failover candidate %s rejected: %v", dsnHost(fm.connector.dsns[i]), err)
+			continue
+		}
+
+		event := &FailoverEvent{
+			From:   dsnHost(fm.connector.dsns[current]),
+			To:     dsnHost(fm.connector.dsns[i]),
+			Reason: cause.Error(),
+			At:     time.Now(),
+		}
+		fm.connector.switchTo(i)
+
+		// Drop idle connections to the old primary right away; busy ones are discarded when returned
+		fm.db.SetMaxIdleConns(0)
+		fm.db.SetMaxIdleConns(fm.idleConns())
+
+		fm.mu.Lock()
+		fm.failures = 0
+		fm.failovers++
+		fm.lastEvent = event
+		fm.mu.Unlock()
+
+		logger.Info("Database failover: %s -> %s (%s)", event.From, event.To, event.Reason)
+		return
+	}
+
+	logger.Error("Database failover from %s failed: no healthy candidate", fm.connector.activeHost())
+}
+
+// Status returns the current failover state
+func (fm *failoverMonitor) Status() FailoverStatus {
+	fm.mu.RLock()
+	defer fm.mu.RUnlock()
+
+	return FailoverStatus{
+		Enabled:             len(fm.connector.dsns) > 1,
+		ActivePrimary:       fm.connector.activeHost(),
+		ActiveIndex:         int(fm.connector.active.Load()),
+		Candidates:          len(fm.connector.dsns),
+		ConsecutiveFailures: fm.failures,
+		Failovers:           fm.failovers,
+		LastEvent:           fm.lastEvent,
+	}
+}
+
+// dsnHost extracts host:port from a URL or key=value DSN so it can be logged without credentials
+func dsnHost(dsn string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			return u.Host
+		}
+		return "invalid-dsn"
+	}
+
+	host, port := "localhost", "5432"
+	for _, field := range strings.Fields(dsn) {
+		if v, ok := strings.CutPrefix(field, "host="); ok {
+			host = v
+		} else if v, ok := strings.CutPrefix(field, "port="); ok {
+			port = v
+		}
+	}
+	return host + ":" + port
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -18,6 +19,12 @@ const (
 	DBKey          ContextKey = "tenant_db"
 	StatelessDBKey ContextKey = "stateless_tenant_db"
 	PoolKey        ContextKey = "pool_manager"
+	// APIKeyIDKey holds the ID of the API key a request authenticated with, when it used one
+	// rather than a user's credentials
+	APIKeyIDKey ContextKey = "api_key_id"
+	// AuthUserIDKey holds the user a session token authenticated, set by the session
+	// middleware running ahead of StatelessDatabaseMiddleware
+	AuthUserIDKey ContextKey = "auth_user_id"
 )
 
 func StatelessDatabaseMiddleware(spm *StatelessPoolManager) gin.HandlerFunc {
@@ -31,7 +38,33 @@ func StatelessDatabaseMiddleware(spm *StatelessPoolManager) gin.HandlerFunc {
 			return
 		}
 
-		tenantDB, err := spm.NewTenantDB(c.Request.Context(), userID)
+		// Users in several organizations pick the one a request acts in with X-Org-ID
+		orgID := uuid.Nil
+		if header := c.GetHeader("X-Org-ID"); header != "" {
+			if orgID, err = uuid.Parse(header); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid X-Org-ID header"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Routes of one organization act in the organization of their URL, so its content is
+		// visible whatever X-Org-ID says. The handler answers for organizations the user is not
+		// a member of.
+		routeOrg, isRouteOrg := routeOrganization(c)
+		if isRouteOrg {
+			orgID = routeOrg
+		}
+
+		tenantDB, err := spm.NewTenantDB(c.Request.Context(), userID, orgID)
+		if errors.Is(err, ErrNotMember) && isRouteOrg {
+			tenantDB, err = spm.NewTenantDB(c.Request.Context(), userID, uuid.Nil)
+		}
+		if errors.Is(err, ErrNotMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of the organization in X-Org-ID"})
+			c.Abort()
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection failed"})
 			c.Abort()
@@ -39,6 +72,11 @@ func StatelessDatabaseMiddleware(spm *StatelessPoolManager) gin.HandlerFunc {
 		}
 
 		c.Set(string(StatelessDBKey), tenantDB)
+		if tenantDB.GetOrganizationID() != uuid.Nil {
+			c.Set(string(OrgIDKey), tenantDB.GetOrganizationID())
+			c.Set(string(RoleKey), tenantDB.GetRole())
+			c.Writer.Header().Set("X-Org-ID", tenantDB.GetOrganizationID().String())
+		}
 
 		c.Writer.Header().Set("X-Tenant-ID", userID.String())
 		c.Writer.Header().Set("X-Pool-Type", "stateless")
@@ -54,6 +92,10 @@ func StatelessDatabaseMiddleware(spm *StatelessPoolManager) gin.HandlerFunc {
 }
 
 func extractUserID(c *gin.Context) (uuid.UUID, error) {
+	if userID, ok := c.Get(string(AuthUserIDKey)); ok {
+		return userID.(uuid.UUID), nil
+	}
+
 	if userIDHeader := c.GetHeader("X-User-ID"); userIDHeader != "" {
 		userID, err := uuid.Parse(userIDHeader)
 		if err != nil {
@@ -234,3 +276,13 @@ func OptionalAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// routeOrganization returns the organization a route of one organization names in its URL, as
+// in /api/v1/organizations/:id/entitlements
+func routeOrganization(c *gin.Context) (uuid.UUID, bool) {
+	if !strings.Contains(c.FullPath(), "/organizations/:id") {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	return id, err == nil
+}
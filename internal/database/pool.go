@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"openvdo/internal/config"
+	"openvdo/internal/queries"
 	"openvdo/pkg/logger"
 
 	"github.com/google/uuid"
@@ -37,7 +38,7 @@ type PoolManager struct {
 
 // NewPoolManager creates a new connection pool manager
 func NewPoolManager(cfg config.Database) (*PoolManager, error) {
-	masterDB, err := createMasterConnection(cfg)
+	masterDB, _, err := createMasterConnection(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create master connection: %w", err)
 	}
@@ -58,17 +59,33 @@ func NewPoolManager(cfg config.Database) (*PoolManager, error) {
 	return pm, nil
 }
 
-// createMasterConnection creates the master database connection with pool configuration
-func createMasterConnection(cfg config.Database) (*sql.DB, error) {
-	dsn := cfg.DSN()
-
-	db, err := sql.Open("postgres", dsn)
+// createMasterConnection creates the master database connection with pool configuration.
+// Connections are dialed through a failover connector so the active primary can change at runtime.
+func createMasterConnection(cfg config.Database) (*sql.DB, *failoverConnector, error) {
+	connector, err := newFailoverConnector(cfg.PrimaryDSNList())
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, nil, err
+	}
+	// Named statements live on one server connection, which PgBouncer's transaction pooling
+	// does not keep between transactions
+	if !cfg.PgBouncerMode {
+		connector.statementCacheSize = cfg.StatementCacheSize
+	}
+
+	if len(connector.dsns) > 1 {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		index, err := connector.elect(ctx)
+		cancel()
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Info("Using database primary %s (candidate %d of %d)", connector.activeHost(), index+1, len(connector.dsns))
 	}
 
+	db := sql.OpenDB(connector)
 	if err = db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	// Configure connection pool
@@ -80,7 +97,7 @@ func createMasterConnection(cfg config.Database) (*sql.DB, error) {
 	logger.Info("Master database connection established with pool config: MaxOpen=%d, MaxIdle=%d, Lifetime=%v, IdleTime=%v",
 		cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime, cfg.ConnMaxIdleTime)
 
-	return db, nil
+	return db, connector, nil
 }
 
 // GetTenantConnection returns a database connection with RLS context set for the tenant
@@ -169,17 +186,7 @@ func (pm *PoolManager) createTenantPool(ctx context.Context, userID uuid.UUID) (
 
 // getUserOrgInfo retrieves user's organization and role information
 func (pm *PoolManager) getUserOrgInfo(ctx context.Context, userID uuid.UUID) (uuid.UUID, string, error) {
-	var orgID uuid.UUID
-	var role string
-
-	query := `
-		SELECT organization_id, role
-		FROM user_org_roles
-		WHERE user_id = $1
-		LIMIT 1
-	`
-
-	err := pm.masterDB.QueryRowContext(ctx, query, userID).Scan(&orgID, &role)
+	membership, err := queries.New(pm.masterDB).FirstMembership(ctx, userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return uuid.Nil, "", fmt.Errorf("user not found in any organization")
@@ -187,7 +194,7 @@ func (pm *PoolManager) getUserOrgInfo(ctx context.Context, userID uuid.UUID) (uu
 		return uuid.Nil, "", fmt.Errorf("failed to query user org info: %w", err)
 	}
 
-	return orgID, role, nil
+	return membership.OrganizationID, membership.Role, nil
 }
 
 // setUserContext sets the PostgreSQL RLS user context for the connection
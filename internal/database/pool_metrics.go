@@ -0,0 +1,73 @@
+package database
+
+import (
+	"openvdo/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Latency histograms of the stateless pool, exported to Prometheus and summarized in PoolMetrics
+var (
+	acquireLatency = metrics.NewLatency("db", "connection_acquire_seconds",
+		"Time to obtain a connection or transaction from the shared pool")
+	contextLatency = metrics.NewLatency("db", "rls_context_setup_seconds",
+		"Time to set the RLS user context on a tenant connection")
+	queryLatency = metrics.NewLatency("db", "query_duration_seconds",
+		"Duration of tenant queries until the first result is available")
+)
+
+var (
+	poolOpenDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_open_connections",
+		"Open connections in the shared pool", nil, nil)
+	poolInUseDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_in_use_connections",
+		"Connections currently in use", nil, nil)
+	poolIdleDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_idle_connections",
+		"Idle connections in the shared pool", nil, nil)
+	poolMaxIdleDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_max_idle_connections",
+		"Current idle connection limit set by adaptive sizing", nil, nil)
+	poolWaitCountDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_wait_count_total",
+		"Total number of waits for a free connection", nil, nil)
+	poolWaitSecondsDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_wait_seconds_total",
+		"Total time spent waiting for a free connection", nil, nil)
+	poolAdjustmentsDesc = prometheus.NewDesc(metrics.Namespace+"_db_pool_adjustments_total",
+		"Changes of the idle connection limit made by adaptive sizing", nil, nil)
+	poolFailoversDesc = prometheus.NewDesc(metrics.Namespace+"_db_failovers_total",
+		"Switches to another primary database", nil, nil)
+	poolPrimaryDesc = prometheus.NewDesc(metrics.Namespace+"_db_active_primary",
+		"Index of the active primary in the configured priority list", nil, nil)
+)
+
+// poolCollector exports connection pool state read at scrape time
+type poolCollector struct {
+	spm *StatelessPoolManager
+}
+
+// Describe implements prometheus.Collector
+func (pc poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolOpenDesc
+	ch <- poolInUseDesc
+	ch <- poolIdleDesc
+	ch <- poolMaxIdleDesc
+	ch <- poolWaitCountDesc
+	ch <- poolWaitSecondsDesc
+	ch <- poolAdjustmentsDesc
+	ch <- poolFailoversDesc
+	ch <- poolPrimaryDesc
+}
+
+// Collect implements prometheus.Collector
+func (pc poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := pc.spm.masterDB.Stats()
+	sizing := pc.spm.sizer.Status()
+	failover := pc.spm.failover.Status()
+
+	ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(poolMaxIdleDesc, prometheus.GaugeValue, float64(sizing.MaxIdleConns))
+	ch <- prometheus.MustNewConstMetric(poolWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(poolWaitSecondsDesc, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(poolAdjustmentsDesc, prometheus.CounterValue, float64(sizing.Adjustments))
+	ch <- prometheus.MustNewConstMetric(poolFailoversDesc, prometheus.CounterValue, float64(failover.Failovers))
+	ch <- prometheus.MustNewConstMetric(poolPrimaryDesc, prometheus.GaugeValue, float64(failover.ActiveIndex))
+}
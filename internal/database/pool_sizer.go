@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/pkg/logger"
+)
+
+// PoolAdjustment records a change of the idle connection limit
+type PoolAdjustment struct {
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// PoolSizingStatus describes the adaptive pool controller
+type PoolSizingStatus struct {
+	Adaptive       bool            `json:"adaptive"`
+	MaxIdleConns   int             `json:"max_idle_conns"`
+	MinIdleConns   int             `json:"min_idle_conns"`
+	MaxOpenConns   int             `json:"max_open_conns"`
+	WarmedUp       int             `json:"warmed_up"`
+	Adjustments    int64           `json:"adjustments"`
+	LastAdjustment *PoolAdjustment `json:"last_adjustment,omitempty"`
+}
+
+// poolSizer warms up the shared pool and adapts MaxIdleConns to the observed load. Waiting
+// for a connection or closing connections because the idle pool is full means the idle limit
+// is too low; a pool with surplus idle connections and no waits lets it shrink again.
+type poolSizer struct {
+	db          *sql.DB
+	config      config.Database
+	mu          sync.RWMutex
+	maxIdle     int
+	warmedUp    int
+	last        sql.DBStats
+	adjustments int64
+	lastChange  *PoolAdjustment
+	ticker      *time.Ticker
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func newPoolSizer(db *sql.DB, cfg config.Database) *poolSizer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &poolSizer{
+		db:      db,
+		config:  cfg,
+		maxIdle: cfg.MaxIdleConns,
+		last:    db.Stats(),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// WarmUp opens the configured number of connections up front so the first requests after
+// a deploy do not pay the connection setup cost
+func (ps *poolSizer) WarmUp(ctx context.Context) error {
+	n := ps.config.WarmupConns
+	if n > ps.MaxIdle() {
+		n = ps.MaxIdle()
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		conn, err := ps.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("warm-up stopped after %d connections: %w", len(conns), err)
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			return fmt.Errorf("warm-up stopped after %d connections: %w", len(conns)-1, err)
+		}
+	}
+
+	ps.mu.Lock()
+	ps.warmedUp = len(conns)
+	ps.mu.Unlock()
+
+	logger.Info("Database pool warmed up with %d connections", len(conns))
+	return nil
+}
+
+// Start runs the adaptive controller until Stop is called
+func (ps *poolSizer) Start() {
+	if !ps.config.AdaptivePool {
+		return
+	}
+
+	ps.ticker = time.NewTicker(ps.config.AdaptiveInterval)
+	go func() {
+		for {
+			select {
+			case <-ps.ctx.Done():
+				return
+			case <-ps.ticker.C:
+				ps.adjust()
+			}
+		}
+	}()
+
+	logger.Info("Adaptive pool sizing started (idle %d-%d, interval %v)",
+		ps.minIdle(), ps.config.MaxOpenConns, ps.config.AdaptiveInterval)
+}
+
+// Stop stops the adaptive controller
+func (ps *poolSizer) Stop() {
+	ps.cancel()
+	if ps.ticker != nil {
+		ps.ticker.Stop()
+	}
+}
+
+// MaxIdle returns the current idle connection limit
+func (ps *poolSizer) MaxIdle() int {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.maxIdle
+}
+
+// adjust compares pool statistics with the previous interval and resizes the idle limit
+func (ps *poolSizer) adjust() {
+	stats := ps.db.Stats()
+
+	ps.mu.Lock()
+	waits := stats.WaitCount - ps.last.WaitCount
+	waited := stats.WaitDuration - ps.last.WaitDuration
+	idleClosed := stats.MaxIdleClosed - ps.last.MaxIdleClosed
+	ps.last = stats
+
+	current := ps.maxIdle
+	target := current
+	var reason string
+	switch {
+	case waits > 0 || idleClosed > 0:
+		target = current + max(1, current/4)
+		reason = fmt.Sprintf("%d waits (%v total), %d connections closed by idle limit", waits, waited, idleClosed)
+	case stats.Idle > stats.InUse && stats.Idle >= current:
+		target = current - max(1, current/4)
+		reason = fmt.Sprintf("%d idle vs %d in use with no waits", stats.Idle, stats.InUse)
+	}
+	target = min(max(target, ps.minIdle()), ps.config.MaxOpenConns)
+
+	if target == current {
+		ps.mu.Unlock()
+		return
+	}
+
+	change := &PoolAdjustment{From: current, To: target, Reason: reason, At: time.Now()}
+	ps.maxIdle = target
+	ps.adjustments++
+	ps.lastChange = change
+	ps.mu.Unlock()
+
+	ps.db.SetMaxIdleConns(target)
+	logger.Info("Adjusted database MaxIdleConns %d -> %d: %s", change.From, change.To, change.Reason)
+}
+
+// minIdle is the floor for the idle limit
+func (ps *poolSizer) minIdle() int {
+	if ps.config.MinIdleConns > 0 {
+		return ps.config.MinIdleConns
+	}
+	return 1
+}
+
+// Status returns the controller state
+func (ps *poolSizer) Status() PoolSizingStatus {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	return PoolSizingStatus{
+		Adaptive:       ps.config.AdaptivePool,
+		MaxIdleConns:   ps.maxIdle,
+		MinIdleConns:   ps.minIdle(),
+		MaxOpenConns:   ps.config.MaxOpenConns,
+		WarmedUp:       ps.warmedUp,
+		Adjustments:    ps.adjustments,
+		LastAdjustment: ps.lastChange,
+	}
+}
@@ -0,0 +1,16 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is the common query surface of *sql.DB, *sql.Conn, *sql.Tx and tenant connections,
+// letting services run the same SQL inside or outside a transaction. It is the DBTX of the
+// generated queries of internal/queries.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
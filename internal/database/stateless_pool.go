@@ -3,13 +3,17 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"openvdo/internal/config"
+	"openvdo/internal/metrics"
+	"openvdo/internal/queries"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/google/uuid"
@@ -22,6 +26,9 @@ type StatelessPoolManager struct {
 	redis    *redis.Client
 	config   config.Database
 	mu       sync.RWMutex
+	failover *failoverMonitor
+	sizer    *poolSizer
+	counts   CountProvider
 
 	// Metrics
 	metrics PoolMetrics
@@ -34,40 +41,91 @@ type PoolMetrics struct {
 	ContextSwitches      int64     `json:"context_switches"`
 	RedisCacheHits       int64     `json:"redis_cache_hits"`
 	RedisCacheMisses     int64     `json:"redis_cache_misses"`
-	AverageResponseTime  time.Duration `json:"average_response_time"`
+	ConnectionAcquire    metrics.LatencySnapshot `json:"connection_acquire"`
+	ContextSetup         metrics.LatencySnapshot `json:"context_setup"`
+	QueryDuration        metrics.LatencySnapshot `json:"query_duration"`
+	Failovers            int64     `json:"failovers"`
+	WaitCount            int64     `json:"wait_count"`
+	WaitDuration         time.Duration `json:"wait_duration"`
+	Sizing               PoolSizingStatus `json:"sizing"`
 	LastReset           time.Time `json:"last_reset"`
 }
 
 // UserSession represents cached user session data
+// UserSession describes a user's memberships. OrgID and Role are those of the organization
+// requests act in unless they name another with X-Org-ID: the one chosen with the
+// organization switch, otherwise the one joined last.
 type UserSession struct {
-	UserID    uuid.UUID `json:"user_id"`
-	OrgID     uuid.UUID `json:"org_id"`
-	Role      string    `json:"role"`
-	ExpiresAt time.Time `json:"expires_at"`
+	UserID uuid.UUID `json:"user_id"`
+	OrgID  uuid.UUID `json:"org_id"`
+	Role   string    `json:"role"`
+	// Organizations maps every organization the user belongs to to their role in it
+	Organizations map[uuid.UUID]string `json:"organizations"`
+	ExpiresAt     time.Time            `json:"expires_at"`
 }
 
+// TenantContext is what the RLS context of a tenant connection is set to. OrgID is uuid.Nil
+// for users who do not belong to any organization yet.
+type TenantContext struct {
+	UserID uuid.UUID
+	OrgID  uuid.UUID
+	Role   string
+}
+
+// ErrNoOrganization is returned for the session of a user without memberships
+var ErrNoOrganization = errors.New("user not found in any organization")
+
+// ErrNotMember is returned when a request names an organization the user does not belong to
+var ErrNotMember = errors.New("user is not a member of the organization")
+
 // NewStatelessPoolManager creates a new stateless connection pool manager
 func NewStatelessPoolManager(cfg config.Database, redisClient *redis.Client) (*StatelessPoolManager, error) {
-	masterDB, err := createMasterConnection(cfg)
+	masterDB, connector, err := createMasterConnection(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create master connection: %w", err)
 	}
 
+	sizer := newPoolSizer(masterDB, cfg)
 	spm := &StatelessPoolManager{
 		masterDB: masterDB,
 		redis:    redisClient,
 		config:   cfg,
+		failover: newFailoverMonitor(masterDB, connector, cfg, sizer.MaxIdle),
+		sizer:    sizer,
+		counts:   NewCountProvider(redisClient, cfg.CountCacheTTL, cfg.CountEstimateThreshold),
 		metrics: PoolMetrics{
 			LastReset: time.Now(),
 		},
 	}
 
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := spm.validateTenantContext(ctx); err != nil {
+		masterDB.Close()
+		return nil, fmt.Errorf("tenant context validation failed: %w", err)
+	}
+
+	if err := sizer.WarmUp(ctx); err != nil {
+		log.Printf("WARN: Database pool warm-up incomplete: %v", err)
+	}
+
+	spm.failover.Start()
+	spm.sizer.Start()
+
+	if err := metrics.Registry.Register(poolCollector{spm: spm}); err != nil {
+		log.Printf("WARN: Database pool metrics not registered: %v", err)
+	}
+
+	if cfg.PgBouncerMode {
+		log.Println("INFO: PgBouncer mode enabled: RLS context is transaction-scoped")
+	}
 	log.Println("INFO: Stateless connection pool manager initialized")
 	return spm, nil
 }
 
-// GetTenantConnection returns a database connection with RLS context dynamically set
-func (spm *StatelessPoolManager) GetTenantConnection(ctx context.Context, userID uuid.UUID) (*sql.Conn, error) {
+// GetTenantConnection returns a database connection with RLS context dynamically set.
+// The context is session-scoped, so this must not be used in PgBouncer mode.
+func (spm *StatelessPoolManager) GetTenantConnection(ctx context.Context, tenant TenantContext) (*sql.Conn, error) {
 	start := time.Now()
 
 	// Get connection from shared pool
@@ -76,40 +134,61 @@ func (spm *StatelessPoolManager) GetTenantConnection(ctx context.Context, userID
 		spm.recordError()
 		return nil, fmt.Errorf("failed to get connection from pool: %w", err)
 	}
+	acquireLatency.Since(start)
 
 	// Set RLS context dynamically
-	if err := spm.setUserContext(ctx, conn, userID); err != nil {
+	start = time.Now()
+	if err := spm.setTenantContext(ctx, conn, tenant, false); err != nil {
 		conn.Close()
 		spm.recordError()
 		return nil, fmt.Errorf("failed to set user context: %w", err)
 	}
+	contextLatency.Since(start)
 
-	spm.recordMetrics(start)
+	spm.recordContextSwitch()
 	return conn, nil
 }
 
-// setUserContext sets the PostgreSQL RLS user context for the connection
-func (spm *StatelessPoolManager) setUserContext(ctx context.Context, conn *sql.Conn, userID uuid.UUID) error {
-	return conn.Raw(func(driverConn interface{}) error {
-		if pgConn, ok := driverConn.(interface{
-			ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
-		}); ok {
-			// Set the user context for RLS
-			_, err := pgConn.ExecContext(ctx, "SET LOCAL app.current_user_id = $1", userID.String())
-			if err != nil {
-				return fmt.Errorf("failed to set RLS context: %w", err)
-			}
-
-			// Optionally set additional context variables for more granular RLS
-			_, err = pgConn.ExecContext(ctx, "SET LOCAL app.request_timestamp = $1", time.Now().Format(time.RFC3339))
-			if err != nil {
-				// Log error but don't fail the connection setup
-			}
-
-			return nil
-		}
-		return fmt.Errorf("failed to cast connection to PostgreSQL driver")
-	})
+// setTenantContext sets the PostgreSQL RLS context: the user, and the organization and role
+// requests act in, which are empty for users without an organization. With local set, the
+// settings only last until the end of the current transaction, which is the only safe scope
+// behind a transaction pooler such as PgBouncer.
+func (spm *StatelessPoolManager) setTenantContext(ctx context.Context, q Querier, tenant TenantContext, local bool) error {
+	orgID := ""
+	if tenant.OrgID != uuid.Nil {
+		orgID = tenant.OrgID.String()
+	}
+	_, err := q.ExecContext(ctx, `
+		SELECT set_config('app.current_user_id', $1, $5), set_config('app.current_org_id', $2, $5),
+			set_config('app.current_role', $3, $5), set_config('app.request_timestamp', $4, $5)`,
+		tenant.UserID.String(), orgID, tenant.Role, time.Now().Format(time.RFC3339), local)
+	if err != nil {
+		return fmt.Errorf("failed to set RLS context: %w", err)
+	}
+	return nil
+}
+
+// beginTenantTransaction starts a transaction with a transaction-scoped RLS context (PgBouncer mode)
+func (spm *StatelessPoolManager) beginTenantTransaction(ctx context.Context, tenant TenantContext) (*sql.Tx, error) {
+	start := time.Now()
+
+	tx, err := spm.masterDB.BeginTx(ctx, nil)
+	if err != nil {
+		spm.recordError()
+		return nil, fmt.Errorf("failed to begin tenant transaction: %w", err)
+	}
+	acquireLatency.Since(start)
+
+	start = time.Now()
+	if err := spm.setTenantContext(ctx, tx, tenant, true); err != nil {
+		tx.Rollback()
+		spm.recordError()
+		return nil, fmt.Errorf("failed to set user context: %w", err)
+	}
+	contextLatency.Since(start)
+
+	spm.recordContextSwitch()
+	return tx, nil
 }
 
 // ReleaseConnection returns connection to shared pool with context cleanup
@@ -118,39 +197,74 @@ func (spm *StatelessPoolManager) ReleaseConnection(conn *sql.Conn) error {
 		return nil
 	}
 
+	// Behind PgBouncer the server connection is handed to other clients between transactions,
+	// so there is no session state to reset (and RESET ALL would hit an arbitrary backend)
+	if spm.config.PgBouncerMode {
+		return conn.Close()
+	}
+
 	// Reset connection context to prevent contamination
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := conn.Raw(func(driverConn interface{}) error {
-		if pgConn, ok := driverConn.(interface{
-			ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
-		}); ok {
-			// Reset all session variables
-			_, err := pgConn.ExecContext(ctx, "RESET ALL")
-			if err != nil {
-				log.Printf("WARN: Failed to reset connection context: %v", err)
-			}
-
-			// Optionally reset search_path to default
-			_, err = pgConn.ExecContext(ctx, "SET search_path TO public")
-			if err != nil {
-				log.Printf("WARN: Failed to reset search_path: %v", err)
-			}
-
-			return nil
-		}
-		return fmt.Errorf("failed to cast connection to PostgreSQL driver")
-	})
+	if _, err := conn.ExecContext(ctx, "RESET ALL"); err != nil {
+		// Never hand a connection that may still carry a user's context back to the pool;
+		// returning ErrBadConn from Raw makes database/sql discard it
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		return fmt.Errorf("failed to reset connection context: %w", err)
+	}
 
 	// Close connection to return it to pool
-	closeErr := conn.Close()
+	return conn.Close()
+}
+
+// validateTenantContext checks at startup that the RLS context strategy works with the
+// server we are connected to: session settings must survive between statements unless
+// PgBouncer mode is enabled, and transaction-scoped settings must not outlive their transaction.
+func (spm *StatelessPoolManager) validateTenantContext(ctx context.Context) error {
+	probe := uuid.New()
 
+	tx, err := spm.masterDB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("context reset error: %w, close error: %w", err, closeErr)
+		return fmt.Errorf("failed to begin validation transaction: %w", err)
+	}
+	if err := spm.setTenantContext(ctx, tx, TenantContext{UserID: probe}, true); err != nil {
+		tx.Rollback()
+		return err
+	}
+	var value string
+	if err := tx.QueryRowContext(ctx, "SELECT current_setting('app.current_user_id', true)").Scan(&value); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to read RLS context: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit validation transaction: %w", err)
+	}
+	if value != probe.String() {
+		return fmt.Errorf("transaction-scoped RLS context was not visible inside its transaction")
 	}
 
-	return closeErr
+	if spm.config.PgBouncerMode {
+		return nil
+	}
+
+	conn, err := spm.masterDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get validation connection: %w", err)
+	}
+	defer spm.ReleaseConnection(conn)
+
+	if err := spm.setTenantContext(ctx, conn, TenantContext{UserID: probe}, false); err != nil {
+		return err
+	}
+	value = ""
+	if err := conn.QueryRowContext(ctx, "SELECT COALESCE(current_setting('app.current_user_id', true), '')").Scan(&value); err != nil {
+		return fmt.Errorf("failed to read RLS context: %w", err)
+	}
+	if value != probe.String() {
+		return fmt.Errorf("session settings are not preserved between statements; the database appears to be behind a transaction pooler such as PgBouncer, set DB_PGBOUNCER_MODE=true")
+	}
+	return nil
 }
 
 // GetUserSession retrieves user session data from cache or database
@@ -179,6 +293,55 @@ func (spm *StatelessPoolManager) GetUserSession(ctx context.Context, userID uuid
 	return session, nil
 }
 
+// refreshUserSession loads the session from the database, replacing the cached one
+func (spm *StatelessPoolManager) refreshUserSession(ctx context.Context, userID uuid.UUID) (*UserSession, error) {
+	session, err := spm.getUserSessionFromDB(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if spm.redis != nil {
+		spm.cacheUserSession(ctx, session)
+	}
+	return session, nil
+}
+
+// ResolveTenant picks the organization a request of the user acts in: orgID when given, which
+// the user must belong to, otherwise the session's organization
+func (spm *StatelessPoolManager) ResolveTenant(ctx context.Context, userID, orgID uuid.UUID) (TenantContext, error) {
+	tenant := TenantContext{UserID: userID}
+
+	session, err := spm.GetUserSession(ctx, userID)
+	if errors.Is(err, ErrNoOrganization) {
+		if orgID != uuid.Nil {
+			return tenant, ErrNotMember
+		}
+		return tenant, nil
+	}
+	if err != nil {
+		return tenant, err
+	}
+	if orgID == uuid.Nil {
+		tenant.OrgID, tenant.Role = session.OrgID, session.Role
+		return tenant, nil
+	}
+
+	role, ok := session.Organizations[orgID]
+	if !ok {
+		// The cached session may predate the membership
+		if session, err = spm.refreshUserSession(ctx, userID); err != nil && !errors.Is(err, ErrNoOrganization) {
+			return tenant, err
+		}
+		if session != nil {
+			role, ok = session.Organizations[orgID]
+		}
+		if !ok {
+			return tenant, ErrNotMember
+		}
+	}
+	tenant.OrgID, tenant.Role = orgID, role
+	return tenant, nil
+}
+
 // getUserSessionFromCache retrieves user session from Redis
 func (spm *StatelessPoolManager) getUserSessionFromCache(ctx context.Context, userID uuid.UUID) (*UserSession, error) {
 	if spm.redis == nil {
@@ -216,30 +379,27 @@ func (spm *StatelessPoolManager) getUserSessionFromDB(ctx context.Context, userI
 	}
 	defer spm.ReleaseConnection(conn)
 
-	query := `
-		SELECT uor.organization_id, uor.role
-		FROM user_org_roles uor
-		WHERE uor.user_id = $1
-		ORDER BY uor.created_at DESC
-		LIMIT 1
-	`
-
-	var orgID uuid.UUID
-	var role string
-	err = conn.QueryRowContext(ctx, query, userID).Scan(&orgID, &role)
+	// The organization chosen with the organization switch comes first, then the newest membership
+	memberships, err := queries.New(conn).ListSessionMemberships(ctx, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found in any organization")
-		}
 		return nil, fmt.Errorf("failed to query user session: %w", err)
 	}
 
-	return &UserSession{
-		UserID:    userID,
-		OrgID:     orgID,
-		Role:      role,
-		ExpiresAt: time.Now().Add(30 * time.Minute), // Cache for 30 minutes
-	}, nil
+	session := &UserSession{
+		UserID:        userID,
+		Organizations: make(map[uuid.UUID]string),
+		ExpiresAt:     time.Now().Add(30 * time.Minute), // Cache for 30 minutes
+	}
+	for _, m := range memberships {
+		if len(session.Organizations) == 0 {
+			session.OrgID, session.Role = m.OrganizationID, m.Role
+		}
+		session.Organizations[m.OrganizationID] = m.Role
+	}
+	if len(session.Organizations) == 0 {
+		return nil, ErrNoOrganization
+	}
+	return session, nil
 }
 
 // cacheUserSession caches user session in Redis
@@ -272,6 +432,21 @@ func (spm *StatelessPoolManager) GetMasterConnection() *sql.DB {
 	return spm.masterDB
 }
 
+// GetRedisClient returns the Redis client used for the session cache, or nil
+func (spm *StatelessPoolManager) GetRedisClient() *redis.Client {
+	return spm.redis
+}
+
+// Counts returns the provider of the row counts list endpoints paginate
+func (spm *StatelessPoolManager) Counts() CountProvider {
+	return spm.counts
+}
+
+// GetFailoverStatus returns the active primary and failover history
+func (spm *StatelessPoolManager) GetFailoverStatus() FailoverStatus {
+	return spm.failover.Status()
+}
+
 // GetMetrics returns current pool metrics
 func (spm *StatelessPoolManager) GetMetrics() PoolMetrics {
 	spm.mu.RLock()
@@ -283,6 +458,13 @@ func (spm *StatelessPoolManager) GetMetrics() PoolMetrics {
 	metrics := spm.metrics
 	metrics.TotalConnections = int64(dbStats.OpenConnections)
 	metrics.ActiveConnections = int64(dbStats.InUse)
+	metrics.Failovers = spm.failover.Status().Failovers
+	metrics.WaitCount = dbStats.WaitCount
+	metrics.WaitDuration = dbStats.WaitDuration
+	metrics.Sizing = spm.sizer.Status()
+	metrics.ConnectionAcquire = acquireLatency.Snapshot()
+	metrics.ContextSetup = contextLatency.Snapshot()
+	metrics.QueryDuration = queryLatency.Snapshot()
 
 	return metrics
 }
@@ -308,6 +490,12 @@ func (spm *StatelessPoolManager) GetHealth() HealthStatus {
 		status.MasterHealthy = true
 	}
 
+	failover := spm.failover.Status()
+	status.Failover = &failover
+	if failover.ConsecutiveFailures > 0 {
+		status.Errors = append(status.Errors, fmt.Sprintf("Primary %s failed %d consecutive checks", failover.ActivePrimary, failover.ConsecutiveFailures))
+	}
+
 	// Check Redis health if available
 	if spm.redis != nil {
 		if err := spm.redis.Ping(ctx).Err(); err != nil {
@@ -343,6 +531,10 @@ func (spm *StatelessPoolManager) Close() error {
 
 	var lastErr error
 
+	spm.failover.Stop()
+	spm.sizer.Stop()
+	metrics.Registry.Unregister(poolCollector{spm: spm})
+
 	// Close database connection
 	if err := spm.masterDB.Close(); err != nil {
 		log.Printf("ERROR: Failed to close database connection: %v", err)
@@ -361,21 +553,12 @@ func (spm *StatelessPoolManager) Close() error {
 	return lastErr
 }
 
-// recordMetrics updates performance metrics
-func (spm *StatelessPoolManager) recordMetrics(start time.Time) {
+// recordContextSwitch counts a tenant context applied to a pooled connection
+func (spm *StatelessPoolManager) recordContextSwitch() {
 	spm.mu.Lock()
 	defer spm.mu.Unlock()
 
-	duration := time.Since(start)
 	spm.metrics.ContextSwitches++
-
-	// Calculate rolling average
-	if spm.metrics.AverageResponseTime == 0 {
-		spm.metrics.AverageResponseTime = duration
-	} else {
-		// Simple moving average
-		spm.metrics.AverageResponseTime = (spm.metrics.AverageResponseTime + duration) / 2
-	}
 }
 
 // recordError records an error occurrence
@@ -395,4 +578,7 @@ func (spm *StatelessPoolManager) ResetMetrics() {
 	spm.metrics = PoolMetrics{
 		LastReset: time.Now(),
 	}
+	acquireLatency.Reset()
+	contextLatency.Reset()
+	queryLatency.Reset()
 }
\ No newline at end of file
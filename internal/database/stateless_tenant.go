@@ -7,37 +7,87 @@ import (
 	"log"
 	"time"
 
+	"openvdo/internal/queries"
+
 	"github.com/google/uuid"
 )
 
-// StatelessTenantDB represents a database connection with tenant context (stateless version)
+// StatelessTenantDB represents a database connection with tenant context (stateless version).
+// In PgBouncer mode the tenant DB wraps a request-long transaction instead of a pinned
+// connection, since the RLS context only survives within a transaction there.
 type StatelessTenantDB struct {
-	conn     *sql.Conn
-	userID   uuid.UUID
-	pool     *StatelessPoolManager
-	released bool
+	conn       *sql.Conn
+	tx         *sql.Tx
+	tenant     TenantContext
+	pool       *StatelessPoolManager
+	released   bool
+	savepoints int
 }
 
-// NewTenantDB creates a new tenant-aware database connection (stateless version)
-func (spm *StatelessPoolManager) NewTenantDB(ctx context.Context, userID uuid.UUID) (*StatelessTenantDB, error) {
-	conn, err := spm.GetTenantConnection(ctx, userID)
+// NewTenantDB creates a new tenant-aware database connection (stateless version) acting in
+// orgID, or in the user's current organization when orgID is uuid.Nil. ErrNotMember is
+// returned for organizations the user does not belong to.
+func (spm *StatelessPoolManager) NewTenantDB(ctx context.Context, userID, orgID uuid.UUID) (*StatelessTenantDB, error) {
+	tenant, err := spm.ResolveTenant(ctx, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return spm.NewTenantDBFor(ctx, tenant)
+}
+
+// NewTenantDBFor creates a tenant database connection for a tenant resolved elsewhere, such as
+// by the pool manager of another region, without looking up the user's memberships again
+func (spm *StatelessPoolManager) NewTenantDBFor(ctx context.Context, tenant TenantContext) (*StatelessTenantDB, error) {
+	if spm.config.PgBouncerMode {
+		tx, err := spm.beginTenantTransaction(ctx, tenant)
+		if err != nil {
+			return nil, err
+		}
+		return &StatelessTenantDB{
+			tx:     tx,
+			tenant: tenant,
+			pool:   spm,
+		}, nil
+	}
+
+	conn, err := spm.GetTenantConnection(ctx, tenant)
 	if err != nil {
 		return nil, err
 	}
 
 	return &StatelessTenantDB{
 		conn:   conn,
-		userID: userID,
+		tenant: tenant,
 		pool:   spm,
 	}, nil
 }
 
+// querier returns the request transaction in PgBouncer mode, otherwise the pinned connection
+func (t *StatelessTenantDB) querier() Querier {
+	if t.tx != nil {
+		return t.tx
+	}
+	return t.conn
+}
+
 // ExecContext executes a query without returning rows
 func (t *StatelessTenantDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
 	if t.released {
 		return nil, fmt.Errorf("connection has been released")
 	}
-	return t.conn.ExecContext(ctx, query, args...)
+	start := time.Now()
+	defer queryLatency.Since(start)
+	result, err := t.querier().ExecContext(ctx, query, args...)
+	t.trace(ctx, query, args, start, err)
+	return result, err
+}
+
+// PrepareContext prepares a statement on the request transaction or the pinned connection
+func (t *StatelessTenantDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if t.released {
+		return nil, fmt.Errorf("connection has been released")
+	}
+	return t.querier().PrepareContext(ctx, query)
 }
 
 // QueryContext executes a query that returns rows
@@ -45,7 +95,11 @@ func (t *StatelessTenantDB) QueryContext(ctx context.Context, query string, args
 	if t.released {
 		return nil, fmt.Errorf("connection has been released")
 	}
-	return t.conn.QueryContext(ctx, query, args...)
+	start := time.Now()
+	defer queryLatency.Since(start)
+	rows, err := t.querier().QueryContext(ctx, query, args...)
+	t.trace(ctx, query, args, start, err)
+	return rows, err
 }
 
 // QueryRowContext executes a query that returns a single row
@@ -54,14 +108,22 @@ func (t *StatelessTenantDB) QueryRowContext(ctx context.Context, query string, a
 		// Return a row that will error on any operation
 		return &sql.Row{}
 	}
-	return t.conn.QueryRowContext(ctx, query, args...)
+	start := time.Now()
+	defer queryLatency.Since(start)
+	row := t.querier().QueryRowContext(ctx, query, args...)
+	t.trace(ctx, query, args, start, row.Err())
+	return row
 }
 
-// BeginTx starts a transaction with tenant context
+// BeginTx starts a transaction with tenant context. It is unavailable in PgBouncer mode,
+// where the request already runs in a transaction; use WithTransaction instead.
 func (t *StatelessTenantDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
 	if t.released {
 		return nil, fmt.Errorf("connection has been released")
 	}
+	if t.tx != nil {
+		return nil, fmt.Errorf("nested transactions are not supported in PgBouncer mode")
+	}
 	return t.conn.BeginTx(ctx, opts)
 }
 
@@ -74,28 +136,61 @@ func (t *StatelessTenantDB) Ping(ctx context.Context) error {
 	return t.pool.masterDB.PingContext(ctx)
 }
 
-// Release returns the connection to the pool with context cleanup
+// Release returns the connection to the pool with context cleanup. In PgBouncer mode the
+// request transaction is committed, which also discards the RLS context.
 func (t *StatelessTenantDB) Release() error {
 	if t.released {
 		return nil
 	}
 
 	t.released = true
+	if t.tx != nil {
+		return t.tx.Commit()
+	}
 	return t.pool.ReleaseConnection(t.conn)
 }
 
 // GetUserID returns the user ID for this tenant connection
 func (t *StatelessTenantDB) GetUserID() uuid.UUID {
-	return t.userID
+	return t.tenant.UserID
 }
 
-// GetUserSession returns cached user session information
+// GetOrganizationID returns the organization this tenant connection acts in, or uuid.Nil for
+// users without an organization
+func (t *StatelessTenantDB) GetOrganizationID() uuid.UUID {
+	return t.tenant.OrgID
+}
+
+// GetRole returns the user's role in the organization this tenant connection acts in
+func (t *StatelessTenantDB) GetRole() string {
+	return t.tenant.Role
+}
+
+// GetUserSession returns cached user session information, with OrgID and Role those of the
+// organization this tenant connection acts in
 func (t *StatelessTenantDB) GetUserSession(ctx context.Context) (*UserSession, error) {
-	return t.pool.GetUserSession(ctx, t.userID)
+	session, err := t.pool.GetUserSession(ctx, t.tenant.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if t.tenant.OrgID == uuid.Nil || t.tenant.OrgID == session.OrgID {
+		return session, nil
+	}
+	active := *session
+	active.OrgID, active.Role = t.tenant.OrgID, t.tenant.Role
+	return &active, nil
 }
 
-// WithTransaction executes a function within a transaction
-func (t *StatelessTenantDB) WithTransaction(ctx context.Context, fn func(*sql.Tx) error) error {
+// WithTransaction executes a function within a transaction. In PgBouncer mode it runs inside
+// a savepoint of the request transaction so a failure only rolls back fn's changes.
+func (t *StatelessTenantDB) WithTransaction(ctx context.Context, fn func(*sql.Tx) error) (err error) {
+	start := time.Now()
+	defer func() { t.trace(ctx, "-- transaction", nil, start, err) }()
+
+	if t.tx != nil && !t.released {
+		return t.withSavepoint(ctx, fn)
+	}
+
 	tx, err := t.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -109,6 +204,26 @@ func (t *StatelessTenantDB) WithTransaction(ctx context.Context, fn func(*sql.Tx
 	return tx.Commit()
 }
 
+// withSavepoint runs fn on the request transaction between a savepoint and its release
+func (t *StatelessTenantDB) withSavepoint(ctx context.Context, fn func(*sql.Tx) error) error {
+	t.savepoints++
+	name := fmt.Sprintf("tenant_sp_%d", t.savepoints)
+
+	if _, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if err := fn(t.tx); err != nil {
+		if _, rbErr := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			log.Printf("WARN: Failed to roll back to savepoint %s: %v", name, rbErr)
+		}
+		return err
+	}
+
+	_, err := t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
 // StatelessTenantOperations provides high-level operations for tenant data
 type StatelessTenantOperations struct {
 	spm *StatelessPoolManager
@@ -133,13 +248,14 @@ func (sto *StatelessTenantOperations) HasRole(ctx context.Context, userID, orgID
 		return false, fmt.Errorf("session expired")
 	}
 
-	// Check organization match
-	if session.OrgID != orgID {
+	// Check membership of the organization, which need not be the session's current one
+	memberRole, ok := session.Organizations[orgID]
+	if !ok {
 		return false, nil
 	}
 
 	// Check role (if specified)
-	if role != "" && session.Role != role {
+	if role != "" && memberRole != role {
 		return false, nil
 	}
 
@@ -148,39 +264,13 @@ func (sto *StatelessTenantOperations) HasRole(ctx context.Context, userID, orgID
 
 // GetUserOrganizations returns all organizations for a user
 func (sto *StatelessTenantOperations) GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]OrganizationInfo, error) {
-	conn, err := sto.spm.GetTenantConnection(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer sto.spm.ReleaseConnection(conn)
-
-	query := `
-		SELECT o.id, o.name, o.description, o.created_at, o.updated_at, uor.role
-		FROM organizations o
-		JOIN user_org_roles uor ON o.id = uor.organization_id
-		WHERE uor.user_id = $1
-		ORDER BY o.created_at DESC
-	`
-
-	rows, err := conn.QueryContext(ctx, query, userID)
+	conn, err := sto.spm.NewTenantDB(ctx, userID, uuid.Nil)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var orgs []OrganizationInfo
-	for rows.Next() {
-		var org OrganizationInfo
-		if err := rows.Scan(
-			&org.ID, &org.Name, &org.Description,
-			&org.CreatedAt, &org.UpdatedAt, &org.Role,
-		); err != nil {
-			return nil, err
-		}
-		orgs = append(orgs, org)
-	}
+	defer conn.Release()
 
-	return orgs, rows.Err()
+	return organizationInfos(queries.New(conn).ListUserOrganizations(ctx, userID))
 }
 
 // InvalidateUserSession removes cached session for a user (useful after role changes)
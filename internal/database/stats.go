@@ -48,6 +48,7 @@ type HealthStatus struct {
 	LastCheck         time.Time     `json:"last_check"`
 	CheckInterval     time.Duration `json:"check_interval"`
 	PoolType          string        `json:"pool_type"` // "stateless" or "stateful"
+	Failover          *FailoverStatus `json:"failover,omitempty"`
 }
 
 // GetHealth returns the current health status of the pool manager
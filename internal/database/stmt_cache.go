@@ -0,0 +1,130 @@
+package database
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"openvdo/internal/metrics"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	statementCacheLookups = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "db",
+		Name:      "statement_cache_lookups_total",
+		Help:      "Statements with bind parameters looked up in the prepared statement cache of their connection, by result (hit or miss)",
+	}, []string{"result"})
+	statementCacheEvictions = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "db",
+		Name:      "statement_cache_evictions_total",
+		Help:      "Prepared statements closed to make room in a full cache or after the server invalidated them",
+	})
+)
+
+// statementCache keeps the statements one connection prepared, so running the same SQL
+// again skips parsing and planning it. lib/pq otherwise prepares every statement with bind
+// parameters anew, an extra round trip each time. database/sql never uses a driver
+// connection from two goroutines at once, so the cache needs no lock.
+type statementCache struct {
+	capacity int
+	// order lists the cached statements, most recently used first
+	order   *list.List
+	byQuery map[string]*list.Element
+}
+
+type cachedStatement struct {
+	query string
+	stmt  driver.Stmt
+}
+
+func newStatementCache(capacity int) *statementCache {
+	return &statementCache{capacity: capacity, order: list.New(), byQuery: make(map[string]*list.Element)}
+}
+
+// get returns the statement prepared for query, preparing it on a miss and closing the least
+// recently used one when the cache is full
+func (sc *statementCache) get(ctx context.Context, conn driver.ConnPrepareContext, query string) (driver.Stmt, error) {
+	if el, ok := sc.byQuery[query]; ok {
+		statementCacheLookups.WithLabelValues("hit").Inc()
+		sc.order.MoveToFront(el)
+		return el.Value.(*cachedStatement).stmt, nil
+	}
+	statementCacheLookups.WithLabelValues("miss").Inc()
+
+	if sc.order.Len() >= sc.capacity {
+		sc.evict(sc.order.Back().Value.(*cachedStatement).query)
+	}
+	stmt, err := conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	sc.byQuery[query] = sc.order.PushFront(&cachedStatement{query: query, stmt: stmt})
+	return stmt, nil
+}
+
+// evict closes the statement prepared for query, if any
+func (sc *statementCache) evict(query string) {
+	el, ok := sc.byQuery[query]
+	if !ok {
+		return
+	}
+	sc.order.Remove(el)
+	delete(sc.byQuery, query)
+	statementCacheEvictions.Inc()
+	el.Value.(*cachedStatement).stmt.Close()
+}
+
+// checkInvalidated drops the statement for query when the server no longer accepts it, such
+// as after a migration changed the columns it returns, so it is prepared again next time
+func (sc *statementCache) checkInvalidated(query string, err error) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return
+	}
+	switch pqErr.Code {
+	case "0A000", // cached plan must not change result type
+		"26000": // prepared statement does not exist
+		sc.evict(query)
+	}
+}
+
+// QueryContext implements driver.QueryerContext, running statements with bind parameters
+// through the connection's statement cache. Statements without parameters keep using the
+// simple query protocol, which needs a single round trip already.
+func (c *failoverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.stmts == nil || len(args) == 0 {
+		return c.pqConn.QueryContext(ctx, query, args)
+	}
+	stmt, err := c.stmts.get(ctx, c.pqConn, query)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	if err != nil {
+		c.stmts.checkInvalidated(query, err)
+	}
+	return rows, err
+}
+
+// ExecContext implements driver.ExecerContext like QueryContext
+func (c *failoverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.stmts == nil || len(args) == 0 {
+		return c.pqConn.ExecContext(ctx, query, args)
+	}
+	stmt, err := c.stmts.get(ctx, c.pqConn, query)
+	if err != nil {
+		return nil, err
+	}
+	result, err := stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	if err != nil {
+		c.stmts.checkInvalidated(query, err)
+	}
+	return result, err
+}
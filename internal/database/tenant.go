@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"openvdo/internal/queries"
 	"openvdo/pkg/logger"
 
 	"github.com/google/uuid"
@@ -41,6 +42,14 @@ func (t *TenantDB) ExecContext(ctx context.Context, query string, args ...interf
 	return t.conn.ExecContext(ctx, query, args...)
 }
 
+// PrepareContext prepares a statement on the tenant connection
+func (t *TenantDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if t.released {
+		return nil, fmt.Errorf("connection has been released")
+	}
+	return t.conn.PrepareContext(ctx, query)
+}
+
 // QueryContext executes a query that returns rows
 func (t *TenantDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
 	if t.released {
@@ -165,32 +174,17 @@ func NewTenantOperations(pm *PoolManager) *TenantOperations {
 
 // CreateUserOrganization creates a new user-organization relationship
 func (to *TenantOperations) CreateUserOrganization(ctx context.Context, userID, orgID uuid.UUID, role string) error {
-	query := `
-		INSERT INTO user_org_roles (user_id, organization_id, role)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, organization_id)
-		DO UPDATE SET role = $3, updated_at = NOW()
-	`
-
-	_, err := to.pm.masterDB.ExecContext(ctx, query, userID, orgID, role)
-	return err
+	return queries.New(to.pm.masterDB).GrantRole(ctx, queries.GrantRoleParams{
+		UserID: userID, OrganizationID: orgID, Role: role,
+	})
 }
 
 // HasRole checks if a user has a specific role in an organization
 func (to *TenantOperations) HasRole(ctx context.Context, userID, orgID uuid.UUID, role string) (bool, error) {
-	var count int
-	query := `
-		SELECT COUNT(*) FROM user_org_roles
-		WHERE user_id = $1 AND organization_id = $2
-	`
-
-	args := []interface{}{userID, orgID}
-	if role != "" {
-		query += " AND role = $3"
-		args = append(args, role)
-	}
-
-	err := to.pm.masterDB.QueryRowContext(ctx, query, args...).Scan(&count)
+	// An empty role matches any
+	count, err := queries.New(to.pm.masterDB).CountMemberships(ctx, queries.CountMembershipsParams{
+		UserID: userID, OrganizationID: orgID, Role: role,
+	})
 	if err != nil {
 		return false, err
 	}
@@ -200,33 +194,19 @@ func (to *TenantOperations) HasRole(ctx context.Context, userID, orgID uuid.UUID
 
 // GetUserOrganizations returns all organizations for a user
 func (to *TenantOperations) GetUserOrganizations(ctx context.Context, userID uuid.UUID) ([]OrganizationInfo, error) {
-	query := `
-		SELECT o.id, o.name, o.description, o.created_at, o.updated_at, uor.role
-		FROM organizations o
-		JOIN user_org_roles uor ON o.id = uor.organization_id
-		WHERE uor.user_id = $1
-		ORDER BY o.created_at DESC
-	`
-
-	rows, err := to.pm.masterDB.QueryContext(ctx, query, userID)
+	return organizationInfos(queries.New(to.pm.masterDB).ListUserOrganizations(ctx, userID))
+}
+
+// organizationInfos converts the rows of ListUserOrganizations, passing their error through
+func organizationInfos(rows []queries.ListUserOrganizationsRow, err error) ([]OrganizationInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
 	var orgs []OrganizationInfo
-	for rows.Next() {
-		var org OrganizationInfo
-		if err := rows.Scan(
-			&org.ID, &org.Name, &org.Description,
-			&org.CreatedAt, &org.UpdatedAt, &org.Role,
-		); err != nil {
-			return nil, err
-		}
-		orgs = append(orgs, org)
+	for _, row := range rows {
+		orgs = append(orgs, OrganizationInfo(row))
 	}
-
-	return orgs, rows.Err()
+	return orgs, nil
 }
 
 // OrganizationInfo represents organization information with user role
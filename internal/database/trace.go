@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"openvdo/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// maxLoggedQuery bounds the SQL text of a slow query log line
+const maxLoggedQuery = 2000
+
+var slowQueries = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "db",
+	Name:      "slow_queries_total",
+	Help:      "Tenant statements that took at least DB_SLOW_QUERY_THRESHOLD",
+})
+
+// QueryTracer is told about each statement run on a tenant connection for a request.
+// Statements run on a transaction's *sql.Tx are not seen one by one; the transaction is
+// reported as a whole when it ends.
+type QueryTracer interface {
+	TraceQuery(query string, duration time.Duration, err error)
+}
+
+type queryTracersKey struct{}
+
+// WithQueryTracer returns a context whose tenant connection statements are also reported to
+// tracer
+func WithQueryTracer(ctx context.Context, tracer QueryTracer) context.Context {
+	existing, _ := ctx.Value(queryTracersKey{}).([]QueryTracer)
+	tracers := make([]QueryTracer, len(existing), len(existing)+1)
+	copy(tracers, existing)
+	return context.WithValue(ctx, queryTracersKey{}, append(tracers, tracer))
+}
+
+// QueryCounter counts the statements of a request
+type QueryCounter struct {
+	n atomic.Int64
+}
+
+// TraceQuery counts one statement
+func (c *QueryCounter) TraceQuery(string, time.Duration, error) {
+	c.n.Add(1)
+}
+
+// Count returns the statements counted so far
+func (c *QueryCounter) Count() int64 {
+	return c.n.Load()
+}
+
+// trace reports a statement started at start to the context's tracers and logs it when it
+// reached the slow query threshold. Bind parameters are logged by type only, since they
+// hold user data.
+func (t *StatelessTenantDB) trace(ctx context.Context, query string, args []interface{}, start time.Time, err error) {
+	duration := time.Since(start)
+	if threshold := t.pool.config.SlowQueryThreshold; threshold > 0 && duration >= threshold {
+		slowQueries.Inc()
+		compact := strings.Join(strings.Fields(query), " ")
+		if len(compact) > maxLoggedQuery {
+			compact = compact[:maxLoggedQuery] + "..."
+		}
+		log.Printf("WARN: Slow query took %v (org %s): %s%s", duration.Round(time.Millisecond), t.tenant.OrgID,
+			compact, describeArgs(args))
+	}
+
+	tracers, _ := ctx.Value(queryTracersKey{}).([]QueryTracer)
+	for _, tracer := range tracers {
+		tracer.TraceQuery(query, duration, err)
+	}
+}
+
+// describeArgs lists the types of bind parameters, such as " [$1 uuid.UUID, $2 string]"
+func describeArgs(args []interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = fmt.Sprintf("$%d %T", i+1, arg)
+	}
+	return " [" + strings.Join(types, ", ") + "]"
+}
@@ -0,0 +1,246 @@
+// Package egress shapes the bandwidth each organization's media is served at, so one tenant
+// on a small plan cannot saturate an instance's uplink. Every organization has a token bucket
+// per instance that all of its streams draw from.
+package egress
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/metrics"
+	"openvdo/internal/middleware"
+	"openvdo/internal/queries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+// maxChunk bounds what a stream sends per turn. Streams take turns with the tokens of their
+// organization chunk by chunk, so smaller chunks share the bandwidth more evenly.
+const maxChunk = 32 * 1024
+
+// idleBucket is how long a bucket goes unused before it is dropped, so the shaper does not
+// keep one for every organization that ever served media
+const idleBucket = 10 * time.Minute
+
+var throttled = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "egress",
+	Name:      "throttled_seconds_total",
+	Help:      "Time responses spent waiting for the egress limit of their organization",
+})
+
+// Shaper holds the token buckets of organizations. Limits are cached per instance for the
+// cache TTL, so a change reaches every instance within it.
+type Shaper struct {
+	db           *sql.DB
+	defaultLimit int64
+	burst        int
+	cacheTTL     time.Duration
+
+	mu      sync.Mutex
+	buckets map[uuid.UUID]*bucket
+	// swept is when idle buckets were last dropped
+	swept time.Time
+}
+
+type bucket struct {
+	// limiter is nil while the organization is unlimited
+	limiter *rate.Limiter
+	expires time.Time
+	// used is when a response last took the bucket
+	used time.Time
+}
+
+// NewShaper creates a shaper reading limits through db, which must not carry a tenant context
+func NewShaper(db *sql.DB, cfg config.Egress) *Shaper {
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = maxChunk
+	}
+	return &Shaper{
+		db: db, defaultLimit: cfg.DefaultLimit, burst: burst, cacheTTL: cfg.CacheTTL,
+		buckets: make(map[uuid.UUID]*bucket),
+	}
+}
+
+// Limit returns an organization's own limit in bytes per second, nil when it follows the
+// default
+func (s *Shaper) Limit(ctx context.Context, orgID uuid.UUID) (*int64, error) {
+	return queries.New(s.db).GetEgressLimit(ctx, orgID)
+}
+
+// SetLimit sets an organization's limit in bytes per second: 0 is unlimited and nil follows
+// the default. Other instances apply it once their cached copy expires.
+func (s *Shaper) SetLimit(ctx context.Context, orgID uuid.UUID, limit *int64) error {
+	n, err := queries.New(s.db).SetEgressLimit(ctx, queries.SetEgressLimitParams{ID: orgID, EgressLimit: limit})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	s.mu.Lock()
+	if b, ok := s.buckets[orgID]; ok {
+		b.expires = time.Time{}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// limiter returns the bucket of an organization, nil when it is unlimited. A bucket outlives
+// changes to its limit, so streams under way keep sharing it with new ones.
+func (s *Shaper) limiter(ctx context.Context, orgID uuid.UUID) (*rate.Limiter, error) {
+	now := time.Now()
+	s.mu.Lock()
+	s.sweep(now)
+	b, ok := s.buckets[orgID]
+	if ok && now.Before(b.expires) {
+		b.used = now
+		s.mu.Unlock()
+		return b.limiter, nil
+	}
+	s.mu.Unlock()
+
+	limit, err := s.Limit(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	bytesPerSecond := s.defaultLimit
+	if limit != nil {
+		bytesPerSecond = *limit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok = s.buckets[orgID]
+	if !ok {
+		b = &bucket{}
+		s.buckets[orgID] = b
+	}
+	b.used = time.Now()
+	b.expires = b.used.Add(s.cacheTTL)
+	switch {
+	case bytesPerSecond <= 0:
+		b.limiter = nil
+	case b.limiter == nil:
+		b.limiter = rate.NewLimiter(rate.Limit(bytesPerSecond), s.burst)
+	default:
+		b.limiter.SetLimit(rate.Limit(bytesPerSecond))
+	}
+	return b.limiter, nil
+}
+
+// sweep drops the buckets no response took for idleBucket, at most once per idleBucket. A
+// bucket still refilling was drawn from lately by a stream under way, and is kept so that the
+// stream and new ones go on sharing it. s.mu must be held.
+func (s *Shaper) sweep(now time.Time) {
+	if now.Sub(s.swept) < idleBucket {
+		return
+	}
+	s.swept = now
+	for orgID, b := range s.buckets {
+		if now.Sub(b.used) < idleBucket {
+			continue
+		}
+		if b.limiter != nil && b.limiter.TokensAt(now) < float64(b.limiter.Burst()) {
+			continue
+		}
+		delete(s.buckets, orgID)
+	}
+}
+
+// Shape has the rest of the response draw from the bucket of the organization serving it.
+// The response is sent unshaped when the limit cannot be read, as media should rather play
+// than fail over a lookup.
+func (s *Shaper) Shape(c *gin.Context, orgID uuid.UUID) {
+	limiter, err := s.limiter(c.Request.Context(), orgID)
+	if err != nil {
+		log.Printf("WARN: Failed to read egress limit of organization %s: %v", orgID, err)
+		return
+	}
+	if limiter == nil {
+		return
+	}
+	c.Writer = &shapedWriter{
+		ResponseWriter: c.Writer, ctx: c.Request.Context(), limiter: limiter,
+		chunk: min(maxChunk, s.burst),
+	}
+}
+
+// shapedWriter waits for the tokens of each chunk before writing it. The limiter grants
+// tokens in the order they are asked for, so concurrent streams of an organization alternate
+// chunk by chunk and each gets an even share, whatever their size.
+type shapedWriter struct {
+	gin.ResponseWriter
+	ctx     context.Context
+	limiter *rate.Limiter
+	chunk   int
+}
+
+func (w *shapedWriter) wait(n int) error {
+	start := time.Now()
+	err := w.limiter.WaitN(w.ctx, n)
+	throttled.Add(time.Since(start).Seconds())
+	return err
+}
+
+func (w *shapedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), w.chunk)
+		if err := w.wait(n); err != nil {
+			return written, err
+		}
+		n, err := w.ResponseWriter.Write(p[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+func (w *shapedWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// ReadFrom passes chunks on to the wrapped writer's ReadFrom, so files are still sent with
+// sendfile(2)
+func (w *shapedWriter) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	for {
+		size := int64(w.chunk)
+		part := io.LimitReader(r, size)
+		// The connection sees through one limit to the file beneath, not through two
+		lr, limited := r.(*io.LimitedReader)
+		if limited {
+			size = min(size, lr.N)
+			part = io.LimitReader(lr.R, size)
+		}
+		if size <= 0 {
+			return total, nil
+		}
+		if err := w.wait(int(size)); err != nil {
+			return total, err
+		}
+
+		n, err := middleware.ReadFrom(w.ResponseWriter, part)
+		total += n
+		if limited {
+			lr.N -= n
+		}
+		if err != nil || n < size {
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package errortracking
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Event is an error as sent to the tracker. It follows Sentry's event payload, which other
+// collectors receiving the webhook can read as well.
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	LogEntry    *LogEntry         `json:"logentry,omitempty"`
+	Exception   *Exceptions       `json:"exception,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	User        *User             `json:"user,omitempty"`
+	Request     *Request          `json:"request,omitempty"`
+}
+
+// LogEntry is a logged message. Events are grouped by Message, the format string, so errors
+// differing only in IDs land in one issue.
+type LogEntry struct {
+	Message   string `json:"message"`
+	Formatted string `json:"formatted"`
+}
+
+// Exceptions lists the exceptions of an event, the innermost last
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+// Exception is a panic with the stack it was raised on
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+}
+
+// Stacktrace lists frames from the outermost call to the innermost
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is one function call of a stack
+type Frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	AbsPath  string `json:"abs_path,omitempty"`
+	Lineno   int    `json:"lineno,omitempty"`
+	InApp    bool   `json:"in_app"`
+}
+
+// User identifies the user a request was made by
+type User struct {
+	ID string `json:"id"`
+}
+
+// Request describes the request an error happened in. The query string is left out since it
+// can carry tokens.
+type Request struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// SetTag sets a tag, skipping empty values
+func (e *Event) SetTag(name, value string) {
+	if value == "" {
+		return
+	}
+	if e.Tags == nil {
+		e.Tags = map[string]string{}
+	}
+	e.Tags[name] = value
+}
+
+// NewPanicEvent describes a recovered panic. It must be called from the deferred function
+// that recovered it, whose stack still holds the frames that panicked.
+func NewPanicEvent(recovered interface{}) *Event {
+	typ := "panic"
+	if err, ok := recovered.(error); ok {
+		typ = fmt.Sprintf("%T", err)
+	}
+	return &Event{
+		Level: "fatal",
+		Exception: &Exceptions{Values: []Exception{{
+			Type:       typ,
+			Value:      fmt.Sprint(recovered),
+			Stacktrace: panicStacktrace(),
+		}}},
+	}
+}
+
+// panicStacktrace returns the stack below runtime.gopanic, dropping the recovering frames
+func panicStacktrace() *Stacktrace {
+	pcs := make([]uintptr, 100)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.gopanic" {
+			stack = stack[:0]
+		} else if !strings.HasPrefix(f.Function, "runtime.") {
+			stack = append(stack, newFrame(f))
+		}
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return &Stacktrace{Frames: stack}
+}
+
+// newFrame splits a qualified function name such as openvdo/internal/handlers.(*H).Get into
+// its package and function
+func newFrame(f runtime.Frame) Frame {
+	module, function := "", f.Function
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		module, function = function[:slash+1+dot], function[slash+1+dot+1:]
+	}
+	return Frame{
+		Function: function,
+		Module:   module,
+		AbsPath:  f.File,
+		Lineno:   f.Line,
+		InApp:    strings.HasPrefix(module, "openvdo/"),
+	}
+}
@@ -0,0 +1,149 @@
+package errortracking
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Sink receives events one at a time
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, e *Event) error
+}
+
+// errRateLimited is returned while a sink asked for no more events
+var errRateLimited = errors.New("rate limited")
+
+// sentrySink sends events to a Sentry project's envelope endpoint. When Sentry answers 429
+// it drops events until the Retry-After delay has passed.
+type sentrySink struct {
+	dsn      string
+	endpoint string
+	auth     string
+	client   *http.Client
+	until    atomic.Int64
+}
+
+// newSentrySink parses a DSN of the form https://key@host/project-id
+func newSentrySink(dsn string, timeout time.Duration) (*sentrySink, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User == nil {
+		return nil, fmt.Errorf("invalid Sentry DSN, expected https://key@host/project-id")
+	}
+	key := u.User.Username()
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project := path[slash+1:]
+	if key == "" || project == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN, expected https://key@host/project-id")
+	}
+	return &sentrySink{
+		dsn:      dsn,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:slash], project),
+		auth:     fmt.Sprintf("Sentry sentry_version=7, sentry_key=%s, sentry_client=openvdo/1.0", key),
+		client:   &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *sentrySink) Name() string {
+	return "sentry"
+}
+
+func (s *sentrySink) Send(ctx context.Context, e *Event) error {
+	if time.Now().UnixNano() < s.until.Load() {
+		return errRateLimited
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	header, _ := json.Marshal(map[string]string{
+		"event_id": e.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+		"dsn":      s.dsn,
+	})
+	item, _ := json.Marshal(map[string]interface{}{"type": "event", "length": len(payload)})
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(item)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait := 60 * time.Second
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		s.until.Store(time.Now().Add(wait).UnixNano())
+		return errRateLimited
+	}
+	return checkResponse(resp)
+}
+
+// webhookSink posts events as JSON to any collector
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func newWebhookSink(raw string, timeout time.Duration) (*webhookSink, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("ERROR_TRACKING_WEBHOOK_URL must be an http or https URL")
+	}
+	return &webhookSink{url: raw, client: &http.Client{Timeout: timeout}}, nil
+}
+
+func (s *webhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *webhookSink) Send(ctx context.Context, e *Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("answered %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
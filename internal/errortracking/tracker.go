@@ -0,0 +1,209 @@
+// Package errortracking reports panics and logged errors to Sentry or to any collector
+// accepting Sentry's event JSON on a webhook.
+package errortracking
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"openvdo/internal/buildinfo"
+	"openvdo/internal/config"
+	"openvdo/internal/metrics"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of events, as counted by eventsTotal
+const (
+	OutcomeSent        = "sent"
+	OutcomeSampledOut  = "sampled_out"
+	OutcomeDropped     = "dropped"
+	OutcomeRateLimited = "rate_limited"
+	OutcomeFailed      = "failed"
+)
+
+var eventsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "error_tracking",
+	Name:      "events_total",
+	Help:      "Error events, by sink and outcome",
+}, []string{"sink", "outcome"})
+
+// Tracker sends events to its sinks in the background. Without sinks it does nothing, so
+// callers need not check whether error tracking is configured.
+type Tracker struct {
+	config     config.ErrorTracking
+	sinks      []Sink
+	release    string
+	serverName string
+	queue      chan *Event
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a tracker for the configured Sentry DSN and webhook
+func New(cfg config.ErrorTracking) (*Tracker, error) {
+	var sinks []Sink
+	if cfg.SentryDSN != "" {
+		sink, err := newSentrySink(cfg.SentryDSN, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.WebhookURL != "" {
+		sink, err := newWebhookSink(cfg.WebhookURL, cfg.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	release := cfg.Release
+	if release == "" {
+		release = buildinfo.Version()
+	}
+	serverName, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Tracker{
+		config:     cfg,
+		sinks:      sinks,
+		release:    release,
+		serverName: serverName,
+		queue:      make(chan *Event, cfg.BufferSize),
+		ctx:        ctx,
+		cancel:     cancel,
+	}, nil
+}
+
+// Enabled reports whether events go anywhere
+func (t *Tracker) Enabled() bool {
+	return len(t.sinks) > 0
+}
+
+// Capture queues an event without blocking, filling in its ID, time and the release
+func (t *Tracker) Capture(e *Event) {
+	if !t.Enabled() {
+		return
+	}
+	t.prepare(e)
+	select {
+	case t.queue <- e:
+	default:
+		t.count(OutcomeDropped)
+	}
+}
+
+func (t *Tracker) prepare(e *Event) {
+	if e.EventID == "" {
+		e.EventID = strings.ReplaceAll(uuid.NewString(), "-", "")
+	}
+	if commit := buildinfo.Get().Commit; commit != "" {
+		e.SetTag("commit", commit)
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.Level == "" {
+		e.Level = "error"
+	}
+	e.Platform = "go"
+	e.ServerName = t.serverName
+	e.Release = t.release
+	e.Environment = t.config.Environment
+}
+
+// LogHook returns the hook reporting errors logged through pkg/logger. Errors are sampled at
+// SampleRate; fatal ones are always sent, before the process exits.
+func (t *Tracker) LogHook() logger.Hook {
+	return func(level, format string, args []interface{}) {
+		e := &Event{
+			Level:    level,
+			Logger:   "openvdo",
+			LogEntry: &LogEntry{Message: format, Formatted: fmt.Sprintf(format, args...)},
+		}
+		if level == logger.LevelFatal {
+			t.prepare(e)
+			t.send(e)
+			return
+		}
+		if rate := t.config.SampleRate; rate < 1 && rand.Float64() >= rate {
+			t.count(OutcomeSampledOut)
+			return
+		}
+		t.Capture(e)
+	}
+}
+
+// Start sends queued events as they come
+func (t *Tracker) Start() {
+	if !t.Enabled() {
+		return
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		for {
+			select {
+			case <-t.ctx.Done():
+				t.drain()
+				return
+			case e := <-t.queue:
+				t.send(e)
+			}
+		}
+	}()
+}
+
+// Stop sends the events still queued and stops the tracker
+func (t *Tracker) Stop() {
+	t.cancel()
+	t.wg.Wait()
+}
+
+func (t *Tracker) drain() {
+	for {
+		select {
+		case e := <-t.queue:
+			t.send(e)
+		default:
+			return
+		}
+	}
+}
+
+func (t *Tracker) send(e *Event) {
+	for _, sink := range t.sinks {
+		ctx, cancel := context.WithTimeout(context.Background(), t.config.Timeout)
+		err := sink.Send(ctx, e)
+		cancel()
+		switch {
+		case err == errRateLimited:
+			eventsTotal.WithLabelValues(sink.Name(), OutcomeRateLimited).Inc()
+		case err != nil:
+			// Written past pkg/logger, whose errors would be reported again
+			log.Printf("[ERROR] Failed to send error event %s to %s: %v\n", e.EventID, sink.Name(), err)
+			eventsTotal.WithLabelValues(sink.Name(), OutcomeFailed).Inc()
+		default:
+			eventsTotal.WithLabelValues(sink.Name(), OutcomeSent).Inc()
+		}
+	}
+}
+
+// count records an outcome of an event that reached no sink
+func (t *Tracker) count(outcome string) {
+	for _, sink := range t.sinks {
+		eventsTotal.WithLabelValues(sink.Name(), outcome).Inc()
+	}
+}
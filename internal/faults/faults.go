@@ -0,0 +1,170 @@
+// Package faults injects failures into a running instance for resilience testing: answers
+// with server errors, slow tenant statements and failing Redis commands. It is meant for
+// staging, where client retries and backoff and the server's degraded paths can be watched
+// against failures that otherwise rarely happen on cue.
+package faults
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// InjectedHeader marks responses a fault was injected into, with the kind of fault
+const InjectedHeader = "X-Fault-Injected"
+
+// Kinds of faults, as counted by injected
+const (
+	KindError     = "error"
+	KindDBLatency = "db_latency"
+	KindRedisDrop = "redis_drop"
+)
+
+// ErrRedisDropped is what Redis commands failed by the injector return
+var ErrRedisDropped = &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection dropped by fault injection")}
+
+var injected = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "faults",
+	Name:      "injected_total",
+	Help:      "Faults injected for resilience testing, by kind (error, db_latency or redis_drop)",
+}, []string{"kind"})
+
+// Injector decides which requests, statements and commands fail. A disabled injector
+// leaves everything alone.
+type Injector struct {
+	cfg config.Faults
+}
+
+// New returns an injector for cfg, warning loudly when it is enabled. Error statuses outside
+// 4xx and 5xx become 503.
+func New(cfg config.Faults) *Injector {
+	if cfg.ErrorStatus < 400 || cfg.ErrorStatus > 599 {
+		cfg.ErrorStatus = http.StatusServiceUnavailable
+	}
+	if cfg.Enabled {
+		log.Printf("WARN: Fault injection is enabled: %.0f%% of requests fail with %d, %.0f%% of tenant statements are delayed by %v and %.0f%% of Redis commands fail",
+			cfg.ErrorRate*100, cfg.ErrorStatus, cfg.DBLatencyRate*100, cfg.DBLatency, cfg.RedisDropRate*100)
+	}
+	return &Injector{cfg: cfg}
+}
+
+// Enabled tells whether any fault is injected
+func (i *Injector) Enabled() bool {
+	return i.cfg.Enabled
+}
+
+func (i *Injector) applies(path string) bool {
+	if len(i.cfg.Paths) == 0 {
+		return true
+	}
+	for _, prefix := range i.cfg.Paths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware fails a share of the requests under the configured paths before their handler
+// runs, and slows down the tenant statements of a share of the others
+func (i *Injector) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !i.cfg.Enabled || !i.applies(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		if i.cfg.ErrorRate > 0 && rand.Float64() < i.cfg.ErrorRate {
+			injected.WithLabelValues(KindError).Inc()
+			c.Header(InjectedHeader, KindError)
+			if i.cfg.ErrorStatus == http.StatusServiceUnavailable || i.cfg.ErrorStatus == http.StatusTooManyRequests {
+				c.Header("Retry-After", strconv.Itoa(int(i.cfg.RetryAfter.Seconds())))
+			}
+			c.AbortWithStatusJSON(i.cfg.ErrorStatus, gin.H{"error": "Injected fault"})
+			return
+		}
+
+		if i.cfg.DBLatency > 0 && i.cfg.DBLatencyRate > 0 {
+			ctx := c.Request.Context()
+			c.Request = c.Request.WithContext(database.WithQueryTracer(ctx, &latencyTracer{ctx: ctx, injector: i}))
+		}
+		c.Next()
+	}
+}
+
+// latencyTracer holds up the request after a share of its tenant statements, with the
+// connection still taken, as a slow database would
+type latencyTracer struct {
+	ctx      context.Context
+	injector *Injector
+}
+
+func (t *latencyTracer) TraceQuery(string, time.Duration, error) {
+	if rand.Float64() >= t.injector.cfg.DBLatencyRate {
+		return
+	}
+	injected.WithLabelValues(KindDBLatency).Inc()
+	timer := time.NewTimer(t.injector.cfg.DBLatency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-t.ctx.Done():
+	}
+}
+
+// RedisHook fails a share of Redis commands with a dropped connection error, before they
+// are sent
+func (i *Injector) RedisHook() redis.Hook {
+	return redisHook{injector: i}
+}
+
+type redisHook struct {
+	injector *Injector
+}
+
+func (h redisHook) drop() bool {
+	rate := h.injector.cfg.RedisDropRate
+	if !h.injector.cfg.Enabled || rate <= 0 || rand.Float64() >= rate {
+		return false
+	}
+	injected.WithLabelValues(KindRedisDrop).Inc()
+	return true
+}
+
+func (h redisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if h.drop() {
+		return ctx, ErrRedisDropped
+	}
+	return ctx, nil
+}
+
+func (h redisHook) AfterProcess(context.Context, redis.Cmder) error {
+	return nil
+}
+
+func (h redisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if h.drop() {
+		return ctx, ErrRedisDropped
+	}
+	return ctx, nil
+}
+
+func (h redisHook) AfterProcessPipeline(context.Context, []redis.Cmder) error {
+	return nil
+}
@@ -0,0 +1,315 @@
+// Package flags evaluates feature flags, so risky features such as a new packager or LL-HLS can
+// be turned on for a few organizations, then a growing share of them, and switched off again
+// without a deploy.
+package flags
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"regexp"
+	"sync"
+	"time"
+
+	"openvdo/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned for flags, or overrides of organizations, that do not exist
+var ErrNotFound = errors.New("feature flag not found")
+
+// cacheKey holds every flag and override as one JSON document, since both are few and every
+// evaluation needs them
+const cacheKey = "flags:snapshot"
+
+// redisTTL bounds how long flags changed in the database directly, rather than through the
+// Store, stay cached in Redis
+const redisTTL = 10 * time.Minute
+
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+// ValidKey reports whether key can name a flag: lowercase letters, digits, dots, dashes and
+// underscores, such as "packager.v2" or "ll-hls"
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Flag is a feature flag. A disabled flag is off for every organization. An enabled one is on
+// for the organizations overridden to on, off for those overridden to off, and on for
+// Percentage percent of the others.
+type Flag struct {
+	Key         string             `json:"key"`
+	Description string             `json:"description"`
+	Enabled     bool               `json:"enabled"`
+	Percentage  int                `json:"percentage"`
+	Overrides   map[uuid.UUID]bool `json:"overrides"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// EnabledFor reports whether the flag is on for the organization
+func (f *Flag) EnabledFor(orgID uuid.UUID) bool {
+	if !f.Enabled {
+		return false
+	}
+	if on, ok := f.Overrides[orgID]; ok {
+		return on
+	}
+	return bucket(f.Key, orgID) < f.Percentage
+}
+
+// bucket places an organization in one of 100 buckets per flag. Raising a flag's percentage
+// only adds organizations, and different flags reach different organizations first.
+func bucket(key string, orgID uuid.UUID) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write(orgID[:])
+	return int(h.Sum32() % 100)
+}
+
+type snapshot struct {
+	Flags    map[string]*Flag `json:"flags"`
+	loadedAt time.Time
+}
+
+// Store reads and changes flags. Evaluations are served from a snapshot kept in memory for
+// cacheTTL and shared through Redis, so changes reach every instance within cacheTTL.
+type Store struct {
+	db       *sql.DB
+	redis    *redis.Client
+	cacheTTL time.Duration
+
+	mu       sync.Mutex
+	snapshot *snapshot
+}
+
+// NewStore creates a store. db must not carry a tenant context; redis may be nil.
+func NewStore(db *sql.DB, redisClient *redis.Client, cacheTTL time.Duration) *Store {
+	return &Store{db: db, redis: redisClient, cacheTTL: cacheTTL}
+}
+
+// Enabled reports whether the flag is on for the organization. Unknown flags are off, and so
+// are all flags while they cannot be loaded for the first time.
+func (s *Store) Enabled(ctx context.Context, key string, orgID uuid.UUID) bool {
+	f := s.load(ctx).Flags[key]
+	return f != nil && f.EnabledFor(orgID)
+}
+
+// Evaluate returns whether each flag is on for the organization
+func (s *Store) Evaluate(ctx context.Context, orgID uuid.UUID) map[string]bool {
+	flags := s.load(ctx).Flags
+	out := make(map[string]bool, len(flags))
+	for key, f := range flags {
+		out[key] = f.EnabledFor(orgID)
+	}
+	return out
+}
+
+// load returns the cached snapshot, refreshing it from Redis or the database once it is older
+// than cacheTTL. When refreshing fails the previous snapshot is kept.
+func (s *Store) load(ctx context.Context) *snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.snapshot != nil && time.Since(s.snapshot.loadedAt) < s.cacheTTL {
+		return s.snapshot
+	}
+
+	snap, err := s.fetch(ctx)
+	if err != nil {
+		logger.Error("Failed to load feature flags: %v", err)
+		if s.snapshot != nil {
+			return s.snapshot
+		}
+		return &snapshot{}
+	}
+	snap.loadedAt = time.Now()
+	s.snapshot = snap
+	return snap
+}
+
+func (s *Store) fetch(ctx context.Context) (*snapshot, error) {
+	if s.redis != nil {
+		data, err := s.redis.Get(ctx, cacheKey).Bytes()
+		if err == nil {
+			var snap snapshot
+			if err := json.Unmarshal(data, &snap); err == nil {
+				return &snap, nil
+			}
+		} else if err != redis.Nil {
+			logger.Error("Failed to read feature flags from Redis: %v", err)
+		}
+	}
+
+	flags, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{Flags: make(map[string]*Flag, len(flags))}
+	for _, f := range flags {
+		snap.Flags[f.Key] = f
+	}
+
+	if s.redis != nil {
+		if data, err := json.Marshal(snap); err == nil {
+			if err := s.redis.Set(ctx, cacheKey, data, redisTTL).Err(); err != nil {
+				logger.Error("Failed to cache feature flags in Redis: %v", err)
+			}
+		}
+	}
+	return snap, nil
+}
+
+// invalidate drops the cached snapshots after a change. Other instances keep theirs until it
+// is older than cacheTTL.
+func (s *Store) invalidate(ctx context.Context) {
+	s.mu.Lock()
+	s.snapshot = nil
+	s.mu.Unlock()
+
+	if s.redis != nil {
+		if err := s.redis.Del(ctx, cacheKey).Err(); err != nil {
+			logger.Error("Failed to invalidate cached feature flags: %v", err)
+		}
+	}
+}
+
+// List returns every flag with its overrides, read from the database
+func (s *Store) List(ctx context.Context) ([]*Flag, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT key, description, enabled, percentage, created_at, updated_at
+		FROM feature_flags ORDER BY key
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := []*Flag{}
+	byKey := map[string]*Flag{}
+	for rows.Next() {
+		f := &Flag{Overrides: map[uuid.UUID]bool{}}
+		if err := rows.Scan(&f.Key, &f.Description, &f.Enabled, &f.Percentage, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+		byKey[f.Key] = f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	overrides, err := s.db.QueryContext(ctx, `SELECT flag_key, organization_id, enabled FROM feature_flag_overrides`)
+	if err != nil {
+		return nil, err
+	}
+	defer overrides.Close()
+	for overrides.Next() {
+		var key string
+		var orgID uuid.UUID
+		var enabled bool
+		if err := overrides.Scan(&key, &orgID, &enabled); err != nil {
+			return nil, err
+		}
+		if f := byKey[key]; f != nil {
+			f.Overrides[orgID] = enabled
+		}
+	}
+	return flags, overrides.Err()
+}
+
+// Get returns one flag with its overrides, read from the database
+func (s *Store) Get(ctx context.Context, key string) (*Flag, error) {
+	f := &Flag{Overrides: map[uuid.UUID]bool{}}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT key, description, enabled, percentage, created_at, updated_at
+		FROM feature_flags WHERE key = $1
+	`, key).Scan(&f.Key, &f.Description, &f.Enabled, &f.Percentage, &f.CreatedAt, &f.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT organization_id, enabled FROM feature_flag_overrides WHERE flag_key = $1`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var orgID uuid.UUID
+		var enabled bool
+		if err := rows.Scan(&orgID, &enabled); err != nil {
+			return nil, err
+		}
+		f.Overrides[orgID] = enabled
+	}
+	return f, rows.Err()
+}
+
+// Put creates or replaces a flag, keeping its overrides
+func (s *Store) Put(ctx context.Context, key, description string, enabled bool, percentage int) (*Flag, error) {
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO feature_flags (key, description, enabled, percentage)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE
+		SET description = EXCLUDED.description, enabled = EXCLUDED.enabled, percentage = EXCLUDED.percentage
+	`, key, description, enabled, percentage); err != nil {
+		return nil, err
+	}
+	s.invalidate(ctx)
+	return s.Get(ctx, key)
+}
+
+// Delete removes a flag and its overrides; code checking it sees it as off
+func (s *Store) Delete(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+// SetOverride forces a flag on or off for an organization. ErrNotFound is returned when the
+// flag or the organization does not exist.
+func (s *Store) SetOverride(ctx context.Context, key string, orgID uuid.UUID, enabled bool) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO feature_flag_overrides (flag_key, organization_id, enabled)
+		SELECT f.key, o.id, $3 FROM feature_flags f, organizations o
+		WHERE f.key = $1 AND o.id = $2
+		ON CONFLICT (flag_key, organization_id) DO UPDATE SET enabled = EXCLUDED.enabled
+	`, key, orgID, enabled)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	s.invalidate(ctx)
+	return nil
+}
+
+// DeleteOverride returns an organization to the flag's percentage rollout
+func (s *Store) DeleteOverride(ctx context.Context, key string, orgID uuid.UUID) error {
+	res, err := s.db.ExecContext(ctx,
+		`DELETE FROM feature_flag_overrides WHERE flag_key = $1 AND organization_id = $2`, key, orgID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	s.invalidate(ctx)
+	return nil
+}
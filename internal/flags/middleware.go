@@ -0,0 +1,37 @@
+package flags
+
+import (
+	"net/http"
+
+	"openvdo/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnabledForRequest reports whether the flag is on for the caller's organization, for
+// handlers that choose between an old and a new code path. Flags are off for requests
+// without a tenant database.
+func (s *Store) EnabledForRequest(c *gin.Context, key string) bool {
+	tenantDB, ok := database.GetStatelessTenantDBFromContext(c)
+	if !ok {
+		return false
+	}
+	session, err := tenantDB.GetUserSession(c.Request.Context())
+	if err != nil {
+		return false
+	}
+	return s.Enabled(c.Request.Context(), key, session.OrgID)
+}
+
+// Require answers 404 to callers whose organization the flag is off for, so the routes of a
+// feature being rolled out do not exist for everyone else
+func (s *Store) Require(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.EnabledForRequest(c, key) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,222 @@
+package flightrecorder
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"openvdo/internal/database"
+	"openvdo/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const redacted = "[REDACTED]"
+
+// secretHeaders are redacted along with any header or parameter naming a token, secret,
+// password, signature or key
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// secretJSONField matches string values of JSON fields with secret-looking names
+var secretJSONField = regexp.MustCompile(`(?i)("[^"]*(?:password|secret|token|signature|api_?key)[^"]*"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+
+func secretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"token", "secret", "password", "signature", "key"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware records requests while recording is on. It goes after the compression
+// middleware so bodies are kept as the handlers wrote them. The admin API is never recorded.
+func (r *Recorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !r.Recording() || strings.HasPrefix(c.Request.URL.Path, "/admin/") {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		rec := &Record{
+			Time:           start.UTC(),
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			Query:          redactQuery(c.Request.URL.RawQuery),
+			RequestHeaders: redactHeaders(c.Request.Header),
+		}
+		tracer := &queryTracer{max: r.config.MaxQueries, queries: []Query{}}
+		c.Request = c.Request.WithContext(database.WithQueryTracer(c.Request.Context(), tracer))
+
+		var reqBody *capture
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			reqBody = &capture{limit: r.config.MaxBodySize}
+			c.Request.Body = &captureReader{ReadCloser: c.Request.Body, capture: reqBody}
+		}
+		writer := &captureWriter{ResponseWriter: c.Writer, capture: capture{limit: r.config.MaxBodySize}}
+		c.Writer = writer
+
+		c.Next()
+
+		rec.ID = r.seq.Add(1)
+		rec.RequestID = middleware.GetRequestID(c)
+		rec.Route = c.FullPath()
+		rec.Status = c.Writer.Status()
+		rec.DurationMS = float64(time.Since(start).Microseconds()) / 1000
+		rec.ResponseHeaders = redactHeaders(c.Writer.Header())
+		if reqBody != nil {
+			rec.RequestBody = reqBody.body()
+			if c.Request.ContentLength > rec.RequestBody.Size {
+				rec.RequestBody.Size = c.Request.ContentLength
+				rec.RequestBody.Truncated = true
+			}
+		}
+		if writer.size > 0 {
+			rec.ResponseBody = writer.body()
+		}
+		rec.Queries, rec.QueriesDropped = tracer.finish()
+		for _, err := range c.Errors {
+			rec.Errors = append(rec.Errors, err.Error())
+		}
+		r.add(rec)
+	}
+}
+
+func redactHeaders(h map[string][]string) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		if secretHeaders[name] || secretName(name) {
+			out[name] = redacted
+			continue
+		}
+		out[name] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func redactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	params := strings.Split(raw, "&")
+	for i, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if secretName(name) {
+			params[i] = url.QueryEscape(name) + "=" + redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// capture keeps the first limit bytes passing through it and counts them all
+type capture struct {
+	limit int
+	buf   bytes.Buffer
+	size  int64
+}
+
+func (c *capture) keep(p []byte) {
+	c.size += int64(len(p))
+	if room := c.limit - c.buf.Len(); room > 0 {
+		c.buf.Write(p[:min(room, len(p))])
+	}
+}
+
+func (c *capture) body() *Body {
+	b := &Body{Size: c.size, Truncated: c.size > int64(c.buf.Len())}
+	data := c.buf.Bytes()
+	// A multi-byte character cut off by the limit does not make the body binary
+	for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data) && b.Truncated; i++ {
+		data = data[:len(data)-1]
+	}
+	if !utf8.Valid(data) {
+		b.Binary = true
+		return b
+	}
+	b.Text = secretJSONField.ReplaceAllString(string(data), `$1"`+redacted+`"`)
+	return b
+}
+
+type captureReader struct {
+	io.ReadCloser
+	capture *capture
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.capture.keep(p[:n])
+	return n, err
+}
+
+type captureWriter struct {
+	gin.ResponseWriter
+	capture
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.keep(p[:n])
+	return n, err
+}
+
+// ReadFrom copies through Write, so the body is kept; requests are only recorded while
+// debugging, when sending files without copying them matters less
+func (w *captureWriter) ReadFrom(r io.Reader) (int64, error) {
+	return middleware.CopyBuffered(w, r)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	w.keep([]byte(s[:n]))
+	return n, err
+}
+
+// queryTracer collects the statements of one request. Handlers may run statements from
+// other goroutines, and ones reported after the request ended are ignored.
+type queryTracer struct {
+	mu      sync.Mutex
+	max     int
+	queries []Query
+	dropped int
+	done    bool
+}
+
+func (t *queryTracer) TraceQuery(query string, duration time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return
+	}
+	if len(t.queries) >= t.max {
+		t.dropped++
+		return
+	}
+	q := Query{SQL: strings.TrimSpace(query), DurationMS: float64(duration.Microseconds()) / 1000}
+	if err != nil {
+		q.Error = err.Error()
+	}
+	t.queries = append(t.queries, q)
+}
+
+func (t *queryTracer) finish() ([]Query, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = true
+	return t.queries, t.dropped
+}
@@ -0,0 +1,187 @@
+// Package flightrecorder keeps the last requests an instance served, with their headers,
+// the start of their bodies, their timings and the statements they ran on the tenant
+// connection, so production incidents can be looked at after the fact. Recording is off
+// unless an operator turns it on, and secrets are redacted before anything is kept.
+package flightrecorder
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"openvdo/internal/config"
+)
+
+// Query is a statement run on the tenant connection during a request
+type Query struct {
+	SQL        string  `json:"sql"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Body is the start of a request or response body. Bodies that are not UTF-8 text are only
+// described by their size.
+type Body struct {
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Binary    bool   `json:"binary,omitempty"`
+	Text      string `json:"text,omitempty"`
+}
+
+// Record is one recorded request
+type Record struct {
+	ID              uint64            `json:"id"`
+	RequestID       string            `json:"request_id,omitempty"`
+	Time            time.Time         `json:"time"`
+	Method          string            `json:"method"`
+	Route           string            `json:"route,omitempty"`
+	Path            string            `json:"path"`
+	Query           string            `json:"query,omitempty"`
+	Status          int               `json:"status"`
+	DurationMS      float64           `json:"duration_ms"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     *Body             `json:"request_body,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    *Body             `json:"response_body,omitempty"`
+	Queries         []Query           `json:"queries"`
+	QueriesDropped  int               `json:"queries_dropped,omitempty"`
+	Errors          []string          `json:"errors,omitempty"`
+}
+
+// State tells whether an instance is recording
+type State struct {
+	Enabled bool `json:"enabled"`
+	// Until is when recording turned on through the admin API stops by itself
+	Until    *time.Time `json:"until,omitempty"`
+	Instance string     `json:"instance"`
+	Capacity int        `json:"capacity"`
+	Recorded int        `json:"recorded"`
+}
+
+// Filter selects records
+type Filter struct {
+	// MinStatus keeps responses with at least this status, such as 500 for server errors
+	MinStatus int
+	// PathPrefix keeps requests whose path starts with it
+	PathPrefix string
+	// MinDuration keeps requests that took at least this long
+	MinDuration time.Duration
+	Limit       int
+}
+
+// Recorder keeps the most recent requests of one instance in a ring buffer. Each instance
+// records on its own, so with several replicas the admin API must reach the one to debug.
+type Recorder struct {
+	config   config.FlightRecorder
+	instance string
+
+	// until is the unix time in nanoseconds recording stops at; 0 is off and -1 has no end
+	until atomic.Int64
+	seq   atomic.Uint64
+
+	mu      sync.Mutex
+	records []*Record
+	next    int
+}
+
+// New creates a recorder, recording from the start when the configuration says so
+func New(cfg config.FlightRecorder) *Recorder {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 1
+	}
+	instance, _ := os.Hostname()
+	r := &Recorder{config: cfg, instance: instance, records: make([]*Record, 0, cfg.Capacity)}
+	if cfg.Enabled {
+		r.until.Store(-1)
+	}
+	return r
+}
+
+// MaxDuration is the longest recording the admin API may turn on
+func (r *Recorder) MaxDuration() time.Duration {
+	return r.config.MaxDuration
+}
+
+// Recording reports whether requests are being recorded
+func (r *Recorder) Recording() bool {
+	until := r.until.Load()
+	return until == -1 || (until > 0 && time.Now().UnixNano() < until)
+}
+
+// Set turns recording on for duration, or off. Records are kept either way.
+func (r *Recorder) Set(enabled bool, duration time.Duration) State {
+	if enabled {
+		r.until.Store(time.Now().Add(duration).UnixNano())
+	} else {
+		r.until.Store(0)
+	}
+	return r.State()
+}
+
+// State returns whether the instance is recording and how much it holds
+func (r *Recorder) State() State {
+	s := State{Enabled: r.Recording(), Instance: r.instance, Capacity: r.config.Capacity}
+	if until := r.until.Load(); s.Enabled && until > 0 {
+		t := time.Unix(0, until).UTC()
+		s.Until = &t
+	}
+	r.mu.Lock()
+	s.Recorded = len(r.records)
+	r.mu.Unlock()
+	return s
+}
+
+// Records returns the records matching the filter, newest first
+func (r *Recorder) Records(f Filter) []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := []Record{}
+	for _, rec := range r.records {
+		if rec.Status < f.MinStatus || !strings.HasPrefix(rec.Path, f.PathPrefix) ||
+			rec.DurationMS < float64(f.MinDuration.Microseconds())/1000 {
+			continue
+		}
+		out = append(out, *rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out
+}
+
+// Get returns a record by ID, or nil once it has been overwritten
+func (r *Recorder) Get(id uint64) *Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rec := range r.records {
+		if rec.ID == id {
+			copied := *rec
+			return &copied
+		}
+	}
+	return nil
+}
+
+// Clear drops all records
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = r.records[:0]
+	r.next = 0
+}
+
+func (r *Recorder) add(rec *Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.records) < r.config.Capacity {
+		r.records = append(r.records, rec)
+		return
+	}
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// playbackAds tells a player where to insert ads and which VAST tag to request them from
+type playbackAds struct {
+	VASTTagURL string          `json:"vast_tag_url,omitempty"`
+	Breaks     models.AdBreaks `json:"breaks"`
+}
+
+// loadPlaybackAds reads the organization's VAST tag for a video's ad breaks. db must not carry
+// a tenant context.
+func loadPlaybackAds(ctx context.Context, db *sql.DB, videoID, orgID uuid.UUID, breaks models.AdBreaks) (*playbackAds, error) {
+	tag, err := queries.New(db).GetVASTTagURL(ctx, orgID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	ads := &playbackAds{Breaks: breaks}
+	if ads.Breaks == nil {
+		ads.Breaks = models.AdBreaks{}
+	}
+	if tag != nil {
+		ads.VASTTagURL = services.ExpandVASTTag(*tag, videoID)
+	}
+	return ads, nil
+}
+
+// GetVideoAdBreaks godoc
+// @Summary Get video ad breaks
+// @Description Returns a video's ad break cue points in order of their offset
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{ad_breaks=[]models.AdBreak}} "Ad breaks retrieved"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/ad-breaks [get]
+func GetVideoAdBreaks(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	breaks, err := queries.New(tenantDB).GetAdBreaks(c.Request.Context(), videoID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ad breaks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Ad breaks retrieved successfully",
+		"data":    gin.H{"ad_breaks": breaks},
+	})
+}
+
+type adBreaksRequest struct {
+	AdBreaks []models.AdBreak `json:"ad_breaks" binding:"required"`
+}
+
+// SetVideoAdBreaks godoc
+// @Summary Set video ad breaks
+// @Description Replaces a video's ad breaks. Each break has the offset in the video where ads are inserted and the duration of the avail,
+// @Description at most 600 seconds. HLS playlists of the video are served with an #EXT-X-CUE-OUT/#EXT-X-CUE-IN pair and the SCTE-35
+// @Description splice_insert at each break. An offset of 0 is a pre-roll. An empty list removes the breaks.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body adBreaksRequest true "Ad breaks with their offset and duration"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Ad breaks updated"
+// @Failure 400 {object} ErrorResponse "Invalid ad breaks"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/ad-breaks [put]
+func SetVideoAdBreaks(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req adBreaksRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	breaks, err := services.NormalizeAdBreaks(req.AdBreaks)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var video *models.Video
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		video, err = services.VideoModel(queries.New(tx).SetAdBreaks(ctx, queries.SetAdBreaksParams{
+			ID: videoID, AdBreaks: breaks,
+		}))
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoUpdated, video))
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to set ad breaks of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ad breaks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Ad breaks updated",
+		"data":    video,
+	})
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"openvdo/internal/analytics"
+	"openvdo/internal/database"
+	"openvdo/internal/regions"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// defaultAnalyticsRange is the window queried when no from is given
+const defaultAnalyticsRange = 24 * time.Hour
+
+type AnalyticsHandler struct {
+	store   analytics.Store
+	regions *regions.Router
+}
+
+func NewAnalyticsHandler(store analytics.Store, router *regions.Router) *AnalyticsHandler {
+	return &AnalyticsHandler{store: store, regions: router}
+}
+
+// QoESummary godoc
+// @Summary Get playback quality summary
+// @Description Aggregates the beacon events of the current organization: sessions, startup time percentiles,
+// @Description rebuffering, errors and bitrate. Session counts are estimated from the sampled sessions.
+// @Description The range defaults to the last 24 hours and may cover at most 90 days.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Produce json
+// @Param from query string false "Start of the range (RFC 3339)"
+// @Param to query string false "End of the range (RFC 3339), defaults to now"
+// @Param video_id query string false "Only events of this video"
+// @Param share_link_id query string false "Only events of playbacks through this share link"
+// @Success 200 {object} SuccessResponse{data=object{from=string,to=string,video_id=string,share_link_id=string,backend=string,summary=analytics.Summary}} "Summary retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid range"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "No organization selected"
+// @Router /api/v1/analytics/qoe [get]
+func (h *AnalyticsHandler) QoESummary(c *gin.Context) {
+	q, ok := h.parseQuery(c, 0)
+	if !ok {
+		return
+	}
+	store, ok := h.storeFor(c, q.OrganizationID)
+	if !ok {
+		return
+	}
+
+	summary, err := store.Summary(c.Request.Context(), q)
+	if err != nil {
+		logger.Error("Failed to query playback analytics from %s: %v", store.Name(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query playback analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Playback analytics retrieved successfully",
+		"data": gin.H{
+			"from":          q.From,
+			"to":            q.To,
+			"video_id":      q.VideoID,
+			"share_link_id": q.ShareLinkID,
+			"backend":       store.Name(),
+			"summary":       summary,
+		},
+	})
+}
+
+// QoETimeseries godoc
+// @Summary Get playback quality over time
+// @Description Aggregates the beacon events of the current organization per hour or day, aligned to UTC.
+// @Description Intervals without events are left out. A series may have at most 1000 intervals.
+// @Tags analytics
+// @Security ApiKeyAuth
+// @Produce json
+// @Param from query string false "Start of the range (RFC 3339)"
+// @Param to query string false "End of the range (RFC 3339), defaults to now"
+// @Param video_id query string false "Only events of this video"
+// @Param share_link_id query string false "Only events of playbacks through this share link"
+// @Param interval query string false "hour or day" default(hour)
+// @Success 200 {object} SuccessResponse{data=object{from=string,to=string,video_id=string,share_link_id=string,interval=string,backend=string,buckets=[]analytics.Bucket}} "Time series retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid range or interval"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "No organization selected"
+// @Router /api/v1/analytics/qoe/timeseries [get]
+func (h *AnalyticsHandler) QoETimeseries(c *gin.Context) {
+	var interval time.Duration
+	switch c.DefaultQuery("interval", "hour") {
+	case "hour":
+		interval = analytics.IntervalHour
+	case "day":
+		interval = analytics.IntervalDay
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be hour or day"})
+		return
+	}
+
+	q, ok := h.parseQuery(c, interval)
+	if !ok {
+		return
+	}
+	store, ok := h.storeFor(c, q.OrganizationID)
+	if !ok {
+		return
+	}
+
+	buckets, err := store.Timeseries(c.Request.Context(), q)
+	if err != nil {
+		logger.Error("Failed to query playback analytics from %s: %v", store.Name(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query playback analytics"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Playback analytics retrieved successfully",
+		"data": gin.H{
+			"from":          q.From,
+			"to":            q.To,
+			"video_id":      q.VideoID,
+			"share_link_id": q.ShareLinkID,
+			"interval":      c.DefaultQuery("interval", "hour"),
+			"backend":       store.Name(),
+			"buckets":       buckets,
+		},
+	})
+}
+
+// parseQuery reads the organization and the range of an analytics request, writing the error
+// response when they are missing or invalid
+func (h *AnalyticsHandler) parseQuery(c *gin.Context, interval time.Duration) (analytics.Query, bool) {
+	tenantDB, ok := database.GetStatelessTenantDBFromContext(c)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database context not found"})
+		return analytics.Query{}, false
+	}
+	if tenantDB.GetOrganizationID() == uuid.Nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Select an organization to view its analytics"})
+		return analytics.Query{}, false
+	}
+
+	q := analytics.Query{OrganizationID: tenantDB.GetOrganizationID(), To: time.Now().UTC(), Interval: interval}
+	if raw := c.Query("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp"})
+			return analytics.Query{}, false
+		}
+		q.To = to.UTC()
+	}
+	q.From = q.To.Add(-defaultAnalyticsRange)
+	if raw := c.Query("from"); raw != "" {
+		from, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp"})
+			return analytics.Query{}, false
+		}
+		q.From = from.UTC()
+	}
+	if raw := c.Query("video_id"); raw != "" {
+		videoID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+			return analytics.Query{}, false
+		}
+		q.VideoID = &videoID
+	}
+	if raw := c.Query("share_link_id"); raw != "" {
+		shareLinkID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+			return analytics.Query{}, false
+		}
+		q.ShareLinkID = &shareLinkID
+	}
+
+	if err := q.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return analytics.Query{}, false
+	}
+	return q, true
+}
+
+// storeFor returns the store holding an organization's events. ClickHouse is shared by all
+// regions, while in Postgres the events of an organization pinned to another region were
+// written by that region's instances into its database.
+func (h *AnalyticsHandler) storeFor(c *gin.Context, orgID uuid.UUID) (analytics.Store, bool) {
+	if _, ok := h.store.(*analytics.PostgresStore); !ok || !h.regions.Regional() {
+		return h.store, true
+	}
+
+	region, err := h.regions.RegionOf(c.Request.Context(), orgID)
+	if err == nil && region == h.regions.Local() {
+		return h.store, true
+	}
+	var pool *database.StatelessPoolManager
+	if err == nil {
+		pool, err = h.regions.Pool(region)
+	}
+	if err != nil {
+		logger.Error("Failed to resolve region of organization %s: %v", orgID, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Organization region unavailable"})
+		return nil, false
+	}
+	return analytics.NewPostgresStore(pool.GetMasterConnection()), true
+}
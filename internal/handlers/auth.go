@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"openvdo/internal/auth"
+	"openvdo/internal/database"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AuthHandler signs users in and manages the sessions they are signed in with
+type AuthHandler struct {
+	sessions *auth.Sessions
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(sessions *auth.Sessions) *AuthHandler {
+	return &AuthHandler{sessions: sessions}
+}
+
+type loginRequest struct {
+	Email    string `json:"email" binding:"required,email,max=255"`
+	Password string `json:"password" binding:"required,max=1024"`
+}
+
+// Login godoc
+// @Summary Sign in
+// @Description Signs in with an email address and password and opens a session. Send the returned token as "Authorization: Bearer <token>"; it is only returned here.
+// @Description Failed sign-ins slow down further attempts; repeated ones lock the account for a while, notifying its owner, and block the address they come from.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body loginRequest true "Email address and password"
+// @Success 201 {object} SuccessResponse{data=object{token=string,session=models.AuthSession}} "Signed in"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 401 {object} ErrorResponse "Invalid email or password"
+// @Failure 429 {object} ErrorResponse "Account locked or address blocked after repeated failures; see Retry-After"
+// @Router /api/v1/auth/login [post]
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	token, session, err := h.sessions.SignIn(c.Request.Context(), req.Email, req.Password, c.ClientIP(), c.Request.UserAgent())
+	var throttled *auth.ThrottledError
+	if errors.As(err, &throttled) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		message := "Too many failed sign-ins from this address, try again later"
+		if throttled.Reason == auth.ReasonAccountLocked {
+			message = "Account locked after too many failed sign-ins, try again later"
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": message})
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Signed in",
+		"data":    gin.H{"token": token, "session": session},
+	})
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description Lists the devices and browsers the caller is signed in on, most recently seen first. The session of the request is marked current.
+// @Tags auth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{sessions=[]models.AuthSession}} "Sessions retrieved"
+// @Router /api/v1/me/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	currentID, _ := auth.GetSessionID(c)
+	sessions, err := h.sessions.List(c.Request.Context(), tenantDB, tenantDB.GetUserID(), currentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Sessions retrieved successfully",
+		"data":    gin.H{"sessions": sessions},
+	})
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Signs the caller out of one of their sessions. Requests with its token are refused from then on.
+// @Tags auth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} SuccessResponse{data=object{id=string}} "Session revoked"
+// @Failure 400 {object} ErrorResponse "Invalid session ID"
+// @Failure 404 {object} ErrorResponse "Session not found"
+// @Router /api/v1/me/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	err = h.sessions.Revoke(c.Request.Context(), tenantDB.GetUserID(), id)
+	if errors.Is(err, auth.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Session revoked",
+		"data":    gin.H{"id": id},
+	})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"openvdo/internal/analytics"
+	"openvdo/internal/config"
+	"openvdo/internal/queries"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type BeaconHandler struct {
+	db       *sql.DB
+	ingester *analytics.Ingester
+	config   config.Beacon
+}
+
+// NewBeaconHandler creates a beacon handler. db must not carry a tenant context, since
+// players report anonymously.
+func NewBeaconHandler(db *sql.DB, ingester *analytics.Ingester, cfg config.Beacon) *BeaconHandler {
+	return &BeaconHandler{db: db, ingester: ingester, config: cfg}
+}
+
+// beaconRejection tells the player which event of its batch was refused and why
+type beaconRejection struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// PostBeacon godoc
+// @Summary Report player events
+// @Description Accepts a batch of quality-of-experience events from a player: startup, rebuffer, bitrate_switch,
+// @Description error, heartbeat and end. The body is read as JSON whatever its Content-Type, so browsers can send it
+// @Description with navigator.sendBeacon or a text/plain fetch, which need no CORS preflight.
+// @Description Invalid events are listed in the response and the others kept. Sessions are sampled at sample_rate,
+// @Description which players may use to sample on their side; error events are always kept.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param request body object{player=string,events=[]analytics.Event} true "Player name and events"
+// @Success 202 {object} SuccessResponse{data=object{accepted=int,rejected=[]beaconRejection,sample_rate=number}} "Events accepted"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Beacon disabled"
+// @Failure 413 {object} ErrorResponse "Too many events or body too large"
+// @Router /api/v1/beacon [post]
+func (h *BeaconHandler) PostBeacon(c *gin.Context) {
+	if !h.config.Enabled {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Beacon is disabled"})
+		return
+	}
+
+	var req struct {
+		Player string            `json:"player"`
+		Events []json.RawMessage `json:"events"`
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, h.config.MaxBodySize)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Beacon body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid beacon body: " + err.Error()})
+		return
+	}
+	if len(req.Events) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No events"})
+		return
+	}
+	if len(req.Events) > h.config.MaxEvents {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "At most " + strconv.Itoa(h.config.MaxEvents) + " events per beacon"})
+		return
+	}
+
+	now := time.Now()
+	player, userAgent := analytics.NormalizeClient(req.Player, c.Request.UserAgent())
+	rejected := []beaconRejection{}
+	events := make([]analytics.Event, 0, len(req.Events))
+	sampledOut := 0
+	for i, raw := range req.Events {
+		var e analytics.Event
+		if err := json.Unmarshal(raw, &e); err != nil {
+			rejected = append(rejected, beaconRejection{Index: i, Error: "invalid event: " + err.Error()})
+			continue
+		}
+		if err := e.Validate(now); err != nil {
+			rejected = append(rejected, beaconRejection{Index: i, Error: err.Error()})
+			continue
+		}
+
+		e.SampleRate = h.config.SampleRate
+		if e.Type == analytics.EventError {
+			e.SampleRate = 1
+		}
+		if !analytics.Sampled(e.SessionID, e.SampleRate) {
+			sampledOut++
+			continue
+		}
+		e.Player, e.UserAgent, e.ReceivedAt = player, userAgent, now
+		events = append(events, e)
+	}
+	analytics.Count(analytics.OutcomeRejected, len(rejected))
+	analytics.Count(analytics.OutcomeSampledOut, sampledOut)
+
+	events, err := h.attachOrganizations(c, events)
+	if err != nil {
+		logger.Error("Failed to look up videos of beacon events: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process events"})
+		return
+	}
+	accepted := h.ingester.Submit(events)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Events accepted",
+		"data": gin.H{
+			"accepted":    accepted,
+			"rejected":    rejected,
+			"sample_rate": h.config.SampleRate,
+		},
+	})
+}
+
+// attachOrganizations sets the organization of each event from its video and leaves out
+// events for videos that do not exist. They are not reported back, so the beacon cannot be
+// used to probe for private videos.
+func (h *BeaconHandler) attachOrganizations(c *gin.Context, events []analytics.Event) ([]analytics.Event, error) {
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	ids := make([]uuid.UUID, 0, len(events))
+	for _, e := range events {
+		if !seen[e.VideoID] {
+			seen[e.VideoID] = true
+			ids = append(ids, e.VideoID)
+		}
+	}
+
+	rows, err := queries.New(h.db).ListVideoOrganizations(c.Request.Context(), ids)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make(map[uuid.UUID]uuid.UUID, len(rows))
+	for _, row := range rows {
+		orgs[row.ID] = row.OrganizationID
+	}
+
+	links, err := h.shareLinkVideos(c, events)
+	if err != nil {
+		return nil, err
+	}
+
+	known := events[:0]
+	for _, e := range events {
+		if orgID, ok := orgs[e.VideoID]; ok {
+			e.OrganizationID = orgID
+			// A share link of another video is dropped rather than the event
+			if e.ShareLinkID != nil && links[*e.ShareLinkID] != e.VideoID {
+				e.ShareLinkID = nil
+			}
+			known = append(known, e)
+		}
+	}
+	analytics.Count(analytics.OutcomeUnknown, len(events)-len(known))
+	return known, nil
+}
+
+// shareLinkVideos maps the share links events were reported for to their videos
+func (h *BeaconHandler) shareLinkVideos(c *gin.Context, events []analytics.Event) (map[uuid.UUID]uuid.UUID, error) {
+	links := make(map[uuid.UUID]uuid.UUID)
+	ids := []string{}
+	for _, e := range events {
+		if e.ShareLinkID != nil {
+			if _, ok := links[*e.ShareLinkID]; !ok {
+				links[*e.ShareLinkID] = uuid.Nil
+				ids = append(ids, e.ShareLinkID.String())
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return links, nil
+	}
+
+	rows, err := h.db.QueryContext(c.Request.Context(),
+		`SELECT id, video_id FROM share_links WHERE id = ANY($1::uuid[])`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, videoID uuid.UUID
+		if err := rows.Scan(&id, &videoID); err != nil {
+			return nil, err
+		}
+		links[id] = videoID
+	}
+	return links, rows.Err()
+}
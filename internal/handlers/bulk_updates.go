@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+
+	"openvdo/internal/audit"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Outcomes of a bulk update for one video
+const (
+	bulkUpdated     = "updated"
+	bulkNotFound    = "not_found"
+	bulkTooManyTags = "too_many_tags"
+	// bulkRolledBack marks videos that could have been updated when others could not
+	bulkRolledBack = "rolled_back"
+)
+
+// maxVideoTags matches the limit on the tags of a single update
+const maxVideoTags = 50
+
+// errBulkUpdateFailed rolls back a bulk update some videos could not take
+var errBulkUpdateFailed = errors.New("bulk update failed")
+
+type bulkUpdateVideosRequest struct {
+	VideoIDs   []uuid.UUID `json:"video_ids" binding:"required,min=1,max=500,unique"`
+	Visibility *string     `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	// Tags replaces the tags of every video
+	Tags *[]string `json:"tags" binding:"omitempty,max=50,dive,max=64"`
+	// AddTags and RemoveTags change the tags every video has, after Tags is applied
+	AddTags    []string `json:"add_tags" binding:"omitempty,max=50,dive,max=64"`
+	RemoveTags []string `json:"remove_tags" binding:"omitempty,max=50,dive,max=64"`
+	// CustomFields sets values of custom fields by key, such as a category; null removes a value
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+// bulkVideoResult is the outcome of a bulk update for one video
+type bulkVideoResult struct {
+	VideoID uuid.UUID `json:"video_id"`
+	// Status is updated, or not_found or too_many_tags for a video that could not be updated,
+	// in which case the others are rolled_back
+	Status string        `json:"status"`
+	Video  *models.Video `json:"video,omitempty"`
+}
+
+// BulkUpdateVideos godoc
+// @Summary Bulk update videos
+// @Description Changes the visibility, tags or custom fields of up to 500 videos of the current organization at once, such as a category kept in a custom field. Tags replaces the tags of every video, and add_tags and remove_tags change them after that.
+// @Description The update is one transaction: if any video is missing or would end up with more than 50 tags, nothing is changed and the response lists what went wrong with which video. Versions are not checked. Every video updated gets an audit entry and a video.updated event. Requires the owner or admin role.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body bulkUpdateVideosRequest true "Videos and fields to change"
+// @Success 200 {object} SuccessResponse{data=object{results=[]bulkVideoResult}} "Videos updated"
+// @Failure 400 {object} ErrorResponse{data=object{results=[]bulkVideoResult}} "Invalid request, or videos that could not be updated"
+// @Failure 403 {object} ErrorResponse "Owner or admin role required"
+// @Failure 503 {object} ErrorResponse "Conflicted with a concurrent change; retry"
+// @Router /api/v1/videos/bulk [patch]
+func BulkUpdateVideos(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req bulkUpdateVideosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Visibility == nil && req.Tags == nil && len(req.AddTags) == 0 && len(req.RemoveTags) == 0 &&
+		len(req.CustomFields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to update"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+	if session.Role != models.RoleOwner && session.Role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bulk updates require the owner or admin role"})
+		return
+	}
+	setFields, unsetFields, ok := videoCustomFields(c, tenantDB, session.OrgID, req.CustomFields)
+	if !ok {
+		return
+	}
+
+	changes := gin.H{}
+	if req.Visibility != nil {
+		changes["visibility"] = *req.Visibility
+	}
+	if req.Tags != nil {
+		tags := services.NormalizeTags(*req.Tags)
+		req.Tags = &tags
+		changes["tags"] = tags
+	}
+	if req.AddTags = services.NormalizeTags(req.AddTags); len(req.AddTags) > 0 {
+		changes["add_tags"] = req.AddTags
+	}
+	if req.RemoveTags = services.NormalizeTags(req.RemoveTags); len(req.RemoveTags) > 0 {
+		changes["remove_tags"] = req.RemoveTags
+	}
+	if len(req.CustomFields) > 0 {
+		changes["custom_fields"] = req.CustomFields
+	}
+
+	results := make([]bulkVideoResult, len(req.VideoIDs))
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		// Locking in ID order keeps overlapping bulk updates from deadlocking
+		q := queries.New(tx)
+		rows, err := q.LockVideoTags(ctx, queries.LockVideoTagsParams{Ids: req.VideoIDs, OrganizationID: session.OrgID})
+		if err != nil {
+			return err
+		}
+		current := make(map[uuid.UUID][]string, len(rows))
+		for _, row := range rows {
+			current[row.ID] = row.Tags
+		}
+
+		failed := false
+		for i, id := range req.VideoIDs {
+			results[i].VideoID = id
+			tags, found := current[id]
+			switch {
+			case !found:
+				results[i].Status = bulkNotFound
+			case len(bulkTags(tags, &req)) > maxVideoTags:
+				results[i].Status = bulkTooManyTags
+			default:
+				results[i].Status = bulkUpdated
+				continue
+			}
+			failed = true
+		}
+		if failed {
+			for i := range results {
+				if results[i].Status == bulkUpdated {
+					results[i].Status = bulkRolledBack
+				}
+			}
+			return errBulkUpdateFailed
+		}
+
+		for i, id := range req.VideoIDs {
+			video, err := services.VideoModel(q.BulkUpdateVideo(ctx, queries.BulkUpdateVideoParams{
+				Visibility:  req.Visibility,
+				Tags:        bulkTags(current[id], &req),
+				SetFields:   setFields,
+				UnsetFields: unsetFields,
+				ID:          id,
+			}))
+			if err != nil {
+				return err
+			}
+			results[i].Video = video
+			if err := outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoUpdated, video)); err != nil {
+				return err
+			}
+			if err := audit.Record(ctx, tx, audit.Entry{
+				OrganizationID: session.OrgID,
+				UserID:         session.UserID,
+				Action:         models.AuditVideoBulkUpdated,
+				IPAddress:      c.ClientIP(),
+				Details:        gin.H{"video_id": id, "changes": changes},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkUpdateFailed) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Some videos could not be updated; nothing was changed",
+			"data":  gin.H{"results": results},
+		})
+		return
+	}
+	if databaseError(c, err) {
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to bulk update %d videos of org %s: %v", len(req.VideoIDs), session.OrgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update videos"})
+		return
+	}
+	logger.Info("Bulk updated %d videos of org %s", len(req.VideoIDs), session.OrgID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Videos updated successfully",
+		"data":    gin.H{"results": results},
+	})
+}
+
+// bulkTags returns the tags a video ends up with: those of the request if it replaces them,
+// plus the ones it adds, minus the ones it removes
+func bulkTags(current []string, req *bulkUpdateVideosRequest) []string {
+	tags := current
+	if req.Tags != nil {
+		tags = *req.Tags
+	}
+	tags = services.NormalizeTags(append(slices.Clone(tags), req.AddTags...))
+	return slices.DeleteFunc(tags, func(tag string) bool {
+		return slices.Contains(req.RemoveTags, tag)
+	})
+}
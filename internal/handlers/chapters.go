@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxChapterFileSize = 1 << 20
+	vttContentType     = "text/vtt; charset=utf-8"
+)
+
+// timelineChapter is a chapter as players get it, with its end; the last chapter runs to the
+// end of the video and has none
+type timelineChapter struct {
+	StartSeconds float64  `json:"start_seconds"`
+	EndSeconds   *float64 `json:"end_seconds,omitempty"`
+	Title        string   `json:"title"`
+}
+
+func chapterTimeline(chapters models.Chapters) []timelineChapter {
+	timeline := make([]timelineChapter, len(chapters))
+	for i, ch := range chapters {
+		timeline[i] = timelineChapter{StartSeconds: ch.StartSeconds, Title: ch.Title}
+		if i+1 < len(chapters) {
+			end := chapters[i+1].StartSeconds
+			timeline[i].EndSeconds = &end
+		}
+	}
+	return timeline
+}
+
+// writeChapters answers with the chapters as JSON, or as a WebVTT chapters track for
+// format=vtt
+func writeChapters(c *gin.Context, chapters models.Chapters) {
+	if c.Query("format") == "vtt" {
+		c.Data(http.StatusOK, vttContentType, services.ChaptersVTT(chapters))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Chapters retrieved successfully",
+		"data":    gin.H{"chapters": chapterTimeline(chapters)},
+	})
+}
+
+// GetVideoChapters godoc
+// @Summary Get video chapters
+// @Description Returns a video's chapters in order, each with its end except the last, which runs to the end of the video.
+// @Description With format=vtt the chapters are returned as a WebVTT chapters track.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json,text/vtt
+// @Param id path string true "Video ID"
+// @Param format query string false "json (default) or vtt"
+// @Success 200 {object} SuccessResponse{data=object{chapters=[]timelineChapter}} "Chapters retrieved"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/chapters [get]
+func GetVideoChapters(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	chapters, err := queries.New(tenantDB).GetChapters(c.Request.Context(), videoID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get chapters"})
+		return
+	}
+	writeChapters(c, chapters)
+}
+
+type chaptersRequest struct {
+	Chapters []models.Chapter `json:"chapters" binding:"required"`
+}
+
+// SetVideoChapters godoc
+// @Summary Set video chapters
+// @Description Replaces a video's chapters. Send JSON with a chapters list of start_seconds and title, or a chapter file as the raw
+// @Description body or the file field of a multipart form: a WebVTT chapters track, an SRT file, an FFmpeg metadata file, or text
+// @Description with a timestamp at the start of each line such as "0:00 Intro". An empty list removes the chapters.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json,text/vtt,text/plain,multipart/form-data
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body chaptersRequest false "Chapters as JSON, or a chapter file"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Chapters updated"
+// @Failure 400 {object} ErrorResponse "Invalid chapters"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 413 {object} ErrorResponse "Chapter file too large"
+// @Router /api/v1/videos/{id}/chapters [put]
+func SetVideoChapters(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var chapters models.Chapters
+	if c.ContentType() == "application/json" {
+		var req chaptersRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		chapters, err = services.NormalizeChapters(req.Chapters)
+	} else {
+		var data []byte
+		data, err = readChapterFile(c)
+		if errors.Is(err, errChapterFileTooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Chapter file exceeds %d bytes", maxChapterFileSize)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		chapters, err = services.ParseChapterFile(data)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var video *models.Video
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		video, err = services.VideoModel(queries.New(tx).SetChapters(ctx, queries.SetChaptersParams{
+			ID: videoID, Chapters: chapters,
+		}))
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoUpdated, video))
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to set chapters of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chapters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Chapters updated",
+		"data":    video,
+	})
+}
+
+var errChapterFileTooLarge = errors.New("chapter file too large")
+
+// readChapterFile reads a chapter file sent as the raw body or the file field of a multipart form
+func readChapterFile(c *gin.Context) ([]byte, error) {
+	var r io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxChapterFileSize+1<<20)
+		fh, err := c.FormFile("file")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, errChapterFileTooLarge
+			}
+			return nil, errors.New("missing file field")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxChapterFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxChapterFileSize {
+		return nil, errChapterFileTooLarge
+	}
+	if len(data) == 0 {
+		return nil, errors.New("chapter file is empty")
+	}
+	return data, nil
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// resourceETag returns a weak ETag for a single resource that changes whenever it is updated
+func resourceETag(id uuid.UUID, updatedAt time.Time) string {
+	return fmt.Sprintf(`W/"%s-%d"`, id, updatedAt.UnixMicro())
+}
+
+// listETag returns a weak ETag over everything that determines a list response
+func listETag(parts ...interface{}) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%v|", part)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag header and answers 304 when it matches If-None-Match.
+// It returns true when the response has been written.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// preconditionFailed answers 412 when If-Match is present and does not match the current
+// ETag of the resource. It returns true when the response has been written.
+func preconditionFailed(c *gin.Context, etag string) bool {
+	match := c.GetHeader("If-Match")
+	if match == "" || etagMatches(match, etag) {
+		return false
+	}
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Resource was modified; fetch it again and retry with the new ETag"})
+	return true
+}
+
+// etagMatches compares a list of entity tags from a conditional header with etag. Weak and
+// strong tags compare equal, since every ETag of this API is weak.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// versionConflict answers 409 with the current state of a resource whose version did not
+// match the one an update was based on
+func versionConflict(c *gin.Context, current interface{}) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error": "Resource was changed by someone else; merge with the current state and retry with its version",
+		"data":  current,
+	})
+}
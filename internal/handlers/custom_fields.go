@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ListCustomFields godoc
+// @Summary List custom fields
+// @Description Lists the typed fields the organization defined for its videos, by key. Any member may list them.
+// @Tags custom-fields
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=object{custom_fields=[]models.CustomField}} "Custom fields retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid organization ID"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/custom-fields [get]
+func ListCustomFields(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	member, err := queries.New(tenantDB).IsMember(ctx, queries.IsMemberParams{
+		UserID: tenantDB.GetUserID(), OrganizationID: orgID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+		return
+	}
+	if !member {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(ctx,
+		`SELECT `+services.CustomFieldColumns+` FROM custom_fields WHERE organization_id = $1 ORDER BY key`, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query custom fields"})
+		return
+	}
+	defer rows.Close()
+
+	fields := []models.CustomField{}
+	for rows.Next() {
+		f, err := services.ScanCustomField(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan custom field"})
+			return
+		}
+		fields = append(fields, *f)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing custom field results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Custom fields retrieved",
+		"data":    gin.H{"custom_fields": fields},
+	})
+}
+
+type createCustomFieldRequest struct {
+	// Key is what videos store the value under: lowercase letters, digits and underscores,
+	// starting with a letter
+	Key  string `json:"key" binding:"required,max=64"`
+	Name string `json:"name" binding:"required,max=255"`
+	Type string `json:"type" binding:"required,oneof=string number date enum"`
+	// Options are the values of enum fields
+	Options []string `json:"options" binding:"omitempty,max=100,dive,min=1,max=255"`
+}
+
+// CreateCustomField godoc
+// @Summary Create custom field
+// @Description Defines a typed field the organization's videos can hold a value of: a string, a number, a date (YYYY-MM-DD) or one of the options of an enum. Values are validated when videos are written and can filter video lists.
+// @Description The key and type cannot be changed later. Requires the owner or admin role.
+// @Tags custom-fields
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body createCustomFieldRequest true "Key, name, type and enum options"
+// @Success 201 {object} SuccessResponse{data=models.CustomField} "Custom field created"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 409 {object} ErrorResponse "A field with the key exists"
+// @Router /api/v1/organizations/{id}/custom-fields [post]
+func CreateCustomField(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing custom fields")
+	if !ok {
+		return
+	}
+
+	var req createCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if !services.CustomFieldKeyPattern.MatchString(req.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key must be lowercase letters, digits and underscores, starting with a letter"})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must not be empty"})
+		return
+	}
+	options, msg := customFieldOptions(req.Type, req.Options)
+	if msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	field, err := services.ScanCustomField(tenantDB.QueryRowContext(c.Request.Context(), `
+		INSERT INTO custom_fields (organization_id, key, name, type, options, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (organization_id, key) DO NOTHING
+		RETURNING `+services.CustomFieldColumns,
+		orgID, req.Key, name, req.Type, pq.Array(options), tenantDB.GetUserID()))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"error": "A custom field with this key already exists"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create custom field for organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create custom field"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Custom field created",
+		"data":    field,
+	})
+}
+
+var errOptionInUse = errors.New("option is in use")
+
+type updateCustomFieldRequest struct {
+	Name *string `json:"name" binding:"omitempty,max=255"`
+	// Options replaces the options of an enum field; options videos still use cannot be removed
+	Options *[]string `json:"options" binding:"omitempty,max=100,dive,min=1,max=255"`
+}
+
+// UpdateCustomField godoc
+// @Summary Update custom field
+// @Description Renames a custom field or replaces the options of an enum field. Options that videos still hold are refused with 409. Requires the owner or admin role.
+// @Tags custom-fields
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param key path string true "Custom field key"
+// @Param request body updateCustomFieldRequest true "Fields to change"
+// @Success 200 {object} SuccessResponse{data=models.CustomField} "Custom field updated"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Custom field not found"
+// @Failure 409 {object} ErrorResponse "A removed option is still in use"
+// @Router /api/v1/organizations/{id}/custom-fields/{key} [patch]
+func UpdateCustomField(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing custom fields")
+	if !ok {
+		return
+	}
+
+	var req updateCustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		if trimmed == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name must not be empty"})
+			return
+		}
+		req.Name = &trimmed
+	}
+
+	ctx := c.Request.Context()
+	key := c.Param("key")
+	current, err := services.ScanCustomField(tenantDB.QueryRowContext(ctx, `
+		SELECT `+services.CustomFieldColumns+` FROM custom_fields
+		WHERE organization_id = $1 AND key = $2`, orgID, key))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get custom field"})
+		return
+	}
+	var options interface{}
+	var opts []string
+	if req.Options != nil {
+		var msg string
+		opts, msg = customFieldOptions(current.Type, *req.Options)
+		if msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+		options = pq.Array(opts)
+	}
+
+	var field *models.CustomField
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		field, err = services.ScanCustomField(tx.QueryRowContext(ctx, `
+			UPDATE custom_fields SET name = COALESCE($2, name), options = COALESCE($3::text[], options)
+			WHERE id = $1
+			RETURNING `+services.CustomFieldColumns, current.ID, req.Name, options))
+		if err != nil || options == nil {
+			return err
+		}
+		inUse, err := queries.New(tx).CustomFieldOptionInUse(ctx, queries.CustomFieldOptionInUseParams{
+			OrganizationID: orgID, Key: key, Options: opts,
+		})
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return errOptionInUse
+		}
+		return nil
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	case errors.Is(err, errOptionInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Videos still hold an option that would be removed"})
+		return
+	case err != nil:
+		logger.Error("Failed to update custom field %s of organization %s: %v", key, orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update custom field"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Custom field updated",
+		"data":    field,
+	})
+}
+
+// DeleteCustomField godoc
+// @Summary Delete custom field
+// @Description Removes a custom field and its values from every video of the organization. Requires the owner or admin role.
+// @Tags custom-fields
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param key path string true "Custom field key"
+// @Success 200 {object} SuccessResponse{data=object{key=string}} "Custom field deleted"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Custom field not found"
+// @Router /api/v1/organizations/{id}/custom-fields/{key} [delete]
+func DeleteCustomField(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing custom fields")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := c.Param("key")
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM custom_fields WHERE organization_id = $1 AND key = $2`, orgID, key)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows
+		}
+		return queries.New(tx).RemoveCustomFieldValues(ctx, queries.RemoveCustomFieldValuesParams{
+			Key: key, OrganizationID: orgID,
+		})
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete custom field %s of organization %s: %v", key, orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete custom field"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Custom field deleted",
+		"data":    gin.H{"key": key},
+	})
+}
+
+// customFieldOptions checks the options of a field of type t, returning them without
+// duplicates or a message explaining why they are invalid
+func customFieldOptions(t string, options []string) ([]string, string) {
+	if t != models.CustomFieldEnum {
+		if len(options) > 0 {
+			return nil, "Only enum fields have options"
+		}
+		return []string{}, ""
+	}
+	var unique []string
+	for _, o := range options {
+		o = strings.TrimSpace(o)
+		if o != "" && !slices.Contains(unique, o) {
+			unique = append(unique, o)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, "Enum fields need at least one option"
+	}
+	return unique, ""
+}
+
+// videoCustomFields validates values of custom fields written to a video of orgID, returning
+// the values to set and the keys to unset. It responds with an error and returns false when
+// they are invalid.
+func videoCustomFields(c *gin.Context, q database.Querier, orgID uuid.UUID, values map[string]interface{}) (models.CustomFieldValues, []string, bool) {
+	if len(values) == 0 {
+		return models.CustomFieldValues{}, []string{}, true
+	}
+	fields, err := services.CustomFields(c.Request.Context(), q, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get custom fields"})
+		return nil, nil, false
+	}
+	set, unset, err := services.CheckCustomFields(fields, values)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	return set, unset, true
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes of the database errors clients are told about
+const (
+	pgUniqueViolation      = "23505"
+	pgForeignKeyViolation  = "23503"
+	pgSerializationFailure = "40001"
+	pgDeadlockDetected     = "40P01"
+)
+
+// Codes of the database failures a client can act on, sent in "code" next to "error"
+const (
+	codeAlreadyExists    = "already_exists"
+	codeInvalidReference = "invalid_reference"
+	codeStillReferenced  = "still_referenced"
+	codeRetry            = "serialization_failure"
+)
+
+// uniqueMessages explain the unique constraints and indexes clients run into
+var uniqueMessages = map[string]string{
+	"organizations_name_unique":     "An organization with this name already exists",
+	"idx_organizations_external_id": "Another organization has this external ID",
+	"idx_videos_external_id":        "Another video of the organization has this external ID",
+	"users_email_key":               "A user with this email address already exists",
+}
+
+// databaseError answers the failures of a statement the client can do something about, and
+// reports whether it did. A value another row already has answers 409 already_exists. A
+// foreign key violation answers 409 still_referenced for DELETE requests, which only remove
+// rows, and 422 invalid_reference for the others, which refer to rows that do not exist;
+// the error's message is translated by the server's lc_messages, so it is not looked at. A
+// transaction that lost against a concurrent one, or a query a standby cancelled to replay
+// changes, answers 503 with Retry-After, so clients retry it like any other unavailability.
+func databaseError(c *gin.Context, err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case pgUniqueViolation:
+		message, ok := uniqueMessages[pqErr.Constraint]
+		if !ok {
+			message = "A resource with this value already exists"
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": message, "code": codeAlreadyExists})
+	case pgForeignKeyViolation:
+		if c.Request.Method == http.MethodDelete {
+			c.JSON(http.StatusConflict, gin.H{"error": "The resource is still in use", "code": codeStillReferenced})
+		} else {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "A referenced resource does not exist", "code": codeInvalidReference})
+		}
+	case pgSerializationFailure, pgDeadlockDetected:
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "The request conflicted with a concurrent change; retry it",
+			"code":  codeRetry,
+		})
+	default:
+		return false
+	}
+	return true
+}
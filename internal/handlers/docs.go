@@ -0,0 +1,17 @@
+package handlers
+
+// SuccessResponse documents the body of successful /api/v1 responses, which handlers write
+// with gin.H. Annotations narrow data per endpoint, as in SuccessResponse{data=models.Video}.
+type SuccessResponse struct {
+	Status  string      `json:"status" example:"success"`
+	Message string      `json:"message" example:"Video retrieved successfully"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// ErrorResponse documents the body of failed /api/v1 responses
+type ErrorResponse struct {
+	Error string `json:"error" example:"Video not found"`
+	// Code tells failures of the same status apart where clients handle them differently, such
+	// as already_exists
+	Code string `json:"code,omitempty" example:"already_exists"`
+}
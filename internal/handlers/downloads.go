@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"openvdo/internal/analytics"
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/egress"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DownloadHandler lets members download videos as files. Files are rendered in the background
+// and fetched through expiring signed links, which are served here rather than redirected to
+// storage so they carry a file name and count against the organization's egress.
+type DownloadHandler struct {
+	db         *sql.DB
+	storage    storage.Storage
+	downloader *services.Downloader
+	ingester   *analytics.Ingester
+	queue      *jobs.Queue
+	config     config.Downloads
+	playback   config.Playback
+	egress     *egress.Shaper
+	cache      cachecontrol.Policies
+}
+
+// NewDownloadHandler creates a download handler. downloader is nil when ffmpeg is missing,
+// which leaves files rendered earlier downloadable.
+func NewDownloadHandler(db *sql.DB, store storage.Storage, downloader *services.Downloader, ingester *analytics.Ingester,
+	queue *jobs.Queue, cfg config.Downloads, playback config.Playback, shaper *egress.Shaper, cache cachecontrol.Policies) *DownloadHandler {
+	return &DownloadHandler{
+		db: db, storage: store, downloader: downloader, ingester: ingester, queue: queue,
+		config: cfg, playback: playback, egress: shaper, cache: cache,
+	}
+}
+
+type createDownloadRequest struct {
+	// RenditionID picks a rendition to download; the source is downloaded without it
+	RenditionID *uuid.UUID `json:"rendition_id"`
+	// Watermark burns the requesting member's email into the picture
+	Watermark bool `json:"watermark"`
+}
+
+// videoDownload is a download with a link to its file once it is ready
+type videoDownload struct {
+	models.VideoDownload
+	URL          string     `json:"url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+// CreateDownload godoc
+// @Summary Request a video download
+// @Description Renders an MP4 of the video's source, or of one of its renditions, for the caller to download. The video must have downloads_enabled. With watermark the caller's email is burned into the picture.
+// @Description The file is rendered in the background; poll GET /api/v1/videos/{id}/downloads/{download_id} until it is ready for a link. A file already rendered for the same choice is returned with its link instead.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body createDownloadRequest false "Rendition and watermark"
+// @Success 200 {object} SuccessResponse{data=videoDownload} "Existing download"
+// @Success 202 {object} SuccessResponse{data=videoDownload} "Download queued"
+// @Failure 400 {object} ErrorResponse "Invalid request or rendition"
+// @Failure 403 {object} ErrorResponse "Downloads are not enabled for the video"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 503 {object} ErrorResponse "Downloads not configured"
+// @Router /api/v1/videos/{id}/downloads [post]
+func (h *DownloadHandler) CreateDownload(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	if h.downloader == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Downloads are not available (DOWNLOADS_FFMPEG_PATH)"})
+		return
+	}
+	if h.playback.SigningKey == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Download links are not configured (PLAYBACK_SIGNING_KEY)"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+	var req createDownloadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	userID := tenantDB.GetUserID()
+	var download *models.VideoDownload
+	var reused bool
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		video, err := services.VideoModel(queries.New(tx).GetVideo(ctx, videoID))
+		if err != nil {
+			return err
+		}
+		download, reused, err = h.downloader.Request(ctx, tx, video, req.RenditionID, req.Watermark, userID)
+		return err
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	case errors.Is(err, services.ErrDownloadsDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Downloads are not enabled for this video"})
+		return
+	case errors.Is(err, services.ErrRenditionNotDownloadable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to request download of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request download"})
+		return
+	}
+
+	if reused {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Download already requested",
+			"data":    h.withLink(c, download),
+		})
+		return
+	}
+	h.queue.Notify()
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Download queued",
+		"data":    h.withLink(c, download),
+	})
+}
+
+// GetDownload godoc
+// @Summary Get a video download
+// @Description Returns a download and, once its file is ready, a link to it that expires after DOWNLOADS_LINK_TTL. Each call signs a new link.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param download_id path string true "Download ID"
+// @Success 200 {object} SuccessResponse{data=videoDownload} "Download"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Download not found"
+// @Router /api/v1/videos/{id}/downloads/{download_id} [get]
+func (h *DownloadHandler) GetDownload(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+	downloadID, err := uuid.Parse(c.Param("download_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid download ID"})
+		return
+	}
+
+	download, err := services.ScanDownload(tenantDB.QueryRowContext(c.Request.Context(), `
+		SELECT `+services.DownloadColumns+` FROM video_downloads
+		WHERE id = $1 AND video_id = $2 AND expires_at > NOW()`, downloadID, videoID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Download not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get download"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Download retrieved",
+		"data":    h.withLink(c, download),
+	})
+}
+
+// withLink signs a link to a ready download. Links never outlive the file.
+func (h *DownloadHandler) withLink(c *gin.Context, download *models.VideoDownload) videoDownload {
+	out := videoDownload{VideoDownload: *download}
+	if download.Status != models.DownloadReady || h.playback.SigningKey == "" {
+		return out
+	}
+	expiresAt := time.Now().Add(h.config.LinkTTL).UTC()
+	if download.ExpiresAt.Before(expiresAt) {
+		expiresAt = download.ExpiresAt.UTC()
+	}
+	token := services.SignDownloadToken(h.playback.SigningKey, download.ID, expiresAt)
+	base := h.playback.PublicURL
+	if base == "" {
+		base = requestBaseURL(c)
+	}
+	out.URL = base + "/downloads/" + download.ID.String() + tokenQuery(token)
+	out.URLExpiresAt = &expiresAt
+	return out
+}
+
+// ServeDownload godoc
+// @Summary Download a video file
+// @Description Serves the file of a download through a signed link from GET /api/v1/videos/{id}/downloads/{download_id}, as an attachment. Links stop working when they expire, and when the video no longer allows downloads.
+// @Description Each download is recorded in the organization's analytics; resuming with a range request is not counted again.
+// @Tags embed
+// @Produce octet-stream
+// @Param id path string true "Download ID"
+// @Param token query string true "Download token"
+// @Success 200 {file} file "Video file"
+// @Success 206 {file} file "Part of the video file"
+// @Failure 404 {object} ErrorResponse "Download not found or link expired"
+// @Router /downloads/{id} [get]
+func (h *DownloadHandler) ServeDownload(c *gin.Context) {
+	downloadID, err := uuid.Parse(c.Param("id"))
+	if err != nil || services.VerifyDownloadToken(h.playback.SigningKey, downloadID, c.Query("token")) != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Download not found"})
+		return
+	}
+
+	// Videos of deleted organizations, taken down or no longer downloadable stop serving files
+	ctx := c.Request.Context()
+	download, err := services.ScanDownload(h.db.QueryRowContext(ctx, `
+		SELECT `+services.DownloadColumns+` FROM video_downloads dl
+		WHERE id = $1 AND status = $2 AND expires_at > NOW() AND EXISTS (
+			SELECT 1 FROM videos v JOIN organizations o ON o.id = v.organization_id
+			WHERE v.id = dl.video_id AND v.downloads_enabled AND v.status <> $3 AND v.moderation_status = $4
+				AND o.deleted_at IS NULL
+		)`, downloadID, models.DownloadReady, models.VideoStatusDeleted, models.ModerationActive))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Download not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to look up download %s: %v", downloadID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access download"})
+		return
+	}
+
+	title, err := queries.New(h.db).GetVideoTitle(ctx, download.VideoID)
+	if err != nil {
+		logger.Debug("Failed to look up title of video %s: %v", download.VideoID, err)
+	}
+	rc, err := openObject(c, h.storage, download.ObjectKey)
+	if err != nil {
+		logger.Error("Failed to open download %s: %v", downloadID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to access download"})
+		return
+	}
+	defer rc.Close()
+
+	// The download ID stands for the session, so the fetches of one file are told apart
+	recordDownload(c, h.ingester, download.OrganizationID, download.VideoID, download.ID.String(), nil)
+	h.egress.Shape(c, download.OrganizationID)
+	h.cache.NoStore.Apply(c)
+	c.Header("Content-Type", "video/mp4")
+	c.Header("Content-Disposition", attachment(title, ".mp4"))
+	serveFile(c, rc, fmt.Sprintf(`"%s"`, download.ID), download.UpdatedAt)
+}
+
+// openObject opens a stored object for proxying. Backends with ranged reads read only the
+// ranges of the request; others, such as local disk, open the whole object.
+func openObject(c *gin.Context, store storage.Storage, key string) (io.ReadCloser, error) {
+	if rr, ok := storage.AsRangeReader(store); ok {
+		r, err := newObjectReader(c.Request.Context(), rr, key, c.GetHeader("Range"))
+		if !errors.Is(err, storage.ErrRangeUnsupported) {
+			return r, err
+		}
+	}
+	return store.Get(c.Request.Context(), key)
+}
+
+// serveFile writes a file being downloaded, answering range requests where rc can seek
+func serveFile(c *gin.Context, rc io.Reader, etag string, modified time.Time) {
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		c.Header("ETag", etag)
+		http.ServeContent(c.Writer, c.Request, "", modified, rs)
+		return
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, rc)
+}
+
+// recordDownload submits a download event to analytics, unless the request resumes one with a
+// range past the start of the file
+func recordDownload(c *gin.Context, ingester *analytics.Ingester, orgID, videoID uuid.UUID, sessionID string, shareLinkID *uuid.UUID) {
+	if rangeHeader := c.GetHeader("Range"); rangeHeader != "" && !strings.HasPrefix(rangeHeader, "bytes=0-") {
+		return
+	}
+	now := time.Now().UTC()
+	_, userAgent := analytics.NormalizeClient("", c.GetHeader("User-Agent"))
+	ingester.Submit([]analytics.Event{{
+		VideoID:        videoID,
+		SessionID:      sessionID,
+		Type:           analytics.EventDownload,
+		OccurredAt:     now,
+		ShareLinkID:    shareLinkID,
+		OrganizationID: orgID,
+		Player:         "download",
+		UserAgent:      userAgent,
+		SampleRate:     1,
+		ReceivedAt:     now,
+	}})
+}
+
+// attachment is the Content-Disposition of a file saved under the video's title
+func attachment(title, ext string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": downloadFilename(title, ext)})
+}
+
+// downloadFilename is the file name a download is saved as, from the video's title
+func downloadFilename(title, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "video"
+	}
+	return name + ext
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"openvdo/internal/egress"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EgressHandler sets the bandwidth organizations' media is served at
+type EgressHandler struct {
+	shaper *egress.Shaper
+}
+
+// NewEgressHandler creates an egress handler
+func NewEgressHandler(shaper *egress.Shaper) *EgressHandler {
+	return &EgressHandler{shaper: shaper}
+}
+
+type egressRequest struct {
+	// BytesPerSecond is the limit of each instance; 0 is unlimited and null follows EGRESS_DEFAULT_LIMIT
+	BytesPerSecond *int64 `json:"bytes_per_second" binding:"omitempty,min=0" example:"1250000"`
+}
+
+// GetOrganizationEgress godoc
+// @Summary Get organization egress limit
+// @Description Returns the bytes per second each instance serves the organization's media at; null follows EGRESS_DEFAULT_LIMIT.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=object{organization_id=string,bytes_per_second=int}} "Egress limit retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid organization ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /admin/v1/organizations/{id}/egress [get]
+func (h *EgressHandler) GetOrganizationEgress(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	limit, err := h.shaper.Limit(c.Request.Context(), orgID)
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	case err != nil:
+		logger.Error("Failed to read egress limit of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read egress limit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Egress limit retrieved",
+		"data":    gin.H{"organization_id": orgID, "bytes_per_second": limit},
+	})
+}
+
+// SetOrganizationEgress godoc
+// @Summary Set organization egress limit
+// @Description Sets the bytes per second each instance serves the organization's proxied media at, shared evenly between its concurrent streams: 0 is unlimited and null follows EGRESS_DEFAULT_LIMIT.
+// @Description Media redirected to storage or to a transcoding provider is not shaped. Other instances apply the change within EGRESS_CACHE_TTL.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body egressRequest true "Egress limit"
+// @Success 200 {object} SuccessResponse{data=object{organization_id=string,bytes_per_second=int}} "Egress limit saved"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /admin/v1/organizations/{id}/egress [put]
+func (h *EgressHandler) SetOrganizationEgress(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req egressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	err = h.shaper.SetLimit(c.Request.Context(), orgID, req.BytesPerSecond)
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	case err != nil:
+		logger.Error("Failed to set egress limit of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save egress limit"})
+		return
+	}
+	if req.BytesPerSecond == nil {
+		logger.Info("Organization %s now follows the default egress limit", orgID)
+	} else {
+		logger.Info("Organization %s egress limit set to %d bytes per second", orgID, *req.BytesPerSecond)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Egress limit saved",
+		"data":    gin.H{"organization_id": orgID, "bytes_per_second": req.BytesPerSecond},
+	})
+}
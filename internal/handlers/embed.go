@@ -0,0 +1,756 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/coalesce"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/egress"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	hlsContentType = "application/vnd.apple.mpegurl"
+	// maxAdPlaylistSize bounds the HLS playlists read to mark ad breaks
+	maxAdPlaylistSize = 4 << 20
+
+	defaultEmbedWidth  = 640
+	defaultEmbedHeight = 360
+)
+
+var embedPage = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbedURL}}" title="{{.Title}}">
+<style nonce="{{.Nonce}}">
+html, body { margin: 0; height: 100%; background: #000; }
+video { display: block; width: 100%; height: 100%; }
+.message { display: flex; align-items: center; justify-content: center; height: 100%; color: #ccc; font: 16px sans-serif; }
+.download { position: fixed; top: 12px; right: 12px; padding: 6px 12px; border-radius: 4px; background: rgba(0,0,0,.6); color: #fff; font: 14px sans-serif; text-decoration: none; }
+</style>
+</head>
+<body>
+{{if .Message}}<div class="message">{{.Message}}</div>
+{{else}}<video id="player" controls playsinline preload="metadata"{{if .Poster}} poster="{{.Poster}}"{{end}} data-src="{{.Src}}" data-type="{{.Type}}"{{if .BeaconURL}} data-beacon="{{.BeaconURL}}" data-video="{{.VideoID}}"{{if .ShareLinkID}} data-share="{{.ShareLinkID}}"{{end}}{{end}}{{if .AllowComments}} data-comments="true"{{end}}>{{if .ChaptersURL}}<track kind="chapters" default src="{{.ChaptersURL}}">{{end}}</video>
+{{if .DownloadURL}}<a class="download" href="{{.DownloadURL}}" download>Download</a>
+{{end}}{{if .HLS}}<script nonce="{{.Nonce}}" src="{{.HLSJSURL}}"></script>
+{{end}}<script nonce="{{.Nonce}}">
+var video = document.getElementById("player");
+var src = video.dataset.src, type = video.dataset.type;
+if (type === "` + hlsContentType + `" && !video.canPlayType(type) && window.Hls && Hls.isSupported()) {
+  var hls = new Hls();
+  hls.loadSource(src);
+  hls.attachMedia(video);
+} else {
+  video.src = src;
+}
+{{if .BeaconURL}}(function () {
+  var url = video.dataset.beacon, session = Math.random().toString(36).slice(2) + Date.now().toString(36);
+  var queue = [], playAt = 0, started = false, waitingAt = 0, rebuffers = 0, kbps = 0;
+  function report(type, fields) {
+    var e = {type: type, video_id: video.dataset.video, session_id: session,
+      occurred_at: new Date().toISOString(), position: video.currentTime};
+    if (video.dataset.share) e.share_link_id = video.dataset.share;
+    for (var k in fields) e[k] = fields[k];
+    queue.push(e);
+    if (queue.length >= 20) flush();
+  }
+  function flush() {
+    if (!queue.length) return;
+    var body = JSON.stringify({player: "openvdo-embed", events: queue});
+    queue = [];
+    if (!navigator.sendBeacon || !navigator.sendBeacon(url, body)) {
+      fetch(url, {method: "POST", body: body, keepalive: true, headers: {"Content-Type": "text/plain"}});
+    }
+  }
+  video.addEventListener("play", function () { if (!playAt) playAt = Date.now(); });
+  video.addEventListener("waiting", function () { if (started) waitingAt = Date.now(); });
+  video.addEventListener("playing", function () {
+    if (!started) {
+      started = true;
+      report("startup", {startup_ms: Date.now() - playAt});
+    } else if (waitingAt) {
+      report("rebuffer", {rebuffer_ms: Date.now() - waitingAt, rebuffer_count: ++rebuffers});
+    }
+    waitingAt = 0;
+  });
+  video.addEventListener("error", function () {
+    var err = video.error;
+    report("error", {error_code: "media_" + (err ? err.code : 0), error_message: err && err.message || ""});
+    flush();
+  });
+  video.addEventListener("ended", function () { report("end", {}); flush(); });
+  if (hls) {
+    hls.on(Hls.Events.LEVEL_SWITCHED, function (_, data) {
+      var level = hls.levels[data.level];
+      if (!level) return;
+      var next = Math.round(level.bitrate / 1000);
+      report("bitrate_switch", kbps ? {bitrate_kbps: next, previous_bitrate_kbps: kbps} : {bitrate_kbps: next});
+      kbps = next;
+    });
+  }
+  setInterval(function () {
+    if (started && !video.paused) report("heartbeat", {rebuffer_count: rebuffers});
+    flush();
+  }, 30000);
+  document.addEventListener("visibilitychange", function () { if (document.visibilityState === "hidden") flush(); });
+})();
+{{end}}</script>
+{{end}}</body>
+</html>
+`))
+
+// EmbedHandler serves the embeddable player, its media and oEmbed metadata. These endpoints
+// are public, so videos are looked up without tenant context and access follows the video's
+// visibility: public and unlisted videos play for anyone, private ones need a playback token.
+type EmbedHandler struct {
+	db            *sql.DB
+	storage       storage.Storage
+	config        config.Playback
+	presignExpiry time.Duration
+	// beacon has the player report playback quality to /api/v1/beacon
+	beacon bool
+	// Players opening a video at once share the lookups of its row, playlist and source
+	videos    *coalesce.Group
+	playlists *coalesce.Group
+	accesses  *coalesce.Group
+	sources   *coalesce.Group
+	cache     cachecontrol.Policies
+	egress    *egress.Shaper
+}
+
+// NewEmbedHandler creates a new embed handler. db must not carry a tenant context.
+func NewEmbedHandler(db *sql.DB, store storage.Storage, cfg config.Playback, presignExpiry time.Duration, beacon bool,
+	cache cachecontrol.Policies, shaper *egress.Shaper) *EmbedHandler {
+	return &EmbedHandler{
+		db: db, storage: store, config: cfg, presignExpiry: presignExpiry, beacon: beacon, cache: cache,
+		egress:    shaper,
+		videos:    coalesce.NewGroup("embed_video"),
+		playlists: coalesce.NewGroup("embed_playlist"),
+		accesses:  coalesce.NewGroup("embed_access"),
+		sources:   coalesce.NewGroup("embed_ad_playlist"),
+	}
+}
+
+// Embed godoc
+// @Summary Embedded player
+// @Description Serves an HTML5 player page for iframes. Private videos require a playback token. Videos of organizations with embed domains may only be framed on those.
+// @Tags embed
+// @Produce html
+// @Param id path string true "Video ID"
+// @Param token query string false "Playback token for private videos"
+// @Success 200 {string} string "Player page"
+// @Failure 403 {string} string "Embedded on a site outside the organization's embed domains"
+// @Failure 404 {string} string "Video not found"
+// @Router /embed/{id} [get]
+func (h *EmbedHandler) Embed(c *gin.Context) {
+	video, token, ok := h.playableVideo(c, c.Param("id"), c.Query("token"))
+	if !ok {
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte("Video not found"))
+		return
+	}
+	domains, err := services.EmbedDomains(c.Request.Context(), h.db, video.OrganizationID)
+	if err != nil {
+		logger.Error("Failed to load embed domains of video %s: %v", video.ID, err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	if !h.embedAllowed(c, domains) {
+		c.Data(http.StatusForbidden, "text/html; charset=utf-8", []byte("This video cannot be embedded on this site"))
+		return
+	}
+	h.servePlayer(c, video, token, domains, nil)
+}
+
+// servePlayer renders the player page of a video played with token. Pages opened through a
+// share link report their playbacks for it and offer what the link allows.
+func (h *EmbedHandler) servePlayer(c *gin.Context, video *models.Video, token string, domains []string, link *models.ShareLink) {
+	nonce, err := newNonce()
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	playlist, err := services.PlaybackPlaylist(c.Request.Context(), h.db, video.ID)
+	if err != nil {
+		logger.Error("Failed to look up playlist of video %s: %v", video.ID, err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	base := h.baseURL(c)
+	embedURL := base + "/embed/" + video.ID.String() + tokenQuery(token)
+	data := struct {
+		Title, Nonce, OEmbedURL, Src, Type, Poster, HLSJSURL, Message, BeaconURL, VideoID, ChaptersURL string
+		ShareLinkID, DownloadURL                                                                       string
+		HLS, AllowComments                                                                             bool
+	}{
+		Title:     video.Title,
+		Nonce:     nonce,
+		OEmbedURL: base + "/oembed?format=json&url=" + url.QueryEscape(embedURL),
+		Src:       base + "/embed/" + video.ID.String() + "/media" + tokenQuery(token),
+		Type:      video.ContentType,
+		HLSJSURL:  h.config.HLSJSURL,
+		HLS:       video.ContentType == hlsContentType,
+	}
+	if playlist != "" {
+		data.Type, data.HLS = hlsContentType, true
+	}
+	if video.Thumbnail != nil {
+		data.Poster = imageURL(base+"/embed/"+video.ID.String()+"/thumbnail", video.Thumbnail, token)
+	}
+	if !models.VideoPlayable(video.Status) {
+		data.Message = "This video is not ready yet"
+	}
+	if len(video.Chapters) > 0 {
+		query := url.Values{"format": {"vtt"}}
+		if token != "" {
+			query.Set("token", token)
+		}
+		data.ChaptersURL = base + "/embed/" + video.ID.String() + "/chapters?" + query.Encode()
+	}
+	if h.beacon {
+		data.BeaconURL = base + "/api/v1/beacon"
+		data.VideoID = video.ID.String()
+	}
+	if link != nil {
+		data.ShareLinkID = link.ID.String()
+		data.AllowComments = link.AllowComments
+		if link.AllowDownload && shareDownloadable(video) {
+			data.DownloadURL = base + "/s/" + link.Slug + "/download"
+		}
+	}
+
+	// The page is meant to be framed, on the organization's embed domains when it has any, and
+	// runs only its own nonce-tagged script
+	scriptSrc := "'nonce-" + nonce + "'"
+	if u, err := url.Parse(h.config.HLSJSURL); err == nil && u.Host != "" {
+		scriptSrc += " " + u.Scheme + "://" + u.Host
+	}
+	frameAncestors := "*"
+	if len(domains) > 0 {
+		frameAncestors = "'self' " + strings.Join(domains, " ")
+	}
+	c.Writer.Header().Del("X-Frame-Options")
+	c.Header("Content-Security-Policy", "default-src 'none'; script-src "+scriptSrc+"; style-src 'nonce-"+nonce+"'; "+
+		"media-src * blob:; connect-src *; img-src * data:; frame-ancestors "+frameAncestors)
+	if link != nil {
+		// Every open of a share link counts as a view, so caches must not answer it
+		h.cache.NoStore.Apply(c)
+	} else {
+		h.playbackCache(h.cache.Revalidate, token).Apply(c)
+	}
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := embedPage.Execute(c.Writer, data); err != nil {
+		logger.Error("Failed to render embed page for video %s: %v", video.ID, err)
+	}
+}
+
+// embedAllowed tells whether the site a request came from, by its Origin or Referer, is one
+// of the embed domains. Requests without either, such as from servers and from pages sending
+// no referrer, are allowed; frame-ancestors still keeps the player off other sites.
+func (h *EmbedHandler) embedAllowed(c *gin.Context, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	origin := strings.ToLower(services.RequestOrigin(c.GetHeader("Origin"), c.GetHeader("Referer")))
+	return origin == "" || origin == strings.ToLower(h.baseURL(c)) || origin == strings.ToLower(requestBaseURL(c)) ||
+		services.EmbedDomainAllowed(domains, origin)
+}
+
+// Media godoc
+// @Summary Embedded player media
+// @Description Streams the video source, or redirects to a time-limited storage URL. Private videos require a playback token.
+// @Description Videos with an HLS master playlist from a transcoder, streaming provider or encoder redirect to that playlist instead.
+// @Description HLS media playlists of videos with ad breaks are served with the ad cue markers.
+// @Tags embed
+// @Param id path string true "Video ID"
+// @Param token query string false "Playback token for private videos"
+// @Success 200 {file} file "Video data"
+// @Success 302 {string} string "Redirect to storage or to the master playlist"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /embed/{id}/media [get]
+func (h *EmbedHandler) Media(c *gin.Context) {
+	video, token, ok := h.playableVideo(c, c.Param("id"), c.Query("token"))
+	if !ok || !models.VideoPlayable(video.Status) || video.SourceKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	playlist, err := coalesce.Do(ctx, h.playlists, video.ID.String(), func(ctx context.Context) (string, error) {
+		return services.PlaybackPlaylist(ctx, h.db, video.ID)
+	})
+	if err != nil {
+		logger.Error("Failed to look up playlist of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access video"})
+		return
+	}
+	if playlist != "" {
+		// The provider streams the video. Not cached, as a replaced source brings a new playlist.
+		h.cache.NoStore.Apply(c)
+		c.Redirect(http.StatusFound, playlist)
+		return
+	}
+
+	// One refresh of the inactivity clock stands for all the players starting at once
+	_, err = coalesce.Do(ctx, h.accesses, video.SourceKey, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, services.MarkAccessed(ctx, h.db, video.SourceKey)
+	})
+	if err != nil {
+		logger.Debug("Failed to record access to %s: %v", video.SourceKey, err)
+	}
+
+	if video.ContentType == hlsContentType && len(video.AdBreaks) > 0 && h.serveAdPlaylist(c, video, token) {
+		return
+	}
+
+	if presigner, ok := storage.AsPresigner(h.storage); ok && !h.config.ProxyMedia {
+		u, err := presigner.PresignGet(ctx, video.SourceKey, h.presignExpiry)
+		if err != nil {
+			logger.Error("Failed to presign playback of video %s: %v", video.ID, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to access video source"})
+			return
+		}
+		h.cache.NoStore.Apply(c)
+		c.Redirect(http.StatusFound, u)
+		return
+	}
+
+	rc, err := h.openSource(ctx, video.SourceKey, c.GetHeader("Range"))
+	if err != nil {
+		logger.Error("Failed to open source of video %s: %v", video.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to access video source"})
+		return
+	}
+	defer rc.Close()
+	h.egress.Shape(c, video.OrganizationID)
+
+	if video.ContentType != "" {
+		c.Header("Content-Type", video.ContentType)
+	}
+	// Sources change when replaced, so all but playlists are revalidated against their ETag
+	if video.ContentType == hlsContentType {
+		h.playbackCache(h.cache.Playlist, token).Apply(c)
+	} else {
+		h.playbackCache(h.cache.Revalidate, token).Apply(c)
+	}
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		// Seeking in the player needs range requests. The validator changes with the source, so
+		// a range resumed across a replacement never mixes bytes of two files.
+		c.Header("ETag", fmt.Sprintf(`"%s-%d"`, video.ID, video.SourceRevision))
+		http.ServeContent(c.Writer, c.Request, "", video.UpdatedAt, rs)
+		return
+	}
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, rc)
+}
+
+// openSource opens a source for proxying. Backends with ranged reads read only the ranges of
+// the request; others, such as local disk, open the whole object.
+func (h *EmbedHandler) openSource(ctx context.Context, key, rangeHeader string) (io.ReadCloser, error) {
+	if rr, ok := storage.AsRangeReader(h.storage); ok {
+		r, err := newObjectReader(ctx, rr, key, rangeHeader)
+		if !errors.Is(err, storage.ErrRangeUnsupported) {
+			return r, err
+		}
+	}
+	return h.storage.Get(ctx, key)
+}
+
+// serveAdPlaylist serves an HLS media playlist with the video's ad cues. Relative segment URIs
+// are replaced with storage URLs where the backend presigns, since the playlist is no longer
+// fetched from storage. It returns false, having written nothing, when the source is not a
+// media playlist it can mark.
+func (h *EmbedHandler) serveAdPlaylist(c *gin.Context, video *models.Video, token string) bool {
+	ctx := c.Request.Context()
+	// The source is only read here, and InsertHLSAdCues writes the cues into a copy of it
+	playlist, err := coalesce.Do(ctx, h.sources, video.SourceKey, func(ctx context.Context) ([]byte, error) {
+		rc, err := h.storage.Get(ctx, video.SourceKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open: %w", err)
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxAdPlaylistSize+1))
+	})
+	if err != nil {
+		logger.Error("Failed to read playlist of video %s: %v", video.ID, err)
+		return false
+	}
+	if len(playlist) > maxAdPlaylistSize {
+		return false
+	}
+	playlist, ok := services.InsertHLSAdCues(playlist, video.AdBreaks)
+	if !ok {
+		return false
+	}
+
+	policy := h.playbackCache(h.cache.Playlist, token)
+	if presigner, ok := storage.AsPresigner(h.storage); ok {
+		// The presigned segment URLs expire
+		policy = h.cache.NoStore
+		dir := path.Dir(video.SourceKey)
+		var b strings.Builder
+		for _, line := range strings.SplitAfter(string(playlist), "\n") {
+			uri := strings.TrimSpace(line)
+			if uri != "" && !strings.HasPrefix(uri, "#") && !strings.Contains(uri, "://") && !strings.HasPrefix(uri, "/") {
+				u, err := presigner.PresignGet(ctx, path.Join(dir, uri), h.presignExpiry)
+				if err != nil {
+					logger.Error("Failed to presign segment of video %s: %v", video.ID, err)
+					c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to access video source"})
+					return true
+				}
+				line = u + "\n"
+			}
+			b.WriteString(line)
+		}
+		playlist = []byte(b.String())
+	}
+
+	policy.Apply(c)
+	c.Data(http.StatusOK, hlsContentType, playlist)
+	return true
+}
+
+// Thumbnail godoc
+// @Summary Video thumbnail
+// @Description Serves the video's poster image in the best format the Accept header allows. Private videos require a playback token.
+// @Tags embed
+// @Produce image/avif,image/webp,image/jpeg,image/png
+// @Param id path string true "Video ID"
+// @Param w query int false "Smallest acceptable width in pixels"
+// @Param token query string false "Playback token for private videos"
+// @Success 200 {file} file "Image data"
+// @Failure 404 {object} ErrorResponse "Video or thumbnail not found"
+// @Router /embed/{id}/thumbnail [get]
+func (h *EmbedHandler) Thumbnail(c *gin.Context) {
+	video, token, ok := h.playableVideo(c, c.Param("id"), c.Query("token"))
+	if !ok || video.Thumbnail == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+		return
+	}
+
+	// Shared caches may keep public posters, but not ones reached through a playback token
+	serveImage(c, h.storage, h.cache, video.Thumbnail, token == "")
+}
+
+// Chapters godoc
+// @Summary Embedded player chapters
+// @Description Returns a video's chapters for rendering a segmented timeline, each with its end except the last. With format=vtt
+// @Description they are returned as the WebVTT chapters track the embedded player loads. Private videos require a playback token.
+// @Tags embed
+// @Produce json,text/vtt
+// @Param id path string true "Video ID"
+// @Param format query string false "json (default) or vtt"
+// @Param token query string false "Playback token for private videos"
+// @Success 200 {object} SuccessResponse{data=object{chapters=[]timelineChapter}} "Chapters retrieved"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /embed/{id}/chapters [get]
+func (h *EmbedHandler) Chapters(c *gin.Context) {
+	video, _, ok := h.playableVideo(c, c.Param("id"), c.Query("token"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	writeChapters(c, video.Chapters)
+}
+
+// Ads godoc
+// @Summary Embedded player ads
+// @Description Returns a video's ad breaks and its organization's VAST tag URL, with [VIDEO_ID] filled in, for players that insert
+// @Description ads. Private videos require a playback token.
+// @Tags embed
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param token query string false "Playback token for private videos"
+// @Success 200 {object} SuccessResponse{data=playbackAds} "Ads retrieved"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /embed/{id}/ads [get]
+func (h *EmbedHandler) Ads(c *gin.Context) {
+	video, _, ok := h.playableVideo(c, c.Param("id"), c.Query("token"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	ads, err := loadPlaybackAds(c.Request.Context(), h.db, video.ID, video.OrganizationID, video.AdBreaks)
+	if err != nil {
+		logger.Error("Failed to load ads of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ads"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Ads retrieved successfully",
+		"data":    ads,
+	})
+}
+
+// OEmbed godoc
+// @Summary oEmbed
+// @Description Returns oEmbed metadata with an iframe for an embed URL, so CMSs and chat apps can show rich previews
+// @Tags embed
+// @Produce json
+// @Param url query string true "Embed URL of the video (/embed/{id}, with ?token= for private videos)"
+// @Param format query string false "Response format; only json is supported"
+// @Param maxwidth query int false "Maximum iframe width"
+// @Param maxheight query int false "Maximum iframe height"
+// @Success 200 {object} object{version=string,type=string,title=string,provider_name=string,provider_url=string,width=int,height=int,html=string,thumbnail_url=string,thumbnail_width=int,thumbnail_height=int} "oEmbed response"
+// @Failure 401 {object} ErrorResponse "Private video"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 501 {object} ErrorResponse "Unsupported format"
+// @Router /oembed [get]
+func (h *EmbedHandler) OEmbed(c *gin.Context) {
+	if format := c.DefaultQuery("format", "json"); format != "json" {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Only the json format is supported"})
+		return
+	}
+
+	u, err := url.Parse(c.Query("url"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	videoID, ok := strings.CutPrefix(strings.TrimSuffix(u.Path, "/"), "/embed/")
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	video, err := h.loadVideo(c.Request.Context(), videoID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	token := u.Query().Get("token")
+	if !h.authorized(video, token) {
+		// oEmbed asks for 401 on resources that exist but are not public
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Video is private"})
+		return
+	}
+
+	width, height := embedSize(c.Query("maxwidth"), c.Query("maxheight"))
+	base := h.baseURL(c)
+	src := base + "/embed/" + video.ID.String() + tokenQuery(token)
+
+	// The oEmbed specification defines the response shape, so it is not wrapped
+	response := gin.H{
+		"version":       "1.0",
+		"type":          "video",
+		"title":         video.Title,
+		"provider_name": h.config.ProviderName,
+		"provider_url":  base,
+		"width":         width,
+		"height":        height,
+		"html": fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" title="%s" frameborder="0" `+
+			`allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>`,
+			html.EscapeString(src), width, height, html.EscapeString(video.Title)),
+	}
+	if video.Thumbnail != nil {
+		response["thumbnail_url"] = imageURL(base+"/embed/"+video.ID.String()+"/thumbnail", video.Thumbnail, token)
+		response["thumbnail_width"] = video.Thumbnail.Width
+		response["thumbnail_height"] = video.Thumbnail.Height
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreatePlaybackToken godoc
+// @Summary Create playback token
+// @Description Signs a time-limited token that lets the embedded player play a video, including private ones.
+// @Description The response carries the video's ad breaks and the organization's VAST tag URL for players that insert ads.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 201 {object} SuccessResponse{data=object{token=string,expires_at=string,embed_url=string,media_url=string,ads=playbackAds}} "Token with embed and media URLs"
+// @Failure 403 {object} ErrorResponse "Requested from a site outside the organization's embed domains"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 503 {object} ErrorResponse "Playback signing not configured"
+// @Router /api/v1/videos/{id}/playback-token [post]
+func (h *EmbedHandler) CreatePlaybackToken(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	if h.config.SigningKey == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Playback tokens are not configured (PLAYBACK_SIGNING_KEY)"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	// Looking the video up under RLS limits tokens to members of its organization
+	video, err := queries.New(tenantDB).GetVideoAdBreaks(c.Request.Context(), videoID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+	domains, err := services.EmbedDomains(c.Request.Context(), h.db, video.OrganizationID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+	if !h.embedAllowed(c, domains) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The organization does not allow embedding its videos on this site"})
+		return
+	}
+	ads, err := loadPlaybackAds(c.Request.Context(), h.db, videoID, video.OrganizationID, video.AdBreaks)
+	if err != nil {
+		logger.Error("Failed to load ads of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+
+	expiresAt := time.Now().Add(h.config.TokenTTL).UTC()
+	token := services.SignPlaybackToken(h.config.SigningKey, videoID, expiresAt)
+	base := h.baseURL(c)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Playback token created",
+		"data": gin.H{
+			"token":      token,
+			"expires_at": expiresAt,
+			"embed_url":  base + "/embed/" + videoID.String() + tokenQuery(token),
+			"media_url":  base + "/embed/" + videoID.String() + "/media" + tokenQuery(token),
+			"ads":        ads,
+		},
+	})
+}
+
+// playableVideo loads a video and checks that it may be played with token, which is only
+// returned when it was needed
+func (h *EmbedHandler) playableVideo(c *gin.Context, id, token string) (*models.Video, string, bool) {
+	video, err := h.loadVideo(c.Request.Context(), id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Error("Failed to load video %s for playback: %v", id, err)
+		}
+		return nil, "", false
+	}
+	if !h.authorized(video, token) {
+		return nil, "", false
+	}
+	if !tokenRequired(video) {
+		token = ""
+	}
+	return video, token, true
+}
+
+func (h *EmbedHandler) loadVideo(ctx context.Context, id string) (*models.Video, error) {
+	videoID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, sql.ErrNoRows
+	}
+	// Shared by the requests loading the video at the same time, none of which modify it
+	return coalesce.Do(ctx, h.videos, videoID.String(), func(ctx context.Context) (*models.Video, error) {
+		// Videos of deleted organizations stop playing before the teardown removes them
+		return services.VideoModel(queries.New(h.db).GetPlayableVideo(ctx, videoID))
+	})
+}
+
+func (h *EmbedHandler) authorized(video *models.Video, token string) bool {
+	// Videos unpublished by reports or taken down do not play for anyone
+	if video.ModerationStatus != models.ModerationActive {
+		return false
+	}
+	if !tokenRequired(video) {
+		return true
+	}
+	return services.VerifyPlaybackToken(h.config.SigningKey, video.ID, token) == nil
+}
+
+// tokenRequired tells whether a video only plays with a playback token: private videos, and
+// videos waiting for approval or rejected, which members preview with one
+func tokenRequired(video *models.Video) bool {
+	return video.Visibility == models.VideoVisibilityPrivate || video.ReviewStatus != models.ReviewApproved
+}
+
+// playbackCache is policy for a response about a video played with token, which is only set
+// for private videos; those must not be kept by shared caches
+func (h *EmbedHandler) playbackCache(policy cachecontrol.Policy, token string) cachecontrol.Policy {
+	if token != "" {
+		return policy.Private()
+	}
+	return policy
+}
+
+// baseURL is the configured public URL, or the scheme and host the request arrived on
+func (h *EmbedHandler) baseURL(c *gin.Context) string {
+	if h.config.PublicURL != "" {
+		return h.config.PublicURL
+	}
+	return requestBaseURL(c)
+}
+
+// requestBaseURL is the base URL the request was made to
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func tokenQuery(token string) string {
+	if token == "" {
+		return ""
+	}
+	return "?token=" + url.QueryEscape(token)
+}
+
+// embedSize fits a 16:9 player into the requested maximum dimensions
+func embedSize(maxWidth, maxHeight string) (int, int) {
+	width, height := defaultEmbedWidth, defaultEmbedHeight
+	if w, err := strconv.Atoi(maxWidth); err == nil && w > 0 && w < width {
+		width, height = w, w*9/16
+	}
+	if hgt, err := strconv.Atoi(maxHeight); err == nil && hgt > 0 && hgt < height {
+		width, height = hgt*16/9, hgt
+	}
+	return width, height
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const encoderIntegrationColumns = `id, organization_id, name, created_by, last_received_at, created_at, updated_at`
+
+func scanEncoderIntegration(row interface{ Scan(...interface{}) error }) (*models.EncoderIntegration, error) {
+	var e models.EncoderIntegration
+	if err := row.Scan(&e.ID, &e.OrganizationID, &e.Name, &e.CreatedBy, &e.LastReceivedAt, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// EncoderHandler receives notifications from external encoders
+type EncoderHandler struct {
+	db     *sql.DB
+	config config.Encoders
+}
+
+// NewEncoderHandler creates an encoder handler. db must not carry a tenant context, as
+// encoders are not members.
+func NewEncoderHandler(db *sql.DB, cfg config.Encoders) *EncoderHandler {
+	return &EncoderHandler{db: db, config: cfg}
+}
+
+// ReceiveEncoderNotification godoc
+// @Summary Receive encoder notification
+// @Description Accepts a notification from an external encoder, signed in X-OpenVDO-Signature as webhooks are: t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>"> keyed with the integration's secret.
+// @Description A rendition.complete notification registers the renditions, hosted by the encoder, on a video of the integration's organization; renditions with a URL the video already has are replaced. Other types are acknowledged and ignored.
+// @Tags encoders
+// @Accept json
+// @Produce json
+// @Param id path string true "Encoder integration ID"
+// @Param X-OpenVDO-Signature header string true "Signature of the body"
+// @Param request body services.EncoderNotification true "Notification"
+// @Success 200 {object} SuccessResponse{data=object{renditions=[]models.VideoRendition}} "Renditions registered"
+// @Failure 400 {object} ErrorResponse "Invalid notification"
+// @Failure 401 {object} ErrorResponse "Unknown integration, or invalid or expired signature"
+// @Failure 404 {object} ErrorResponse "Video not found in the organization"
+// @Failure 413 {object} ErrorResponse "Body too large"
+// @Router /hooks/encoders/{id} [post]
+func (h *EncoderHandler) ReceiveEncoderNotification(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, h.config.WebhookMaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Notification body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read notification body"})
+		return
+	}
+
+	// Unknown integrations and bad signatures answer alike, so integration IDs cannot be probed
+	integrationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+	ctx := c.Request.Context()
+	orgID, secret, err := services.EncoderSecret(ctx, h.db, integrationID)
+	if err != nil && !errors.Is(err, services.ErrEncoderIntegrationNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up encoder integration"})
+		return
+	}
+	if err != nil || services.VerifyWebhook(secret, c.GetHeader(services.WebhookSignatureHeader), body,
+		time.Now(), h.config.WebhookTolerance) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	var notification services.EncoderNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification body: " + err.Error()})
+		return
+	}
+	if notification.Type != services.EncoderRenditionComplete {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "success",
+			"message": "Notification type ignored",
+			"data":    gin.H{"renditions": []models.VideoRendition{}},
+		})
+		return
+	}
+	specs, err := services.ValidateRenditions(notification.Renditions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	renditions, err := services.RegisterRenditions(ctx, h.db, integrationID, orgID, notification.VideoID, specs)
+	if errors.Is(err, services.ErrEncoderVideo) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to register renditions of video %s from encoder integration %s: %v", notification.VideoID, integrationID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register renditions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Renditions registered",
+		"data":    gin.H{"renditions": renditions},
+	})
+}
+
+// ListEncoderIntegrations godoc
+// @Summary List encoder integrations
+// @Description Lists the external encoders allowed to register renditions on the organization's videos. Requires the owner or admin role.
+// @Tags encoders
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=object{integrations=[]models.EncoderIntegration}} "Integrations retrieved"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/encoder-integrations [get]
+func ListEncoderIntegrations(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing encoder integrations")
+	if !ok {
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(c.Request.Context(),
+		`SELECT `+encoderIntegrationColumns+` FROM encoder_integrations WHERE organization_id = $1 ORDER BY created_at`, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query encoder integrations"})
+		return
+	}
+	defer rows.Close()
+
+	integrations := []models.EncoderIntegration{}
+	for rows.Next() {
+		e, err := scanEncoderIntegration(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan encoder integration"})
+			return
+		}
+		integrations = append(integrations, *e)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing encoder integration results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Encoder integrations retrieved",
+		"data":    gin.H{"integrations": integrations},
+	})
+}
+
+type createEncoderIntegrationRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// CreateEncoderIntegration godoc
+// @Summary Create encoder integration
+// @Description Adds an external encoder, such as a MediaConvert or Mux pipeline, that posts signed notifications to /hooks/encoders/{integration id}.
+// @Description The response includes the signing secret, which is not shown again. Requires the owner or admin role.
+// @Tags encoders
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body createEncoderIntegrationRequest true "Name of the integration"
+// @Success 201 {object} SuccessResponse{data=models.EncoderIntegration} "Integration created, with its secret"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Router /api/v1/organizations/{id}/encoder-integrations [post]
+func CreateEncoderIntegration(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing encoder integrations")
+	if !ok {
+		return
+	}
+
+	var req createEncoderIntegrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name must not be empty"})
+		return
+	}
+
+	secret, err := services.NewWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	integration, err := scanEncoderIntegration(tenantDB.QueryRowContext(c.Request.Context(), `
+		INSERT INTO encoder_integrations (organization_id, name, secret, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+encoderIntegrationColumns,
+		orgID, name, secret, tenantDB.GetUserID()))
+	if err != nil {
+		logger.Error("Failed to create encoder integration for organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create encoder integration"})
+		return
+	}
+	integration.Secret = secret
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Encoder integration created",
+		"data":    integration,
+	})
+}
+
+// DeleteEncoderIntegration godoc
+// @Summary Delete encoder integration
+// @Description Removes an encoder integration, so its notifications are refused. Renditions it registered are kept. Requires the owner or admin role.
+// @Tags encoders
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param integration_id path string true "Encoder integration ID"
+// @Success 200 {object} SuccessResponse{data=object{id=string}} "Integration deleted"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Integration not found"
+// @Router /api/v1/organizations/{id}/encoder-integrations/{integration_id} [delete]
+func DeleteEncoderIntegration(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing encoder integrations")
+	if !ok {
+		return
+	}
+
+	integrationID, err := uuid.Parse(c.Param("integration_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid encoder integration ID"})
+		return
+	}
+
+	var deleted uuid.UUID
+	err = tenantDB.QueryRowContext(c.Request.Context(),
+		`DELETE FROM encoder_integrations WHERE id = $1 AND organization_id = $2 RETURNING id`, integrationID, orgID).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Encoder integration not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete encoder integration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Encoder integration deleted",
+		"data":    gin.H{"id": deleted},
+	})
+}
+
+// ListVideoRenditions godoc
+// @Summary List video renditions
+// @Description Lists the renditions of a video hosted by external encoders, highest bitrate first
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{renditions=[]models.VideoRendition}} "Renditions retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/renditions [get]
+func ListVideoRenditions(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	found, err := queries.New(tenantDB).VideoExists(ctx, videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(ctx, `
+		SELECT `+services.RenditionColumns+` FROM video_renditions
+		WHERE video_id = $1
+		ORDER BY bitrate_kbps DESC NULLS LAST, created_at
+	`, videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query renditions"})
+		return
+	}
+	defer rows.Close()
+
+	renditions := []models.VideoRendition{}
+	for rows.Next() {
+		r, err := services.ScanRendition(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan rendition"})
+			return
+		}
+		renditions = append(renditions, *r)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing rendition results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Renditions retrieved",
+		"data":    gin.H{"renditions": renditions},
+	})
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxEntitlementCSVSize bounds the CSV of a bulk grant
+const maxEntitlementCSVSize = 1 << 20
+
+func entitlementEvent(eventType string, e *models.Entitlement) outbox.NewEvent {
+	return outbox.NewEvent{OrganizationID: e.OrganizationID, Type: eventType, SubjectID: &e.ID, Data: e}
+}
+
+// ListEntitlements godoc
+// @Summary List entitlements
+// @Description Lists the organization's entitlements, newest first. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param user_id query string false "Only grants of this user"
+// @Param video_id query string false "Only grants of this video"
+// @Param project_id query string false "Only grants of this project"
+// @Param active query bool false "Only grants that have not expired"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} SuccessResponse{data=object{entitlements=[]models.Entitlement,pagination=response.Pagination}} "Entitlements retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/entitlements [get]
+func ListEntitlements(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing entitlements")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	filter := ` WHERE organization_id = $1`
+	args := []interface{}{orgID}
+	for _, param := range []string{"user_id", "video_id", "project_id"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+			return
+		}
+		args = append(args, id)
+		filter += fmt.Sprintf(` AND %s = $%d`, param, len(args))
+	}
+	if active, _ := strconv.ParseBool(c.Query("active")); active {
+		filter += ` AND (expires_at IS NULL OR expires_at > NOW())`
+	}
+
+	ctx := c.Request.Context()
+	rows, err := tenantDB.QueryContext(ctx, `SELECT `+services.EntitlementColumns+` FROM entitlements`+filter+
+		fmt.Sprintf(` ORDER BY created_at DESC LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2), append(args, limit, offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query entitlements"})
+		return
+	}
+	defer rows.Close()
+
+	entitlements := []models.Entitlement{}
+	for rows.Next() {
+		e, err := services.ScanEntitlement(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan entitlement"})
+			return
+		}
+		entitlements = append(entitlements, *e)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing entitlement results"})
+		return
+	}
+
+	var total int
+	if err := tenantDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM entitlements`+filter, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Entitlements retrieved successfully",
+		"data": gin.H{
+			"entitlements": entitlements,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+type grantEntitlementRequest struct {
+	UserID          *uuid.UUID `json:"user_id"`
+	Email           string     `json:"email" binding:"omitempty,email"`
+	VideoID         *uuid.UUID `json:"video_id"`
+	ProjectID       *uuid.UUID `json:"project_id"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+	DurationSeconds int64      `json:"duration_seconds" binding:"min=0"`
+	Reference       string     `json:"reference" binding:"max=255"`
+}
+
+// GrantEntitlement godoc
+// @Summary Grant entitlement
+// @Description Grants a user, given by user_id or email, playback of a video or of every video of a project until expires_at, or for
+// @Description duration_seconds as with rentals; without either the grant does not expire. Granting the same video or project to the
+// @Description user again replaces the previous grant. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body grantEntitlementRequest true "user_id or email, video_id or project_id, and optionally expires_at or duration_seconds, and reference"
+// @Success 201 {object} SuccessResponse{data=models.Entitlement} "Entitlement granted"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "User, video or project not found"
+// @Router /api/v1/organizations/{id}/entitlements [post]
+func GrantEntitlement(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing entitlements")
+	if !ok {
+		return
+	}
+
+	var req grantEntitlementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if (req.UserID == nil) == (req.Email == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either user_id or email"})
+		return
+	}
+	if (req.VideoID == nil) == (req.ProjectID == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either video_id or project_id"})
+		return
+	}
+	if req.ExpiresAt != nil && req.DurationSeconds > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Give either expires_at or duration_seconds"})
+		return
+	}
+
+	grant := services.EntitlementGrant{
+		Email:     req.Email,
+		VideoID:   req.VideoID,
+		ProjectID: req.ProjectID,
+		ExpiresAt: req.ExpiresAt,
+		Reference: strings.TrimSpace(req.Reference),
+	}
+	if req.UserID != nil {
+		grant.UserID = *req.UserID
+	}
+	if req.DurationSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.DurationSeconds) * time.Second).UTC()
+		grant.ExpiresAt = &expiresAt
+	}
+
+	ctx := c.Request.Context()
+	var entitlement *models.Entitlement
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		entitlement, err = services.GrantEntitlement(ctx, tx, orgID, tenantDB.GetUserID(), grant)
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, entitlementEvent(outbox.EventEntitlementGranted, entitlement))
+	})
+	switch {
+	case errors.Is(err, services.ErrEntitlementUser):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case errors.Is(err, services.ErrEntitlementTarget):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video or project not found in the organization"})
+		return
+	case err != nil:
+		logger.Error("Failed to grant entitlement in organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant entitlement"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Entitlement granted",
+		"data":    entitlement,
+	})
+}
+
+// BulkGrantEntitlements godoc
+// @Summary Bulk grant entitlements
+// @Description Grants entitlements from a CSV body, e.g. to enroll a class in a course. The columns are user (a user ID or email address),
+// @Description video_id, project_id, expires_at (RFC 3339, empty for none) and reference; they are positional unless the first row
+// @Description names them. Rows that cannot be granted are skipped and listed. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Accept text/csv
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body string true "CSV rows"
+// @Success 200 {object} SuccessResponse{data=object{granted=int,skipped=[]string}} "Number of grants and skipped rows"
+// @Failure 400 {object} ErrorResponse "Invalid CSV"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 413 {object} ErrorResponse "CSV too large"
+// @Router /api/v1/organizations/{id}/entitlements/bulk [post]
+func BulkGrantEntitlements(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing entitlements")
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxEntitlementCSVSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(data) > maxEntitlementCSVSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("CSV exceeds %d bytes", maxEntitlementCSVSize)})
+		return
+	}
+	grants, skipped, err := services.ParseEntitlementCSV(string(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	grantedBy := tenantDB.GetUserID()
+	granted := 0
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		events := make([]outbox.NewEvent, 0, len(grants))
+		for _, g := range grants {
+			entitlement, err := services.GrantEntitlement(ctx, tx, orgID, grantedBy, g)
+			if errors.Is(err, services.ErrEntitlementUser) || errors.Is(err, services.ErrEntitlementTarget) {
+				skipped = append(skipped, fmt.Sprintf("row %d: %v", g.Row, err))
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			events = append(events, entitlementEvent(outbox.EventEntitlementGranted, entitlement))
+		}
+		granted = len(events)
+		return outbox.WriteAll(ctx, tx, events)
+	})
+	if err != nil {
+		logger.Error("Failed to bulk grant entitlements in organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant entitlements"})
+		return
+	}
+	if skipped == nil {
+		skipped = []string{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Entitlements granted",
+		"data":    gin.H{"granted": granted, "skipped": skipped},
+	})
+}
+
+// RevokeEntitlement godoc
+// @Summary Revoke entitlement
+// @Description Removes an entitlement. Playback sessions relying on it end at their next heartbeat. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param entitlement_id path string true "Entitlement ID"
+// @Success 200 {object} SuccessResponse{data=models.Entitlement} "Entitlement revoked"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Entitlement not found"
+// @Router /api/v1/organizations/{id}/entitlements/{entitlement_id} [delete]
+func RevokeEntitlement(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing entitlements")
+	if !ok {
+		return
+	}
+
+	entitlementID, err := uuid.Parse(c.Param("entitlement_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entitlement ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var entitlement *models.Entitlement
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		entitlement, err = services.ScanEntitlement(tx.QueryRowContext(ctx,
+			`DELETE FROM entitlements WHERE id = $1 AND organization_id = $2 RETURNING `+services.EntitlementColumns,
+			entitlementID, orgID))
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, entitlementEvent(outbox.EventEntitlementRevoked, entitlement))
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entitlement not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to revoke entitlement %s: %v", entitlementID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke entitlement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Entitlement revoked",
+		"data":    entitlement,
+	})
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// FeedHandler serves the public catalog feeds of projects and smart playlists
+type FeedHandler struct {
+	generator *services.FeedGenerator
+	playlists *services.SmartPlaylists
+	cache     cachecontrol.Policies
+}
+
+// NewFeedHandler creates a new feed handler
+func NewFeedHandler(generator *services.FeedGenerator, playlists *services.SmartPlaylists, cache cachecontrol.Policies) *FeedHandler {
+	return &FeedHandler{generator: generator, playlists: playlists, cache: cache}
+}
+
+// MRSSFeed godoc
+// @Summary Project MRSS feed
+// @Description Lists the project's public, playable videos that need no entitlement as RSS 2.0 with Media RSS elements, for connected TV and
+// @Description syndication ingestion. Feeds are cached by CDNs for FEEDS_CDN_MAX_AGE and purged when they change. Projects without such videos have no feed.
+// @Tags feeds
+// @Produce application/rss+xml
+// @Param id path string true "Project ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {string} string "MRSS document"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Router /feeds/projects/{id}/mrss.xml [get]
+func (h *FeedHandler) MRSSFeed(c *gin.Context) {
+	h.serve(c, services.FeedFormatMRSS)
+}
+
+// JSONFeed godoc
+// @Summary Project JSON Feed
+// @Description Lists the project's public, playable videos that need no entitlement as JSON Feed 1.1, with each source as an attachment
+// @Tags feeds
+// @Produce application/feed+json
+// @Param id path string true "Project ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} services.jsonFeed "JSON Feed document"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Router /feeds/projects/{id}/feed.json [get]
+func (h *FeedHandler) JSONFeed(c *gin.Context) {
+	h.serve(c, services.FeedFormatJSON)
+}
+
+// PodcastFeed godoc
+// @Summary Project podcast feed
+// @Description Lists the project's public, playable audio and video files that need no entitlement as podcast RSS with iTunes tags and
+// @Description the sources as enclosures. Other sources, such as HLS playlists, are left out, since podcast apps only play files.
+// @Tags feeds
+// @Produce application/rss+xml
+// @Param id path string true "Project ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {string} string "Podcast RSS document"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Router /feeds/projects/{id}/podcast.xml [get]
+func (h *FeedHandler) PodcastFeed(c *gin.Context) {
+	h.serve(c, services.FeedFormatPodcast)
+}
+
+// serve writes a project's feed in format. Stored feeds change only through regeneration,
+// which purges them, so CDNs may keep them long; feed readers revalidate sooner with the ETag.
+func (h *FeedHandler) serve(c *gin.Context, format string) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var body []byte
+	var etag string
+	if h.generator.Stored() {
+		var feed *services.Feed
+		if feed, err = h.generator.Load(ctx, projectID, format); err == nil {
+			body, etag = feed.Body, `"`+feed.ETag+`"`
+		}
+	} else {
+		if body, err = h.generator.Render(ctx, projectID, format, requestBaseURL(c)); err == nil {
+			etag = listETag(string(body))
+		}
+	}
+	if errors.Is(err, services.ErrFeedNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to load %s feed of project %s: %v", format, projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+
+	h.cache.Feed.Apply(c)
+	if notModified(c, etag) {
+		return
+	}
+	c.Data(http.StatusOK, services.FeedContentType(format), body)
+}
+
+// PlaylistMRSSFeed godoc
+// @Summary Smart playlist MRSS feed
+// @Description Lists the smart playlist's public, playable videos that need no entitlement as RSS 2.0 with Media RSS elements, in the
+// @Description playlist's order. The feed is computed when read and cached for FEEDS_PLAYLIST_CACHE_TTL. Playlists without such videos have no feed.
+// @Tags feeds
+// @Produce application/rss+xml
+// @Param id path string true "Smart playlist ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {string} string "MRSS document"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Router /feeds/playlists/{id}/mrss.xml [get]
+func (h *FeedHandler) PlaylistMRSSFeed(c *gin.Context) {
+	h.servePlaylist(c, services.FeedFormatMRSS)
+}
+
+// PlaylistJSONFeed godoc
+// @Summary Smart playlist JSON Feed
+// @Description Lists the smart playlist's public, playable videos that need no entitlement as JSON Feed 1.1, in the playlist's order
+// @Tags feeds
+// @Produce application/feed+json
+// @Param id path string true "Smart playlist ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} services.jsonFeed "JSON Feed document"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Router /feeds/playlists/{id}/feed.json [get]
+func (h *FeedHandler) PlaylistJSONFeed(c *gin.Context) {
+	h.servePlaylist(c, services.FeedFormatJSON)
+}
+
+// PlaylistPodcastFeed godoc
+// @Summary Smart playlist podcast feed
+// @Description Lists the smart playlist's public, playable audio and video files that need no entitlement as podcast RSS with the
+// @Description sources as enclosures, in the playlist's order
+// @Tags feeds
+// @Produce application/rss+xml
+// @Param id path string true "Smart playlist ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {string} string "Podcast RSS document"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Router /feeds/playlists/{id}/podcast.xml [get]
+func (h *FeedHandler) PlaylistPodcastFeed(c *gin.Context) {
+	h.servePlaylist(c, services.FeedFormatPodcast)
+}
+
+// servePlaylist writes a smart playlist's feed in format. Nothing purges these feeds, so
+// caches keep them only as long as the playlist's videos are reused.
+func (h *FeedHandler) servePlaylist(c *gin.Context, format string) {
+	playlistID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+
+	body, err := h.playlists.RenderFeed(c.Request.Context(), playlistID, format, requestBaseURL(c))
+	if errors.Is(err, services.ErrFeedNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feed not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to render %s feed of smart playlist %s: %v", format, playlistID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+
+	h.cache.PlaylistFeed.Apply(c)
+	if notModified(c, listETag(string(body))) {
+		return
+	}
+	c.Data(http.StatusOK, services.FeedContentType(format), body)
+}
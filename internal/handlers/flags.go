@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/flags"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FlagHandler struct {
+	flags *flags.Store
+}
+
+func NewFlagHandler(store *flags.Store) *FlagHandler {
+	return &FlagHandler{flags: store}
+}
+
+// GetFlags godoc
+// @Summary Get feature flags
+// @Description Returns whether each feature flag is on for the caller's organization
+// @Tags flags
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{organization_id=string,flags=map[string]bool}} "Flags retrieved"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/v1/flags [get]
+func (h *FlagHandler) GetFlags(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	session, err := tenantDB.GetUserSession(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User is not a member of any organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Flags retrieved successfully",
+		"data": gin.H{
+			"organization_id": session.OrgID,
+			"flags":           h.flags.Evaluate(c.Request.Context(), session.OrgID),
+		},
+	})
+}
+
+// ListFlags godoc
+// @Summary List feature flags
+// @Description Lists every feature flag with its rollout percentage and organization overrides
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{flags=[]flags.Flag}} "Flags retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/flags [get]
+func (h *FlagHandler) ListFlags(c *gin.Context) {
+	list, err := h.flags.List(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list feature flags: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query flags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Flags retrieved successfully",
+		"data":    gin.H{"flags": list},
+	})
+}
+
+type putFlagRequest struct {
+	Description string `json:"description" binding:"max=255"`
+	Enabled     *bool  `json:"enabled" binding:"required"`
+	Percentage  int    `json:"percentage" binding:"min=0,max=100"`
+}
+
+// PutFlag godoc
+// @Summary Create or update feature flag
+// @Description Creates a flag or replaces its settings, keeping its overrides. A disabled flag is off everywhere;
+// @Description an enabled one is on for overridden organizations and for percentage percent of the rest.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param key path string true "Flag key"
+// @Param request body putFlagRequest true "Whether the flag is on, for which percentage of organizations, and its description"
+// @Success 200 {object} SuccessResponse{data=flags.Flag} "Flag saved"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/flags/{key} [put]
+func (h *FlagHandler) PutFlag(c *gin.Context) {
+	key := c.Param("key")
+	if !flags.ValidKey(key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flag key"})
+		return
+	}
+
+	var req putFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	flag, err := h.flags.Put(c.Request.Context(), key, strings.TrimSpace(req.Description), *req.Enabled, req.Percentage)
+	if err != nil {
+		logger.Error("Failed to save feature flag %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save flag"})
+		return
+	}
+	logger.Info("Feature flag %s set to enabled=%t percentage=%d", key, flag.Enabled, flag.Percentage)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Flag saved",
+		"data":    flag,
+	})
+}
+
+// DeleteFlag godoc
+// @Summary Delete feature flag
+// @Description Removes a flag and its overrides; code checking it then sees it as off
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param key path string true "Flag key"
+// @Success 200 {object} SuccessResponse{data=object{key=string}} "Flag deleted"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Flag not found"
+// @Router /admin/v1/flags/{key} [delete]
+func (h *FlagHandler) DeleteFlag(c *gin.Context) {
+	key := c.Param("key")
+	err := h.flags.Delete(c.Request.Context(), key)
+	if errors.Is(err, flags.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flag not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete feature flag %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete flag"})
+		return
+	}
+	logger.Info("Feature flag %s deleted", key)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Flag deleted",
+		"data":    gin.H{"key": key},
+	})
+}
+
+type flagOverrideRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// SetFlagOverride godoc
+// @Summary Override feature flag for organization
+// @Description Forces an enabled flag on or off for one organization, regardless of its percentage
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param key path string true "Flag key"
+// @Param org_id path string true "Organization ID"
+// @Param request body flagOverrideRequest true "Whether the flag is on for the organization"
+// @Success 200 {object} SuccessResponse{data=object{key=string,organization_id=string,enabled=bool}} "Override saved"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Flag or organization not found"
+// @Router /admin/v1/flags/{key}/organizations/{org_id} [put]
+func (h *FlagHandler) SetFlagOverride(c *gin.Context) {
+	key := c.Param("key")
+	orgID, err := uuid.Parse(c.Param("org_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req flagOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	err = h.flags.SetOverride(c.Request.Context(), key, orgID, *req.Enabled)
+	if errors.Is(err, flags.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flag or organization not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to override feature flag %s for organization %s: %v", key, orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save override"})
+		return
+	}
+	logger.Info("Feature flag %s overridden to %t for organization %s", key, *req.Enabled, orgID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Override saved",
+		"data":    gin.H{"key": key, "organization_id": orgID, "enabled": *req.Enabled},
+	})
+}
+
+// DeleteFlagOverride godoc
+// @Summary Remove feature flag override
+// @Description Returns an organization to the flag's percentage rollout
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param key path string true "Flag key"
+// @Param org_id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=object{key=string,organization_id=string}} "Override removed"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Override not found"
+// @Router /admin/v1/flags/{key}/organizations/{org_id} [delete]
+func (h *FlagHandler) DeleteFlagOverride(c *gin.Context) {
+	key := c.Param("key")
+	orgID, err := uuid.Parse(c.Param("org_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	err = h.flags.DeleteOverride(c.Request.Context(), key, orgID)
+	if errors.Is(err, flags.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Override not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to remove override of feature flag %s for organization %s: %v", key, orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove override"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Override removed",
+		"data":    gin.H{"key": key, "organization_id": orgID},
+	})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"openvdo/internal/flightrecorder"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxFlightRecords = 1000
+
+type FlightRecorderHandler struct {
+	recorder *flightrecorder.Recorder
+}
+
+func NewFlightRecorderHandler(recorder *flightrecorder.Recorder) *FlightRecorderHandler {
+	return &FlightRecorderHandler{recorder: recorder}
+}
+
+// GetFlightRecorder godoc
+// @Summary Get flight recorder state
+// @Description Returns whether the instance answering records requests, until when, and how many it holds.
+// @Description Each instance records on its own.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=flightrecorder.State} "Flight recorder state retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/flight-recorder [get]
+func (h *FlightRecorderHandler) GetFlightRecorder(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Flight recorder state retrieved successfully",
+		"data":    h.recorder.State(),
+	})
+}
+
+type flightRecorderRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+	// Duration is in seconds
+	Duration *int `json:"duration" binding:"omitempty,min=1" example:"600"`
+}
+
+// SetFlightRecorder godoc
+// @Summary Turn the flight recorder on or off
+// @Description Turns recording on the instance answering on for duration seconds, by default and at most FLIGHT_RECORDER_MAX_DURATION,
+// @Description or off. Requests are kept with their headers, the start of their bodies and the statements run on the tenant connection;
+// @Description credentials and fields that look secret are redacted. Turning recording off keeps the records.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param request body flightRecorderRequest true "Whether to record, and for how long"
+// @Success 200 {object} SuccessResponse{data=flightrecorder.State} "Flight recorder state saved"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/flight-recorder [put]
+func (h *FlightRecorderHandler) SetFlightRecorder(c *gin.Context) {
+	var req flightRecorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	duration := h.recorder.MaxDuration()
+	if req.Duration != nil {
+		duration = time.Duration(*req.Duration) * time.Second
+		if duration > h.recorder.MaxDuration() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duration may be at most " + h.recorder.MaxDuration().String()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Flight recorder state saved",
+		"data":    h.recorder.Set(*req.Enabled, duration),
+	})
+}
+
+// ListFlightRecords godoc
+// @Summary List recorded requests
+// @Description Returns the requests recorded by the instance answering, newest first.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param min_status query int false "Only responses with at least this status, such as 500"
+// @Param path query string false "Only paths starting with this prefix"
+// @Param min_duration_ms query int false "Only requests that took at least this many milliseconds"
+// @Param limit query int false "Most records returned (default 100, max 1000)"
+// @Success 200 {object} SuccessResponse{data=object{requests=[]flightrecorder.Record,state=flightrecorder.State}} "Recorded requests retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/flight-recorder/requests [get]
+func (h *FlightRecorderHandler) ListFlightRecords(c *gin.Context) {
+	filter := flightrecorder.Filter{PathPrefix: c.Query("path"), Limit: 100}
+	for _, param := range []struct {
+		name string
+		max  int
+		set  func(int)
+	}{
+		{"min_status", 599, func(v int) { filter.MinStatus = v }},
+		{"min_duration_ms", 1 << 30, func(v int) { filter.MinDuration = time.Duration(v) * time.Millisecond }},
+		{"limit", maxFlightRecords, func(v int) { filter.Limit = v }},
+	} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 || v > param.max {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param.name})
+			return
+		}
+		param.set(v)
+	}
+
+	records := h.recorder.Records(filter)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Recorded requests retrieved successfully",
+		"data":    gin.H{"requests": records, "state": h.recorder.State()},
+	})
+}
+
+// GetFlightRecord godoc
+// @Summary Get a recorded request
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param id path int true "Record ID"
+// @Success 200 {object} SuccessResponse{data=flightrecorder.Record} "Recorded request retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Record not found or overwritten"
+// @Router /admin/v1/flight-recorder/requests/{id} [get]
+func (h *FlightRecorderHandler) GetFlightRecord(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record ID"})
+		return
+	}
+	record := h.recorder.Get(id)
+	if record == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Recorded request retrieved successfully",
+		"data":    record,
+	})
+}
+
+// ClearFlightRecords godoc
+// @Summary Clear recorded requests
+// @Description Drops the requests recorded by the instance answering. Recording goes on if it is on.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=flightrecorder.State} "Recorded requests cleared"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/flight-recorder/requests [delete]
+func (h *FlightRecorderHandler) ClearFlightRecords(c *gin.Context) {
+	h.recorder.Clear()
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Recorded requests cleared",
+		"data":    h.recorder.State(),
+	})
+}
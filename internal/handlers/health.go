@@ -3,7 +3,8 @@ package handlers
 import (
 	"net/http"
 
-	"openvdo/internal/database"
+	"openvdo/internal/buildinfo"
+	"openvdo/internal/health"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,66 +14,105 @@ import (
 // @Description Checks if the server is running and responds with basic status
 // @Tags health
 // @Produce json
-// @Success 200 {object} map[string]interface{} "Server is healthy"
+// @Success 200 {object} SuccessResponse{data=object{version=string}} "Server is healthy"
 // @Router /health [get]
 func HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "ok",
 		"message": "Server is healthy",
-		"data":    gin.H{},
+		"data":    gin.H{"version": buildinfo.Version()},
 	})
 }
 
-// DatabaseHealthCheck godoc
-// @Summary Database pool health check
-// @Description Checks the health of database connection pools
+// Version godoc
+// @Summary Build information
+// @Description Returns the version, commit and build date of the running server and the Go version it was built with
 // @Tags health
 // @Produce json
-// @Success 200 {object} map[string]interface{} "Database pools are healthy"
-// @Failure 503 {object} map[string]string "Database pool not available"
-// @Router /health/db [get]
-func DatabaseHealthCheck(c *gin.Context) {
-	pm := database.GetPoolManager()
-	if pm == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database pool not available"})
-		return
-	}
+// @Success 200 {object} SuccessResponse{data=buildinfo.Info} "Build information"
+// @Router /version [get]
+func Version(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Build information retrieved successfully",
+		"data":    buildinfo.Get(),
+	})
+}
+
+// HealthHandler serves Kubernetes-style liveness and readiness probes and the drain hook
+type HealthHandler struct {
+	checks *health.Registry
+	drain  *health.Drain
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(checks *health.Registry, drain *health.Drain) *HealthHandler {
+	return &HealthHandler{checks: checks, drain: drain}
+}
+
+// Livez godoc
+// @Summary Liveness probe
+// @Description Reports that the process is up and serving requests. It does not check dependencies, so a failing database never causes restarts.
+// @Tags health
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{version=string}} "Process is alive"
+// @Router /livez [get]
+func (h *HealthHandler) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "ok",
+		"message": "Server is alive",
+		"data":    gin.H{"version": buildinfo.Version()},
+	})
+}
+
+// Readyz godoc
+// @Summary Readiness probe
+// @Description Reports whether the database, Redis, storage and the job queue are reachable and the schema migrations are applied. Results come from a background refresh, so the probe never waits on a dependency. A draining instance is never ready.
+// @Tags health
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=health.Report} "All dependencies ready"
+// @Failure 503 {object} SuccessResponse{data=health.Report} "One or more dependencies not ready, or the instance is draining, with the report"
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	report := h.checks.Report()
 
-	health := pm.GetHealth()
-	if health.Healthy {
+	if h.drain.Draining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "draining",
+			"message": "The instance is shutting down",
+			"data":    report,
+		})
+	} else if report.Ready {
 		c.JSON(http.StatusOK, gin.H{
-			"status":  "healthy",
-			"message": "Database pools are healthy",
-			"data":    health,
+			"status":  "ready",
+			"message": "All dependencies are ready",
+			"data":    report,
 		})
 	} else {
 		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status":  "unhealthy",
-			"message": "Database pools unhealthy",
-			"data":    health,
+			"status":  "not_ready",
+			"message": "One or more dependencies are not ready",
+			"data":    report,
 		})
 	}
 }
 
-// DatabaseStats godoc
-// @Summary Database pool statistics
-// @Description Returns detailed statistics about database connection pools
-// @Tags stats
+// Drainz godoc
+// @Summary Drain the instance
+// @Description Meant for a Kubernetes preStop hook. Starts draining: /readyz fails from then on and long-lived responses are ended over the stream grace. The answer is held until the drain delay has passed, so load balancers have stopped routing here when the pod receives SIGTERM.
+// @Tags health
 // @Produce json
-// @Success 200 {object} map[string]interface{} "Database pool statistics"
-// @Failure 503 {object} map[string]string "Database pool not available"
-// @Router /stats/db [get]
-func DatabaseStats(c *gin.Context) {
-	pm := database.GetPoolManager()
-	if pm == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database pool not available"})
-		return
-	}
+// @Security AdminToken
+// @Success 200 {object} SuccessResponse "Instance drained"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Admin API is disabled"
+// @Router /drainz [get]
+func (h *HealthHandler) Drainz(c *gin.Context) {
+	h.drain.Start("drain requested")
+	h.drain.Wait(c.Request.Context())
 
-	metrics := pm.GetMetrics()
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
-		"message": "Database pool statistics",
-		"data":    metrics,
+		"message": "Instance drained",
 	})
-}
\ No newline at end of file
+}
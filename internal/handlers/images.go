@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/config"
+	"openvdo/internal/images"
+	"openvdo/internal/models"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errImageTooLarge = errors.New("image file is too large")
+
+// readImageUpload reads an uploaded image from the "file" field of a multipart form, or from
+// the raw request body for any other content type
+func readImageUpload(c *gin.Context, maxSize int64) ([]byte, error) {
+	var r io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		// Leaves room for the form's own framing around the file
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+		fh, err := c.FormFile("file")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, errImageTooLarge
+			}
+			return nil, fmt.Errorf("missing file field: %w", err)
+		}
+		if fh.Size > maxSize {
+			return nil, errImageTooLarge
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errImageTooLarge
+	}
+	if len(data) == 0 {
+		return nil, images.ErrUnsupported
+	}
+	return data, nil
+}
+
+// storeImageUpload reads, processes and stores an uploaded image under prefix, writing the
+// error response itself when it fails
+func storeImageUpload(c *gin.Context, store storage.Storage, processor *images.Processor, cfg config.Images,
+	spec images.Spec, prefix string) (*models.Image, bool) {
+	data, err := readImageUpload(c, cfg.MaxUploadSize)
+	if err != nil {
+		imageUploadError(c, err, cfg.MaxUploadSize)
+		return nil, false
+	}
+	ctx := c.Request.Context()
+	variants, err := processor.Process(ctx, data, spec)
+	if err != nil {
+		imageUploadError(c, err, cfg.MaxUploadSize)
+		return nil, false
+	}
+
+	img, err := images.Store(ctx, store, prefix, models.ImageSourceCustom, variants)
+	if err != nil {
+		logger.Error("Failed to store image under %s: %v", prefix, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+		return nil, false
+	}
+	return img, true
+}
+
+// imageUploadError writes the response for a failure to read or process an uploaded image
+func imageUploadError(c *gin.Context, err error, maxSize int64) {
+	switch {
+	case errors.Is(err, errImageTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Image exceeds the maximum size of %d bytes", maxSize)})
+	case errors.Is(err, images.ErrUnsupported):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": images.ErrUnsupported.Error()})
+	case errors.Is(err, images.ErrTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Image dimensions are too large"})
+	default:
+		logger.Error("Failed to process image: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process image"})
+	}
+}
+
+// serveImage writes the variant of img that best fits the w query parameter and the Accept
+// header. Files on local disk are served as they are; variants from other backends are small,
+// so they are read into memory to support conditional and range requests. Every image has its own ID, so links carrying it as the v
+// query parameter may be cached for good; without it clients revalidate after a few minutes.
+func serveImage(c *gin.Context, store storage.Storage, cache cachecontrol.Policies, img *models.Image, public bool) {
+	width, _ := strconv.Atoi(c.Query("w"))
+	variant := images.Pick(img, width, c.GetHeader("Accept"))
+	if variant == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	rc, err := store.Get(c.Request.Context(), variant.Key)
+	if err != nil {
+		logger.Error("Failed to open image %s: %v", variant.Key, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read image"})
+		return
+	}
+	defer rc.Close()
+	content, ok := rc.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			logger.Error("Failed to read image %s: %v", variant.Key, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read image"})
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	policy := cache.Image
+	if c.Query("v") == img.ID {
+		policy = cache.Immutable
+	}
+	if !public {
+		policy = policy.Private()
+	}
+
+	c.Header("Content-Type", variant.ContentType)
+	policy.Apply(c)
+	c.Header("Vary", "Accept")
+	c.Header("ETag", fmt.Sprintf(`"%s-%d-%s"`, img.ID, variant.Width, variant.Format))
+	http.ServeContent(c.Writer, c.Request, "", img.CreatedAt, content)
+}
+
+// imageURL is the address of an image served at path, pinned to its current version
+func imageURL(path string, img *models.Image, token string) string {
+	u := path + "?v=" + url.QueryEscape(img.ID)
+	if token != "" {
+		u += "&token=" + url.QueryEscape(token)
+	}
+	return u
+}
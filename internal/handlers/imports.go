@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"path"
+
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ImportHandler queues server-side downloads of video sources from URLs
+type ImportHandler struct {
+	importer *services.Importer
+	bulk     *services.BulkImporter
+	queue    *jobs.Queue
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importer *services.Importer, bulk *services.BulkImporter, queue *jobs.Queue) *ImportHandler {
+	return &ImportHandler{importer: importer, bulk: bulk, queue: queue}
+}
+
+type importVideoRequest struct {
+	URL         string     `json:"url" binding:"required"`
+	Title       string     `json:"title" binding:"omitempty,max=255"`
+	Description string     `json:"description"`
+	Tags        []string   `json:"tags" binding:"omitempty,max=50,dive,max=64"`
+	ProjectID   *uuid.UUID `json:"project_id"`
+	// ExternalID is the video's ID in an external system, unique within the organization
+	ExternalID *string `json:"external_id" binding:"omitempty,min=1,max=255,excludes=/"`
+	// CustomFields are values of the organization's custom fields, by key
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+// ImportVideo godoc
+// @Summary Import a video from a URL
+// @Description Creates a video and queues a background job that downloads its source from an HTTPS URL. Private and internal addresses are refused.
+// @Description Follow the job's progress at /api/v1/jobs/{id}; once downloaded the video is uploaded and hashed like a multipart upload.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body importVideoRequest true "Source URL and video details"
+// @Success 202 {object} SuccessResponse{data=object{video=models.Video,job=jobs.Job}} "Video created and import queued"
+// @Failure 400 {object} ErrorResponse "Invalid request or URL"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 409 {object} ErrorResponse "External ID already used by another video"
+// @Router /api/v1/videos/import [post]
+func (h *ImportHandler) ImportVideo(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req importVideoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	u, err := h.importer.ParseURL(req.URL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source URL: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+
+	customFields, _, ok := videoCustomFields(c, tenantDB, session.OrgID, req.CustomFields)
+	if !ok {
+		return
+	}
+
+	filename := path.Base(u.Path)
+	if req.Title == "" {
+		req.Title = filename
+		if req.Title == "" || req.Title == "." || req.Title == "/" {
+			req.Title = u.Hostname()
+		}
+	}
+
+	videoID := uuid.New()
+	var video *models.Video
+	var job *jobs.Job
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		video, err = services.VideoModel(queries.New(tx).CreateImportedVideo(ctx, queries.CreateImportedVideoParams{
+			ID:             videoID,
+			OrganizationID: session.OrgID,
+			ProjectID:      req.ProjectID,
+			Title:          req.Title,
+			Description:    req.Description,
+			Tags:           services.NormalizeTags(req.Tags),
+			CustomFields:   customFields,
+			Status:         models.VideoStatusUploading,
+			SourceKey:      services.SourceKey(session.OrgID, videoID, filename),
+			CreatedBy:      session.UserID,
+			ExternalID:     req.ExternalID,
+		}))
+		if err != nil {
+			return fmt.Errorf("failed to insert video: %w", err)
+		}
+		held, err := services.HoldForReview(ctx, tx, session.OrgID, videoID, session.UserID, session.Role)
+		if err != nil {
+			return err
+		}
+		if held {
+			video.ReviewStatus = models.ReviewPending
+		}
+		if err := outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoCreated, video)); err != nil {
+			return err
+		}
+
+		job, err = jobs.Enqueue(ctx, tx, services.JobKindVideoImport,
+			services.ImportPayload{VideoID: videoID, URL: u.String()},
+			jobs.Options{OrganizationID: &session.OrgID, CreatedBy: &session.UserID})
+		return err
+	})
+	if databaseError(c, err) {
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to queue import of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue import"})
+		return
+	}
+	tenantDB.InvalidateCount(ctx, database.VideoCount(session.OrgID))
+	h.queue.Notify()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Import queued",
+		"data": gin.H{
+			"video": video,
+			"job":   job,
+		},
+	})
+}
+
+// BulkImportVideos godoc
+// @Summary Bulk import videos
+// @Description Queues a job that walks an S3 prefix or a CSV manifest (url, title, tags; tags separated by ; or |) and queues an import for every entry.
+// @Description Give exactly one of s3 ({bucket, prefix, region, endpoint, force_path_style}), manifest (CSV text) or manifest_url. Buckets are listed anonymously unless secrets ({access_key_id, secret_access_key}) are given; secrets are never returned and are deleted when the walk ends.
+// @Description Imports are throttled to a few in flight at once. The job's checkpoint shows how far the walk got and its children counts show the imports by status. Requires the owner or admin role.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body services.BulkImportPayload true "Source (s3, manifest or manifest_url), secrets, tags added to every video and project_id"
+// @Success 202 {object} SuccessResponse{data=jobs.Job} "Bulk import queued"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Owner or admin role required"
+// @Router /api/v1/videos/import/bulk [post]
+func (h *ImportHandler) BulkImportVideos(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req services.BulkImportPayload
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if err := h.bulk.Validate(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+	if session.Role != models.RoleOwner && session.Role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bulk imports require the owner or admin role"})
+		return
+	}
+
+	job, err := jobs.Enqueue(ctx, tenantDB, services.JobKindBulkImport, req,
+		jobs.Options{OrganizationID: &session.OrgID, CreatedBy: &session.UserID})
+	if err != nil {
+		logger.Error("Failed to queue bulk import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue bulk import"})
+		return
+	}
+	h.queue.Notify()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Bulk import queued",
+		"data":    job,
+	})
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"openvdo/internal/audit"
+	"openvdo/internal/ipaccess"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPAccessHandler manages the address ranges organizations' APIs may be used from, and the
+// audit log recording changes to them and the requests they refused
+type IPAccessHandler struct {
+	guard *ipaccess.Guard
+}
+
+// NewIPAccessHandler creates a new IP access handler
+func NewIPAccessHandler(guard *ipaccess.Guard) *IPAccessHandler {
+	return &IPAccessHandler{guard: guard}
+}
+
+// GetIPAccess godoc
+// @Summary Get IP access rules
+// @Description Returns the address ranges requests in the organization may come from. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=models.IPAccessRules} "Rules retrieved"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/ip-access [get]
+func (h *IPAccessHandler) GetIPAccess(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing IP access")
+	if !ok {
+		return
+	}
+
+	rules, err := ipaccess.Load(c.Request.Context(), tenantDB, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load IP access rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "IP access rules retrieved",
+		"data":    rules,
+	})
+}
+
+// PutIPAccess godoc
+// @Summary Set IP access rules
+// @Description Replaces the address ranges requests in the organization may come from, as CIDR ranges or single addresses. Denied ranges win over allowed ones; with no allowed ranges every address not denied is allowed. Send empty lists to lift the restriction.
+// @Description Rules that would deny the caller's own address are rejected. Requests refused by the rules answer 403 and are recorded in the audit log. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body models.IPAccessRules true "Allowed and denied ranges"
+// @Success 200 {object} SuccessResponse{data=models.IPAccessRules} "Rules updated"
+// @Failure 400 {object} ErrorResponse "Invalid range, too many ranges, or rules denying the caller"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/ip-access [put]
+func (h *IPAccessHandler) PutIPAccess(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing IP access")
+	if !ok {
+		return
+	}
+
+	var req models.IPAccessRules
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	rules, err := ipaccess.Normalize(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	compiled, err := ipaccess.Parse(rules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	caller, err := netip.ParseAddr(c.ClientIP())
+	if err == nil && !compiled.Allows(caller) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The rules would deny your own address " + caller.String()})
+		return
+	}
+
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode IP access rules"})
+		return
+	}
+	ctx := c.Request.Context()
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		n, err := queries.New(tx).SetIPAccessRules(ctx, queries.SetIPAccessRulesParams{
+			Key: ipaccess.SettingsKey, Rules: encoded, ID: orgID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return audit.Record(ctx, tx, audit.Entry{
+			OrganizationID: orgID,
+			UserID:         tenantDB.GetUserID(),
+			Action:         models.AuditIPAccessUpdated,
+			IPAddress:      c.ClientIP(),
+			Details:        rules,
+		})
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update IP access rules of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update IP access rules"})
+		return
+	}
+	h.guard.Invalidate(ctx, orgID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "IP access rules updated",
+		"data":    rules,
+	})
+}
+
+// ListAuditLog godoc
+// @Summary List audit log
+// @Description Lists the organization's audit log newest first: changes to its IP access rules and the requests they refused. Page with before, the created_at of the last entry of the previous page. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param action query string false "Only entries of this action, e.g. ip_access.denied"
+// @Param before query string false "Only entries created before this RFC 3339 time"
+// @Param limit query int false "Maximum number of entries (default 50, max 500)"
+// @Success 200 {object} SuccessResponse{data=object{entries=[]models.AuditEntry}} "Entries retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid before or limit"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/audit-log [get]
+func (h *IPAccessHandler) ListAuditLog(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Reading the audit log")
+	if !ok {
+		return
+	}
+
+	var before time.Time
+	if s := c.Query("before"); s != "" {
+		var err error
+		if before, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before, expected an RFC 3339 time"})
+			return
+		}
+	}
+	limit := 50
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := audit.List(c.Request.Context(), tenantDB, orgID, c.Query("action"), before, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query the audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Audit log retrieved",
+		"data":    gin.H{"entries": entries},
+	})
+}
@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/internal/transcode"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetJob godoc
+// @Summary Get job
+// @Description Returns the status and progress (0 to 1) of a background job of the user's organizations.
+// @Description Jobs that spawn others, such as bulk imports, also have children: the number of spawned jobs in each status.
+// @Tags jobs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} SuccessResponse{data=jobs.Job} "Job retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid job ID"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Router /api/v1/jobs/{id} [get]
+func GetJob(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	job, err := jobs.Get(ctx, tenantDB, jobID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job"})
+		}
+		return
+	}
+
+	if job.Children, err = jobs.ChildCounts(ctx, tenantDB, jobID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Job retrieved successfully",
+		"data":    job,
+	})
+}
+
+// videoProcessingJobKinds are the jobs that bring a video's source in and process it
+var videoProcessingJobKinds = []string{services.JobKindVideoImport, transcode.JobKindTranscode}
+
+// parseJobFilter reads the filters of failed job listings and bulk retries from the query
+func parseJobFilter(c *gin.Context) (jobs.Filter, error) {
+	f := jobs.Filter{Status: c.Query("status"), Kinds: c.QueryArray("kind")}
+	if f.Status != "" && f.Status != jobs.StatusFailed && f.Status != jobs.StatusQuarantined {
+		return f, errors.New("status must be failed or quarantined")
+	}
+	if raw := c.Query("video_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return f, errors.New("invalid video_id")
+		}
+		f.VideoID = &id
+	}
+	if raw := c.Query("organization_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return f, errors.New("invalid organization_id")
+		}
+		f.OrganizationID = &id
+	}
+	if raw := c.Query("failed_since"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return f, errors.New("failed_since must be an RFC 3339 time")
+		}
+		f.FailedSince = t
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > 1000 {
+			return f, errors.New("limit must be between 1 and 1000")
+		}
+		f.Limit = limit
+	}
+	return f, nil
+}
+
+// ListFailedJobs godoc
+// @Summary List failed jobs
+// @Description Lists the failed and quarantined background jobs of the user's organizations, the latest to give up first, with their last error, attempts and failures.
+// @Description Jobs are quarantined once they have failed JOBS_QUARANTINE_AFTER times across retries.
+// @Tags jobs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param status query string false "Only failed or only quarantined jobs" Enums(failed, quarantined)
+// @Param kind query []string false "Only jobs of these kinds, such as video.transcode" collectionFormat(multi)
+// @Param video_id query string false "Only jobs of this video"
+// @Param failed_since query string false "Only jobs that gave up at or after this RFC 3339 time"
+// @Param limit query int false "Maximum jobs returned" default(100)
+// @Success 200 {object} SuccessResponse{data=object{jobs=[]jobs.Job}} "Failed jobs retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Router /api/v1/jobs [get]
+func ListFailedJobs(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	f, err := parseJobFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f.OrganizationID = nil
+
+	list, err := jobs.List(c.Request.Context(), tenantDB, f)
+	if err != nil {
+		logger.Error("Failed to list failed jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Failed jobs retrieved",
+		"data":    gin.H{"jobs": list},
+	})
+}
+
+// JobHandler requeues failed jobs
+type JobHandler struct {
+	db    *sql.DB
+	queue *jobs.Queue
+}
+
+// NewJobHandler creates a job handler. db is the master connection the admin endpoints act
+// for every organization through.
+func NewJobHandler(db *sql.DB, queue *jobs.Queue) *JobHandler {
+	return &JobHandler{db: db, queue: queue}
+}
+
+// RetryVideoProcessing godoc
+// @Summary Retry video processing
+// @Description Requeues the video's latest import or transcode job when it failed, with its attempts renewed. A retried import downloads the source again and a retried transcode submits it again.
+// @Description Quarantined jobs can only be released by an administrator.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 202 {object} SuccessResponse{data=jobs.Job} "Processing requeued"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 409 {object} ErrorResponse{data=jobs.Job} "The latest job has not failed or is quarantined, with the job"
+// @Router /api/v1/videos/{id}/retry [post]
+func (h *JobHandler) RetryVideoProcessing(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	found, err := queries.New(tenantDB).VideoExists(ctx, videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+
+	latest, err := jobs.LatestForVideo(ctx, tenantDB, videoID, videoProcessingJobKinds)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusConflict, gin.H{"error": "The video has no processing jobs"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get jobs"})
+		return
+	}
+	switch latest.Status {
+	case jobs.StatusFailed:
+	case jobs.StatusQuarantined:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The job failed too often and is quarantined until an administrator releases it",
+			"data":  latest,
+		})
+		return
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "The video's latest processing job has not failed", "data": latest})
+		return
+	}
+
+	job, err := jobs.Retry(ctx, tenantDB, latest.ID, false)
+	if errors.Is(err, jobs.ErrNotRetryable) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The job was retried already"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to retry job %s of video %s: %v", latest.ID, videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry job"})
+		return
+	}
+	h.queue.Notify()
+	logger.Info("Retrying %s job %s of video %s", job.Kind, job.ID, videoID)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Processing requeued",
+		"data":    job,
+	})
+}
+
+// ListJobs godoc
+// @Summary List failed jobs of all organizations
+// @Description Lists failed and quarantined background jobs of every organization, the latest to give up first, with their last error.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param status query string false "Only failed or only quarantined jobs" Enums(failed, quarantined)
+// @Param kind query []string false "Only jobs of these kinds" collectionFormat(multi)
+// @Param organization_id query string false "Only jobs of this organization"
+// @Param video_id query string false "Only jobs of this video"
+// @Param failed_since query string false "Only jobs that gave up at or after this RFC 3339 time"
+// @Param limit query int false "Maximum jobs returned" default(100)
+// @Success 200 {object} SuccessResponse{data=object{jobs=[]jobs.Job}} "Failed jobs retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/jobs [get]
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	f, err := parseJobFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := jobs.List(c.Request.Context(), h.db, f)
+	if err != nil {
+		logger.Error("Failed to list failed jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Failed jobs retrieved",
+		"data":    gin.H{"jobs": list},
+	})
+}
+
+// RetryJob godoc
+// @Summary Retry a job
+// @Description Requeues a failed job with its attempts renewed. Quarantined jobs are only requeued with release=true, which also resets their failures.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param id path string true "Job ID"
+// @Param release query bool false "Release a quarantined job"
+// @Success 202 {object} SuccessResponse{data=jobs.Job} "Job requeued"
+// @Failure 400 {object} ErrorResponse "Invalid job ID"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Job not found"
+// @Failure 409 {object} ErrorResponse "Job has not failed, or is quarantined and not released"
+// @Router /admin/v1/jobs/{id}/retry [post]
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+	release := c.Query("release") == "true"
+
+	job, err := jobs.Retry(c.Request.Context(), h.db, jobID, release)
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	case errors.Is(err, jobs.ErrNotRetryable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to retry job %s: %v", jobID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry job"})
+		return
+	}
+	h.queue.Notify()
+	logger.Info("Retrying %s job %s (release: %v)", job.Kind, job.ID, release)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Job requeued",
+		"data":    job,
+	})
+}
+
+// RetryFailedJobs godoc
+// @Summary Retry failed jobs in bulk
+// @Description Requeues failed jobs matching the filters, the earliest to give up first, with their attempts renewed; typically after fixing an outage such as a transcoder's. Quarantined jobs are left alone and must be retried one by one.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param kind query []string false "Only jobs of these kinds, such as video.transcode" collectionFormat(multi)
+// @Param organization_id query string false "Only jobs of this organization"
+// @Param video_id query string false "Only jobs of this video"
+// @Param failed_since query string false "Only jobs that gave up at or after this RFC 3339 time"
+// @Param limit query int false "Maximum jobs requeued" default(100)
+// @Success 202 {object} SuccessResponse{data=object{retried=int}} "Jobs requeued"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/jobs/retry [post]
+func (h *JobHandler) RetryFailedJobs(c *gin.Context) {
+	f, err := parseJobFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	n, err := jobs.RetryFailed(c.Request.Context(), h.db, f)
+	if err != nil {
+		logger.Error("Failed to retry failed jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry jobs"})
+		return
+	}
+	if n > 0 {
+		h.queue.Notify()
+	}
+	logger.Info("Retrying %d failed jobs", n)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Jobs requeued",
+		"data":    gin.H{"retried": n},
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"openvdo/internal/maintenance"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceHandler struct {
+	mode       *maintenance.Mode
+	retryAfter time.Duration
+}
+
+func NewMaintenanceHandler(mode *maintenance.Mode, retryAfter time.Duration) *MaintenanceHandler {
+	return &MaintenanceHandler{mode: mode, retryAfter: retryAfter}
+}
+
+// GetMaintenance godoc
+// @Summary Get maintenance mode
+// @Description Returns the maintenance mode in effect on the instance answering
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=maintenance.State} "Maintenance mode retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/maintenance [get]
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Maintenance mode retrieved successfully",
+		"data":    h.mode.Current(),
+	})
+}
+
+type maintenanceRequest struct {
+	Enabled    *bool  `json:"enabled" binding:"required"`
+	Message    string `json:"message" binding:"max=500"`
+	RetryAfter *int   `json:"retry_after" binding:"omitempty,min=0,max=86400"`
+}
+
+// SetMaintenance godoc
+// @Summary Turn maintenance mode on or off
+// @Description While maintenance mode is on, requests that change data are answered with 503 and Retry-After; reads, playback and the admin API keep working.
+// @Description Other instances pick the change up within MAINTENANCE_POLL_INTERVAL.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param request body maintenanceRequest true "Whether maintenance mode is on, and optionally its message and retry_after in seconds"
+// @Success 200 {object} SuccessResponse{data=maintenance.State} "Maintenance mode saved"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/maintenance [put]
+func (h *MaintenanceHandler) SetMaintenance(c *gin.Context) {
+	var req maintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	retryAfter := int(h.retryAfter.Seconds())
+	if req.RetryAfter != nil {
+		retryAfter = *req.RetryAfter
+	}
+
+	state, err := h.mode.Set(c.Request.Context(), *req.Enabled, strings.TrimSpace(req.Message), retryAfter)
+	if err != nil {
+		logger.Error("Failed to save maintenance mode: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save maintenance mode"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Maintenance mode saved",
+		"data":    state,
+	})
+}
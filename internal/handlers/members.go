@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errAlreadyMember = errors.New("user is already a member")
+
+type addMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=owner admin developer viewer"`
+}
+
+// AddOrganizationMember godoc
+// @Summary Add organization member
+// @Description Gives an existing user a role in the organization and notifies them of the invitation. Requires the owner or admin role; only owners can add owners.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body addMemberRequest true "Email address and role of the user"
+// @Success 201 {object} SuccessResponse{data=models.Membership} "Member added"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization or user not found"
+// @Failure 409 {object} ErrorResponse "Already a member"
+// @Router /api/v1/organizations/{id}/members [post]
+func AddOrganizationMember(c *gin.Context) {
+	tenantDB, orgID, callerRole, ok := organizationAdmin(c, "Adding members")
+	if !ok {
+		return
+	}
+
+	var req addMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Role == models.RoleOwner && callerRole != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can add owners"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	inviterID := tenantDB.GetUserID()
+	var member *models.Membership
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		qtx := queries.New(tx)
+		userID, err := qtx.FindUserByEmailFold(ctx, strings.TrimSpace(req.Email))
+		if err != nil {
+			return err
+		}
+
+		member, err = services.MembershipModel(qtx.AddMember(ctx, queries.AddMemberParams{
+			UserID: userID, OrganizationID: orgID, Role: req.Role, InvitedBy: &inviterID,
+		}))
+		if err == sql.ErrNoRows {
+			return errAlreadyMember
+		}
+		if err != nil {
+			return err
+		}
+
+		names, err := qtx.GetInvitationNames(ctx, queries.GetInvitationNamesParams{
+			OrganizationID: orgID, InviterID: inviterID,
+		})
+		if err != nil {
+			return err
+		}
+		orgName, inviter := names.Organization, names.Inviter
+		_, err = services.Notify(ctx, tx, services.NewNotification{
+			UserID:         member.UserID,
+			OrganizationID: &orgID,
+			Type:           models.NotificationInviteReceived,
+			Title:          fmt.Sprintf("You were added to %s", orgName),
+			Body:           fmt.Sprintf("%s added you to %s as %s.", inviter, orgName, req.Role),
+			Data:           map[string]interface{}{"organization_id": orgID, "role": req.Role, "invited_by": inviterID},
+		})
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, outbox.NewEvent{
+			OrganizationID: orgID,
+			Type:           outbox.EventMemberAdded,
+			SubjectID:      &member.UserID,
+			Data:           map[string]interface{}{"user_id": member.UserID, "role": req.Role, "invited_by": inviterID},
+		})
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "No user with this email address"})
+		return
+	case err == errAlreadyMember:
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of the organization"})
+		return
+	case databaseError(c, err):
+		return
+	case err != nil:
+		logger.Error("Failed to add member to organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+		return
+	}
+
+	// The member's cached session does not know the organization yet
+	if spm, ok := database.GetStatelessPoolManagerFromContext(c); ok {
+		if err := spm.InvalidateUserSession(ctx, member.UserID); err != nil {
+			logger.Error("Failed to invalidate session of user %s: %v", member.UserID, err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Member added",
+		"data":    member,
+	})
+}
+
+// organizationAdmin resolves the organization in the id path parameter and checks that the
+// caller is one of its owners or admins, writing the error response otherwise. action names
+// what is being attempted in the 403 message.
+func organizationAdmin(c *gin.Context, action string) (*database.StatelessTenantDB, uuid.UUID, string, bool) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return nil, uuid.Nil, "", false
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return nil, uuid.Nil, "", false
+	}
+
+	role, err := queries.New(tenantDB).GetMemberRole(c.Request.Context(), queries.GetMemberRoleParams{
+		UserID: tenantDB.GetUserID(), OrganizationID: orgID,
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return nil, uuid.Nil, "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role"})
+		return nil, uuid.Nil, "", false
+	}
+	if role != models.RoleOwner && role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": action + " requires the owner or admin role"})
+		return nil, uuid.Nil, "", false
+	}
+	return tenantDB, orgID, role, true
+}
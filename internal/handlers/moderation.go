@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/moderation"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ModerationHandler struct {
+	db        *sql.DB
+	moderator *moderation.Moderator
+	scanner   *moderation.Scanner
+	playback  config.Playback
+	config    config.Moderation
+}
+
+// NewModerationHandler creates a moderation handler. db must not carry a tenant context, since
+// viewers report anonymously. scanner is nil when automatic content scanning is off.
+func NewModerationHandler(db *sql.DB, moderator *moderation.Moderator, scanner *moderation.Scanner, playback config.Playback, cfg config.Moderation) *ModerationHandler {
+	return &ModerationHandler{db: db, moderator: moderator, scanner: scanner, playback: playback, config: cfg}
+}
+
+type reportVideoRequest struct {
+	Reason  string `json:"reason" binding:"required"`
+	Details string `json:"details" binding:"max=2000"`
+	Token   string `json:"token"`
+}
+
+// ReportVideo godoc
+// @Summary Report video
+// @Description Flags a video for review. Anyone who can play the video may report it: private videos need a playback token.
+// @Description Reason is copyright, sexual, violence, harassment, hateful, spam or other. A reporter's repeated reports of a
+// @Description video count once until reviewed; once enough reporters flag a video it stops playing until a moderator reviews it.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body reportVideoRequest true "Reason, and optionally details and token"
+// @Success 202 {object} SuccessResponse "Report received"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/reports [post]
+func (h *ModerationHandler) ReportVideo(c *gin.Context) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req reportVideoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if !moderation.IsReason(req.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason must be one of " + strings.Join(moderation.Reasons, ", ")})
+		return
+	}
+
+	// Private videos can only be reported by viewers who could play them, so reports do not
+	// reveal which video IDs exist
+	visibility, err := queries.New(h.db).GetVideoVisibility(c.Request.Context(), videoID)
+	if err == nil && visibility == models.VideoVisibilityPrivate &&
+		services.VerifyPlaybackToken(h.playback.SigningKey, videoID, req.Token) != nil {
+		err = sql.ErrNoRows
+	}
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+
+	restricted, err := h.moderator.Report(c.Request.Context(), videoID, req.Reason, strings.TrimSpace(req.Details), reporterKey(c))
+	if errors.Is(err, moderation.ErrVideoNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to file report of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to file report"})
+		return
+	}
+	if restricted {
+		logger.Info("Video %s unpublished after reaching %d reports", videoID, h.config.ReportThreshold)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Report received",
+	})
+}
+
+// reporterKey identifies the reporter by user ID when the request names one, and otherwise by
+// a hash of the client address, which is not stored in the clear
+func reporterKey(c *gin.Context) string {
+	if userID, err := uuid.Parse(c.GetHeader("X-User-ID")); err == nil {
+		return "user:" + userID.String()
+	}
+	sum := sha256.Sum256([]byte(c.ClientIP()))
+	return "ip:" + hex.EncodeToString(sum[:20])
+}
+
+// GetModerationQueue godoc
+// @Summary Get moderation queue
+// @Description Lists videos with open reports: those unpublished by the report threshold first, then the most reported.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param limit query int false "Maximum videos" default(50)
+// @Success 200 {object} SuccessResponse{data=object{videos=[]moderation.QueueItem}} "Queue retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/moderation/queue [get]
+func (h *ModerationHandler) GetModerationQueue(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	items, err := h.moderator.Queue(c.Request.Context(), limit)
+	if err != nil {
+		logger.Error("Failed to load moderation queue: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load moderation queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Moderation queue retrieved successfully",
+		"data":    gin.H{"videos": items},
+	})
+}
+
+// GetModerationCase godoc
+// @Summary Review video
+// @Description Returns a video with all its reports, takedowns and automatic content scans, newest first.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=moderation.Case} "Case retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /admin/v1/moderation/videos/{id} [get]
+func (h *ModerationHandler) GetModerationCase(c *gin.Context) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	moderationCase, err := h.moderator.Review(c.Request.Context(), videoID)
+	if errors.Is(err, moderation.ErrVideoNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to load moderation case of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load video"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Moderation case retrieved successfully",
+		"data":    moderationCase,
+	})
+}
+
+type dismissReportsRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// DismissReports godoc
+// @Summary Dismiss reports
+// @Description Closes a video's open reports without action. A video the report threshold unpublished plays again.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body dismissReportsRequest false "Optional note"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Reports dismissed"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /admin/v1/moderation/videos/{id}/dismiss [post]
+func (h *ModerationHandler) DismissReports(c *gin.Context) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req dismissReportsRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	video, err := h.moderator.Dismiss(c.Request.Context(), videoID, req.Note)
+	if errors.Is(err, moderation.ErrVideoNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to dismiss reports of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Reports dismissed",
+		"data":    video,
+	})
+}
+
+type takedownRequest struct {
+	Kind     string `json:"kind" binding:"required,oneof=dmca policy"`
+	Reason   string `json:"reason" binding:"required,max=1000"`
+	Claimant string `json:"claimant" binding:"max=500"`
+}
+
+// TakeDownVideo godoc
+// @Summary Take down video
+// @Description Stops a video from playing, for a DMCA claim (kind dmca, with the claimant) or a policy violation (kind policy),
+// @Description and closes its open reports as actioned. The organization may file a counter-notice against DMCA takedowns.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body takedownRequest true "Kind, reason, and the claimant of DMCA takedowns"
+// @Success 201 {object} SuccessResponse{data=models.Takedown} "Video taken down"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 409 {object} ErrorResponse "Video already taken down"
+// @Router /admin/v1/moderation/videos/{id}/takedown [post]
+func (h *ModerationHandler) TakeDownVideo(c *gin.Context) {
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req takedownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Kind == models.TakedownKindDMCA && strings.TrimSpace(req.Claimant) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "DMCA takedowns need the claimant"})
+		return
+	}
+
+	takedown, err := h.moderator.TakeDown(c.Request.Context(), videoID, req.Kind, req.Reason, strings.TrimSpace(req.Claimant))
+	switch {
+	case errors.Is(err, moderation.ErrVideoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	case errors.Is(err, moderation.ErrAlreadyTakenDown):
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is already taken down"})
+		return
+	case err != nil:
+		logger.Error("Failed to take down video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to take down video"})
+		return
+	}
+	logger.Info("Video %s taken down (%s)", videoID, req.Kind)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Video taken down",
+		"data":    takedown,
+	})
+}
+
+// ListTakedowns godoc
+// @Summary List takedowns
+// @Description Lists takedowns, newest first, optionally only those with a status: active, counter_noticed, restored or upheld.
+// @Description Counter-noticed takedowns show when the video may be restored in restore_after.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param status query string false "Status"
+// @Success 200 {object} SuccessResponse{data=object{takedowns=[]models.Takedown}} "Takedowns retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Router /admin/v1/moderation/takedowns [get]
+func (h *ModerationHandler) ListTakedowns(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.TakedownStatusActive, models.TakedownStatusCounterNoticed, models.TakedownStatusRestored,
+		models.TakedownStatusUpheld:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown takedown status"})
+		return
+	}
+
+	takedowns, err := h.moderator.Takedowns(c.Request.Context(), status)
+	if err != nil {
+		logger.Error("Failed to list takedowns: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list takedowns"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Takedowns retrieved successfully",
+		"data":    gin.H{"takedowns": takedowns},
+	})
+}
+
+// RestoreTakedown godoc
+// @Summary Restore taken down video
+// @Description Ends an active or counter-noticed takedown and lets the video play again.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param id path string true "Takedown ID"
+// @Success 200 {object} SuccessResponse{data=models.Takedown} "Video restored"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Takedown not found"
+// @Failure 409 {object} ErrorResponse "Takedown already resolved"
+// @Router /admin/v1/moderation/takedowns/{id}/restore [post]
+func (h *ModerationHandler) RestoreTakedown(c *gin.Context) {
+	h.resolveTakedown(c, h.moderator.Restore, "Video restored")
+}
+
+// UpholdTakedown godoc
+// @Summary Uphold takedown
+// @Description Keeps a counter-noticed video down for good, when the claimant has taken legal action.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param id path string true "Takedown ID"
+// @Success 200 {object} SuccessResponse{data=models.Takedown} "Takedown upheld"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Takedown not found"
+// @Failure 409 {object} ErrorResponse "Takedown is not counter-noticed"
+// @Router /admin/v1/moderation/takedowns/{id}/uphold [post]
+func (h *ModerationHandler) UpholdTakedown(c *gin.Context) {
+	h.resolveTakedown(c, h.moderator.Uphold, "Takedown upheld")
+}
+
+func (h *ModerationHandler) resolveTakedown(c *gin.Context, resolve func(ctx context.Context, id uuid.UUID) (*models.Takedown, error), message string) {
+	takedownID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid takedown ID"})
+		return
+	}
+
+	takedown, err := resolve(c.Request.Context(), takedownID)
+	switch {
+	case errors.Is(err, moderation.ErrTakedownNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Takedown not found"})
+		return
+	case errors.Is(err, moderation.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Takedown does not allow this change"})
+		return
+	case err != nil:
+		logger.Error("Failed to resolve takedown %s: %v", takedownID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve takedown"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": message,
+		"data":    takedown,
+	})
+}
+
+type counterNoticeRequest struct {
+	Statement string `json:"statement" binding:"required,min=20,max=5000"`
+	Contact   string `json:"contact" binding:"required,max=500"`
+}
+
+// FileCounterNotice godoc
+// @Summary File DMCA counter-notice
+// @Description Disputes the DMCA takedown of one of the organization's videos. The statement should include the
+// @Description declarations a counter-notice requires; contact is where the claimant may reach the uploader.
+// @Description The video may be restored once restore_after has passed, unless the claimant takes legal action.
+// @Description Requires the owner or admin role.
+// @Tags moderation
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body counterNoticeRequest true "Statement and contact"
+// @Success 200 {object} SuccessResponse{data=models.Takedown} "Counter-notice filed"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "No DMCA takedown in effect"
+// @Router /api/v1/videos/{id}/counter-notice [post]
+func (h *ModerationHandler) FileCounterNotice(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	if role := tenantDB.GetRole(); role != models.RoleOwner && role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Filing a counter-notice requires the owner or admin role"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req counterNoticeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	takedown, err := moderation.CounterNotice(c.Request.Context(), tenantDB, videoID, req.Statement, req.Contact,
+		h.config.CounterNoticeWait)
+	if errors.Is(err, moderation.ErrTakedownNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No DMCA takedown in effect on this video"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to file counter-notice for video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to file counter-notice"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Counter-notice filed",
+		"data":    takedown,
+	})
+}
+
+// ScanVideo godoc
+// @Summary Scan video
+// @Description Queues an automatic content scan of a video's current source, as done when it becomes ready. Categories
+// @Description scoring at or above the threshold file a report, which brings the video into the moderation queue.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 202 {object} SuccessResponse{data=jobs.Job} "Scan queued"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 409 {object} ErrorResponse "Content scanning is off"
+// @Router /admin/v1/moderation/videos/{id}/scan [post]
+func (h *ModerationHandler) ScanVideo(c *gin.Context) {
+	if h.scanner == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Automatic content scanning is not enabled"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	video, err := queries.New(h.db).GetVideoSource(c.Request.Context(), videoID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+	if video.SourceKey == nil || *video.SourceKey == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video has no source to scan"})
+		return
+	}
+
+	job, err := moderation.Enqueue(c.Request.Context(), h.db, videoID, video.OrganizationID)
+	if err != nil {
+		logger.Error("Failed to queue scan of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue scan"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Scan queued",
+		"data":    job,
+	})
+}
+
+// ListVideoScans godoc
+// @Summary List video scans
+// @Description Lists the automatic content scans of a video, newest first, with the highest score of each category and the
+// @Description categories that flagged the video for review.
+// @Tags moderation
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{scans=[]models.ModerationScan}} "Scans retrieved"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/v1/videos/{id}/scans [get]
+func (h *ModerationHandler) ListVideoScans(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	scans, err := moderation.Scans(c.Request.Context(), tenantDB, videoID)
+	if err != nil {
+		logger.Error("Failed to list scans of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scans"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Scans retrieved successfully",
+		"data":    gin.H{"scans": scans},
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonFlushEvery is how many rows are written between flushes of an NDJSON export
+const ndjsonFlushEvery = 100
+
+// wantsNDJSON reports whether the Accept header prefers NDJSON to JSON
+func wantsNDJSON(c *gin.Context) bool {
+	return c.NegotiateFormat(gin.MIMEJSON, ndjsonContentType) == ndjsonContentType
+}
+
+// streamNDJSON writes one JSON document per row without pagination, flushing regularly so
+// exports of any size stream in constant memory. Since the status is already sent, a failure
+// midway is reported as a final {"error": ...} line.
+func streamNDJSON(c *gin.Context, rows *sql.Rows, scan func(*sql.Rows) (interface{}, error)) {
+	defer rows.Close()
+
+	c.Header("Content-Type", ndjsonContentType)
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	count := 0
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			logger.Error("NDJSON export of %s failed after %d rows: %v", c.FullPath(), count, err)
+			enc.Encode(gin.H{"error": "Export interrupted"})
+			return
+		}
+		if err := enc.Encode(item); err != nil {
+			// The client went away
+			return
+		}
+
+		count++
+		if count%ndjsonFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		logger.Error("NDJSON export of %s failed after %d rows: %v", c.FullPath(), count, err)
+		enc.Encode(gin.H{"error": "Export interrupted"})
+	}
+}
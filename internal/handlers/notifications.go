@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListNotifications godoc
+// @Summary List notifications
+// @Description Lists the caller's notifications, newest first, with the number of unread ones
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size" default(20)
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {object} SuccessResponse{data=object{notifications=[]models.Notification,unread_count=int,pagination=response.Pagination}} "Notifications retrieved"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Router /api/v1/notifications [get]
+func ListNotifications(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	// RLS limits the rows to the caller's own; filtering by user as well keeps the indexes usable
+	filter := ""
+	if unread, _ := strconv.ParseBool(c.Query("unread")); unread {
+		filter = ` AND read_at IS NULL`
+	}
+	userID := tenantDB.GetUserID()
+
+	ctx := c.Request.Context()
+	rows, err := tenantDB.QueryContext(ctx, `SELECT `+services.NotificationColumns+` FROM notifications WHERE user_id = $1`+filter+
+		` ORDER BY created_at DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query notifications"})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := services.ScanNotification(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan notification"})
+			return
+		}
+		notifications = append(notifications, *n)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing notification results"})
+		return
+	}
+
+	var total, unread int
+	if err := tenantDB.QueryRowContext(ctx, `
+		SELECT (SELECT COUNT(*) FROM notifications WHERE user_id = $1`+filter+`),
+			(SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL)
+	`, userID).Scan(&total, &unread); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Notifications retrieved successfully",
+		"data": gin.H{
+			"notifications": notifications,
+			"unread_count":  unread,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark notification read
+// @Description Marks one of the caller's notifications as read
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} SuccessResponse{data=models.Notification} "Notification marked read"
+// @Failure 400 {object} ErrorResponse "Invalid notification ID"
+// @Failure 404 {object} ErrorResponse "Notification not found"
+// @Router /api/v1/notifications/{id}/read [post]
+func MarkNotificationRead(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	n, err := services.ScanNotification(tenantDB.QueryRowContext(c.Request.Context(), `
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+services.NotificationColumns,
+		id, tenantDB.GetUserID()))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Notification marked read",
+		"data":    n,
+	})
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all notifications read
+// @Description Marks every unread notification of the caller as read
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{updated=int}} "Notifications marked read, with their number"
+// @Router /api/v1/notifications/read [post]
+func MarkAllNotificationsRead(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	result, err := tenantDB.ExecContext(c.Request.Context(),
+		`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, tenantDB.GetUserID())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+	updated, _ := result.RowsAffected()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Notifications marked read",
+		"data":    gin.H{"updated": updated},
+	})
+}
+
+// GetNotificationPreferences godoc
+// @Summary Get notification preferences
+// @Description Lists, for every notification type, whether the caller receives it by email and push. In-app notifications are always recorded.
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{preferences=[]models.NotificationPreference}} "Preferences retrieved"
+// @Router /api/v1/notifications/preferences [get]
+// @Router /api/v1/me/notification-preferences [get]
+func GetNotificationPreferences(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	prefs, err := services.NotificationPreferences(c.Request.Context(), tenantDB, tenantDB.GetUserID())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Notification preferences retrieved",
+		"data":    gin.H{"preferences": prefs},
+	})
+}
+
+type notificationPreferencesRequest struct {
+	Preferences []models.NotificationPreference `json:"preferences" binding:"required,dive"`
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary Update notification preferences
+// @Description Sets the email and push channels of the listed notification types; other types keep their settings
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body notificationPreferencesRequest true "Preferences by notification type"
+// @Success 200 {object} SuccessResponse{data=object{preferences=[]models.NotificationPreference}} "Preferences updated"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Router /api/v1/notifications/preferences [put]
+// @Router /api/v1/me/notification-preferences [put]
+func UpdateNotificationPreferences(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req notificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	for _, pref := range req.Preferences {
+		if !services.IsNotificationType(pref.Type) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type: " + pref.Type})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	userID := tenantDB.GetUserID()
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		for _, pref := range req.Preferences {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO notification_preferences (user_id, type, email, push)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (user_id, type) DO UPDATE SET email = EXCLUDED.email, push = EXCLUDED.push
+			`, userID, pref.Type, pref.Email, pref.Push); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	prefs, err := services.NotificationPreferences(ctx, tenantDB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Notification preferences updated",
+		"data":    gin.H{"preferences": prefs},
+	})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"openvdo/internal/storage"
+)
+
+// byteRange is an inclusive range of bytes of an object
+type byteRange struct {
+	start, end int64
+}
+
+// objectReader reads a stored object through ranged reads, as the io.ReadSeeker
+// http.ServeContent answers range requests from. Each range of the request becomes one read of
+// exactly its bytes, so seeking in a large video fetches a few megabytes from the bucket rather
+// than the whole object; reads elsewhere, such as of a whole object served without a range,
+// run to the end of the object. The body of a read is consumed as the client takes the
+// response, so a slow client slows the transfer from the bucket instead of filling memory.
+type objectReader struct {
+	ctx    context.Context
+	rr     storage.RangeReader
+	key    string
+	size   int64
+	ranges []byteRange
+
+	offset int64
+	body   io.ReadCloser
+	// end is the offset the current body stops at
+	end int64
+}
+
+// newObjectReader looks up the size of the object and plans its reads from the Range header of
+// the request, which may be empty
+func newObjectReader(ctx context.Context, rr storage.RangeReader, key, rangeHeader string) (*objectReader, error) {
+	info, err := rr.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &objectReader{
+		ctx: ctx, rr: rr, key: key, size: info.Size,
+		ranges: parseByteRanges(rangeHeader, info.Size),
+	}, nil
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		length := r.readLength(r.offset)
+		body, err := r.rr.GetRange(r.ctx, r.key, r.offset, length)
+		if err != nil {
+			return 0, err
+		}
+		r.body, r.end = body, r.size
+		if length >= 0 {
+			r.end = r.offset + length
+		}
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if errors.Is(err, io.EOF) {
+		r.body.Close()
+		r.body = nil
+		if r.offset < r.end {
+			return n, io.ErrUnexpectedEOF
+		}
+		// The read of one range ended; reading on opens the next
+		if r.offset < r.size {
+			err = nil
+		}
+	}
+	return n, err
+}
+
+// readLength is how much to read from offset: to the end of the requested range it starts, or
+// to the end of the object when it starts none
+func (r *objectReader) readLength(offset int64) int64 {
+	for _, br := range r.ranges {
+		if br.start <= offset && offset <= br.end {
+			return br.end - offset + 1
+		}
+	}
+	return -1
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek to a negative offset")
+	}
+	if offset != r.offset && r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *objectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+// parseByteRanges returns the ranges of a Range header within an object of size bytes: a-b,
+// the open-ended a- and the suffix -n. It returns nil for a header it cannot parse, leaving
+// http.ServeContent to answer it.
+func parseByteRanges(header string, size int64) []byteRange {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return nil
+	}
+	var ranges []byteRange
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		var br byteRange
+		if first == "" {
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n <= 0 {
+				return nil
+			}
+			br = byteRange{start: max(size-n, 0), end: size - 1}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil
+			}
+			br = byteRange{start: start, end: size - 1}
+			if last != "" {
+				end, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil
+				}
+				br.end = min(end, size-1)
+			}
+		}
+		// Ranges past the end are not satisfiable and read nothing
+		if br.start < size {
+			ranges = append(ranges, br)
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"openvdo/internal/storage"
+)
+
+func TestParseByteRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []byteRange
+	}{
+		{"empty", "", nil},
+		{"other unit", "items=0-10", nil},
+		{"closed", "bytes=0-99", []byteRange{{0, 99}}},
+		{"single byte", "bytes=5-5", []byteRange{{5, 5}}},
+		{"open ended", "bytes=900-", []byteRange{{900, 999}}},
+		{"suffix", "bytes=-100", []byteRange{{900, 999}}},
+		{"suffix longer than object", "bytes=-5000", []byteRange{{0, 999}}},
+		{"end beyond size", "bytes=950-2000", []byteRange{{950, 999}}},
+		{"start beyond size", "bytes=1000-1100", nil},
+		{"open ended beyond size", "bytes=1000-", nil},
+		{"multi range", "bytes=0-9, 20-29,-10", []byteRange{{0, 9}, {20, 29}, {990, 999}}},
+		{"overlapping", "bytes=0-499,400-599", []byteRange{{0, 499}, {400, 599}}},
+		{"unsatisfiable part dropped", "bytes=0-9,5000-6000", []byteRange{{0, 9}}},
+		{"empty parts skipped", "bytes=0-9,,", []byteRange{{0, 9}}},
+		{"spaces", "bytes= 10 - 19 ", []byteRange{{10, 19}}},
+		{"no dash", "bytes=10", nil},
+		{"reversed", "bytes=20-10", nil},
+		{"not a number", "bytes=a-10", nil},
+		{"negative start", "bytes=--10", nil},
+		{"zero suffix", "bytes=-0", nil},
+		{"bare dash", "bytes=-", nil},
+		{"one malformed part", "bytes=0-9,x-", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseByteRanges(tt.header, 1000)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseByteRanges(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+// rangeCall records a GetRange a test expects
+type rangeCall struct {
+	offset, length int64
+}
+
+// fakeRangeReader serves one object from memory and records the ranged reads made of it
+type fakeRangeReader struct {
+	data  []byte
+	calls []rangeCall
+	// short cuts every body this many bytes before the end of its range
+	short int
+	err   error
+}
+
+func (f *fakeRangeReader) Stat(_ context.Context, key string) (storage.ObjectInfo, error) {
+	if key != "video.mp4" {
+		return storage.ObjectInfo{}, storage.ErrNotFound
+	}
+	return storage.ObjectInfo{Size: int64(len(f.data))}, nil
+}
+
+func (f *fakeRangeReader) GetRange(_ context.Context, _ string, offset, length int64) (io.ReadCloser, error) {
+	f.calls = append(f.calls, rangeCall{offset, length})
+	if f.err != nil {
+		return nil, f.err
+	}
+	end := int64(len(f.data))
+	if length >= 0 {
+		end = offset + length
+	}
+	end -= int64(f.short)
+	return io.NopCloser(bytes.NewReader(f.data[offset:end])), nil
+}
+
+func testObject() []byte {
+	return []byte(strings.Repeat("0123456789", 100))
+}
+
+func TestObjectReaderReadsWholeObject(t *testing.T) {
+	rr := &fakeRangeReader{data: testObject()}
+	r, err := newObjectReader(context.Background(), rr, "video.mp4", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, rr.data) {
+		t.Errorf("read %d bytes, want the %d of the object", len(got), len(rr.data))
+	}
+	if want := []rangeCall{{0, -1}}; !reflect.DeepEqual(rr.calls, want) {
+		t.Errorf("GetRange calls = %v, want %v", rr.calls, want)
+	}
+}
+
+func TestObjectReaderReadsEachRequestedRange(t *testing.T) {
+	rr := &fakeRangeReader{data: testObject()}
+	r, err := newObjectReader(context.Background(), rr, "video.mp4", "bytes=10-19,-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// http.ServeContent seeks to each range and reads exactly its length
+	for _, tt := range []struct {
+		start, length int64
+	}{{10, 10}, {995, 5}} {
+		if _, err := r.Seek(tt.start, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, tt.length)
+		if _, err := io.ReadFull(r, got); err != nil {
+			t.Fatalf("reading %d bytes at %d: %v", tt.length, tt.start, err)
+		}
+		if want := rr.data[tt.start : tt.start+tt.length]; !bytes.Equal(got, want) {
+			t.Errorf("read %q at %d, want %q", got, tt.start, want)
+		}
+	}
+	if want := []rangeCall{{10, 10}, {995, 5}}; !reflect.DeepEqual(rr.calls, want) {
+		t.Errorf("GetRange calls = %v, want %v", rr.calls, want)
+	}
+}
+
+func TestObjectReaderReadsOnPastARange(t *testing.T) {
+	rr := &fakeRangeReader{data: testObject()}
+	r, err := newObjectReader(context.Background(), rr, "video.mp4", "bytes=0-9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	got := make([]byte, 15)
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatal(err)
+	}
+	if want := rr.data[:15]; !bytes.Equal(got, want) {
+		t.Errorf("read %q, want %q", got, want)
+	}
+	if want := []rangeCall{{0, 10}, {10, -1}}; !reflect.DeepEqual(rr.calls, want) {
+		t.Errorf("GetRange calls = %v, want %v", rr.calls, want)
+	}
+}
+
+func TestObjectReaderSeek(t *testing.T) {
+	rr := &fakeRangeReader{data: testObject()}
+	r, err := newObjectReader(context.Background(), rr, "video.mp4", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// http.ServeContent finds the size by seeking to the end and back
+	if n, err := r.Seek(0, io.SeekEnd); err != nil || n != 1000 {
+		t.Fatalf("Seek(0, SeekEnd) = %d, %v, want 1000", n, err)
+	}
+	if n, err := r.Seek(0, io.SeekStart); err != nil || n != 0 {
+		t.Fatalf("Seek(0, SeekStart) = %d, %v, want 0", n, err)
+	}
+	if len(rr.calls) != 0 {
+		t.Errorf("seeking read the object: %v", rr.calls)
+	}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	// Seeking to where the reader already is keeps the open body
+	if _, err := r.Seek(0, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "4567" {
+		t.Errorf("read %q after seeking in place, want %q", buf, "4567")
+	}
+	// Seeking elsewhere opens a new read there
+	if n, err := r.Seek(-8, io.SeekCurrent); err != nil || n != 0 {
+		t.Fatalf("Seek(-8, SeekCurrent) = %d, %v, want 0", n, err)
+	}
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := []rangeCall{{0, -1}, {0, -1}}; !reflect.DeepEqual(rr.calls, want) {
+		t.Errorf("GetRange calls = %v, want %v", rr.calls, want)
+	}
+
+	if _, err := r.Seek(-1, io.SeekStart); err == nil {
+		t.Error("seeking to a negative offset succeeded")
+	}
+	if _, err := r.Seek(5, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.Read(buf); n != 0 || err != io.EOF {
+		t.Errorf("Read past the end = %d, %v, want 0, EOF", n, err)
+	}
+}
+
+func TestObjectReaderShortBody(t *testing.T) {
+	rr := &fakeRangeReader{data: testObject(), short: 3}
+	r, err := newObjectReader(context.Background(), rr, "video.mp4", "bytes=0-9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	_, err = io.ReadFull(r, make([]byte, 10))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading a truncated range = %v, want ErrUnexpectedEOF", err)
+	}
+}
+
+func TestObjectReaderErrors(t *testing.T) {
+	rr := &fakeRangeReader{data: testObject()}
+	if _, err := newObjectReader(context.Background(), rr, "missing.mp4", ""); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("newObjectReader of a missing object = %v, want ErrNotFound", err)
+	}
+
+	rr.err = storage.ErrRangeUnsupported
+	r, err := newObjectReader(context.Background(), rr, "video.mp4", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Read(make([]byte, 4)); !errors.Is(err, storage.ErrRangeUnsupported) {
+		t.Errorf("Read with a failing backend = %v, want ErrRangeUnsupported", err)
+	}
+}
+
+func BenchmarkParseByteRanges(b *testing.B) {
+	for _, header := range []string{"bytes=0-", "bytes=1048576-2097151", "bytes=0-99,1000-1999,-500"} {
+		b.Run(header, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				parseByteRanges(header, 1<<30)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OrganizationTeardownHandler struct {
+	teardowns *services.OrganizationTeardowns
+}
+
+func NewOrganizationTeardownHandler(teardowns *services.OrganizationTeardowns) *OrganizationTeardownHandler {
+	return &OrganizationTeardownHandler{teardowns: teardowns}
+}
+
+// deletionConfirmation is the token DeleteOrganization must be called with
+type deletionConfirmation struct {
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type deleteOrganizationRequest struct {
+	ConfirmationToken string `json:"confirmation_token" binding:"required"`
+}
+
+// organizationOwner resolves the organization in the id path parameter and checks that the
+// caller owns it, writing the error response otherwise
+func organizationOwner(c *gin.Context, action string) (uuid.UUID, uuid.UUID, bool) {
+	tenantDB, orgID, role, ok := organizationAdmin(c, action)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	if role != models.RoleOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": action + " requires the owner role"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return orgID, tenantDB.GetUserID(), true
+}
+
+// RequestOrganizationDeletion godoc
+// @Summary Request the deletion of an organization
+// @Description Issues the token that confirms the deletion of an organization through DELETE /api/v1/organizations/{id}. The token is valid for 15 minutes, and asking again replaces it. Requires the owner role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 201 {object} SuccessResponse{data=deletionConfirmation} "Confirmation token issued"
+// @Failure 400 {object} ErrorResponse "Invalid organization ID"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 409 {object} ErrorResponse "Organization already deleted"
+// @Router /api/v1/organizations/{id}/deletion [post]
+func (h *OrganizationTeardownHandler) RequestOrganizationDeletion(c *gin.Context) {
+	orgID, userID, ok := organizationOwner(c, "Deleting an organization")
+	if !ok {
+		return
+	}
+
+	token, expiresAt, err := h.teardowns.RequestDeletion(c.Request.Context(), orgID, userID)
+	if errors.Is(err, services.ErrOrganizationDeleted) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Organization is already being deleted"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to request deletion of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request deletion"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Confirm the deletion with this token",
+		"data":    deletionConfirmation{ConfirmationToken: token, ExpiresAt: expiresAt},
+	})
+}
+
+// DeleteOrganization godoc
+// @Summary Delete organization
+// @Description Deletes an organization with the confirmation token from POST /api/v1/organizations/{id}/deletion. Requires the owner role.
+// @Description Access ends at once: the organization is marked deleted, its members and API keys are revoked and its videos stop playing. A background job then deletes its stored files, videos and playback events, which GET /api/v1/organizations/{id}/teardown follows. Deletion cannot be undone.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body deleteOrganizationRequest true "Confirmation token"
+// @Success 202 {object} SuccessResponse{data=models.OrganizationTeardown} "Deletion started"
+// @Failure 400 {object} ErrorResponse "Invalid request or confirmation token"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 409 {object} ErrorResponse "Organization already deleted"
+// @Router /api/v1/organizations/{id} [delete]
+func (h *OrganizationTeardownHandler) DeleteOrganization(c *gin.Context) {
+	orgID, userID, ok := organizationOwner(c, "Deleting an organization")
+	if !ok {
+		return
+	}
+	var req deleteOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	teardown, members, err := h.teardowns.ConfirmDeletion(ctx, orgID, userID, req.ConfirmationToken)
+	switch {
+	case errors.Is(err, services.ErrInvalidConfirmation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired confirmation token"})
+		return
+	case errors.Is(err, services.ErrOrganizationDeleted):
+		c.JSON(http.StatusConflict, gin.H{"error": "Organization is already being deleted"})
+		return
+	case err != nil:
+		logger.Error("Failed to delete organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete organization"})
+		return
+	}
+
+	// Cached sessions still list the organization among the members' roles
+	if spm, ok := database.GetStatelessPoolManagerFromContext(c); ok {
+		for _, member := range members {
+			if err := spm.InvalidateUserSession(ctx, member); err != nil {
+				logger.Error("Failed to invalidate session of user %s: %v", member, err)
+			}
+		}
+	}
+	logger.Info("Organization %s deleted by user %s; teardown job %s queued", orgID, userID, teardown.JobID)
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Organization deleted; its data is being removed",
+		"data":    teardown,
+	})
+}
+
+// GetOrganizationTeardown godoc
+// @Summary Get the teardown of a deleted organization
+// @Description Returns the progress of removing a deleted organization's data: the step under way (storage, videos or analytics) and the objects, bytes and videos deleted so far. Only the user who requested the deletion sees it, including after losing their membership.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=models.OrganizationTeardown} "Teardown retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid organization ID"
+// @Failure 404 {object} ErrorResponse "No deletion requested by the user"
+// @Router /api/v1/organizations/{id}/teardown [get]
+func (h *OrganizationTeardownHandler) GetOrganizationTeardown(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	teardown, err := h.teardowns.Get(c.Request.Context(), orgID, tenantDB.GetUserID())
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No deletion of this organization was requested"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to get teardown of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get teardown"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Teardown retrieved successfully",
+		"data":    teardown,
+	})
+}
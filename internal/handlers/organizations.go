@@ -5,9 +5,10 @@ import (
 	"strconv"
 
 	"openvdo/internal/database"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // GetOrganizations retrieves organizations for the authenticated user
@@ -23,53 +24,17 @@ func GetOrganizations(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
 
-	query := `
-		SELECT id, name, description, created_at, updated_at
-		FROM organizations
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-
-	rows, err := tenantDB.QueryContext(c.Request.Context(), query, limit, offset)
+	q := queries.New(tenantDB)
+	organizations, err := services.OrganizationModels(q.ListOrganizationsPage(c.Request.Context(),
+		queries.ListOrganizationsPageParams{Limit: int32(limit), Offset: int32(offset)}))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query organizations"})
 		return
 	}
-	defer rows.Close()
-
-	var organizations []gin.H
-	for rows.Next() {
-		var org struct {
-			ID          uuid.UUID `json:"id"`
-			Name        string    `json:"name"`
-			Description string    `json:"description"`
-			CreatedAt   string    `json:"created_at"`
-			UpdatedAt   string    `json:"updated_at"`
-		}
-
-		if err := rows.Scan(&org.ID, &org.Name, &org.Description, &org.CreatedAt, &org.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan organization"})
-			return
-		}
-
-		organizations = append(organizations, gin.H{
-			"id":          org.ID,
-			"name":        org.Name,
-			"description": org.Description,
-			"created_at":  org.CreatedAt,
-			"updated_at":  org.UpdatedAt,
-		})
-	}
-
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing organization results"})
-		return
-	}
 
 	// Get total count for pagination
-	var total int
-	countQuery := "SELECT COUNT(*) FROM organizations"
-	if err := tenantDB.QueryRowContext(c.Request.Context(), countQuery).Scan(&total); err != nil {
+	total, err := q.CountOrganizations(c.Request.Context())
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
 		return
 	}
@@ -106,13 +71,12 @@ func CreateOrganization(c *gin.Context) {
 		return
 	}
 
-	// Simple query for now
-	query := `
-		INSERT INTO organizations (name, description)
-		VALUES ($1, $2)
-	`
-
-	_, err := tenantDB.ExecContext(c.Request.Context(), query, req.Name, req.Description)
+	err := queries.New(tenantDB).CreateOrganization(c.Request.Context(), queries.CreateOrganizationParams{
+		Name: req.Name, Description: &req.Description,
+	})
+	if databaseError(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
 		return
@@ -0,0 +1,455 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var (
+	errVideoUnavailable = errors.New("video unavailable")
+	errVideoNotReady    = errors.New("video not ready")
+	errNotEntitled      = errors.New("not entitled")
+)
+
+// sessionPlayback is the token and URLs a session plays with, renewed by every heartbeat
+func (h *EmbedHandler) sessionPlayback(c *gin.Context, session *models.PlaybackSession) gin.H {
+	expiresAt := time.Now().Add(h.config.SessionTokenTTL).UTC()
+	token := services.SignPlaybackToken(h.config.SigningKey, session.VideoID, expiresAt)
+	base := h.baseURL(c)
+	return gin.H{
+		"session":      session,
+		"token":        token,
+		"expires_at":   expiresAt,
+		"manifest_url": base + "/embed/" + session.VideoID.String() + "/media" + tokenQuery(token),
+		"embed_url":    base + "/embed/" + session.VideoID.String() + tokenQuery(token),
+	}
+}
+
+// playbackSeries is the series of an episode as players get it
+type playbackSeries struct {
+	ID         uuid.UUID `json:"id"`
+	Title      string    `json:"title"`
+	ArtworkURL string    `json:"artwork_url,omitempty"`
+}
+
+// nextEpisode is the episode to offer when one ends, with the URL to start its session
+type nextEpisode struct {
+	models.Episode
+	PlaybackSessionURL string `json:"playback_session_url"`
+}
+
+// seriesPlayback returns the series a video is an episode of, its episode and the next one
+// that can be played, nil when there is none. The series is nil for videos in no series.
+func (h *EmbedHandler) seriesPlayback(c *gin.Context, q database.Querier, videoID uuid.UUID) (*playbackSeries, *models.Episode, *nextEpisode, error) {
+	ctx := c.Request.Context()
+	series, episode, err := services.VideoEpisode(ctx, q, videoID)
+	if err != nil || series == nil {
+		return nil, nil, nil, err
+	}
+	next, err := services.NextEpisode(ctx, q, videoID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	base := h.baseURL(c)
+	ps := &playbackSeries{ID: series.ID, Title: series.Title}
+	if series.Artwork != nil {
+		ps.ArtworkURL = imageURL(base+"/images/series/"+series.ID.String()+"/artwork", series.Artwork, "")
+	}
+	var ne *nextEpisode
+	if next != nil {
+		ne = &nextEpisode{Episode: *next, PlaybackSessionURL: base + "/api/v1/videos/" + next.VideoID.String() + "/playback-sessions"}
+	}
+	return ps, episode, ne, nil
+}
+
+// CreatePlaybackSession godoc
+// @Summary Start playback session
+// @Description Checks that the caller may play the video, which for videos that require an entitlement means an unexpired grant of
+// @Description the video or its project unless the caller is an owner, admin or developer, and starts a playback session, returning a short-lived playback token
+// @Description with the manifest and embed URLs. The player keeps the session active by sending a heartbeat every
+// @Description heartbeat_interval_seconds, each of which returns a fresh token; without heartbeats the session expires.
+// @Description Active sessions count against PLAYBACK_MAX_CONCURRENT_STREAMS of the caller in the organization.
+// @Description For episodes of a series the response also names the series, the episode and the next episode that can be played,
+// @Description null after the last, for autoplay and "up next" prompts. The player settings are the caller's playback preferences: whether to autoplay,
+// @Description the quality to start at and whether captions are on.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 201 {object} SuccessResponse{data=object{session=models.PlaybackSession,token=string,expires_at=string,manifest_url=string,embed_url=string,heartbeat_url=string,heartbeat_interval_seconds=int,player=models.PlaybackPreferences,ads=playbackAds,series=playbackSeries,episode=models.Episode,next_episode=nextEpisode}} "Session with token, manifest URL, heartbeat URL, player settings, ads and the next episode"
+// @Failure 403 {object} ErrorResponse "Video is unavailable or needs an entitlement"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 409 {object} ErrorResponse "Video is not ready"
+// @Failure 429 {object} ErrorResponse "Concurrent stream limit reached"
+// @Failure 503 {object} ErrorResponse "Playback signing not configured"
+// @Router /api/v1/videos/{id}/playback-sessions [post]
+func (h *EmbedHandler) CreatePlaybackSession(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	if h.config.SigningKey == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Playback sessions are not configured (PLAYBACK_SIGNING_KEY)"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var video *models.Video
+	var session *models.PlaybackSession
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		video, err = services.VideoModel(queries.New(tx).GetVideo(ctx, videoID))
+		if err != nil {
+			return err
+		}
+		if video.ModerationStatus != models.ModerationActive || !reviewPlayable(video, tenantDB.GetRole()) {
+			return errVideoUnavailable
+		}
+		if !models.VideoPlayable(video.Status) || video.SourceKey == "" {
+			return errVideoNotReady
+		}
+		entitled, err := services.Entitled(ctx, tx, tenantDB.GetUserID(), video)
+		if err != nil {
+			return err
+		}
+		if !entitled {
+			return errNotEntitled
+		}
+		session, err = services.StartPlaybackSession(ctx, tx, services.NewPlaybackSession{
+			Video:      video,
+			UserID:     tenantDB.GetUserID(),
+			ClientIP:   c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Timeout:    h.config.SessionTimeout,
+			MaxStreams: h.config.MaxConcurrentStreams,
+		})
+		return err
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	case err == errVideoUnavailable:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Video is unavailable"})
+		return
+	case err == errVideoNotReady:
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is not ready for playback"})
+		return
+	case err == errNotEntitled:
+		c.JSON(http.StatusForbidden, gin.H{"error": "An entitlement is required to play this video"})
+		return
+	case errors.Is(err, services.ErrConcurrentStreamLimit):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": fmt.Sprintf("Concurrent stream limit of %d reached; end another playback session first", h.config.MaxConcurrentStreams),
+		})
+		return
+	case err != nil:
+		logger.Error("Failed to start playback session of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start playback session"})
+		return
+	}
+
+	ads, err := loadPlaybackAds(ctx, h.db, video.ID, video.OrganizationID, video.AdBreaks)
+	if err != nil {
+		logger.Error("Failed to load ads of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start playback session"})
+		return
+	}
+
+	series, episode, next, err := h.seriesPlayback(c, tenantDB, video.ID)
+	if err != nil {
+		logger.Error("Failed to load series of video %s: %v", video.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start playback session"})
+		return
+	}
+
+	player, err := services.UserPlaybackPreferences(ctx, tenantDB, tenantDB.GetUserID())
+	if err != nil {
+		logger.Error("Failed to load playback preferences of user %s: %v", tenantDB.GetUserID(), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start playback session"})
+		return
+	}
+
+	data := h.sessionPlayback(c, session)
+	data["player"] = player
+	data["heartbeat_url"] = h.baseURL(c) + "/api/v1/playback-sessions/" + session.ID.String() + "/heartbeat"
+	data["heartbeat_interval_seconds"] = int(h.config.HeartbeatInterval.Seconds())
+	data["ads"] = ads
+	if series != nil {
+		data["series"] = series
+		data["episode"] = episode
+		data["next_episode"] = next
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Playback session started",
+		"data":    data,
+	})
+}
+
+// PlaybackSessionHeartbeat godoc
+// @Summary Playback session heartbeat
+// @Description Keeps the caller's playback session active and records the playback position. The response carries a fresh
+// @Description playback token and manifest URL. Sessions that were ended or missed their heartbeats answer 410; start a new one.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Playback session ID"
+// @Param request body sessionPositionRequest false "Playback position"
+// @Success 200 {object} SuccessResponse{data=object{session=models.PlaybackSession,token=string,expires_at=string,manifest_url=string,embed_url=string}} "Session with a renewed token"
+// @Failure 403 {object} ErrorResponse "Video no longer available, e.g. after an entitlement expired; the session is ended"
+// @Failure 404 {object} ErrorResponse "Playback session not found"
+// @Failure 410 {object} ErrorResponse "Playback session ended"
+// @Router /api/v1/playback-sessions/{id}/heartbeat [post]
+func (h *EmbedHandler) PlaybackSessionHeartbeat(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid playback session ID"})
+		return
+	}
+	position, ok := bindSessionPosition(c)
+	if !ok {
+		return
+	}
+
+	// Sessions end when the video is taken down, is rejected or the viewer's rental runs out
+	// mid-playback
+	ctx := c.Request.Context()
+	userID := tenantDB.GetUserID()
+	video, err := services.VideoModel(queries.New(tenantDB).GetSessionVideo(ctx, queries.GetSessionVideoParams{
+		SessionID: sessionID, UserID: userID,
+	}))
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+	if err == nil {
+		entitled, err := services.Entitled(ctx, tenantDB, userID, video)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check entitlement"})
+			return
+		}
+		if !entitled || video.ModerationStatus != models.ModerationActive || !reviewPlayable(video, tenantDB.GetRole()) {
+			if _, err := services.EndPlaybackSession(ctx, tenantDB, sessionID, userID, position); err != nil &&
+				!errors.Is(err, services.ErrPlaybackSessionEnded) {
+				logger.Error("Failed to end playback session %s: %v", sessionID, err)
+			}
+			c.JSON(http.StatusForbidden, gin.H{"error": "Video is no longer available to play"})
+			return
+		}
+	}
+
+	session, err := services.HeartbeatPlaybackSession(ctx, tenantDB, sessionID, userID, position, h.config.SessionTimeout)
+	if !sessionFound(c, sessionID, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Heartbeat recorded",
+		"data":    h.sessionPlayback(c, session),
+	})
+}
+
+// EndPlaybackSession godoc
+// @Summary End playback session
+// @Description Ends the caller's playback session, freeing its stream, and records the final position when given
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Playback session ID"
+// @Param request body sessionPositionRequest false "Final playback position"
+// @Success 200 {object} SuccessResponse{data=models.PlaybackSession} "Session ended"
+// @Failure 404 {object} ErrorResponse "Playback session not found"
+// @Failure 410 {object} ErrorResponse "Playback session already ended"
+// @Router /api/v1/playback-sessions/{id} [delete]
+func (h *EmbedHandler) EndPlaybackSession(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid playback session ID"})
+		return
+	}
+	position, ok := bindSessionPosition(c)
+	if !ok {
+		return
+	}
+
+	session, err := services.EndPlaybackSession(c.Request.Context(), tenantDB, sessionID, tenantDB.GetUserID(), position)
+	if !sessionFound(c, sessionID, err) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Playback session ended",
+		"data":    session,
+	})
+}
+
+// ListPlaybackSessions godoc
+// @Summary List playback sessions
+// @Description Lists the playback sessions of the organization's videos, newest first, with their position and watch time
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param video_id query string false "Only sessions of this video"
+// @Param user_id query string false "Only sessions of this viewer"
+// @Param active query bool false "Only active sessions"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} SuccessResponse{data=object{sessions=[]models.PlaybackSession,pagination=response.Pagination}} "Sessions retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Router /api/v1/playback-sessions [get]
+func ListPlaybackSessions(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	// RLS limits the rows to the caller's organizations
+	filter := ` WHERE true`
+	var args []interface{}
+	for _, param := range []string{"video_id", "user_id"} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param})
+			return
+		}
+		args = append(args, id)
+		filter += fmt.Sprintf(` AND %s = $%d`, param, len(args))
+	}
+	if active, _ := strconv.ParseBool(c.Query("active")); active {
+		filter += ` AND ended_at IS NULL AND expires_at > NOW()`
+	}
+
+	ctx := c.Request.Context()
+	rows, err := tenantDB.QueryContext(ctx, `SELECT `+services.PlaybackSessionColumns+` FROM playback_sessions`+filter+
+		fmt.Sprintf(` ORDER BY started_at DESC LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2), append(args, limit, offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query playback sessions"})
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.PlaybackSession{}
+	for rows.Next() {
+		s, err := services.ScanPlaybackSession(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan playback session"})
+			return
+		}
+		sessions = append(sessions, *s)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing playback session results"})
+		return
+	}
+
+	var total int
+	if err := tenantDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM playback_sessions`+filter, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Playback sessions retrieved successfully",
+		"data": gin.H{
+			"sessions": sessions,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+type sessionPositionRequest struct {
+	PositionSeconds *float64 `json:"position_seconds" binding:"omitempty,min=0"`
+}
+
+// bindSessionPosition reads the optional position of a heartbeat or end request
+func bindSessionPosition(c *gin.Context) (*float64, bool) {
+	var req sessionPositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return nil, false
+	}
+	return req.PositionSeconds, true
+}
+
+// sessionFound answers for the errors of heartbeats and ends, and reports whether the
+// session was updated
+func sessionFound(c *gin.Context, sessionID uuid.UUID, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Playback session not found"})
+	case errors.Is(err, services.ErrPlaybackSessionEnded):
+		c.JSON(http.StatusGone, gin.H{"error": "Playback session ended; start a new one"})
+	default:
+		logger.Error("Failed to update playback session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update playback session"})
+	}
+	return false
+}
+
+// reviewPlayable tells whether a member with role may play a video in its review status.
+// Videos waiting for approval or rejected play for the members who manage content, so they can
+// be reviewed, but not for viewers.
+func reviewPlayable(video *models.Video, role string) bool {
+	return video.ReviewStatus == models.ReviewApproved || role != models.RoleViewer
+}
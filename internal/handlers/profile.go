@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProfile godoc
+// @Summary Get profile
+// @Description Returns the caller's profile: name, avatar, locale, timezone and the playback preferences players start videos with
+// @Tags users
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=models.UserProfile} "Profile retrieved"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Router /api/v1/me [get]
+func GetProfile(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	profile, err := services.UserProfileModel(queries.New(tenantDB).GetUserProfile(c.Request.Context(), tenantDB.GetUserID()))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get profile"})
+		return
+	}
+	profileAvatarURL(profile)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Profile retrieved",
+		"data":    profile,
+	})
+}
+
+type playbackPreferencesRequest struct {
+	Autoplay *bool `json:"autoplay"`
+	// DefaultQuality is auto or a rendition height
+	DefaultQuality *string `json:"default_quality" binding:"omitempty,oneof=auto 2160p 1440p 1080p 720p 480p 360p 240p"`
+	CaptionsOn     *bool   `json:"captions_on"`
+}
+
+type updateProfileRequest struct {
+	// Name is the display name; an empty string removes it
+	Name *string `json:"name" binding:"omitempty,max=255"`
+	// Locale is a BCP 47 language tag such as en or pt-BR
+	Locale *string `json:"locale" binding:"omitempty,max=35"`
+	// Timezone is an IANA time zone name such as Europe/Paris
+	Timezone *string                     `json:"timezone" binding:"omitempty,max=64"`
+	Playback *playbackPreferencesRequest `json:"playback"`
+}
+
+// UpdateProfile godoc
+// @Summary Update profile
+// @Description Changes the caller's name, locale, timezone or playback preferences; fields left out are kept. Playback preferences are returned
+// @Description as the player settings of the caller's playback sessions. The avatar is changed with PUT /api/v1/me/avatar.
+// @Tags users
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body updateProfileRequest true "Fields to change"
+// @Success 200 {object} SuccessResponse{data=models.UserProfile} "Profile updated"
+// @Failure 400 {object} ErrorResponse "Invalid request, locale or timezone"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Router /api/v1/me [patch]
+func UpdateProfile(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req updateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		req.Name = &trimmed
+	}
+	if req.Locale != nil {
+		locale, err := services.CanonicalLocale(*req.Locale)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Locale = &locale
+	}
+	if req.Timezone != nil {
+		if err := services.CheckTimezone(*req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	playback := req.Playback
+	if playback == nil {
+		playback = &playbackPreferencesRequest{}
+	}
+
+	ctx := c.Request.Context()
+	userID := tenantDB.GetUserID()
+	row, err := queries.New(tenantDB).UpdateUserProfile(ctx, queries.UpdateUserProfileParams{
+		Name:             req.Name,
+		Locale:           req.Locale,
+		Timezone:         req.Timezone,
+		PlaybackAutoplay: playback.Autoplay,
+		PlaybackQuality:  playback.DefaultQuality,
+		PlaybackCaptions: playback.CaptionsOn,
+		ID:               userID,
+	})
+	profile, err := services.UserProfileModel(queries.GetUserProfileRow(row), err)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update profile of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+	profileAvatarURL(profile)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Profile updated",
+		"data":    profile,
+	})
+}
+
+// profileAvatarURL links a profile to its avatar, if it has one
+func profileAvatarURL(profile *models.UserProfile) {
+	if profile.Avatar != nil {
+		profile.AvatarURL = imageURL(fmt.Sprintf("/images/users/%s/avatar", profile.ID), profile.Avatar, "")
+	}
+}
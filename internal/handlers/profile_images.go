@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/images"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// bannerAspect is the width to height ratio banners are cropped to
+const bannerAspect = 4.0
+
+// ProfileImageHandler manages user avatars and organization banners. Both are served
+// publicly, so they can be shown on embed pages and in emails without credentials.
+type ProfileImageHandler struct {
+	db        *sql.DB
+	storage   storage.Storage
+	processor *images.Processor
+	config    config.Images
+	cache     cachecontrol.Policies
+}
+
+// NewProfileImageHandler creates a new profile image handler. db is used for the public image
+// endpoints and must not carry a tenant context.
+func NewProfileImageHandler(db *sql.DB, store storage.Storage, processor *images.Processor, cfg config.Images,
+	cache cachecontrol.Policies) *ProfileImageHandler {
+	return &ProfileImageHandler{db: db, storage: store, processor: processor, config: cfg, cache: cache}
+}
+
+// UploadAvatar godoc
+// @Summary Upload avatar
+// @Description Sets the caller's avatar from a JPEG, PNG or WebP file, sent as the raw body or as the file field of a multipart form.
+// @Description The image is cropped to a square around its center, resized to the configured sizes and stripped of metadata.
+// @Tags users
+// @Security ApiKeyAuth
+// @Accept image/jpeg,image/png,image/webp,multipart/form-data
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{avatar=models.Image,url=string}} "Avatar updated, with its URL"
+// @Failure 400 {object} ErrorResponse "Invalid image"
+// @Failure 413 {object} ErrorResponse "Image too large"
+// @Failure 415 {object} ErrorResponse "Unsupported image format"
+// @Router /api/v1/me/avatar [put]
+func (h *ProfileImageHandler) UploadAvatar(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	userID := tenantDB.GetUserID()
+
+	avatar, ok := storeImageUpload(c, h.storage, h.processor, h.config,
+		images.Spec{Widths: h.config.AvatarSizes, Aspect: 1}, fmt.Sprintf("users/%s/avatars", userID))
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	previous, err := h.setAvatar(ctx, tenantDB, userID, avatar)
+	if err != nil {
+		images.Delete(context.WithoutCancel(ctx), h.storage, avatar)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		logger.Error("Failed to set avatar of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update avatar"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Avatar updated",
+		"data": gin.H{
+			"avatar": avatar,
+			"url":    imageURL(fmt.Sprintf("/images/users/%s/avatar", userID), avatar, ""),
+		},
+	})
+}
+
+// DeleteAvatar godoc
+// @Summary Remove avatar
+// @Description Deletes the caller's avatar
+// @Tags users
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} SuccessResponse "Avatar removed"
+// @Failure 404 {object} ErrorResponse "No avatar set"
+// @Router /api/v1/me/avatar [delete]
+func (h *ProfileImageHandler) DeleteAvatar(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	userID := tenantDB.GetUserID()
+
+	ctx := c.Request.Context()
+	previous, err := h.setAvatar(ctx, tenantDB, userID, nil)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error("Failed to remove avatar of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove avatar"})
+		return
+	}
+	if previous == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No avatar set"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Avatar removed",
+	})
+}
+
+// Avatar godoc
+// @Summary User avatar
+// @Description Serves a user's avatar in the best format the Accept header allows. Links with ?v= set to the avatar's ID are cacheable indefinitely.
+// @Tags users
+// @Produce image/avif,image/webp,image/jpeg,image/png
+// @Param id path string true "User ID"
+// @Param w query int false "Smallest acceptable size in pixels"
+// @Param v query string false "Avatar ID the link was made for"
+// @Success 200 {file} file "Image data"
+// @Failure 404 {object} ErrorResponse "Avatar not found"
+// @Router /images/users/{id}/avatar [get]
+func (h *ProfileImageHandler) Avatar(c *gin.Context) {
+	h.serve(c, (*queries.Queries).GetAvatar)
+}
+
+// UploadBanner godoc
+// @Summary Upload organization banner
+// @Description Sets an organization's banner from a JPEG, PNG or WebP file, sent as the raw body or as the file field of a multipart form. Requires the owner or admin role.
+// @Description The image is cropped to 4:1 around its center, resized to the configured widths and stripped of metadata.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Accept image/jpeg,image/png,image/webp,multipart/form-data
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=models.Organization} "Banner updated"
+// @Failure 400 {object} ErrorResponse "Invalid image"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 413 {object} ErrorResponse "Image too large"
+// @Failure 415 {object} ErrorResponse "Unsupported image format"
+// @Router /api/v1/organizations/{id}/banner [put]
+func (h *ProfileImageHandler) UploadBanner(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Changing the banner")
+	if !ok {
+		return
+	}
+
+	banner, ok := storeImageUpload(c, h.storage, h.processor, h.config,
+		images.Spec{Widths: h.config.BannerWidths, Aspect: bannerAspect}, fmt.Sprintf("orgs/%s/banners", orgID))
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	org, previous, err := h.setBanner(ctx, tenantDB, orgID, banner)
+	if err != nil {
+		images.Delete(context.WithoutCancel(ctx), h.storage, banner)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+			return
+		}
+		logger.Error("Failed to set banner of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update banner"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.Header("ETag", resourceETag(org.ID, org.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Banner updated",
+		"data":    org,
+	})
+}
+
+// DeleteBanner godoc
+// @Summary Remove organization banner
+// @Description Deletes an organization's banner. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=models.Organization} "Banner removed"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization or banner not found"
+// @Router /api/v1/organizations/{id}/banner [delete]
+func (h *ProfileImageHandler) DeleteBanner(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Changing the banner")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	org, previous, err := h.setBanner(ctx, tenantDB, orgID, nil)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to remove banner of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove banner"})
+		return
+	}
+	if previous == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization has no banner"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.Header("ETag", resourceETag(org.ID, org.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Banner removed",
+		"data":    org,
+	})
+}
+
+// Banner godoc
+// @Summary Organization banner
+// @Description Serves an organization's banner in the best format the Accept header allows. Links with ?v= set to the banner's ID are cacheable indefinitely.
+// @Tags organizations
+// @Produce image/avif,image/webp,image/jpeg,image/png
+// @Param id path string true "Organization ID"
+// @Param w query int false "Smallest acceptable width in pixels"
+// @Param v query string false "Banner ID the link was made for"
+// @Success 200 {file} file "Image data"
+// @Failure 404 {object} ErrorResponse "Banner not found"
+// @Router /images/organizations/{id}/banner [get]
+func (h *ProfileImageHandler) Banner(c *gin.Context) {
+	h.serve(c, (*queries.Queries).GetBanner)
+}
+
+// serve looks up the image load returns for the id path parameter and writes it
+func (h *ProfileImageHandler) serve(c *gin.Context, load func(*queries.Queries, context.Context, uuid.UUID) (*models.Image, error)) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	img, err := load(queries.New(h.db), c.Request.Context(), id)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error("Failed to load image for %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load image"})
+		return
+	}
+	if img == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+	serveImage(c, h.storage, h.cache, img, true)
+}
+
+// setAvatar stores avatar (nil to remove it) and returns the previous one, whose files the
+// caller deletes. Users belong to no organization, so avatars have no storage object records.
+func (h *ProfileImageHandler) setAvatar(ctx context.Context, tenantDB *database.StatelessTenantDB, userID uuid.UUID,
+	avatar *models.Image) (*models.Image, error) {
+	var previous *models.Image
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		qtx := queries.New(tx)
+		var err error
+		if previous, err = qtx.LockAvatar(ctx, userID); err != nil {
+			return err
+		}
+		return qtx.SetAvatar(ctx, queries.SetAvatarParams{Avatar: avatar, ID: userID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+// setBanner swaps the organization's banner (nil to remove it) and its storage object records
+// in one transaction, returning the updated organization and the previous banner
+func (h *ProfileImageHandler) setBanner(ctx context.Context, tenantDB *database.StatelessTenantDB, orgID uuid.UUID,
+	banner *models.Image) (*models.Organization, *models.Image, error) {
+	var org *models.Organization
+	var previous *models.Image
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		qtx := queries.New(tx)
+		var err error
+		if previous, err = qtx.LockBanner(ctx, orgID); err != nil {
+			return err
+		}
+		if banner == nil && previous == nil {
+			return nil
+		}
+
+		org, err = services.OrganizationModel(qtx.SetBanner(ctx, queries.SetBannerParams{Banner: banner, ID: orgID}))
+		if err != nil {
+			return err
+		}
+
+		if previous != nil {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = ANY($1)`,
+				pq.Array(previous.Keys())); err != nil {
+				return err
+			}
+		}
+		if banner != nil {
+			for _, v := range banner.Variants {
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO storage_objects (organization_id, object_key, kind, size_bytes)
+					VALUES ($1, $2, $3, $4)
+					ON CONFLICT (object_key) DO NOTHING
+				`, orgID, v.Key, models.ObjectKindImage, v.SizeBytes); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return org, previous, nil
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PushDeviceHandler registers the browsers and app installations a user receives push
+// notifications on
+type PushDeviceHandler struct {
+	push           *services.PushNotifier
+	vapidPublicKey string
+}
+
+// NewPushDeviceHandler creates a new push device handler. vapidPublicKey is handed to browsers
+// when Web Push is enabled.
+func NewPushDeviceHandler(push *services.PushNotifier, vapidPublicKey string) *PushDeviceHandler {
+	return &PushDeviceHandler{push: push, vapidPublicKey: vapidPublicKey}
+}
+
+// GetPushConfig godoc
+// @Summary Get push configuration
+// @Description Lists the platforms devices can register for and, when Web Push is enabled, the VAPID public key to pass to pushManager.subscribe as applicationServerKey
+// @Tags notifications
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{platforms=[]string,vapid_public_key=string}} "Push configuration"
+// @Router /api/v1/push/config [get]
+func (h *PushDeviceHandler) GetPushConfig(c *gin.Context) {
+	data := gin.H{"platforms": h.push.Platforms()}
+	if h.push.Supports(models.PushPlatformWeb) {
+		data["vapid_public_key"] = h.vapidPublicKey
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Push configuration retrieved",
+		"data":    data,
+	})
+}
+
+// ListPushDevices godoc
+// @Summary List push devices
+// @Description Lists the caller's registered push devices
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{devices=[]models.PushDevice}} "Devices retrieved"
+// @Router /api/v1/me/devices [get]
+func (h *PushDeviceHandler) ListPushDevices(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(c.Request.Context(),
+		`SELECT `+services.PushDeviceColumns+` FROM push_devices WHERE user_id = $1 ORDER BY created_at`, tenantDB.GetUserID())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query devices"})
+		return
+	}
+	defer rows.Close()
+
+	devices := []models.PushDevice{}
+	for rows.Next() {
+		device, err := services.ScanPushDevice(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan device"})
+			return
+		}
+		devices = append(devices, *device)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing device results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Devices retrieved successfully",
+		"data":    gin.H{"devices": devices},
+	})
+}
+
+type registerPushDeviceRequest struct {
+	Platform string `json:"platform" binding:"required,oneof=web fcm apns"`
+	Token    string `json:"token" binding:"max=4096"`
+	Name     string `json:"name" binding:"max=255"`
+	// Subscription is the Web Push subscription of web devices, which have no token
+	Subscription *struct {
+		Endpoint string `json:"endpoint" binding:"required,max=4096"`
+		Keys     struct {
+			P256DH string `json:"p256dh" binding:"required"`
+			Auth   string `json:"auth" binding:"required"`
+		} `json:"keys"`
+	} `json:"subscription"`
+}
+
+// RegisterPushDevice godoc
+// @Summary Register push device
+// @Description Registers a device for push notifications, or renames it when it is already registered.
+// @Description Apps send platform fcm or apns with their device token; browsers send platform web with the JSON of their PushSubscription as subscription.
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body registerPushDeviceRequest true "Platform, token or Web Push subscription, and an optional name"
+// @Success 201 {object} SuccessResponse{data=models.PushDevice} "Device registered"
+// @Failure 400 {object} ErrorResponse "Invalid request or unsupported platform"
+// @Router /api/v1/me/devices [post]
+func (h *PushDeviceHandler) RegisterPushDevice(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req registerPushDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if !h.push.Supports(req.Platform) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Push notifications are not configured for platform " + req.Platform})
+		return
+	}
+
+	token := strings.TrimSpace(req.Token)
+	var p256dh, auth *string
+	switch req.Platform {
+	case models.PushPlatformWeb:
+		if req.Subscription == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Web devices require a subscription"})
+			return
+		}
+		sub := req.Subscription
+		if err := services.ValidateWebPushSubscription(sub.Endpoint, sub.Keys.P256DH, sub.Keys.Auth); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription: " + err.Error()})
+			return
+		}
+		token, p256dh, auth = sub.Endpoint, &sub.Keys.P256DH, &sub.Keys.Auth
+	case models.PushPlatformAPNs:
+		token = strings.ToLower(token)
+		if !services.ValidAPNsToken(token) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid APNs device token"})
+			return
+		}
+	default:
+		if token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Token is required"})
+			return
+		}
+	}
+
+	device, err := services.ScanPushDevice(tenantDB.QueryRowContext(c.Request.Context(), `
+		INSERT INTO push_devices (user_id, platform, token, p256dh, auth, name)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, platform, token)
+		DO UPDATE SET p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth, name = EXCLUDED.name
+		RETURNING `+services.PushDeviceColumns,
+		tenantDB.GetUserID(), req.Platform, token, p256dh, auth, strings.TrimSpace(req.Name)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Device registered",
+		"data":    device,
+	})
+}
+
+// DeletePushDevice godoc
+// @Summary Delete push device
+// @Description Stops push notifications to one of the caller's devices, e.g. on sign-out
+// @Tags notifications
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Device ID"
+// @Success 200 {object} SuccessResponse{data=object{id=string}} "Device deleted"
+// @Failure 400 {object} ErrorResponse "Invalid device ID"
+// @Failure 404 {object} ErrorResponse "Device not found"
+// @Router /api/v1/me/devices/{id} [delete]
+func (h *PushDeviceHandler) DeletePushDevice(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	var deleted uuid.UUID
+	err = tenantDB.QueryRowContext(c.Request.Context(),
+		`DELETE FROM push_devices WHERE id = $1 AND user_id = $2 RETURNING id`, id, tenantDB.GetUserID()).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Device deleted",
+		"data":    gin.H{"id": deleted},
+	})
+}
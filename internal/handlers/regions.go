@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"openvdo/internal/queries"
+	"openvdo/internal/regions"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RegionHandler struct {
+	regions *regions.Router
+}
+
+func NewRegionHandler(router *regions.Router) *RegionHandler {
+	return &RegionHandler{regions: router}
+}
+
+// regionStats summarizes the content one region holds
+type regionStats struct {
+	Name          string `json:"name"`
+	Home          bool   `json:"home"`
+	Organizations int64  `json:"organizations"`
+	Videos        int64  `json:"videos"`
+	Bytes         int64  `json:"bytes"`
+	QueuedJobs    int64  `json:"queued_jobs"`
+}
+
+// ListRegions godoc
+// @Summary List regions
+// @Description Lists the configured regions with the organizations, videos, bytes and queued jobs each holds.
+// @Description The figures are queried from every region's database at once, so one unreachable region fails the request.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=object{home=string,local=string,regions=[]regionStats}} "Regions retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 502 {object} ErrorResponse "A region could not be queried"
+// @Router /admin/v1/regions [get]
+func (h *RegionHandler) ListRegions(c *gin.Context) {
+	stats, err := regions.Federate(c.Request.Context(), h.regions,
+		func(ctx context.Context, region string, db *sql.DB) (regionStats, error) {
+			row, err := queries.New(db).GetRegionStats(ctx)
+			return regionStats{
+				Name:          region,
+				Home:          region == h.regions.Home(),
+				Organizations: row.Organizations,
+				Videos:        row.Videos,
+				Bytes:         row.Bytes,
+				QueuedJobs:    row.QueuedJobs,
+			}, err
+		})
+	if err != nil {
+		logger.Error("Failed to query regions: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to query every region"})
+		return
+	}
+
+	list := make([]regionStats, 0, len(stats))
+	for _, name := range h.regions.Names() {
+		list = append(list, stats[name])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Regions retrieved successfully",
+		"data": gin.H{
+			"home":    h.regions.Home(),
+			"local":   h.regions.Local(),
+			"regions": list,
+		},
+	})
+}
+
+type regionRequest struct {
+	Region string `json:"region" binding:"required,max=50" example:"eu"`
+}
+
+// SetOrganizationRegion godoc
+// @Summary Pin organization to region
+// @Description Pins an organization's database rows and storage objects to a region. Only organizations without
+// @Description videos or pending jobs can move, since existing data is not copied between regions.
+// @Description Other instances route to the new region within REGION_LOOKUP_TTL.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body regionRequest true "Region to pin the organization to"
+// @Success 200 {object} SuccessResponse{data=object{organization_id=string,region=string}} "Region saved"
+// @Failure 400 {object} ErrorResponse "Invalid request or unknown region"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 409 {object} ErrorResponse "Organization already has content"
+// @Router /admin/v1/organizations/{id}/region [put]
+func (h *RegionHandler) SetOrganizationRegion(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req regionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	err = h.regions.SetRegion(c.Request.Context(), orgID, req.Region)
+	switch {
+	case errors.Is(err, regions.ErrUnknownRegion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown region"})
+		return
+	case errors.Is(err, regions.ErrOrganizationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	case errors.Is(err, regions.ErrNotEmpty):
+		c.JSON(http.StatusConflict, gin.H{"error": "Organization already has videos or pending jobs in its region"})
+		return
+	case err != nil:
+		logger.Error("Failed to pin organization %s to region %s: %v", orgID, req.Region, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save region"})
+		return
+	}
+	logger.Info("Organization %s pinned to region %s", orgID, req.Region)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Region saved",
+		"data":    gin.H{"organization_id": orgID, "region": req.Region},
+	})
+}
@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReviewHandler serves the approval workflow of organizations that require review: the queue
+// of videos waiting for approval, comments on reviews, and the decisions of owners and admins.
+type ReviewHandler struct {
+	queue *jobs.Queue
+}
+
+// NewReviewHandler creates a review handler. queue is woken up for the notifications decisions
+// send.
+func NewReviewHandler(queue *jobs.Queue) *ReviewHandler {
+	return &ReviewHandler{queue: queue}
+}
+
+type reviewCommentRequest struct {
+	Body string `json:"body" binding:"required,max=5000"`
+	// PositionSeconds pins the comment to a moment of the video
+	PositionSeconds *float64 `json:"position_seconds" binding:"omitempty,min=0"`
+}
+
+type reviewDecisionRequest struct {
+	Note string `json:"note" binding:"max=2000"`
+}
+
+// ListReviews godoc
+// @Summary List reviews
+// @Description Lists the reviews of the current organization's videos in a status, pending by default. Pending reviews, the review queue, are listed oldest first; decided ones newest first.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Produce json
+// @Param status query string false "pending, approved or rejected (default pending)"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 20, max 100)"
+// @Success 200 {object} SuccessResponse{data=object{reviews=[]models.VideoReview,pagination=response.Pagination}} "Reviews retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid status"
+// @Router /api/v1/reviews [get]
+func (h *ReviewHandler) ListReviews(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.ReviewPending)
+	order := "DESC"
+	switch status {
+	case models.ReviewPending:
+		order = "ASC"
+	case models.ReviewApproved, models.ReviewRejected:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, approved or rejected"})
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx := c.Request.Context()
+	rows, err := tenantDB.QueryContext(ctx, `SELECT `+services.ReviewColumns+` FROM video_reviews
+		WHERE status = $1
+		ORDER BY created_at `+order+`
+		LIMIT $2 OFFSET $3`, status, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query reviews"})
+		return
+	}
+	defer rows.Close()
+
+	reviews := []models.VideoReview{}
+	for rows.Next() {
+		review, err := services.ScanReview(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan review"})
+			return
+		}
+		reviews = append(reviews, *review)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing review results"})
+		return
+	}
+
+	var total int
+	if err := tenantDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM video_reviews WHERE status = $1`,
+		status).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Reviews retrieved successfully",
+		"data": gin.H{
+			"reviews": reviews,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+// GetReview godoc
+// @Summary Get a review
+// @Description Returns a review with its comments, oldest first.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Review ID"
+// @Success 200 {object} SuccessResponse{data=object{review=models.VideoReview,comments=[]models.ReviewComment}} "Review retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid review ID"
+// @Failure 404 {object} ErrorResponse "Review not found"
+// @Router /api/v1/reviews/{id} [get]
+func (h *ReviewHandler) GetReview(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	reviewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	review, err := services.ScanReview(tenantDB.QueryRowContext(ctx,
+		`SELECT `+services.ReviewColumns+` FROM video_reviews WHERE id = $1`, reviewID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get review"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(ctx, `SELECT `+services.ReviewCommentColumns+` FROM review_comments
+		WHERE review_id = $1
+		ORDER BY created_at`, reviewID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query review comments"})
+		return
+	}
+	defer rows.Close()
+
+	comments := []models.ReviewComment{}
+	for rows.Next() {
+		comment, err := services.ScanReviewComment(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan review comment"})
+			return
+		}
+		comments = append(comments, *comment)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing review comment results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Review retrieved successfully",
+		"data": gin.H{
+			"review":   review,
+			"comments": comments,
+		},
+	})
+}
+
+// AddReviewComment godoc
+// @Summary Comment on a review
+// @Description Adds a comment to a review, optionally pinned to a position of the video in seconds.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param request body reviewCommentRequest true "Comment"
+// @Success 201 {object} SuccessResponse{data=models.ReviewComment} "Comment added"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Review not found"
+// @Router /api/v1/reviews/{id}/comments [post]
+func (h *ReviewHandler) AddReviewComment(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	reviewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+	var req reviewCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	comment, err := services.ScanReviewComment(tenantDB.QueryRowContext(c.Request.Context(), `
+		INSERT INTO review_comments (review_id, organization_id, author_id, body, position_seconds)
+		SELECT id, organization_id, $2, $3, $4 FROM video_reviews WHERE id = $1
+		RETURNING `+services.ReviewCommentColumns,
+		reviewID, tenantDB.GetUserID(), req.Body, req.PositionSeconds))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to comment on review %s: %v", reviewID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add comment"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Comment added",
+		"data":    comment,
+	})
+}
+
+// ApproveReview godoc
+// @Summary Approve a review
+// @Description Approves a pending review, publishing its video. Sends a video.approved event and notifies the member who submitted the video. Requires the owner or admin role.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param request body reviewDecisionRequest false "Optional note for the submitter"
+// @Success 200 {object} SuccessResponse{data=models.VideoReview} "Review approved"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Review not found"
+// @Failure 409 {object} ErrorResponse "Review already decided"
+// @Router /api/v1/reviews/{id}/approve [post]
+func (h *ReviewHandler) ApproveReview(c *gin.Context) {
+	h.decide(c, true)
+}
+
+// RejectReview godoc
+// @Summary Reject a review
+// @Description Rejects a pending review with a note saying why. The video keeps playing only for members until it is submitted again. Sends a video.rejected event and notifies the member who submitted the video. Requires the owner or admin role.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Review ID"
+// @Param request body reviewDecisionRequest true "Why the video was rejected"
+// @Success 200 {object} SuccessResponse{data=models.VideoReview} "Review rejected"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Review not found"
+// @Failure 409 {object} ErrorResponse "Review already decided"
+// @Router /api/v1/reviews/{id}/reject [post]
+func (h *ReviewHandler) RejectReview(c *gin.Context) {
+	h.decide(c, false)
+}
+
+func (h *ReviewHandler) decide(c *gin.Context, approve bool) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	if role := tenantDB.GetRole(); role != models.RoleOwner && role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Deciding a review requires the owner or admin role"})
+		return
+	}
+	reviewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return
+	}
+	var req reviewDecisionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+	if !approve && req.Note == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A note is required to reject a review"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var review *models.VideoReview
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		review, err = services.DecideReview(ctx, tx, reviewID, tenantDB.GetUserID(), approve, req.Note)
+		return err
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	case errors.Is(err, services.ErrReviewDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": "Review was already decided"})
+		return
+	case err != nil:
+		logger.Error("Failed to decide review %s: %v", reviewID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decide review"})
+		return
+	}
+	h.queue.Notify()
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": fmt.Sprintf("Review %s", review.Status),
+		"data":    review,
+	})
+}
+
+// SubmitVideoForReview godoc
+// @Summary Submit a video for review
+// @Description Submits a rejected video for approval again, opening a new review. Videos created in organizations that require review are submitted when they are created.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 201 {object} SuccessResponse{data=models.VideoReview} "Video submitted for review"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 409 {object} ErrorResponse "Video is waiting for review or approved"
+// @Router /api/v1/videos/{id}/reviews [post]
+func (h *ReviewHandler) SubmitVideoForReview(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var review *models.VideoReview
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		review, err = services.SubmitForReview(ctx, tx, videoID, tenantDB.GetUserID())
+		return err
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	case errors.Is(err, services.ErrReviewPending), errors.Is(err, services.ErrNotRejected):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to submit video %s for review: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit video for review"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Video submitted for review",
+		"data":    review,
+	})
+}
+
+// ListVideoReviews godoc
+// @Summary List reviews of a video
+// @Description Lists every review of a video, newest first.
+// @Tags reviews
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{reviews=[]models.VideoReview}} "Reviews retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Router /api/v1/videos/{id}/reviews [get]
+func (h *ReviewHandler) ListVideoReviews(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(c.Request.Context(), `SELECT `+services.ReviewColumns+` FROM video_reviews
+		WHERE video_id = $1
+		ORDER BY created_at DESC`, videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query reviews"})
+		return
+	}
+	defer rows.Close()
+
+	reviews := []models.VideoReview{}
+	for rows.Next() {
+		review, err := services.ScanReview(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan review"})
+			return
+		}
+		reviews = append(reviews, *review)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing review results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Reviews retrieved successfully",
+		"data":    gin.H{"reviews": reviews},
+	})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"openvdo/internal/scheduler"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewScheduleHandler(s *scheduler.Scheduler) *ScheduleHandler {
+	return &ScheduleHandler{scheduler: s}
+}
+
+// ListSchedules godoc
+// @Summary List scheduled tasks
+// @Description Lists the recurring maintenance tasks with their schedule, whether they are enabled, when they run next and the outcome of their last run
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Success 200 {object} SuccessResponse{data=[]scheduler.State} "Scheduled tasks retrieved"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /admin/v1/schedules [get]
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	states, err := h.scheduler.List(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list scheduled tasks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list scheduled tasks"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Scheduled tasks retrieved successfully",
+		"data":    states,
+	})
+}
+
+type scheduleRequest struct {
+	Enabled *bool `json:"enabled"`
+	// Schedule is a cron expression, a descriptor such as @hourly or "@every 10m"; empty restores the default
+	Schedule *string `json:"schedule" binding:"omitempty,max=100"`
+}
+
+// UpdateSchedule godoc
+// @Summary Change a scheduled task
+// @Description Pauses or resumes a task and overrides its schedule. Schedules are five-field cron expressions evaluated in UTC, descriptors such as @hourly and @daily, or "@every <duration>"; an empty schedule restores the task's default.
+// @Description The next run is planned afresh from now.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param name path string true "Task name"
+// @Param request body scheduleRequest true "Settings to change"
+// @Success 200 {object} SuccessResponse{data=scheduler.State} "Scheduled task saved"
+// @Failure 400 {object} ErrorResponse "Invalid request or schedule"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Unknown task"
+// @Router /admin/v1/schedules/{name} [patch]
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	var req scheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Schedule != nil {
+		trimmed := strings.TrimSpace(*req.Schedule)
+		req.Schedule = &trimmed
+	}
+
+	state, err := h.scheduler.Update(c.Request.Context(), c.Param("name"), req.Enabled, req.Schedule)
+	if err != nil {
+		h.respondError(c, err, "Failed to save scheduled task")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Scheduled task saved",
+		"data":    state,
+	})
+}
+
+// RunSchedule godoc
+// @Summary Run a scheduled task now
+// @Description Makes a task due now. The leader starts it within SCHEDULER_CHECK_INTERVAL, or once the run under way ends.
+// @Tags admin
+// @Security AdminToken
+// @Produce json
+// @Param name path string true "Task name"
+// @Success 202 {object} SuccessResponse{data=scheduler.State} "Run requested"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Unknown task"
+// @Failure 409 {object} ErrorResponse "Task is disabled"
+// @Router /admin/v1/schedules/{name}/run [post]
+func (h *ScheduleHandler) RunSchedule(c *gin.Context) {
+	state, err := h.scheduler.Trigger(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		h.respondError(c, err, "Failed to run scheduled task")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Run requested",
+		"data":    state,
+	})
+}
+
+func (h *ScheduleHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownTask):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown task"})
+	case errors.Is(err, scheduler.ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, scheduler.ErrTaskDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is disabled"})
+	default:
+		logger.Error("%s %s: %v", message, c.Param("name"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,693 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/images"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// artworkAspect is the width to height ratio series artwork is cropped to
+const artworkAspect = 16.0 / 9.0
+
+// SeriesHandler manages series, their episodes and their artwork
+type SeriesHandler struct {
+	db        *sql.DB
+	storage   storage.Storage
+	processor *images.Processor
+	config    config.Images
+	cache     cachecontrol.Policies
+}
+
+// NewSeriesHandler creates a new series handler; db serves artwork outside any tenant
+func NewSeriesHandler(db *sql.DB, store storage.Storage, processor *images.Processor, cfg config.Images,
+	cache cachecontrol.Policies) *SeriesHandler {
+	return &SeriesHandler{db: db, storage: store, processor: processor, config: cfg, cache: cache}
+}
+
+// ListSeries godoc
+// @Summary List series
+// @Description Lists the series of the current organization, newest first, optionally only those of a project
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Param project_id query string false "Only series of this project"
+// @Success 200 {object} SuccessResponse{data=object{series=[]models.Series,pagination=response.Pagination}} "Series retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Router /api/v1/series [get]
+func (h *SeriesHandler) ListSeries(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := ""
+	args := []interface{}{}
+	if raw := c.Query("project_id"); raw != "" {
+		projectID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project_id"})
+			return
+		}
+		args = append(args, projectID)
+		filter += fmt.Sprintf(" AND project_id = $%d", len(args))
+	}
+
+	ctx := c.Request.Context()
+	var total int
+	if err := tenantDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM series WHERE TRUE`+filter, args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count series"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(ctx, fmt.Sprintf(`
+		SELECT `+services.SeriesColumns+` FROM series WHERE TRUE%s
+		ORDER BY created_at DESC LIMIT $%d OFFSET $%d
+	`, filter, len(args)+1, len(args)+2), append(args, limit, (page-1)*limit)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query series"})
+		return
+	}
+	defer rows.Close()
+
+	series := []models.Series{}
+	for rows.Next() {
+		s, err := services.ScanSeries(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan series"})
+			return
+		}
+		series = append(series, *s)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing series results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Series retrieved successfully",
+		"data": gin.H{
+			"series": series,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+type createSeriesRequest struct {
+	Title       string     `json:"title" binding:"required,min=1,max=255"`
+	Description string     `json:"description" binding:"max=10000"`
+	ProjectID   *uuid.UUID `json:"project_id"`
+}
+
+// CreateSeries godoc
+// @Summary Create series
+// @Description Creates a series in the current organization. Episodes are added with PUT /api/v1/series/{id}/episodes.
+// @Tags series
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body createSeriesRequest true "Series details"
+// @Success 201 {object} SuccessResponse{data=models.Series} "Series created"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Router /api/v1/series [post]
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req createSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+
+	var series *models.Series
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := services.CheckSeriesProject(ctx, tx, session.OrgID, req.ProjectID); err != nil {
+			return err
+		}
+		var err error
+		series, err = services.ScanSeries(tx.QueryRowContext(ctx, `
+			INSERT INTO series (organization_id, project_id, title, description, created_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING `+services.SeriesColumns,
+			session.OrgID, req.ProjectID, req.Title, req.Description, session.UserID))
+		return err
+	})
+	if errors.Is(err, services.ErrSeriesProject) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found in the organization"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create series in org %s: %v", session.OrgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+		return
+	}
+
+	c.Header("ETag", resourceETag(series.ID, series.UpdatedAt))
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Series created",
+		"data":    series,
+	})
+}
+
+// GetSeries godoc
+// @Summary Get series
+// @Description Retrieves a series with its episodes ordered by season and episode number
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} SuccessResponse{data=models.Series} "Series retrieved"
+// @Failure 404 {object} ErrorResponse "Series not found"
+// @Router /api/v1/series/{id} [get]
+func (h *SeriesHandler) GetSeries(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	series, err := services.ScanSeries(tenantDB.QueryRowContext(ctx, `SELECT `+services.SeriesColumns+` FROM series WHERE id = $1`, seriesID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get series"})
+		return
+	}
+	if series.Episodes, err = services.ListEpisodes(ctx, tenantDB, seriesID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get episodes"})
+		return
+	}
+
+	// The ETag is for If-Match on updates; episodes show the current titles of their videos,
+	// which it does not cover
+	c.Header("ETag", resourceETag(series.ID, series.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Series retrieved successfully",
+		"data":    series,
+	})
+}
+
+type updateSeriesRequest struct {
+	Version     *int64  `json:"version" binding:"required"`
+	Title       *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description" binding:"omitempty,max=10000"`
+	// ProjectID removes the project when empty
+	ProjectID *string `json:"project_id"`
+}
+
+// UpdateSeries godoc
+// @Summary Update series
+// @Description Updates the title, description or project of a series; an empty project_id removes the project. The request must carry
+// @Description the version it is based on; if the series was changed since, the update is rejected with 409 and the current state.
+// @Tags series
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param If-Match header string false "ETag the update is based on"
+// @Param request body updateSeriesRequest true "Expected version and fields to change"
+// @Success 200 {object} SuccessResponse{data=models.Series} "Series updated"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Series not found"
+// @Failure 409 {object} ErrorResponse{data=models.Series} "Version conflict, with the current series"
+// @Failure 412 {object} ErrorResponse "Series was modified"
+// @Router /api/v1/series/{id} [patch]
+func (h *SeriesHandler) UpdateSeries(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	var req updateSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	var projectID *uuid.UUID
+	if req.ProjectID != nil && *req.ProjectID != "" {
+		id, err := uuid.Parse(*req.ProjectID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project_id"})
+			return
+		}
+		projectID = &id
+	}
+
+	ctx := c.Request.Context()
+	current, err := services.ScanSeries(tenantDB.QueryRowContext(ctx,
+		`SELECT `+services.SeriesColumns+` FROM series WHERE id = $1`, seriesID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get series"})
+		}
+		return
+	}
+	if preconditionFailed(c, resourceETag(current.ID, current.UpdatedAt)) {
+		return
+	}
+	if current.Version != *req.Version {
+		versionConflict(c, current)
+		return
+	}
+
+	// Matching the version makes the check hold even against a concurrent update
+	var series *models.Series
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := services.CheckSeriesProject(ctx, tx, current.OrganizationID, projectID); err != nil {
+			return err
+		}
+		var err error
+		series, err = services.ScanSeries(tx.QueryRowContext(ctx, `
+			UPDATE series
+			SET title = COALESCE($2, title), description = COALESCE($3, description),
+				project_id = CASE WHEN $5 THEN $6 ELSE project_id END, version = version + 1
+			WHERE id = $1 AND version = $4
+			RETURNING `+services.SeriesColumns,
+			seriesID, req.Title, req.Description, *req.Version, req.ProjectID != nil, projectID))
+		return err
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		if current, err := services.ScanSeries(tenantDB.QueryRowContext(ctx,
+			`SELECT `+services.SeriesColumns+` FROM series WHERE id = $1`, seriesID)); err == nil {
+			versionConflict(c, current)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	case errors.Is(err, services.ErrSeriesProject):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project not found in the organization"})
+		return
+	case err != nil:
+		logger.Error("Failed to update series %s: %v", seriesID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+
+	c.Header("ETag", resourceETag(series.ID, series.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Series updated successfully",
+		"data":    series,
+	})
+}
+
+// DeleteSeries godoc
+// @Summary Delete series
+// @Description Deletes a series and its artwork. The videos of its episodes are kept.
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} SuccessResponse "Series deleted"
+// @Failure 404 {object} ErrorResponse "Series not found"
+// @Router /api/v1/series/{id} [delete]
+func (h *SeriesHandler) DeleteSeries(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var artwork *models.Image
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, `DELETE FROM series WHERE id = $1 RETURNING artwork`, seriesID).Scan(&artwork); err != nil {
+			return err
+		}
+		if artwork == nil {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = ANY($1)`, pq.Array(artwork.Keys()))
+		return err
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete series %s: %v", seriesID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete series"})
+		return
+	}
+	images.Delete(ctx, h.storage, artwork)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Series deleted",
+	})
+}
+
+type seriesEpisodesRequest struct {
+	Episodes []struct {
+		VideoID       uuid.UUID `json:"video_id" binding:"required"`
+		SeasonNumber  *int      `json:"season_number"`
+		EpisodeNumber *int      `json:"episode_number"`
+	} `json:"episodes" binding:"required,dive"`
+}
+
+// SetSeriesEpisodes godoc
+// @Summary Set series episodes
+// @Description Replaces the episodes of a series with a list of videos of the organization, each with an optional season_number and
+// @Description episode_number. A missing season continues that of the previous entry, starting at 1, and a missing episode number
+// @Description follows the previous episode of the season, so a plain list of video IDs numbers them in order. Season 0 is meant for
+// @Description specials. A video can be an episode of one series only.
+// @Tags series
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param request body seriesEpisodesRequest true "Videos with their optional season and episode numbers"
+// @Success 200 {object} SuccessResponse{data=models.Series} "Episodes updated, with the series"
+// @Failure 400 {object} ErrorResponse "Invalid episodes"
+// @Failure 404 {object} ErrorResponse "Series not found"
+// @Router /api/v1/series/{id}/episodes [put]
+func (h *SeriesHandler) SetSeriesEpisodes(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	var req seriesEpisodesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	specs := make([]services.EpisodeSpec, len(req.Episodes))
+	for i, e := range req.Episodes {
+		specs[i] = services.EpisodeSpec{VideoID: e.VideoID, SeasonNumber: e.SeasonNumber, EpisodeNumber: e.EpisodeNumber}
+	}
+	episodes, err := services.NumberEpisodes(specs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var series *models.Series
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		series, err = services.ScanSeries(tx.QueryRowContext(ctx, `
+			UPDATE series SET version = version + 1 WHERE id = $1
+			RETURNING `+services.SeriesColumns, seriesID))
+		if err != nil {
+			return err
+		}
+		if err := services.SetEpisodes(ctx, tx, series, episodes); err != nil {
+			return err
+		}
+		series.Episodes, err = services.ListEpisodes(ctx, tx, seriesID)
+		return err
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	case errors.Is(err, services.ErrInvalidEpisodes):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to set episodes of series %s: %v", seriesID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update episodes"})
+		return
+	}
+
+	c.Header("ETag", resourceETag(series.ID, series.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Episodes updated",
+		"data":    series,
+	})
+}
+
+// UploadSeriesArtwork godoc
+// @Summary Upload series artwork
+// @Description Sets a series' artwork from a JPEG, PNG or WebP file, sent as the raw body or as the file field of a multipart form.
+// @Description The image is cropped to 16:9 around its center, resized to the configured widths and stripped of metadata. It is served at /images/series/{id}/artwork.
+// @Tags series
+// @Security ApiKeyAuth
+// @Accept image/jpeg,image/png,image/webp,multipart/form-data
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} SuccessResponse{data=models.Series} "Artwork updated"
+// @Failure 400 {object} ErrorResponse "Invalid image"
+// @Failure 404 {object} ErrorResponse "Series not found"
+// @Failure 413 {object} ErrorResponse "Image too large"
+// @Failure 415 {object} ErrorResponse "Unsupported image format"
+// @Router /api/v1/series/{id}/artwork [put]
+func (h *SeriesHandler) UploadSeriesArtwork(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var orgID uuid.UUID
+	if err := tenantDB.QueryRowContext(ctx, `SELECT organization_id FROM series WHERE id = $1`, seriesID).Scan(&orgID); err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get series"})
+		}
+		return
+	}
+
+	artwork, ok := storeImageUpload(c, h.storage, h.processor, h.config,
+		images.Spec{Widths: h.config.ArtworkWidths, Aspect: artworkAspect}, fmt.Sprintf("orgs/%s/series/%s/artwork", orgID, seriesID))
+	if !ok {
+		return
+	}
+
+	series, previous, err := h.setArtwork(ctx, tenantDB, seriesID, artwork)
+	if err != nil {
+		images.Delete(context.WithoutCancel(ctx), h.storage, artwork)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+			return
+		}
+		logger.Error("Failed to set artwork of series %s: %v", seriesID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update artwork"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.Header("ETag", resourceETag(series.ID, series.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Artwork updated",
+		"data":    series,
+	})
+}
+
+// DeleteSeriesArtwork godoc
+// @Summary Remove series artwork
+// @Description Deletes a series' artwork
+// @Tags series
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Series ID"
+// @Success 200 {object} SuccessResponse{data=models.Series} "Artwork removed"
+// @Failure 404 {object} ErrorResponse "Series or artwork not found"
+// @Router /api/v1/series/{id}/artwork [delete]
+func (h *SeriesHandler) DeleteSeriesArtwork(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	series, previous, err := h.setArtwork(ctx, tenantDB, seriesID, nil)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to remove artwork of series %s: %v", seriesID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove artwork"})
+		return
+	}
+	if previous == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series has no artwork"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.Header("ETag", resourceETag(series.ID, series.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Artwork removed",
+		"data":    series,
+	})
+}
+
+// SeriesArtwork godoc
+// @Summary Series artwork
+// @Description Serves a series' artwork in the best format the Accept header allows. Links with ?v= set to the artwork's ID are cacheable indefinitely.
+// @Tags series
+// @Produce image/avif,image/webp,image/jpeg,image/png
+// @Param id path string true "Series ID"
+// @Param w query int false "Smallest acceptable width in pixels"
+// @Param v query string false "Artwork ID the link was made for"
+// @Success 200 {file} file "Image data"
+// @Failure 404 {object} ErrorResponse "Artwork not found"
+// @Router /images/series/{id}/artwork [get]
+func (h *SeriesHandler) SeriesArtwork(c *gin.Context) {
+	seriesID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	var artwork *models.Image
+	err = h.db.QueryRowContext(c.Request.Context(), `SELECT artwork FROM series WHERE id = $1`, seriesID).Scan(&artwork)
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error("Failed to load artwork of series %s: %v", seriesID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load image"})
+		return
+	}
+	if artwork == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+	serveImage(c, h.storage, h.cache, artwork, true)
+}
+
+// setArtwork swaps the series' artwork (nil to remove it) and its storage object records in
+// one transaction, returning the updated series and the previous artwork
+func (h *SeriesHandler) setArtwork(ctx context.Context, tenantDB *database.StatelessTenantDB, seriesID uuid.UUID,
+	artwork *models.Image) (*models.Series, *models.Image, error) {
+	var series *models.Series
+	var previous *models.Image
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, `SELECT artwork FROM series WHERE id = $1 FOR UPDATE`, seriesID).Scan(&previous); err != nil {
+			return err
+		}
+		if artwork == nil && previous == nil {
+			var err error
+			series, err = services.ScanSeries(tx.QueryRowContext(ctx, `SELECT `+services.SeriesColumns+` FROM series WHERE id = $1`, seriesID))
+			return err
+		}
+
+		var err error
+		series, err = services.ScanSeries(tx.QueryRowContext(ctx, `
+			UPDATE series SET artwork = $1, version = version + 1 WHERE id = $2
+			RETURNING `+services.SeriesColumns,
+			artwork, seriesID))
+		if err != nil {
+			return err
+		}
+
+		if previous != nil {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = ANY($1)`,
+				pq.Array(previous.Keys())); err != nil {
+				return err
+			}
+		}
+		if artwork != nil {
+			for _, v := range artwork.Variants {
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO storage_objects (organization_id, object_key, kind, size_bytes)
+					VALUES ($1, $2, $3, $4)
+					ON CONFLICT (object_key) DO NOTHING
+				`, series.OrganizationID, v.Key, models.ObjectKindImage, v.SizeBytes); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return series, previous, nil
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"openvdo/internal/analytics"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ShareLinkHandler manages share links of videos and serves the pages they open. A link plays
+// its video whatever the video's visibility, so sharing a private video needs no playback
+// token in the URL, and counts its views apart from other playbacks.
+type ShareLinkHandler struct {
+	embed    *EmbedHandler
+	ingester *analytics.Ingester
+}
+
+// NewShareLinkHandler creates a share link handler that renders pages with the embedded player
+func NewShareLinkHandler(embed *EmbedHandler, ingester *analytics.Ingester) *ShareLinkHandler {
+	return &ShareLinkHandler{embed: embed, ingester: ingester}
+}
+
+type createShareLinkRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+	// MaxViews limits how many times the link may be opened
+	MaxViews      *int `json:"max_views" binding:"omitempty,min=1"`
+	AllowDownload bool `json:"allow_download"`
+	AllowComments bool `json:"allow_comments"`
+}
+
+// shareLink is a share link with its URL
+type shareLink struct {
+	models.ShareLink
+	URL    string `json:"url"`
+	Active bool   `json:"active"`
+}
+
+func (h *ShareLinkHandler) withURL(c *gin.Context, link *models.ShareLink) shareLink {
+	return shareLink{ShareLink: *link, URL: h.embed.baseURL(c) + "/s/" + link.Slug, Active: link.Active(time.Now())}
+}
+
+// CreateShareLink godoc
+// @Summary Create a share link
+// @Description Creates a public link that plays the video under a random slug, whatever the video's visibility. The link may expire, allow a number of views, and allow viewers to download the source or comment.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body createShareLinkRequest false "Limits and permissions of the link"
+// @Success 201 {object} SuccessResponse{data=shareLink} "Share link created"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/share-links [post]
+func (h *ShareLinkHandler) CreateShareLink(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+	var req createShareLinkRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	slug, err := services.NewShareSlug()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	link, err := services.ScanShareLink(tenantDB.QueryRowContext(c.Request.Context(), `
+		INSERT INTO share_links (video_id, organization_id, created_by, slug, expires_at, max_views, allow_download, allow_comments)
+		SELECT id, organization_id, $2, $3, $4, $5, $6, $7 FROM videos WHERE id = $1 AND status <> $8
+		RETURNING `+services.ShareLinkColumns,
+		videoID, tenantDB.GetUserID(), slug, req.ExpiresAt, req.MaxViews, req.AllowDownload, req.AllowComments,
+		models.VideoStatusDeleted))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create share link of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Share link created",
+		"data":    h.withURL(c, link),
+	})
+}
+
+// ListShareLinks godoc
+// @Summary List share links
+// @Description Lists the share links of a video, newest first, including revoked and expired ones with their view counts.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{share_links=[]shareLink}} "Share links retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Router /api/v1/videos/{id}/share-links [get]
+func (h *ShareLinkHandler) ListShareLinks(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(c.Request.Context(), `
+		SELECT `+services.ShareLinkColumns+` FROM share_links
+		WHERE video_id = $1
+		ORDER BY created_at DESC`, videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query share links"})
+		return
+	}
+	defer rows.Close()
+
+	links := []shareLink{}
+	for rows.Next() {
+		link, err := services.ScanShareLink(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan share link"})
+			return
+		}
+		links = append(links, h.withURL(c, link))
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing share link results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Share links retrieved",
+		"data":    gin.H{"share_links": links},
+	})
+}
+
+// RevokeShareLink godoc
+// @Summary Revoke a share link
+// @Description Stops a share link from working. Other links of the video keep working, and the link's views stay in analytics.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param link_id path string true "Share link ID"
+// @Success 200 {object} SuccessResponse{data=shareLink} "Share link revoked"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Share link not found"
+// @Router /api/v1/videos/{id}/share-links/{link_id} [delete]
+func (h *ShareLinkHandler) RevokeShareLink(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+	linkID, err := uuid.Parse(c.Param("link_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+		return
+	}
+
+	link, err := services.ScanShareLink(tenantDB.QueryRowContext(c.Request.Context(), `
+		UPDATE share_links SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1 AND video_id = $2
+		RETURNING `+services.ShareLinkColumns, linkID, videoID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to revoke share link %s: %v", linkID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Share link revoked",
+		"data":    h.withURL(c, link),
+	})
+}
+
+// Share godoc
+// @Summary Shared video page
+// @Description Serves the player page of a share link and counts a view. The page reports playbacks with the link's ID, and offers a download when the link allows it.
+// @Tags embed
+// @Produce html
+// @Param slug path string true "Share link slug"
+// @Success 200 {string} string "Player page"
+// @Failure 404 {string} string "Unknown link"
+// @Failure 410 {string} string "Link revoked, expired or out of views"
+// @Router /s/{slug} [get]
+func (h *ShareLinkHandler) Share(c *gin.Context) {
+	link, ok := h.link(c, services.ViewShareLink)
+	if !ok {
+		return
+	}
+	video, ok := h.sharedVideo(c, link)
+	if !ok {
+		return
+	}
+	domains, err := services.EmbedDomains(c.Request.Context(), h.embed.db, video.OrganizationID)
+	if err != nil {
+		logger.Error("Failed to load embed domains of video %s: %v", video.ID, err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	// The link stands in for the playback token of private videos
+	token := ""
+	if video.Visibility == models.VideoVisibilityPrivate {
+		if h.embed.config.SigningKey == "" {
+			c.Data(http.StatusServiceUnavailable, "text/html; charset=utf-8", []byte("This video cannot be shared"))
+			return
+		}
+		token = services.SignPlaybackToken(h.embed.config.SigningKey, video.ID, time.Now().Add(h.embed.config.TokenTTL))
+	}
+	h.embed.servePlayer(c, video, token, domains, link)
+}
+
+// ShareDownload godoc
+// @Summary Download a shared video
+// @Description Serves the source of the video of a share link that allows downloads, as an attachment. Downloads are not views, and are recorded in analytics with the link's ID.
+// @Tags embed
+// @Produce octet-stream
+// @Param slug path string true "Share link slug"
+// @Success 200 {file} file "Video source"
+// @Success 206 {file} file "Part of the video source"
+// @Failure 404 {string} string "Unknown link, or downloads not allowed"
+// @Failure 410 {string} string "Link revoked, expired or out of views"
+// @Router /s/{slug}/download [get]
+func (h *ShareLinkHandler) ShareDownload(c *gin.Context) {
+	link, ok := h.link(c, services.ActiveShareLink)
+	if !ok {
+		return
+	}
+	video, ok := h.sharedVideo(c, link)
+	if !ok {
+		return
+	}
+	if !link.AllowDownload || !shareDownloadable(video) {
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte("This video cannot be downloaded"))
+		return
+	}
+
+	rc, err := openObject(c, h.embed.storage, video.SourceKey)
+	if err != nil {
+		logger.Error("Failed to open source of video %s: %v", video.ID, err)
+		c.AbortWithStatus(http.StatusBadGateway)
+		return
+	}
+	defer rc.Close()
+
+	recordDownload(c, h.ingester, video.OrganizationID, video.ID, link.ID.String(), &link.ID)
+	h.embed.egress.Shape(c, video.OrganizationID)
+	h.embed.cache.NoStore.Apply(c)
+	if video.ContentType != "" {
+		c.Header("Content-Type", video.ContentType)
+	}
+	c.Header("Content-Disposition", attachment(video.Title, path.Ext(video.SourceKey)))
+	serveFile(c, rc, fmt.Sprintf(`"%s-%d"`, video.ID, video.SourceRevision), video.UpdatedAt)
+}
+
+// link looks up the share link in the slug path parameter with lookup, writing the error page
+// when it does not work
+func (h *ShareLinkHandler) link(c *gin.Context, lookup func(context.Context, database.Querier, string) (*models.ShareLink, error)) (*models.ShareLink, bool) {
+	link, err := lookup(c.Request.Context(), h.embed.db, c.Param("slug"))
+	switch {
+	case err == sql.ErrNoRows:
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte("Video not found"))
+		return nil, false
+	case errors.Is(err, services.ErrShareLinkUnavailable):
+		c.Data(http.StatusGone, "text/html; charset=utf-8", []byte("This link is no longer available"))
+		return nil, false
+	case err != nil:
+		logger.Error("Failed to look up share link %s: %v", c.Param("slug"), err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return nil, false
+	}
+	return link, true
+}
+
+// sharedVideo loads the video of a share link. Videos taken down, deleted or not approved, or of
+// deleted organizations, are not found.
+func (h *ShareLinkHandler) sharedVideo(c *gin.Context, link *models.ShareLink) (*models.Video, bool) {
+	video, err := h.embed.loadVideo(c.Request.Context(), link.VideoID.String())
+	if err != nil && err != sql.ErrNoRows {
+		logger.Error("Failed to load video %s of share link %s: %v", link.VideoID, link.ID, err)
+	}
+	if err != nil || video.Status == models.VideoStatusDeleted || video.ModerationStatus != models.ModerationActive ||
+		video.ReviewStatus != models.ReviewApproved {
+		c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte("Video not found"))
+		return nil, false
+	}
+	return video, true
+}
+
+// shareDownloadable tells whether the source of a video is a file that can be downloaded, as
+// opposed to a playlist
+func shareDownloadable(video *models.Video) bool {
+	return models.VideoPlayable(video.Status) && video.SourceKey != "" && video.ContentType != hlsContentType
+}
@@ -0,0 +1,373 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SmartPlaylistHandler manages smart playlists and lists the videos their criteria select
+type SmartPlaylistHandler struct {
+	playlists *services.SmartPlaylists
+}
+
+// NewSmartPlaylistHandler creates a new smart playlist handler
+func NewSmartPlaylistHandler(playlists *services.SmartPlaylists) *SmartPlaylistHandler {
+	return &SmartPlaylistHandler{playlists: playlists}
+}
+
+// ListSmartPlaylists godoc
+// @Summary List smart playlists
+// @Description Lists the smart playlists of the current organization, newest first, without their videos
+// @Tags playlists
+// @Security ApiKeyAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size (max 100)" default(20)
+// @Success 200 {object} SuccessResponse{data=object{playlists=[]models.SmartPlaylist,pagination=response.Pagination}} "Smart playlists retrieved"
+// @Router /api/v1/playlists [get]
+func (h *SmartPlaylistHandler) ListSmartPlaylists(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	ctx := c.Request.Context()
+	var total int
+	if err := tenantDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM smart_playlists`).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count smart playlists"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(ctx, `
+		SELECT `+services.SmartPlaylistColumns+` FROM smart_playlists
+		ORDER BY created_at DESC LIMIT $1 OFFSET $2
+	`, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query smart playlists"})
+		return
+	}
+	defer rows.Close()
+
+	playlists := []models.SmartPlaylist{}
+	for rows.Next() {
+		p, err := services.ScanSmartPlaylist(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan smart playlist"})
+			return
+		}
+		playlists = append(playlists, *p)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing smart playlist results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Smart playlists retrieved successfully",
+		"data": gin.H{
+			"playlists": playlists,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+			},
+		},
+	})
+}
+
+type createSmartPlaylistRequest struct {
+	Title       string                  `json:"title" binding:"required,min=1,max=255"`
+	Description string                  `json:"description" binding:"max=10000"`
+	Criteria    models.PlaylistCriteria `json:"criteria"`
+	// Sort is newest (the default), oldest or title
+	Sort     string `json:"sort" binding:"omitempty,oneof=newest oldest title"`
+	MaxItems int    `json:"max_items" binding:"omitempty,min=1,max=500"`
+}
+
+// CreateSmartPlaylist godoc
+// @Summary Create smart playlist
+// @Description Saves search criteria as a smart playlist of the current organization. Its videos are those with a source to play that match all criteria: query (text in the title or description), project_id, tags (all of them), visibility,
+// @Description fields, fields_min and fields_max (custom field values, like the field filters of video lists), created_after and created_before. They are computed when the playlist is read, up to max_items (100 by default, at most 500).
+// @Tags playlists
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body createSmartPlaylistRequest true "Title, criteria and order"
+// @Success 201 {object} SuccessResponse{data=models.SmartPlaylist} "Smart playlist created"
+// @Failure 400 {object} ErrorResponse "Invalid request or criteria"
+// @Router /api/v1/playlists [post]
+func (h *SmartPlaylistHandler) CreateSmartPlaylist(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req createSmartPlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Sort == "" {
+		req.Sort = models.PlaylistSortNewest
+	}
+	if req.MaxItems == 0 {
+		req.MaxItems = 100
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+	err = services.CheckPlaylistCriteria(ctx, tenantDB, session.OrgID, &req.Criteria)
+	if errors.Is(err, services.ErrInvalidPlaylistCriteria) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check criteria"})
+		return
+	}
+
+	playlist, err := services.ScanSmartPlaylist(tenantDB.QueryRowContext(ctx, `
+		INSERT INTO smart_playlists (organization_id, title, description, criteria, sort, max_items, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+services.SmartPlaylistColumns,
+		session.OrgID, req.Title, req.Description, req.Criteria, req.Sort, req.MaxItems, session.UserID))
+	if err != nil {
+		logger.Error("Failed to create smart playlist in org %s: %v", session.OrgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create smart playlist"})
+		return
+	}
+
+	c.Header("ETag", resourceETag(playlist.ID, playlist.UpdatedAt))
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Smart playlist created",
+		"data":    playlist,
+	})
+}
+
+// GetSmartPlaylist godoc
+// @Summary Get smart playlist
+// @Description Retrieves a smart playlist with the videos its criteria select, in its order. Which videos match is cached for FEEDS_PLAYLIST_CACHE_TTL, so newly matching videos can take that long to appear; changing the playlist takes effect at once.
+// @Tags playlists
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Smart playlist ID"
+// @Success 200 {object} SuccessResponse{data=models.SmartPlaylist} "Smart playlist retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid smart playlist ID"
+// @Failure 404 {object} ErrorResponse "Smart playlist not found"
+// @Router /api/v1/playlists/{id} [get]
+func (h *SmartPlaylistHandler) GetSmartPlaylist(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	playlistID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid smart playlist ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	playlist, err := services.ScanSmartPlaylist(tenantDB.QueryRowContext(ctx,
+		`SELECT `+services.SmartPlaylistColumns+` FROM smart_playlists WHERE id = $1`, playlistID))
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Smart playlist not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get smart playlist"})
+		return
+	}
+	if playlist.Videos, err = h.playlists.Videos(ctx, tenantDB, playlist); err != nil {
+		logger.Error("Failed to compute videos of smart playlist %s: %v", playlistID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get videos"})
+		return
+	}
+
+	// The ETag is for If-Match on updates; it does not cover the videos
+	c.Header("ETag", resourceETag(playlist.ID, playlist.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Smart playlist retrieved successfully",
+		"data":    playlist,
+	})
+}
+
+type updateSmartPlaylistRequest struct {
+	Version     *int64  `json:"version" binding:"required"`
+	Title       *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description" binding:"omitempty,max=10000"`
+	// Criteria replaces all criteria of the playlist
+	Criteria *models.PlaylistCriteria `json:"criteria"`
+	Sort     *string                  `json:"sort" binding:"omitempty,oneof=newest oldest title"`
+	MaxItems *int                     `json:"max_items" binding:"omitempty,min=1,max=500"`
+}
+
+// UpdateSmartPlaylist godoc
+// @Summary Update smart playlist
+// @Description Updates the title, description, criteria, order or maximum number of videos of a smart playlist; criteria are replaced as a whole. The request must carry
+// @Description the version it is based on; if the playlist was changed since, the update is rejected with 409 and the current state.
+// @Tags playlists
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Smart playlist ID"
+// @Param If-Match header string false "ETag the update is based on"
+// @Param request body updateSmartPlaylistRequest true "Expected version and fields to change"
+// @Success 200 {object} SuccessResponse{data=models.SmartPlaylist} "Smart playlist updated"
+// @Failure 400 {object} ErrorResponse "Invalid request or criteria"
+// @Failure 404 {object} ErrorResponse "Smart playlist not found"
+// @Failure 409 {object} ErrorResponse{data=models.SmartPlaylist} "Version conflict, with the current smart playlist"
+// @Failure 412 {object} ErrorResponse "Smart playlist was modified"
+// @Router /api/v1/playlists/{id} [patch]
+func (h *SmartPlaylistHandler) UpdateSmartPlaylist(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	playlistID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid smart playlist ID"})
+		return
+	}
+
+	var req updateSmartPlaylistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	current, err := services.ScanSmartPlaylist(tenantDB.QueryRowContext(ctx,
+		`SELECT `+services.SmartPlaylistColumns+` FROM smart_playlists WHERE id = $1`, playlistID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Smart playlist not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get smart playlist"})
+		}
+		return
+	}
+	if preconditionFailed(c, resourceETag(current.ID, current.UpdatedAt)) {
+		return
+	}
+	if current.Version != *req.Version {
+		versionConflict(c, current)
+		return
+	}
+	var criteria interface{}
+	if req.Criteria != nil {
+		err := services.CheckPlaylistCriteria(ctx, tenantDB, current.OrganizationID, req.Criteria)
+		if errors.Is(err, services.ErrInvalidPlaylistCriteria) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check criteria"})
+			return
+		}
+		criteria = *req.Criteria
+	}
+
+	// Matching the version makes the check hold even against a concurrent update
+	playlist, err := services.ScanSmartPlaylist(tenantDB.QueryRowContext(ctx, `
+		UPDATE smart_playlists
+		SET title = COALESCE($2, title), description = COALESCE($3, description),
+			criteria = COALESCE($5::jsonb, criteria), sort = COALESCE($6, sort), max_items = COALESCE($7, max_items),
+			version = version + 1
+		WHERE id = $1 AND version = $4
+		RETURNING `+services.SmartPlaylistColumns,
+		playlistID, req.Title, req.Description, *req.Version, criteria, req.Sort, req.MaxItems))
+	if err == sql.ErrNoRows {
+		if current, err := services.ScanSmartPlaylist(tenantDB.QueryRowContext(ctx,
+			`SELECT `+services.SmartPlaylistColumns+` FROM smart_playlists WHERE id = $1`, playlistID)); err == nil {
+			versionConflict(c, current)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Smart playlist not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update smart playlist %s: %v", playlistID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update smart playlist"})
+		return
+	}
+
+	c.Header("ETag", resourceETag(playlist.ID, playlist.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Smart playlist updated successfully",
+		"data":    playlist,
+	})
+}
+
+// DeleteSmartPlaylist godoc
+// @Summary Delete smart playlist
+// @Description Deletes a smart playlist and its feeds. The videos it listed are kept.
+// @Tags playlists
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Smart playlist ID"
+// @Success 200 {object} SuccessResponse "Smart playlist deleted"
+// @Failure 400 {object} ErrorResponse "Invalid smart playlist ID"
+// @Failure 404 {object} ErrorResponse "Smart playlist not found"
+// @Router /api/v1/playlists/{id} [delete]
+func (h *SmartPlaylistHandler) DeleteSmartPlaylist(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	playlistID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid smart playlist ID"})
+		return
+	}
+
+	result, err := tenantDB.ExecContext(c.Request.Context(), `DELETE FROM smart_playlists WHERE id = $1`, playlistID)
+	if err != nil {
+		logger.Error("Failed to delete smart playlist %s: %v", playlistID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete smart playlist"})
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Smart playlist not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Smart playlist deleted",
+	})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SourceVersionHandler lists the sources videos had before they were replaced and rolls
+// videos back to them
+type SourceVersionHandler struct {
+	config config.Storage
+	purger *services.CachePurger
+}
+
+// NewSourceVersionHandler creates a new source version handler
+func NewSourceVersionHandler(cfg config.Storage, purger *services.CachePurger) *SourceVersionHandler {
+	return &SourceVersionHandler{config: cfg, purger: purger}
+}
+
+// ListSourceVersions godoc
+// @Summary List a video's source versions
+// @Description Lists the sources the video had before it was replaced, newest first, with the renditions made from each. Versions are kept for STORAGE_SOURCE_VERSION_RETENTION, at most STORAGE_SOURCE_VERSIONS_MAX per video, and archived like other sources while they are kept.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{source_revision=int,versions=[]models.SourceVersion}} "Source versions"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/source-versions [get]
+func (h *SourceVersionHandler) ListSourceVersions(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	revision, err := queries.New(tenantDB).GetVideoSourceRevision(ctx, videoID)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		return
+	}
+
+	versions, err := services.ListSourceVersions(ctx, tenantDB, videoID)
+	if err != nil {
+		logger.Error("Failed to list source versions of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query source versions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Source versions retrieved successfully",
+		"data": gin.H{
+			"source_revision": revision,
+			"versions":        versions,
+		},
+	})
+}
+
+// RollbackVideoSource godoc
+// @Summary Roll a video back to an earlier source
+// @Description Makes a kept source version the video's source again, with the renditions it had, so the video is ready without transcoding. The source it replaces is kept as a version in turn, so the rollback can be undone, and the source revision goes up like on a replacement.
+// @Description CDN caches of the playback URLs are purged when a purge webhook is configured. Versions in cold storage are restored through the video's storage first.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param revision path int true "Source revision of the version"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Video rolled back"
+// @Failure 400 {object} ErrorResponse "Invalid video ID or revision"
+// @Failure 404 {object} ErrorResponse "Video or source version not found"
+// @Failure 409 {object} ErrorResponse "Video is uploading or was deleted, or the version is archived"
+// @Router /api/v1/videos/{id}/source-versions/{revision}/rollback [post]
+func (h *SourceVersionHandler) RollbackVideoSource(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source revision"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+
+	var video *models.Video
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		video, err = services.RollbackSource(ctx, tx, videoID, revision, &session.UserID, h.config.SourceVersionRetention)
+		if err != nil {
+			return err
+		}
+		if err := outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoSourceRolledBack, video)); err != nil {
+			return err
+		}
+		if h.purger.Enabled() {
+			_, err = jobs.Enqueue(ctx, tx, services.JobKindCDNPurge,
+				services.PurgePayload{VideoID: &videoID, Paths: services.PlaybackPaths(videoID)},
+				jobs.Options{OrganizationID: &video.OrganizationID, CreatedBy: &session.UserID})
+		}
+		return err
+	})
+	switch {
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	case errors.Is(err, services.ErrSourceVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Source version not found"})
+		return
+	case errors.Is(err, services.ErrSourceVersionArchived):
+		c.JSON(http.StatusConflict, gin.H{"error": "Source version is archived; restore the video's storage first"})
+		return
+	case errors.Is(err, services.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": "Video is still uploading or was deleted"})
+		return
+	case err != nil:
+		logger.Error("Failed to roll back source of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to roll back video source"})
+		return
+	}
+	logger.Info("Rolled back video %s to source revision %d (now revision %d)", videoID, revision, video.SourceRevision)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Video source rolled back",
+		"data":    video,
+	})
+}
@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,13 +19,17 @@ import (
 
 // StatelessGetOrganizations godoc
 // @Summary Get user organizations
-// @Description Retrieves all organizations for the authenticated user using stateless connection pooling with RLS filtering
+// @Description Retrieves all organizations for the authenticated user using stateless connection pooling with RLS filtering.
+// @Description With Accept: application/x-ndjson all organizations are streamed as one JSON object per line, without pagination.
 // @Tags organizations
 // @Security ApiKeyAuth
 // @Produce json
-// @Success 200 {object} map[string]interface{} "Organizations retrieved successfully"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Produce application/x-ndjson
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} SuccessResponse{data=object{organizations=[]models.Organization,pagination=response.Pagination,pool_type=string}} "Organizations retrieved successfully"
+// @Success 304 "Not modified"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/organizations [get]
 func StatelessGetOrganizations(c *gin.Context) {
 	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
@@ -32,85 +43,73 @@ func StatelessGetOrganizations(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	offset := (page - 1) * limit
 
-	query := `
-		SELECT id, name, description, created_at, updated_at
-		FROM organizations
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-
-	rows, err := tenantDB.QueryContext(c.Request.Context(), query, limit, offset)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query organizations"})
-		return
-	}
-	defer rows.Close()
-
-	var organizations []gin.H
-	for rows.Next() {
-		var org struct {
-			ID          uuid.UUID `json:"id"`
-			Name        string    `json:"name"`
-			Description string    `json:"description"`
-			CreatedAt   string    `json:"created_at"`
-			UpdatedAt   string    `json:"updated_at"`
-		}
-
-		if err := rows.Scan(&org.ID, &org.Name, &org.Description, &org.CreatedAt, &org.UpdatedAt); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan organization"})
+	if wantsNDJSON(c) {
+		// Streamed rather than loaded at once, so the generated statement is scanned row by row
+		rows, err := tenantDB.QueryContext(c.Request.Context(), queries.ListOrganizations)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query organizations"})
 			return
 		}
-
-		organizations = append(organizations, gin.H{
-			"id":          org.ID,
-			"name":        org.Name,
-			"description": org.Description,
-			"created_at":  org.CreatedAt,
-			"updated_at":  org.UpdatedAt,
+		streamNDJSON(c, rows, func(rows *sql.Rows) (interface{}, error) {
+			return services.OrganizationModel(queries.ScanRow[queries.Organization](rows))
 		})
+		return
 	}
 
-	if err := rows.Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing organization results"})
+	organizations, err := services.OrganizationModels(queries.New(tenantDB).ListOrganizationsPage(c.Request.Context(),
+		queries.ListOrganizationsPageParams{Limit: int32(limit), Offset: int32(offset)}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query organizations"})
 		return
 	}
 
-	// Get total count for pagination
-	var total int
-	countQuery := "SELECT COUNT(*) FROM organizations"
-	if err := tenantDB.QueryRowContext(c.Request.Context(), countQuery).Scan(&total); err != nil {
+	// Get total count for pagination, from the count cache
+	total, err := tenantDB.Count(c.Request.Context(), database.OrganizationCount(tenantDB.GetUserID()))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
 		return
 	}
 
+	if notModified(c, listETag(page, limit, total.Total, organizations)) {
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":  "success",
 		"message": "Organizations retrieved successfully (stateless)",
 		"data": gin.H{
 			"organizations": organizations,
 			"pagination": gin.H{
-				"page":  page,
-				"limit": limit,
-				"total": total,
+				"page":            page,
+				"limit":           limit,
+				"total":           total.Total,
+				"total_estimated": total.Estimated,
 			},
 			"pool_type": "stateless",
 		},
 	})
 }
 
+type createOrganizationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	// ExternalID is the organization's ID in an external system, unique among organizations
+	ExternalID *string `json:"external_id" binding:"omitempty,min=1,max=255"`
+}
+
 // StatelessCreateOrganization godoc
 // @Summary Create organization
-// @Description Creates a new organization using stateless connection pooling
+// @Description Creates a new organization using stateless connection pooling. Its name, and an external ID such as the organization's ID in a CMS, must not be used by another organization; if they are, the 409 carries the code already_exists.
 // @Tags organizations
 // @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param name body string true "Organization name"
-// @Param description body string false "Organization description"
-// @Success 201 {object} map[string]interface{} "Organization created successfully"
-// @Failure 400 {object} map[string]string "Invalid request body"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Param request body createOrganizationRequest true "Organization name, description and external ID"
+// @Success 201 {object} SuccessResponse{data=object{id=string,name=string,created_at=string,updated_at=string,pool_type=string}} "Organization created successfully"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 409 {object} ErrorResponse "Name or external ID already used by another organization"
+// @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/organizations [post]
 func StatelessCreateOrganization(c *gin.Context) {
 	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
@@ -119,53 +118,269 @@ func StatelessCreateOrganization(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
-	}
-
+	var req createOrganizationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 		return
 	}
 
-	// Simple query for now
-	query := `
-		INSERT INTO organizations (name, description)
-		VALUES ($1, $2)
-		RETURNING id, created_at
-	`
-
-	var newID uuid.UUID
-	var createdAt string
-	err := tenantDB.QueryRowContext(c.Request.Context(), query, req.Name, req.Description).Scan(&newID, &createdAt)
+	created, err := queries.New(tenantDB).InsertOrganization(c.Request.Context(), queries.InsertOrganizationParams{
+		Name: req.Name, Description: &req.Description, ExternalID: req.ExternalID,
+	})
+	if databaseError(c, err) {
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
 		return
 	}
+	tenantDB.InvalidateCount(c.Request.Context(), database.OrganizationCount(tenantDB.GetUserID()))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"status":  "success",
 		"message": "Organization created successfully (stateless)",
 		"data": gin.H{
-			"id":        newID,
-			"name":      req.Name,
-			"created_at": createdAt,
-			"pool_type": "stateless",
+			"id":         created.ID,
+			"name":       req.Name,
+			"created_at": created.CreatedAt,
+			"updated_at": created.UpdatedAt,
+			"pool_type":  "stateless",
 		},
 	})
 }
 
+// StatelessGetOrganization godoc
+// @Summary Get organization
+// @Description Retrieves an organization of the authenticated user. The response carries an ETag for conditional requests.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} SuccessResponse{data=models.Organization} "Organization retrieved"
+// @Success 304 "Not modified"
+// @Failure 400 {object} ErrorResponse "Invalid organization ID"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id} [get]
+func StatelessGetOrganization(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	org, err := services.OrganizationModel(queries.New(tenantDB).GetOrganization(c.Request.Context(), orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization"})
+		}
+		return
+	}
+
+	if notModified(c, resourceETag(org.ID, org.UpdatedAt)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Organization retrieved successfully",
+		"data":    org,
+	})
+}
+
+type updateOrganizationRequest struct {
+	Version     *int64          `json:"version" binding:"required"`
+	Name        *string         `json:"name" binding:"omitempty,min=1,max=255"`
+	Description *string         `json:"description"`
+	Settings    json.RawMessage `json:"settings" swaggertype:"object"`
+	// PlaybackDomains replaces the list of origins; send [] to clear it
+	PlaybackDomains *[]string `json:"playback_domains" binding:"omitempty,max=50"`
+	// EmbedDomains replaces the domains the player may be embedded on; send [] to allow any
+	EmbedDomains *[]string `json:"embed_domains" binding:"omitempty,max=100"`
+	// VASTTagURL replaces the ad tag; send "" to remove it
+	VASTTagURL *string `json:"vast_tag_url"`
+	// RequireReview holds new videos of members other than owners and admins for approval
+	RequireReview *bool `json:"require_review"`
+	// ExternalID replaces the organization's ID in an external system; send "" to remove it
+	ExternalID *string `json:"external_id" binding:"omitempty,max=255"`
+}
+
+// StatelessUpdateOrganization godoc
+// @Summary Update organization
+// @Description Updates the name, description, settings, playback and embed domains or VAST ad tag of an organization. Requires the owner or admin role.
+// @Description The ip_access key of settings is managed through the IP access endpoint and kept as is.
+// @Description Playback domains are origins such as https://www.example.com that may read the organization's videos cross-origin.
+// @Description Embed domains restrict the sites the player may be framed on and playback tokens requested from, as CSP host sources such as https://*.example.com.
+// @Description The VAST tag URL is handed to players with the ad breaks of its videos; [VIDEO_ID] in it is replaced, and an empty string removes it.
+// @Description With require_review, videos created by developers wait for an owner or admin to approve them before they play outside the organization. Turning it off leaves waiting videos in review.
+// @Description External IDs are unique among organizations; an empty one removes it.
+// @Description The request must carry the version it is based on; if the organization was changed since, the update is rejected with 409 and the current state. If-Match with a previous ETag is also honored.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param If-Match header string false "ETag the update is based on"
+// @Param request body updateOrganizationRequest true "Expected version and fields to change"
+// @Success 200 {object} SuccessResponse{data=models.Organization} "Organization updated"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Failure 409 {object} ErrorResponse{data=models.Organization} "Version conflict, with the current organization, or name or external ID already used"
+// @Failure 412 {object} ErrorResponse "Organization was modified"
+// @Router /api/v1/organizations/{id} [patch]
+func StatelessUpdateOrganization(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req updateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	var settings *string
+	if len(req.Settings) > 0 {
+		if req.Settings[0] != '{' {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "settings must be a JSON object"})
+			return
+		}
+		encoded := string(req.Settings)
+		settings = &encoded
+	}
+	var playbackDomains []string
+	if req.PlaybackDomains != nil {
+		domains := make([]string, 0, len(*req.PlaybackDomains))
+		for _, domain := range *req.PlaybackDomains {
+			origin, err := services.NormalizeOrigin(domain)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid playback domain: " + err.Error()})
+				return
+			}
+			domains = append(domains, origin)
+		}
+		playbackDomains = domains
+	}
+	var embedDomains []string
+	if req.EmbedDomains != nil {
+		domains := make([]string, 0, len(*req.EmbedDomains))
+		for _, domain := range *req.EmbedDomains {
+			normalized, err := services.NormalizeEmbedDomain(domain)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid embed domain: " + err.Error()})
+				return
+			}
+			domains = append(domains, normalized)
+		}
+		embedDomains = domains
+	}
+	if req.VASTTagURL != nil {
+		tag, err := services.NormalizeVASTTagURL(*req.VASTTagURL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid VAST tag URL: " + err.Error()})
+			return
+		}
+		req.VASTTagURL = &tag
+	}
+
+	ctx := c.Request.Context()
+	role, err := queries.New(tenantDB).GetMemberRole(ctx, queries.GetMemberRoleParams{
+		UserID: tenantDB.GetUserID(), OrganizationID: orgID,
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check role"})
+		return
+	}
+	if role != models.RoleOwner && role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Updating an organization requires the owner or admin role"})
+		return
+	}
+
+	current, err := services.OrganizationModel(queries.New(tenantDB).GetOrganization(ctx, orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organization"})
+		}
+		return
+	}
+	if preconditionFailed(c, resourceETag(current.ID, current.UpdatedAt)) {
+		return
+	}
+	if current.Version != *req.Version {
+		versionConflict(c, current)
+		return
+	}
+
+	// Matching the version makes the check hold even against a concurrent update. IP access
+	// rules in settings are kept, as they are only changed through their own endpoint.
+	org, err := services.OrganizationModel(queries.New(tenantDB).UpdateOrganization(ctx, queries.UpdateOrganizationParams{
+		Name:            req.Name,
+		Description:     req.Description,
+		Settings:        settings,
+		PlaybackDomains: playbackDomains,
+		VASTTagURL:      req.VASTTagURL,
+		EmbedDomains:    embedDomains,
+		RequireReview:   req.RequireReview,
+		ExternalID:      req.ExternalID,
+		ID:              orgID,
+		Version:         *req.Version,
+	}))
+	if err == sql.ErrNoRows {
+		if current, err := services.OrganizationModel(queries.New(tenantDB).GetOrganization(ctx, orgID)); err == nil {
+			versionConflict(c, current)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+	if databaseError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update organization"})
+		return
+	}
+
+	c.Header("ETag", resourceETag(org.ID, org.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Organization updated successfully",
+		"data":    org,
+	})
+}
+
 // StatelessGetUserSession godoc
 // @Summary Get user session
 // @Description Retrieves the current user's session information including organization and role details
 // @Tags sessions
 // @Security ApiKeyAuth
 // @Produce json
-// @Success 200 {object} map[string]interface{} "User session retrieved"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 404 {object} map[string]string "Session not found"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Success 200 {object} SuccessResponse{data=database.UserSession} "User session retrieved"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Session not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/sessions [get]
 func StatelessGetUserSession(c *gin.Context) {
 	spm, exists := database.GetStatelessPoolManagerFromContext(c)
@@ -204,9 +419,9 @@ func StatelessGetUserSession(c *gin.Context) {
 // @Tags sessions
 // @Security ApiKeyAuth
 // @Produce json
-// @Success 200 {object} map[string]interface{} "Session invalidated"
-// @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 500 {object} map[string]string "Internal server error"
+// @Success 200 {object} SuccessResponse "Session invalidated"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/v1/sessions [delete]
 func StatelessInvalidateSession(c *gin.Context) {
 	spm, exists := database.GetStatelessPoolManagerFromContext(c)
@@ -235,4 +450,73 @@ func StatelessInvalidateSession(c *gin.Context) {
 		"status":  "success",
 		"message": "User session invalidated",
 	})
-}
\ No newline at end of file
+}
+
+type switchOrganizationRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required,uuid"`
+}
+
+// SwitchOrganization godoc
+// @Summary Switch organization
+// @Description Sets the organization the caller's requests act in when they do not send X-Org-ID. Content such as videos is scoped to that organization.
+// @Tags sessions
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body switchOrganizationRequest true "Organization to make the default"
+// @Success 200 {object} SuccessResponse{data=database.UserSession} "Organization switched, with the new session"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Not a member of the organization"
+// @Router /api/v1/sessions/organization [put]
+func SwitchOrganization(c *gin.Context) {
+	spm, exists := database.GetStatelessPoolManagerFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Pool manager not available"})
+		return
+	}
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	var req switchOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	orgID := uuid.MustParse(req.OrganizationID)
+
+	ctx := c.Request.Context()
+	userID := tenantDB.GetUserID()
+	if _, err := spm.ResolveTenant(ctx, userID, orgID); err != nil {
+		if errors.Is(err, database.ErrNotMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of the organization"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+		return
+	}
+
+	if err := queries.New(tenantDB).SetDefaultOrganization(ctx, queries.SetDefaultOrganizationParams{
+		OrganizationID: orgID, ID: userID,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to switch organization"})
+		return
+	}
+	if err := spm.InvalidateUserSession(ctx, userID); err != nil {
+		logger.Error("Failed to invalidate session of user %s: %v", userID, err)
+	}
+
+	session, err := spm.GetUserSession(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Organization switched",
+		"data":    session,
+	})
+}
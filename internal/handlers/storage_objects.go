@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// StorageObjectHandler exposes storage tier status and on-demand restores for videos
+type StorageObjectHandler struct {
+	lifecycle *services.LifecycleManager
+}
+
+// NewStorageObjectHandler creates a new storage object handler
+func NewStorageObjectHandler(lifecycle *services.LifecycleManager) *StorageObjectHandler {
+	return &StorageObjectHandler{lifecycle: lifecycle}
+}
+
+type restoreRequest struct {
+	Kind string `json:"kind"`
+}
+
+// GetVideoStorage godoc
+// @Summary Get video storage status
+// @Description Lists the stored objects of a video with their storage tier and restore status
+// @Tags storage
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{objects=[]models.StorageObject,lifecycle_enabled=bool}} "Storage objects retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/videos/{id}/storage [get]
+func (h *StorageObjectHandler) GetVideoStorage(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	objects, err := listVideoObjects(c, tenantDB, videoID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query storage objects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Storage objects retrieved successfully",
+		"data": gin.H{
+			"objects":           objects,
+			"lifecycle_enabled": h.lifecycle.Enabled(),
+		},
+	})
+}
+
+// RestoreVideoStorage godoc
+// @Summary Restore archived video files
+// @Description Requests an on-demand restore of a video's archived objects from cold storage, optionally limited to one kind (source, rendition)
+// @Tags storage
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body restoreRequest false "Optional object kind filter"
+// @Success 202 {object} SuccessResponse{data=object{objects=[]models.StorageObject}} "Restore requested"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "No archived objects"
+// @Failure 501 {object} ErrorResponse "Storage backend does not support archival"
+// @Router /api/v1/videos/{id}/storage/restore [post]
+func (h *StorageObjectHandler) RestoreVideoStorage(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	if !h.lifecycle.Enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": storage.ErrArchiveUnsupported.Error()})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req restoreRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+	}
+
+	objects, err := listVideoObjects(c, tenantDB, videoID, req.Kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query storage objects"})
+		return
+	}
+
+	var restoring []models.StorageObject
+	for i := range objects {
+		obj := &objects[i]
+		if obj.TierStatus != models.TierStatusArchived && obj.TierStatus != models.TierStatusRestoring {
+			continue
+		}
+		if err := h.lifecycle.RequestRestore(c.Request.Context(), tenantDB, obj); err != nil {
+			if errors.Is(err, storage.ErrArchiveUnsupported) {
+				c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+				return
+			}
+			logger.Error("Failed to restore object %s: %v", obj.ObjectKey, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Storage failed to start the restore"})
+			return
+		}
+		restoring = append(restoring, *obj)
+	}
+
+	if len(restoring) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No archived objects to restore"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":  "success",
+		"message": "Restore requested",
+		"data": gin.H{
+			"objects": restoring,
+		},
+	})
+}
+
+// listVideoObjects returns the storage objects of a video, optionally filtered by kind
+func listVideoObjects(c *gin.Context, tenantDB *database.StatelessTenantDB, videoID uuid.UUID, kind string) ([]models.StorageObject, error) {
+	query := `SELECT ` + services.StorageObjectColumns + ` FROM storage_objects WHERE video_id = $1`
+	args := []interface{}{videoID}
+	if kind != "" {
+		query += " AND kind = $2"
+		args = append(args, kind)
+	}
+	query += " ORDER BY kind, object_key"
+
+	rows, err := tenantDB.QueryContext(c.Request.Context(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := []models.StorageObject{}
+	for rows.Next() {
+		obj, err := services.ScanStorageObject(rows)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, *obj)
+	}
+	return objects, rows.Err()
+}
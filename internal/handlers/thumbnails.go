@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/images"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ThumbnailHandler manages the custom poster images of videos
+type ThumbnailHandler struct {
+	storage   storage.Storage
+	processor *images.Processor
+	config    config.Images
+}
+
+// NewThumbnailHandler creates a new thumbnail handler
+func NewThumbnailHandler(store storage.Storage, processor *images.Processor, cfg config.Images) *ThumbnailHandler {
+	return &ThumbnailHandler{storage: store, processor: processor, config: cfg}
+}
+
+// UploadThumbnail godoc
+// @Summary Upload a custom thumbnail
+// @Description Sets a video's poster image from a JPEG, PNG or WebP file, sent as the raw body or as the file field of a multipart form.
+// @Description The image is resized to the configured widths and stored as JPEG (PNG when transparent) plus WebP and AVIF when the encoders are installed, with metadata stripped. It overrides generated thumbnails and is served at /embed/{id}/thumbnail.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept image/jpeg,image/png,image/webp,multipart/form-data
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Thumbnail updated"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 413 {object} ErrorResponse "Image too large"
+// @Failure 415 {object} ErrorResponse "Unsupported image format"
+// @Router /api/v1/videos/{id}/thumbnail [put]
+func (h *ThumbnailHandler) UploadThumbnail(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	orgID, err := queries.New(tenantDB).GetVideoOrganization(ctx, videoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		}
+		return
+	}
+
+	thumbnail, ok := storeImageUpload(c, h.storage, h.processor, h.config, images.Spec{Widths: h.config.ThumbnailWidths},
+		fmt.Sprintf("orgs/%s/videos/%s/thumbnails", orgID, videoID))
+	if !ok {
+		return
+	}
+
+	video, previous, err := h.setThumbnail(ctx, tenantDB, videoID, thumbnail)
+	if err != nil {
+		images.Delete(context.WithoutCancel(ctx), h.storage, thumbnail)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+			return
+		}
+		logger.Error("Failed to set thumbnail of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update thumbnail"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Thumbnail updated",
+		"data":    video,
+	})
+}
+
+// DeleteThumbnail godoc
+// @Summary Remove the custom thumbnail
+// @Description Deletes a video's custom poster image
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Thumbnail removed"
+// @Failure 404 {object} ErrorResponse "Video or thumbnail not found"
+// @Router /api/v1/videos/{id}/thumbnail [delete]
+func (h *ThumbnailHandler) DeleteThumbnail(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	video, previous, err := h.setThumbnail(ctx, tenantDB, videoID, nil)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to remove thumbnail of video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove thumbnail"})
+		return
+	}
+	if previous == nil || previous.Source != models.ImageSourceCustom {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video has no custom thumbnail"})
+		return
+	}
+	images.Delete(ctx, h.storage, previous)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Thumbnail removed",
+		"data":    video,
+	})
+}
+
+// setThumbnail swaps the video's thumbnail and its storage object records in one transaction,
+// returning the updated video and the previous thumbnail, whose files the caller deletes.
+// Removing (thumbnail nil) only applies to custom thumbnails.
+func (h *ThumbnailHandler) setThumbnail(ctx context.Context, tenantDB *database.StatelessTenantDB, videoID uuid.UUID,
+	thumbnail *models.Image) (*models.Video, *models.Image, error) {
+	var video *models.Video
+	var previous *models.Image
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		q := queries.New(tx)
+		var err error
+		if previous, err = q.LockThumbnail(ctx, videoID); err != nil {
+			return err
+		}
+		if thumbnail == nil && (previous == nil || previous.Source != models.ImageSourceCustom) {
+			video, err = services.VideoModel(q.GetVideo(ctx, videoID))
+			return err
+		}
+
+		video, err = services.VideoModel(q.SetThumbnail(ctx, queries.SetThumbnailParams{Thumbnail: thumbnail, ID: videoID}))
+		if err != nil {
+			return err
+		}
+
+		if previous != nil {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = ANY($1)`,
+				pq.Array(previous.Keys())); err != nil {
+				return err
+			}
+		}
+		if thumbnail != nil {
+			for _, v := range thumbnail.Variants {
+				if _, err := tx.ExecContext(ctx, `
+					INSERT INTO storage_objects (organization_id, video_id, object_key, kind, size_bytes)
+					VALUES ($1, $2, $3, $4, $5)
+					ON CONFLICT (object_key) DO NOTHING
+				`, video.OrganizationID, videoID, v.Key, models.ObjectKindThumbnail, v.SizeBytes); err != nil {
+					return err
+				}
+			}
+		}
+		return outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoUpdated, video))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return video, previous, nil
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/transcode"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxTranscoderCallbackSize bounds callback bodies; job events are a few kilobytes
+const maxTranscoderCallbackSize = 1 << 20
+
+const transcodeJobColumns = `id, video_id, organization_id, provider, external_id, source_revision, status, progress, error,
+	created_at, updated_at, completed_at`
+
+func scanTranscodeJob(row interface{ Scan(...interface{}) error }) (*models.TranscodeJob, error) {
+	var j models.TranscodeJob
+	if err := row.Scan(&j.ID, &j.VideoID, &j.OrganizationID, &j.Provider, &j.ExternalID, &j.SourceRevision, &j.Status,
+		&j.Progress, &j.Error, &j.CreatedAt, &j.UpdatedAt, &j.CompletedAt); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// TranscodeHandler selects organizations' transcoders and receives the transcoders' callbacks
+type TranscodeHandler struct {
+	manager *transcode.Manager
+}
+
+// NewTranscodeHandler creates a transcode handler
+func NewTranscodeHandler(manager *transcode.Manager) *TranscodeHandler {
+	return &TranscodeHandler{manager: manager}
+}
+
+type transcoderRequest struct {
+	// Provider is a configured transcoder, none, or empty to follow TRANSCODE_PROVIDER
+	Provider string `json:"provider" binding:"max=50" example:"mediaconvert"`
+	// Profile is a configured transcode profile, or empty to follow TRANSCODE_PROFILE
+	Profile string `json:"profile" binding:"max=50" example:"efficient"`
+}
+
+// SetOrganizationTranscoder godoc
+// @Summary Set organization transcoder
+// @Description Selects the transcoder new sources of an organization go through: a configured one such as mediaconvert, or mux and cloudflare to have those providers store and stream them; none to play sources as uploaded; or empty to follow TRANSCODE_PROVIDER.
+// @Description The profile selects the codecs sources are encoded to: standard for H.264 alone, or a profile of TRANSCODE_PROFILES adding HEVC or AV1 renditions next to the H.264 ladder; empty follows TRANSCODE_PROFILE. Only MediaConvert honors profiles.
+// @Description Existing videos are not transcoded again.
+// @Tags admin
+// @Security AdminToken
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body transcoderRequest true "Transcoder"
+// @Success 200 {object} SuccessResponse{data=object{organization_id=string,provider=string,profile=string}} "Transcoder saved"
+// @Failure 400 {object} ErrorResponse "Invalid request, unknown transcoder or unknown profile"
+// @Failure 401 {object} ErrorResponse "Invalid admin token"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /admin/v1/organizations/{id}/transcoder [put]
+func (h *TranscodeHandler) SetOrganizationTranscoder(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	var req transcoderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	provider, profile := strings.TrimSpace(req.Provider), strings.TrimSpace(req.Profile)
+
+	err = h.manager.SetTranscoder(c.Request.Context(), orgID, provider, profile)
+	switch {
+	case errors.Is(err, transcode.ErrUnknownProvider):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown transcoder; configured: " + strings.Join(append(h.manager.Providers(), transcode.ProviderNone), ", "),
+		})
+		return
+	case errors.Is(err, transcode.ErrUnknownProfile):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unknown transcode profile; configured: " + strings.Join(h.manager.Profiles(), ", "),
+		})
+		return
+	case err == sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	case err != nil:
+		logger.Error("Failed to set transcoder of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save transcoder"})
+		return
+	}
+	logger.Info("Organization %s now transcodes with %q, profile %q", orgID, provider, profile)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Transcoder saved",
+		"data":    gin.H{"organization_id": orgID, "provider": provider, "profile": profile},
+	})
+}
+
+// TranscoderCallback godoc
+// @Summary Receive transcoder callback
+// @Description Accepts job state changes from a transcoder: MediaConvert Job State Change events sent by an EventBridge API destination, authorized with TRANSCODE_CALLBACK_TOKEN as a bearer token;
+// @Description Mux webhooks signed with MUX_WEBHOOK_SECRET; and Cloudflare Stream webhooks signed with CLOUDFLARE_STREAM_WEBHOOK_SECRET.
+// @Description Callbacks finish jobs without waiting for the next poll; those about jobs OpenVDO did not submit are acknowledged and ignored.
+// @Tags transcode
+// @Accept json
+// @Produce json
+// @Param provider path string true "Transcoder: mediaconvert, mux or cloudflare"
+// @Param Authorization header string false "Bearer token, for mediaconvert"
+// @Param Mux-Signature header string false "Webhook signature, for mux"
+// @Param Webhook-Signature header string false "Webhook signature, for cloudflare"
+// @Success 200 {object} SuccessResponse "Callback applied"
+// @Failure 400 {object} ErrorResponse "Invalid callback"
+// @Failure 401 {object} ErrorResponse "Invalid token or signature"
+// @Failure 404 {object} ErrorResponse "Callbacks disabled or unknown transcoder"
+// @Failure 413 {object} ErrorResponse "Body too large"
+// @Router /hooks/transcoders/{provider} [post]
+func (h *TranscodeHandler) TranscoderCallback(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTranscoderCallbackSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Callback body too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read callback body"})
+		return
+	}
+
+	err = h.manager.Callback(c.Request.Context(), c.Param("provider"), c.Request.Header, body)
+	switch {
+	case errors.Is(err, transcode.ErrUnknownProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown transcoder"})
+		return
+	case errors.Is(err, transcode.ErrCallbacksUnsupported):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Callbacks of this transcoder are disabled"})
+		return
+	case errors.Is(err, transcode.ErrUnauthorizedCallback):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token or signature"})
+		return
+	case errors.Is(err, transcode.ErrInvalidCallback):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("Failed to apply %s callback: %v", c.Param("provider"), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply callback"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Callback applied",
+	})
+}
+
+// ListVideoTranscodes godoc
+// @Summary List video transcodes
+// @Description Lists the jobs the organization's transcoder ran for a video, newest first. Renditions of complete jobs are listed under the video's renditions.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{transcodes=[]models.TranscodeJob}} "Transcodes retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Router /api/v1/videos/{id}/transcodes [get]
+func ListVideoTranscodes(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(c.Request.Context(),
+		`SELECT `+transcodeJobColumns+` FROM transcode_jobs WHERE video_id = $1 ORDER BY created_at DESC`, videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query transcodes"})
+		return
+	}
+	defer rows.Close()
+
+	transcodes := []models.TranscodeJob{}
+	for rows.Next() {
+		j, err := scanTranscodeJob(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan transcode"})
+			return
+		}
+		transcodes = append(transcodes, *j)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing transcode results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Transcodes retrieved",
+		"data":    gin.H{"transcodes": transcodes},
+	})
+}
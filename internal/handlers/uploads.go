@@ -0,0 +1,830 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// S3 limits: parts must be at least 5 MiB (except the last) and an upload has at most 10,000 parts
+	minPartSize      = 5 << 20
+	maxPartCount     = 10000
+	maxPresignBatch  = 100
+	uploadSessionTTL = 24 * time.Hour
+)
+
+// errUploadNotPending is returned when a concurrent request completed or aborted the upload first
+var errUploadNotPending = errors.New("upload is no longer pending")
+
+// UploadHandler serves the presigned multipart upload flow
+type UploadHandler struct {
+	storage storage.Storage
+	config  config.Storage
+	hasher  *services.ContentHasher
+	purger  *services.CachePurger
+}
+
+// NewUploadHandler creates a new upload handler
+func NewUploadHandler(store storage.Storage, cfg config.Storage, hasher *services.ContentHasher, purger *services.CachePurger) *UploadHandler {
+	return &UploadHandler{storage: store, config: cfg, hasher: hasher, purger: purger}
+}
+
+type createMultipartUploadRequest struct {
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Filename    string     `json:"filename"`
+	ContentType string     `json:"content_type"`
+	SizeBytes   int64      `json:"size_bytes" binding:"required,min=1"`
+	ProjectID   *uuid.UUID `json:"project_id"`
+	SHA256      string     `json:"sha256" binding:"omitempty,len=64,hexadecimal"`
+	// ExternalID is the video's ID in an external system, unique within the organization
+	ExternalID *string `json:"external_id" binding:"omitempty,min=1,max=255,excludes=/"`
+	// CustomFields are values of the organization's custom fields, by key
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+type replaceVideoSourceRequest struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes" binding:"required,min=1"`
+	// Version optionally guards against replacing a video changed since the client read it
+	Version *int64 `json:"version"`
+}
+
+type completeMultipartUploadRequest struct {
+	Parts []storage.CompletedPart `json:"parts" binding:"required,min=1,dive"`
+}
+
+// CreateMultipartUpload godoc
+// @Summary Start a multipart upload
+// @Description Creates a video record and an S3 multipart upload, returning presigned URLs for the first parts so the client can upload bytes directly to storage.
+// @Description When the client declares the file's SHA-256 and the organization already has identical content, the response carries duplicate_of; with the reuse policy no upload is created and the new video shares the existing source.
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body createMultipartUploadRequest true "Upload details"
+// @Success 201 {object} SuccessResponse{data=object{upload=models.VideoUpload,parts=[]models.PresignedPart,duplicate_of=string,warning=string,video=models.Video,deduplicated=bool}} "Multipart upload created, or video deduplicated"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 409 {object} ErrorResponse "External ID already used by another video"
+// @Failure 501 {object} ErrorResponse "Storage backend does not support multipart uploads"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/uploads/multipart [post]
+func (h *UploadHandler) CreateMultipartUpload(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	mu, err := storage.AsMultipartUploader(h.storage)
+	if err != nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req createMultipartUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	if h.config.MaxUploadSize > 0 && req.SizeBytes > h.config.MaxUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File exceeds maximum upload size of %d bytes", h.config.MaxUploadSize)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+
+	customFields, _, ok := videoCustomFields(c, tenantDB, session.OrgID, req.CustomFields)
+	if !ok {
+		return
+	}
+
+	// A declared hash is only a hint; the stored hash is always computed from the uploaded bytes
+	var original *models.Video
+	if req.SHA256 != "" && h.hasher.Policy() != models.DuplicatePolicyOff {
+		req.SHA256 = strings.ToLower(req.SHA256)
+		original, err = services.FindDuplicate(ctx, tenantDB, session.OrgID, req.SHA256, uuid.Nil)
+		if err != nil {
+			logger.Error("Failed to look up duplicates for org %s: %v", session.OrgID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check for duplicate content"})
+			return
+		}
+		if original != nil && h.hasher.Policy() == models.DuplicatePolicyReuse {
+			h.createDuplicateVideo(c, tenantDB, session, &req, customFields, original)
+			return
+		}
+	}
+
+	partSize, partCount := planParts(req.SizeBytes, h.config.MultipartPartSize)
+	videoID := uuid.New()
+	key := services.SourceKey(session.OrgID, videoID, req.Filename)
+
+	uploadID, err := mu.CreateMultipartUpload(ctx, key, req.ContentType)
+	if err != nil {
+		logger.Error("Failed to create multipart upload for video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create multipart upload"})
+		return
+	}
+
+	upload := models.VideoUpload{
+		VideoID:        videoID,
+		OrganizationID: session.OrgID,
+		StorageKey:     key,
+		UploadID:       uploadID,
+		PartSize:       partSize,
+		PartCount:      partCount,
+		Status:         models.UploadStatusPending,
+		ExpiresAt:      time.Now().Add(uploadSessionTTL),
+	}
+
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		err := queries.New(tx).CreateUploadingVideo(ctx, queries.CreateUploadingVideoParams{
+			ID:             videoID,
+			OrganizationID: session.OrgID,
+			ProjectID:      req.ProjectID,
+			Title:          req.Title,
+			Description:    req.Description,
+			Status:         models.VideoStatusUploading,
+			SourceKey:      key,
+			ContentType:    req.ContentType,
+			SizeBytes:      req.SizeBytes,
+			CreatedBy:      session.UserID,
+			CustomFields:   customFields,
+			ExternalID:     req.ExternalID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert video: %w", err)
+		}
+
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO video_uploads (video_id, organization_id, storage_key, upload_id, part_size, part_count, status, expires_at, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id, created_at
+		`, videoID, session.OrgID, key, uploadID, partSize, partCount, upload.Status, upload.ExpiresAt, session.UserID,
+		).Scan(&upload.ID, &upload.CreatedAt); err != nil {
+			return err
+		}
+		if _, err := services.HoldForReview(ctx, tx, session.OrgID, videoID, session.UserID, session.Role); err != nil {
+			return err
+		}
+		return services.RecordVideoEvent(ctx, tx, outbox.EventVideoCreated, videoID)
+	})
+	if err != nil {
+		if abortErr := mu.AbortMultipartUpload(ctx, key, uploadID); abortErr != nil {
+			logger.Error("Failed to abort orphaned multipart upload %s: %v", uploadID, abortErr)
+		}
+		if databaseError(c, err) {
+			return
+		}
+		logger.Error("Failed to record multipart upload for video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+	tenantDB.InvalidateCount(ctx, database.VideoCount(session.OrgID))
+
+	parts, err := h.presignParts(ctx, mu, &upload, 1, maxPresignBatch)
+	if err != nil {
+		logger.Error("Failed to presign parts for upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to presign upload parts"})
+		return
+	}
+
+	data := gin.H{
+		"upload": upload,
+		"parts":  parts,
+	}
+	if original != nil {
+		data["duplicate_of"] = original.ID
+		data["warning"] = "The organization already has a video with identical content"
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Multipart upload created",
+		"data":    data,
+	})
+}
+
+// createDuplicateVideo records a new video that shares the source of an identical original,
+// skipping the upload entirely
+func (h *UploadHandler) createDuplicateVideo(c *gin.Context, tenantDB *database.StatelessTenantDB, session *database.UserSession, req *createMultipartUploadRequest, customFields models.CustomFieldValues, original *models.Video) {
+	ctx := c.Request.Context()
+	var video *models.Video
+	err := tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		video, err = services.VideoModel(queries.New(tx).CreateDuplicateVideo(ctx, queries.CreateDuplicateVideoParams{
+			OrganizationID: session.OrgID,
+			ProjectID:      req.ProjectID,
+			Title:          req.Title,
+			Description:    req.Description,
+			Status:         models.VideoStatusQueued,
+			SourceKey:      original.SourceKey,
+			ContentType:    original.ContentType,
+			SizeBytes:      original.SizeBytes,
+			SHA256:         original.SHA256,
+			DuplicateOf:    original.ID,
+			CreatedBy:      session.UserID,
+			CustomFields:   customFields,
+			ExternalID:     req.ExternalID,
+		}))
+		if err != nil {
+			return err
+		}
+		held, err := services.HoldForReview(ctx, tx, session.OrgID, video.ID, session.UserID, session.Role)
+		if err != nil {
+			return err
+		}
+		if held {
+			video.ReviewStatus = models.ReviewPending
+		}
+		// The video is playable right away, so it is created and ready at once
+		if err := outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoCreated, video)); err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoReady, video))
+	})
+	if databaseError(c, err) {
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create duplicate of video %s: %v", original.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create video"})
+		return
+	}
+	tenantDB.InvalidateCount(ctx, database.VideoCount(session.OrgID))
+
+	if err := services.MarkAccessed(ctx, tenantDB, original.SourceKey); err != nil {
+		logger.Error("Failed to mark source %s accessed: %v", original.SourceKey, err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Identical content already uploaded; video created from the existing source",
+		"data": gin.H{
+			"video":        video,
+			"deduplicated": true,
+		},
+	})
+}
+
+// ReplaceVideoSource godoc
+// @Summary Replace a video's source
+// @Description Starts a multipart upload of a new source for an existing video. Upload the parts and complete it through the multipart upload endpoints; until then the current source keeps playing.
+// @Description On completion the video switches to the new source in one transaction, keeping its ID, playback URLs and history. Its hash is recomputed, the old source and its renditions are kept as a version the video can be rolled back to, or removed when STORAGE_SOURCE_VERSION_RETENTION is 0, and CDN caches of the playback URLs are purged when a purge webhook is configured.
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param request body replaceVideoSourceRequest true "New source details and optionally the expected version"
+// @Success 201 {object} SuccessResponse{data=object{upload=models.VideoUpload,parts=[]models.PresignedPart}} "Replacement upload created"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 409 {object} ErrorResponse{data=models.Video} "Video is still uploading, was deleted or was changed, with the current video"
+// @Failure 501 {object} ErrorResponse "Storage backend does not support multipart uploads"
+// @Router /api/v1/videos/{id}/replace [post]
+func (h *UploadHandler) ReplaceVideoSource(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	mu, err := storage.AsMultipartUploader(h.storage)
+	if err != nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req replaceVideoSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if h.config.MaxUploadSize > 0 && req.SizeBytes > h.config.MaxUploadSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File exceeds maximum upload size of %d bytes", h.config.MaxUploadSize)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+
+	video, err := services.VideoModel(queries.New(tenantDB).GetVideo(ctx, videoID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		}
+		return
+	}
+	if video.Status == models.VideoStatusUploading {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video source is still uploading"})
+		return
+	}
+	if video.Status == models.VideoStatusDeleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video was deleted"})
+		return
+	}
+	if req.Version != nil && *req.Version != video.Version {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video was modified", "data": video})
+		return
+	}
+
+	partSize, partCount := planParts(req.SizeBytes, h.config.MultipartPartSize)
+	upload := models.VideoUpload{
+		ID:             uuid.New(),
+		VideoID:        videoID,
+		OrganizationID: video.OrganizationID,
+		PartSize:       partSize,
+		PartCount:      partCount,
+		Status:         models.UploadStatusPending,
+		ExpiresAt:      time.Now().Add(uploadSessionTTL),
+		ReplacesKey:    &video.SourceKey,
+		Replacement:    true,
+		ContentType:    req.ContentType,
+		SizeBytes:      req.SizeBytes,
+	}
+	upload.StorageKey = services.ReplacementSourceKey(video.OrganizationID, videoID, upload.ID, req.Filename)
+
+	upload.UploadID, err = mu.CreateMultipartUpload(ctx, upload.StorageKey, req.ContentType)
+	if err != nil {
+		logger.Error("Failed to create replacement upload for video %s: %v", videoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create multipart upload"})
+		return
+	}
+
+	err = tenantDB.QueryRowContext(ctx, `
+		INSERT INTO video_uploads (id, video_id, organization_id, storage_key, upload_id, part_size, part_count, status, expires_at,
+			created_by, replaces_key, content_type, size_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING created_at
+	`, upload.ID, videoID, video.OrganizationID, upload.StorageKey, upload.UploadID, partSize, partCount, upload.Status,
+		upload.ExpiresAt, session.UserID, video.SourceKey, req.ContentType, req.SizeBytes,
+	).Scan(&upload.CreatedAt)
+	if err != nil {
+		logger.Error("Failed to record replacement upload for video %s: %v", videoID, err)
+		if abortErr := mu.AbortMultipartUpload(ctx, upload.StorageKey, upload.UploadID); abortErr != nil {
+			logger.Error("Failed to abort orphaned multipart upload %s: %v", upload.UploadID, abortErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload"})
+		return
+	}
+
+	parts, err := h.presignParts(ctx, mu, &upload, 1, maxPresignBatch)
+	if err != nil {
+		logger.Error("Failed to presign parts for upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to presign upload parts"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Replacement upload created",
+		"data": gin.H{
+			"upload": upload,
+			"parts":  parts,
+		},
+	})
+}
+
+// GetMultipartUploadParts godoc
+// @Summary Presign upload parts
+// @Description Returns fresh presigned URLs for a range of parts of a pending multipart upload
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Param start query int false "First part number" default(1)
+// @Param count query int false "Number of parts to presign (max 100)" default(100)
+// @Success 200 {object} SuccessResponse{data=object{upload=models.VideoUpload,parts=[]models.PresignedPart}} "Presigned parts"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Upload not found"
+// @Failure 409 {object} ErrorResponse "Upload is no longer pending"
+// @Router /api/v1/uploads/multipart/{id}/parts [get]
+func (h *UploadHandler) GetMultipartUploadParts(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	mu, err := storage.AsMultipartUploader(h.storage)
+	if err != nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, ok := h.loadPendingUpload(c, tenantDB)
+	if !ok {
+		return
+	}
+
+	start, _ := strconv.Atoi(c.DefaultQuery("start", "1"))
+	count, _ := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(maxPresignBatch)))
+	if start < 1 || start > upload.PartCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start must be between 1 and the part count"})
+		return
+	}
+	if count < 1 || count > maxPresignBatch {
+		count = maxPresignBatch
+	}
+
+	parts, err := h.presignParts(c.Request.Context(), mu, upload, start, count)
+	if err != nil {
+		logger.Error("Failed to presign parts for upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to presign upload parts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Upload parts presigned",
+		"data": gin.H{
+			"upload": upload,
+			"parts":  parts,
+		},
+	})
+}
+
+// CompleteMultipartUpload godoc
+// @Summary Complete a multipart upload
+// @Description Assembles the uploaded parts on storage and marks the video source as uploaded. The SHA-256 of the source is computed in the background.
+// @Description Completing a replacement upload switches the video to the new source; it fails with 409 when another replacement completed first.
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Param request body completeMultipartUploadRequest true "Uploaded parts with their ETags"
+// @Success 200 {object} SuccessResponse{data=object{upload=models.VideoUpload,video_id=string,video=models.Video}} "Upload completed; video is set when a replacement completed"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 404 {object} ErrorResponse "Upload not found"
+// @Failure 409 {object} ErrorResponse "Upload is no longer pending, or the video was deleted"
+// @Failure 502 {object} ErrorResponse "Storage rejected the upload"
+// @Router /api/v1/uploads/multipart/{id}/complete [post]
+func (h *UploadHandler) CompleteMultipartUpload(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	mu, err := storage.AsMultipartUploader(h.storage)
+	if err != nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req completeMultipartUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	upload, ok := h.loadPendingUpload(c, tenantDB)
+	if !ok {
+		return
+	}
+
+	if len(req.Parts) != upload.PartCount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Expected %d parts, got %d", upload.PartCount, len(req.Parts))})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := mu.CompleteMultipartUpload(ctx, upload.StorageKey, upload.UploadID, req.Parts); err != nil {
+		logger.Error("Failed to complete multipart upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Storage rejected the upload"})
+		return
+	}
+
+	if upload.ReplacesKey != nil {
+		h.completeReplacement(c, tenantDB, upload)
+		return
+	}
+
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := finishUpload(ctx, tx, upload.ID, models.UploadStatusCompleted); err != nil {
+			return err
+		}
+		if err := services.TransitionVideo(ctx, tx, upload.VideoID, models.VideoStatusQueued, "upload completed"); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO storage_objects (organization_id, video_id, object_key, kind, size_bytes)
+			SELECT organization_id, id, source_key, $1, size_bytes FROM videos WHERE id = $2
+			ON CONFLICT (object_key) DO NOTHING
+		`, models.ObjectKindSource, upload.VideoID); err != nil {
+			return err
+		}
+		if err := services.NotifyVideoReady(ctx, tx, upload.VideoID); err != nil {
+			return err
+		}
+		return services.RecordVideoEvent(ctx, tx, outbox.EventVideoReady, upload.VideoID)
+	})
+	if errors.Is(err, errUploadNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is no longer pending"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidTransition) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Video was deleted"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to mark upload %s completed: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update upload status"})
+		return
+	}
+
+	h.hasher.Enqueue(upload.VideoID)
+
+	upload.Status = models.UploadStatusCompleted
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Upload completed",
+		"data": gin.H{
+			"upload":   upload,
+			"video_id": upload.VideoID,
+		},
+	})
+}
+
+// completeReplacement switches the video to the completed upload's source and keeps the old
+// source as a version, or deletes what it leaves behind
+func (h *UploadHandler) completeReplacement(c *gin.Context, tenantDB *database.StatelessTenantDB, upload *models.VideoUpload) {
+	ctx := c.Request.Context()
+	session, err := tenantDB.GetUserSession(ctx)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User session not found"})
+		return
+	}
+
+	var video *models.Video
+	var obsolete []string
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := finishUpload(ctx, tx, upload.ID, models.UploadStatusCompleted); err != nil {
+			return err
+		}
+
+		var err error
+		video, obsolete, err = services.ReplaceSource(ctx, tx, services.SourceReplacement{
+			VideoID:     upload.VideoID,
+			OldKey:      *upload.ReplacesKey,
+			NewKey:      upload.StorageKey,
+			ContentType: upload.ContentType,
+			SizeBytes:   upload.SizeBytes,
+			KeepFor:     h.config.SourceVersionRetention,
+			ReplacedBy:  &session.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoSourceReplaced, video)); err != nil {
+			return err
+		}
+
+		if h.purger.Enabled() {
+			_, err = jobs.Enqueue(ctx, tx, services.JobKindCDNPurge,
+				services.PurgePayload{VideoID: &upload.VideoID, Paths: services.PlaybackPaths(upload.VideoID)},
+				jobs.Options{OrganizationID: &upload.OrganizationID, CreatedBy: &session.UserID})
+		}
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errUploadNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Upload is no longer pending"})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidTransition) {
+			// Deleted since the upload started
+			c.JSON(http.StatusConflict, gin.H{"error": "Video was deleted"})
+			return
+		}
+		if errors.Is(err, services.ErrSourceChanged) {
+			// Nothing references the new object, and the upload cannot be completed again
+			if _, dbErr := tenantDB.ExecContext(ctx, `UPDATE video_uploads SET status = $1 WHERE id = $2`,
+				models.UploadStatusAborted, upload.ID); dbErr != nil {
+				logger.Error("Failed to mark upload %s aborted: %v", upload.ID, dbErr)
+			}
+			if delErr := h.storage.Delete(ctx, upload.StorageKey); delErr != nil {
+				logger.Error("Failed to delete superseded source %s: %v", upload.StorageKey, delErr)
+			}
+			c.JSON(http.StatusConflict, gin.H{"error": "The video's source was replaced since this upload started"})
+			return
+		}
+		logger.Error("Failed to replace source of video %s: %v", upload.VideoID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replace video source"})
+		return
+	}
+
+	for _, key := range obsolete {
+		if err := h.storage.Delete(ctx, key); err != nil {
+			logger.Error("Failed to delete replaced object %s: %v", key, err)
+		}
+	}
+	h.hasher.Enqueue(upload.VideoID)
+	logger.Info("Replaced source of video %s (revision %d)", video.ID, video.SourceRevision)
+
+	upload.Status = models.UploadStatusCompleted
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Video source replaced",
+		"data": gin.H{
+			"upload":   upload,
+			"video_id": upload.VideoID,
+			"video":    video,
+		},
+	})
+}
+
+// AbortMultipartUpload godoc
+// @Summary Abort a multipart upload
+// @Description Discards the uploaded parts on storage and marks the video as failed. Aborting a replacement upload leaves the video on its current source.
+// @Tags uploads
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Upload ID"
+// @Success 200 {object} SuccessResponse "Upload aborted"
+// @Failure 404 {object} ErrorResponse "Upload not found"
+// @Failure 409 {object} ErrorResponse "Upload is no longer pending"
+// @Router /api/v1/uploads/multipart/{id} [delete]
+func (h *UploadHandler) AbortMultipartUpload(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	mu, err := storage.AsMultipartUploader(h.storage)
+	if err != nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, ok := h.loadPendingUpload(c, tenantDB)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := mu.AbortMultipartUpload(ctx, upload.StorageKey, upload.UploadID); err != nil {
+		logger.Error("Failed to abort multipart upload %s: %v", upload.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Storage failed to abort the upload"})
+		return
+	}
+
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := finishUpload(ctx, tx, upload.ID, models.UploadStatusAborted); err != nil {
+			return err
+		}
+		if upload.ReplacesKey != nil {
+			return nil
+		}
+		err := services.TransitionVideo(ctx, tx, upload.VideoID, models.VideoStatusFailed, "upload aborted")
+		if errors.Is(err, services.ErrInvalidTransition) {
+			// The video was deleted while uploading and stays deleted
+			return nil
+		}
+		return err
+	})
+	if errors.Is(err, errUploadNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is no longer pending"})
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to mark upload %s aborted: %v", upload.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update upload status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Upload aborted",
+	})
+}
+
+// finishUpload moves a pending upload to status. Concurrent requests finishing the same upload
+// wait on its row, and all but the first get errUploadNotPending.
+func finishUpload(ctx context.Context, tx *sql.Tx, id uuid.UUID, status string) error {
+	result, err := tx.ExecContext(ctx, `UPDATE video_uploads SET status = $1 WHERE id = $2 AND status = $3`,
+		status, id, models.UploadStatusPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return errUploadNotPending
+	}
+	return nil
+}
+
+// loadPendingUpload loads the upload named in the path, writing an error response if it is not usable
+func (h *UploadHandler) loadPendingUpload(c *gin.Context, tenantDB *database.StatelessTenantDB) (*models.VideoUpload, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload ID"})
+		return nil, false
+	}
+
+	var upload models.VideoUpload
+	err = tenantDB.QueryRowContext(c.Request.Context(), `
+		SELECT id, video_id, organization_id, storage_key, upload_id, part_size, part_count, status, expires_at, created_at,
+			replaces_key, COALESCE(content_type, ''), COALESCE(size_bytes, 0)
+		FROM video_uploads
+		WHERE id = $1
+	`, id).Scan(
+		&upload.ID, &upload.VideoID, &upload.OrganizationID, &upload.StorageKey, &upload.UploadID,
+		&upload.PartSize, &upload.PartCount, &upload.Status, &upload.ExpiresAt, &upload.CreatedAt,
+		&upload.ReplacesKey, &upload.ContentType, &upload.SizeBytes,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load upload"})
+		}
+		return nil, false
+	}
+
+	upload.Replacement = upload.ReplacesKey != nil
+
+	if upload.Status != models.UploadStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload is " + upload.Status})
+		return nil, false
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Upload has expired"})
+		return nil, false
+	}
+
+	return &upload, true
+}
+
+// presignParts presigns up to count parts starting at part number start
+func (h *UploadHandler) presignParts(ctx context.Context, mu storage.MultipartUploader, upload *models.VideoUpload, start, count int) ([]models.PresignedPart, error) {
+	end := start + count - 1
+	if end > upload.PartCount {
+		end = upload.PartCount
+	}
+
+	parts := make([]models.PresignedPart, 0, end-start+1)
+	for n := start; n <= end; n++ {
+		url, err := mu.PresignUploadPart(ctx, upload.StorageKey, upload.UploadID, int32(n), h.config.PresignExpiry)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, models.PresignedPart{PartNumber: int32(n), URL: url})
+	}
+	return parts, nil
+}
+
+// planParts picks a part size honoring the configured size and S3's part limits
+func planParts(size, preferred int64) (int64, int) {
+	partSize := preferred
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	if minimum := (size + maxPartCount - 1) / maxPartCount; partSize < minimum {
+		partSize = minimum
+	}
+
+	count := int((size + partSize - 1) / partSize)
+	return partSize, count
+}
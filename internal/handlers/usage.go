@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"openvdo/internal/usage"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAPIUsage godoc
+// @Summary Get API usage
+// @Description Reports the organization's API requests, 4xx and 5xx errors and bytes per UTC day or month, for dashboards
+// @Description and billing. Days default to the last 30 and months to the last 12; a report covers at most 400 days.
+// @Description Counts reach the report within USAGE_FLUSH_INTERVAL. Requires the owner or admin role.
+// @Tags organizations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param granularity query string false "day or month" default(day)
+// @Param from query string false "First day (YYYY-MM-DD)"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to today"
+// @Param group_by query string false "api_key to split each period by API key"
+// @Success 200 {object} SuccessResponse{data=object{granularity=string,from=string,to=string,usage=usage.Report}} "Usage retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid range or granularity"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/api-usage [get]
+func GetAPIUsage(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Viewing API usage")
+	if !ok {
+		return
+	}
+
+	q := usage.ReportQuery{
+		OrganizationID: orgID,
+		Granularity:    c.DefaultQuery("granularity", usage.GranularityDay),
+		ByAPIKey:       c.Query("group_by") == "api_key",
+		To:             time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date (YYYY-MM-DD)"})
+			return
+		}
+		q.To = to
+	}
+	if q.Granularity == usage.GranularityMonth {
+		q.From = time.Date(q.To.Year(), q.To.Month()-11, 1, 0, 0, 0, 0, time.UTC)
+	} else {
+		q.From = q.To.AddDate(0, 0, -29)
+	}
+	if raw := c.Query("from"); raw != "" {
+		from, err := time.Parse(time.DateOnly, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date (YYYY-MM-DD)"})
+			return
+		}
+		q.From = from
+	}
+	if err := q.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := usage.Load(c.Request.Context(), tenantDB, q)
+	if err != nil {
+		logger.Error("Failed to load API usage of organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load API usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "API usage retrieved successfully",
+		"data": gin.H{
+			"granularity": q.Granularity,
+			"from":        q.From.Format(time.DateOnly),
+			"to":          q.To.Format(time.DateOnly),
+			"usage":       report,
+		},
+	})
+}
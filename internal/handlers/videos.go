@@ -0,0 +1,520 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListVideos godoc
+// @Summary List videos
+// @Description Lists the videos of the current organization, optionally filtered by SHA-256 content hash to find identical uploads, by status, or by custom fields: field[key]=value matches a value exactly, and field_min[key] and field_max[key] bound number and date fields.
+// @Description With Accept: application/x-ndjson all matching videos are streamed as one JSON object per line, without pagination.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Produce application/x-ndjson
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Page size" default(10)
+// @Param sha256 query string false "Only videos with this content hash"
+// @Param status query string false "Only videos in this status" Enums(uploading, queued, processing, ready, failed, deleted)
+// @Param field[key] query string false "Only videos whose custom field key has this value"
+// @Param field_min[key] query string false "Only videos whose number or date field key is at least this"
+// @Param field_max[key] query string false "Only videos whose number or date field key is at most this"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} SuccessResponse{data=object{videos=[]models.Video,pagination=response.Pagination}} "Videos retrieved"
+// @Success 304 "Not modified"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/videos [get]
+func ListVideos(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	var conditions []string
+	args := []interface{}{}
+	if sum := c.Query("sha256"); sum != "" {
+		args = append(args, strings.ToLower(sum))
+		conditions = append(conditions, "sha256 = $"+strconv.Itoa(len(args)))
+	}
+	if status := c.Query("status"); status != "" {
+		args = append(args, status)
+		conditions = append(conditions, "status = $"+strconv.Itoa(len(args)))
+	}
+	ctx := c.Request.Context()
+	fieldEqual, fieldMin, fieldMax := c.QueryMap("field"), c.QueryMap("field_min"), c.QueryMap("field_max")
+	if len(fieldEqual)+len(fieldMin)+len(fieldMax) > 0 {
+		fields, err := services.CustomFields(ctx, tenantDB, tenantDB.GetOrganizationID())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get custom fields"})
+			return
+		}
+		fieldConditions, err := services.CustomFieldFilters(fields, fieldEqual, fieldMin, fieldMax, &args)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		conditions = append(conditions, fieldConditions...)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if wantsNDJSON(c) {
+		rows, err := tenantDB.QueryContext(ctx, `SELECT `+services.VideoColumns+` FROM videos`+where+` ORDER BY created_at DESC`, args...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query videos"})
+			return
+		}
+		streamNDJSON(c, rows, func(rows *sql.Rows) (interface{}, error) {
+			return services.ScanVideo(rows)
+		})
+		return
+	}
+
+	query := `SELECT ` + services.VideoColumns + ` FROM videos` + where +
+		` ORDER BY created_at DESC LIMIT $` + strconv.Itoa(len(args)+1) + ` OFFSET $` + strconv.Itoa(len(args)+2)
+	rows, err := tenantDB.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query videos"})
+		return
+	}
+	defer rows.Close()
+
+	videos := []models.Video{}
+	for rows.Next() {
+		video, err := services.ScanVideo(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan video"})
+			return
+		}
+		videos = append(videos, *video)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing video results"})
+		return
+	}
+
+	// Counts of whole organizations come from the count cache; filtered ones are cheap to count
+	var total database.Count
+	if orgID := tenantDB.GetOrganizationID(); where == "" && orgID != uuid.Nil {
+		total, err = tenantDB.Count(ctx, database.VideoCount(orgID))
+	} else {
+		err = tenantDB.QueryRowContext(ctx, `SELECT COUNT(*) FROM videos`+where, args...).Scan(&total.Total)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get total count"})
+		return
+	}
+
+	etagParts := []interface{}{page, limit, c.Query("sha256"), c.Query("status"), fieldEqual, fieldMin, fieldMax, total.Total}
+	for _, video := range videos {
+		etagParts = append(etagParts, video.ID, video.UpdatedAt.UnixMicro())
+	}
+	if notModified(c, listETag(etagParts...)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Videos retrieved successfully",
+		"data": gin.H{
+			"videos": videos,
+			"pagination": gin.H{
+				"page":            page,
+				"limit":           limit,
+				"total":           total.Total,
+				"total_estimated": total.Estimated,
+			},
+		},
+	})
+}
+
+// ListTrendingVideos godoc
+// @Summary List trending videos
+// @Description Lists the ready videos of the current organization with the most plays, scored from the plays of the last day and the daily average of the last week. Scores are recomputed every 15 minutes by default.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param limit query int false "Number of videos, at most 100" default(10)
+// @Success 200 {object} SuccessResponse{data=object{videos=[]models.TrendingVideo}} "Trending videos retrieved"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /api/v1/videos/trending [get]
+func ListTrendingVideos(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	rows, err := queries.New(tenantDB).ListTrendingVideos(c.Request.Context(), queries.ListTrendingVideosParams{
+		Status: models.VideoStatusReady, Limit: int32(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query trending videos"})
+		return
+	}
+
+	videos := make([]models.TrendingVideo, len(rows))
+	for i, row := range rows {
+		video, _ := services.VideoModel(row.Video, nil)
+		videos[i] = models.TrendingVideo{
+			Video:      *video,
+			Plays24h:   row.Plays24h,
+			Plays7d:    row.Plays7d,
+			Score:      row.Score,
+			ComputedAt: row.ComputedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Trending videos retrieved successfully",
+		"data":    gin.H{"videos": videos},
+	})
+}
+
+// GetVideo godoc
+// @Summary Get video
+// @Description Retrieves a video including its SHA-256 content hash, the original it duplicates, if any, and the color signaling of its source, which tells HDR10, HLG and Dolby Vision sources apart once probed. The response carries an ETag for conditional requests.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Video retrieved"
+// @Success 304 "Not modified"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id} [get]
+func GetVideo(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	video, err := services.VideoModel(queries.New(tenantDB).GetVideo(c.Request.Context(), videoID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		}
+		return
+	}
+
+	if notModified(c, resourceETag(video.ID, video.UpdatedAt)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Video retrieved successfully",
+		"data":    video,
+	})
+}
+
+// GetVideoByExternalID godoc
+// @Summary Get video by external ID
+// @Description Retrieves the video of the current organization with an external ID, such as the ID of the CMS entry it was synced from. The response carries an ETag for conditional requests.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param external_id path string true "External ID"
+// @Param If-None-Match header string false "ETag of a previous response"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Video retrieved"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/by-external-id/{external_id} [get]
+func GetVideoByExternalID(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	// Videos of other organizations the user belongs to may share the external ID
+	externalID := c.Param("external_id")
+	video, err := services.VideoModel(queries.New(tenantDB).GetVideoByExternalID(c.Request.Context(),
+		queries.GetVideoByExternalIDParams{OrganizationID: tenantDB.GetOrganizationID(), ExternalID: &externalID}))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		}
+		return
+	}
+
+	if notModified(c, resourceETag(video.ID, video.UpdatedAt)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Video retrieved successfully",
+		"data":    video,
+	})
+}
+
+type updateVideoRequest struct {
+	Version     *int64  `json:"version" binding:"required"`
+	Title       *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description"`
+	Visibility  *string `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	// RequiresEntitlement limits playback sessions to viewers holding an entitlement
+	RequiresEntitlement *bool `json:"requires_entitlement"`
+	// DownloadsEnabled lets members request files of the video to download
+	DownloadsEnabled *bool     `json:"downloads_enabled"`
+	Tags             *[]string `json:"tags" binding:"omitempty,max=50,dive,max=64"`
+	// ExternalID replaces the video's ID in an external system; send "" to remove it
+	ExternalID *string `json:"external_id" binding:"omitempty,max=255,excludes=/"`
+	// CustomFields sets the values of custom fields by key; null removes a value, and fields
+	// left out keep theirs
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+// UpdateVideo godoc
+// @Summary Update video
+// @Description Updates the title, description, tags, visibility (public, unlisted or private), requires_entitlement, downloads_enabled, external_id or custom_fields of a video. External IDs are unique within the organization; an empty one removes it. Custom field values are checked against the organization's fields; null removes a value. The request must carry the version it is based on; if the video was changed since, the update is rejected with 409 and the current state.
+// @Description If-Match with a previous ETag is also honored.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Video ID"
+// @Param If-Match header string false "ETag the update is based on"
+// @Param request body updateVideoRequest true "Expected version and fields to change"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Video updated"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Failure 409 {object} ErrorResponse{data=models.Video} "Version conflict, with the current video, or external ID already used"
+// @Failure 412 {object} ErrorResponse "Video was modified"
+// @Router /api/v1/videos/{id} [patch]
+func UpdateVideo(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	var req updateVideoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var tags []string
+	if req.Tags != nil {
+		tags = services.NormalizeTags(*req.Tags)
+	}
+
+	ctx := c.Request.Context()
+	current, err := services.VideoModel(queries.New(tenantDB).GetVideo(ctx, videoID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		}
+		return
+	}
+	if preconditionFailed(c, resourceETag(current.ID, current.UpdatedAt)) {
+		return
+	}
+	if current.Version != *req.Version {
+		versionConflict(c, current)
+		return
+	}
+	setFields, unsetFields, ok := videoCustomFields(c, tenantDB, current.OrganizationID, req.CustomFields)
+	if !ok {
+		return
+	}
+
+	// Matching the version makes the check hold even against a concurrent update
+	var video *models.Video
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		var err error
+		video, err = services.VideoModel(queries.New(tx).UpdateVideo(ctx, queries.UpdateVideoParams{
+			Title:               req.Title,
+			Description:         req.Description,
+			Visibility:          req.Visibility,
+			Tags:                tags,
+			RequiresEntitlement: req.RequiresEntitlement,
+			DownloadsEnabled:    req.DownloadsEnabled,
+			SetFields:           setFields,
+			UnsetFields:         unsetFields,
+			ExternalID:          req.ExternalID,
+			ID:                  videoID,
+			Version:             *req.Version,
+		}))
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoUpdated, video))
+	})
+	if err == sql.ErrNoRows {
+		if current, err := services.VideoModel(queries.New(tenantDB).GetVideo(ctx, videoID)); err == nil {
+			versionConflict(c, current)
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if databaseError(c, err) {
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update video"})
+		return
+	}
+
+	c.Header("ETag", resourceETag(video.ID, video.UpdatedAt))
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Video updated successfully",
+		"data":    video,
+	})
+}
+
+// DeleteVideo godoc
+// @Summary Delete video
+// @Description Deletes a video. It stays listed with status deleted, but no longer plays and leaves feeds and series; its stored files are kept. Deleting a deleted video does nothing.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=models.Video} "Video deleted"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id} [delete]
+func DeleteVideo(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var video *models.Video
+	err = tenantDB.WithTransaction(ctx, func(tx *sql.Tx) error {
+		if err := services.TransitionVideo(ctx, tx, videoID, models.VideoStatusDeleted, "deleted through the API"); err != nil {
+			return err
+		}
+		var err error
+		video, err = services.VideoModel(queries.New(tx).GetVideo(ctx, videoID))
+		if err != nil {
+			return err
+		}
+		return outbox.Write(ctx, tx, services.VideoEvent(outbox.EventVideoUpdated, video))
+	})
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete video"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Video deleted successfully",
+		"data":    video,
+	})
+}
+
+// GetVideoStatusHistory godoc
+// @Summary Get video status history
+// @Description Returns the current status of a video and every change of it, oldest first, with when and why it happened. Videos move from uploading to queued, processing and ready; uploads and imports that fail end in failed.
+// @Tags videos
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Video ID"
+// @Success 200 {object} SuccessResponse{data=object{video_id=string,status=string,status_changed_at=string,transitions=[]models.VideoStatusChange}} "Status history retrieved"
+// @Failure 400 {object} ErrorResponse "Invalid video ID"
+// @Failure 404 {object} ErrorResponse "Video not found"
+// @Router /api/v1/videos/{id}/status-history [get]
+func GetVideoStatusHistory(c *gin.Context) {
+	tenantDB, exists := database.GetStatelessTenantDBFromContext(c)
+	if !exists {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database connection not available"})
+		return
+	}
+
+	videoID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid video ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	current, err := queries.New(tenantDB).GetVideoStatus(ctx, videoID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Video not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get video"})
+		}
+		return
+	}
+	transitions, err := services.VideoStatusHistory(ctx, tenantDB, videoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Status history retrieved",
+		"data": gin.H{
+			"video_id":          videoID,
+			"status":            current.Status,
+			"status_changed_at": current.StatusChangedAt,
+			"transitions":       transitions,
+		},
+	})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/services"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, organization_id, url, event_types, description, created_by, created_at, updated_at`
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*models.WebhookEndpoint, error) {
+	var w models.WebhookEndpoint
+	if err := row.Scan(&w.ID, &w.OrganizationID, &w.URL, pq.Array(&w.EventTypes), &w.Description, &w.CreatedBy,
+		&w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if w.EventTypes == nil {
+		w.EventTypes = []string{}
+	}
+	return &w, nil
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description Lists the organization's webhook endpoints. Requires the owner or admin role.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Success 200 {object} SuccessResponse{data=object{webhooks=[]models.WebhookEndpoint,event_types=[]string}} "Webhooks retrieved"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Organization not found"
+// @Router /api/v1/organizations/{id}/webhooks [get]
+func ListWebhooks(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing webhooks")
+	if !ok {
+		return
+	}
+
+	rows, err := tenantDB.QueryContext(c.Request.Context(),
+		`SELECT `+webhookColumns+` FROM webhook_endpoints WHERE organization_id = $1 ORDER BY created_at`, orgID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query webhooks"})
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []models.WebhookEndpoint{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan webhook"})
+			return
+		}
+		webhooks = append(webhooks, *w)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error processing webhook results"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Webhooks retrieved successfully",
+		"data":    gin.H{"webhooks": webhooks, "event_types": outbox.EventTypes},
+	})
+}
+
+type createWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=2048"`
+	EventTypes  []string `json:"event_types" binding:"max=50"`
+	Description string   `json:"description" binding:"max=255"`
+}
+
+// CreateWebhook godoc
+// @Summary Create webhook
+// @Description Adds an HTTPS endpoint that receives the organization's events as signed POST requests. Without event_types it receives every event.
+// @Description The response includes the signing secret, which is not shown again. Requires the owner or admin role.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param request body createWebhookRequest true "URL, and optionally the event types to deliver and a description"
+// @Success 201 {object} SuccessResponse{data=models.WebhookEndpoint} "Webhook created, with its secret"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Router /api/v1/organizations/{id}/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing webhooks")
+	if !ok {
+		return
+	}
+
+	var req createWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err == nil {
+		err = services.CheckFetchURL(u, false)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook URL: " + err.Error()})
+		return
+	}
+	eventTypes := []string{}
+	for _, t := range req.EventTypes {
+		if !outbox.IsEventType(t) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + t})
+			return
+		}
+		eventTypes = append(eventTypes, t)
+	}
+
+	secret, err := services.NewWebhookSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+
+	userID := tenantDB.GetUserID()
+	webhook, err := scanWebhook(tenantDB.QueryRowContext(c.Request.Context(), `
+		INSERT INTO webhook_endpoints (organization_id, url, secret, event_types, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+webhookColumns,
+		orgID, u.String(), secret, pq.Array(eventTypes), strings.TrimSpace(req.Description), userID))
+	if err != nil {
+		logger.Error("Failed to create webhook for organization %s: %v", orgID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+	webhook.Secret = secret
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status":  "success",
+		"message": "Webhook created",
+		"data":    webhook,
+	})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook
+// @Description Removes a webhook endpoint; queued deliveries to it are dropped. Requires the owner or admin role.
+// @Tags webhooks
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "Organization ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 200 {object} SuccessResponse{data=object{id=string}} "Webhook deleted"
+// @Failure 403 {object} ErrorResponse "Insufficient role"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Router /api/v1/organizations/{id}/webhooks/{webhook_id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	tenantDB, orgID, _, ok := organizationAdmin(c, "Managing webhooks")
+	if !ok {
+		return
+	}
+
+	webhookID, err := uuid.Parse(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var deleted uuid.UUID
+	err = tenantDB.QueryRowContext(c.Request.Context(),
+		`DELETE FROM webhook_endpoints WHERE id = $1 AND organization_id = $2 RETURNING id`, webhookID, orgID).Scan(&deleted)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "Webhook deleted",
+		"data":    gin.H{"id": deleted},
+	})
+}
@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"openvdo/internal/jobs"
+	"openvdo/internal/storage"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// probeKey is looked up to verify storage access; it does not need to exist
+const probeKey = ".healthcheck"
+
+// Database checks that the database accepts connections
+func Database(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Migrations checks that golang-migrate applied at least the expected schema version and
+// did not leave the schema dirty
+func Migrations(db *sql.DB, expected uint) CheckFunc {
+	return func(ctx context.Context) error {
+		var version uint
+		var dirty bool
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no migrations applied, expected version %d", expected)
+		}
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d failed and left the schema dirty", version)
+		}
+		if version < expected {
+			return fmt.Errorf("schema at version %d, expected %d", version, expected)
+		}
+		return nil
+	}
+}
+
+// JobQueue checks that the jobs table can be queried the way workers claim from it, so an
+// instance that could not enqueue or run jobs takes no traffic
+func JobQueue(db *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		var queued bool
+		return db.QueryRowContext(ctx,
+			"SELECT EXISTS (SELECT 1 FROM jobs WHERE status = $1 AND run_at <= NOW())", jobs.StatusQueued).Scan(&queued)
+	}
+}
+
+// Redis checks that Redis answers a ping
+func Redis(client *redis.Client) CheckFunc {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// Storage checks that the object storage backend can be queried
+func Storage(store storage.Storage) CheckFunc {
+	return func(ctx context.Context) error {
+		_, err := store.Exists(ctx, probeKey)
+		return err
+	}
+}
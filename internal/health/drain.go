@@ -0,0 +1,104 @@
+package health
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"openvdo/pkg/logger"
+)
+
+// Drain takes an instance out of rotation before it shuts down. Once draining, /readyz fails so
+// load balancers stop sending new requests, while requests already routed here are still
+// served for the drain delay. Long-lived responses, such as event streams, are ended at random
+// points within the stream grace, so their clients reconnect to other instances a few at a
+// time instead of all at once.
+type Drain struct {
+	delay       time.Duration
+	streamGrace time.Duration
+
+	once    sync.Once
+	mu      sync.RWMutex
+	started time.Time
+	done    chan struct{}
+}
+
+// NewDrain creates a drain that has not started
+func NewDrain(delay, streamGrace time.Duration) *Drain {
+	return &Drain{delay: delay, streamGrace: streamGrace, done: make(chan struct{})}
+}
+
+// Start begins draining. Later calls do nothing, so a preStop hook and the SIGTERM following it
+// share one drain.
+func (d *Drain) Start(reason string) {
+	d.once.Do(func() {
+		d.mu.Lock()
+		d.started = time.Now()
+		d.mu.Unlock()
+		close(d.done)
+		logger.Info("Draining (%s): readiness fails from now on, streams end within %v", reason, d.streamGrace)
+	})
+}
+
+// Draining reports whether draining started
+func (d *Drain) Draining() bool {
+	select {
+	case <-d.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done is closed when draining starts
+func (d *Drain) Done() <-chan struct{} {
+	return d.done
+}
+
+// Wait blocks until the drain delay has passed since draining started, or ctx is done. It
+// returns at once when draining has not started.
+func (d *Drain) Wait(ctx context.Context) {
+	d.mu.RLock()
+	started := d.started
+	d.mu.RUnlock()
+	if started.IsZero() {
+		return
+	}
+	remaining := time.Until(started.Add(d.delay))
+	if remaining <= 0 {
+		return
+	}
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// StreamContext derives the context of a long-lived response from that of its request. It is
+// cancelled when the request's is, or at a random point within the stream grace after draining
+// starts, when the handler should tell the client to reconnect and return. cancel must be called
+// once the response ends.
+func (d *Drain) StreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.done:
+		}
+		if d.streamGrace > 0 {
+			timer := time.NewTimer(time.Duration(rand.Int63n(int64(d.streamGrace))))
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+		}
+		cancel()
+	}()
+	return ctx, cancel
+}
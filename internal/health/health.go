@@ -0,0 +1,196 @@
+// Package health runs dependency checks in the background and serves the cached results,
+// so readiness probes stay fast and cannot pile up load on a struggling dependency.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"openvdo/internal/buildinfo"
+	"openvdo/pkg/logger"
+)
+
+// CheckFunc reports an error when a dependency is not usable
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of the last run of a check
+type Result struct {
+	Healthy    bool      `json:"healthy"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Report aggregates the results of all checks
+type Report struct {
+	Ready     bool              `json:"ready"`
+	Version   string            `json:"version"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+}
+
+// Registry holds the readiness checks and their cached results
+type Registry struct {
+	checks    []check
+	interval  time.Duration
+	timeout   time.Duration
+	mu        sync.RWMutex
+	results   map[string]Result
+	checkedAt time.Time
+	ticker    *time.Ticker
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewRegistry creates a registry refreshing every interval. timeout is the default per-check timeout.
+func NewRegistry(interval, timeout time.Duration) *Registry {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Registry{
+		interval: interval,
+		timeout:  timeout,
+		results:  make(map[string]Result),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register adds a named check. A zero timeout uses the registry default.
+// Checks must be registered before Start.
+func (r *Registry) Register(name string, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = r.timeout
+	}
+	r.checks = append(r.checks, check{name: name, timeout: timeout, fn: fn})
+}
+
+// Start runs all checks once, then refreshes them in the background until Stop is called
+func (r *Registry) Start() {
+	r.Refresh(r.ctx)
+
+	r.ticker = time.NewTicker(r.interval)
+	go func() {
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-r.ticker.C:
+				r.Refresh(r.ctx)
+			}
+		}
+	}()
+
+	logger.Info("Health checks started (%d checks, interval %v)", len(r.checks), r.interval)
+}
+
+// Stop stops the background refresh
+func (r *Registry) Stop() {
+	r.cancel()
+	if r.ticker != nil {
+		r.ticker.Stop()
+	}
+}
+
+// Refresh runs all checks concurrently, each bounded by its own timeout
+func (r *Registry) Refresh(ctx context.Context) {
+	results := make(map[string]Result, len(r.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range r.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			result := run(ctx, c)
+
+			mu.Lock()
+			results[c.name] = result
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	previous := r.results
+	r.results = results
+	r.checkedAt = time.Now()
+	r.mu.Unlock()
+
+	for name, result := range results {
+		if prev, ok := previous[name]; ok && prev.Healthy == result.Healthy {
+			continue
+		}
+		if result.Healthy {
+			logger.Info("Health check %s passing", name)
+		} else {
+			logger.Error("Health check %s failing: %s", name, result.Error)
+		}
+	}
+}
+
+// run executes one check, converting timeouts and panics into failures
+func run(ctx context.Context, c check) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		result.CheckedAt = time.Now()
+	}()
+
+	// A check that ignores its context must not hold up the refresh
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return Result{Error: err.Error()}
+		}
+		return Result{Healthy: true}
+	case <-ctx.Done():
+		return Result{Error: fmt.Sprintf("timed out after %v", c.timeout)}
+	}
+}
+
+// Report returns the cached results. Results older than three refresh intervals count as
+// failures, since the refresh loop itself is then stuck.
+func (r *Registry) Report() Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := Report{
+		Ready:     !r.checkedAt.IsZero(),
+		Version:   buildinfo.Version(),
+		Checks:    make(map[string]Result, len(r.results)),
+		CheckedAt: r.checkedAt,
+	}
+	stale := time.Since(r.checkedAt) > 3*r.interval
+
+	for name, result := range r.results {
+		if stale && result.Healthy {
+			result.Healthy = false
+			result.Error = "result is stale"
+		}
+		if !result.Healthy {
+			report.Ready = false
+		}
+		report.Checks[name] = result
+	}
+	return report
+}
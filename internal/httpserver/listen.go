@@ -0,0 +1,152 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"openvdo/internal/config"
+)
+
+// Ways the server can be reached, chosen with SERVER_LISTEN
+const (
+	ListenTCP     = "tcp"
+	ListenUnix    = "unix"
+	ListenSystemd = "systemd"
+)
+
+// systemdFirstFD is the first file descriptor systemd passes sockets as
+const systemdFirstFD = 3
+
+// Listen opens the listener cfg selects: a TCP port, a Unix socket or the socket systemd
+// passed in. It returns what it listens on, for logging.
+func Listen(cfg config.Server) (net.Listener, string, error) {
+	switch cfg.Listen {
+	case ListenTCP, "":
+		ln, err := net.Listen("tcp", ":"+cfg.Port)
+		if err != nil {
+			return nil, "", err
+		}
+		return ln, "port " + cfg.Port, nil
+	case ListenUnix:
+		ln, err := listenUnix(cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		return ln, "Unix socket " + cfg.UnixSocket, nil
+	case ListenSystemd:
+		ln, err := listenSystemd()
+		if err != nil {
+			return nil, "", err
+		}
+		return ln, "systemd socket " + ln.Addr().String(), nil
+	default:
+		return nil, "", fmt.Errorf("unknown SERVER_LISTEN %q, expected tcp, unix or systemd", cfg.Listen)
+	}
+}
+
+// listenUnix creates the socket file with the configured mode and group. A socket left behind
+// by a previous run is replaced; any other file at the path is an error.
+func listenUnix(cfg config.Server) (net.Listener, error) {
+	if cfg.UnixSocket == "" {
+		return nil, errors.New("UNIX_SOCKET is required with SERVER_LISTEN=unix")
+	}
+	mode, err := strconv.ParseUint(cfg.UnixSocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return nil, fmt.Errorf("invalid UNIX_SOCKET_MODE %q, expected octal permissions such as 0660", cfg.UnixSocketMode)
+	}
+	gid := -1
+	if cfg.UnixSocketGroup != "" {
+		group, err := user.LookupGroup(cfg.UnixSocketGroup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up UNIX_SOCKET_GROUP: %w", err)
+		}
+		if gid, err = strconv.Atoi(group.Gid); err != nil {
+			return nil, fmt.Errorf("group %s has no numeric ID", cfg.UnixSocketGroup)
+		}
+	}
+
+	if info, err := os.Lstat(cfg.UnixSocket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", cfg.UnixSocket)
+		}
+		if err := os.Remove(cfg.UnixSocket); err != nil {
+			return nil, fmt.Errorf("failed to remove the stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", cfg.UnixSocket)
+	if err != nil {
+		return nil, err
+	}
+	// The socket is created with the umask applied; connecting needs write permission
+	if err := os.Chmod(cfg.UnixSocket, os.FileMode(mode)); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set the socket's permissions: %w", err)
+	}
+	if gid >= 0 {
+		if err := os.Chown(cfg.UnixSocket, -1, gid); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set the socket's group: %w", err)
+		}
+	}
+	return localListener{ln}, nil
+}
+
+// listenSystemd takes over the socket systemd opened for the service, following the
+// sd_listen_fds protocol
+func listenSystemd() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no socket passed by systemd: LISTEN_PID is not this process")
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, errors.New("no socket passed by systemd: LISTEN_FDS is not set")
+	}
+	if fds > 1 {
+		return nil, fmt.Errorf("systemd passed %d sockets, the server takes one", fds)
+	}
+	// Child processes must not take the socket as well
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(systemdFirstFD, "systemd socket")
+	ln, err := net.FileListener(f)
+	// FileListener duplicates the descriptor
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use the socket passed by systemd: %w", err)
+	}
+	if ln.Addr().Network() == "unix" {
+		return localListener{ln}, nil
+	}
+	return ln, nil
+}
+
+// localListener accepts connections over a Unix socket as coming from the loopback address.
+// Peers of Unix sockets have no IP address, which would leave requests without a client IP;
+// as loopback, the proxy in front is trusted and its X-Forwarded-For header names the client.
+type localListener struct {
+	net.Listener
+}
+
+func (l localListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return localConn{conn}, nil
+}
+
+type localConn struct {
+	net.Conn
+}
+
+func (localConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
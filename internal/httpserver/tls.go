@@ -0,0 +1,92 @@
+// Package httpserver sets up how the API server is reached: TLS termination with static or
+// Let's Encrypt certificates, client certificates for the admin API and the HTTP versions
+// offered.
+package httpserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"openvdo/internal/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLS is the TLS set-up of the server
+type TLS struct {
+	Config *tls.Config
+	// Challenges answers HTTP-01 challenges and redirects other requests to HTTPS; it is nil
+	// without autocert
+	Challenges http.Handler
+}
+
+// NewTLS loads the certificates cfg names, or prepares requesting them from Let's Encrypt. It
+// returns nil when TLS is not enabled.
+func NewTLS(cfg config.TLS) (*TLS, error) {
+	if !cfg.Enabled() {
+		if cfg.ClientCAFile != "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE needs TLS, with TLS_CERT_FILE or TLS_AUTOCERT_HOSTS")
+		}
+		return nil, nil
+	}
+	if cfg.CertFile != "" && len(cfg.AutocertHosts) > 0 {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_AUTOCERT_HOSTS are mutually exclusive")
+	}
+
+	t := &TLS{}
+	if cfg.CertFile != "" {
+		if cfg.KeyFile == "" {
+			return nil, fmt.Errorf("TLS_KEY_FILE is required with TLS_CERT_FILE")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the TLS certificate: %w", err)
+		}
+		t.Config = &tls.Config{Certificates: []tls.Certificate{cert}}
+	} else {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertHosts...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		// Also answers TLS-ALPN-01 challenges, through the acme-tls/1 protocol it adds
+		t.Config = m.TLSConfig()
+		if cfg.AutocertHTTPAddr != "" {
+			t.Challenges = m.HTTPHandler(nil)
+		}
+	}
+	t.Config.MinVersion = tls.VersionTLS12
+	if !cfg.HTTP2 {
+		t.Config.NextProtos = slices.DeleteFunc(t.Config.NextProtos, func(p string) bool { return p == "h2" })
+	}
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the client CAs: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.ClientCAFile)
+		}
+		// Certificates are only required by the admin API, which checks for a verified chain
+		t.Config.ClientCAs = pool
+		t.Config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return t, nil
+}
+
+// Protocols are the HTTP versions the server offers. HTTP/2 is only negotiated over TLS,
+// unless cleartext HTTP/2 is on for a proxy speaking it to the server.
+func Protocols(cfg config.TLS) *http.Protocols {
+	p := new(http.Protocols)
+	p.SetHTTP1(true)
+	p.SetHTTP2(cfg.HTTP2)
+	p.SetUnencryptedHTTP2(cfg.HTTP2Cleartext)
+	return p
+}
@@ -0,0 +1,207 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+// catalog holds the translations of the most common error messages by language
+var catalog = map[language.Tag]map[string]string{
+	language.Spanish: {
+		"Database connection not available":                              "Conexión a la base de datos no disponible",
+		"Database connection failed":                                     "Error de conexión a la base de datos",
+		"Authentication required":                                        "Se requiere autenticación",
+		"User not authenticated":                                         "Usuario no autenticado",
+		"Invalid user identification":                                    "Identificación de usuario no válida",
+		"Invalid, expired or revoked session":                            "Sesión no válida, caducada o revocada",
+		"User session not found":                                         "Sesión de usuario no encontrada",
+		"Insufficient permissions":                                       "Permisos insuficientes",
+		"Rate limit exceeded, try again later":                           "Límite de solicitudes superado, inténtalo más tarde",
+		"Invalid X-Org-ID header":                                        "Cabecera X-Org-ID no válida",
+		"Invalid X-Timezone header":                                      "Cabecera X-Timezone no válida",
+		"Not a member of the organization in X-Org-ID":                   "No eres miembro de la organización indicada en X-Org-ID",
+		"Access from this IP address is not allowed by the organization": "La organización no permite el acceso desde esta dirección IP",
+		"Organization region unavailable":                                "Región de la organización no disponible",
+		"Organization not found":                                         "Organización no encontrada",
+		"Invalid organization ID":                                        "ID de organización no válido",
+		"User not found":                                                 "Usuario no encontrado",
+		"Video not found":                                                "Vídeo no encontrado",
+		"Invalid video ID":                                               "ID de vídeo no válido",
+		"Failed to get video":                                            "No se pudo obtener el vídeo",
+		"Video was deleted":                                              "El vídeo fue eliminado",
+		"Video is unavailable":                                           "El vídeo no está disponible",
+		"Video is not ready for playback":                                "El vídeo no está listo para reproducirse",
+		"An entitlement is required to play this video":                  "Se necesita un derecho de acceso para reproducir este vídeo",
+		"Series not found":                                               "Serie no encontrada",
+		"Invalid series ID":                                              "ID de serie no válido",
+		"Smart playlist not found":                                       "Lista inteligente no encontrada",
+		"Invalid smart playlist ID":                                      "ID de lista inteligente no válido",
+		"Custom field not found":                                         "Campo personalizado no encontrado",
+		"Review not found":                                               "Revisión no encontrada",
+		"Job not found":                                                  "Tarea no encontrada",
+		"Download not found":                                             "Descarga no encontrada",
+		"Feed not found":                                                 "Feed no encontrado",
+		"Image not found":                                                "Imagen no encontrada",
+		"Name must not be empty":                                         "El nombre no puede estar vacío",
+		"Nothing to update":                                              "Nada que actualizar",
+		"Resource was modified; fetch it again and retry with the new ETag":                             "El recurso fue modificado; vuelve a obtenerlo y reintenta con el nuevo ETag",
+		"Resource was changed by someone else; merge with the current state and retry with its version": "Otra persona cambió el recurso; combina tus cambios con el estado actual y reintenta con su versión",
+		"An organization with this name already exists":                                                 "Ya existe una organización con este nombre",
+		"Another organization has this external ID":                                                     "Otra organización tiene este ID externo",
+		"Another video of the organization has this external ID":                                        "Otro vídeo de la organización tiene este ID externo",
+		"A user with this email address already exists":                                                 "Ya existe un usuario con esta dirección de correo electrónico",
+		"A resource with this value already exists":                                                     "Ya existe un recurso con este valor",
+		"A referenced resource does not exist":                                                          "Un recurso referenciado no existe",
+		"The resource is still in use":                                                                  "El recurso todavía está en uso",
+		"The request conflicted with a concurrent change; retry it":                                     "La solicitud entró en conflicto con un cambio simultáneo; reinténtala",
+	},
+	language.French: {
+		"Database connection not available":                              "Connexion à la base de données indisponible",
+		"Database connection failed":                                     "Échec de la connexion à la base de données",
+		"Authentication required":                                        "Authentification requise",
+		"User not authenticated":                                         "Utilisateur non authentifié",
+		"Invalid user identification":                                    "Identification de l'utilisateur invalide",
+		"Invalid, expired or revoked session":                            "Session invalide, expirée ou révoquée",
+		"User session not found":                                         "Session utilisateur introuvable",
+		"Insufficient permissions":                                       "Permissions insuffisantes",
+		"Rate limit exceeded, try again later":                           "Limite de requêtes dépassée, réessayez plus tard",
+		"Invalid X-Org-ID header":                                        "En-tête X-Org-ID invalide",
+		"Invalid X-Timezone header":                                      "En-tête X-Timezone invalide",
+		"Not a member of the organization in X-Org-ID":                   "Vous n'êtes pas membre de l'organisation indiquée dans X-Org-ID",
+		"Access from this IP address is not allowed by the organization": "L'organisation n'autorise pas l'accès depuis cette adresse IP",
+		"Organization region unavailable":                                "Région de l'organisation indisponible",
+		"Organization not found":                                         "Organisation introuvable",
+		"Invalid organization ID":                                        "ID d'organisation invalide",
+		"User not found":                                                 "Utilisateur introuvable",
+		"Video not found":                                                "Vidéo introuvable",
+		"Invalid video ID":                                               "ID de vidéo invalide",
+		"Failed to get video":                                            "Impossible de récupérer la vidéo",
+		"Video was deleted":                                              "La vidéo a été supprimée",
+		"Video is unavailable":                                           "La vidéo n'est pas disponible",
+		"Video is not ready for playback":                                "La vidéo n'est pas prête à être lue",
+		"An entitlement is required to play this video":                  "Un droit d'accès est nécessaire pour lire cette vidéo",
+		"Series not found":                                               "Série introuvable",
+		"Invalid series ID":                                              "ID de série invalide",
+		"Smart playlist not found":                                       "Playlist intelligente introuvable",
+		"Invalid smart playlist ID":                                      "ID de playlist intelligente invalide",
+		"Custom field not found":                                         "Champ personnalisé introuvable",
+		"Review not found":                                               "Révision introuvable",
+		"Job not found":                                                  "Tâche introuvable",
+		"Download not found":                                             "Téléchargement introuvable",
+		"Feed not found":                                                 "Flux introuvable",
+		"Image not found":                                                "Image introuvable",
+		"Name must not be empty":                                         "Le nom ne doit pas être vide",
+		"Nothing to update":                                              "Rien à mettre à jour",
+		"Resource was modified; fetch it again and retry with the new ETag":                             "La ressource a été modifiée ; récupérez-la à nouveau et réessayez avec le nouvel ETag",
+		"Resource was changed by someone else; merge with the current state and retry with its version": "La ressource a été modifiée par quelqu'un d'autre ; fusionnez avec l'état actuel et réessayez avec sa version",
+		"An organization with this name already exists":                                                 "Une organisation portant ce nom existe déjà",
+		"Another organization has this external ID":                                                     "Une autre organisation a cet ID externe",
+		"Another video of the organization has this external ID":                                        "Une autre vidéo de l'organisation a cet ID externe",
+		"A user with this email address already exists":                                                 "Un utilisateur avec cette adresse e-mail existe déjà",
+		"A resource with this value already exists":                                                     "Une ressource avec cette valeur existe déjà",
+		"A referenced resource does not exist":                                                          "Une ressource référencée n'existe pas",
+		"The resource is still in use":                                                                  "La ressource est encore utilisée",
+		"The request conflicted with a concurrent change; retry it":                                     "La requête est entrée en conflit avec une modification simultanée ; réessayez-la",
+	},
+	language.German: {
+		"Database connection not available":                              "Datenbankverbindung nicht verfügbar",
+		"Database connection failed":                                     "Datenbankverbindung fehlgeschlagen",
+		"Authentication required":                                        "Authentifizierung erforderlich",
+		"User not authenticated":                                         "Benutzer nicht authentifiziert",
+		"Invalid user identification":                                    "Ungültige Benutzerkennung",
+		"Invalid, expired or revoked session":                            "Ungültige, abgelaufene oder widerrufene Sitzung",
+		"User session not found":                                         "Benutzersitzung nicht gefunden",
+		"Insufficient permissions":                                       "Unzureichende Berechtigungen",
+		"Rate limit exceeded, try again later":                           "Anfragelimit überschritten, versuchen Sie es später erneut",
+		"Invalid X-Org-ID header":                                        "Ungültiger X-Org-ID-Header",
+		"Invalid X-Timezone header":                                      "Ungültiger X-Timezone-Header",
+		"Not a member of the organization in X-Org-ID":                   "Kein Mitglied der Organisation in X-Org-ID",
+		"Access from this IP address is not allowed by the organization": "Die Organisation erlaubt keinen Zugriff von dieser IP-Adresse",
+		"Organization region unavailable":                                "Region der Organisation nicht verfügbar",
+		"Organization not found":                                         "Organisation nicht gefunden",
+		"Invalid organization ID":                                        "Ungültige Organisations-ID",
+		"User not found":                                                 "Benutzer nicht gefunden",
+		"Video not found":                                                "Video nicht gefunden",
+		"Invalid video ID":                                               "Ungültige Video-ID",
+		"Failed to get video":                                            "Video konnte nicht abgerufen werden",
+		"Video was deleted":                                              "Das Video wurde gelöscht",
+		"Video is unavailable":                                           "Das Video ist nicht verfügbar",
+		"Video is not ready for playback":                                "Das Video ist noch nicht abspielbereit",
+		"An entitlement is required to play this video":                  "Zum Abspielen dieses Videos ist eine Berechtigung erforderlich",
+		"Series not found":                                               "Serie nicht gefunden",
+		"Invalid series ID":                                              "Ungültige Serien-ID",
+		"Smart playlist not found":                                       "Intelligente Playlist nicht gefunden",
+		"Invalid smart playlist ID":                                      "Ungültige ID der intelligenten Playlist",
+		"Custom field not found":                                         "Benutzerdefiniertes Feld nicht gefunden",
+		"Review not found":                                               "Prüfung nicht gefunden",
+		"Job not found":                                                  "Auftrag nicht gefunden",
+		"Download not found":                                             "Download nicht gefunden",
+		"Feed not found":                                                 "Feed nicht gefunden",
+		"Image not found":                                                "Bild nicht gefunden",
+		"Name must not be empty":                                         "Der Name darf nicht leer sein",
+		"Nothing to update":                                              "Nichts zu aktualisieren",
+		"Resource was modified; fetch it again and retry with the new ETag":                             "Die Ressource wurde geändert; rufen Sie sie erneut ab und versuchen Sie es mit dem neuen ETag",
+		"Resource was changed by someone else; merge with the current state and retry with its version": "Die Ressource wurde von jemand anderem geändert; führen Sie sie mit dem aktuellen Stand zusammen und versuchen Sie es mit dessen Version erneut",
+		"An organization with this name already exists":                                                 "Eine Organisation mit diesem Namen existiert bereits",
+		"Another organization has this external ID":                                                     "Eine andere Organisation hat diese externe ID",
+		"Another video of the organization has this external ID":                                        "Ein anderes Video der Organisation hat diese externe ID",
+		"A user with this email address already exists":                                                 "Ein Benutzer mit dieser E-Mail-Adresse existiert bereits",
+		"A resource with this value already exists":                                                     "Eine Ressource mit diesem Wert existiert bereits",
+		"A referenced resource does not exist":                                                          "Eine referenzierte Ressource existiert nicht",
+		"The resource is still in use":                                                                  "Die Ressource wird noch verwendet",
+		"The request conflicted with a concurrent change; retry it":                                     "Die Anfrage stand im Konflikt mit einer gleichzeitigen Änderung; versuchen Sie es erneut",
+	},
+	language.BrazilianPortuguese: {
+		"Database connection not available":                              "Conexão com o banco de dados indisponível",
+		"Database connection failed":                                     "Falha na conexão com o banco de dados",
+		"Authentication required":                                        "Autenticação necessária",
+		"User not authenticated":                                         "Usuário não autenticado",
+		"Invalid user identification":                                    "Identificação de usuário inválida",
+		"Invalid, expired or revoked session":                            "Sessão inválida, expirada ou revogada",
+		"User session not found":                                         "Sessão do usuário não encontrada",
+		"Insufficient permissions":                                       "Permissões insuficientes",
+		"Rate limit exceeded, try again later":                           "Limite de requisições excedido, tente novamente mais tarde",
+		"Invalid X-Org-ID header":                                        "Cabeçalho X-Org-ID inválido",
+		"Invalid X-Timezone header":                                      "Cabeçalho X-Timezone inválido",
+		"Not a member of the organization in X-Org-ID":                   "Você não é membro da organização em X-Org-ID",
+		"Access from this IP address is not allowed by the organization": "A organização não permite acesso a partir deste endereço IP",
+		"Organization region unavailable":                                "Região da organização indisponível",
+		"Organization not found":                                         "Organização não encontrada",
+		"Invalid organization ID":                                        "ID de organização inválido",
+		"User not found":                                                 "Usuário não encontrado",
+		"Video not found":                                                "Vídeo não encontrado",
+		"Invalid video ID":                                               "ID de vídeo inválido",
+		"Failed to get video":                                            "Não foi possível obter o vídeo",
+		"Video was deleted":                                              "O vídeo foi excluído",
+		"Video is unavailable":                                           "O vídeo não está disponível",
+		"Video is not ready for playback":                                "O vídeo não está pronto para reprodução",
+		"An entitlement is required to play this video":                  "É necessário um direito de acesso para reproduzir este vídeo",
+		"Series not found":                                               "Série não encontrada",
+		"Invalid series ID":                                              "ID de série inválido",
+		"Smart playlist not found":                                       "Playlist inteligente não encontrada",
+		"Invalid smart playlist ID":                                      "ID de playlist inteligente inválido",
+		"Custom field not found":                                         "Campo personalizado não encontrado",
+		"Review not found":                                               "Revisão não encontrada",
+		"Job not found":                                                  "Tarefa não encontrada",
+		"Download not found":                                             "Download não encontrado",
+		"Feed not found":                                                 "Feed não encontrado",
+		"Image not found":                                                "Imagem não encontrada",
+		"Name must not be empty":                                         "O nome não pode ficar vazio",
+		"Nothing to update":                                              "Nada para atualizar",
+		"Resource was modified; fetch it again and retry with the new ETag":                             "O recurso foi modificado; obtenha-o novamente e tente de novo com o novo ETag",
+		"Resource was changed by someone else; merge with the current state and retry with its version": "O recurso foi alterado por outra pessoa; mescle com o estado atual e tente novamente com a versão dele",
+		"An organization with this name already exists":                                                 "Já existe uma organização com este nome",
+		"Another organization has this external ID":                                                     "Outra organização tem este ID externo",
+		"Another video of the organization has this external ID":                                        "Outro vídeo da organização tem este ID externo",
+		"A user with this email address already exists":                                                 "Já existe um usuário com este endereço de e-mail",
+		"A resource with this value already exists":                                                     "Já existe um recurso com este valor",
+		"A referenced resource does not exist":                                                          "Um recurso referenciado não existe",
+		"The resource is still in use":                                                                  "O recurso ainda está em uso",
+		"The request conflicted with a concurrent change; retry it":                                     "A solicitação entrou em conflito com uma alteração simultânea; tente novamente",
+	},
+}
+
+// prefixes translate the start of messages that go on with details, such as validation errors
+var prefixes = map[language.Tag]map[string]string{
+	language.Spanish:             {"Invalid request body: ": "Cuerpo de la solicitud no válido: "},
+	language.French:              {"Invalid request body: ": "Corps de la requête invalide : "},
+	language.German:              {"Invalid request body: ": "Ungültiger Anfragetext: "},
+	language.BrazilianPortuguese: {"Invalid request body: ": "Corpo da requisição inválido: "},
+}
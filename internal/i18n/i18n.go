@@ -0,0 +1,53 @@
+// Package i18n translates the messages of API responses into the languages callers prefer.
+// Messages are written in English, which is also what callers get for messages and
+// languages the catalog does not cover.
+package i18n
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Default is the language messages are written in
+var Default = language.English
+
+// supported are the languages of the catalog, Default first so it wins ties
+var supported = []language.Tag{Default, language.Spanish, language.French, language.German, language.BrazilianPortuguese}
+
+var matcher = language.NewMatcher(supported)
+
+// Negotiate returns the supported language that best fits an Accept-Language header, or
+// Default when none does
+func Negotiate(acceptLanguage string) language.Tag {
+	if acceptLanguage == "" {
+		return Default
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return supported[index]
+}
+
+// Translate returns message in lang. Messages starting with a translated prefix, such as
+// "Invalid request body: ", keep the details that follow it. Anything else is returned as is.
+func Translate(lang language.Tag, message string) string {
+	messages, ok := catalog[lang]
+	if !ok {
+		return message
+	}
+	if translated, ok := messages[message]; ok {
+		return translated
+	}
+	for prefix, translated := range prefixes[lang] {
+		if rest, ok := strings.CutPrefix(message, prefix); ok {
+			return translated + rest
+		}
+	}
+	return message
+}
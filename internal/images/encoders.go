@@ -0,0 +1,83 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+)
+
+// externalEncoder produces a format the standard library cannot encode by running its
+// reference command-line encoder on a PNG
+type externalEncoder struct {
+	format  string
+	path    string
+	timeout time.Duration
+	args    func(quality int, in, out string) []string
+}
+
+func newExternalEncoder(format string, cfg config.Images) (*externalEncoder, error) {
+	enc := &externalEncoder{format: format, timeout: cfg.EncodeTimeout}
+	var command string
+	switch format {
+	case FormatWebP:
+		command = cfg.CWebPPath
+		enc.args = func(quality int, in, out string) []string {
+			return []string{"-quiet", "-q", strconv.Itoa(quality), "-metadata", "none", in, "-o", out}
+		}
+	case FormatAVIF:
+		command = cfg.AVIFEncPath
+		enc.args = func(quality int, in, out string) []string {
+			return []string{"--speed", "6", "-q", strconv.Itoa(quality), in, out}
+		}
+	default:
+		return nil, fmt.Errorf("unknown format")
+	}
+
+	path, err := exec.LookPath(command)
+	if err != nil {
+		return nil, fmt.Errorf("encoder %s not found", command)
+	}
+	enc.path = path
+	return enc, nil
+}
+
+func (e *externalEncoder) encode(ctx context.Context, img image.Image, quality int) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "openvdo-image-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.png")
+	out := filepath.Join(dir, "out."+e.format)
+
+	var buf bytes.Buffer
+	// The intermediate file is read once, so compressing it harder is wasted time
+	if err := (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(in, buf.Bytes(), 0o600); err != nil {
+		return nil, err
+	}
+
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, e.path, e.args(quality, in, out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", filepath.Base(e.path), err, strings.TrimSpace(string(output)))
+	}
+	return os.ReadFile(out)
+}
@@ -0,0 +1,225 @@
+// Package images turns uploaded pictures, such as video thumbnails, avatars and banners, into
+// resized variants in formats browsers can pick from. Images are decoded and re-encoded, so
+// EXIF and other metadata never reach storage; JPEG orientation is applied to the pixels first.
+package images
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"sort"
+
+	"openvdo/internal/config"
+	"openvdo/pkg/logger"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Output formats
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatAVIF = "avif"
+)
+
+// ErrUnsupported is returned for data that is not a JPEG, PNG or WebP image
+var ErrUnsupported = errors.New("unsupported image format; use JPEG, PNG or WebP")
+
+// ErrTooLarge is returned for images with more pixels than configured
+var ErrTooLarge = errors.New("image dimensions are too large")
+
+var decodableFormats = map[string]bool{"jpeg": true, "png": true, "webp": true}
+
+var contentTypes = map[string]string{
+	FormatJPEG: "image/jpeg",
+	FormatPNG:  "image/png",
+	FormatWebP: "image/webp",
+	FormatAVIF: "image/avif",
+}
+
+// ContentType returns the MIME type of an output format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// Spec describes the variants to produce
+type Spec struct {
+	// Widths are the target widths; widths above the image's own are capped to it
+	Widths []int
+	// Aspect crops the image around its center to width/height; zero keeps its shape
+	Aspect float64
+}
+
+// Variant is one encoded size and format of a processed image
+type Variant struct {
+	Width       int
+	Height      int
+	Format      string
+	ContentType string
+	Data        []byte
+}
+
+// Processor decodes, resizes and encodes images
+type Processor struct {
+	config   config.Images
+	encoders []*externalEncoder
+}
+
+// NewProcessor creates a processor. Configured formats whose encoder is not installed are
+// left out with a warning, since JPEG or PNG variants are always produced.
+func NewProcessor(cfg config.Images) *Processor {
+	p := &Processor{config: cfg}
+	for _, format := range cfg.Formats {
+		enc, err := newExternalEncoder(format, cfg)
+		if err != nil {
+			logger.Info("Image format %s disabled: %v", format, err)
+			continue
+		}
+		p.encoders = append(p.encoders, enc)
+	}
+	return p
+}
+
+// Formats lists the formats produced besides JPEG or PNG
+func (p *Processor) Formats() []string {
+	formats := make([]string, len(p.encoders))
+	for i, enc := range p.encoders {
+		formats[i] = enc.format
+	}
+	return formats
+}
+
+// Process decodes data and returns its variants, largest first. Opaque images get JPEG
+// variants and transparent ones PNG, plus every available extra format.
+func (p *Processor) Process(ctx context.Context, data []byte, spec Spec) ([]Variant, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || !decodableFormats[format] {
+		return nil, ErrUnsupported
+	}
+	// Checked before decoding, since a small file can declare enormous dimensions
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > int64(p.config.MaxPixels) {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	img := toRGBA(src)
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	if spec.Aspect > 0 {
+		img = cropToAspect(img, spec.Aspect)
+	}
+
+	base := FormatJPEG
+	if !img.Opaque() {
+		base = FormatPNG
+	}
+
+	var variants []Variant
+	for _, width := range targetWidths(spec.Widths, img.Bounds().Dx()) {
+		scaled := scale(img, width)
+		b := scaled.Bounds()
+
+		encoded, err := p.encode(base, scaled)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, Variant{
+			Width: b.Dx(), Height: b.Dy(), Format: base, ContentType: ContentType(base), Data: encoded,
+		})
+
+		for _, enc := range p.encoders {
+			encoded, err := enc.encode(ctx, scaled, p.config.Quality)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				// The base format is always there to fall back on
+				logger.Error("Failed to encode %s image: %v", enc.format, err)
+				continue
+			}
+			variants = append(variants, Variant{
+				Width: b.Dx(), Height: b.Dy(), Format: enc.format, ContentType: ContentType(enc.format), Data: encoded,
+			})
+		}
+	}
+	return variants, nil
+}
+
+func (p *Processor) encode(format string, img *image.RGBA) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == FormatPNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: p.config.Quality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s image: %w", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// targetWidths returns the distinct widths capped to max, largest first, or max alone when
+// none are set
+func targetWidths(widths []int, max int) []int {
+	seen := make(map[int]bool)
+	var out []int
+	for _, w := range widths {
+		// Images are never upscaled; a width beyond the source is served at its full size
+		if w > max {
+			w = max
+		}
+		if w > 0 && !seen[w] {
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	if len(out) == 0 {
+		return []int{max}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(out)))
+	return out
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	if img, ok := src.(*image.RGBA); ok && img.Bounds().Min == (image.Point{}) {
+		return img
+	}
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(img, img.Bounds(), src, b.Min, draw.Src)
+	return img
+}
+
+func scale(img *image.RGBA, width int) *image.RGBA {
+	b := img.Bounds()
+	if width == b.Dx() {
+		return img
+	}
+	height := max(1, (b.Dy()*width+b.Dx()/2)/b.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+func cropToAspect(img *image.RGBA, aspect float64) *image.RGBA {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if float64(w)/float64(h) > aspect {
+		w = max(1, int(float64(h)*aspect+0.5))
+	} else {
+		h = max(1, int(float64(w)/aspect+0.5))
+	}
+	x, y := (b.Dx()-w)/2, (b.Dy()-h)/2
+	return img.SubImage(image.Rect(x, y, x+w, y+h)).(*image.RGBA)
+}
@@ -0,0 +1,111 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"openvdo/internal/models"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+)
+
+// Formats in order of preference when the client accepts them
+var preferredFormats = []string{FormatAVIF, FormatWebP}
+
+// Store writes variants under prefix and returns the image describing them. Every image gets
+// new keys, so replacing one never changes bytes that may be cached under an old key.
+func Store(ctx context.Context, store storage.Storage, prefix, source string, variants []Variant) (*models.Image, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	img := &models.Image{ID: hex.EncodeToString(b), Source: source, CreatedAt: time.Now().UTC()}
+	for _, v := range variants {
+		key := fmt.Sprintf("%s/%s/%dw.%s", prefix, img.ID, v.Width, v.Format)
+		if err := store.Put(ctx, key, bytes.NewReader(v.Data), int64(len(v.Data)), v.ContentType); err != nil {
+			Delete(context.WithoutCancel(ctx), store, img)
+			return nil, fmt.Errorf("failed to store image: %w", err)
+		}
+		img.Variants = append(img.Variants, models.ImageVariant{
+			Width:       v.Width,
+			Height:      v.Height,
+			Format:      v.Format,
+			ContentType: v.ContentType,
+			Key:         key,
+			SizeBytes:   int64(len(v.Data)),
+		})
+		if v.Width > img.Width {
+			img.Width, img.Height = v.Width, v.Height
+		}
+	}
+	return img, nil
+}
+
+// Delete removes the stored variants of an image. Failures only leave orphans behind, so they
+// are logged rather than returned.
+func Delete(ctx context.Context, store storage.Storage, img *models.Image) {
+	if img == nil {
+		return
+	}
+	for _, key := range img.Keys() {
+		if err := store.Delete(ctx, key); err != nil {
+			logger.Error("Failed to delete image %s: %v", key, err)
+		}
+	}
+}
+
+// Pick chooses the variant to serve: the narrowest at least width wide (the widest when none
+// is, or when width is zero) in the best format the Accept header allows
+func Pick(img *models.Image, width int, accept string) *models.ImageVariant {
+	if img == nil || len(img.Variants) == 0 {
+		return nil
+	}
+
+	target := 0
+	for _, v := range img.Variants {
+		switch {
+		case target == 0:
+			target = v.Width
+		case width > 0 && v.Width >= width && (v.Width < target || target < width):
+			target = v.Width
+		case (width <= 0 || target < width) && v.Width > target:
+			target = v.Width
+		}
+	}
+
+	var fallback *models.ImageVariant
+	best := len(preferredFormats)
+	var chosen *models.ImageVariant
+	for i := range img.Variants {
+		v := &img.Variants[i]
+		if v.Width != target {
+			continue
+		}
+		rank := len(preferredFormats)
+		for r, format := range preferredFormats {
+			if v.Format == format {
+				rank = r
+			}
+		}
+		if rank == len(preferredFormats) {
+			fallback = v
+			continue
+		}
+		if rank < best && strings.Contains(accept, ContentType(v.Format)) {
+			best, chosen = rank, v
+		}
+	}
+	if chosen != nil {
+		return chosen
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return &img.Variants[0]
+}
@@ -0,0 +1,235 @@
+package ipaccess
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"openvdo/internal/audit"
+	"openvdo/internal/database"
+	"openvdo/internal/metrics"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SettingsKey is the key of the rules in organization settings
+const SettingsKey = "ip_access"
+
+var denied = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "ip_access",
+	Name:      "denied_total",
+	Help:      "Requests refused by the IP access rules of their organization",
+})
+
+// invalidationChannel carries the IDs of organizations whose rules changed
+const invalidationChannel = "ipaccess:invalidate"
+
+// Guard enforces organizations' IP access rules. Rules are cached per instance for the cache
+// TTL. With Redis, a change made on one instance is announced to the others, which drop their
+// copy right away; without it they apply the change once their copy expires.
+type Guard struct {
+	db       *sql.DB
+	redis    *redis.Client
+	cacheTTL time.Duration
+
+	mu    sync.Mutex
+	cache map[uuid.UUID]cachedRules
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type cachedRules struct {
+	rules   *Rules
+	expires time.Time
+}
+
+// NewGuard creates a guard reading rules through db, which must not carry a tenant context.
+// Redis, when given, limits audit entries of repeated denials.
+func NewGuard(db *sql.DB, redisClient *redis.Client, cacheTTL time.Duration) *Guard {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Guard{
+		db: db, redis: redisClient, cacheTTL: cacheTTL, cache: make(map[uuid.UUID]cachedRules),
+		ctx: ctx, cancel: cancel,
+	}
+}
+
+// Start listens for rule changes made on other instances until Stop is called
+func (g *Guard) Start() {
+	if g.redis == nil {
+		return
+	}
+	sub := g.redis.Subscribe(g.ctx, invalidationChannel)
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		// The subscription reconnects by itself; changes announced meanwhile are missed and
+		// apply when the cached copy expires
+		for msg := range sub.Channel() {
+			if orgID, err := uuid.Parse(msg.Payload); err == nil {
+				g.drop(orgID)
+			}
+		}
+	}()
+	go func() {
+		<-g.ctx.Done()
+		sub.Close()
+	}()
+}
+
+// Stop stops listening for rule changes
+func (g *Guard) Stop() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+// Load reads an organization's rules as stored
+func Load(ctx context.Context, q database.Querier, orgID uuid.UUID) (models.IPAccessRules, error) {
+	rules := models.IPAccessRules{Allow: []string{}, Deny: []string{}}
+	raw, err := queries.New(q).GetIPAccessRules(ctx, queries.GetIPAccessRulesParams{Key: SettingsKey, ID: orgID})
+	if err != nil {
+		return rules, err
+	}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &rules); err != nil {
+			return rules, fmt.Errorf("invalid IP access rules: %w", err)
+		}
+	}
+	return rules, nil
+}
+
+// Rules returns an organization's compiled rules, from the cache while it is fresh
+func (g *Guard) Rules(ctx context.Context, orgID uuid.UUID) (*Rules, error) {
+	g.mu.Lock()
+	cached, ok := g.cache[orgID]
+	g.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.rules, nil
+	}
+
+	stored, err := Load(ctx, g.db, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rules, err := Parse(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	g.cache[orgID] = cachedRules{rules: rules, expires: time.Now().Add(g.cacheTTL)}
+	g.mu.Unlock()
+	return rules, nil
+}
+
+// Invalidate drops the cached rules of an organization after they changed, here and on the
+// instances listening through Redis
+func (g *Guard) Invalidate(ctx context.Context, orgID uuid.UUID) {
+	g.drop(orgID)
+	if g.redis != nil {
+		if err := g.redis.Publish(ctx, invalidationChannel, orgID.String()).Err(); err != nil {
+			log.Printf("WARN: Failed to announce new IP access rules of organization %s: %v", orgID, err)
+		}
+	}
+}
+
+func (g *Guard) drop(orgID uuid.UUID) {
+	g.mu.Lock()
+	delete(g.cache, orgID)
+	g.mu.Unlock()
+}
+
+// Middleware refuses requests acting in an organization from addresses its rules do not
+// allow. It runs after the database middleware, which resolves the organization.
+func (g *Guard) Middleware() gin.HandlerFunc {
+	return g.middleware(func(c *gin.Context) (uuid.UUID, bool) {
+		orgID, ok := c.Get(string(database.OrgIDKey))
+		if !ok {
+			return uuid.Nil, false
+		}
+		id, ok := orgID.(uuid.UUID)
+		return id, ok
+	})
+}
+
+// PathMiddleware refuses requests about the organization in the id path parameter, such as
+// managing its members, from addresses its rules do not allow
+func (g *Guard) PathMiddleware() gin.HandlerFunc {
+	return g.middleware(func(c *gin.Context) (uuid.UUID, bool) {
+		id, err := uuid.Parse(c.Param("id"))
+		return id, err == nil
+	})
+}
+
+func (g *Guard) middleware(organization func(*gin.Context) (uuid.UUID, bool)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orgID, ok := organization(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		rules, err := g.Rules(c.Request.Context(), orgID)
+		if err != nil {
+			// Failing open would let a broken rule set disable the restriction
+			logger.Error("Failed to load IP access rules of organization %s: %v", orgID, err)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to check IP access rules"})
+			return
+		}
+		addr, err := netip.ParseAddr(c.ClientIP())
+		if err != nil || rules.Allows(addr) {
+			c.Next()
+			return
+		}
+
+		denied.Inc()
+		g.recordDenial(c, orgID, addr)
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access from this IP address is not allowed by the organization"})
+	}
+}
+
+// recordDenial writes an audit entry for a refused request. Repeated denials of one user and
+// address are recorded once a minute, so a client retrying in a loop does not flood the log.
+func (g *Guard) recordDenial(c *gin.Context, orgID uuid.UUID, addr netip.Addr) {
+	ctx := c.Request.Context()
+	userID := uuid.Nil
+	if tenantDB, ok := database.GetStatelessTenantDBFromContext(c); ok {
+		userID = tenantDB.GetUserID()
+	}
+	if g.redis != nil {
+		key := fmt.Sprintf("ip_access:denied:%s:%s:%s", orgID, userID, addr)
+		if fresh, err := g.redis.SetNX(ctx, key, 1, time.Minute).Result(); err == nil && !fresh {
+			return
+		}
+	}
+
+	err := audit.Record(ctx, g.db, audit.Entry{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Action:         models.AuditIPAccessDenied,
+		IPAddress:      addr.String(),
+		Details:        map[string]string{"method": c.Request.Method, "path": c.Request.URL.Path},
+	})
+	if err != nil {
+		logger.Error("Failed to audit a request refused by IP access rules: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+// Package ipaccess restricts the addresses organizations' APIs are used from to the ranges
+// their owners and admins allow
+package ipaccess
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"openvdo/internal/models"
+)
+
+// MaxRanges bounds the ranges of each list, so checking and caching rules stays cheap
+const MaxRanges = 500
+
+// Rules are an organization's parsed IP access rules
+type Rules struct {
+	allow *trie
+	deny  *trie
+}
+
+// Parse validates and compiles rules. Ranges are CIDR prefixes or single addresses; IPv4
+// addresses mapped into IPv6 match IPv4 ranges.
+func Parse(rules models.IPAccessRules) (*Rules, error) {
+	if len(rules.Allow) > MaxRanges || len(rules.Deny) > MaxRanges {
+		return nil, fmt.Errorf("at most %d ranges may be allowed or denied", MaxRanges)
+	}
+	r := &Rules{}
+	var err error
+	if r.allow, err = compile(rules.Allow); err != nil {
+		return nil, err
+	}
+	if r.deny, err = compile(rules.Deny); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Normalize returns rules with their ranges in canonical form, e.g. 10.1.2.3/8 as 10.0.0.0/8,
+// or an error for invalid ones
+func Normalize(rules models.IPAccessRules) (models.IPAccessRules, error) {
+	normalized := models.IPAccessRules{Allow: []string{}, Deny: []string{}}
+	for _, list := range []struct {
+		in  []string
+		out *[]string
+	}{{rules.Allow, &normalized.Allow}, {rules.Deny, &normalized.Deny}} {
+		for _, s := range list.in {
+			prefix, err := parsePrefix(s)
+			if err != nil {
+				return models.IPAccessRules{}, err
+			}
+			*list.out = append(*list.out, prefix.String())
+		}
+	}
+	return normalized, nil
+}
+
+// Empty tells whether the rules allow every address
+func (r *Rules) Empty() bool {
+	return r == nil || (r.allow.empty() && r.deny.empty())
+}
+
+// Allows tells whether requests from addr are allowed
+func (r *Rules) Allows(addr netip.Addr) bool {
+	if r.Empty() {
+		return true
+	}
+	addr = addr.Unmap()
+	if r.deny.contains(addr) {
+		return false
+	}
+	return r.allow.empty() || r.allow.contains(addr)
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid range %q", s)
+		}
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), max(prefix.Bits()-96, 0))
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid address %q", s)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func compile(ranges []string) (*trie, error) {
+	t := &trie{}
+	for _, s := range ranges {
+		prefix, err := parsePrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		t.insert(prefix)
+	}
+	return t, nil
+}
+
+// trie is a binary trie of prefixes, one root per address family, so a lookup takes at most
+// one step per bit of the address however many ranges there are
+type trie struct {
+	v4, v6 *node
+}
+
+type node struct {
+	children [2]*node
+	// terminal marks the end of a prefix; everything below it is covered
+	terminal bool
+}
+
+func (t *trie) root(addr netip.Addr, create bool) **node {
+	root := &t.v6
+	if addr.Is4() {
+		root = &t.v4
+	}
+	if *root == nil && create {
+		*root = &node{}
+	}
+	return root
+}
+
+func (t *trie) insert(prefix netip.Prefix) {
+	n := *t.root(prefix.Addr(), true)
+	bytes := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		if n.terminal {
+			// Already covered by a shorter prefix
+			return
+		}
+		bit := bytes[i/8] >> (7 - i%8) & 1
+		if n.children[bit] == nil {
+			n.children[bit] = &node{}
+		}
+		n = n.children[bit]
+	}
+	n.terminal = true
+	n.children = [2]*node{}
+}
+
+func (t *trie) contains(addr netip.Addr) bool {
+	n := *t.root(addr, false)
+	bytes := addr.AsSlice()
+	for i := 0; n != nil; i++ {
+		if n.terminal {
+			return true
+		}
+		if i == len(bytes)*8 {
+			return false
+		}
+		n = n.children[bytes[i/8]>>(7-i%8)&1]
+	}
+	return false
+}
+
+func (t *trie) empty() bool {
+	return t == nil || (t.v4 == nil && t.v6 == nil)
+}
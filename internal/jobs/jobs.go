@@ -0,0 +1,244 @@
+// Package jobs is a Postgres-backed queue for background work. Jobs are rows in the jobs
+// table, so they survive restarts and any instance running workers can pick them up.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"openvdo/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	// StatusQuarantined jobs failed config.Jobs.QuarantineAfter times and are only retried one
+	// by one
+	StatusQuarantined = "quarantined"
+)
+
+const defaultMaxAttempts = 3
+
+// Job is a unit of background work. Payload fields under the top-level key "secrets", such
+// as credentials for a source, are left out when a job is marshaled.
+type Job struct {
+	ID             uuid.UUID       `json:"id"`
+	OrganizationID *uuid.UUID      `json:"organization_id,omitempty"`
+	ParentID       *uuid.UUID      `json:"parent_id,omitempty"`
+	Kind           string          `json:"kind"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Checkpoint     json.RawMessage `json:"checkpoint,omitempty" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Progress       float64         `json:"progress"`
+	Attempts       int             `json:"attempts"`
+	MaxAttempts    int             `json:"max_attempts"`
+	// Failures counts failed runs; only releasing the job from quarantine resets it
+	Failures int `json:"failures"`
+	// Retries counts how often the job was requeued by hand after failing
+	Retries    int        `json:"retries"`
+	LastError  *string    `json:"last_error,omitempty"`
+	RunAt      time.Time  `json:"run_at"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Children is filled in by callers that load ChildCounts
+	Children map[string]int `json:"children,omitempty"`
+}
+
+// MarshalJSON leaves the payload's secrets out, so jobs can be returned by the API
+func (j Job) MarshalJSON() ([]byte, error) {
+	type job Job
+	out := job(j)
+
+	var payload map[string]json.RawMessage
+	if json.Unmarshal(j.Payload, &payload) == nil {
+		if _, ok := payload["secrets"]; ok {
+			delete(payload, "secrets")
+			redacted, err := json.Marshal(payload)
+			if err != nil {
+				return nil, err
+			}
+			out.Payload = redacted
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Decode unmarshals the job's payload into v
+func (j *Job) Decode(v interface{}) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid %s payload: %w", j.Kind, err))
+	}
+	return nil
+}
+
+// Columns is the column list matching Scan
+const Columns = `id, organization_id, parent_id, kind, payload, checkpoint, status, progress, attempts, max_attempts,
+	failures, retries, last_error, run_at, created_by, created_at, updated_at, finished_at`
+
+// Scan scans a row selected with Columns
+func Scan(row interface{ Scan(...interface{}) error }) (*Job, error) {
+	var j Job
+	var payload, checkpoint []byte
+	err := row.Scan(&j.ID, &j.OrganizationID, &j.ParentID, &j.Kind, &payload, &checkpoint, &j.Status, &j.Progress,
+		&j.Attempts, &j.MaxAttempts, &j.Failures, &j.Retries, &j.LastError, &j.RunAt, &j.CreatedBy, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	j.Payload = payload
+	j.Checkpoint = checkpoint
+	return &j, nil
+}
+
+// Options control how a job is enqueued
+type Options struct {
+	OrganizationID *uuid.UUID
+	CreatedBy      *uuid.UUID
+	// ParentID links the job to the job that spawned it; see ChildCounts
+	ParentID *uuid.UUID
+	// MaxAttempts defaults to 3
+	MaxAttempts int
+	// RunAt delays the job; zero runs it as soon as a worker is free
+	RunAt time.Time
+}
+
+// Enqueue inserts a job. Passing the request's tenant connection or transaction makes the
+// job part of the same unit of work as the records it refers to.
+func Enqueue(ctx context.Context, q database.Querier, kind string, payload interface{}, opts Options) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", kind, err)
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = defaultMaxAttempts
+	}
+	var runAt interface{}
+	if !opts.RunAt.IsZero() {
+		runAt = opts.RunAt
+	}
+
+	return Scan(q.QueryRowContext(ctx, `
+		INSERT INTO jobs (organization_id, parent_id, kind, payload, max_attempts, run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()), $7)
+		RETURNING `+Columns,
+		opts.OrganizationID, opts.ParentID, kind, data, maxAttempts, runAt, opts.CreatedBy))
+}
+
+// EnqueueAll inserts a job of kind for each payload, all with the same options, with a few
+// statements instead of one per job
+func EnqueueAll(ctx context.Context, q database.Querier, kind string, payloads []interface{}, opts Options) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = defaultMaxAttempts
+	}
+	columns := []string{"organization_id", "parent_id", "kind", "payload", "max_attempts", "created_by"}
+	if !opts.RunAt.IsZero() {
+		columns = append(columns, "run_at")
+	}
+
+	insert := database.NewBulkInsert("jobs", columns...)
+	for _, payload := range payloads {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s payload: %w", kind, err)
+		}
+		values := []interface{}{opts.OrganizationID, opts.ParentID, kind, data, maxAttempts, opts.CreatedBy}
+		if !opts.RunAt.IsZero() {
+			values = append(values, opts.RunAt)
+		}
+		insert.Add(values...)
+	}
+	_, err := insert.Exec(ctx, q)
+	return err
+}
+
+// Get loads a job. Through a tenant connection only jobs of the user's organizations are visible.
+func Get(ctx context.Context, q database.Querier, id uuid.UUID) (*Job, error) {
+	return Scan(q.QueryRowContext(ctx, `SELECT `+Columns+` FROM jobs WHERE id = $1`, id))
+}
+
+// SaveCheckpoint stores where a job got to, so a later run can resume there. Saving it in the
+// transaction that records the work makes the two consistent.
+func SaveCheckpoint(ctx context.Context, q database.Querier, id uuid.UUID, checkpoint interface{}) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `UPDATE jobs SET checkpoint = $1 WHERE id = $2`, data, id)
+	return err
+}
+
+// ChildCounts returns how many jobs spawned by a job are in each status
+func ChildCounts(ctx context.Context, q database.Querier, id uuid.UUID) (map[string]int, error) {
+	rows, err := q.QueryContext(ctx, `SELECT status, COUNT(*) FROM jobs WHERE parent_id = $1 GROUP BY status`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// rescheduleError asks for the job to run again later without using up an attempt
+type rescheduleError struct {
+	delay time.Duration
+}
+
+func (e *rescheduleError) Error() string { return fmt.Sprintf("rescheduled in %v", e.delay) }
+
+// Reschedule is returned by handlers that did part of their work, such as a batch of a long
+// walk, and want to continue after delay. The job keeps its checkpoint and attempts.
+func Reschedule(delay time.Duration) error {
+	return &rescheduleError{delay: delay}
+}
+
+// IsReschedule reports whether err was returned by Reschedule
+func IsReschedule(err error) bool {
+	var r *rescheduleError
+	return errors.As(err, &r)
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job fails immediately instead of being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
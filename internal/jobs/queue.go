@@ -0,0 +1,332 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/leader"
+	"openvdo/pkg/logger"
+
+	"github.com/lib/pq"
+)
+
+// Handler runs a job. Calls to progress, with values between 0 and 1, are persisted with the
+// lease heartbeat. A returned error is retried with backoff unless it is Permanent or the job
+// has no attempts left. ctx is cancelled on shutdown and when the lease is lost.
+type Handler func(ctx context.Context, job *Job, progress func(float64)) error
+
+// Queue runs registered handlers on jobs claimed from the jobs table
+type Queue struct {
+	db       *sql.DB
+	config   config.Jobs
+	leader   *leader.Elector
+	workerID string
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+
+	wake   chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewQueue creates a queue. The master connection is used because workers act for every
+// organization. Jobs whose worker died are recovered by the leader's queue only.
+func NewQueue(db *sql.DB, cfg config.Jobs, elector *leader.Elector) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		db:       db,
+		config:   cfg,
+		leader:   elector,
+		workerID: newWorkerID(),
+		handlers: make(map[string]Handler),
+		wake:     make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register sets the handler for a job kind. Only registered kinds are claimed, so processes
+// can specialize on the kinds they register.
+func (q *Queue) Register(kind string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = handler
+}
+
+// Start begins the workers and the recovery of jobs whose worker died
+func (q *Queue) Start() {
+	workers := q.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	q.wg.Add(workers + 1)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	go q.recoverExpired()
+
+	logger.Info("Job queue started with %d workers (%s)", workers, q.workerID)
+}
+
+// Stop cancels running jobs, which are put back in the queue, and waits for the workers to exit
+func (q *Queue) Stop() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+// Notify wakes an idle worker of this process, so jobs enqueued locally start without waiting
+// for the next poll
+func (q *Queue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for q.ctx.Err() == nil && q.runNext() {
+		}
+
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// runNext claims and runs one job, reporting whether there was one
+func (q *Queue) runNext() bool {
+	job, err := q.claim()
+	if err == sql.ErrNoRows {
+		return false
+	}
+	if err != nil {
+		if q.ctx.Err() == nil {
+			logger.Error("Failed to claim job: %v", err)
+		}
+		return false
+	}
+
+	q.mu.RLock()
+	handler := q.handlers[job.Kind]
+	q.mu.RUnlock()
+
+	q.finish(job, q.run(job, handler))
+	return true
+}
+
+func (q *Queue) claim() (*Job, error) {
+	q.mu.RLock()
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	q.mu.RUnlock()
+
+	if len(kinds) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return Scan(q.db.QueryRowContext(q.ctx, `
+		UPDATE jobs
+		SET status = $1, attempts = attempts + 1, locked_by = $2,
+			locked_until = NOW() + make_interval(secs => $3)
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = $4 AND run_at <= NOW() AND kind = ANY($5)
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+Columns,
+		StatusRunning, q.workerID, q.config.Lease.Seconds(), StatusQueued, pq.Array(kinds)))
+}
+
+// run calls the handler while a heartbeat renews the lease and saves progress
+func (q *Queue) run(job *Job, handler Handler) (err error) {
+	ctx, cancel := context.WithCancel(q.ctx)
+	defer cancel()
+
+	var progress atomic.Uint64
+	progress.Store(math.Float64bits(job.Progress))
+	report := func(p float64) {
+		progress.Store(math.Float64bits(math.Max(0, math.Min(1, p))))
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(q.config.Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			res, err := q.db.ExecContext(ctx, `
+				UPDATE jobs SET locked_until = NOW() + make_interval(secs => $1), progress = $2
+				WHERE id = $3 AND locked_by = $4 AND status = $5
+			`, q.config.Lease.Seconds(), math.Float64frombits(progress.Load()), job.ID, q.workerID, StatusRunning)
+			if err != nil {
+				logger.Error("Failed to renew lease of job %s: %v", job.ID, err)
+				continue
+			}
+			if n, _ := res.RowsAffected(); n == 0 {
+				logger.Error("Job %s lost its lease, cancelling", job.ID)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job, report)
+}
+
+// finish records the outcome of a run, scheduling a retry with exponential backoff when the
+// job has attempts left. A job that has failed QuarantineAfter times in all is quarantined
+// instead, whatever its attempts.
+func (q *Queue) finish(job *Job, runErr error) {
+	// The queue's context may be cancelled by now, and the outcome must still be written
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var reschedule *rescheduleError
+	var err error
+	switch {
+	case runErr == nil:
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = $1, progress = 1, last_error = NULL, locked_by = NULL, locked_until = NULL,
+				finished_at = NOW()
+			WHERE id = $2 AND locked_by = $3
+		`, StatusSucceeded, job.ID, q.workerID)
+
+	case q.ctx.Err() != nil:
+		// Interrupted by shutdown: hand the job to the next worker without using up an attempt
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = $1, attempts = attempts - 1, locked_by = NULL, locked_until = NULL
+			WHERE id = $2 AND locked_by = $3
+		`, StatusQueued, job.ID, q.workerID)
+
+	case errors.As(runErr, &reschedule):
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = $1, attempts = attempts - 1, locked_by = NULL, locked_until = NULL,
+				run_at = NOW() + make_interval(secs => $2)
+			WHERE id = $3 AND locked_by = $4
+		`, StatusQueued, reschedule.delay.Seconds(), job.ID, q.workerID)
+
+	case q.config.QuarantineAfter > 0 && job.Failures+1 >= q.config.QuarantineAfter:
+		log.Printf("WARN: Job %s (%s) quarantined after %d failed runs: %v", job.ID, job.Kind, job.Failures+1, runErr)
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = $1, failures = failures + 1, last_error = $2, locked_by = NULL,
+				locked_until = NULL, finished_at = NOW()
+			WHERE id = $3 AND locked_by = $4
+		`, StatusQuarantined, runErr.Error(), job.ID, q.workerID)
+
+	case IsPermanent(runErr) || job.Attempts >= job.MaxAttempts:
+		logger.Error("Job %s (%s) failed: %v", job.ID, job.Kind, runErr)
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = $1, failures = failures + 1, last_error = $2, locked_by = NULL,
+				locked_until = NULL, finished_at = NOW()
+			WHERE id = $3 AND locked_by = $4
+		`, StatusFailed, runErr.Error(), job.ID, q.workerID)
+
+	default:
+		delay := q.retryDelay(job.Attempts)
+		logger.Info("Job %s (%s) attempt %d failed, retrying in %v: %v", job.ID, job.Kind, job.Attempts, delay, runErr)
+		_, err = q.db.ExecContext(ctx, `
+			UPDATE jobs SET status = $1, failures = failures + 1, last_error = $2, locked_by = NULL,
+				locked_until = NULL, run_at = NOW() + make_interval(secs => $3)
+			WHERE id = $4 AND locked_by = $5
+		`, StatusQueued, runErr.Error(), delay.Seconds(), job.ID, q.workerID)
+	}
+	if err != nil {
+		logger.Error("Failed to record outcome of job %s: %v", job.ID, err)
+	}
+}
+
+// retryDelay is the backoff after a failed attempt: RetryDelay doubled per attempt, up to
+// MaxRetryDelay
+func (q *Queue) retryDelay(attempt int) time.Duration {
+	delay := q.config.RetryDelay * time.Duration(1<<min(attempt-1, 10))
+	if q.config.MaxRetryDelay > 0 {
+		delay = min(delay, q.config.MaxRetryDelay)
+	}
+	return delay
+}
+
+// recoverExpired requeues jobs whose worker stopped renewing its lease, typically because the
+// process crashed, or fails them when they have no attempts left. These count as failures, so
+// a job that crashes every worker it runs on ends up quarantined.
+func (q *Queue) recoverExpired() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.config.Lease / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !q.leader.IsLeader() {
+			continue
+		}
+
+		res, err := q.db.ExecContext(q.ctx, `
+			UPDATE jobs
+			SET status = CASE
+					WHEN $4 > 0 AND failures + 1 >= $4 THEN $5
+					WHEN attempts >= max_attempts THEN $1
+					ELSE $2 END,
+				finished_at = CASE WHEN ($4 > 0 AND failures + 1 >= $4) OR attempts >= max_attempts THEN NOW() END,
+				failures = failures + 1, last_error = 'worker stopped responding', locked_by = NULL, locked_until = NULL
+			WHERE status = $3 AND locked_until < NOW()
+		`, StatusFailed, StatusQueued, StatusRunning, q.config.QuarantineAfter, StatusQuarantined)
+		if err != nil {
+			if q.ctx.Err() == nil {
+				logger.Error("Failed to recover expired jobs: %v", err)
+			}
+			continue
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			logger.Info("Recovered %d jobs with expired leases", n)
+		}
+	}
+}
+
+func newWorkerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
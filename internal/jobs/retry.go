@@ -0,0 +1,156 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/database"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrNotRetryable is returned when retrying a job that has not failed, or is quarantined and
+// not released
+var ErrNotRetryable = errors.New("job cannot be retried")
+
+// Filter selects failed and quarantined jobs
+type Filter struct {
+	// Status is StatusFailed or StatusQuarantined; empty selects both
+	Status         string
+	Kinds          []string
+	OrganizationID *uuid.UUID
+	// VideoID selects jobs whose payload names the video
+	VideoID *uuid.UUID
+	// FailedSince skips jobs that gave up before it
+	FailedSince time.Time
+	// RetriedFewerThan, when positive, skips jobs retried that many times already
+	RetriedFewerThan int
+	// Limit defaults to 100
+	Limit int
+}
+
+// where builds the conditions of f, with its arguments numbered from $1
+func (f Filter) where() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if f.Status != "" {
+		add("status = ?", f.Status)
+	} else {
+		add("status = ANY(?)", pq.Array([]string{StatusFailed, StatusQuarantined}))
+	}
+	if len(f.Kinds) > 0 {
+		add("kind = ANY(?)", pq.Array(f.Kinds))
+	}
+	if f.OrganizationID != nil {
+		add("organization_id = ?", *f.OrganizationID)
+	}
+	if f.VideoID != nil {
+		add("payload->>'video_id' = ?", f.VideoID.String())
+	}
+	if !f.FailedSince.IsZero() {
+		add("finished_at >= ?", f.FailedSince)
+	}
+	if f.RetriedFewerThan > 0 {
+		add("retries < ?", f.RetriedFewerThan)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+func (f Filter) limit() int {
+	if f.Limit <= 0 {
+		return 100
+	}
+	return f.Limit
+}
+
+// List returns the jobs f selects, the latest to give up first, with their last error.
+// Through a tenant connection only jobs of the user's organizations are visible.
+func List(ctx context.Context, q database.Querier, f Filter) ([]Job, error) {
+	where, args := f.where()
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+Columns+` FROM jobs
+		WHERE `+where+`
+		ORDER BY finished_at DESC NULLS LAST
+		LIMIT `+strconv.Itoa(f.limit()), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Job{}
+	for rows.Next() {
+		job, err := Scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *job)
+	}
+	return list, rows.Err()
+}
+
+// LatestForVideo returns the latest job of one of kinds whose payload names the video
+func LatestForVideo(ctx context.Context, q database.Querier, videoID uuid.UUID, kinds []string) (*Job, error) {
+	return Scan(q.QueryRowContext(ctx, `
+		SELECT `+Columns+` FROM jobs
+		WHERE payload->>'video_id' = $1 AND kind = ANY($2)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, videoID.String(), pq.Array(kinds)))
+}
+
+// retrySet is the assignment that requeues a job with its attempts renewed. The checkpoint is
+// kept, so handlers resume where the job got to; those that must start over compare Retries.
+const retrySet = `status = 'queued', attempts = 0, retries = retries + 1, run_at = NOW(), finished_at = NULL,
+	locked_by = NULL, locked_until = NULL`
+
+// Retry requeues a failed job. A quarantined job is only requeued when release is set, which
+// also resets its failures. It returns ErrNotRetryable for jobs in other states and
+// sql.ErrNoRows for missing ones.
+func Retry(ctx context.Context, q database.Querier, id uuid.UUID, release bool) (*Job, error) {
+	job, err := Scan(q.QueryRowContext(ctx, `
+		UPDATE jobs SET `+retrySet+`,
+			failures = CASE WHEN status = 'quarantined' THEN 0 ELSE failures END
+		WHERE id = $1 AND (status = 'failed' OR (status = 'quarantined' AND $2))
+		RETURNING `+Columns, id, release))
+	if err != sql.ErrNoRows {
+		return job, err
+	}
+
+	current, err := Get(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%w: it is %s", ErrNotRetryable, current.Status)
+}
+
+// RetryFailed requeues the failed jobs f selects, the earliest to give up first, and returns
+// how many it requeued. Quarantined jobs are never requeued in bulk, whatever f.Status.
+func RetryFailed(ctx context.Context, q database.Querier, f Filter) (int, error) {
+	f.Status = StatusFailed
+	where, args := f.where()
+	result, err := q.ExecContext(ctx, `
+		UPDATE jobs SET `+retrySet+`
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE `+where+`
+			ORDER BY finished_at
+			LIMIT `+strconv.Itoa(f.limit())+`
+			FOR UPDATE SKIP LOCKED
+		)`, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,145 @@
+// Package leader elects one worker process of the fleet to run periodic routines, such as
+// storage lifecycle policies and retention deletes, that should run once per interval rather
+// than once per replica. The leader holds a Postgres session advisory lock on a connection kept
+// for it; the lock goes away with the connection, so a crashed leader is replaced as soon as
+// Postgres notices.
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/metrics"
+	"openvdo/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// lockName is hashed into the advisory lock key, as the playback session locks are
+const lockName = "openvdo:leader"
+
+var isLeader = promauto.With(metrics.Registry).NewGauge(prometheus.GaugeOpts{
+	Namespace: metrics.Namespace,
+	Name:      "leader",
+	Help:      "1 while this process is the leader running periodic routines",
+})
+
+// Elector campaigns for leadership until stopped. Routines check IsLeader on every tick and skip
+// the tick when it is false.
+type Elector struct {
+	db     *sql.DB
+	config config.Leader
+
+	leader atomic.Bool
+	// conn holds the lock while leading; only the campaign goroutine uses it
+	conn *sql.Conn
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewElector creates an elector. The master connection is used because the lock is
+// fleet-wide.
+func NewElector(db *sql.DB, cfg config.Leader) *Elector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Elector{db: db, config: cfg, ctx: ctx, cancel: cancel}
+}
+
+// IsLeader reports whether this process currently holds the leadership. A nil elector always
+// leads, for processes running alone.
+func (e *Elector) IsLeader() bool {
+	return e == nil || e.leader.Load()
+}
+
+// Start tries to become leader right away and then every check interval, and checks that the
+// lock is still held while leading
+func (e *Elector) Start() {
+	e.campaign()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.config.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.ctx.Done():
+				return
+			case <-ticker.C:
+				e.campaign()
+			}
+		}
+	}()
+}
+
+// Stop gives up the leadership, so another process takes over on its next check
+func (e *Elector) Stop() {
+	e.cancel()
+	e.wg.Wait()
+	if e.conn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), e.config.CheckInterval)
+		defer cancel()
+		if _, err := e.conn.ExecContext(ctx, `SELECT pg_advisory_unlock(hashtextextended($1, 0))`, lockName); err != nil {
+			logger.Error("Failed to release the leader lock: %v", err)
+		}
+		e.resign()
+	}
+}
+
+// campaign acquires the lock when not leading, and verifies the connection holding it when
+// leading
+func (e *Elector) campaign() {
+	ctx, cancel := context.WithTimeout(e.ctx, e.config.CheckInterval)
+	defer cancel()
+
+	if e.conn != nil {
+		// The lock lives as long as the session does
+		if err := e.conn.PingContext(ctx); err != nil {
+			if e.ctx.Err() == nil {
+				logger.Error("Lost the leader lock with its connection: %v", err)
+			}
+			e.resign()
+		}
+		return
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		if e.ctx.Err() == nil {
+			logger.Error("Failed to get a connection for the leader lock: %v", err)
+		}
+		return
+	}
+	var acquired bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock(hashtextextended($1, 0))`, lockName).Scan(&acquired)
+	if err != nil || !acquired {
+		if err != nil && e.ctx.Err() == nil {
+			logger.Error("Failed to try the leader lock: %v", err)
+		}
+		conn.Close()
+		return
+	}
+
+	e.conn = conn
+	e.leader.Store(true)
+	isLeader.Set(1)
+	logger.Info("This process is now the leader running periodic routines")
+}
+
+// resign drops the connection, and with it the lock if the session still held it
+func (e *Elector) resign() {
+	e.leader.Store(false)
+	isLeader.Set(0)
+	// Closing the connection returns it to the pool with the session still holding the lock,
+	// so it is discarded instead
+	e.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	e.conn.Close()
+	e.conn = nil
+}
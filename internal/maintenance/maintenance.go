@@ -0,0 +1,192 @@
+// Package maintenance implements a fleet-wide maintenance mode. While it is on, requests that
+// change data are refused with 503 and Retry-After, and playback and reads keep working, so
+// migrations and deployments can run without writes racing them.
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultMessage is shown to refused requests when no message is set
+const DefaultMessage = "The service is undergoing maintenance; please try again later"
+
+// State is the maintenance mode as stored in the database
+type State struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+	// RetryAfter is the number of seconds refused requests are told to wait
+	RetryAfter int        `json:"retry_after"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	// Forced is set when MAINTENANCE_MODE turns maintenance on for this instance regardless of
+	// the database
+	Forced bool `json:"forced,omitempty"`
+}
+
+// Load reads the stored state
+func Load(ctx context.Context, q database.Querier) (*State, error) {
+	var s State
+	err := q.QueryRowContext(ctx,
+		`SELECT enabled, message, retry_after_seconds, started_at, updated_at FROM maintenance_mode`,
+	).Scan(&s.Enabled, &s.Message, &s.RetryAfter, &s.StartedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Save turns maintenance on or off. StartedAt is kept while it stays on, so it tells how long
+// the current maintenance has lasted.
+func Save(ctx context.Context, q database.Querier, enabled bool, message string, retryAfter int) (*State, error) {
+	var s State
+	err := q.QueryRowContext(ctx, `
+		UPDATE maintenance_mode
+		SET enabled = $1, message = $2, retry_after_seconds = $3,
+			started_at = CASE WHEN NOT $1 THEN NULL WHEN enabled THEN started_at ELSE NOW() END
+		RETURNING enabled, message, retry_after_seconds, started_at, updated_at
+	`, enabled, message, retryAfter).Scan(&s.Enabled, &s.Message, &s.RetryAfter, &s.StartedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Mode keeps the state of one instance in sync with the database. Each instance polls, so a
+// change reaches all of them within the poll interval; while the database is unreachable,
+// such as during a migration that locks it, the last known state stays in effect.
+type Mode struct {
+	db     *sql.DB
+	config config.Maintenance
+	state  atomic.Pointer[State]
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewMode creates a mode that is off until Start loads the stored state, unless
+// MAINTENANCE_MODE forces it on
+func NewMode(db *sql.DB, cfg config.Maintenance) *Mode {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mode{db: db, config: cfg, ctx: ctx, cancel: cancel}
+	m.state.Store(&State{RetryAfter: int(cfg.RetryAfter.Seconds())})
+	return m
+}
+
+// Start loads the stored state, then polls it until Stop is called
+func (m *Mode) Start() {
+	m.refresh()
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(m.config.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+				m.refresh()
+			}
+		}
+	}()
+}
+
+// Stop stops polling
+func (m *Mode) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *Mode) refresh() {
+	ctx, cancel := context.WithTimeout(m.ctx, m.config.PollInterval)
+	defer cancel()
+
+	s, err := Load(ctx, m.db)
+	if err != nil {
+		if m.ctx.Err() == nil {
+			logger.Error("Failed to load maintenance mode, keeping the last state: %v", err)
+		}
+		return
+	}
+	m.update(s)
+}
+
+func (m *Mode) update(s *State) {
+	if prev := m.state.Load(); prev.Enabled != s.Enabled {
+		if s.Enabled {
+			logger.Info("Maintenance mode on: refusing writes")
+		} else {
+			logger.Info("Maintenance mode off")
+		}
+	}
+	m.state.Store(s)
+}
+
+// Current returns the state in effect on this instance
+func (m *Mode) Current() State {
+	s := *m.state.Load()
+	if m.config.Enabled {
+		s.Enabled = true
+		s.Forced = true
+	}
+	return s
+}
+
+// Set stores a new state and applies it to this instance at once
+func (m *Mode) Set(ctx context.Context, enabled bool, message string, retryAfter int) (State, error) {
+	s, err := Save(ctx, m.db, enabled, message, retryAfter)
+	if err != nil {
+		return State{}, err
+	}
+	m.update(s)
+	return m.Current(), nil
+}
+
+// Guard refuses requests that change data while maintenance is on. Safe methods pass, as do
+// the admin API, so maintenance can be turned off again, and playback token requests, which
+// only sign a URL.
+func (m *Mode) Guard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		path := c.Request.URL.Path
+		if strings.HasPrefix(path, "/admin/") || strings.HasSuffix(path, "/playback-token") {
+			c.Next()
+			return
+		}
+
+		s := m.Current()
+		if !s.Enabled {
+			c.Next()
+			return
+		}
+
+		message := s.Message
+		if message == "" {
+			message = DefaultMessage
+		}
+		if s.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(s.RetryAfter))
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": message, "maintenance": true})
+		c.Abort()
+	}
+}
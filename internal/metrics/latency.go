@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// LatencyBuckets are the histogram bucket upper bounds in seconds, from sub-millisecond
+// pool hits to multi-second queries
+var LatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// LatencySnapshot summarizes a latency histogram in milliseconds
+type LatencySnapshot struct {
+	Count  uint64  `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// Latency is a histogram exported to Prometheus that also keeps local bucket counts, so
+// percentiles can be estimated in-process the same way histogram_quantile does. The local
+// counts can be reset; the Prometheus series are cumulative.
+type Latency struct {
+	prom   prometheus.Histogram
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+	max    float64
+}
+
+// NewLatency creates a latency histogram and registers it with Registry
+func NewLatency(subsystem, name, help string) *Latency {
+	l := &Latency{
+		prom: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: subsystem,
+			Name:      name,
+			Help:      help,
+			Buckets:   LatencyBuckets,
+		}),
+		counts: make([]uint64, len(LatencyBuckets)+1),
+	}
+	Registry.MustRegister(l.prom)
+	return l
+}
+
+// Observe records one duration
+func (l *Latency) Observe(d time.Duration) {
+	seconds := d.Seconds()
+	l.prom.Observe(seconds)
+
+	i := 0
+	for i < len(LatencyBuckets) && seconds > LatencyBuckets[i] {
+		i++
+	}
+
+	l.mu.Lock()
+	l.counts[i]++
+	l.count++
+	l.sum += seconds
+	if seconds > l.max {
+		l.max = seconds
+	}
+	l.mu.Unlock()
+}
+
+// Since records the time elapsed since start
+func (l *Latency) Since(start time.Time) {
+	l.Observe(time.Since(start))
+}
+
+// Snapshot returns the count, mean and estimated percentiles since the last reset
+func (l *Latency) Snapshot() LatencySnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.count == 0 {
+		return LatencySnapshot{}
+	}
+	return LatencySnapshot{
+		Count:  l.count,
+		MeanMs: toMs(l.sum / float64(l.count)),
+		P50Ms:  toMs(l.quantile(0.50)),
+		P95Ms:  toMs(l.quantile(0.95)),
+		P99Ms:  toMs(l.quantile(0.99)),
+		MaxMs:  toMs(l.max),
+	}
+}
+
+// Reset clears the local counts used for snapshots
+func (l *Latency) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.counts = make([]uint64, len(LatencyBuckets)+1)
+	l.count = 0
+	l.sum = 0
+	l.max = 0
+}
+
+// quantile interpolates linearly within the bucket holding the requested rank. Ranks in the
+// overflow bucket resolve to the largest observation.
+func (l *Latency) quantile(q float64) float64 {
+	rank := q * float64(l.count)
+	var cumulative uint64
+	for i, n := range l.counts {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i == len(LatencyBuckets) {
+			return l.max
+		}
+
+		lower := 0.0
+		if i > 0 {
+			lower = LatencyBuckets[i-1]
+		}
+		upper := LatencyBuckets[i]
+		value := lower + (upper-lower)*(rank-float64(cumulative))/float64(n)
+		return math.Min(value, l.max)
+	}
+	return l.max
+}
+
+func toMs(seconds float64) float64 {
+	return math.Round(seconds*1e6) / 1e3
+}
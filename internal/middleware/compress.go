@@ -0,0 +1,294 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"openvdo/internal/config"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// Content types that are already compressed, or streamed and must reach the client unbuffered
+var incompressibleTypes = []string{
+	"video/",
+	"audio/",
+	"image/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/octet-stream",
+	"text/event-stream",
+}
+
+// compressiblePrefixes override incompressibleTypes
+var compressiblePrefixes = []string{
+	"image/svg+xml",
+}
+
+var (
+	gzipWriters = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+		return w
+	}}
+	brotliWriters = sync.Pool{New: func() interface{} {
+		return brotli.NewWriterLevel(io.Discard, 4)
+	}}
+)
+
+// Compress encodes responses with brotli or gzip, as negotiated by Accept-Encoding. Bodies
+// smaller than cfg.MinSize, already-compressed media, range responses and excluded paths are
+// passed through unchanged.
+func Compress(cfg config.Compression) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.Enabled || c.Request.Method == http.MethodHead || excludedPath(c.Request.URL.Path, cfg.ExcludePaths) {
+			c.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Accept-Encoding")
+		cw := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, minSize: cfg.MinSize, status: http.StatusOK}
+		c.Writer = cw
+		defer cw.close()
+
+		c.Next()
+	}
+}
+
+func excludedPath(path string, excluded []string) bool {
+	for _, prefix := range excluded {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding picks br or gzip from an Accept-Encoding header, preferring br on equal
+// quality
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "br" && name != "gzip" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether compressing pays
+// off, then either streams through an encoder or writes the buffer unchanged
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	minSize  int
+	status   int
+	buf      []byte
+	decided  bool
+	encoder  io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.status = code
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	w.decide()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.minSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if w.encoder != nil {
+		return w.encoder.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// ReadFrom decides on the start of the body as Write does, then passes bodies left
+// uncompressed, such as media, on to the connection
+func (w *compressWriter) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	if !w.decided {
+		lr := &io.LimitedReader{R: r, N: int64(w.minSize)}
+		var err error
+		if n, err = CopyBuffered(w, lr); err != nil || lr.N > 0 {
+			// A body shorter than minSize is decided on by close
+			return n, err
+		}
+		if err := w.decide(); err != nil {
+			return n, err
+		}
+	}
+
+	var m int64
+	var err error
+	if w.encoder != nil {
+		m, err = CopyBuffered(w.encoder, r)
+	} else {
+		m, err = ReadFrom(w.ResponseWriter, r)
+	}
+	return n + m, err
+}
+
+// Flush sends what has been written so far, compressed if it was decided to compress
+func (w *compressWriter) Flush() {
+	w.decide()
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// decide chooses between compressing and passing through, then writes the buffered bytes
+func (w *compressWriter) decide() error {
+	if w.decided {
+		return nil
+	}
+	w.decided = true
+
+	if w.shouldCompress() {
+		h := w.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			// The encoded body differs from the identity one, so a strong ETag no longer applies
+			h.Set("ETag", "W/"+etag)
+		}
+
+		switch w.encoding {
+		case "br":
+			bw := brotliWriters.Get().(*brotli.Writer)
+			bw.Reset(w.ResponseWriter)
+			w.encoder = bw
+		default:
+			gw := gzipWriters.Get().(*gzip.Writer)
+			gw.Reset(w.ResponseWriter)
+			w.encoder = gw
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf)
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf)
+	}
+	w.buf = nil
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	if len(w.buf) < w.minSize {
+		return false
+	}
+	if w.status < 200 || w.status == http.StatusNoContent || w.status == http.StatusNotModified ||
+		w.status == http.StatusPartialContent {
+		return false
+	}
+
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentType := strings.ToLower(h.Get("Content-Type"))
+	if contentType == "" {
+		contentType = http.DetectContentType(w.buf)
+	}
+	for _, prefix := range compressiblePrefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	for _, prefix := range incompressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// close writes out anything still buffered and finishes the encoded stream
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide()
+		w.ResponseWriter.WriteHeaderNow()
+	}
+
+	switch encoder := w.encoder.(type) {
+	case *brotli.Writer:
+		encoder.Close()
+		brotliWriters.Put(encoder)
+	case *gzip.Writer:
+		encoder.Close()
+		gzipWriters.Put(encoder)
+	}
+	w.encoder = nil
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"openvdo/internal/metrics"
+	"openvdo/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Modes of ContractCheck
+const (
+	ContractCheckOff     = "off"
+	ContractCheckLog     = "log"
+	ContractCheckEnforce = "enforce"
+)
+
+var contractViolations = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "http",
+	Name:      "contract_violations_total",
+	Help:      "Responses departing from the OpenAPI document, by method, route and status",
+}, []string{"method", "route", "status"})
+
+// ContractCheck checks the JSON responses of documented routes against the OpenAPI document.
+// In log mode violations are logged and counted; in enforce mode, meant for CI and staging,
+// the response is replaced with a 500 listing them, so whatever exercised the route fails.
+func ContractCheck(contract *openapi.Contract, mode string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if mode == ContractCheckOff || !contract.Documents(c.Request.Method, route) {
+			c.Next()
+			return
+		}
+
+		w := &jsonBufferWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.buffering {
+			return
+		}
+		body := w.body.Bytes()
+		violations := contract.CheckResponse(c.Request.Method, route, w.Status(), body)
+		if len(violations) > 0 {
+			contractViolations.WithLabelValues(c.Request.Method, route, strconv.Itoa(w.Status())).Inc()
+			messages := make([]string, len(violations))
+			for i, v := range violations {
+				messages[i] = v.String()
+			}
+			log.Printf("WARN: %s %s answered %d outside the API contract: %s", c.Request.Method, route, w.Status(),
+				strings.Join(messages, "; "))
+
+			if mode == ContractCheckEnforce {
+				replaced, err := json.Marshal(gin.H{
+					"error":           "Response violates the API contract",
+					"original_status": w.Status(),
+					"violations":      violations,
+				})
+				if err == nil {
+					w.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+					body = replaced
+				}
+			}
+		}
+		w.ResponseWriter.Write(body)
+	}
+}
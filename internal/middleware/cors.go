@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OriginLoader lists the playback domains registered by organizations
+type OriginLoader func(ctx context.Context) ([]string, error)
+
+// Playback domains only embed the player and read video data
+var playbackMethods = []string{http.MethodGet, http.MethodHead}
+
+// CORS answers cross-origin requests from cfg.AllowedOrigins with the configured methods and
+// headers, and from organizations' playback domains read-only and without credentials. Other
+// origins get no CORS headers and their preflight requests are rejected.
+func CORS(cfg config.CORS, playbackOrigins OriginLoader) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[strings.ToLower(strings.TrimSuffix(origin, "/"))] = true
+	}
+	playback := &originCache{load: playbackOrigins, ttl: cfg.PlaybackDomainsTTL}
+
+	allowHeaders := make(map[string]bool, len(cfg.AllowedHeaders))
+	for _, header := range cfg.AllowedHeaders {
+		allowHeaders[strings.ToLower(header)] = true
+	}
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Origin")
+
+		methods, credentials := cfg.AllowedMethods, cfg.AllowCredentials
+		switch key := strings.ToLower(origin); {
+		case allowed[key]:
+		case allowAll:
+			// Browsers refuse credentials with a wildcard, so the origin is echoed without them
+			credentials = false
+		case playback.contains(c.Request.Context(), key):
+			methods, credentials = playbackMethods, false
+		default:
+			if isPreflight(c.Request) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		if credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !isPreflight(c.Request) {
+			if len(cfg.ExposedHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+			}
+			c.Next()
+			return
+		}
+
+		if !containsFold(methods, c.GetHeader("Access-Control-Request-Method")) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		for _, header := range strings.Split(c.GetHeader("Access-Control-Request-Headers"), ",") {
+			if header = strings.ToLower(strings.TrimSpace(header)); header != "" && !allowHeaders[header] {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+		}
+
+		h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		h.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+		h.Set("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// originCache keeps the playback domains in memory for ttl. When reloading fails the previous
+// list stays in use, so a database hiccup does not break embedded players.
+type originCache struct {
+	load OriginLoader
+	ttl  time.Duration
+
+	mu       sync.Mutex
+	origins  map[string]bool
+	loadedAt time.Time
+}
+
+func (oc *originCache) contains(ctx context.Context, origin string) bool {
+	if oc.load == nil {
+		return false
+	}
+
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	if time.Since(oc.loadedAt) > oc.ttl {
+		// Also rate-limits retries after a failed load
+		oc.loadedAt = time.Now()
+
+		list, err := oc.load(ctx)
+		if err != nil {
+			logger.Error("Failed to load playback domains: %v", err)
+		} else {
+			origins := make(map[string]bool, len(list))
+			for _, o := range list {
+				origins[o] = true
+			}
+			oc.origins = origins
+		}
+	}
+	return oc.origins[origin]
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"openvdo/pkg/logger"
+	"openvdo/pkg/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnvelopeV2 serves the routes it wraps as /api/v2: handlers write the /api/v1 shape, and
+// their JSON responses are rewritten into response.Envelope before being sent. Other
+// responses, such as NDJSON streams, files and redirects, pass through unchanged.
+func EnvelopeV2() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &jsonBufferWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.buffering {
+			return
+		}
+		body, err := json.Marshal(response.FromV1(w.Status(), w.body.Bytes(), GetRequestID(c)))
+		if err != nil {
+			logger.Error("Failed to encode the response envelope of %s: %v", c.Request.URL.Path, err)
+			body = w.body.Bytes()
+		}
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// jsonBufferWriter holds back JSON bodies so middleware can check or rewrite them once the
+// handler is done. Whether a response is JSON is decided when it starts being written.
+type jsonBufferWriter struct {
+	gin.ResponseWriter
+	decided   bool
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (w *jsonBufferWriter) decide() {
+	if !w.decided {
+		w.decided = true
+		w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), gin.MIMEJSON)
+	}
+}
+
+func (w *jsonBufferWriter) WriteHeaderNow() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *jsonBufferWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *jsonBufferWriter) WriteString(s string) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *jsonBufferWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.ReadFrom(r)
+	}
+	return ReadFrom(w.ResponseWriter, r)
+}
+
+func (w *jsonBufferWriter) Flush() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *jsonBufferWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *jsonBufferWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// benchmarkVideos is a page of a video listing, as the list handlers answer it
+func benchmarkVideos() gin.H {
+	videos := make([]gin.H, 50)
+	for i := range videos {
+		videos[i] = gin.H{
+			"id": fmt.Sprintf("6f1c2a9e-4b7d-4c3e-9a51-%012d", i), "title": fmt.Sprintf("Video %d", i),
+			"status": "ready", "duration": 60 + i, "tags": []string{"news", "sports"},
+		}
+	}
+	return gin.H{
+		"status": "success", "message": "Videos retrieved successfully",
+		"data": gin.H{"videos": videos, "pagination": gin.H{"page": 1, "limit": 50, "total": 1234}},
+	}
+}
+
+// BenchmarkEnvelopeV2 compares serving a listing as /api/v1 with serving it through the
+// envelope of /api/v2, which buffers, decodes and encodes the body again
+func BenchmarkEnvelopeV2(b *testing.B) {
+	gin.SetMode(gin.ReleaseMode)
+	body := benchmarkVideos()
+	handler := func(c *gin.Context) { c.JSON(http.StatusOK, body) }
+
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/api/v1/videos", handler)
+	router.GET("/api/v2/videos", EnvelopeV2(), handler)
+
+	for _, version := range []string{"v1", "v2"} {
+		b.Run(version, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, "/api/"+version+"/videos", nil)
+			b.ReportAllocs()
+			for b.Loop() {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					b.Fatalf("status %d", w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"openvdo/internal/i18n"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// Localize adapts the JSON responses of the routes it wraps to the caller. Error messages are
+// translated to the language Accept-Language prefers, and with an X-Timezone header naming an
+// IANA time zone, timestamps (the fields ending in _at) are given in that zone as RFC 3339 with
+// its offset; X-Timezone: UTC gives them all in UTC. Responses needing neither pass through
+// without being buffered.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Language, X-Timezone")
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		if lang != i18n.Default {
+			c.Header("Content-Language", lang.String())
+		}
+
+		var zone *time.Location
+		if name := c.GetHeader("X-Timezone"); name != "" {
+			loc, err := time.LoadLocation(name)
+			if err != nil || name == "Local" {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "Invalid X-Timezone header")})
+				return
+			}
+			zone = loc
+		}
+		if lang == i18n.Default && zone == nil {
+			c.Next()
+			return
+		}
+
+		w := &jsonBufferWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.buffering {
+			return
+		}
+		body := w.body.Bytes()
+		if w.body.Len() > 0 {
+			localized, err := localizeJSON(body, lang, zone)
+			if err != nil {
+				logger.Error("Failed to localize the response of %s: %v", c.Request.URL.Path, err)
+			} else {
+				body = localized
+			}
+		}
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// localizeJSON translates the error message of a response body to lang and moves its
+// timestamps to zone, when it is set
+func localizeJSON(body []byte, lang language.Tag, zone *time.Location) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	if m, ok := v.(map[string]interface{}); ok && lang != i18n.Default {
+		if message, ok := m["error"].(string); ok {
+			m["error"] = i18n.Translate(lang, message)
+		}
+	}
+	if zone != nil {
+		v = inZone(v, "", zone)
+	}
+	return json.Marshal(v)
+}
+
+// inZone rewrites the RFC 3339 timestamps under keys ending in _at in zone, key being the key
+// v is found under
+func inZone(v interface{}, key string, zone *time.Location) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = inZone(e, k, zone)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = inZone(e, key, zone)
+		}
+	case string:
+		if strings.HasSuffix(key, "_at") {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t.In(zone).Format(time.RFC3339Nano)
+			}
+		}
+	}
+	return v
+}
@@ -3,44 +3,38 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"time"
 
-	"github.com/gin-contrib/cors"
+	"openvdo/internal/database"
+	"openvdo/internal/errortracking"
+
 	"github.com/gin-gonic/gin"
 )
 
-func Logger() gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("%s - [%s] \"%s %s %s %d %s \"%s\" %s\"\n",
-			param.ClientIP,
-			param.TimeStamp.Format(time.RFC1123),
-			param.Method,
-			param.Path,
-			param.Request.Proto,
-			param.StatusCode,
-			param.Latency,
-			param.Request.UserAgent(),
-			param.ErrorMessage,
-		)
-	})
-}
-
-func Recovery() gin.HandlerFunc {
+// Recovery answers panics with 500 and reports them to the error tracker with the request ID,
+// route and the organization and user the request was made for
+func Recovery(tracker *errortracking.Tracker) gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		if tracker.Enabled() {
+			e := errortracking.NewPanicEvent(recovered)
+			e.Request = &errortracking.Request{
+				Method:  c.Request.Method,
+				URL:     c.Request.URL.Path,
+				Headers: map[string]string{"User-Agent": c.Request.UserAgent()},
+			}
+			e.SetTag("request_id", GetRequestID(c))
+			e.SetTag("route", c.FullPath())
+			if orgID, ok := c.Get(string(database.OrgIDKey)); ok {
+				e.SetTag("organization_id", fmt.Sprint(orgID))
+			}
+			if userID, ok := c.Get(string(database.UserIDKey)); ok {
+				e.User = &errortracking.User{ID: fmt.Sprint(userID)}
+			}
+			tracker.Capture(e)
+		}
+
 		if err, ok := recovered.(string); ok {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err})
 		}
 		c.AbortWithStatus(http.StatusInternalServerError)
 	})
 }
-
-func CORS() gin.HandlerFunc {
-	return cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	})
-}
\ No newline at end of file
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"io"
+	"strconv"
+
+	"openvdo/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueryCountHeader returns how many statements a request ran on its tenant connection
+const QueryCountHeader = "X-DB-Query-Count"
+
+const queryCountKey = "db_query_count"
+
+// QueryCount counts the statements each request runs on its tenant connection, for the access
+// log. With header set, as in debug mode, responses carry the count in X-DB-Query-Count, as
+// of when they start being written.
+func QueryCount(header bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		counter := &database.QueryCounter{}
+		c.Set(queryCountKey, counter)
+		c.Request = c.Request.WithContext(database.WithQueryTracer(c.Request.Context(), counter))
+		if header {
+			c.Writer = &queryCountWriter{ResponseWriter: c.Writer, counter: counter}
+		}
+		c.Next()
+	}
+}
+
+// GetQueryCount returns the statements the request ran so far, or 0 before QueryCount ran
+func GetQueryCount(c *gin.Context) int64 {
+	value, _ := c.Get(queryCountKey)
+	if counter, ok := value.(*database.QueryCounter); ok {
+		return counter.Count()
+	}
+	return 0
+}
+
+// queryCountWriter sets the query count header just before the headers are sent
+type queryCountWriter struct {
+	gin.ResponseWriter
+	counter *database.QueryCounter
+	sent    bool
+}
+
+func (w *queryCountWriter) setHeader() {
+	if !w.sent && !w.Written() {
+		w.Header().Set(QueryCountHeader, strconv.FormatInt(w.counter.Count(), 10))
+	}
+	w.sent = true
+}
+
+func (w *queryCountWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queryCountWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *queryCountWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *queryCountWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.setHeader()
+	return ReadFrom(w.ResponseWriter, r)
+}
+
+func (w *queryCountWriter) Flush() {
+	w.setHeader()
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// copyBuffers are the buffers of copies that cannot be handed to the connection, such as
+// bodies proxied from remote storage
+var copyBuffers = sync.Pool{New: func() interface{} {
+	buf := make([]byte, 32*1024)
+	return &buf
+}}
+
+// SendFile lets bodies written with io.Copy or http.ServeContent reach the connection's
+// ReadFrom, which sends files with sendfile(2) over plain TCP rather than copying them
+// through user space. It must come before the middleware wrapping the response writer, as
+// each of those hands ReadFrom on to the writer it wraps.
+func SendFile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer = &sendFileWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// ReadFrom copies r to w, with w's ReadFrom when it has one and through a pooled buffer
+// otherwise. Response writers wrapping another use it to pass ReadFrom on.
+func ReadFrom(w io.Writer, r io.Reader) (int64, error) {
+	if rf, ok := w.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return CopyBuffered(w, r)
+}
+
+// CopyBuffered copies r to w through a pooled buffer, by w's Write even when w has ReadFrom
+func CopyBuffered(w io.Writer, r io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(writerOnly{w}, r, *buf)
+}
+
+// writerOnly hides the ReadFrom of a writer from io.CopyBuffer, which would call it
+type writerOnly struct {
+	io.Writer
+}
+
+// sendFileWriter sits directly on gin's writer, which has no ReadFrom of its own, and counts
+// the bytes it passes to the connection
+type sendFileWriter struct {
+	gin.ResponseWriter
+	sent int64
+}
+
+func (w *sendFileWriter) ReadFrom(r io.Reader) (int64, error) {
+	w.ResponseWriter.WriteHeaderNow()
+	var dst io.Writer = w.ResponseWriter
+	if u, ok := w.ResponseWriter.(interface{ Unwrap() http.ResponseWriter }); ok {
+		dst = u.Unwrap()
+	}
+	n, err := ReadFrom(dst, r)
+	w.sent += n
+	return n, err
+}
+
+func (w *sendFileWriter) Size() int {
+	size := w.ResponseWriter.Size()
+	if w.sent == 0 {
+		return size
+	}
+	return max(size, 0) + int(w.sent)
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// AdBreak is a cue point where players insert ads. DurationSeconds is the length of the avail,
+// the longest the inserted ads may run; the video resumes where it stopped.
+type AdBreak struct {
+	OffsetSeconds   float64 `json:"offset_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// AdBreaks are a video's ad breaks in order of their offset. They are kept as JSONB on the video.
+type AdBreaks []AdBreak
+
+// Value stores the ad breaks as JSON
+func (b AdBreaks) Value() (driver.Value, error) {
+	if b == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]AdBreak(b))
+}
+
+// Scan reads ad breaks stored as JSON
+func (b *AdBreaks) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, (*[]AdBreak)(b))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]AdBreak)(b))
+	case nil:
+		*b = AdBreaks{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into AdBreaks", src)
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit log actions
+const (
+	AuditIPAccessUpdated  = "ip_access.updated"
+	AuditIPAccessDenied   = "ip_access.denied"
+	AuditVideoBulkUpdated = "video.bulk_updated"
+)
+
+// AuditEntry records a security-relevant action in an organization, or a request refused on
+// its behalf
+type AuditEntry struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	// UserID is who acted or was refused; null once the user is deleted
+	UserID    *uuid.UUID      `json:"user_id"`
+	Action    string          `json:"action"`
+	IPAddress string          `json:"ip_address"`
+	Details   json.RawMessage `json:"details" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Chapter is a titled section of a video's timeline. A chapter ends where the next begins, and
+// the last at the end of the video.
+type Chapter struct {
+	StartSeconds float64 `json:"start_seconds"`
+	Title        string  `json:"title"`
+}
+
+// Chapters are a video's chapters in order of their start. They are kept as JSONB on the video.
+type Chapters []Chapter
+
+// Value stores the chapters as JSON
+func (c Chapters) Value() (driver.Value, error) {
+	if c == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]Chapter(c))
+}
+
+// Scan reads chapters stored as JSON
+func (c *Chapters) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, (*[]Chapter)(c))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]Chapter)(c))
+	case nil:
+		*c = Chapters{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Chapters", src)
+	}
+}
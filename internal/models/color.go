@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Dynamic ranges of sources and renditions
+const (
+	DynamicRangeSDR         = "sdr"
+	DynamicRangeHDR10       = "hdr10"
+	DynamicRangeHLG         = "hlg"
+	DynamicRangeDolbyVision = "dolby_vision"
+)
+
+// DynamicRanges are the dynamic ranges renditions may be labelled with
+var DynamicRanges = []string{DynamicRangeSDR, DynamicRangeHDR10, DynamicRangeHLG, DynamicRangeDolbyVision}
+
+// ColorInfo is the color signaling of a video's source, as ffprobe reports it for the first
+// video stream. It is kept as JSONB on the video.
+type ColorInfo struct {
+	// DynamicRange is sdr, hdr10 (PQ), hlg or dolby_vision
+	DynamicRange string `json:"dynamic_range"`
+	// ColorPrimaries, Transfer and Matrix are the stream's tags in ffprobe's names, such as
+	// bt2020, smpte2084 and bt2020nc
+	ColorPrimaries string `json:"color_primaries,omitempty"`
+	Transfer       string `json:"transfer,omitempty"`
+	Matrix         string `json:"matrix,omitempty"`
+	BitDepth       int    `json:"bit_depth,omitempty"`
+	// MaxCLL and MaxFALL are the content light levels of HDR10 sources in nits, when tagged
+	MaxCLL  int `json:"max_cll,omitempty"`
+	MaxFALL int `json:"max_fall,omitempty"`
+	// MasteringDisplay reports whether the source carries SMPTE ST 2086 mastering metadata
+	MasteringDisplay bool `json:"mastering_display"`
+	// DolbyVision describes the Dolby Vision configuration of dolby_vision sources
+	DolbyVision *DolbyVisionInfo `json:"dolby_vision,omitempty"`
+	// SourceRevision is the source revision that was probed
+	SourceRevision int       `json:"source_revision"`
+	ProbedAt       time.Time `json:"probed_at"`
+}
+
+// DolbyVisionInfo is a source's Dolby Vision configuration record
+type DolbyVisionInfo struct {
+	Profile int `json:"profile"`
+	Level   int `json:"level"`
+	// Compatibility is the signal the base layer is compatible with: 0 none (as in profile 5),
+	// 1 HDR10, 2 SDR or 4 HLG
+	Compatibility int `json:"compatibility"`
+}
+
+// HDR reports whether the source has a high dynamic range
+func (c *ColorInfo) HDR() bool {
+	return c != nil && c.DynamicRange != "" && c.DynamicRange != DynamicRangeSDR
+}
+
+// Value stores the color information as JSON
+func (c *ColorInfo) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Scan reads color information stored as JSON
+func (c *ColorInfo) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	default:
+		return fmt.Errorf("cannot scan %T into ColorInfo", src)
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Custom field types
+const (
+	CustomFieldString = "string"
+	CustomFieldNumber = "number"
+	CustomFieldDate   = "date"
+	CustomFieldEnum   = "enum"
+)
+
+// CustomFieldDateLayout is the format of date field values
+const CustomFieldDateLayout = "2006-01-02"
+
+// CustomField is a typed field an organization defines for its videos. Videos store values
+// of the field under its key.
+type CustomField struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Key            string    `json:"key"`
+	Name           string    `json:"name"`
+	// Type is string, number, date or enum
+	Type string `json:"type"`
+	// Options are the values an enum field accepts
+	Options   []string   `json:"options"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CustomFieldValues are the values of a video's custom fields by key: strings, numbers, and
+// dates as YYYY-MM-DD strings
+type CustomFieldValues map[string]interface{}
+
+// Value stores the values as a JSON object
+func (v CustomFieldValues) Value() (driver.Value, error) {
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(map[string]interface{}(v))
+}
+
+// Scan reads values stored as a JSON object
+func (v *CustomFieldValues) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, (*map[string]interface{})(v))
+	case string:
+		return json.Unmarshal([]byte(s), (*map[string]interface{})(v))
+	case nil:
+		*v = CustomFieldValues{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into CustomFieldValues", src)
+	}
+}
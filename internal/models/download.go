@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Video download statuses
+const (
+	DownloadPending = "pending"
+	DownloadReady   = "ready"
+	DownloadFailed  = "failed"
+)
+
+// VideoDownload is a file rendered from a video's source or one of its renditions for a member
+// to download. Links to it are signed and expire; the file itself is kept until ExpiresAt.
+type VideoDownload struct {
+	ID             uuid.UUID  `json:"id"`
+	VideoID        uuid.UUID  `json:"video_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	RequestedBy    *uuid.UUID `json:"requested_by"`
+	// RenditionID is the rendition the file was made from; null for the source
+	RenditionID    *uuid.UUID `json:"rendition_id"`
+	SourceRevision int        `json:"source_revision"`
+	// Watermark is the text burned into the picture, the email of the member who asked for it
+	Watermark *string   `json:"watermark,omitempty"`
+	Status    string    `json:"status"`
+	SizeBytes *int64    `json:"size_bytes,omitempty"`
+	Error     *string   `json:"error,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ObjectKey is where the file is stored once ready
+	ObjectKey string `json:"-"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EncoderIntegration is an external encoder that notifies an organization of finished
+// renditions. The secret signs its notifications and is only returned when it is created.
+type EncoderIntegration struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	Secret         string     `json:"secret,omitempty"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	LastReceivedAt *time.Time `json:"last_received_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// VideoRendition is a rendition of a video hosted outside OpenVDO's storage, such as an HLS
+// playlist written by an external encoder
+type VideoRendition struct {
+	ID      uuid.UUID `json:"id"`
+	VideoID uuid.UUID `json:"video_id"`
+	// IntegrationID is the encoder that registered the rendition; null once it is deleted
+	IntegrationID *uuid.UUID `json:"integration_id"`
+	// ExternalID is the encoder's own ID of the rendition or job
+	ExternalID  string `json:"external_id"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       *int   `json:"width"`
+	Height      *int   `json:"height"`
+	BitrateKbps *int   `json:"bitrate_kbps"`
+	Codec       string `json:"codec"`
+	// DynamicRange is sdr, hdr10, hlg or dolby_vision; empty when unknown
+	DynamicRange string    `json:"dynamic_range,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
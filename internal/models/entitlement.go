@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entitlement grants a user playback of a video, or of every video of a project, until
+// ExpiresAt; nil never expires
+type Entitlement struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	VideoID        *uuid.UUID `json:"video_id,omitempty"`
+	ProjectID      *uuid.UUID `json:"project_id,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	// Reference ties the grant to an order, rental or enrollment in the caller's system
+	Reference string     `json:"reference"`
+	GrantedBy *uuid.UUID `json:"granted_by,omitempty"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Image origins
+const (
+	ImageSourceCustom    = "custom"
+	ImageSourceGenerated = "generated"
+)
+
+// Image is a picture such as a video thumbnail or an avatar, stored in several sizes and
+// formats. It is kept as JSONB on the record it belongs to.
+type Image struct {
+	ID     string `json:"id"`
+	Source string `json:"source,omitempty"`
+	// Width and Height are those of the largest variant
+	Width     int            `json:"width"`
+	Height    int            `json:"height"`
+	Variants  []ImageVariant `json:"variants"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// ImageVariant is one stored size and format of an image
+type ImageVariant struct {
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	Key         string `json:"key"`
+	SizeBytes   int64  `json:"size_bytes"`
+}
+
+// Keys returns the storage keys of every variant
+func (i *Image) Keys() []string {
+	keys := make([]string, len(i.Variants))
+	for n, v := range i.Variants {
+		keys[n] = v.Key
+	}
+	return keys
+}
+
+// Value stores the image as JSON
+func (i *Image) Value() (driver.Value, error) {
+	if i == nil {
+		return nil, nil
+	}
+	return json.Marshal(i)
+}
+
+// Scan reads an image stored as JSON
+func (i *Image) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, i)
+	case string:
+		return json.Unmarshal([]byte(v), i)
+	default:
+		return fmt.Errorf("cannot scan %T into Image", src)
+	}
+}
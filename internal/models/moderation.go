@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Abuse report reasons
+const (
+	ReportReasonCopyright  = "copyright"
+	ReportReasonSexual     = "sexual"
+	ReportReasonViolence   = "violence"
+	ReportReasonHarassment = "harassment"
+	ReportReasonHateful    = "hateful"
+	ReportReasonSpam       = "spam"
+	ReportReasonOther      = "other"
+)
+
+// Abuse report statuses
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusDismissed = "dismissed"
+	ReportStatusActioned  = "actioned"
+)
+
+// Takedown kinds
+const (
+	TakedownKindDMCA   = "dmca"
+	TakedownKindPolicy = "policy"
+)
+
+// Takedown statuses. A DMCA takedown the uploader disputes is counter_noticed until a
+// moderator restores the video or upholds the takedown because the claimant went to court.
+const (
+	TakedownStatusActive         = "active"
+	TakedownStatusCounterNoticed = "counter_noticed"
+	TakedownStatusRestored       = "restored"
+	TakedownStatusUpheld         = "upheld"
+)
+
+// AbuseReport is a viewer's report of a video
+type AbuseReport struct {
+	ID             uuid.UUID  `json:"id"`
+	VideoID        uuid.UUID  `json:"video_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Takedown removes a video from playback for a copyright claim or a policy violation
+type Takedown struct {
+	ID             uuid.UUID `json:"id"`
+	VideoID        uuid.UUID `json:"video_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Kind           string    `json:"kind"`
+	Reason         string    `json:"reason"`
+	Claimant       string    `json:"claimant,omitempty"`
+	Status         string    `json:"status"`
+	// The counter-notice filed by the organization, for DMCA takedowns
+	CounterNoticeStatement *string    `json:"counter_notice_statement,omitempty"`
+	CounterNoticeContact   *string    `json:"counter_notice_contact,omitempty"`
+	CounterNoticedAt       *time.Time `json:"counter_noticed_at,omitempty"`
+	// RestoreAfter is when a counter-noticed video may be restored
+	RestoreAfter *time.Time `json:"restore_after,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ModerationScan is the result of an automatic content scan of a video's source
+type ModerationScan struct {
+	ID             uuid.UUID `json:"id"`
+	VideoID        uuid.UUID `json:"video_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Provider       string    `json:"provider"`
+	SourceKey      string    `json:"source_key"`
+	Frames         int       `json:"frames"`
+	AudioSeconds   int       `json:"audio_seconds"`
+	// Scores are the highest score of each category, from 0 to 1
+	Scores map[string]float64 `json:"scores"`
+	// Flagged lists the categories at or above the threshold
+	Flagged   []string  `json:"flagged"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification types
+const (
+	NotificationVideoReady     = "video.ready"
+	NotificationCommentReply   = "comment.reply"
+	NotificationInviteReceived = "invite.received"
+	NotificationAccountLocked  = "account.locked"
+	NotificationVideoApproved  = "video.approved"
+	NotificationVideoRejected  = "video.rejected"
+)
+
+// Delivery channels besides the in-app list, which always receives every notification
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelPush  = "push"
+)
+
+// Notification is a message to one user about something that happened in an organization
+type Notification struct {
+	ID             uuid.UUID       `json:"id"`
+	UserID         uuid.UUID       `json:"user_id"`
+	OrganizationID *uuid.UUID      `json:"organization_id,omitempty"`
+	Type           string          `json:"type"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Data           json.RawMessage `json:"data" swaggertype:"object"`
+	ReadAt         *time.Time      `json:"read_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// NotificationPreference selects the channels a user receives one type of notification on
+type NotificationPreference struct {
+	Type  string `json:"type"`
+	Email bool   `json:"email"`
+	Push  bool   `json:"push"`
+}
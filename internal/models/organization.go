@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization is a tenant owning projects and videos
+type Organization struct {
+	ID          uuid.UUID       `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Settings    json.RawMessage `json:"settings" swaggertype:"object"`
+	// ExternalID is the ID of the organization in an external system, unique among organizations
+	ExternalID *string `json:"external_id,omitempty"`
+	// PlaybackDomains are the origins allowed to embed the organization's videos
+	PlaybackDomains []string `json:"playback_domains"`
+	// EmbedDomains restrict the sites the player may be framed on and playback tokens issued
+	// to, as CSP host sources such as https://*.example.com; empty allows any
+	EmbedDomains []string `json:"embed_domains"`
+	// Region is the region the organization's data is pinned to; null means the home region
+	Region *string `json:"region"`
+	// VASTTagURL is the ad tag players of the organization's videos request ads from
+	VASTTagURL *string `json:"vast_tag_url"`
+	// RequireReview holds the videos members other than owners and admins create until an
+	// owner or admin approves them
+	RequireReview bool      `json:"require_review"`
+	Banner        *Image    `json:"banner,omitempty"`
+	Version       int64     `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// IPAccessRules restrict the addresses an organization's API is used from, as CIDR ranges or
+// single addresses. Denied ranges win over allowed ones; with no allowed ranges every address
+// not denied is allowed.
+type IPAccessRules struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+// Teardown statuses. A deletion is confirming until its owner confirms it, then pending and
+// running while the teardown job removes the organization's data.
+const (
+	TeardownConfirming = "confirming"
+	TeardownPending    = "pending"
+	TeardownRunning    = "running"
+	TeardownCompleted  = "completed"
+	TeardownFailed     = "failed"
+)
+
+// OrganizationTeardown is the deletion of an organization and the progress of removing its
+// stored files, videos and playback events
+type OrganizationTeardown struct {
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Status         string     `json:"status"`
+	JobID          *uuid.UUID `json:"job_id,omitempty"`
+	// Step is what the teardown is removing: storage, videos or analytics
+	Step           *string    `json:"step,omitempty"`
+	ObjectsTotal   int64      `json:"objects_total"`
+	ObjectsDeleted int64      `json:"objects_deleted"`
+	BytesDeleted   int64      `json:"bytes_deleted"`
+	VideosTotal    int64      `json:"videos_total"`
+	VideosDeleted  int64      `json:"videos_deleted"`
+	LastError      *string    `json:"last_error,omitempty"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PlaybackSession is a viewer's playback of a video. It stays active while the player sends
+// heartbeats, until it is ended or ExpiresAt passes.
+type PlaybackSession struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	VideoID        uuid.UUID `json:"video_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	ClientIP       string    `json:"client_ip"`
+	UserAgent      string    `json:"user_agent"`
+	// PositionSeconds is the playback position of the last heartbeat
+	PositionSeconds float64 `json:"position_seconds"`
+	// WatchedSeconds adds up the progress between heartbeats, bounded by the time that passed
+	WatchedSeconds  float64    `json:"watched_seconds"`
+	Heartbeats      int        `json:"heartbeats"`
+	Active          bool       `json:"active"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastHeartbeatAt time.Time  `json:"last_heartbeat_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+}
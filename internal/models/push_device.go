@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Push platforms a device can register for
+const (
+	PushPlatformWeb  = "web"
+	PushPlatformFCM  = "fcm"
+	PushPlatformAPNs = "apns"
+)
+
+// PushDevice is a browser or app installation that receives a user's push notifications
+type PushDevice struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Platform string    `json:"platform"`
+	// Token is the FCM registration token, the APNs device token or the Web Push endpoint
+	Token string `json:"token"`
+	// P256DH and Auth are the encryption keys of a Web Push subscription
+	P256DH     string     `json:"-"`
+	Auth       string     `json:"-"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VideoReview is one submission of a video for approval. It is pending until an owner or admin
+// approves or rejects it; a rejected video may be submitted again, opening a new review.
+type VideoReview struct {
+	ID             uuid.UUID  `json:"id"`
+	VideoID        uuid.UUID  `json:"video_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	SubmittedBy    *uuid.UUID `json:"submitted_by"`
+	// Status is one of the review statuses of videos
+	Status     string     `json:"status"`
+	ReviewedBy *uuid.UUID `json:"reviewed_by"`
+	// DecisionNote is the reviewer's reason, required for rejections
+	DecisionNote string     `json:"decision_note"`
+	DecidedAt    *time.Time `json:"decided_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	VideoTitle   string     `json:"video_title"`
+}
+
+// ReviewComment is a comment on a review. PositionSeconds pins it to a moment of the video.
+type ReviewComment struct {
+	ID              uuid.UUID  `json:"id"`
+	ReviewID        uuid.UUID  `json:"review_id"`
+	AuthorID        *uuid.UUID `json:"author_id"`
+	Body            string     `json:"body"`
+	PositionSeconds *float64   `json:"position_seconds"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization roles, as allowed by the user_org_roles.role check constraint
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleDeveloper = "developer"
+	RoleViewer    = "viewer"
+)
+
+// Roles lists every organization role from most to least privileged
+var Roles = []string{RoleOwner, RoleAdmin, RoleDeveloper, RoleViewer}
+
+// ValidRole reports whether role is a known organization role
+func ValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Membership is a user's role in an organization
+type Membership struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Role           string     `json:"role"`
+	InvitedBy      *uuid.UUID `json:"invited_by"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
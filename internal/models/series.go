@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Series groups videos into episodes numbered within seasons, such as the lessons of a course
+// or the episodes of a show
+type Series struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	ProjectID      *uuid.UUID `json:"project_id,omitempty"`
+	Title          string     `json:"title"`
+	Description    string     `json:"description"`
+	Artwork        *Image     `json:"artwork,omitempty"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	Version        int64      `json:"version"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	// Episodes are only loaded for a single series
+	Episodes []Episode `json:"episodes,omitempty"`
+}
+
+// Episode is a video's place in a series. Season 0 holds specials, by convention.
+type Episode struct {
+	SeriesID      uuid.UUID `json:"series_id"`
+	VideoID       uuid.UUID `json:"video_id"`
+	SeasonNumber  int       `json:"season_number"`
+	EpisodeNumber int       `json:"episode_number"`
+	// Title and Status are those of the video
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
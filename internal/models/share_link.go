@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShareLink opens one video to anyone holding its URL, under a slug of its own rather than the
+// video ID. It stops working once revoked, past ExpiresAt, or after MaxViews views.
+type ShareLink struct {
+	ID             uuid.UUID  `json:"id"`
+	VideoID        uuid.UUID  `json:"video_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	CreatedBy      *uuid.UUID `json:"created_by"`
+	Slug           string     `json:"slug"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	// MaxViews is how many times the link may be opened; null for no limit
+	MaxViews  *int `json:"max_views"`
+	ViewCount int  `json:"view_count"`
+	// AllowDownload lets viewers download the video's source through the link
+	AllowDownload bool `json:"allow_download"`
+	// AllowComments tells players and pages built on the link to offer comments
+	AllowComments bool       `json:"allow_comments"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	LastViewedAt  *time.Time `json:"last_viewed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Active tells whether the link may still be opened at now
+func (l *ShareLink) Active(now time.Time) bool {
+	if l.RevokedAt != nil || (l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)) {
+		return false
+	}
+	return l.MaxViews == nil || l.ViewCount < *l.MaxViews
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Orders of the videos of smart playlists
+const (
+	PlaylistSortNewest = "newest"
+	PlaylistSortOldest = "oldest"
+	PlaylistSortTitle  = "title"
+)
+
+// SmartPlaylist is a saved search over an organization's videos. Its videos are computed
+// from the criteria when it is read, so videos matching them later show up on their own.
+type SmartPlaylist struct {
+	ID             uuid.UUID        `json:"id"`
+	OrganizationID uuid.UUID        `json:"organization_id"`
+	Title          string           `json:"title"`
+	Description    string           `json:"description"`
+	Criteria       PlaylistCriteria `json:"criteria"`
+	// Sort is newest, oldest or title
+	Sort string `json:"sort"`
+	// MaxItems bounds the videos listed
+	MaxItems  int        `json:"max_items"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	Version   int64      `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	// Videos are only computed for a single playlist
+	Videos []Video `json:"videos,omitempty"`
+}
+
+// PlaylistCriteria select the videos of a smart playlist; a video must match all of them
+type PlaylistCriteria struct {
+	// Query matches text in the title or description
+	Query     string     `json:"query,omitempty"`
+	ProjectID *uuid.UUID `json:"project_id,omitempty"`
+	// Tags are tags a video must all have
+	Tags []string `json:"tags,omitempty"`
+	// Visibility is public, unlisted or private
+	Visibility string `json:"visibility,omitempty"`
+	// Fields match custom field values exactly, and FieldsMin and FieldsMax bound number and
+	// date fields, by key
+	Fields        map[string]string `json:"fields,omitempty"`
+	FieldsMin     map[string]string `json:"fields_min,omitempty"`
+	FieldsMax     map[string]string `json:"fields_max,omitempty"`
+	CreatedAfter  *time.Time        `json:"created_after,omitempty"`
+	CreatedBefore *time.Time        `json:"created_before,omitempty"`
+}
+
+// Value stores the criteria as a JSON object
+func (c PlaylistCriteria) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// Scan reads criteria stored as a JSON object
+func (c *PlaylistCriteria) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		return json.Unmarshal(s, c)
+	case string:
+		return json.Unmarshal([]byte(s), c)
+	case nil:
+		*c = PlaylistCriteria{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into PlaylistCriteria", src)
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SourceVersion is a source a video had before it was replaced, kept with the renditions made
+// from it until it expires, so the video can be rolled back to it
+type SourceVersion struct {
+	ID      uuid.UUID `json:"id"`
+	VideoID uuid.UUID `json:"video_id"`
+	// Revision is the video's source revision while the version was its source
+	Revision    int        `json:"revision"`
+	SourceKey   string     `json:"source_key"`
+	ContentType string     `json:"content_type,omitempty"`
+	SizeBytes   int64      `json:"size_bytes"`
+	SHA256      *string    `json:"sha256,omitempty"`
+	Color       *ColorInfo `json:"color,omitempty"`
+	// Renditions are the renditions the video had with this source, restored on rollback
+	Renditions []VideoRendition `json:"renditions"`
+	// TierStatus is the storage tier status of the source; archived versions are restored
+	// through the video's storage before they can be rolled back to
+	TierStatus string     `json:"tier_status"`
+	ReplacedBy *uuid.UUID `json:"replaced_by"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	// CreatedAt is when the source was replaced
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Storage object kinds
+const (
+	ObjectKindSource    = "source"
+	ObjectKindRendition = "rendition"
+	ObjectKindThumbnail = "thumbnail"
+	ObjectKindImage     = "image"
+	ObjectKindDownload  = "download"
+)
+
+// Storage tier statuses
+const (
+	TierStatusHot       = "hot"
+	TierStatusArchiving = "archiving"
+	TierStatusArchived  = "archived"
+	TierStatusRestoring = "restoring"
+	TierStatusRestored  = "restored"
+)
+
+// StorageObject tracks where a stored file lives in the storage tier lifecycle
+type StorageObject struct {
+	ID                 uuid.UUID  `json:"id"`
+	OrganizationID     uuid.UUID  `json:"organization_id"`
+	VideoID            *uuid.UUID `json:"video_id,omitempty"`
+	ObjectKey          string     `json:"object_key"`
+	Kind               string     `json:"kind"`
+	SizeBytes          int64      `json:"size_bytes"`
+	TierStatus         string     `json:"tier_status"`
+	StorageClass       string     `json:"storage_class"`
+	LastAccessedAt     time.Time  `json:"last_accessed_at"`
+	ArchivedAt         *time.Time `json:"archived_at,omitempty"`
+	RestoreRequestedAt *time.Time `json:"restore_requested_at,omitempty"`
+	RestoreExpiresAt   *time.Time `json:"restore_expires_at,omitempty"`
+	LastError          *string    `json:"last_error,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// Readable reports whether the object can currently be served without a restore
+func (o *StorageObject) Readable() bool {
+	return o.TierStatus == TierStatusHot || o.TierStatus == TierStatusRestored || o.TierStatus == TierStatusArchiving
+}
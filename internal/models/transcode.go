@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Transcode job statuses
+const (
+	TranscodeSubmitted   = "submitted"
+	TranscodeProgressing = "progressing"
+	TranscodeComplete    = "complete"
+	TranscodeFailed      = "failed"
+)
+
+// TranscodeJob is a job submitted to an external transcoder for one revision of a video's source
+type TranscodeJob struct {
+	ID             uuid.UUID `json:"id"`
+	VideoID        uuid.UUID `json:"video_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Provider       string    `json:"provider"`
+	// ExternalID is the transcoder's job ID
+	ExternalID     string     `json:"external_id"`
+	SourceRevision int        `json:"source_revision"`
+	Status         string     `json:"status"`
+	Progress       float64    `json:"progress"`
+	Error          *string    `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PlaybackQualityAuto lets players pick the quality by bandwidth; the other default qualities
+// are rendition heights such as 720p
+const PlaybackQualityAuto = "auto"
+
+// UserProfile is a user's own view of their account
+type UserProfile struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	Name  string    `json:"name"`
+	// Avatar is nil until one is uploaded; AvatarURL links to it
+	Avatar    *Image `json:"avatar,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	// Locale is a BCP 47 language tag such as en or pt-BR
+	Locale string `json:"locale"`
+	// Timezone is an IANA time zone name such as Europe/Paris
+	Timezone  string              `json:"timezone"`
+	Playback  PlaybackPreferences `json:"playback"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// PlaybackPreferences are how players start videos for a user
+type PlaybackPreferences struct {
+	Autoplay bool `json:"autoplay"`
+	// DefaultQuality is auto or a rendition height such as 720p
+	DefaultQuality string `json:"default_quality"`
+	CaptionsOn     bool   `json:"captions_on"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Video statuses. A video is uploading until its source is stored, then queued until its
+// transcoder takes it, processing while the transcoder works and ready once it is done, or
+// straight away without a transcoder. Failed videos never got a source. Deleted videos stop
+// playing but keep their row and history.
+const (
+	VideoStatusUploading  = "uploading"
+	VideoStatusQueued     = "queued"
+	VideoStatusProcessing = "processing"
+	VideoStatusReady      = "ready"
+	VideoStatusFailed     = "failed"
+	VideoStatusDeleted    = "deleted"
+)
+
+// PlayableVideoStatuses are the statuses of videos with a source to play. Queued and
+// processing videos play their source until the transcoder's renditions arrive.
+var PlayableVideoStatuses = []string{VideoStatusQueued, VideoStatusProcessing, VideoStatusReady}
+
+// VideoPlayable reports whether videos in status have a source to play
+func VideoPlayable(status string) bool {
+	return status == VideoStatusQueued || status == VideoStatusProcessing || status == VideoStatusReady
+}
+
+// Video visibilities
+const (
+	VideoVisibilityPublic   = "public"
+	VideoVisibilityUnlisted = "unlisted"
+	VideoVisibilityPrivate  = "private"
+)
+
+// Video moderation statuses. Restricted and taken down videos do not play.
+const (
+	ModerationActive     = "active"
+	ModerationRestricted = "restricted"
+	ModerationTakenDown  = "taken_down"
+)
+
+// Video review statuses. Videos of organizations that require review are pending until an
+// owner or admin approves them; pending and rejected videos only play for members.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Multipart upload statuses
+const (
+	UploadStatusPending   = "pending"
+	UploadStatusCompleted = "completed"
+	UploadStatusAborted   = "aborted"
+)
+
+// Duplicate upload policies
+const (
+	DuplicatePolicyOff   = "off"
+	DuplicatePolicyWarn  = "warn"
+	DuplicatePolicyReuse = "reuse"
+)
+
+// Video represents an uploaded video within an organization
+type Video struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	ProjectID      *uuid.UUID `json:"project_id,omitempty"`
+	// ExternalID is the ID of the video in an external system, unique within the organization
+	ExternalID  *string `json:"external_id,omitempty"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Status      string  `json:"status"`
+	// StatusChangedAt is when the video entered its status
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	Visibility      string    `json:"visibility"`
+	// RequiresEntitlement limits playback sessions to viewers with an entitlement to the video
+	RequiresEntitlement bool `json:"requires_entitlement"`
+	// DownloadsEnabled lets members request files of the video to download
+	DownloadsEnabled bool `json:"downloads_enabled"`
+	// ModerationStatus is restricted while reports wait for review and taken_down after a takedown
+	ModerationStatus string `json:"moderation_status"`
+	// ReviewStatus is pending until a video that needs approval is approved or rejected
+	ReviewStatus string   `json:"review_status"`
+	Tags         []string `json:"tags"`
+	// CustomFields are the values of the organization's custom fields, by key
+	CustomFields   CustomFieldValues `json:"custom_fields"`
+	Thumbnail      *Image            `json:"thumbnail,omitempty"`
+	Chapters       Chapters          `json:"chapters"`
+	AdBreaks       AdBreaks          `json:"ad_breaks"`
+	SourceKey      string            `json:"source_key,omitempty"`
+	ContentType    string            `json:"content_type,omitempty"`
+	SizeBytes      int64             `json:"size_bytes"`
+	SHA256         *string           `json:"sha256,omitempty"`
+	DuplicateOf    *uuid.UUID        `json:"duplicate_of,omitempty"`
+	SourceRevision int               `json:"source_revision"`
+	// Color is the color signaling of the source, telling HDR sources apart; null until the
+	// source is probed
+	Color      *ColorInfo `json:"color,omitempty"`
+	ReplacedAt *time.Time `json:"replaced_at,omitempty"`
+	CreatedBy  *uuid.UUID `json:"created_by,omitempty"`
+	Version    int64      `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// VideoStatusChange is a move of a video from one status to another
+type VideoStatusChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Reason explains changes made by the system, such as the transcoder job that finished
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TrendingVideo is a video with its estimated plays of the last day and week. Score weighs the
+// last day fully and the week by its daily average.
+type TrendingVideo struct {
+	Video      Video     `json:"video"`
+	Plays24h   int64     `json:"plays_24h"`
+	Plays7d    int64     `json:"plays_7d"`
+	Score      float64   `json:"score"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// VideoUpload tracks a multipart upload whose parts go directly to object storage
+type VideoUpload struct {
+	ID             uuid.UUID `json:"id"`
+	VideoID        uuid.UUID `json:"video_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	StorageKey     string    `json:"storage_key"`
+	UploadID       string    `json:"-"`
+	PartSize       int64     `json:"part_size"`
+	PartCount      int       `json:"part_count"`
+	Status         string    `json:"status"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	// ReplacesKey is the source the upload replaces when it completes; nil for new videos
+	ReplacesKey *string `json:"-"`
+	Replacement bool    `json:"replacement,omitempty"`
+	ContentType string  `json:"-"`
+	SizeBytes   int64   `json:"-"`
+}
+
+// PresignedPart is a presigned URL for uploading a single part
+type PresignedPart struct {
+	PartNumber int32  `json:"part_number"`
+	URL        string `json:"url"`
+}
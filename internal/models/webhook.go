@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEndpoint is a URL an organization receives events on. The secret signs deliveries
+// and is only returned when the endpoint is created.
+type WebhookEndpoint struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	URL            string     `json:"url"`
+	Secret         string     `json:"secret,omitempty"`
+	EventTypes     []string   `json:"event_types"`
+	Description    string     `json:"description"`
+	CreatedBy      *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
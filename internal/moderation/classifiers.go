@@ -0,0 +1,141 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"openvdo/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// maxClassifierResponse bounds the responses read from classifiers
+const maxClassifierResponse = 1 << 20
+
+// httpClassifier posts the whole sample to a moderation API in one request, frames as base64
+// JPEG and audio as base64 WAV, and expects the scores per category back:
+//
+//	{"scores": {"sexual": 0.02, "violence": 0.91}}
+type httpClassifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func newHTTPClassifier(cfg config.Moderation) *httpClassifier {
+	return &httpClassifier{url: cfg.ScanURL, token: cfg.ScanToken, client: &http.Client{}}
+}
+
+func (c *httpClassifier) Name() string {
+	return "http"
+}
+
+type classifierMedia struct {
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+type classifierFrame struct {
+	OffsetSeconds float64 `json:"offset_seconds"`
+	classifierMedia
+}
+
+func (c *httpClassifier) Classify(ctx context.Context, s *Sample) (map[string]float64, error) {
+	request := struct {
+		VideoID        uuid.UUID         `json:"video_id"`
+		OrganizationID uuid.UUID         `json:"organization_id"`
+		Frames         []classifierFrame `json:"frames"`
+		Audio          *classifierMedia  `json:"audio,omitempty"`
+	}{VideoID: s.VideoID, OrganizationID: s.OrganizationID, Frames: []classifierFrame{}}
+	for _, f := range s.Frames {
+		request.Frames = append(request.Frames, classifierFrame{
+			OffsetSeconds:   f.Offset,
+			classifierMedia: classifierMedia{ContentType: "image/jpeg", Data: f.JPEG},
+		})
+	}
+	if s.Audio != nil {
+		request.Audio = &classifierMedia{ContentType: "audio/wav", Data: s.Audio}
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Scores map[string]float64 `json:"scores"`
+	}
+	if err := c.post(ctx, "application/json", body, &response); err != nil {
+		return nil, err
+	}
+	if response.Scores == nil {
+		return nil, fmt.Errorf("response has no scores")
+	}
+	return response.Scores, nil
+}
+
+func (c *httpClassifier) post(ctx context.Context, contentType string, body []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "OpenVDO-Moderation/1.0")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxClassifierResponse))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("classifier returned %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid classifier response: %w", err)
+	}
+	return nil
+}
+
+// nsfwClassifier posts each frame to an image classifier serving an NSFW model, such as the
+// open source nsfw_model, which answers with the probability of each of its classes:
+//
+//	{"drawings": 0.01, "hentai": 0.02, "neutral": 0.9, "porn": 0.05, "sexy": 0.02}
+//
+// Audio is not classified. The highest score of each class over the frames is kept under
+// "nsfw.<class>", and porn and hentai count as sexual.
+type nsfwClassifier struct {
+	*httpClassifier
+}
+
+func newNSFWClassifier(cfg config.Moderation) *nsfwClassifier {
+	return &nsfwClassifier{newHTTPClassifier(cfg)}
+}
+
+func (c *nsfwClassifier) Name() string {
+	return "nsfw"
+}
+
+func (c *nsfwClassifier) Classify(ctx context.Context, s *Sample) (map[string]float64, error) {
+	scores := map[string]float64{}
+	for _, f := range s.Frames {
+		var classes map[string]float64
+		if err := c.post(ctx, "image/jpeg", f.JPEG, &classes); err != nil {
+			return nil, err
+		}
+		for class, p := range classes {
+			scores["nsfw."+class] = max(scores["nsfw."+class], p)
+		}
+	}
+	scores["sexual"] = max(scores["nsfw.porn"], scores["nsfw.hentai"])
+	return scores, nil
+}
@@ -0,0 +1,407 @@
+// Package moderation handles viewers' abuse reports and moderators' decisions on them. Enough
+// reports from distinct reporters unpublish a video until it is reviewed; a moderator then
+// dismisses the reports or takes the video down. For DMCA takedowns the organization may file a
+// counter-notice, after which the video can be restored. Optionally, a scanner has each ready
+// video classified and reports those that score above a threshold.
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrVideoNotFound    = errors.New("video not found")
+	ErrTakedownNotFound = errors.New("takedown not found")
+	// ErrAlreadyTakenDown is returned when a video already has a takedown in effect
+	ErrAlreadyTakenDown = errors.New("video is already taken down")
+	// ErrInvalidTransition is returned for decisions the takedown's status does not allow
+	ErrInvalidTransition = errors.New("takedown does not allow this change")
+)
+
+// Reasons lists the reasons a video may be reported for
+var Reasons = []string{
+	models.ReportReasonCopyright,
+	models.ReportReasonSexual,
+	models.ReportReasonViolence,
+	models.ReportReasonHarassment,
+	models.ReportReasonHateful,
+	models.ReportReasonSpam,
+	models.ReportReasonOther,
+}
+
+// IsReason reports whether r is a known report reason
+func IsReason(r string) bool {
+	for _, known := range Reasons {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+const reportColumns = `id, video_id, organization_id, reason, details, status, resolution_note, created_at, resolved_at`
+
+func scanReport(row interface{ Scan(...interface{}) error }) (*models.AbuseReport, error) {
+	var r models.AbuseReport
+	if err := row.Scan(&r.ID, &r.VideoID, &r.OrganizationID, &r.Reason, &r.Details, &r.Status, &r.ResolutionNote,
+		&r.CreatedAt, &r.ResolvedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// TakedownColumns is the column list matching ScanTakedown
+const TakedownColumns = `id, video_id, organization_id, kind, reason, claimant, status, counter_notice_statement,
+	counter_notice_contact, counter_noticed_at, restore_after, resolved_at, created_at, updated_at`
+
+// ScanTakedown scans a row selected with TakedownColumns
+func ScanTakedown(row interface{ Scan(...interface{}) error }) (*models.Takedown, error) {
+	var t models.Takedown
+	if err := row.Scan(&t.ID, &t.VideoID, &t.OrganizationID, &t.Kind, &t.Reason, &t.Claimant, &t.Status,
+		&t.CounterNoticeStatement, &t.CounterNoticeContact, &t.CounterNoticedAt, &t.RestoreAfter, &t.ResolvedAt,
+		&t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// QueueItem is a video with open reports waiting for review
+type QueueItem struct {
+	VideoID          uuid.UUID `json:"video_id"`
+	OrganizationID   uuid.UUID `json:"organization_id"`
+	Title            string    `json:"title"`
+	ModerationStatus string    `json:"moderation_status"`
+	OpenReports      int       `json:"open_reports"`
+	Reasons          []string  `json:"reasons"`
+	FirstReportedAt  time.Time `json:"first_reported_at"`
+	LastReportedAt   time.Time `json:"last_reported_at"`
+}
+
+// Case is everything a moderator reviews about a video
+type Case struct {
+	Video     *models.Video        `json:"video"`
+	Reports   []models.AbuseReport `json:"reports"`
+	Takedowns []models.Takedown    `json:"takedowns"`
+	// Scans are the automatic content scans of the video
+	Scans []models.ModerationScan `json:"scans"`
+}
+
+// Moderator files reports and applies moderators' decisions on the master connection
+type Moderator struct {
+	db     *sql.DB
+	config config.Moderation
+}
+
+// NewModerator creates a moderator. db must not carry a tenant context.
+func NewModerator(db *sql.DB, cfg config.Moderation) *Moderator {
+	return &Moderator{db: db, config: cfg}
+}
+
+// Report files a report. A reporter's repeated reports of a video count once while open.
+// It returns whether the report pushed the video over the threshold and unpublished it.
+func (m *Moderator) Report(ctx context.Context, videoID uuid.UUID, reason, details, reporterKey string) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	restricted, err := m.fileReport(ctx, tx, videoID, reason, details, reporterKey, m.config.ReportThreshold)
+	if err != nil {
+		return false, err
+	}
+	return restricted, tx.Commit()
+}
+
+// fileReport files a report in tx and unpublishes the video once it has threshold open reports;
+// 0 never unpublishes
+func (m *Moderator) fileReport(ctx context.Context, tx *sql.Tx, videoID uuid.UUID, reason, details, reporterKey string, threshold int) (bool, error) {
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO abuse_reports (video_id, organization_id, reason, details, reporter_key)
+		SELECT id, organization_id, $2, $3, $4 FROM videos WHERE id = $1
+		ON CONFLICT (video_id, reporter_key) WHERE status = 'open' DO NOTHING
+	`, videoID, reason, details, reporterKey)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		exists, err := queries.New(tx).VideoExists(ctx, videoID)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, ErrVideoNotFound
+		}
+		return false, nil
+	}
+
+	restricted := false
+	if threshold > 0 {
+		n, err := queries.New(tx).RestrictReportedVideo(ctx, queries.RestrictReportedVideoParams{
+			ModerationStatus: models.ModerationRestricted, ID: videoID, FromStatus: models.ModerationActive,
+			Threshold: int64(threshold),
+		})
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			restricted = true
+			if err := services.RecordVideoEvent(ctx, tx, outbox.EventVideoModerated, videoID); err != nil {
+				return false, err
+			}
+		}
+	}
+	return restricted, nil
+}
+
+// Queue lists videos with open reports, those unpublished by the threshold first, then the
+// most reported
+func (m *Moderator) Queue(ctx context.Context, limit int) ([]QueueItem, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT v.id, v.organization_id, v.title, v.moderation_status, COUNT(*),
+			array_agg(DISTINCT r.reason ORDER BY r.reason), MIN(r.created_at), MAX(r.created_at)
+		FROM abuse_reports r JOIN videos v ON v.id = r.video_id
+		WHERE r.status = 'open'
+		GROUP BY v.id
+		ORDER BY v.moderation_status = 'restricted' DESC, COUNT(*) DESC, MIN(r.created_at)
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []QueueItem{}
+	for rows.Next() {
+		var item QueueItem
+		if err := rows.Scan(&item.VideoID, &item.OrganizationID, &item.Title, &item.ModerationStatus, &item.OpenReports,
+			pq.Array(&item.Reasons), &item.FirstReportedAt, &item.LastReportedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Review loads a video with all its reports, takedowns and scans, newest first
+func (m *Moderator) Review(ctx context.Context, videoID uuid.UUID) (*Case, error) {
+	video, err := services.VideoModel(queries.New(m.db).GetVideo(ctx, videoID))
+	if err == sql.ErrNoRows {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &Case{Video: video, Reports: []models.AbuseReport{}, Takedowns: []models.Takedown{}}
+
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT `+reportColumns+` FROM abuse_reports WHERE video_id = $1 ORDER BY created_at DESC`, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		c.Reports = append(c.Reports, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	c.Takedowns, err = m.listTakedowns(ctx, `WHERE video_id = $1`, videoID)
+	if err != nil {
+		return nil, err
+	}
+	c.Scans, err = Scans(ctx, m.db, videoID)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Dismiss closes a video's open reports without action and publishes the video again if the
+// threshold had unpublished it
+func (m *Moderator) Dismiss(ctx context.Context, videoID uuid.UUID, note string) (*models.Video, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE abuse_reports SET status = 'dismissed', resolution_note = $2, resolved_at = NOW()
+		WHERE video_id = $1 AND status = 'open'
+	`, videoID, note); err != nil {
+		return nil, err
+	}
+	qtx := queries.New(tx)
+	n, err := qtx.ChangeModerationStatus(ctx, queries.ChangeModerationStatusParams{
+		ModerationStatus: models.ModerationActive, ID: videoID, FromStatus: models.ModerationRestricted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n > 0 {
+		if err := services.RecordVideoEvent(ctx, tx, outbox.EventVideoModerated, videoID); err != nil {
+			return nil, err
+		}
+	}
+
+	video, err := services.VideoModel(qtx.GetVideo(ctx, videoID))
+	if err == sql.ErrNoRows {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return video, tx.Commit()
+}
+
+// TakeDown removes a video from playback and closes its open reports as actioned
+func (m *Moderator) TakeDown(ctx context.Context, videoID uuid.UUID, kind, reason, claimant string) (*models.Takedown, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	takedown, err := ScanTakedown(tx.QueryRowContext(ctx, `
+		INSERT INTO takedowns (video_id, organization_id, kind, reason, claimant)
+		SELECT id, organization_id, $2, $3, $4 FROM videos WHERE id = $1
+		RETURNING `+TakedownColumns,
+		videoID, kind, reason, claimant))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return nil, ErrAlreadyTakenDown
+	}
+	if err == sql.ErrNoRows {
+		return nil, ErrVideoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := queries.New(tx).SetModerationStatus(ctx, queries.SetModerationStatusParams{
+		ID: videoID, ModerationStatus: models.ModerationTakenDown,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE abuse_reports SET status = 'actioned', resolution_note = $2, resolved_at = NOW()
+		WHERE video_id = $1 AND status = 'open'
+	`, videoID, reason); err != nil {
+		return nil, err
+	}
+	if err := services.RecordVideoEvent(ctx, tx, outbox.EventVideoModerated, videoID); err != nil {
+		return nil, err
+	}
+	return takedown, tx.Commit()
+}
+
+// Takedowns lists takedowns with the given status, or all of them, newest first
+func (m *Moderator) Takedowns(ctx context.Context, status string) ([]models.Takedown, error) {
+	return m.listTakedowns(ctx, `WHERE $1 = '' OR status = $1`, status)
+}
+
+func (m *Moderator) listTakedowns(ctx context.Context, where string, arg interface{}) ([]models.Takedown, error) {
+	rows, err := m.db.QueryContext(ctx,
+		`SELECT `+TakedownColumns+` FROM takedowns `+where+` ORDER BY created_at DESC LIMIT 500`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	takedowns := []models.Takedown{}
+	for rows.Next() {
+		t, err := ScanTakedown(rows)
+		if err != nil {
+			return nil, err
+		}
+		takedowns = append(takedowns, *t)
+	}
+	return takedowns, rows.Err()
+}
+
+// Restore ends a takedown in effect and publishes the video again
+func (m *Moderator) Restore(ctx context.Context, takedownID uuid.UUID) (*models.Takedown, error) {
+	return m.resolve(ctx, takedownID, models.TakedownStatusRestored,
+		[]string{models.TakedownStatusActive, models.TakedownStatusCounterNoticed})
+}
+
+// Uphold keeps a counter-noticed video down for good, once the claimant has taken legal action
+func (m *Moderator) Uphold(ctx context.Context, takedownID uuid.UUID) (*models.Takedown, error) {
+	return m.resolve(ctx, takedownID, models.TakedownStatusUpheld, []string{models.TakedownStatusCounterNoticed})
+}
+
+func (m *Moderator) resolve(ctx context.Context, takedownID uuid.UUID, status string, from []string) (*models.Takedown, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	takedown, err := ScanTakedown(tx.QueryRowContext(ctx, `
+		UPDATE takedowns SET status = $2, resolved_at = NOW()
+		WHERE id = $1 AND status = ANY($3)
+		RETURNING `+TakedownColumns,
+		takedownID, status, pq.Array(from)))
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM takedowns WHERE id = $1)`, takedownID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrTakedownNotFound
+		}
+		return nil, ErrInvalidTransition
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if status == models.TakedownStatusRestored {
+		if err := queries.New(tx).SetModerationStatus(ctx, queries.SetModerationStatusParams{
+			ID: takedown.VideoID, ModerationStatus: models.ModerationActive,
+		}); err != nil {
+			return nil, err
+		}
+		if err := services.RecordVideoEvent(ctx, tx, outbox.EventVideoModerated, takedown.VideoID); err != nil {
+			return nil, err
+		}
+	}
+	return takedown, tx.Commit()
+}
+
+// CounterNotice files the organization's counter-notice against the DMCA takedown in effect on
+// a video. q is the caller's tenant connection, so row-level security limits it to owners and
+// admins of the video's organization.
+func CounterNotice(ctx context.Context, q database.Querier, videoID uuid.UUID, statement, contact string, wait time.Duration) (*models.Takedown, error) {
+	takedown, err := ScanTakedown(q.QueryRowContext(ctx, `
+		UPDATE takedowns
+		SET status = $2, counter_notice_statement = $3, counter_notice_contact = $4, counter_noticed_at = NOW(),
+			restore_after = NOW() + $5 * INTERVAL '1 second'
+		WHERE video_id = $1 AND kind = $6 AND status = $7
+		RETURNING `+TakedownColumns,
+		videoID, models.TakedownStatusCounterNoticed, statement, contact, int64(wait.Seconds()),
+		models.TakedownKindDMCA, models.TakedownStatusActive))
+	if err == sql.ErrNoRows {
+		return nil, ErrTakedownNotFound
+	}
+	return takedown, err
+}
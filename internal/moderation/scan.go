@@ -0,0 +1,402 @@
+package moderation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// JobKindVideoScan scans the source of a video with the configured classifier
+const JobKindVideoScan = "video.scan"
+
+// frameWidth is the width frames are scaled to; classifiers work on small images
+const frameWidth = 512
+
+// Frame is a still sampled from a video
+type Frame struct {
+	// Offset is the position of the frame in the video, in seconds
+	Offset float64
+	JPEG   []byte
+}
+
+// Sample is what a classifier sees of a video: stills spread evenly over its duration and the
+// start of its audio track
+type Sample struct {
+	VideoID        uuid.UUID
+	OrganizationID uuid.UUID
+	Frames         []Frame
+	// Audio is 16 kHz mono WAV, or nil for videos without an audio track or when audio is not
+	// sampled
+	Audio        []byte
+	AudioSeconds int
+}
+
+// Classifier scores a sample per category, from 0 to 1. Categories named like report reasons,
+// such as sexual or violence, can flag the video; other categories are only recorded.
+type Classifier interface {
+	Name() string
+	Classify(ctx context.Context, s *Sample) (map[string]float64, error)
+}
+
+// NewClassifier returns the classifier of the configured provider, or nil when scanning is off
+func NewClassifier(cfg config.Moderation) (Classifier, error) {
+	switch cfg.ScanProvider {
+	case "":
+		return nil, nil
+	case "http":
+		if cfg.ScanURL == "" {
+			return nil, fmt.Errorf("MODERATION_SCAN_URL is required for the http scan provider")
+		}
+		return newHTTPClassifier(cfg), nil
+	case "nsfw":
+		if cfg.ScanURL == "" {
+			return nil, fmt.Errorf("MODERATION_SCAN_URL is required for the nsfw scan provider")
+		}
+		return newNSFWClassifier(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown moderation scan provider %q", cfg.ScanProvider)
+	}
+}
+
+// Scanner samples the source of each video that becomes ready and has it classified. As an
+// outbox publisher it queues a video.scan job in the relay's transaction for video.ready and
+// video.source_replaced events; categories scoring at or above the threshold file a report, so
+// the video lands in the moderation queue.
+type Scanner struct {
+	moderator  *Moderator
+	storage    storage.Storage
+	classifier Classifier
+	config     config.Moderation
+	ffmpeg     string
+	ffprobe    string
+}
+
+// NewScanner creates a scanner. It fails when ffmpeg or ffprobe cannot be found.
+func NewScanner(moderator *Moderator, store storage.Storage, classifier Classifier, cfg config.Moderation) (*Scanner, error) {
+	ffmpeg, err := exec.LookPath(cfg.FFmpegPath)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found at %s", cfg.FFmpegPath)
+	}
+	ffprobe, err := exec.LookPath(cfg.FFprobePath)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found at %s", cfg.FFprobePath)
+	}
+	if _, ok := classifier.(*nsfwClassifier); ok {
+		// Image classifiers have no use for audio
+		cfg.ScanAudioSeconds = 0
+	}
+	return &Scanner{
+		moderator:  moderator,
+		storage:    store,
+		classifier: classifier,
+		config:     cfg,
+		ffmpeg:     ffmpeg,
+		ffprobe:    ffprobe,
+	}, nil
+}
+
+type scanPayload struct {
+	VideoID uuid.UUID `json:"video_id"`
+}
+
+// Name implements outbox.Publisher
+func (s *Scanner) Name() string {
+	return "moderation-scan"
+}
+
+// Publish implements outbox.Publisher
+func (s *Scanner) Publish(ctx context.Context, tx *sql.Tx, e *outbox.Event) error {
+	if (e.Type != outbox.EventVideoReady && e.Type != outbox.EventVideoSourceReplaced) || e.SubjectID == nil {
+		return nil
+	}
+	_, err := Enqueue(ctx, tx, *e.SubjectID, e.OrganizationID)
+	return err
+}
+
+// Enqueue queues a scan of a video's current source
+func Enqueue(ctx context.Context, q database.Querier, videoID, orgID uuid.UUID) (*jobs.Job, error) {
+	return jobs.Enqueue(ctx, q, JobKindVideoScan, scanPayload{VideoID: videoID},
+		jobs.Options{OrganizationID: &orgID})
+}
+
+// Handle runs a video.scan job
+func (s *Scanner) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload scanPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	video, err := queries.New(s.moderator.db).GetVideoSource(ctx, payload.VideoID)
+	if err == sql.ErrNoRows {
+		// Deleted since the scan was queued
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if video.SourceKey == nil || *video.SourceKey == "" {
+		return jobs.Permanent(fmt.Errorf("video %s has no source", payload.VideoID))
+	}
+
+	if s.config.ScanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.ScanTimeout)
+		defer cancel()
+	}
+
+	sample, err := s.sample(ctx, *video.SourceKey)
+	if err != nil {
+		return fmt.Errorf("failed to sample source: %w", err)
+	}
+	sample.VideoID = payload.VideoID
+	sample.OrganizationID = video.OrganizationID
+	progress(0.5)
+
+	scores, err := s.classifier.Classify(ctx, sample)
+	if err != nil {
+		return fmt.Errorf("%s classifier: %w", s.classifier.Name(), err)
+	}
+
+	flagged := []string{}
+	for category, score := range scores {
+		if score >= s.config.ScanThreshold && IsReason(category) {
+			flagged = append(flagged, category)
+		}
+	}
+	// Highest score first; the first category is the reason of the report
+	sort.Slice(flagged, func(i, j int) bool { return scores[flagged[i]] > scores[flagged[j]] })
+
+	restricted, err := s.record(ctx, &models.ModerationScan{
+		VideoID:        payload.VideoID,
+		OrganizationID: video.OrganizationID,
+		Provider:       s.classifier.Name(),
+		SourceKey:      *video.SourceKey,
+		Frames:         len(sample.Frames),
+		AudioSeconds:   sample.AudioSeconds,
+		Scores:         scores,
+		Flagged:        flagged,
+	})
+	if errors.Is(err, ErrVideoNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(flagged) > 0 {
+		logger.Info("Scan of video %s flagged %s (unpublished: %v)", payload.VideoID, strings.Join(flagged, ", "), restricted)
+	}
+	return nil
+}
+
+// record stores a scan and, when it flagged categories, files the scanner's report. With
+// ScanRestrict the report unpublishes the video on its own.
+func (s *Scanner) record(ctx context.Context, scan *models.ModerationScan) (bool, error) {
+	scores, err := json.Marshal(scan.Scores)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := s.moderator.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO moderation_scans (video_id, organization_id, provider, source_key, frames, audio_seconds, scores, flagged)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, scan.VideoID, scan.OrganizationID, scan.Provider, scan.SourceKey, scan.Frames, scan.AudioSeconds, scores,
+		pq.Array(scan.Flagged)); err != nil {
+		return false, err
+	}
+
+	restricted := false
+	if len(scan.Flagged) > 0 {
+		details := make([]string, len(scan.Flagged))
+		for i, category := range scan.Flagged {
+			details[i] = fmt.Sprintf("%s %.2f", category, scan.Scores[category])
+		}
+		threshold := s.moderator.config.ReportThreshold
+		if s.config.ScanRestrict {
+			threshold = 1
+		}
+		restricted, err = s.moderator.fileReport(ctx, tx, scan.VideoID, scan.Flagged[0],
+			"Automatic scan by "+scan.Provider+": "+strings.Join(details, ", "), "scan:"+scan.Provider, threshold)
+		if err != nil {
+			return false, err
+		}
+	}
+	return restricted, tx.Commit()
+}
+
+// sample extracts frames and audio from a source. Backends that presign URLs let ffmpeg read
+// only the parts it seeks to; the source is downloaded otherwise.
+func (s *Scanner) sample(ctx context.Context, key string) (*Sample, error) {
+	dir, err := os.MkdirTemp("", "openvdo-scan-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	input := ""
+	if presigner, ok := s.storage.(storage.Presigner); ok {
+		expiry := s.config.ScanTimeout
+		if expiry <= 0 {
+			expiry = time.Hour
+		}
+		if input, err = presigner.PresignGet(ctx, key, expiry); err != nil {
+			return nil, err
+		}
+	} else {
+		input = filepath.Join(dir, "source")
+		if err := s.download(ctx, key, input); err != nil {
+			return nil, err
+		}
+	}
+
+	duration, err := s.duration(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	sample := &Sample{}
+	frames := s.config.ScanFrames
+	if frames < 1 || duration == 0 {
+		frames = 1
+	}
+	for i := 0; i < frames; i++ {
+		// The middle of each of frames equal parts, which skips black leaders and end cards
+		offset := duration * (float64(i) + 0.5) / float64(frames)
+		out := filepath.Join(dir, fmt.Sprintf("frame%d.jpg", i))
+		if err := s.run(ctx, "-ss", strconv.FormatFloat(offset, 'f', 3, 64), "-i", input, "-frames:v", "1",
+			"-vf", fmt.Sprintf("scale=%d:-2", frameWidth), "-q:v", "4", "-f", "image2", out); err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			return nil, err
+		}
+		sample.Frames = append(sample.Frames, Frame{Offset: offset, JPEG: data})
+	}
+
+	if s.config.ScanAudioSeconds > 0 {
+		out := filepath.Join(dir, "audio.wav")
+		err := s.run(ctx, "-i", input, "-t", strconv.Itoa(s.config.ScanAudioSeconds), "-map", "0:a:0",
+			"-vn", "-ac", "1", "-ar", "16000", "-f", "wav", out)
+		if err == nil {
+			sample.Audio, err = os.ReadFile(out)
+		}
+		if err != nil {
+			// Most often the source has no audio track
+			logger.Info("Scanning %s without audio: %v", key, err)
+			sample.Audio = nil
+		} else {
+			sample.AudioSeconds = min(s.config.ScanAudioSeconds, int(duration+0.5))
+		}
+	}
+	return sample, nil
+}
+
+func (s *Scanner) download(ctx context.Context, key, path string) error {
+	r, err := s.storage.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// duration probes the length of a source in seconds
+func (s *Scanner) duration(ctx context.Context, input string) (float64, error) {
+	output, err := exec.CommandContext(ctx, s.ffprobe, "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", input).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe: %w", err)
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil || duration <= 0 {
+		// Some containers carry no duration; sample the start
+		return 0, nil
+	}
+	return duration, nil
+}
+
+func (s *Scanner) run(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, s.ffmpeg, append([]string{"-v", "error", "-y"}, args...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ModerationScanColumns is the column list matching ScanModerationScan
+const ModerationScanColumns = `id, video_id, organization_id, provider, source_key, frames, audio_seconds, scores, flagged, created_at`
+
+// ScanModerationScan scans a row selected with ModerationScanColumns
+func ScanModerationScan(row interface{ Scan(...interface{}) error }) (*models.ModerationScan, error) {
+	var scan models.ModerationScan
+	var scores []byte
+	if err := row.Scan(&scan.ID, &scan.VideoID, &scan.OrganizationID, &scan.Provider, &scan.SourceKey, &scan.Frames,
+		&scan.AudioSeconds, &scores, pq.Array(&scan.Flagged), &scan.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(scores, &scan.Scores); err != nil {
+		return nil, err
+	}
+	if scan.Flagged == nil {
+		scan.Flagged = []string{}
+	}
+	return &scan, nil
+}
+
+// Scans lists the scans of a video, newest first. Through a tenant connection only scans of
+// the caller's organizations are visible.
+func Scans(ctx context.Context, q database.Querier, videoID uuid.UUID) ([]models.ModerationScan, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT `+ModerationScanColumns+` FROM moderation_scans WHERE video_id = $1 ORDER BY created_at DESC LIMIT 100`, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scans := []models.ModerationScan{}
+	for rows.Next() {
+		scan, err := ScanModerationScan(rows)
+		if err != nil {
+			return nil, err
+		}
+		scans = append(scans, *scan)
+	}
+	return scans, rows.Err()
+}
@@ -0,0 +1,320 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Contract checks responses against the operations of an OpenAPI 3.1 document, so handlers
+// drifting from what the document promises are noticed by whoever runs against them
+type Contract struct {
+	schemas    map[string]interface{}
+	operations map[string]map[string]interface{}
+}
+
+// Violation is a way a response departs from its documented schema
+type Violation struct {
+	// Path locates the offending value in the body, as in data.videos[0].status
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// NewContract reads the operations of an OpenAPI 3.1 document, as Document returns
+func NewContract(doc []byte) (*Contract, error) {
+	var parsed struct {
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(doc, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to read the OpenAPI document: %w", err)
+	}
+
+	c := &Contract{schemas: parsed.Components.Schemas, operations: map[string]map[string]interface{}{}}
+	for path, item := range parsed.Paths {
+		for method, op := range item {
+			if operation := object(op); operation != nil {
+				c.operations[strings.ToUpper(method)+" "+path] = operation
+			}
+		}
+	}
+	return c, nil
+}
+
+// Documents tells whether the document describes the route, given in Gin's syntax as in
+// /api/v1/videos/:id
+func (c *Contract) Documents(method, route string) bool {
+	_, ok := c.operations[method+" "+documentPath(route)]
+	return ok
+}
+
+// CheckResponse returns how a JSON response of the route departs from the document: an
+// undocumented status, or a body not matching the schema of its status. Responses of
+// undocumented routes are not checked.
+func (c *Contract) CheckResponse(method, route string, status int, body []byte) []Violation {
+	op, ok := c.operations[method+" "+documentPath(route)]
+	if !ok {
+		return nil
+	}
+	responses := object(op["responses"])
+	response, ok := responses[strconv.Itoa(status)]
+	if !ok {
+		response, ok = responses["default"]
+	}
+	if !ok {
+		return []Violation{{Message: fmt.Sprintf("status %d is not documented", status)}}
+	}
+
+	media := object(object(object(response)["content"])["application/json"])
+	if media == nil || len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return []Violation{{Message: "body is not valid JSON: " + err.Error()}}
+	}
+
+	var violations []Violation
+	c.check(object(media["schema"]), value, "", &violations)
+	return violations
+}
+
+// documentPath rewrites a Gin route into the path templates of the document
+func documentPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (c *Contract) resolve(schema map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 16; i++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		schema = object(c.schemas[strings.TrimPrefix(ref, "#/components/schemas/")])
+	}
+	return schema
+}
+
+func (c *Contract) check(schema map[string]interface{}, value interface{}, path string, violations *[]Violation) {
+	schema = c.resolve(schema)
+	if schema == nil {
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, sub := range list(schema["allOf"]) {
+		c.checkMember(object(sub), value, path, violations)
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		alternatives := list(schema[key])
+		if len(alternatives) == 0 {
+			continue
+		}
+		matched := false
+		for _, alternative := range alternatives {
+			var failed []Violation
+			c.check(object(alternative), value, path, &failed)
+			if len(failed) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("matches none of the documented schemas")
+		}
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !matchesType(types, value) {
+		fail("is %s, documented as %s", jsonType(value), strings.Join(types, " or "))
+		return
+	}
+	if enum := list(schema["enum"]); len(enum) > 0 && value != nil && !inEnum(enum, value) {
+		fail("%v is not one of the documented values", value)
+	}
+	if s, ok := value.(string); ok {
+		switch schema["format"] {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				fail("%q is not a date-time", s)
+			}
+		case "uuid":
+			if _, err := uuid.Parse(s); err != nil {
+				fail("%q is not a UUID", s)
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		if items := object(schema["items"]); items != nil {
+			for i, item := range v {
+				c.check(items, item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case map[string]interface{}:
+		c.checkObject(schema, v, path, violations)
+	}
+}
+
+// checkMember checks a member of allOf, whose properties only describe part of the object
+func (c *Contract) checkMember(schema map[string]interface{}, value interface{}, path string, violations *[]Violation) {
+	schema = c.resolve(schema)
+	if fields, ok := value.(map[string]interface{}); ok && schema != nil {
+		stripped := make(map[string]interface{}, len(schema))
+		for key, v := range schema {
+			if key != "additionalProperties" {
+				stripped[key] = v
+			}
+		}
+		stripped["additionalProperties"] = true
+		c.check(stripped, fields, path, violations)
+		return
+	}
+	c.check(schema, value, path, violations)
+}
+
+// checkObject checks the properties of an object. Optional properties may be null, as
+// pointers of the models encode. Properties the schema does not document are violations
+// when it documents any.
+func (c *Contract) checkObject(schema map[string]interface{}, fields map[string]interface{}, path string, violations *[]Violation) {
+	properties := c.properties(schema)
+	required := map[string]bool{}
+	for _, name := range list(schema["required"]) {
+		if s, ok := name.(string); ok {
+			required[s] = true
+			if _, present := fields[s]; !present {
+				*violations = append(*violations, Violation{Path: path, Message: fmt.Sprintf("required property %q is missing", s)})
+			}
+		}
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	additional := schema["additionalProperties"]
+	for _, name := range names {
+		value := fields[name]
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		if property, ok := properties[name]; ok {
+			if value == nil && !required[name] {
+				continue
+			}
+			c.check(property, value, fieldPath, violations)
+			continue
+		}
+		switch extra := additional.(type) {
+		case map[string]interface{}:
+			c.check(extra, value, fieldPath, violations)
+		case bool:
+			if !extra {
+				*violations = append(*violations, Violation{Path: fieldPath, Message: "property is not documented"})
+			}
+		case nil:
+			if len(properties) > 0 {
+				*violations = append(*violations, Violation{Path: fieldPath, Message: "property is not documented"})
+			}
+		}
+	}
+}
+
+// properties gathers the properties of a schema and of the members of its allOf
+func (c *Contract) properties(schema map[string]interface{}) map[string]map[string]interface{} {
+	properties := map[string]map[string]interface{}{}
+	for name, property := range object(schema["properties"]) {
+		properties[name] = object(property)
+	}
+	for _, member := range list(schema["allOf"]) {
+		for name, property := range c.properties(c.resolve(object(member))) {
+			if _, ok := properties[name]; !ok {
+				properties[name] = property
+			}
+		}
+	}
+	return properties
+}
+
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
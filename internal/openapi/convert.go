@@ -0,0 +1,385 @@
+// Package openapi publishes the API description as OpenAPI 3.1. The annotations on the
+// handlers are compiled by swag, which only emits Swagger 2.0, so the document in docs is
+// converted rather than maintained twice.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Version is the OpenAPI version Convert produces
+const Version = "3.1.0"
+
+// parameterSchemaKeys are the keys of a Swagger 2.0 parameter or header that OpenAPI 3 moves
+// into its schema
+var parameterSchemaKeys = []string{
+	"type", "format", "items", "enum", "default", "maximum", "exclusiveMaximum", "minimum",
+	"exclusiveMinimum", "maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems",
+	"multipleOf",
+}
+
+// Convert rewrites a Swagger 2.0 document into an OpenAPI 3.1 one. Definitions become
+// components, body and form parameters become request bodies, and response schemas are
+// offered in every media type the operation produces.
+func Convert(swagger []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(swagger, &doc); err != nil {
+		return nil, fmt.Errorf("failed to read the Swagger document: %w", err)
+	}
+	if version, _ := doc["swagger"].(string); version != "2.0" {
+		return nil, fmt.Errorf("unsupported Swagger version %q", version)
+	}
+
+	consumes := stringList(doc["consumes"], "application/json")
+	produces := stringList(doc["produces"], "application/json")
+
+	out := map[string]interface{}{
+		"openapi": Version,
+		"info":    doc["info"],
+	}
+	if servers := convertServers(doc); len(servers) > 0 {
+		out["servers"] = servers
+	}
+	for _, key := range []string{"tags", "security", "externalDocs"} {
+		if value, ok := doc[key]; ok {
+			out[key] = value
+		}
+	}
+
+	paths := map[string]interface{}{}
+	for path, item := range object(doc["paths"]) {
+		paths[path] = convertPathItem(object(item), consumes, produces)
+	}
+	out["paths"] = paths
+
+	components := map[string]interface{}{}
+	if definitions := object(doc["definitions"]); len(definitions) > 0 {
+		schemas := map[string]interface{}{}
+		for name, schema := range definitions {
+			schemas[name] = convertSchema(schema)
+		}
+		components["schemas"] = schemas
+	}
+	if definitions := object(doc["securityDefinitions"]); len(definitions) > 0 {
+		schemes := map[string]interface{}{}
+		for name, definition := range definitions {
+			schemes[name] = convertSecurityScheme(object(definition))
+		}
+		components["securitySchemes"] = schemes
+	}
+	if len(components) > 0 {
+		out["components"] = components
+	}
+
+	return json.MarshalIndent(out, "", "    ")
+}
+
+func convertServers(doc map[string]interface{}) []interface{} {
+	host, _ := doc["host"].(string)
+	basePath, _ := doc["basePath"].(string)
+	if host == "" {
+		if basePath == "" {
+			return nil
+		}
+		return []interface{}{map[string]interface{}{"url": basePath}}
+	}
+	var servers []interface{}
+	for _, scheme := range stringList(doc["schemes"], "http") {
+		servers = append(servers, map[string]interface{}{"url": scheme + "://" + host + basePath})
+	}
+	return servers
+}
+
+func convertPathItem(item map[string]interface{}, consumes, produces []string) map[string]interface{} {
+	out := map[string]interface{}{}
+	var shared []interface{}
+	for key, value := range item {
+		switch key {
+		case "parameters":
+			shared = list(value)
+		case "get", "put", "post", "delete", "options", "head", "patch":
+			out[key] = convertOperation(object(value), consumes, produces)
+		default:
+			out[key] = value
+		}
+	}
+	if len(shared) > 0 {
+		var parameters []interface{}
+		for _, parameter := range shared {
+			if p := object(parameter); p["in"] != "body" && p["in"] != "formData" {
+				parameters = append(parameters, convertParameter(p))
+			}
+		}
+		if len(parameters) > 0 {
+			out["parameters"] = parameters
+		}
+	}
+	return out
+}
+
+func convertOperation(op map[string]interface{}, consumes, produces []string) map[string]interface{} {
+	consumes = stringList(op["consumes"], consumes...)
+	produces = stringList(op["produces"], produces...)
+
+	out := map[string]interface{}{}
+	for key, value := range op {
+		switch key {
+		case "consumes", "produces", "parameters", "responses", "schemes":
+		default:
+			out[key] = value
+		}
+	}
+
+	var parameters []interface{}
+	var form []map[string]interface{}
+	for _, parameter := range list(op["parameters"]) {
+		p := object(parameter)
+		switch p["in"] {
+		case "body":
+			body := map[string]interface{}{"content": mediaTypes(consumes, convertSchema(p["schema"]))}
+			if description, ok := p["description"]; ok {
+				body["description"] = description
+			}
+			if required, _ := p["required"].(bool); required {
+				body["required"] = true
+			}
+			out["requestBody"] = body
+		case "formData":
+			form = append(form, p)
+		default:
+			parameters = append(parameters, convertParameter(p))
+		}
+	}
+	if len(parameters) > 0 {
+		out["parameters"] = parameters
+	}
+	if len(form) > 0 {
+		out["requestBody"] = convertForm(form, consumes)
+	}
+
+	responses := map[string]interface{}{}
+	for status, response := range object(op["responses"]) {
+		responses[status] = convertResponse(object(response), produces)
+	}
+	out["responses"] = responses
+	return out
+}
+
+func convertParameter(p map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for _, key := range []string{"name", "in", "description", "required", "allowEmptyValue"} {
+		if value, ok := p[key]; ok {
+			out[key] = value
+		}
+	}
+	if p["in"] == "path" {
+		out["required"] = true
+	}
+	out["schema"] = convertSchema(parameterSchema(p))
+
+	switch p["collectionFormat"] {
+	case "csv":
+		if p["in"] == "query" {
+			out["explode"] = false
+		}
+	case "ssv":
+		out["style"] = "spaceDelimited"
+	case "pipes":
+		out["style"] = "pipeDelimited"
+	case "multi":
+		out["explode"] = true
+	}
+	return out
+}
+
+// convertForm gathers formData parameters into the object schema of a request body
+func convertForm(form []map[string]interface{}, consumes []string) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []interface{}
+	for _, p := range form {
+		name, _ := p["name"].(string)
+		schema := convertSchema(parameterSchema(p))
+		if description, ok := p["description"]; ok {
+			schema["description"] = description
+		}
+		properties[name] = schema
+		if isRequired, _ := p["required"].(bool); isRequired {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	var formTypes []string
+	for _, mediaType := range consumes {
+		if mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded" {
+			formTypes = append(formTypes, mediaType)
+		}
+	}
+	if len(formTypes) == 0 {
+		formTypes = []string{"multipart/form-data"}
+	}
+	return map[string]interface{}{"content": mediaTypes(formTypes, schema)}
+}
+
+func convertResponse(response map[string]interface{}, produces []string) map[string]interface{} {
+	out := map[string]interface{}{"description": response["description"]}
+	if out["description"] == nil {
+		out["description"] = ""
+	}
+	if schema, ok := response["schema"]; ok {
+		out["content"] = mediaTypes(produces, convertSchema(schema))
+	}
+	if headers := object(response["headers"]); len(headers) > 0 {
+		converted := map[string]interface{}{}
+		for name, header := range headers {
+			h := object(header)
+			c := map[string]interface{}{"schema": convertSchema(parameterSchema(h))}
+			if description, ok := h["description"]; ok {
+				c["description"] = description
+			}
+			converted[name] = c
+		}
+		out["headers"] = converted
+	}
+	return out
+}
+
+func convertSecurityScheme(definition map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	if description, ok := definition["description"]; ok {
+		out["description"] = description
+	}
+	switch definition["type"] {
+	case "basic":
+		out["type"] = "http"
+		out["scheme"] = "basic"
+	case "apiKey":
+		out["type"] = "apiKey"
+		out["name"] = definition["name"]
+		out["in"] = definition["in"]
+	case "oauth2":
+		flow := map[string]interface{}{"scopes": definition["scopes"]}
+		if flow["scopes"] == nil {
+			flow["scopes"] = map[string]interface{}{}
+		}
+		for _, key := range []string{"authorizationUrl", "tokenUrl"} {
+			if value, ok := definition[key]; ok {
+				flow[key] = value
+			}
+		}
+		name, _ := definition["flow"].(string)
+		switch name {
+		case "application":
+			name = "clientCredentials"
+		case "accessCode":
+			name = "authorizationCode"
+		}
+		out["type"] = "oauth2"
+		out["flows"] = map[string]interface{}{name: flow}
+	default:
+		return definition
+	}
+	return out
+}
+
+// convertSchema rewrites a Swagger 2.0 schema into JSON Schema as OpenAPI 3.1 uses it
+func convertSchema(value interface{}) map[string]interface{} {
+	schema := object(value)
+	out := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		switch key {
+		case "$ref":
+			ref, _ := value.(string)
+			out[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+		case "properties", "patternProperties":
+			properties := map[string]interface{}{}
+			for name, property := range object(value) {
+				properties[name] = convertSchema(property)
+			}
+			out[key] = properties
+		case "items", "not":
+			out[key] = convertSchema(value)
+		case "additionalProperties":
+			if allowed, ok := value.(bool); ok {
+				out[key] = allowed
+			} else {
+				out[key] = convertSchema(value)
+			}
+		case "allOf", "anyOf", "oneOf":
+			var schemas []interface{}
+			for _, s := range list(value) {
+				schemas = append(schemas, convertSchema(s))
+			}
+			out[key] = schemas
+		case "example":
+			out["examples"] = []interface{}{value}
+		case "x-nullable", "discriminator":
+		default:
+			out[key] = value
+		}
+	}
+
+	if out["type"] == "file" {
+		out["type"] = "string"
+		out["contentMediaType"] = "application/octet-stream"
+	}
+	if nullable, _ := schema["x-nullable"].(bool); nullable {
+		if ref, ok := out["$ref"]; ok {
+			delete(out, "$ref")
+			out["anyOf"] = []interface{}{map[string]interface{}{"$ref": ref}, map[string]interface{}{"type": "null"}}
+		} else if t, ok := out["type"].(string); ok {
+			out["type"] = []interface{}{t, "null"}
+		}
+	}
+	if name, ok := schema["discriminator"].(string); ok {
+		out["discriminator"] = map[string]interface{}{"propertyName": name}
+	}
+	return out
+}
+
+func parameterSchema(p map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{}
+	for _, key := range parameterSchemaKeys {
+		if value, ok := p[key]; ok {
+			schema[key] = value
+		}
+	}
+	return schema
+}
+
+func mediaTypes(types []string, schema map[string]interface{}) map[string]interface{} {
+	content := map[string]interface{}{}
+	for _, mediaType := range types {
+		content[mediaType] = map[string]interface{}{"schema": schema}
+	}
+	return content
+}
+
+func object(value interface{}) map[string]interface{} {
+	m, _ := value.(map[string]interface{})
+	return m
+}
+
+func list(value interface{}) []interface{} {
+	l, _ := value.([]interface{})
+	return l
+}
+
+// stringList returns value as a list of strings, or fallback when it holds none
+func stringList(value interface{}, fallback ...string) []string {
+	var strs []string
+	for _, v := range list(value) {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	if len(strs) == 0 {
+		return fallback
+	}
+	return strs
+}
@@ -0,0 +1,12 @@
+package openapi
+
+import (
+	"sync"
+
+	"openvdo/docs"
+)
+
+// Document returns the API description generated into docs, converted to OpenAPI 3.1 once
+var Document = sync.OnceValues(func() ([]byte, error) {
+	return Convert([]byte(docs.SwaggerInfo.ReadDoc()))
+})
@@ -0,0 +1,35 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+
+	"openvdo/internal/database"
+)
+
+// CountInvalidator forgets the cached row counts events change, including for rows written by
+// workers rather than API handlers
+type CountInvalidator struct {
+	counts database.CountProvider
+}
+
+// NewCountInvalidator creates a publisher invalidating counts of the provider
+func NewCountInvalidator(counts database.CountProvider) *CountInvalidator {
+	return &CountInvalidator{counts: counts}
+}
+
+// Name implements Publisher
+func (ci *CountInvalidator) Name() string {
+	return "counts"
+}
+
+// Publish implements Publisher
+func (ci *CountInvalidator) Publish(ctx context.Context, _ *sql.Tx, e *Event) error {
+	switch {
+	case e.Type == EventVideoCreated:
+		return ci.counts.Invalidate(ctx, database.VideoCount(e.OrganizationID))
+	case e.Type == EventMemberAdded && e.SubjectID != nil:
+		return ci.counts.Invalidate(ctx, database.OrganizationCount(*e.SubjectID))
+	}
+	return nil
+}
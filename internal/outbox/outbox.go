@@ -0,0 +1,146 @@
+// Package outbox is a transactional outbox for domain events. Events are rows written in the
+// transaction of the change they describe, so an event exists exactly when its change was
+// committed, and a relay publishes them afterwards, at least once, even across crashes.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"openvdo/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	EventVideoCreated        = "video.created"
+	EventVideoReady          = "video.ready"
+	EventVideoUpdated        = "video.updated"
+	EventVideoSourceReplaced = "video.source_replaced"
+	// EventVideoSourceRolledBack is written when a video goes back to an earlier source
+	EventVideoSourceRolledBack = "video.source_rolled_back"
+	EventVideoModerated        = "video.moderated"
+	EventVideoRenditions       = "video.renditions_registered"
+	EventVideoApproved         = "video.approved"
+	EventVideoRejected         = "video.rejected"
+	EventMemberAdded           = "member.added"
+	EventEntitlementGranted    = "entitlement.granted"
+	EventEntitlementRevoked    = "entitlement.revoked"
+)
+
+// EventTypes lists every event type, e.g. for validating webhook subscriptions
+var EventTypes = []string{
+	EventVideoCreated,
+	EventVideoReady,
+	EventVideoUpdated,
+	EventVideoSourceReplaced,
+	EventVideoSourceRolledBack,
+	EventVideoModerated,
+	EventVideoRenditions,
+	EventVideoApproved,
+	EventVideoRejected,
+	EventMemberAdded,
+	EventEntitlementGranted,
+	EventEntitlementRevoked,
+}
+
+// IsEventType reports whether t is a known event type
+func IsEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a domain event as stored in the outbox and published. Sequence increases with
+// every event, so consumers can order events and drop ones they already processed.
+type Event struct {
+	ID             uuid.UUID       `json:"id"`
+	Sequence       int64           `json:"sequence"`
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	Type           string          `json:"type"`
+	SubjectID      *uuid.UUID      `json:"subject_id,omitempty"`
+	Data           json.RawMessage `json:"data"`
+	CreatedAt      time.Time       `json:"created_at"`
+	// Attempts counts the relay's tries to publish the event
+	Attempts int `json:"-"`
+}
+
+// NewEvent describes an event to write
+type NewEvent struct {
+	OrganizationID uuid.UUID
+	Type           string
+	// SubjectID is the resource the event is about, such as a video
+	SubjectID *uuid.UUID
+	// Data is marshaled to JSON, typically the resource after the change
+	Data interface{}
+}
+
+// Write adds an event to the outbox. It must be given the transaction of the change the event
+// describes; through a tenant connection OrganizationID must be one of the caller's.
+func Write(ctx context.Context, q database.Querier, e NewEvent) error {
+	data, err := e.encode()
+	if err != nil {
+		return err
+	}
+
+	// No RETURNING, since tenants may write to the outbox but not read it
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO outbox_events (organization_id, type, subject_id, data)
+		VALUES ($1, $2, $3, $4)
+	`, e.OrganizationID, e.Type, e.SubjectID, data); err != nil {
+		return fmt.Errorf("failed to write %s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+// WriteAll adds events to the outbox like Write, with a few statements for all of them. The
+// events get increasing sequence numbers in the order given.
+func WriteAll(ctx context.Context, q database.Querier, events []NewEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	insert := database.NewBulkInsert("outbox_events", "organization_id", "type", "subject_id", "data")
+	for _, e := range events {
+		data, err := e.encode()
+		if err != nil {
+			return err
+		}
+		insert.Add(e.OrganizationID, e.Type, e.SubjectID, data)
+	}
+	if _, err := insert.Exec(ctx, q); err != nil {
+		return fmt.Errorf("failed to write %d events: %w", len(events), err)
+	}
+	return nil
+}
+
+func (e NewEvent) encode() ([]byte, error) {
+	if e.Data == nil {
+		return []byte("{}"), nil
+	}
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event: %w", e.Type, err)
+	}
+	return data, nil
+}
+
+// Columns is the column list matching Scan
+const Columns = `id, sequence, organization_id, type, subject_id, data, created_at, attempts`
+
+// Scan scans a row selected with Columns
+func Scan(row interface{ Scan(...interface{}) error }) (*Event, error) {
+	var e Event
+	var data []byte
+	if err := row.Scan(&e.ID, &e.Sequence, &e.OrganizationID, &e.Type, &e.SubjectID, &data, &e.CreatedAt,
+		&e.Attempts); err != nil {
+		return nil, err
+	}
+	e.Data = data
+	return &e, nil
+}
@@ -0,0 +1,36 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisPublisher publishes every event as JSON on a Redis pub/sub channel. Pub/sub does not
+// keep messages for subscribers that are not connected; consumers that must not miss events
+// should use webhooks.
+type RedisPublisher struct {
+	client  *redis.Client
+	channel string
+}
+
+// NewRedisPublisher creates a publisher for the channel
+func NewRedisPublisher(client *redis.Client, channel string) *RedisPublisher {
+	return &RedisPublisher{client: client, channel: channel}
+}
+
+// Name implements Publisher
+func (p *RedisPublisher) Name() string {
+	return "redis"
+}
+
+// Publish implements Publisher
+func (p *RedisPublisher) Publish(ctx context.Context, _ *sql.Tx, e *Event) error {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return p.client.Publish(ctx, p.channel, msg).Err()
+}
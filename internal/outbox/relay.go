@@ -0,0 +1,190 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/leader"
+	"openvdo/pkg/logger"
+)
+
+// Publisher delivers events to one destination. tx is the relay's transaction, which marks the
+// event published when it commits; publishers that hand events to Postgres, such as by
+// enqueueing jobs, use it so the handoff happens exactly once.
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, tx *sql.Tx, e *Event) error
+}
+
+// Relay publishes outbox events in sequence order. Several relays may run at once; each
+// claims its batch with SKIP LOCKED.
+type Relay struct {
+	db         *sql.DB
+	config     config.Events
+	leader     *leader.Elector
+	publishers []Publisher
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRelay creates a relay. The master connection is used because events of every
+// organization are published. Published events past their retention are deleted by the
+// leader's relay only.
+func NewRelay(db *sql.DB, cfg config.Events, elector *leader.Elector) *Relay {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Relay{db: db, config: cfg, leader: elector, ctx: ctx, cancel: cancel}
+}
+
+// Register adds a publisher. Publishers run in the order they were registered, and those that
+// only use the transaction should come first: when a later one fails, the earlier ones'
+// writes are rolled back, while what they sent elsewhere would be sent again.
+func (r *Relay) Register(p Publisher) {
+	r.publishers = append(r.publishers, p)
+}
+
+// Start publishes pending events until Stop is called
+func (r *Relay) Start() {
+	r.wg.Add(1)
+	go r.run()
+	logger.Info("Outbox relay started with %d publishers (interval %v)", len(r.publishers), r.config.RelayInterval)
+}
+
+// Stop waits for the batch being published and stops the relay
+func (r *Relay) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Relay) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.config.RelayInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Time{}
+
+	for {
+		for r.ctx.Err() == nil {
+			n, err := r.publishBatch(r.ctx)
+			if err != nil {
+				if r.ctx.Err() == nil {
+					logger.Error("Failed to publish outbox events: %v", err)
+				}
+				break
+			}
+			if n < r.config.BatchSize {
+				break
+			}
+		}
+
+		if r.config.Retention > 0 && time.Since(lastCleanup) > time.Hour && r.leader.IsLeader() {
+			lastCleanup = time.Now()
+			r.deletePublished(r.ctx)
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishBatch publishes one batch of due events and returns how many it claimed. Each event
+// runs under a savepoint, so a failing event is rescheduled without undoing the others.
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+Columns+` FROM outbox_events
+		WHERE published_at IS NULL AND next_attempt_at <= NOW()
+		ORDER BY sequence
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, r.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	var events []*Event
+	for rows.Next() {
+		e, err := Scan(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	for _, e := range events {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT publish_event`); err != nil {
+			return 0, err
+		}
+
+		if publishErr := r.publish(ctx, tx, e); publishErr != nil {
+			if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT publish_event`); rbErr != nil {
+				return 0, rbErr
+			}
+			delay := r.config.RetryDelay * time.Duration(1<<min(e.Attempts, 10))
+			logger.Error("Failed to publish event %s (%s), retrying in %v: %v", e.ID, e.Type, delay, publishErr)
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE outbox_events SET attempts = attempts + 1, last_error = $2,
+					next_attempt_at = NOW() + make_interval(secs => $3)
+				WHERE id = $1
+			`, e.ID, publishErr.Error(), delay.Seconds()); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE outbox_events SET attempts = attempts + 1, last_error = NULL, published_at = NOW()
+			WHERE id = $1
+		`, e.ID); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT publish_event`); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), tx.Commit()
+}
+
+func (r *Relay) publish(ctx context.Context, tx *sql.Tx, e *Event) error {
+	for _, p := range r.publishers {
+		if err := p.Publish(ctx, tx, e); err != nil {
+			return fmt.Errorf("%s: %w", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+// deletePublished removes published events past their retention
+func (r *Relay) deletePublished(ctx context.Context) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM outbox_events WHERE published_at < NOW() - make_interval(secs => $1)`, r.config.Retention.Seconds())
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error("Failed to delete published outbox events: %v", err)
+		}
+		return
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		logger.Info("Deleted %d published outbox events", n)
+	}
+}
@@ -0,0 +1,77 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+
+package queries
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"openvdo/internal/models"
+)
+
+type Organization struct {
+	ID               uuid.UUID       `db:"id"`
+	Name             string          `db:"name"`
+	Description      *string         `db:"description"`
+	Settings         json.RawMessage `db:"settings"`
+	CreatedAt        time.Time       `db:"created_at"`
+	UpdatedAt        time.Time       `db:"updated_at"`
+	Version          int64           `db:"version"`
+	PlaybackDomains  []string        `db:"playback_domains"`
+	Banner           *models.Image   `db:"banner"`
+	Region           *string         `db:"region"`
+	VASTTagURL       *string         `db:"vast_tag_url"`
+	EmbedDomains     []string        `db:"embed_domains"`
+	Transcoder       *string         `db:"transcoder"`
+	EgressLimit      *int64          `db:"egress_limit"`
+	DeletedAt        *time.Time      `db:"deleted_at"`
+	TranscodeProfile *string         `db:"transcode_profile"`
+	RequireReview    bool            `db:"require_review"`
+	ExternalID       *string         `db:"external_id"`
+}
+
+type UserOrgRole struct {
+	ID             uuid.UUID  `db:"id"`
+	UserID         uuid.UUID  `db:"user_id"`
+	OrganizationID uuid.UUID  `db:"organization_id"`
+	Role           string     `db:"role"`
+	InvitedBy      *uuid.UUID `db:"invited_by"`
+	CreatedAt      time.Time  `db:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+type Video struct {
+	ID                  uuid.UUID                `db:"id"`
+	OrganizationID      uuid.UUID                `db:"organization_id"`
+	ProjectID           *uuid.UUID               `db:"project_id"`
+	Title               string                   `db:"title"`
+	Description         *string                  `db:"description"`
+	Status              string                   `db:"status"`
+	SourceKey           *string                  `db:"source_key"`
+	ContentType         *string                  `db:"content_type"`
+	SizeBytes           *int64                   `db:"size_bytes"`
+	CreatedBy           *uuid.UUID               `db:"created_by"`
+	CreatedAt           *time.Time               `db:"created_at"`
+	UpdatedAt           *time.Time               `db:"updated_at"`
+	SHA256              *string                  `db:"sha256"`
+	DuplicateOf         *uuid.UUID               `db:"duplicate_of"`
+	Version             int64                    `db:"version"`
+	Visibility          string                   `db:"visibility"`
+	Tags                []string                 `db:"tags"`
+	SourceRevision      int                      `db:"source_revision"`
+	ReplacedAt          *time.Time               `db:"replaced_at"`
+	Thumbnail           *models.Image            `db:"thumbnail"`
+	ModerationStatus    string                   `db:"moderation_status"`
+	Chapters            models.Chapters          `db:"chapters"`
+	AdBreaks            models.AdBreaks          `db:"ad_breaks"`
+	RequiresEntitlement bool                     `db:"requires_entitlement"`
+	StatusChangedAt     time.Time                `db:"status_changed_at"`
+	Color               *models.ColorInfo        `db:"color"`
+	DownloadsEnabled    bool                     `db:"downloads_enabled"`
+	ReviewStatus        string                   `db:"review_status"`
+	CustomFields        models.CustomFieldValues `db:"custom_fields"`
+	ExternalID          *string                  `db:"external_id"`
+}
@@ -0,0 +1,97 @@
+-- name: GetOrganization :one
+SELECT * FROM organizations WHERE id = $1;
+
+-- name: ListOrganizations :many
+SELECT * FROM organizations ORDER BY created_at DESC;
+
+-- name: ListOrganizationsPage :many
+SELECT * FROM organizations ORDER BY created_at DESC LIMIT $1 OFFSET $2;
+
+-- name: CountOrganizations :one
+SELECT COUNT(*) FROM organizations;
+
+-- name: FindOrganization :one
+SELECT id FROM organizations WHERE id::text = sqlc.arg(organization)::text OR name = sqlc.arg(organization)::text;
+
+-- name: GetOrganizationName :one
+SELECT name FROM organizations WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: GetOrganizationRegion :one
+SELECT region FROM organizations WHERE id = $1;
+
+-- name: GetOrganizationTranscoder :one
+SELECT transcoder, transcode_profile FROM organizations WHERE id = $1;
+
+-- name: GetEgressLimit :one
+SELECT egress_limit FROM organizations WHERE id = $1;
+
+-- name: GetVASTTagURL :one
+SELECT vast_tag_url FROM organizations WHERE id = $1;
+
+-- name: GetEmbedDomains :one
+SELECT embed_domains FROM organizations WHERE id = $1;
+
+-- name: ListPlaybackDomains :many
+SELECT DISTINCT unnest(playback_domains)::text AS origin FROM organizations;
+
+-- name: RequiresReview :one
+SELECT require_review FROM organizations WHERE id = $1;
+
+-- name: GetIPAccessRules :one
+SELECT (settings->sqlc.arg(key)::text)::jsonb AS rules FROM organizations WHERE id = sqlc.arg(id);
+
+-- name: GetBanner :one
+SELECT banner FROM organizations WHERE id = $1;
+
+-- name: LockBanner :one
+SELECT banner FROM organizations WHERE id = $1 FOR UPDATE;
+
+-- name: CreateOrganization :exec
+INSERT INTO organizations (name, description) VALUES ($1, $2);
+
+-- name: InsertOrganization :one
+INSERT INTO organizations (name, description, external_id)
+VALUES ($1, $2, $3)
+RETURNING id, created_at, updated_at;
+
+-- name: CreateNamedOrganization :one
+INSERT INTO organizations (name) VALUES ($1) RETURNING id;
+
+-- name: UpdateOrganization :one
+UPDATE organizations
+SET name = COALESCE(sqlc.narg(name), name), description = COALESCE(sqlc.narg(description), description),
+    settings = CASE
+        WHEN sqlc.narg(settings)::text IS NULL THEN settings
+        WHEN settings ? 'ip_access' THEN (sqlc.narg(settings)::text::jsonb - 'ip_access') || jsonb_build_object('ip_access', settings->'ip_access')
+        ELSE sqlc.narg(settings)::text::jsonb - 'ip_access'
+    END,
+    playback_domains = COALESCE(sqlc.narg(playback_domains)::text[], playback_domains),
+    vast_tag_url = CASE WHEN sqlc.narg(vast_tag_url)::text IS NULL THEN vast_tag_url ELSE NULLIF(sqlc.narg(vast_tag_url)::text, '') END,
+    embed_domains = COALESCE(sqlc.narg(embed_domains)::text[], embed_domains),
+    require_review = COALESCE(sqlc.narg(require_review), require_review),
+    external_id = CASE WHEN sqlc.narg(external_id)::text IS NULL THEN external_id ELSE NULLIF(sqlc.narg(external_id)::text, '') END,
+    version = version + 1
+WHERE id = sqlc.arg(id) AND version = sqlc.arg(version)
+RETURNING *;
+
+-- name: SetIPAccessRules :execrows
+UPDATE organizations
+SET settings = jsonb_set(COALESCE(settings, '{}'), ARRAY[sqlc.arg(key)::text], sqlc.arg(rules)::jsonb), version = version + 1
+WHERE id = sqlc.arg(id);
+
+-- name: SetBanner :one
+UPDATE organizations SET banner = $1, version = version + 1 WHERE id = $2
+RETURNING *;
+
+-- name: SetEgressLimit :execrows
+UPDATE organizations SET egress_limit = $2 WHERE id = $1;
+
+-- name: SetRegion :exec
+UPDATE organizations SET region = $2 WHERE id = $1;
+
+-- name: SetTranscoder :execrows
+UPDATE organizations SET transcoder = NULLIF(sqlc.arg(transcoder)::text, ''), transcode_profile = NULLIF(sqlc.arg(transcode_profile)::text, '')
+WHERE id = sqlc.arg(id);
+
+-- name: MarkOrganizationDeleted :exec
+UPDATE organizations SET deleted_at = NOW() WHERE id = $1;
@@ -0,0 +1,545 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: organizations.sql
+
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"openvdo/internal/models"
+)
+
+const CountOrganizations = `-- name: CountOrganizations :one
+SELECT COUNT(*) FROM organizations
+`
+
+func (q *Queries) CountOrganizations(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, CountOrganizations)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateNamedOrganization = `-- name: CreateNamedOrganization :one
+INSERT INTO organizations (name) VALUES ($1) RETURNING id
+`
+
+func (q *Queries) CreateNamedOrganization(ctx context.Context, name string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, CreateNamedOrganization, name)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const CreateOrganization = `-- name: CreateOrganization :exec
+INSERT INTO organizations (name, description) VALUES ($1, $2)
+`
+
+type CreateOrganizationParams struct {
+	Name        string  `db:"name"`
+	Description *string `db:"description"`
+}
+
+func (q *Queries) CreateOrganization(ctx context.Context, arg CreateOrganizationParams) error {
+	_, err := q.db.ExecContext(ctx, CreateOrganization, arg.Name, arg.Description)
+	return err
+}
+
+const FindOrganization = `-- name: FindOrganization :one
+SELECT id FROM organizations WHERE id::text = $1::text OR name = $1::text
+`
+
+func (q *Queries) FindOrganization(ctx context.Context, organization string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, FindOrganization, organization)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const GetBanner = `-- name: GetBanner :one
+SELECT banner FROM organizations WHERE id = $1
+`
+
+func (q *Queries) GetBanner(ctx context.Context, id uuid.UUID) (*models.Image, error) {
+	row := q.db.QueryRowContext(ctx, GetBanner, id)
+	var banner *models.Image
+	err := row.Scan(&banner)
+	return banner, err
+}
+
+const GetEgressLimit = `-- name: GetEgressLimit :one
+SELECT egress_limit FROM organizations WHERE id = $1
+`
+
+func (q *Queries) GetEgressLimit(ctx context.Context, id uuid.UUID) (*int64, error) {
+	row := q.db.QueryRowContext(ctx, GetEgressLimit, id)
+	var egress_limit *int64
+	err := row.Scan(&egress_limit)
+	return egress_limit, err
+}
+
+const GetEmbedDomains = `-- name: GetEmbedDomains :one
+SELECT embed_domains FROM organizations WHERE id = $1
+`
+
+func (q *Queries) GetEmbedDomains(ctx context.Context, id uuid.UUID) ([]string, error) {
+	row := q.db.QueryRowContext(ctx, GetEmbedDomains, id)
+	var embed_domains []string
+	err := row.Scan(pq.Array(&embed_domains))
+	return embed_domains, err
+}
+
+const GetIPAccessRules = `-- name: GetIPAccessRules :one
+SELECT (settings->$1::text)::jsonb AS rules FROM organizations WHERE id = $2
+`
+
+type GetIPAccessRulesParams struct {
+	Key string    `db:"key"`
+	ID  uuid.UUID `db:"id"`
+}
+
+func (q *Queries) GetIPAccessRules(ctx context.Context, arg GetIPAccessRulesParams) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, GetIPAccessRules, arg.Key, arg.ID)
+	var rules json.RawMessage
+	err := row.Scan(&rules)
+	return rules, err
+}
+
+const GetOrganization = `-- name: GetOrganization :one
+SELECT id, name, description, settings, created_at, updated_at, version, playback_domains, banner, region, vast_tag_url, embed_domains, transcoder, egress_limit, deleted_at, transcode_profile, require_review, external_id FROM organizations WHERE id = $1
+`
+
+func (q *Queries) GetOrganization(ctx context.Context, id uuid.UUID) (Organization, error) {
+	row := q.db.QueryRowContext(ctx, GetOrganization, id)
+	var i Organization
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Settings,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		pq.Array(&i.PlaybackDomains),
+		&i.Banner,
+		&i.Region,
+		&i.VASTTagURL,
+		pq.Array(&i.EmbedDomains),
+		&i.Transcoder,
+		&i.EgressLimit,
+		&i.DeletedAt,
+		&i.TranscodeProfile,
+		&i.RequireReview,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const GetOrganizationName = `-- name: GetOrganizationName :one
+SELECT name FROM organizations WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetOrganizationName(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, GetOrganizationName, id)
+	var name string
+	err := row.Scan(&name)
+	return name, err
+}
+
+const GetOrganizationRegion = `-- name: GetOrganizationRegion :one
+SELECT region FROM organizations WHERE id = $1
+`
+
+func (q *Queries) GetOrganizationRegion(ctx context.Context, id uuid.UUID) (*string, error) {
+	row := q.db.QueryRowContext(ctx, GetOrganizationRegion, id)
+	var region *string
+	err := row.Scan(&region)
+	return region, err
+}
+
+const GetOrganizationTranscoder = `-- name: GetOrganizationTranscoder :one
+SELECT transcoder, transcode_profile FROM organizations WHERE id = $1
+`
+
+type GetOrganizationTranscoderRow struct {
+	Transcoder       *string `db:"transcoder"`
+	TranscodeProfile *string `db:"transcode_profile"`
+}
+
+func (q *Queries) GetOrganizationTranscoder(ctx context.Context, id uuid.UUID) (GetOrganizationTranscoderRow, error) {
+	row := q.db.QueryRowContext(ctx, GetOrganizationTranscoder, id)
+	var i GetOrganizationTranscoderRow
+	err := row.Scan(&i.Transcoder, &i.TranscodeProfile)
+	return i, err
+}
+
+const GetVASTTagURL = `-- name: GetVASTTagURL :one
+SELECT vast_tag_url FROM organizations WHERE id = $1
+`
+
+func (q *Queries) GetVASTTagURL(ctx context.Context, id uuid.UUID) (*string, error) {
+	row := q.db.QueryRowContext(ctx, GetVASTTagURL, id)
+	var vast_tag_url *string
+	err := row.Scan(&vast_tag_url)
+	return vast_tag_url, err
+}
+
+const InsertOrganization = `-- name: InsertOrganization :one
+INSERT INTO organizations (name, description, external_id)
+VALUES ($1, $2, $3)
+RETURNING id, created_at, updated_at
+`
+
+type InsertOrganizationParams struct {
+	Name        string  `db:"name"`
+	Description *string `db:"description"`
+	ExternalID  *string `db:"external_id"`
+}
+
+type InsertOrganizationRow struct {
+	ID        uuid.UUID `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (q *Queries) InsertOrganization(ctx context.Context, arg InsertOrganizationParams) (InsertOrganizationRow, error) {
+	row := q.db.QueryRowContext(ctx, InsertOrganization, arg.Name, arg.Description, arg.ExternalID)
+	var i InsertOrganizationRow
+	err := row.Scan(&i.ID, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
+const ListOrganizations = `-- name: ListOrganizations :many
+SELECT id, name, description, settings, created_at, updated_at, version, playback_domains, banner, region, vast_tag_url, embed_domains, transcoder, egress_limit, deleted_at, transcode_profile, require_review, external_id FROM organizations ORDER BY created_at DESC
+`
+
+func (q *Queries) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	rows, err := q.db.QueryContext(ctx, ListOrganizations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Organization
+	for rows.Next() {
+		var i Organization
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Settings,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			pq.Array(&i.PlaybackDomains),
+			&i.Banner,
+			&i.Region,
+			&i.VASTTagURL,
+			pq.Array(&i.EmbedDomains),
+			&i.Transcoder,
+			&i.EgressLimit,
+			&i.DeletedAt,
+			&i.TranscodeProfile,
+			&i.RequireReview,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListOrganizationsPage = `-- name: ListOrganizationsPage :many
+SELECT id, name, description, settings, created_at, updated_at, version, playback_domains, banner, region, vast_tag_url, embed_domains, transcoder, egress_limit, deleted_at, transcode_profile, require_review, external_id FROM organizations ORDER BY created_at DESC LIMIT $1 OFFSET $2
+`
+
+type ListOrganizationsPageParams struct {
+	Limit  int32 `db:"limit"`
+	Offset int32 `db:"offset"`
+}
+
+func (q *Queries) ListOrganizationsPage(ctx context.Context, arg ListOrganizationsPageParams) ([]Organization, error) {
+	rows, err := q.db.QueryContext(ctx, ListOrganizationsPage, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Organization
+	for rows.Next() {
+		var i Organization
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Settings,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+			pq.Array(&i.PlaybackDomains),
+			&i.Banner,
+			&i.Region,
+			&i.VASTTagURL,
+			pq.Array(&i.EmbedDomains),
+			&i.Transcoder,
+			&i.EgressLimit,
+			&i.DeletedAt,
+			&i.TranscodeProfile,
+			&i.RequireReview,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListPlaybackDomains = `-- name: ListPlaybackDomains :many
+SELECT DISTINCT unnest(playback_domains)::text AS origin FROM organizations
+`
+
+func (q *Queries) ListPlaybackDomains(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, ListPlaybackDomains)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var origin string
+		if err := rows.Scan(&origin); err != nil {
+			return nil, err
+		}
+		items = append(items, origin)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockBanner = `-- name: LockBanner :one
+SELECT banner FROM organizations WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockBanner(ctx context.Context, id uuid.UUID) (*models.Image, error) {
+	row := q.db.QueryRowContext(ctx, LockBanner, id)
+	var banner *models.Image
+	err := row.Scan(&banner)
+	return banner, err
+}
+
+const MarkOrganizationDeleted = `-- name: MarkOrganizationDeleted :exec
+UPDATE organizations SET deleted_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) MarkOrganizationDeleted(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, MarkOrganizationDeleted, id)
+	return err
+}
+
+const RequiresReview = `-- name: RequiresReview :one
+SELECT require_review FROM organizations WHERE id = $1
+`
+
+func (q *Queries) RequiresReview(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, RequiresReview, id)
+	var require_review bool
+	err := row.Scan(&require_review)
+	return require_review, err
+}
+
+const SetBanner = `-- name: SetBanner :one
+UPDATE organizations SET banner = $1, version = version + 1 WHERE id = $2
+RETURNING id, name, description, settings, created_at, updated_at, version, playback_domains, banner, region, vast_tag_url, embed_domains, transcoder, egress_limit, deleted_at, transcode_profile, require_review, external_id
+`
+
+type SetBannerParams struct {
+	Banner *models.Image `db:"banner"`
+	ID     uuid.UUID     `db:"id"`
+}
+
+func (q *Queries) SetBanner(ctx context.Context, arg SetBannerParams) (Organization, error) {
+	row := q.db.QueryRowContext(ctx, SetBanner, arg.Banner, arg.ID)
+	var i Organization
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Settings,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		pq.Array(&i.PlaybackDomains),
+		&i.Banner,
+		&i.Region,
+		&i.VASTTagURL,
+		pq.Array(&i.EmbedDomains),
+		&i.Transcoder,
+		&i.EgressLimit,
+		&i.DeletedAt,
+		&i.TranscodeProfile,
+		&i.RequireReview,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const SetEgressLimit = `-- name: SetEgressLimit :execrows
+UPDATE organizations SET egress_limit = $2 WHERE id = $1
+`
+
+type SetEgressLimitParams struct {
+	ID          uuid.UUID `db:"id"`
+	EgressLimit *int64    `db:"egress_limit"`
+}
+
+func (q *Queries) SetEgressLimit(ctx context.Context, arg SetEgressLimitParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, SetEgressLimit, arg.ID, arg.EgressLimit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const SetIPAccessRules = `-- name: SetIPAccessRules :execrows
+UPDATE organizations
+SET settings = jsonb_set(COALESCE(settings, '{}'), ARRAY[$1::text], $2::jsonb), version = version + 1
+WHERE id = $3
+`
+
+type SetIPAccessRulesParams struct {
+	Key   string          `db:"key"`
+	Rules json.RawMessage `db:"rules"`
+	ID    uuid.UUID       `db:"id"`
+}
+
+func (q *Queries) SetIPAccessRules(ctx context.Context, arg SetIPAccessRulesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, SetIPAccessRules, arg.Key, arg.Rules, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const SetRegion = `-- name: SetRegion :exec
+UPDATE organizations SET region = $2 WHERE id = $1
+`
+
+type SetRegionParams struct {
+	ID     uuid.UUID `db:"id"`
+	Region *string   `db:"region"`
+}
+
+func (q *Queries) SetRegion(ctx context.Context, arg SetRegionParams) error {
+	_, err := q.db.ExecContext(ctx, SetRegion, arg.ID, arg.Region)
+	return err
+}
+
+const SetTranscoder = `-- name: SetTranscoder :execrows
+UPDATE organizations SET transcoder = NULLIF($1::text, ''), transcode_profile = NULLIF($2::text, '')
+WHERE id = $3
+`
+
+type SetTranscoderParams struct {
+	Transcoder       string    `db:"transcoder"`
+	TranscodeProfile string    `db:"transcode_profile"`
+	ID               uuid.UUID `db:"id"`
+}
+
+func (q *Queries) SetTranscoder(ctx context.Context, arg SetTranscoderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, SetTranscoder, arg.Transcoder, arg.TranscodeProfile, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const UpdateOrganization = `-- name: UpdateOrganization :one
+UPDATE organizations
+SET name = COALESCE($1, name), description = COALESCE($2, description),
+    settings = CASE
+        WHEN $3::text IS NULL THEN settings
+        WHEN settings ? 'ip_access' THEN ($3::text::jsonb - 'ip_access') || jsonb_build_object('ip_access', settings->'ip_access')
+        ELSE $3::text::jsonb - 'ip_access'
+    END,
+    playback_domains = COALESCE($4::text[], playback_domains),
+    vast_tag_url = CASE WHEN $5::text IS NULL THEN vast_tag_url ELSE NULLIF($5::text, '') END,
+    embed_domains = COALESCE($6::text[], embed_domains),
+    require_review = COALESCE($7, require_review),
+    external_id = CASE WHEN $8::text IS NULL THEN external_id ELSE NULLIF($8::text, '') END,
+    version = version + 1
+WHERE id = $9 AND version = $10
+RETURNING id, name, description, settings, created_at, updated_at, version, playback_domains, banner, region, vast_tag_url, embed_domains, transcoder, egress_limit, deleted_at, transcode_profile, require_review, external_id
+`
+
+type UpdateOrganizationParams struct {
+	Name            *string   `db:"name"`
+	Description     *string   `db:"description"`
+	Settings        *string   `db:"settings"`
+	PlaybackDomains []string  `db:"playback_domains"`
+	VASTTagURL      *string   `db:"vast_tag_url"`
+	EmbedDomains    []string  `db:"embed_domains"`
+	RequireReview   *bool     `db:"require_review"`
+	ExternalID      *string   `db:"external_id"`
+	ID              uuid.UUID `db:"id"`
+	Version         int64     `db:"version"`
+}
+
+func (q *Queries) UpdateOrganization(ctx context.Context, arg UpdateOrganizationParams) (Organization, error) {
+	row := q.db.QueryRowContext(ctx, UpdateOrganization,
+		arg.Name,
+		arg.Description,
+		arg.Settings,
+		pq.Array(arg.PlaybackDomains),
+		arg.VASTTagURL,
+		pq.Array(arg.EmbedDomains),
+		arg.RequireReview,
+		arg.ExternalID,
+		arg.ID,
+		arg.Version,
+	)
+	var i Organization
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.Settings,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Version,
+		pq.Array(&i.PlaybackDomains),
+		&i.Banner,
+		&i.Region,
+		&i.VASTTagURL,
+		pq.Array(&i.EmbedDomains),
+		&i.Transcoder,
+		&i.EgressLimit,
+		&i.DeletedAt,
+		&i.TranscodeProfile,
+		&i.RequireReview,
+		&i.ExternalID,
+	)
+	return i, err
+}
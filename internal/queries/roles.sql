@@ -0,0 +1,41 @@
+-- name: GetMemberRole :one
+SELECT role FROM user_org_roles WHERE user_id = $1 AND organization_id = $2;
+
+-- name: IsMember :one
+SELECT EXISTS (SELECT 1 FROM user_org_roles WHERE user_id = $1 AND organization_id = $2);
+
+-- name: CountMemberships :one
+SELECT COUNT(*) FROM user_org_roles
+WHERE user_id = sqlc.arg(user_id) AND organization_id = sqlc.arg(organization_id)
+    AND (sqlc.arg(role)::text = '' OR role = sqlc.arg(role)::text);
+
+-- name: FirstMembership :one
+SELECT organization_id, role FROM user_org_roles WHERE user_id = $1 LIMIT 1;
+
+-- name: ListSessionMemberships :many
+SELECT uor.organization_id, uor.role
+FROM user_org_roles uor
+JOIN users u ON u.id = uor.user_id
+WHERE uor.user_id = $1
+ORDER BY uor.organization_id = u.default_organization_id DESC NULLS LAST, uor.created_at DESC;
+
+-- name: ListUserOrganizations :many
+SELECT o.id, o.name, COALESCE(o.description, '')::text AS description, o.created_at, o.updated_at, uor.role
+FROM organizations o
+JOIN user_org_roles uor ON o.id = uor.organization_id
+WHERE uor.user_id = $1
+ORDER BY o.created_at DESC;
+
+-- name: AddMember :one
+INSERT INTO user_org_roles (user_id, organization_id, role, invited_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, organization_id) DO NOTHING
+RETURNING *;
+
+-- name: GrantRole :exec
+INSERT INTO user_org_roles (user_id, organization_id, role)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, organization_id) DO UPDATE SET role = EXCLUDED.role;
+
+-- name: DeleteOrganizationMembers :many
+DELETE FROM user_org_roles WHERE organization_id = $1 RETURNING user_id;
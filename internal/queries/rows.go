@@ -0,0 +1,60 @@
+package queries
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// Columns returns the column list of the table model T, in the order ScanRow reads it, for
+// the statements that build their SQL at run time such as filtered listings. The generated
+// models follow the migrations, so the list follows them too.
+func Columns[T any]() string {
+	return columnsOf(reflect.TypeFor[T]())
+}
+
+var columnLists sync.Map
+
+func columnsOf(t reflect.Type) string {
+	if list, ok := columnLists.Load(t); ok {
+		return list.(string)
+	}
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i] = t.Field(i).Tag.Get("db")
+	}
+	list := strings.Join(names, ", ")
+	columnLists.Store(t, list)
+	return list
+}
+
+// ScanRow scans a row selected with Columns[T] into a T
+func ScanRow[T any](row interface{ Scan(...interface{}) error }) (T, error) {
+	var out T
+	v := reflect.ValueOf(&out).Elem()
+	dest := make([]interface{}, v.NumField())
+	for i := range dest {
+		dest[i] = scanDest(v.Field(i))
+	}
+	err := row.Scan(dest...)
+	return out, err
+}
+
+var (
+	scannerType = reflect.TypeFor[sql.Scanner]()
+	rawType     = reflect.TypeFor[json.RawMessage]()
+)
+
+// scanDest passes a field as the generated Scan calls do, reading arrays through pq.Array
+func scanDest(field reflect.Value) interface{} {
+	addr := field.Addr()
+	t := field.Type()
+	if t.Kind() == reflect.Slice && t != rawType && t.Elem().Kind() != reflect.Uint8 && !addr.Type().Implements(scannerType) {
+		return pq.Array(addr.Interface())
+	}
+	return addr.Interface()
+}
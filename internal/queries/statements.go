@@ -0,0 +1,125 @@
+package queries
+
+//go:generate go run github.com/sqlc-dev/sqlc/cmd/sqlc@v1.30.0 generate -f ../../sqlc.yaml
+
+// Statements are the generated statements by name. The server prepares each of them against
+// the database at startup, so a statement the migrated schema no longer accepts stops it
+// instead of failing requests. Add new queries here; a test checks that none is missing.
+var Statements = map[string]string{
+	// organizations.sql
+	"GetOrganization":           GetOrganization,
+	"ListOrganizations":         ListOrganizations,
+	"ListOrganizationsPage":     ListOrganizationsPage,
+	"CountOrganizations":        CountOrganizations,
+	"FindOrganization":          FindOrganization,
+	"GetOrganizationName":       GetOrganizationName,
+	"GetOrganizationRegion":     GetOrganizationRegion,
+	"GetOrganizationTranscoder": GetOrganizationTranscoder,
+	"GetEgressLimit":            GetEgressLimit,
+	"GetVASTTagURL":             GetVASTTagURL,
+	"GetEmbedDomains":           GetEmbedDomains,
+	"ListPlaybackDomains":       ListPlaybackDomains,
+	"RequiresReview":            RequiresReview,
+	"GetIPAccessRules":          GetIPAccessRules,
+	"GetBanner":                 GetBanner,
+	"LockBanner":                LockBanner,
+	"CreateOrganization":        CreateOrganization,
+	"InsertOrganization":        InsertOrganization,
+	"CreateNamedOrganization":   CreateNamedOrganization,
+	"UpdateOrganization":        UpdateOrganization,
+	"SetIPAccessRules":          SetIPAccessRules,
+	"SetBanner":                 SetBanner,
+	"SetEgressLimit":            SetEgressLimit,
+	"SetRegion":                 SetRegion,
+	"SetTranscoder":             SetTranscoder,
+	"MarkOrganizationDeleted":   MarkOrganizationDeleted,
+	// roles.sql
+	"GetMemberRole":             GetMemberRole,
+	"IsMember":                  IsMember,
+	"CountMemberships":          CountMemberships,
+	"FirstMembership":           FirstMembership,
+	"ListSessionMemberships":    ListSessionMemberships,
+	"ListUserOrganizations":     ListUserOrganizations,
+	"AddMember":                 AddMember,
+	"GrantRole":                 GrantRole,
+	"DeleteOrganizationMembers": DeleteOrganizationMembers,
+	// users.sql
+	"GetUserProfile":           GetUserProfile,
+	"UpdateUserProfile":        UpdateUserProfile,
+	"GetPlaybackPreferences":   GetPlaybackPreferences,
+	"GetUserContact":           GetUserContact,
+	"GetUserEmail":             GetUserEmail,
+	"FindUserByEmail":          FindUserByEmail,
+	"FindUserByEmailFold":      FindUserByEmailFold,
+	"UserExists":               UserExists,
+	"GetInvitationNames":       GetInvitationNames,
+	"CreateUser":               CreateUser,
+	"SignIn":                   SignIn,
+	"SetDefaultOrganization":   SetDefaultOrganization,
+	"ClearDefaultOrganization": ClearDefaultOrganization,
+	"GetAvatar":                GetAvatar,
+	"LockAvatar":               LockAvatar,
+	"SetAvatar":                SetAvatar,
+	// videos.sql
+	"GetVideo":                       GetVideo,
+	"VideoExists":                    VideoExists,
+	"VideoExistsInOrganization":      VideoExistsInOrganization,
+	"CountVideosInOrganization":      CountVideosInOrganization,
+	"GetVideoOwner":                  GetVideoOwner,
+	"GetVideoSource":                 GetVideoSource,
+	"GetVideoImportState":            GetVideoImportState,
+	"GetVideoColorState":             GetVideoColorState,
+	"GetVideoTranscodeSource":        GetVideoTranscodeSource,
+	"GetVideoSourceRevision":         GetVideoSourceRevision,
+	"LockVideoSourceRevision":        LockVideoSourceRevision,
+	"VideoAtRevision":                VideoAtRevision,
+	"LockVideoStatus":                LockVideoStatus,
+	"LockVideoReviewStatus":          LockVideoReviewStatus,
+	"ListUnhashedVideoIDs":           ListUnhashedVideoIDs,
+	"FindDuplicateVideo":             FindDuplicateVideo,
+	"FirstVideoWithSource":           FirstVideoWithSource,
+	"SourceKeyInUse":                 SourceKeyInUse,
+	"ListPublicProjectVideos":        ListPublicProjectVideos,
+	"ListVideoSourcesInOrganization": ListVideoSourcesInOrganization,
+	"SetVideoHash":                   SetVideoHash,
+	"SetVideoDuplicate":              SetVideoDuplicate,
+	"ReuseVideoSource":               ReuseVideoSource,
+	"ClearDuplicatesOf":              ClearDuplicatesOf,
+	"SetVideoImported":               SetVideoImported,
+	"SetVideoColor":                  SetVideoColor,
+	"SetVideoStatus":                 SetVideoStatus,
+	"SetVideoReviewStatus":           SetVideoReviewStatus,
+	"DecideVideoReview":              DecideVideoReview,
+	"ReplaceVideoSource":             ReplaceVideoSource,
+	"RollbackVideoSource":            RollbackVideoSource,
+	"DeleteVideos":                   DeleteVideos,
+	"OrganizationHasContent":         OrganizationHasContent,
+	"SetModerationStatus":            SetModerationStatus,
+	"ChangeModerationStatus":         ChangeModerationStatus,
+	"RestrictReportedVideo":          RestrictReportedVideo,
+	"ListTrendingVideos":             ListTrendingVideos,
+	"GetVideoByExternalID":           GetVideoByExternalID,
+	"GetVideoStatus":                 GetVideoStatus,
+	"UpdateVideo":                    UpdateVideo,
+	"CreateUploadingVideo":           CreateUploadingVideo,
+	"CreateDuplicateVideo":           CreateDuplicateVideo,
+	"CreateImportedVideo":            CreateImportedVideo,
+	"GetVideoTitle":                  GetVideoTitle,
+	"GetVideoVisibility":             GetVideoVisibility,
+	"GetVideoOrganization":           GetVideoOrganization,
+	"ListVideoOrganizations":         ListVideoOrganizations,
+	"GetPlayableVideo":               GetPlayableVideo,
+	"GetSessionVideo":                GetSessionVideo,
+	"LockVideoTags":                  LockVideoTags,
+	"BulkUpdateVideo":                BulkUpdateVideo,
+	"GetAdBreaks":                    GetAdBreaks,
+	"GetVideoAdBreaks":               GetVideoAdBreaks,
+	"SetAdBreaks":                    SetAdBreaks,
+	"GetChapters":                    GetChapters,
+	"SetChapters":                    SetChapters,
+	"LockThumbnail":                  LockThumbnail,
+	"SetThumbnail":                   SetThumbnail,
+	"CustomFieldOptionInUse":         CustomFieldOptionInUse,
+	"RemoveCustomFieldValues":        RemoveCustomFieldValues,
+	"GetRegionStats":                 GetRegionStats,
+}
@@ -0,0 +1,45 @@
+package queries
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var queryName = regexp.MustCompile(`(?m)^-- name: (\w+) `)
+
+// TestStatementsCoverQueries checks that every query of the SQL files is prepared at startup
+func TestStatementsCoverQueries(t *testing.T) {
+	files, err := filepath.Glob("*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range queryName.FindAllStringSubmatch(string(src), -1) {
+			names[m[1]] = true
+		}
+	}
+	if len(names) == 0 {
+		t.Fatal("found no queries")
+	}
+
+	for name := range names {
+		if _, ok := Statements[name]; !ok {
+			t.Errorf("%s is missing from Statements", name)
+		}
+	}
+	for name, stmt := range Statements {
+		if !names[name] {
+			t.Errorf("Statements lists %s, which no SQL file defines", name)
+		} else if !strings.HasPrefix(stmt, "-- name: "+name+" ") {
+			t.Errorf("Statements lists another statement as %s", name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+-- name: GetUserProfile :one
+SELECT id, email, COALESCE(name, '')::text AS name, avatar, locale, timezone, playback_autoplay, playback_quality,
+    playback_captions, created_at, updated_at
+FROM users WHERE id = $1;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+SET name = CASE WHEN sqlc.narg(name)::text IS NULL THEN name ELSE NULLIF(sqlc.narg(name)::text, '') END,
+    locale = COALESCE(sqlc.narg(locale), locale), timezone = COALESCE(sqlc.narg(timezone), timezone),
+    playback_autoplay = COALESCE(sqlc.narg(playback_autoplay), playback_autoplay),
+    playback_quality = COALESCE(sqlc.narg(playback_quality), playback_quality),
+    playback_captions = COALESCE(sqlc.narg(playback_captions), playback_captions)
+WHERE id = sqlc.arg(id)
+RETURNING id, email, COALESCE(name, '')::text AS name, avatar, locale, timezone, playback_autoplay, playback_quality,
+    playback_captions, created_at, updated_at;
+
+-- name: GetPlaybackPreferences :one
+SELECT playback_autoplay, playback_quality, playback_captions FROM users WHERE id = $1;
+
+-- name: GetUserContact :one
+SELECT email, COALESCE(name, '')::text AS name FROM users WHERE id = $1;
+
+-- name: GetUserEmail :one
+SELECT email FROM users WHERE id = $1;
+
+-- name: FindUserByEmail :one
+SELECT id FROM users WHERE email = $1;
+
+-- name: FindUserByEmailFold :one
+SELECT id FROM users WHERE lower(email) = lower(sqlc.arg(email)::text);
+
+-- name: UserExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1);
+
+-- name: GetInvitationNames :one
+SELECT o.name AS organization, COALESCE(NULLIF(u.name, ''), u.email)::text AS inviter
+FROM organizations o, users u
+WHERE o.id = sqlc.arg(organization_id) AND u.id = sqlc.arg(inviter_id);
+
+-- name: CreateUser :one
+INSERT INTO users (email, password_hash, name, email_verified)
+VALUES (sqlc.arg(email), crypt(sqlc.arg(password)::text, gen_salt('bf')), NULLIF(sqlc.arg(name)::text, ''), TRUE)
+RETURNING id;
+
+-- name: SignIn :one
+UPDATE users SET last_login_at = NOW()
+WHERE email = sqlc.arg(email) AND password_hash = crypt(sqlc.arg(password)::text, password_hash)
+RETURNING id;
+
+-- name: SetDefaultOrganization :exec
+UPDATE users SET default_organization_id = sqlc.arg(organization_id)::uuid WHERE id = sqlc.arg(id);
+
+-- name: ClearDefaultOrganization :exec
+UPDATE users SET default_organization_id = NULL WHERE default_organization_id = sqlc.arg(organization_id)::uuid;
+
+-- name: GetAvatar :one
+SELECT avatar FROM users WHERE id = $1;
+
+-- name: LockAvatar :one
+SELECT avatar FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: SetAvatar :exec
+UPDATE users SET avatar = $1 WHERE id = $2;
@@ -0,0 +1,312 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: users.sql
+
+package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"openvdo/internal/models"
+)
+
+const ClearDefaultOrganization = `-- name: ClearDefaultOrganization :exec
+UPDATE users SET default_organization_id = NULL WHERE default_organization_id = $1::uuid
+`
+
+func (q *Queries) ClearDefaultOrganization(ctx context.Context, organizationID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, ClearDefaultOrganization, organizationID)
+	return err
+}
+
+const CreateUser = `-- name: CreateUser :one
+INSERT INTO users (email, password_hash, name, email_verified)
+VALUES ($1, crypt($2::text, gen_salt('bf')), NULLIF($3::text, ''), TRUE)
+RETURNING id
+`
+
+type CreateUserParams struct {
+	Email    string `db:"email"`
+	Password string `db:"password"`
+	Name     string `db:"name"`
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, CreateUser, arg.Email, arg.Password, arg.Name)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const FindUserByEmail = `-- name: FindUserByEmail :one
+SELECT id FROM users WHERE email = $1
+`
+
+func (q *Queries) FindUserByEmail(ctx context.Context, email string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, FindUserByEmail, email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const FindUserByEmailFold = `-- name: FindUserByEmailFold :one
+SELECT id FROM users WHERE lower(email) = lower($1::text)
+`
+
+func (q *Queries) FindUserByEmailFold(ctx context.Context, email string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, FindUserByEmailFold, email)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const GetAvatar = `-- name: GetAvatar :one
+SELECT avatar FROM users WHERE id = $1
+`
+
+func (q *Queries) GetAvatar(ctx context.Context, id uuid.UUID) (*models.Image, error) {
+	row := q.db.QueryRowContext(ctx, GetAvatar, id)
+	var avatar *models.Image
+	err := row.Scan(&avatar)
+	return avatar, err
+}
+
+const GetInvitationNames = `-- name: GetInvitationNames :one
+SELECT o.name AS organization, COALESCE(NULLIF(u.name, ''), u.email)::text AS inviter
+FROM organizations o, users u
+WHERE o.id = $1 AND u.id = $2
+`
+
+type GetInvitationNamesParams struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	InviterID      uuid.UUID `db:"inviter_id"`
+}
+
+type GetInvitationNamesRow struct {
+	Organization string `db:"organization"`
+	Inviter      string `db:"inviter"`
+}
+
+func (q *Queries) GetInvitationNames(ctx context.Context, arg GetInvitationNamesParams) (GetInvitationNamesRow, error) {
+	row := q.db.QueryRowContext(ctx, GetInvitationNames, arg.OrganizationID, arg.InviterID)
+	var i GetInvitationNamesRow
+	err := row.Scan(&i.Organization, &i.Inviter)
+	return i, err
+}
+
+const GetPlaybackPreferences = `-- name: GetPlaybackPreferences :one
+SELECT playback_autoplay, playback_quality, playback_captions FROM users WHERE id = $1
+`
+
+type GetPlaybackPreferencesRow struct {
+	PlaybackAutoplay bool   `db:"playback_autoplay"`
+	PlaybackQuality  string `db:"playback_quality"`
+	PlaybackCaptions bool   `db:"playback_captions"`
+}
+
+func (q *Queries) GetPlaybackPreferences(ctx context.Context, id uuid.UUID) (GetPlaybackPreferencesRow, error) {
+	row := q.db.QueryRowContext(ctx, GetPlaybackPreferences, id)
+	var i GetPlaybackPreferencesRow
+	err := row.Scan(&i.PlaybackAutoplay, &i.PlaybackQuality, &i.PlaybackCaptions)
+	return i, err
+}
+
+const GetUserContact = `-- name: GetUserContact :one
+SELECT email, COALESCE(name, '')::text AS name FROM users WHERE id = $1
+`
+
+type GetUserContactRow struct {
+	Email string `db:"email"`
+	Name  string `db:"name"`
+}
+
+func (q *Queries) GetUserContact(ctx context.Context, id uuid.UUID) (GetUserContactRow, error) {
+	row := q.db.QueryRowContext(ctx, GetUserContact, id)
+	var i GetUserContactRow
+	err := row.Scan(&i.Email, &i.Name)
+	return i, err
+}
+
+const GetUserEmail = `-- name: GetUserEmail :one
+SELECT email FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserEmail(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, GetUserEmail, id)
+	var email string
+	err := row.Scan(&email)
+	return email, err
+}
+
+const GetUserProfile = `-- name: GetUserProfile :one
+SELECT id, email, COALESCE(name, '')::text AS name, avatar, locale, timezone, playback_autoplay, playback_quality,
+    playback_captions, created_at, updated_at
+FROM users WHERE id = $1
+`
+
+type GetUserProfileRow struct {
+	ID               uuid.UUID     `db:"id"`
+	Email            string        `db:"email"`
+	Name             string        `db:"name"`
+	Avatar           *models.Image `db:"avatar"`
+	Locale           string        `db:"locale"`
+	Timezone         string        `db:"timezone"`
+	PlaybackAutoplay bool          `db:"playback_autoplay"`
+	PlaybackQuality  string        `db:"playback_quality"`
+	PlaybackCaptions bool          `db:"playback_captions"`
+	CreatedAt        time.Time     `db:"created_at"`
+	UpdatedAt        time.Time     `db:"updated_at"`
+}
+
+func (q *Queries) GetUserProfile(ctx context.Context, id uuid.UUID) (GetUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, GetUserProfile, id)
+	var i GetUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Avatar,
+		&i.Locale,
+		&i.Timezone,
+		&i.PlaybackAutoplay,
+		&i.PlaybackQuality,
+		&i.PlaybackCaptions,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const LockAvatar = `-- name: LockAvatar :one
+SELECT avatar FROM users WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockAvatar(ctx context.Context, id uuid.UUID) (*models.Image, error) {
+	row := q.db.QueryRowContext(ctx, LockAvatar, id)
+	var avatar *models.Image
+	err := row.Scan(&avatar)
+	return avatar, err
+}
+
+const SetAvatar = `-- name: SetAvatar :exec
+UPDATE users SET avatar = $1 WHERE id = $2
+`
+
+type SetAvatarParams struct {
+	Avatar *models.Image `db:"avatar"`
+	ID     uuid.UUID     `db:"id"`
+}
+
+func (q *Queries) SetAvatar(ctx context.Context, arg SetAvatarParams) error {
+	_, err := q.db.ExecContext(ctx, SetAvatar, arg.Avatar, arg.ID)
+	return err
+}
+
+const SetDefaultOrganization = `-- name: SetDefaultOrganization :exec
+UPDATE users SET default_organization_id = $1::uuid WHERE id = $2
+`
+
+type SetDefaultOrganizationParams struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	ID             uuid.UUID `db:"id"`
+}
+
+func (q *Queries) SetDefaultOrganization(ctx context.Context, arg SetDefaultOrganizationParams) error {
+	_, err := q.db.ExecContext(ctx, SetDefaultOrganization, arg.OrganizationID, arg.ID)
+	return err
+}
+
+const SignIn = `-- name: SignIn :one
+UPDATE users SET last_login_at = NOW()
+WHERE email = $1 AND password_hash = crypt($2::text, password_hash)
+RETURNING id
+`
+
+type SignInParams struct {
+	Email    string `db:"email"`
+	Password string `db:"password"`
+}
+
+func (q *Queries) SignIn(ctx context.Context, arg SignInParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, SignIn, arg.Email, arg.Password)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const UpdateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET name = CASE WHEN $1::text IS NULL THEN name ELSE NULLIF($1::text, '') END,
+    locale = COALESCE($2, locale), timezone = COALESCE($3, timezone),
+    playback_autoplay = COALESCE($4, playback_autoplay),
+    playback_quality = COALESCE($5, playback_quality),
+    playback_captions = COALESCE($6, playback_captions)
+WHERE id = $7
+RETURNING id, email, COALESCE(name, '')::text AS name, avatar, locale, timezone, playback_autoplay, playback_quality,
+    playback_captions, created_at, updated_at
+`
+
+type UpdateUserProfileParams struct {
+	Name             *string   `db:"name"`
+	Locale           *string   `db:"locale"`
+	Timezone         *string   `db:"timezone"`
+	PlaybackAutoplay *bool     `db:"playback_autoplay"`
+	PlaybackQuality  *string   `db:"playback_quality"`
+	PlaybackCaptions *bool     `db:"playback_captions"`
+	ID               uuid.UUID `db:"id"`
+}
+
+type UpdateUserProfileRow struct {
+	ID               uuid.UUID     `db:"id"`
+	Email            string        `db:"email"`
+	Name             string        `db:"name"`
+	Avatar           *models.Image `db:"avatar"`
+	Locale           string        `db:"locale"`
+	Timezone         string        `db:"timezone"`
+	PlaybackAutoplay bool          `db:"playback_autoplay"`
+	PlaybackQuality  string        `db:"playback_quality"`
+	PlaybackCaptions bool          `db:"playback_captions"`
+	CreatedAt        time.Time     `db:"created_at"`
+	UpdatedAt        time.Time     `db:"updated_at"`
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UpdateUserProfileRow, error) {
+	row := q.db.QueryRowContext(ctx, UpdateUserProfile,
+		arg.Name,
+		arg.Locale,
+		arg.Timezone,
+		arg.PlaybackAutoplay,
+		arg.PlaybackQuality,
+		arg.PlaybackCaptions,
+		arg.ID,
+	)
+	var i UpdateUserProfileRow
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.Avatar,
+		&i.Locale,
+		&i.Timezone,
+		&i.PlaybackAutoplay,
+		&i.PlaybackQuality,
+		&i.PlaybackCaptions,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const UserExists = `-- name: UserExists :one
+SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)
+`
+
+func (q *Queries) UserExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, UserExists, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
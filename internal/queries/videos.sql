@@ -0,0 +1,258 @@
+-- name: GetVideo :one
+SELECT * FROM videos WHERE id = $1;
+
+-- name: VideoExists :one
+SELECT EXISTS (SELECT 1 FROM videos WHERE id = $1);
+
+-- name: VideoExistsInOrganization :one
+SELECT EXISTS (SELECT 1 FROM videos WHERE id = $1 AND organization_id = $2);
+
+-- name: CountVideosInOrganization :one
+SELECT COUNT(*) FROM videos WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND organization_id = sqlc.arg(organization_id);
+
+-- name: GetVideoOwner :one
+SELECT organization_id, title, created_by FROM videos WHERE id = $1;
+
+-- name: GetVideoSource :one
+SELECT organization_id, source_key FROM videos WHERE id = $1;
+
+-- name: GetVideoImportState :one
+SELECT status, COALESCE(source_key, '')::text AS source_key FROM videos WHERE id = $1;
+
+-- name: GetVideoColorState :one
+SELECT source_key, source_revision, color FROM videos WHERE id = $1;
+
+-- name: GetVideoTranscodeSource :one
+SELECT organization_id, source_key, source_revision FROM videos WHERE id = $1;
+
+-- name: GetVideoSourceRevision :one
+SELECT source_revision FROM videos WHERE id = $1;
+
+-- name: LockVideoSourceRevision :one
+SELECT source_revision FROM videos WHERE id = $1 FOR UPDATE;
+
+-- name: VideoAtRevision :one
+SELECT EXISTS (SELECT 1 FROM videos WHERE id = $1 AND source_revision = $2);
+
+-- name: LockVideoStatus :one
+SELECT status, organization_id FROM videos WHERE id = $1 FOR UPDATE;
+
+-- name: LockVideoReviewStatus :one
+SELECT review_status FROM videos WHERE id = sqlc.arg(id) AND status <> sqlc.arg(excluded_status) FOR UPDATE;
+
+-- name: ListUnhashedVideoIDs :many
+SELECT id FROM videos
+WHERE status = ANY(sqlc.arg(statuses)::text[]) AND sha256 IS NULL AND source_key IS NOT NULL
+ORDER BY created_at
+LIMIT sqlc.arg(max_videos);
+
+-- name: FindDuplicateVideo :one
+SELECT * FROM videos
+WHERE organization_id = sqlc.arg(organization_id) AND sha256 = sqlc.arg(sha256)::text AND id <> sqlc.arg(exclude_id) AND duplicate_of IS NULL
+    AND status = ANY(sqlc.arg(statuses)::text[])
+ORDER BY created_at
+LIMIT 1;
+
+-- name: FirstVideoWithSource :one
+SELECT id FROM videos WHERE source_key = sqlc.arg(source_key)::text ORDER BY created_at LIMIT 1;
+
+-- name: SourceKeyInUse :one
+SELECT (EXISTS (SELECT 1 FROM videos WHERE source_key = sqlc.arg(source_key)::text)
+    OR EXISTS (SELECT 1 FROM video_source_versions WHERE source_key = sqlc.arg(source_key)::text))::boolean AS used;
+
+-- name: ListPublicProjectVideos :many
+SELECT * FROM videos
+WHERE project_id = sqlc.arg(project_id)::uuid AND visibility = sqlc.arg(visibility) AND status = ANY(sqlc.arg(statuses)::text[])
+    AND moderation_status = sqlc.arg(moderation_status) AND review_status = sqlc.arg(review_status) AND NOT requires_entitlement AND COALESCE(source_key, '') <> ''
+ORDER BY created_at DESC
+LIMIT sqlc.arg(max_videos);
+
+-- name: ListVideoSourcesInOrganization :many
+SELECT id, COALESCE(source_key, '')::text AS source_key FROM videos WHERE organization_id = $1 ORDER BY id LIMIT $2;
+
+-- name: SetVideoHash :exec
+UPDATE videos SET sha256 = sqlc.arg(sha256)::text, size_bytes = sqlc.arg(size_bytes)::bigint WHERE id = sqlc.arg(id);
+
+-- name: SetVideoDuplicate :exec
+UPDATE videos SET sha256 = sqlc.arg(sha256)::text, size_bytes = sqlc.arg(size_bytes)::bigint, duplicate_of = sqlc.arg(duplicate_of)::uuid
+WHERE id = sqlc.arg(id);
+
+-- name: ReuseVideoSource :exec
+UPDATE videos
+SET sha256 = sqlc.arg(sha256)::text, size_bytes = sqlc.arg(size_bytes)::bigint, duplicate_of = sqlc.arg(duplicate_of)::uuid,
+    source_key = sqlc.arg(source_key)::text
+WHERE id = sqlc.arg(id);
+
+-- name: ClearDuplicatesOf :exec
+UPDATE videos SET duplicate_of = NULL WHERE duplicate_of = sqlc.arg(video_id)::uuid;
+
+-- name: SetVideoImported :exec
+UPDATE videos SET content_type = sqlc.arg(content_type)::text, size_bytes = sqlc.arg(size_bytes)::bigint WHERE id = sqlc.arg(id);
+
+-- name: SetVideoColor :exec
+UPDATE videos SET color = $3 WHERE id = $1 AND source_revision = $2;
+
+-- name: SetVideoStatus :exec
+UPDATE videos SET status = $2, status_changed_at = NOW() WHERE id = $1;
+
+-- name: SetVideoReviewStatus :exec
+UPDATE videos SET review_status = $2 WHERE id = $1;
+
+-- name: DecideVideoReview :one
+UPDATE videos SET review_status = $2 WHERE id = $1
+RETURNING *;
+
+-- name: ReplaceVideoSource :one
+UPDATE videos
+SET source_key = sqlc.arg(new_key)::text, content_type = NULLIF(sqlc.arg(content_type)::text, ''), size_bytes = sqlc.arg(size_bytes)::bigint,
+    sha256 = NULL, duplicate_of = NULL, color = NULL,
+    source_revision = source_revision + 1, replaced_at = NOW(), version = version + 1
+WHERE id = sqlc.arg(id) AND COALESCE(source_key, '') = sqlc.arg(old_key)::text
+RETURNING *;
+
+-- name: RollbackVideoSource :one
+UPDATE videos
+SET source_key = sqlc.arg(source_key)::text, content_type = NULLIF(sqlc.arg(content_type)::text, ''), size_bytes = sqlc.arg(size_bytes)::bigint,
+    sha256 = sqlc.narg(sha256), duplicate_of = NULL, color = sqlc.narg(color),
+    source_revision = source_revision + 1, replaced_at = NOW(), version = version + 1
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteVideos :exec
+DELETE FROM videos WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: OrganizationHasContent :one
+SELECT (EXISTS (SELECT 1 FROM videos WHERE organization_id = sqlc.arg(organization_id)::uuid)
+    OR EXISTS (SELECT 1 FROM jobs WHERE organization_id = sqlc.arg(organization_id)::uuid AND status IN ('queued', 'running')))::boolean AS has_content;
+
+-- name: SetModerationStatus :exec
+UPDATE videos SET moderation_status = $2 WHERE id = $1;
+
+-- name: ChangeModerationStatus :execrows
+UPDATE videos SET moderation_status = sqlc.arg(moderation_status) WHERE id = sqlc.arg(id) AND moderation_status = sqlc.arg(from_status);
+
+-- name: RestrictReportedVideo :execrows
+UPDATE videos SET moderation_status = sqlc.arg(moderation_status)
+WHERE videos.id = sqlc.arg(id)::uuid AND moderation_status = sqlc.arg(from_status)
+    AND (SELECT COUNT(*) FROM abuse_reports WHERE video_id = sqlc.arg(id)::uuid AND abuse_reports.status = 'open') >= sqlc.arg(threshold)::bigint;
+
+-- name: ListTrendingVideos :many
+SELECT sqlc.embed(videos), t.plays_24h, t.plays_7d, t.score, t.computed_at
+FROM videos
+JOIN (SELECT video_id, plays_24h, plays_7d, score, computed_at FROM video_trending) t ON t.video_id = videos.id
+WHERE status = $1
+ORDER BY t.score DESC
+LIMIT $2;
+
+-- name: GetVideoByExternalID :one
+SELECT * FROM videos WHERE organization_id = $1 AND external_id = $2;
+
+-- name: GetVideoStatus :one
+SELECT status, status_changed_at FROM videos WHERE id = $1;
+
+-- name: UpdateVideo :one
+UPDATE videos
+SET title = COALESCE(sqlc.narg(title), title), description = COALESCE(sqlc.narg(description), description),
+    visibility = COALESCE(sqlc.narg(visibility), visibility), tags = COALESCE(sqlc.narg(tags)::text[], tags),
+    requires_entitlement = COALESCE(sqlc.narg(requires_entitlement), requires_entitlement),
+    downloads_enabled = COALESCE(sqlc.narg(downloads_enabled), downloads_enabled),
+    custom_fields = (custom_fields || sqlc.arg(set_fields)) - sqlc.arg(unset_fields)::text[],
+    external_id = CASE WHEN sqlc.narg(external_id)::text IS NULL THEN external_id ELSE NULLIF(sqlc.narg(external_id)::text, '') END,
+    version = version + 1
+WHERE id = sqlc.arg(id) AND version = sqlc.arg(version)
+RETURNING *;
+
+-- name: CreateUploadingVideo :exec
+INSERT INTO videos (id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, custom_fields, external_id)
+VALUES (sqlc.arg(id), sqlc.arg(organization_id), sqlc.narg(project_id), sqlc.arg(title), sqlc.arg(description)::text, sqlc.arg(status),
+    sqlc.arg(source_key)::text, sqlc.arg(content_type)::text, sqlc.arg(size_bytes)::bigint, sqlc.arg(created_by)::uuid,
+    sqlc.arg(custom_fields), sqlc.narg(external_id));
+
+-- name: CreateDuplicateVideo :one
+INSERT INTO videos (organization_id, project_id, title, description, status, source_key, content_type, size_bytes, sha256, duplicate_of, created_by, custom_fields, external_id)
+VALUES (sqlc.arg(organization_id), sqlc.narg(project_id), sqlc.arg(title), sqlc.arg(description)::text, sqlc.arg(status),
+    sqlc.arg(source_key)::text, sqlc.arg(content_type)::text, sqlc.arg(size_bytes)::bigint, sqlc.narg(sha256),
+    sqlc.arg(duplicate_of)::uuid, sqlc.arg(created_by)::uuid, sqlc.arg(custom_fields), sqlc.narg(external_id))
+RETURNING *;
+
+-- name: CreateImportedVideo :one
+INSERT INTO videos (id, organization_id, project_id, title, description, tags, custom_fields, status, source_key, created_by, external_id)
+VALUES (sqlc.arg(id), sqlc.arg(organization_id), sqlc.narg(project_id), sqlc.arg(title), sqlc.arg(description)::text,
+    sqlc.arg(tags)::text[], sqlc.arg(custom_fields), sqlc.arg(status), sqlc.arg(source_key)::text, sqlc.arg(created_by)::uuid,
+    sqlc.narg(external_id))
+RETURNING *;
+
+-- name: GetVideoTitle :one
+SELECT title FROM videos WHERE id = $1;
+
+-- name: GetVideoVisibility :one
+SELECT visibility FROM videos WHERE id = $1;
+
+-- name: GetVideoOrganization :one
+SELECT organization_id FROM videos WHERE id = $1;
+
+-- name: ListVideoOrganizations :many
+SELECT id, organization_id FROM videos WHERE id = ANY(sqlc.arg(ids)::uuid[]);
+
+-- name: GetPlayableVideo :one
+SELECT * FROM videos
+WHERE videos.id = sqlc.arg(id) AND NOT EXISTS (
+    SELECT 1 FROM organizations o WHERE o.id = videos.organization_id AND o.deleted_at IS NOT NULL
+);
+
+-- name: GetSessionVideo :one
+SELECT * FROM videos
+WHERE videos.id = (SELECT video_id FROM playback_sessions WHERE playback_sessions.id = sqlc.arg(session_id) AND user_id = sqlc.arg(user_id));
+
+-- name: LockVideoTags :many
+SELECT id, tags FROM videos
+WHERE id = ANY(sqlc.arg(ids)::uuid[]) AND organization_id = sqlc.arg(organization_id)
+ORDER BY id
+FOR UPDATE;
+
+-- name: BulkUpdateVideo :one
+UPDATE videos
+SET visibility = COALESCE(sqlc.narg(visibility), visibility), tags = sqlc.arg(tags)::text[],
+    custom_fields = (custom_fields || sqlc.arg(set_fields)) - sqlc.arg(unset_fields)::text[], version = version + 1
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetAdBreaks :one
+SELECT ad_breaks FROM videos WHERE id = $1;
+
+-- name: GetVideoAdBreaks :one
+SELECT organization_id, ad_breaks FROM videos WHERE id = $1;
+
+-- name: SetAdBreaks :one
+UPDATE videos SET ad_breaks = $2, version = version + 1 WHERE id = $1
+RETURNING *;
+
+-- name: GetChapters :one
+SELECT chapters FROM videos WHERE id = $1;
+
+-- name: SetChapters :one
+UPDATE videos SET chapters = $2, version = version + 1 WHERE id = $1
+RETURNING *;
+
+-- name: LockThumbnail :one
+SELECT thumbnail FROM videos WHERE id = $1 FOR UPDATE;
+
+-- name: SetThumbnail :one
+UPDATE videos SET thumbnail = $1, version = version + 1 WHERE id = $2
+RETURNING *;
+
+-- name: CustomFieldOptionInUse :one
+SELECT EXISTS (
+    SELECT 1 FROM videos
+    WHERE organization_id = sqlc.arg(organization_id) AND custom_fields ? sqlc.arg(key)::text
+        AND custom_fields->>sqlc.arg(key)::text <> ALL(sqlc.arg(options)::text[])
+);
+
+-- name: RemoveCustomFieldValues :exec
+UPDATE videos SET custom_fields = custom_fields - sqlc.arg(key)::text
+WHERE organization_id = sqlc.arg(organization_id) AND custom_fields ? sqlc.arg(key)::text;
+
+-- name: GetRegionStats :one
+SELECT COUNT(DISTINCT organization_id) AS organizations, COUNT(*) AS videos, COALESCE(SUM(size_bytes), 0)::bigint AS bytes,
+    (SELECT COUNT(*) FROM jobs WHERE status = 'queued') AS queued_jobs
+FROM videos;
@@ -0,0 +1,1785 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: videos.sql
+
+package queries
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"openvdo/internal/models"
+)
+
+const BulkUpdateVideo = `-- name: BulkUpdateVideo :one
+UPDATE videos
+SET visibility = COALESCE($1, visibility), tags = $2::text[],
+    custom_fields = (custom_fields || $3) - $4::text[], version = version + 1
+WHERE id = $5
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type BulkUpdateVideoParams struct {
+	Visibility  *string                  `db:"visibility"`
+	Tags        []string                 `db:"tags"`
+	SetFields   models.CustomFieldValues `db:"set_fields"`
+	UnsetFields []string                 `db:"unset_fields"`
+	ID          uuid.UUID                `db:"id"`
+}
+
+func (q *Queries) BulkUpdateVideo(ctx context.Context, arg BulkUpdateVideoParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, BulkUpdateVideo,
+		arg.Visibility,
+		pq.Array(arg.Tags),
+		arg.SetFields,
+		pq.Array(arg.UnsetFields),
+		arg.ID,
+	)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const ChangeModerationStatus = `-- name: ChangeModerationStatus :execrows
+UPDATE videos SET moderation_status = $1 WHERE id = $2 AND moderation_status = $3
+`
+
+type ChangeModerationStatusParams struct {
+	ModerationStatus string    `db:"moderation_status"`
+	ID               uuid.UUID `db:"id"`
+	FromStatus       string    `db:"from_status"`
+}
+
+func (q *Queries) ChangeModerationStatus(ctx context.Context, arg ChangeModerationStatusParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, ChangeModerationStatus, arg.ModerationStatus, arg.ID, arg.FromStatus)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ClearDuplicatesOf = `-- name: ClearDuplicatesOf :exec
+UPDATE videos SET duplicate_of = NULL WHERE duplicate_of = $1::uuid
+`
+
+func (q *Queries) ClearDuplicatesOf(ctx context.Context, videoID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, ClearDuplicatesOf, videoID)
+	return err
+}
+
+const CountVideosInOrganization = `-- name: CountVideosInOrganization :one
+SELECT COUNT(*) FROM videos WHERE id = ANY($1::uuid[]) AND organization_id = $2
+`
+
+type CountVideosInOrganizationParams struct {
+	Ids            []uuid.UUID `db:"ids"`
+	OrganizationID uuid.UUID   `db:"organization_id"`
+}
+
+func (q *Queries) CountVideosInOrganization(ctx context.Context, arg CountVideosInOrganizationParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, CountVideosInOrganization, pq.Array(arg.Ids), arg.OrganizationID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const CreateDuplicateVideo = `-- name: CreateDuplicateVideo :one
+INSERT INTO videos (organization_id, project_id, title, description, status, source_key, content_type, size_bytes, sha256, duplicate_of, created_by, custom_fields, external_id)
+VALUES ($1, $2, $3, $4::text, $5,
+    $6::text, $7::text, $8::bigint, $9,
+    $10::uuid, $11::uuid, $12, $13)
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type CreateDuplicateVideoParams struct {
+	OrganizationID uuid.UUID                `db:"organization_id"`
+	ProjectID      *uuid.UUID               `db:"project_id"`
+	Title          string                   `db:"title"`
+	Description    string                   `db:"description"`
+	Status         string                   `db:"status"`
+	SourceKey      string                   `db:"source_key"`
+	ContentType    string                   `db:"content_type"`
+	SizeBytes      int64                    `db:"size_bytes"`
+	SHA256         *string                  `db:"sha256"`
+	DuplicateOf    uuid.UUID                `db:"duplicate_of"`
+	CreatedBy      uuid.UUID                `db:"created_by"`
+	CustomFields   models.CustomFieldValues `db:"custom_fields"`
+	ExternalID     *string                  `db:"external_id"`
+}
+
+func (q *Queries) CreateDuplicateVideo(ctx context.Context, arg CreateDuplicateVideoParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, CreateDuplicateVideo,
+		arg.OrganizationID,
+		arg.ProjectID,
+		arg.Title,
+		arg.Description,
+		arg.Status,
+		arg.SourceKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.SHA256,
+		arg.DuplicateOf,
+		arg.CreatedBy,
+		arg.CustomFields,
+		arg.ExternalID,
+	)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const CreateImportedVideo = `-- name: CreateImportedVideo :one
+INSERT INTO videos (id, organization_id, project_id, title, description, tags, custom_fields, status, source_key, created_by, external_id)
+VALUES ($1, $2, $3, $4, $5::text,
+    $6::text[], $7, $8, $9::text, $10::uuid,
+    $11)
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type CreateImportedVideoParams struct {
+	ID             uuid.UUID                `db:"id"`
+	OrganizationID uuid.UUID                `db:"organization_id"`
+	ProjectID      *uuid.UUID               `db:"project_id"`
+	Title          string                   `db:"title"`
+	Description    string                   `db:"description"`
+	Tags           []string                 `db:"tags"`
+	CustomFields   models.CustomFieldValues `db:"custom_fields"`
+	Status         string                   `db:"status"`
+	SourceKey      string                   `db:"source_key"`
+	CreatedBy      uuid.UUID                `db:"created_by"`
+	ExternalID     *string                  `db:"external_id"`
+}
+
+func (q *Queries) CreateImportedVideo(ctx context.Context, arg CreateImportedVideoParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, CreateImportedVideo,
+		arg.ID,
+		arg.OrganizationID,
+		arg.ProjectID,
+		arg.Title,
+		arg.Description,
+		pq.Array(arg.Tags),
+		arg.CustomFields,
+		arg.Status,
+		arg.SourceKey,
+		arg.CreatedBy,
+		arg.ExternalID,
+	)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const CreateUploadingVideo = `-- name: CreateUploadingVideo :exec
+INSERT INTO videos (id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, custom_fields, external_id)
+VALUES ($1, $2, $3, $4, $5::text, $6,
+    $7::text, $8::text, $9::bigint, $10::uuid,
+    $11, $12)
+`
+
+type CreateUploadingVideoParams struct {
+	ID             uuid.UUID                `db:"id"`
+	OrganizationID uuid.UUID                `db:"organization_id"`
+	ProjectID      *uuid.UUID               `db:"project_id"`
+	Title          string                   `db:"title"`
+	Description    string                   `db:"description"`
+	Status         string                   `db:"status"`
+	SourceKey      string                   `db:"source_key"`
+	ContentType    string                   `db:"content_type"`
+	SizeBytes      int64                    `db:"size_bytes"`
+	CreatedBy      uuid.UUID                `db:"created_by"`
+	CustomFields   models.CustomFieldValues `db:"custom_fields"`
+	ExternalID     *string                  `db:"external_id"`
+}
+
+func (q *Queries) CreateUploadingVideo(ctx context.Context, arg CreateUploadingVideoParams) error {
+	_, err := q.db.ExecContext(ctx, CreateUploadingVideo,
+		arg.ID,
+		arg.OrganizationID,
+		arg.ProjectID,
+		arg.Title,
+		arg.Description,
+		arg.Status,
+		arg.SourceKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.CreatedBy,
+		arg.CustomFields,
+		arg.ExternalID,
+	)
+	return err
+}
+
+const CustomFieldOptionInUse = `-- name: CustomFieldOptionInUse :one
+SELECT EXISTS (
+    SELECT 1 FROM videos
+    WHERE organization_id = $1 AND custom_fields ? $2::text
+        AND custom_fields->>$2::text <> ALL($3::text[])
+)
+`
+
+type CustomFieldOptionInUseParams struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	Key            string    `db:"key"`
+	Options        []string  `db:"options"`
+}
+
+func (q *Queries) CustomFieldOptionInUse(ctx context.Context, arg CustomFieldOptionInUseParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, CustomFieldOptionInUse, arg.OrganizationID, arg.Key, pq.Array(arg.Options))
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const DecideVideoReview = `-- name: DecideVideoReview :one
+UPDATE videos SET review_status = $2 WHERE id = $1
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type DecideVideoReviewParams struct {
+	ID           uuid.UUID `db:"id"`
+	ReviewStatus string    `db:"review_status"`
+}
+
+func (q *Queries) DecideVideoReview(ctx context.Context, arg DecideVideoReviewParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, DecideVideoReview, arg.ID, arg.ReviewStatus)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const DeleteVideos = `-- name: DeleteVideos :exec
+DELETE FROM videos WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteVideos(ctx context.Context, ids []uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, DeleteVideos, pq.Array(ids))
+	return err
+}
+
+const FindDuplicateVideo = `-- name: FindDuplicateVideo :one
+SELECT id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id FROM videos
+WHERE organization_id = $1 AND sha256 = $2::text AND id <> $3 AND duplicate_of IS NULL
+    AND status = ANY($4::text[])
+ORDER BY created_at
+LIMIT 1
+`
+
+type FindDuplicateVideoParams struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	SHA256         string    `db:"sha256"`
+	ExcludeID      uuid.UUID `db:"exclude_id"`
+	Statuses       []string  `db:"statuses"`
+}
+
+func (q *Queries) FindDuplicateVideo(ctx context.Context, arg FindDuplicateVideoParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, FindDuplicateVideo,
+		arg.OrganizationID,
+		arg.SHA256,
+		arg.ExcludeID,
+		pq.Array(arg.Statuses),
+	)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const FirstVideoWithSource = `-- name: FirstVideoWithSource :one
+SELECT id FROM videos WHERE source_key = $1::text ORDER BY created_at LIMIT 1
+`
+
+func (q *Queries) FirstVideoWithSource(ctx context.Context, sourceKey string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, FirstVideoWithSource, sourceKey)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const GetAdBreaks = `-- name: GetAdBreaks :one
+SELECT ad_breaks FROM videos WHERE id = $1
+`
+
+func (q *Queries) GetAdBreaks(ctx context.Context, id uuid.UUID) (models.AdBreaks, error) {
+	row := q.db.QueryRowContext(ctx, GetAdBreaks, id)
+	var ad_breaks models.AdBreaks
+	err := row.Scan(&ad_breaks)
+	return ad_breaks, err
+}
+
+const GetChapters = `-- name: GetChapters :one
+SELECT chapters FROM videos WHERE id = $1
+`
+
+func (q *Queries) GetChapters(ctx context.Context, id uuid.UUID) (models.Chapters, error) {
+	row := q.db.QueryRowContext(ctx, GetChapters, id)
+	var chapters models.Chapters
+	err := row.Scan(&chapters)
+	return chapters, err
+}
+
+const GetPlayableVideo = `-- name: GetPlayableVideo :one
+SELECT id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id FROM videos
+WHERE videos.id = $1 AND NOT EXISTS (
+    SELECT 1 FROM organizations o WHERE o.id = videos.organization_id AND o.deleted_at IS NOT NULL
+)
+`
+
+func (q *Queries) GetPlayableVideo(ctx context.Context, id uuid.UUID) (Video, error) {
+	row := q.db.QueryRowContext(ctx, GetPlayableVideo, id)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const GetRegionStats = `-- name: GetRegionStats :one
+SELECT COUNT(DISTINCT organization_id) AS organizations, COUNT(*) AS videos, COALESCE(SUM(size_bytes), 0)::bigint AS bytes,
+    (SELECT COUNT(*) FROM jobs WHERE status = 'queued') AS queued_jobs
+FROM videos
+`
+
+type GetRegionStatsRow struct {
+	Organizations int64 `db:"organizations"`
+	Videos        int64 `db:"videos"`
+	Bytes         int64 `db:"bytes"`
+	QueuedJobs    int64 `db:"queued_jobs"`
+}
+
+func (q *Queries) GetRegionStats(ctx context.Context) (GetRegionStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, GetRegionStats)
+	var i GetRegionStatsRow
+	err := row.Scan(
+		&i.Organizations,
+		&i.Videos,
+		&i.Bytes,
+		&i.QueuedJobs,
+	)
+	return i, err
+}
+
+const GetSessionVideo = `-- name: GetSessionVideo :one
+SELECT id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id FROM videos
+WHERE videos.id = (SELECT video_id FROM playback_sessions WHERE playback_sessions.id = $1 AND user_id = $2)
+`
+
+type GetSessionVideoParams struct {
+	SessionID uuid.UUID `db:"session_id"`
+	UserID    uuid.UUID `db:"user_id"`
+}
+
+func (q *Queries) GetSessionVideo(ctx context.Context, arg GetSessionVideoParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, GetSessionVideo, arg.SessionID, arg.UserID)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const GetVideo = `-- name: GetVideo :one
+SELECT id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id FROM videos WHERE id = $1
+`
+
+func (q *Queries) GetVideo(ctx context.Context, id uuid.UUID) (Video, error) {
+	row := q.db.QueryRowContext(ctx, GetVideo, id)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const GetVideoAdBreaks = `-- name: GetVideoAdBreaks :one
+SELECT organization_id, ad_breaks FROM videos WHERE id = $1
+`
+
+type GetVideoAdBreaksRow struct {
+	OrganizationID uuid.UUID       `db:"organization_id"`
+	AdBreaks       models.AdBreaks `db:"ad_breaks"`
+}
+
+func (q *Queries) GetVideoAdBreaks(ctx context.Context, id uuid.UUID) (GetVideoAdBreaksRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoAdBreaks, id)
+	var i GetVideoAdBreaksRow
+	err := row.Scan(&i.OrganizationID, &i.AdBreaks)
+	return i, err
+}
+
+const GetVideoByExternalID = `-- name: GetVideoByExternalID :one
+SELECT id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id FROM videos WHERE organization_id = $1 AND external_id = $2
+`
+
+type GetVideoByExternalIDParams struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	ExternalID     *string   `db:"external_id"`
+}
+
+func (q *Queries) GetVideoByExternalID(ctx context.Context, arg GetVideoByExternalIDParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoByExternalID, arg.OrganizationID, arg.ExternalID)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const GetVideoColorState = `-- name: GetVideoColorState :one
+SELECT source_key, source_revision, color FROM videos WHERE id = $1
+`
+
+type GetVideoColorStateRow struct {
+	SourceKey      *string           `db:"source_key"`
+	SourceRevision int               `db:"source_revision"`
+	Color          *models.ColorInfo `db:"color"`
+}
+
+func (q *Queries) GetVideoColorState(ctx context.Context, id uuid.UUID) (GetVideoColorStateRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoColorState, id)
+	var i GetVideoColorStateRow
+	err := row.Scan(&i.SourceKey, &i.SourceRevision, &i.Color)
+	return i, err
+}
+
+const GetVideoImportState = `-- name: GetVideoImportState :one
+SELECT status, COALESCE(source_key, '')::text AS source_key FROM videos WHERE id = $1
+`
+
+type GetVideoImportStateRow struct {
+	Status    string `db:"status"`
+	SourceKey string `db:"source_key"`
+}
+
+func (q *Queries) GetVideoImportState(ctx context.Context, id uuid.UUID) (GetVideoImportStateRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoImportState, id)
+	var i GetVideoImportStateRow
+	err := row.Scan(&i.Status, &i.SourceKey)
+	return i, err
+}
+
+const GetVideoOrganization = `-- name: GetVideoOrganization :one
+SELECT organization_id FROM videos WHERE id = $1
+`
+
+func (q *Queries) GetVideoOrganization(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoOrganization, id)
+	var organization_id uuid.UUID
+	err := row.Scan(&organization_id)
+	return organization_id, err
+}
+
+const GetVideoOwner = `-- name: GetVideoOwner :one
+SELECT organization_id, title, created_by FROM videos WHERE id = $1
+`
+
+type GetVideoOwnerRow struct {
+	OrganizationID uuid.UUID  `db:"organization_id"`
+	Title          string     `db:"title"`
+	CreatedBy      *uuid.UUID `db:"created_by"`
+}
+
+func (q *Queries) GetVideoOwner(ctx context.Context, id uuid.UUID) (GetVideoOwnerRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoOwner, id)
+	var i GetVideoOwnerRow
+	err := row.Scan(&i.OrganizationID, &i.Title, &i.CreatedBy)
+	return i, err
+}
+
+const GetVideoSource = `-- name: GetVideoSource :one
+SELECT organization_id, source_key FROM videos WHERE id = $1
+`
+
+type GetVideoSourceRow struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	SourceKey      *string   `db:"source_key"`
+}
+
+func (q *Queries) GetVideoSource(ctx context.Context, id uuid.UUID) (GetVideoSourceRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoSource, id)
+	var i GetVideoSourceRow
+	err := row.Scan(&i.OrganizationID, &i.SourceKey)
+	return i, err
+}
+
+const GetVideoSourceRevision = `-- name: GetVideoSourceRevision :one
+SELECT source_revision FROM videos WHERE id = $1
+`
+
+func (q *Queries) GetVideoSourceRevision(ctx context.Context, id uuid.UUID) (int, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoSourceRevision, id)
+	var source_revision int
+	err := row.Scan(&source_revision)
+	return source_revision, err
+}
+
+const GetVideoStatus = `-- name: GetVideoStatus :one
+SELECT status, status_changed_at FROM videos WHERE id = $1
+`
+
+type GetVideoStatusRow struct {
+	Status          string    `db:"status"`
+	StatusChangedAt time.Time `db:"status_changed_at"`
+}
+
+func (q *Queries) GetVideoStatus(ctx context.Context, id uuid.UUID) (GetVideoStatusRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoStatus, id)
+	var i GetVideoStatusRow
+	err := row.Scan(&i.Status, &i.StatusChangedAt)
+	return i, err
+}
+
+const GetVideoTitle = `-- name: GetVideoTitle :one
+SELECT title FROM videos WHERE id = $1
+`
+
+func (q *Queries) GetVideoTitle(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoTitle, id)
+	var title string
+	err := row.Scan(&title)
+	return title, err
+}
+
+const GetVideoTranscodeSource = `-- name: GetVideoTranscodeSource :one
+SELECT organization_id, source_key, source_revision FROM videos WHERE id = $1
+`
+
+type GetVideoTranscodeSourceRow struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	SourceKey      *string   `db:"source_key"`
+	SourceRevision int       `db:"source_revision"`
+}
+
+func (q *Queries) GetVideoTranscodeSource(ctx context.Context, id uuid.UUID) (GetVideoTranscodeSourceRow, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoTranscodeSource, id)
+	var i GetVideoTranscodeSourceRow
+	err := row.Scan(&i.OrganizationID, &i.SourceKey, &i.SourceRevision)
+	return i, err
+}
+
+const GetVideoVisibility = `-- name: GetVideoVisibility :one
+SELECT visibility FROM videos WHERE id = $1
+`
+
+func (q *Queries) GetVideoVisibility(ctx context.Context, id uuid.UUID) (string, error) {
+	row := q.db.QueryRowContext(ctx, GetVideoVisibility, id)
+	var visibility string
+	err := row.Scan(&visibility)
+	return visibility, err
+}
+
+const ListPublicProjectVideos = `-- name: ListPublicProjectVideos :many
+SELECT id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id FROM videos
+WHERE project_id = $1::uuid AND visibility = $2 AND status = ANY($3::text[])
+    AND moderation_status = $4 AND review_status = $5 AND NOT requires_entitlement AND COALESCE(source_key, '') <> ''
+ORDER BY created_at DESC
+LIMIT $6
+`
+
+type ListPublicProjectVideosParams struct {
+	ProjectID        uuid.UUID `db:"project_id"`
+	Visibility       string    `db:"visibility"`
+	Statuses         []string  `db:"statuses"`
+	ModerationStatus string    `db:"moderation_status"`
+	ReviewStatus     string    `db:"review_status"`
+	MaxVideos        int32     `db:"max_videos"`
+}
+
+func (q *Queries) ListPublicProjectVideos(ctx context.Context, arg ListPublicProjectVideosParams) ([]Video, error) {
+	rows, err := q.db.QueryContext(ctx, ListPublicProjectVideos,
+		arg.ProjectID,
+		arg.Visibility,
+		pq.Array(arg.Statuses),
+		arg.ModerationStatus,
+		arg.ReviewStatus,
+		arg.MaxVideos,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Video
+	for rows.Next() {
+		var i Video
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrganizationID,
+			&i.ProjectID,
+			&i.Title,
+			&i.Description,
+			&i.Status,
+			&i.SourceKey,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SHA256,
+			&i.DuplicateOf,
+			&i.Version,
+			&i.Visibility,
+			pq.Array(&i.Tags),
+			&i.SourceRevision,
+			&i.ReplacedAt,
+			&i.Thumbnail,
+			&i.ModerationStatus,
+			&i.Chapters,
+			&i.AdBreaks,
+			&i.RequiresEntitlement,
+			&i.StatusChangedAt,
+			&i.Color,
+			&i.DownloadsEnabled,
+			&i.ReviewStatus,
+			&i.CustomFields,
+			&i.ExternalID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListTrendingVideos = `-- name: ListTrendingVideos :many
+SELECT videos.id, videos.organization_id, videos.project_id, videos.title, videos.description, videos.status, videos.source_key, videos.content_type, videos.size_bytes, videos.created_by, videos.created_at, videos.updated_at, videos.sha256, videos.duplicate_of, videos.version, videos.visibility, videos.tags, videos.source_revision, videos.replaced_at, videos.thumbnail, videos.moderation_status, videos.chapters, videos.ad_breaks, videos.requires_entitlement, videos.status_changed_at, videos.color, videos.downloads_enabled, videos.review_status, videos.custom_fields, videos.external_id, t.plays_24h, t.plays_7d, t.score, t.computed_at
+FROM videos
+JOIN (SELECT video_id, plays_24h, plays_7d, score, computed_at FROM video_trending) t ON t.video_id = videos.id
+WHERE status = $1
+ORDER BY t.score DESC
+LIMIT $2
+`
+
+type ListTrendingVideosParams struct {
+	Status string `db:"status"`
+	Limit  int32  `db:"limit"`
+}
+
+type ListTrendingVideosRow struct {
+	Video      Video     `db:"video"`
+	Plays24h   int64     `db:"plays_24h"`
+	Plays7d    int64     `db:"plays_7d"`
+	Score      float64   `db:"score"`
+	ComputedAt time.Time `db:"computed_at"`
+}
+
+func (q *Queries) ListTrendingVideos(ctx context.Context, arg ListTrendingVideosParams) ([]ListTrendingVideosRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListTrendingVideos, arg.Status, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTrendingVideosRow
+	for rows.Next() {
+		var i ListTrendingVideosRow
+		if err := rows.Scan(
+			&i.Video.ID,
+			&i.Video.OrganizationID,
+			&i.Video.ProjectID,
+			&i.Video.Title,
+			&i.Video.Description,
+			&i.Video.Status,
+			&i.Video.SourceKey,
+			&i.Video.ContentType,
+			&i.Video.SizeBytes,
+			&i.Video.CreatedBy,
+			&i.Video.CreatedAt,
+			&i.Video.UpdatedAt,
+			&i.Video.SHA256,
+			&i.Video.DuplicateOf,
+			&i.Video.Version,
+			&i.Video.Visibility,
+			pq.Array(&i.Video.Tags),
+			&i.Video.SourceRevision,
+			&i.Video.ReplacedAt,
+			&i.Video.Thumbnail,
+			&i.Video.ModerationStatus,
+			&i.Video.Chapters,
+			&i.Video.AdBreaks,
+			&i.Video.RequiresEntitlement,
+			&i.Video.StatusChangedAt,
+			&i.Video.Color,
+			&i.Video.DownloadsEnabled,
+			&i.Video.ReviewStatus,
+			&i.Video.CustomFields,
+			&i.Video.ExternalID,
+			&i.Plays24h,
+			&i.Plays7d,
+			&i.Score,
+			&i.ComputedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListUnhashedVideoIDs = `-- name: ListUnhashedVideoIDs :many
+SELECT id FROM videos
+WHERE status = ANY($1::text[]) AND sha256 IS NULL AND source_key IS NOT NULL
+ORDER BY created_at
+LIMIT $2
+`
+
+type ListUnhashedVideoIDsParams struct {
+	Statuses  []string `db:"statuses"`
+	MaxVideos int32    `db:"max_videos"`
+}
+
+func (q *Queries) ListUnhashedVideoIDs(ctx context.Context, arg ListUnhashedVideoIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, ListUnhashedVideoIDs, pq.Array(arg.Statuses), arg.MaxVideos)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListVideoOrganizations = `-- name: ListVideoOrganizations :many
+SELECT id, organization_id FROM videos WHERE id = ANY($1::uuid[])
+`
+
+type ListVideoOrganizationsRow struct {
+	ID             uuid.UUID `db:"id"`
+	OrganizationID uuid.UUID `db:"organization_id"`
+}
+
+func (q *Queries) ListVideoOrganizations(ctx context.Context, ids []uuid.UUID) ([]ListVideoOrganizationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListVideoOrganizations, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListVideoOrganizationsRow
+	for rows.Next() {
+		var i ListVideoOrganizationsRow
+		if err := rows.Scan(&i.ID, &i.OrganizationID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListVideoSourcesInOrganization = `-- name: ListVideoSourcesInOrganization :many
+SELECT id, COALESCE(source_key, '')::text AS source_key FROM videos WHERE organization_id = $1 ORDER BY id LIMIT $2
+`
+
+type ListVideoSourcesInOrganizationParams struct {
+	OrganizationID uuid.UUID `db:"organization_id"`
+	Limit          int32     `db:"limit"`
+}
+
+type ListVideoSourcesInOrganizationRow struct {
+	ID        uuid.UUID `db:"id"`
+	SourceKey string    `db:"source_key"`
+}
+
+func (q *Queries) ListVideoSourcesInOrganization(ctx context.Context, arg ListVideoSourcesInOrganizationParams) ([]ListVideoSourcesInOrganizationRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListVideoSourcesInOrganization, arg.OrganizationID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListVideoSourcesInOrganizationRow
+	for rows.Next() {
+		var i ListVideoSourcesInOrganizationRow
+		if err := rows.Scan(&i.ID, &i.SourceKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockThumbnail = `-- name: LockThumbnail :one
+SELECT thumbnail FROM videos WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockThumbnail(ctx context.Context, id uuid.UUID) (*models.Image, error) {
+	row := q.db.QueryRowContext(ctx, LockThumbnail, id)
+	var thumbnail *models.Image
+	err := row.Scan(&thumbnail)
+	return thumbnail, err
+}
+
+const LockVideoReviewStatus = `-- name: LockVideoReviewStatus :one
+SELECT review_status FROM videos WHERE id = $1 AND status <> $2 FOR UPDATE
+`
+
+type LockVideoReviewStatusParams struct {
+	ID             uuid.UUID `db:"id"`
+	ExcludedStatus string    `db:"excluded_status"`
+}
+
+func (q *Queries) LockVideoReviewStatus(ctx context.Context, arg LockVideoReviewStatusParams) (string, error) {
+	row := q.db.QueryRowContext(ctx, LockVideoReviewStatus, arg.ID, arg.ExcludedStatus)
+	var review_status string
+	err := row.Scan(&review_status)
+	return review_status, err
+}
+
+const LockVideoSourceRevision = `-- name: LockVideoSourceRevision :one
+SELECT source_revision FROM videos WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) LockVideoSourceRevision(ctx context.Context, id uuid.UUID) (int, error) {
+	row := q.db.QueryRowContext(ctx, LockVideoSourceRevision, id)
+	var source_revision int
+	err := row.Scan(&source_revision)
+	return source_revision, err
+}
+
+const LockVideoStatus = `-- name: LockVideoStatus :one
+SELECT status, organization_id FROM videos WHERE id = $1 FOR UPDATE
+`
+
+type LockVideoStatusRow struct {
+	Status         string    `db:"status"`
+	OrganizationID uuid.UUID `db:"organization_id"`
+}
+
+func (q *Queries) LockVideoStatus(ctx context.Context, id uuid.UUID) (LockVideoStatusRow, error) {
+	row := q.db.QueryRowContext(ctx, LockVideoStatus, id)
+	var i LockVideoStatusRow
+	err := row.Scan(&i.Status, &i.OrganizationID)
+	return i, err
+}
+
+const LockVideoTags = `-- name: LockVideoTags :many
+SELECT id, tags FROM videos
+WHERE id = ANY($1::uuid[]) AND organization_id = $2
+ORDER BY id
+FOR UPDATE
+`
+
+type LockVideoTagsParams struct {
+	Ids            []uuid.UUID `db:"ids"`
+	OrganizationID uuid.UUID   `db:"organization_id"`
+}
+
+type LockVideoTagsRow struct {
+	ID   uuid.UUID `db:"id"`
+	Tags []string  `db:"tags"`
+}
+
+func (q *Queries) LockVideoTags(ctx context.Context, arg LockVideoTagsParams) ([]LockVideoTagsRow, error) {
+	rows, err := q.db.QueryContext(ctx, LockVideoTags, pq.Array(arg.Ids), arg.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LockVideoTagsRow
+	for rows.Next() {
+		var i LockVideoTagsRow
+		if err := rows.Scan(&i.ID, pq.Array(&i.Tags)); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const OrganizationHasContent = `-- name: OrganizationHasContent :one
+SELECT (EXISTS (SELECT 1 FROM videos WHERE organization_id = $1::uuid)
+    OR EXISTS (SELECT 1 FROM jobs WHERE organization_id = $1::uuid AND status IN ('queued', 'running')))::boolean AS has_content
+`
+
+func (q *Queries) OrganizationHasContent(ctx context.Context, organizationID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, OrganizationHasContent, organizationID)
+	var has_content bool
+	err := row.Scan(&has_content)
+	return has_content, err
+}
+
+const RemoveCustomFieldValues = `-- name: RemoveCustomFieldValues :exec
+UPDATE videos SET custom_fields = custom_fields - $1::text
+WHERE organization_id = $2 AND custom_fields ? $1::text
+`
+
+type RemoveCustomFieldValuesParams struct {
+	Key            string    `db:"key"`
+	OrganizationID uuid.UUID `db:"organization_id"`
+}
+
+func (q *Queries) RemoveCustomFieldValues(ctx context.Context, arg RemoveCustomFieldValuesParams) error {
+	_, err := q.db.ExecContext(ctx, RemoveCustomFieldValues, arg.Key, arg.OrganizationID)
+	return err
+}
+
+const ReplaceVideoSource = `-- name: ReplaceVideoSource :one
+UPDATE videos
+SET source_key = $1::text, content_type = NULLIF($2::text, ''), size_bytes = $3::bigint,
+    sha256 = NULL, duplicate_of = NULL, color = NULL,
+    source_revision = source_revision + 1, replaced_at = NOW(), version = version + 1
+WHERE id = $4 AND COALESCE(source_key, '') = $5::text
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type ReplaceVideoSourceParams struct {
+	NewKey      string    `db:"new_key"`
+	ContentType string    `db:"content_type"`
+	SizeBytes   int64     `db:"size_bytes"`
+	ID          uuid.UUID `db:"id"`
+	OldKey      string    `db:"old_key"`
+}
+
+func (q *Queries) ReplaceVideoSource(ctx context.Context, arg ReplaceVideoSourceParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, ReplaceVideoSource,
+		arg.NewKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.ID,
+		arg.OldKey,
+	)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const RestrictReportedVideo = `-- name: RestrictReportedVideo :execrows
+UPDATE videos SET moderation_status = $1
+WHERE videos.id = $2::uuid AND moderation_status = $3
+    AND (SELECT COUNT(*) FROM abuse_reports WHERE video_id = $2::uuid AND abuse_reports.status = 'open') >= $4::bigint
+`
+
+type RestrictReportedVideoParams struct {
+	ModerationStatus string    `db:"moderation_status"`
+	ID               uuid.UUID `db:"id"`
+	FromStatus       string    `db:"from_status"`
+	Threshold        int64     `db:"threshold"`
+}
+
+func (q *Queries) RestrictReportedVideo(ctx context.Context, arg RestrictReportedVideoParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, RestrictReportedVideo,
+		arg.ModerationStatus,
+		arg.ID,
+		arg.FromStatus,
+		arg.Threshold,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ReuseVideoSource = `-- name: ReuseVideoSource :exec
+UPDATE videos
+SET sha256 = $1::text, size_bytes = $2::bigint, duplicate_of = $3::uuid,
+    source_key = $4::text
+WHERE id = $5
+`
+
+type ReuseVideoSourceParams struct {
+	SHA256      string    `db:"sha256"`
+	SizeBytes   int64     `db:"size_bytes"`
+	DuplicateOf uuid.UUID `db:"duplicate_of"`
+	SourceKey   string    `db:"source_key"`
+	ID          uuid.UUID `db:"id"`
+}
+
+func (q *Queries) ReuseVideoSource(ctx context.Context, arg ReuseVideoSourceParams) error {
+	_, err := q.db.ExecContext(ctx, ReuseVideoSource,
+		arg.SHA256,
+		arg.SizeBytes,
+		arg.DuplicateOf,
+		arg.SourceKey,
+		arg.ID,
+	)
+	return err
+}
+
+const RollbackVideoSource = `-- name: RollbackVideoSource :one
+UPDATE videos
+SET source_key = $1::text, content_type = NULLIF($2::text, ''), size_bytes = $3::bigint,
+    sha256 = $4, duplicate_of = NULL, color = $5,
+    source_revision = source_revision + 1, replaced_at = NOW(), version = version + 1
+WHERE id = $6
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type RollbackVideoSourceParams struct {
+	SourceKey   string            `db:"source_key"`
+	ContentType string            `db:"content_type"`
+	SizeBytes   int64             `db:"size_bytes"`
+	SHA256      *string           `db:"sha256"`
+	Color       *models.ColorInfo `db:"color"`
+	ID          uuid.UUID         `db:"id"`
+}
+
+func (q *Queries) RollbackVideoSource(ctx context.Context, arg RollbackVideoSourceParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, RollbackVideoSource,
+		arg.SourceKey,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.SHA256,
+		arg.Color,
+		arg.ID,
+	)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const SetAdBreaks = `-- name: SetAdBreaks :one
+UPDATE videos SET ad_breaks = $2, version = version + 1 WHERE id = $1
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type SetAdBreaksParams struct {
+	ID       uuid.UUID       `db:"id"`
+	AdBreaks models.AdBreaks `db:"ad_breaks"`
+}
+
+func (q *Queries) SetAdBreaks(ctx context.Context, arg SetAdBreaksParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, SetAdBreaks, arg.ID, arg.AdBreaks)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const SetChapters = `-- name: SetChapters :one
+UPDATE videos SET chapters = $2, version = version + 1 WHERE id = $1
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type SetChaptersParams struct {
+	ID       uuid.UUID       `db:"id"`
+	Chapters models.Chapters `db:"chapters"`
+}
+
+func (q *Queries) SetChapters(ctx context.Context, arg SetChaptersParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, SetChapters, arg.ID, arg.Chapters)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const SetModerationStatus = `-- name: SetModerationStatus :exec
+UPDATE videos SET moderation_status = $2 WHERE id = $1
+`
+
+type SetModerationStatusParams struct {
+	ID               uuid.UUID `db:"id"`
+	ModerationStatus string    `db:"moderation_status"`
+}
+
+func (q *Queries) SetModerationStatus(ctx context.Context, arg SetModerationStatusParams) error {
+	_, err := q.db.ExecContext(ctx, SetModerationStatus, arg.ID, arg.ModerationStatus)
+	return err
+}
+
+const SetThumbnail = `-- name: SetThumbnail :one
+UPDATE videos SET thumbnail = $1, version = version + 1 WHERE id = $2
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type SetThumbnailParams struct {
+	Thumbnail *models.Image `db:"thumbnail"`
+	ID        uuid.UUID     `db:"id"`
+}
+
+func (q *Queries) SetThumbnail(ctx context.Context, arg SetThumbnailParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, SetThumbnail, arg.Thumbnail, arg.ID)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const SetVideoColor = `-- name: SetVideoColor :exec
+UPDATE videos SET color = $3 WHERE id = $1 AND source_revision = $2
+`
+
+type SetVideoColorParams struct {
+	ID             uuid.UUID         `db:"id"`
+	SourceRevision int               `db:"source_revision"`
+	Color          *models.ColorInfo `db:"color"`
+}
+
+func (q *Queries) SetVideoColor(ctx context.Context, arg SetVideoColorParams) error {
+	_, err := q.db.ExecContext(ctx, SetVideoColor, arg.ID, arg.SourceRevision, arg.Color)
+	return err
+}
+
+const SetVideoDuplicate = `-- name: SetVideoDuplicate :exec
+UPDATE videos SET sha256 = $1::text, size_bytes = $2::bigint, duplicate_of = $3::uuid
+WHERE id = $4
+`
+
+type SetVideoDuplicateParams struct {
+	SHA256      string    `db:"sha256"`
+	SizeBytes   int64     `db:"size_bytes"`
+	DuplicateOf uuid.UUID `db:"duplicate_of"`
+	ID          uuid.UUID `db:"id"`
+}
+
+func (q *Queries) SetVideoDuplicate(ctx context.Context, arg SetVideoDuplicateParams) error {
+	_, err := q.db.ExecContext(ctx, SetVideoDuplicate,
+		arg.SHA256,
+		arg.SizeBytes,
+		arg.DuplicateOf,
+		arg.ID,
+	)
+	return err
+}
+
+const SetVideoHash = `-- name: SetVideoHash :exec
+UPDATE videos SET sha256 = $1::text, size_bytes = $2::bigint WHERE id = $3
+`
+
+type SetVideoHashParams struct {
+	SHA256    string    `db:"sha256"`
+	SizeBytes int64     `db:"size_bytes"`
+	ID        uuid.UUID `db:"id"`
+}
+
+func (q *Queries) SetVideoHash(ctx context.Context, arg SetVideoHashParams) error {
+	_, err := q.db.ExecContext(ctx, SetVideoHash, arg.SHA256, arg.SizeBytes, arg.ID)
+	return err
+}
+
+const SetVideoImported = `-- name: SetVideoImported :exec
+UPDATE videos SET content_type = $1::text, size_bytes = $2::bigint WHERE id = $3
+`
+
+type SetVideoImportedParams struct {
+	ContentType string    `db:"content_type"`
+	SizeBytes   int64     `db:"size_bytes"`
+	ID          uuid.UUID `db:"id"`
+}
+
+func (q *Queries) SetVideoImported(ctx context.Context, arg SetVideoImportedParams) error {
+	_, err := q.db.ExecContext(ctx, SetVideoImported, arg.ContentType, arg.SizeBytes, arg.ID)
+	return err
+}
+
+const SetVideoReviewStatus = `-- name: SetVideoReviewStatus :exec
+UPDATE videos SET review_status = $2 WHERE id = $1
+`
+
+type SetVideoReviewStatusParams struct {
+	ID           uuid.UUID `db:"id"`
+	ReviewStatus string    `db:"review_status"`
+}
+
+func (q *Queries) SetVideoReviewStatus(ctx context.Context, arg SetVideoReviewStatusParams) error {
+	_, err := q.db.ExecContext(ctx, SetVideoReviewStatus, arg.ID, arg.ReviewStatus)
+	return err
+}
+
+const SetVideoStatus = `-- name: SetVideoStatus :exec
+UPDATE videos SET status = $2, status_changed_at = NOW() WHERE id = $1
+`
+
+type SetVideoStatusParams struct {
+	ID     uuid.UUID `db:"id"`
+	Status string    `db:"status"`
+}
+
+func (q *Queries) SetVideoStatus(ctx context.Context, arg SetVideoStatusParams) error {
+	_, err := q.db.ExecContext(ctx, SetVideoStatus, arg.ID, arg.Status)
+	return err
+}
+
+const SourceKeyInUse = `-- name: SourceKeyInUse :one
+SELECT (EXISTS (SELECT 1 FROM videos WHERE source_key = $1::text)
+    OR EXISTS (SELECT 1 FROM video_source_versions WHERE source_key = $1::text))::boolean AS used
+`
+
+func (q *Queries) SourceKeyInUse(ctx context.Context, sourceKey string) (bool, error) {
+	row := q.db.QueryRowContext(ctx, SourceKeyInUse, sourceKey)
+	var used bool
+	err := row.Scan(&used)
+	return used, err
+}
+
+const UpdateVideo = `-- name: UpdateVideo :one
+UPDATE videos
+SET title = COALESCE($1, title), description = COALESCE($2, description),
+    visibility = COALESCE($3, visibility), tags = COALESCE($4::text[], tags),
+    requires_entitlement = COALESCE($5, requires_entitlement),
+    downloads_enabled = COALESCE($6, downloads_enabled),
+    custom_fields = (custom_fields || $7) - $8::text[],
+    external_id = CASE WHEN $9::text IS NULL THEN external_id ELSE NULLIF($9::text, '') END,
+    version = version + 1
+WHERE id = $10 AND version = $11
+RETURNING id, organization_id, project_id, title, description, status, source_key, content_type, size_bytes, created_by, created_at, updated_at, sha256, duplicate_of, version, visibility, tags, source_revision, replaced_at, thumbnail, moderation_status, chapters, ad_breaks, requires_entitlement, status_changed_at, color, downloads_enabled, review_status, custom_fields, external_id
+`
+
+type UpdateVideoParams struct {
+	Title               *string                  `db:"title"`
+	Description         *string                  `db:"description"`
+	Visibility          *string                  `db:"visibility"`
+	Tags                []string                 `db:"tags"`
+	RequiresEntitlement *bool                    `db:"requires_entitlement"`
+	DownloadsEnabled    *bool                    `db:"downloads_enabled"`
+	SetFields           models.CustomFieldValues `db:"set_fields"`
+	UnsetFields         []string                 `db:"unset_fields"`
+	ExternalID          *string                  `db:"external_id"`
+	ID                  uuid.UUID                `db:"id"`
+	Version             int64                    `db:"version"`
+}
+
+func (q *Queries) UpdateVideo(ctx context.Context, arg UpdateVideoParams) (Video, error) {
+	row := q.db.QueryRowContext(ctx, UpdateVideo,
+		arg.Title,
+		arg.Description,
+		arg.Visibility,
+		pq.Array(arg.Tags),
+		arg.RequiresEntitlement,
+		arg.DownloadsEnabled,
+		arg.SetFields,
+		pq.Array(arg.UnsetFields),
+		arg.ExternalID,
+		arg.ID,
+		arg.Version,
+	)
+	var i Video
+	err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.ProjectID,
+		&i.Title,
+		&i.Description,
+		&i.Status,
+		&i.SourceKey,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SHA256,
+		&i.DuplicateOf,
+		&i.Version,
+		&i.Visibility,
+		pq.Array(&i.Tags),
+		&i.SourceRevision,
+		&i.ReplacedAt,
+		&i.Thumbnail,
+		&i.ModerationStatus,
+		&i.Chapters,
+		&i.AdBreaks,
+		&i.RequiresEntitlement,
+		&i.StatusChangedAt,
+		&i.Color,
+		&i.DownloadsEnabled,
+		&i.ReviewStatus,
+		&i.CustomFields,
+		&i.ExternalID,
+	)
+	return i, err
+}
+
+const VideoAtRevision = `-- name: VideoAtRevision :one
+SELECT EXISTS (SELECT 1 FROM videos WHERE id = $1 AND source_revision = $2)
+`
+
+type VideoAtRevisionParams struct {
+	ID             uuid.UUID `db:"id"`
+	SourceRevision int       `db:"source_revision"`
+}
+
+func (q *Queries) VideoAtRevision(ctx context.Context, arg VideoAtRevisionParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, VideoAtRevision, arg.ID, arg.SourceRevision)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const VideoExists = `-- name: VideoExists :one
+SELECT EXISTS (SELECT 1 FROM videos WHERE id = $1)
+`
+
+func (q *Queries) VideoExists(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, VideoExists, id)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const VideoExistsInOrganization = `-- name: VideoExistsInOrganization :one
+SELECT EXISTS (SELECT 1 FROM videos WHERE id = $1 AND organization_id = $2)
+`
+
+type VideoExistsInOrganizationParams struct {
+	ID             uuid.UUID `db:"id"`
+	OrganizationID uuid.UUID `db:"organization_id"`
+}
+
+func (q *Queries) VideoExistsInOrganization(ctx context.Context, arg VideoExistsInOrganizationParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, VideoExistsInOrganization, arg.ID, arg.OrganizationID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,102 @@
+// Package ratelimit counts requests per caller and tier in fixed windows kept in Redis, so the
+// limits hold across instances.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/metrics"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var limited = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Name:      "rate_limited_total",
+	Help:      "Requests refused for exceeding the limit of their rate limit tier, by tier",
+}, []string{"tier"})
+
+// Limit allows Requests per Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Decision is the outcome of counting a request
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the window ends
+	RetryAfter time.Duration
+}
+
+// Limiter applies the limits of the tiers it was given
+type Limiter struct {
+	redis  *redis.Client
+	limits map[string]Limit
+}
+
+// New creates a limiter from tier=requests/period entries, such as api=600/1m. Tiers without
+// an entry are not limited, and without Redis nothing is.
+func New(redisClient *redis.Client, entries []string) (*Limiter, error) {
+	limits, err := parseLimits(entries)
+	if err != nil {
+		return nil, err
+	}
+	return &Limiter{redis: redisClient, limits: limits}, nil
+}
+
+func parseLimits(entries []string) (map[string]Limit, error) {
+	limits := make(map[string]Limit, len(entries))
+	for _, entry := range entries {
+		tier, value, ok := strings.Cut(entry, "=")
+		requests, period, ok2 := strings.Cut(value, "/")
+		n, err := strconv.Atoi(strings.TrimSpace(requests))
+		d, err2 := time.ParseDuration(strings.TrimSpace(period))
+		tier = strings.TrimSpace(tier)
+		if !ok || !ok2 || tier == "" || err != nil || err2 != nil || n <= 0 || d < time.Second {
+			return nil, fmt.Errorf("invalid rate limit %q, expected tier=requests/period such as api=600/1m", entry)
+		}
+		limits[tier] = Limit{Requests: n, Period: d}
+	}
+	return limits, nil
+}
+
+// Allow counts a request of caller in tier. Requests are allowed when the tier has no limit,
+// and when Redis fails, as refusing every request would be worse than not limiting them.
+func (l *Limiter) Allow(ctx context.Context, tier, caller string) Decision {
+	limit, ok := l.limits[tier]
+	if !ok || l.redis == nil {
+		return Decision{Allowed: true}
+	}
+
+	now := time.Now()
+	window := now.Truncate(limit.Period)
+	key := "ratelimit:" + tier + ":" + caller + ":" + strconv.FormatInt(window.Unix(), 10)
+	pipe := l.redis.Pipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, limit.Period)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("WARN: Failed to count request for rate limit tier %s, not limiting: %v", tier, err)
+		return Decision{Allowed: true}
+	}
+
+	d := Decision{
+		Allowed:    count.Val() <= int64(limit.Requests),
+		Limit:      limit.Requests,
+		Remaining:  max(limit.Requests-int(count.Val()), 0),
+		RetryAfter: window.Add(limit.Period).Sub(now),
+	}
+	if !d.Allowed {
+		limited.WithLabelValues(tier).Inc()
+	}
+	return d
+}
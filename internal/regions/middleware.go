@@ -0,0 +1,66 @@
+package regions
+
+import (
+	"net/http"
+
+	"openvdo/internal/database"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Middleware moves requests for organizations pinned to another region onto a tenant
+// connection to that region's database. It runs after StatelessDatabaseMiddleware, which has
+// already resolved the organization and role from the memberships in the local database.
+func (r *Router) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantDB, ok := database.GetStatelessTenantDBFromContext(c)
+		if !ok || !r.Regional() || tenantDB.GetOrganizationID() == uuid.Nil {
+			c.Next()
+			return
+		}
+
+		region, err := r.RegionOf(c.Request.Context(), tenantDB.GetOrganizationID())
+		if err != nil {
+			logger.Error("Failed to resolve region of organization %s: %v", tenantDB.GetOrganizationID(), err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Organization region unavailable"})
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Set("X-Region", region)
+		if region == r.local {
+			c.Next()
+			return
+		}
+
+		pool, err := r.Pool(region)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Organization region unavailable"})
+			c.Abort()
+			return
+		}
+		regionalDB, err := pool.NewTenantDBFor(c.Request.Context(), database.TenantContext{
+			UserID: tenantDB.GetUserID(),
+			OrgID:  tenantDB.GetOrganizationID(),
+			Role:   tenantDB.GetRole(),
+		})
+		if err != nil {
+			logger.Error("Failed to connect to region %s: %v", region, err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Organization region unavailable"})
+			c.Abort()
+			return
+		}
+
+		// The local connection is not needed any more; StatelessDatabaseMiddleware's release of
+		// it afterwards is a no-op
+		tenantDB.Release()
+		c.Set(string(database.StatelessDBKey), regionalDB)
+
+		c.Next()
+
+		if err := regionalDB.Release(); err != nil {
+			logger.Error("Failed to release connection to region %s: %v", region, err)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package regions pins organizations to regional database and storage clusters for data
+// residency. Identity data (users, organizations, memberships) lives in the home region and is
+// replicated to the others; an organization's content lives only in its region.
+package regions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// ErrUnknownRegion is returned for regions this deployment has no cluster for
+var ErrUnknownRegion = errors.New("unknown region")
+
+// ErrOrganizationNotFound is returned when looking up the region of a missing organization
+var ErrOrganizationNotFound = errors.New("organization not found")
+
+// ErrNotEmpty is returned when moving an organization that already has content; its data
+// would have to be copied between clusters, which is not done automatically
+var ErrNotEmpty = errors.New("organization already has content in its region")
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidName reports whether name can be stored as a region
+func ValidName(name string) bool {
+	return len(name) <= 50 && namePattern.MatchString(name)
+}
+
+type cachedRegion struct {
+	region    string
+	expiresAt time.Time
+}
+
+// Router holds the database pools and storage of every region and resolves which one an
+// organization's data lives in
+type Router struct {
+	// home is where organizations without a region live; local is the region of this instance,
+	// whose pools and storage are the main ones
+	home       string
+	local      string
+	localPool  *database.StatelessPoolManager
+	localStore storage.Storage
+	pools      map[string]*database.StatelessPoolManager
+	stores     map[string]storage.Storage
+	ttl        time.Duration
+
+	mu    sync.RWMutex
+	cache map[uuid.UUID]cachedRegion
+}
+
+// NewRouter connects to the database and storage of every other region. localPool and
+// localStore are those of the instance's own region and are not closed by the router.
+func NewRouter(cfg config.Regions, localPool *database.StatelessPoolManager, localStore storage.Storage, redisClient *redis.Client) (*Router, error) {
+	r := &Router{
+		home:       cfg.Home,
+		local:      cfg.LocalRegion(),
+		localPool:  localPool,
+		localStore: localStore,
+		pools:      make(map[string]*database.StatelessPoolManager, len(cfg.Clusters)),
+		stores:     make(map[string]storage.Storage, len(cfg.Clusters)),
+		ttl:        cfg.LookupTTL,
+		cache:      make(map[uuid.UUID]cachedRegion),
+	}
+
+	for name, cluster := range cfg.Clusters {
+		if !ValidName(name) {
+			r.Close()
+			return nil, fmt.Errorf("invalid region name %q: use lowercase letters, digits and dashes", name)
+		}
+		pool, err := database.NewStatelessPoolManager(cluster.Database, redisClient)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("region %s: failed to connect to database: %w", name, err)
+		}
+		r.pools[name] = pool
+
+		store, err := storage.New(cluster.Storage)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("region %s: failed to initialize storage: %w", name, err)
+		}
+		r.stores[name] = store
+	}
+	return r, nil
+}
+
+// Home returns the name of the home region
+func (r *Router) Home() string {
+	return r.home
+}
+
+// Local returns the name of the region this instance runs in
+func (r *Router) Local() string {
+	return r.local
+}
+
+// Names returns every region, the local one first and the others in alphabetical order
+func (r *Router) Names() []string {
+	names := make([]string, 0, len(r.pools))
+	for name := range r.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return append([]string{r.local}, names...)
+}
+
+// Regional reports whether any other region is configured
+func (r *Router) Regional() bool {
+	return len(r.pools) > 0
+}
+
+// Known reports whether the deployment has a cluster for region
+func (r *Router) Known(region string) bool {
+	_, ok := r.pools[region]
+	return ok || region == r.local
+}
+
+// Pool returns the database pools of a region
+func (r *Router) Pool(region string) (*database.StatelessPoolManager, error) {
+	if region == r.local {
+		return r.localPool, nil
+	}
+	if pool, ok := r.pools[region]; ok {
+		return pool, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownRegion, region)
+}
+
+// Storage returns a backend that routes each object to its organization's region. Without
+// other regions it is the local backend itself.
+func (r *Router) Storage() storage.Storage {
+	if !r.Regional() {
+		return r.localStore
+	}
+	return storage.NewRouted(r.localStore, r.stores, r.RegionOf)
+}
+
+// Stores returns the storage backends of the other regions, keyed by region
+func (r *Router) Stores() map[string]storage.Storage {
+	return r.stores
+}
+
+// RegionOf returns the region an organization's data lives in. Lookups are cached for
+// REGION_LOOKUP_TTL, which is also how long other instances may keep routing to the old
+// region after a move.
+func (r *Router) RegionOf(ctx context.Context, orgID uuid.UUID) (string, error) {
+	if !r.Regional() {
+		return r.local, nil
+	}
+
+	r.mu.RLock()
+	cached, ok := r.cache[orgID]
+	r.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.region, nil
+	}
+
+	region, err := queries.New(r.localPool.GetMasterConnection()).GetOrganizationRegion(ctx, orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrOrganizationNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	name := r.home
+	if region != nil {
+		name = *region
+	}
+	if !r.Known(name) {
+		// Falling back to home would write the organization's data outside its region
+		return "", fmt.Errorf("%w %q for organization %s", ErrUnknownRegion, name, orgID)
+	}
+
+	r.mu.Lock()
+	r.cache[orgID] = cachedRegion{region: name, expiresAt: time.Now().Add(r.ttl)}
+	r.mu.Unlock()
+	return name, nil
+}
+
+// SetRegion pins an organization to a region. Only organizations without videos can move,
+// which in practice means new ones.
+func (r *Router) SetRegion(ctx context.Context, orgID uuid.UUID, region string) error {
+	if !r.Known(region) {
+		return fmt.Errorf("%w %q", ErrUnknownRegion, region)
+	}
+
+	current, err := r.RegionOf(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if current == region {
+		return nil
+	}
+
+	pool, err := r.Pool(current)
+	if err != nil {
+		return err
+	}
+	hasContent, err := queries.New(pool.GetMasterConnection()).OrganizationHasContent(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if hasContent {
+		return ErrNotEmpty
+	}
+
+	// The home region is stored as NULL, so renaming it does not strand organizations.
+	// Organizations are written in the home region and replicate to the others.
+	home, err := r.Pool(r.home)
+	if err != nil {
+		return err
+	}
+	var stored *string
+	if region != r.home {
+		stored = &region
+	}
+	if err := queries.New(home.GetMasterConnection()).SetRegion(ctx, queries.SetRegionParams{
+		ID: orgID, Region: stored,
+	}); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	delete(r.cache, orgID)
+	r.mu.Unlock()
+	return nil
+}
+
+// Federate runs fn against the master database of every region at once, for admin queries
+// that span regions. The results are keyed by region; a failing region fails the whole query.
+func Federate[T any](ctx context.Context, r *Router, fn func(ctx context.Context, region string, db *sql.DB) (T, error)) (map[string]T, error) {
+	names := r.Names()
+	results := make([]T, len(names))
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		pool, err := r.Pool(name)
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = fn(ctx, name, pool.GetMasterConnection())
+		}()
+	}
+	wg.Wait()
+
+	out := make(map[string]T, len(names))
+	for i, name := range names {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("region %s: %w", name, errs[i])
+		}
+		out[name] = results[i]
+	}
+	return out, nil
+}
+
+// Close closes the database connections of the other regions
+func (r *Router) Close() error {
+	var errs []error
+	for _, pool := range r.pools {
+		errs = append(errs, pool.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,313 @@
+package routes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/middleware"
+	"openvdo/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Auth is who may call a route
+type Auth int
+
+const (
+	// AuthPublic routes need no credentials
+	AuthPublic Auth = iota
+	// AuthUser routes need a signed-in user, by session token or X-User-ID
+	AuthUser
+	// AuthAdmin routes need the admin token, and a client certificate when TLS_CLIENT_CA_FILE
+	// is set
+	AuthAdmin
+)
+
+// Rate limit tiers routes count against; their limits are set with RATE_LIMITS
+const (
+	TierAPI      = "api"
+	TierUpload   = "upload"
+	TierPlayback = "playback"
+)
+
+// Policy is what a route allows. A zero Timeout or MaxBodySize leaves the request unbounded,
+// and an empty RateTier unlimited. Cache is applied before the handler runs, which may replace
+// it; it must suit error responses too, so content that may be cached sets its own.
+type Policy struct {
+	Timeout     time.Duration
+	MaxBodySize int64
+	RateTier    string
+	Auth        Auth
+	Cache       cachecontrol.Policy
+}
+
+// Policies maps routes to their policy. Keys are a route template, such as
+// /api/v1/videos/:id, optionally preceded by a method; a template ending in * matches every
+// route it prefixes. The most specific key wins, and Default applies to routes no key matches.
+// API routes are listed under /api/v1 and apply under /api/v2 alike.
+type Policies struct {
+	Default Policy
+	Routes  map[string]Policy
+}
+
+// DefaultPolicies is the policy table of the routes Setup registers: tight limits for metadata
+// endpoints, long timeouts for file uploads and none for media served from storage. Routes that
+// check the size of their bodies themselves, such as image uploads, are left unbounded here.
+func DefaultPolicies(cfg *config.Config) Policies {
+	public := Policy{Timeout: cfg.Routes.Timeout, MaxBodySize: cfg.Routes.MaxBodySize}
+	metadata := cachecontrol.New(cfg).Metadata
+
+	api := public
+	api.Auth = AuthUser
+	api.RateTier = TierAPI
+	api.Cache = metadata
+
+	upload := api
+	upload.Timeout = cfg.Routes.UploadTimeout
+	upload.MaxBodySize = 0
+	upload.RateTier = TierUpload
+
+	playback := public
+	playback.RateTier = TierPlayback
+	media := playback
+	media.Timeout = 0
+
+	admin := public
+	admin.Auth = AuthAdmin
+	admin.Cache = metadata
+
+	// Bodies of notifications are checked by their handlers
+	hooks := public
+	hooks.MaxBodySize = 0
+
+	publicAPI := api
+	publicAPI.Auth = AuthPublic
+
+	beacon := playback
+	beacon.MaxBodySize = 0
+
+	drain := admin
+	drain.Timeout = 0
+
+	return Policies{
+		Default: public,
+		Routes: map[string]Policy{
+			"/api/v1/*":                                        api,
+			"POST /api/v1/beacon":                              beacon,
+			"POST /api/v1/videos/:id/reports":                  publicAPI,
+			"POST /api/v1/auth/login":                          publicAPI,
+			"GET /api/v1/push/config":                          publicAPI,
+			"PUT /api/v1/organizations/:id/banner":             upload,
+			"POST /api/v1/organizations/:id/entitlements/bulk": upload,
+			"PUT /api/v1/me/avatar":                            upload,
+			"POST /api/v1/videos/import/bulk":                  upload,
+			"PUT /api/v1/videos/:id/thumbnail":                 upload,
+			"PUT /api/v1/videos/:id/chapters":                  upload,
+			"PUT /api/v1/series/:id/artwork":                   upload,
+			"/embed/*":                                         playback,
+			"GET /embed/:id/media":                             media,
+			"/s/*":                                             playback,
+			"GET /s/:slug/download":                            media,
+			"/images/*":                                        playback,
+			"/feeds/*":                                         playback,
+			"/hooks/*":                                         hooks,
+			"/admin/v1/*":                                      admin,
+			"GET /drainz":                                      drain,
+		},
+	}
+}
+
+// policyKey holds the policy of a user route between requireUser and limitUser
+const policyKey = "route_policy"
+
+// policyRoute is a key of the table as matched
+type policyRoute struct {
+	method, route string
+	prefix        bool
+	policy        Policy
+}
+
+// policyTable resolves the policy of each route once, when the routes are registered
+type policyTable struct {
+	policies Policies
+	entries  []policyRoute
+	resolved map[string]Policy
+	limiter  *ratelimit.Limiter
+	admin    string
+	// clientCerts requires admin requests to carry a verified client certificate
+	clientCerts bool
+}
+
+func newPolicyTable(policies Policies, limiter *ratelimit.Limiter, adminToken string, clientCerts bool) (*policyTable, error) {
+	t := &policyTable{
+		policies: policies, limiter: limiter, admin: adminToken, clientCerts: clientCerts,
+		resolved: make(map[string]Policy),
+	}
+	for key, policy := range policies.Routes {
+		e := policyRoute{route: key, policy: policy}
+		if method, route, ok := strings.Cut(key, " "); ok {
+			e.method, e.route = method, route
+		}
+		if !strings.HasPrefix(e.route, "/") {
+			return nil, fmt.Errorf("invalid route policy key %q, expected [METHOD ]/route", key)
+		}
+		if strings.HasSuffix(e.route, "*") {
+			e.route, e.prefix = strings.TrimSuffix(e.route, "*"), true
+		}
+		t.entries = append(t.entries, e)
+	}
+	// Exact routes before prefixes, longer before shorter, and with a method before without
+	sort.SliceStable(t.entries, func(i, j int) bool {
+		a, b := t.entries[i], t.entries[j]
+		if a.prefix != b.prefix {
+			return !a.prefix
+		}
+		if len(a.route) != len(b.route) {
+			return len(a.route) > len(b.route)
+		}
+		return a.method > b.method
+	})
+	return t, nil
+}
+
+// resolve fills in the policies of the registered routes
+func (t *policyTable) resolve(routes gin.RoutesInfo) {
+	for _, r := range routes {
+		t.resolved[r.Method+" "+r.Path] = t.lookup(r.Method, r.Path)
+	}
+}
+
+func (t *policyTable) lookup(method, route string) Policy {
+	if rest, ok := strings.CutPrefix(route, "/api/v2/"); ok {
+		route = "/api/v1/" + rest
+	}
+	for _, e := range t.entries {
+		if e.method != "" && e.method != method {
+			continue
+		}
+		if route == e.route || (e.prefix && strings.HasPrefix(route, e.route)) {
+			return e.policy
+		}
+	}
+	return t.policies.Default
+}
+
+// policy returns the policy of the request's route; requests matching no route get the default
+func (t *policyTable) policy(c *gin.Context) Policy {
+	if p, ok := t.resolved[c.Request.Method+" "+c.FullPath()]; ok {
+		return p
+	}
+	return t.policies.Default
+}
+
+// limits bounds the time and body of requests, counts them against their rate limit tier and
+// checks the admin token of admin routes. It must run before the response writer is wrapped,
+// as it sets the connection's deadlines through it.
+func (t *policyTable) limits() gin.HandlerFunc {
+	requireAdmin := middleware.AdminAuth(t.admin)
+	return func(c *gin.Context) {
+		p := t.policy(c)
+
+		// The connection deadlines bound slow clients; the context bounds the handler's work
+		var deadline time.Time
+		if p.Timeout > 0 {
+			deadline = time.Now().Add(p.Timeout)
+			ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+		rc := http.NewResponseController(c.Writer)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+
+		if p.MaxBodySize > 0 {
+			if c.Request.ContentLength > p.MaxBodySize {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": fmt.Sprintf("Request body exceeds the limit of %d bytes", p.MaxBodySize),
+				})
+				c.Abort()
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, p.MaxBodySize)
+		}
+		p.Cache.Apply(c)
+
+		// Requests to user routes are counted per user once requireUser has identified them
+		if p.Auth != AuthUser && !t.allow(c, p.RateTier, "ip:"+c.ClientIP()) {
+			return
+		}
+
+		if p.Auth == AuthAdmin {
+			if t.clientCerts && !middleware.ClientCertificateVerified(c.Request) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "A client certificate is required"})
+				c.Abort()
+				return
+			}
+			// Calls c.Next when the token is valid
+			requireAdmin(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireUser refuses requests to AuthUser routes without a signed-in user and counts the
+// others against their rate limit tier per user. It runs after the session and database
+// middleware, which identify the user.
+func (t *policyTable) requireUser() gin.HandlerFunc {
+	requireAuth := database.StatelessRequireAuth()
+	return func(c *gin.Context) {
+		p := t.policy(c)
+		if p.Auth != AuthUser {
+			c.Next()
+			return
+		}
+		// Calls c.Next, and so limitUser, when a user is signed in
+		c.Set(policyKey, p)
+		requireAuth(c)
+	}
+}
+
+// limitUser counts requests of a signed-in user, following requireUser
+func (t *policyTable) limitUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(policyKey)
+		if !ok {
+			c.Next()
+			return
+		}
+		userID, _ := c.Get(string(database.UserIDKey))
+		if t.allow(c, value.(Policy).RateTier, fmt.Sprintf("user:%v", userID)) {
+			c.Next()
+		}
+	}
+}
+
+// allow counts the request against the tier, answering 429 and aborting when it is over the
+// limit
+func (t *policyTable) allow(c *gin.Context, tier, caller string) bool {
+	if tier == "" {
+		return true
+	}
+	d := t.limiter.Allow(c.Request.Context(), tier, caller)
+	if d.Limit > 0 {
+		c.Header("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	}
+	if !d.Allowed {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded, try again later"})
+		c.Abort()
+		return false
+	}
+	return true
+}
@@ -1,62 +1,557 @@
 package routes
 
 import (
+	"context"
+	"net/http"
+	"net/http/pprof"
+
+	"openvdo/internal/accesslog"
+	"openvdo/internal/analytics"
+	"openvdo/internal/auth"
+	"openvdo/internal/cachecontrol"
+	"openvdo/internal/config"
 	"openvdo/internal/database"
+	"openvdo/internal/egress"
+	"openvdo/internal/errortracking"
+	"openvdo/internal/faults"
+	"openvdo/internal/flags"
+	"openvdo/internal/flightrecorder"
 	"openvdo/internal/handlers"
+	"openvdo/internal/health"
+	"openvdo/internal/images"
+	"openvdo/internal/ipaccess"
+	"openvdo/internal/jobs"
+	"openvdo/internal/maintenance"
+	"openvdo/internal/metrics"
 	"openvdo/internal/middleware"
+	"openvdo/internal/moderation"
+	"openvdo/internal/openapi"
+	"openvdo/internal/ratelimit"
+	"openvdo/internal/regions"
+	"openvdo/internal/scheduler"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/internal/transcode"
+	"openvdo/internal/usage"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	_ "openvdo/docs" // swagger docs
 )
 
+// Dependencies are the services the routes are wired to
+type Dependencies struct {
+	Config      *config.Config
+	PoolManager *database.StatelessPoolManager
+	Regions     *regions.Router
+	Storage     storage.Storage
+	Lifecycle   *services.LifecycleManager
+	Hasher      *services.ContentHasher
+	Jobs        *jobs.Queue
+	Scheduler   *scheduler.Scheduler
+	Importer    *services.Importer
+	BulkImports *services.BulkImporter
+	Purger      *services.CachePurger
+	Feeds       *services.FeedGenerator
+	Playlists   *services.SmartPlaylists
+	Teardowns   *services.OrganizationTeardowns
+	Downloads   *services.Downloader
+	Images      *images.Processor
+	Push        *services.PushNotifier
+	Flags       *flags.Store
+	Maintenance *maintenance.Mode
+	Beacon      *analytics.Ingester
+	Analytics   analytics.Store
+	Usage       *usage.Recorder
+	Moderator   *moderation.Moderator
+	Scanner     *moderation.Scanner
+	Transcode   *transcode.Manager
+	Checks      *health.Registry
+	Drain       *health.Drain
+	AccessLog   *accesslog.Logger
+	Errors      *errortracking.Tracker
+	Recorder    *flightrecorder.Recorder
+	Sessions    *auth.Sessions
+	IPAccess    *ipaccess.Guard
+	// Contract checks responses against the OpenAPI document; nil leaves them unchecked
+	Contract *openapi.Contract
+	Faults   *faults.Injector
+	// Policies set the timeout, body limit, rate limit tier and authentication of each route
+	Policies Policies
+	Limiter  *ratelimit.Limiter
+	// AdminRouter and MetricsRouter serve the admin API and the metrics on listeners of their
+	// own; when nil, the router passed to Setup serves them with the public API
+	AdminRouter   *gin.Engine
+	MetricsRouter *gin.Engine
+}
+
 type Server struct {
-	router       *gin.Engine
-	poolManager  *database.StatelessPoolManager
-	redisClient  *redis.Client
+	router      *gin.Engine
+	config      *config.Config
+	poolManager *database.StatelessPoolManager
+	regions     *regions.Router
+	storage     storage.Storage
+	lifecycle   *services.LifecycleManager
+	hasher      *services.ContentHasher
+	jobs        *jobs.Queue
+	importer    *services.Importer
+	bulkImports *services.BulkImporter
+	purger      *services.CachePurger
+	feeds       *services.FeedGenerator
+	playlists   *services.SmartPlaylists
+	images      *images.Processor
+	push        *services.PushNotifier
+	flags       *flags.Store
+	maintenance *maintenance.Mode
+	beacon      *analytics.Ingester
+	analytics   analytics.Store
+	usage       *usage.Recorder
+	moderator   *moderation.Moderator
+	scanner     *moderation.Scanner
+	checks      *health.Registry
 }
 
-func Setup(router *gin.Engine, poolManager *database.StatelessPoolManager, redisClient *redis.Client) {
+func Setup(router *gin.Engine, deps Dependencies) {
 	server := &Server{
 		router:      router,
-		poolManager: poolManager,
-		redisClient: redisClient,
+		config:      deps.Config,
+		poolManager: deps.PoolManager,
+		regions:     deps.Regions,
+		storage:     deps.Storage,
+		lifecycle:   deps.Lifecycle,
+		hasher:      deps.Hasher,
+		jobs:        deps.Jobs,
+		importer:    deps.Importer,
+		bulkImports: deps.BulkImports,
+		purger:      deps.Purger,
+		feeds:       deps.Feeds,
+		playlists:   deps.Playlists,
+		images:      deps.Images,
+		push:        deps.Push,
+		flags:       deps.Flags,
+		maintenance: deps.Maintenance,
+		beacon:      deps.Beacon,
+		analytics:   deps.Analytics,
+		usage:       deps.Usage,
+		moderator:   deps.Moderator,
+		scanner:     deps.Scanner,
+		checks:      deps.Checks,
+	}
+
+	uploadHandler := handlers.NewUploadHandler(server.storage, server.config.Storage, server.hasher, server.purger)
+	sourceVersionHandler := handlers.NewSourceVersionHandler(server.config.Storage, server.purger)
+	storageObjectHandler := handlers.NewStorageObjectHandler(server.lifecycle)
+	healthHandler := handlers.NewHealthHandler(server.checks, deps.Drain)
+	importHandler := handlers.NewImportHandler(server.importer, server.bulkImports, server.jobs)
+	cache := cachecontrol.New(server.config)
+	shaper := egress.NewShaper(server.poolManager.GetMasterConnection(), server.config.Egress)
+	embedHandler := handlers.NewEmbedHandler(server.poolManager.GetMasterConnection(), server.storage,
+		server.config.Playback, server.config.Storage.PresignExpiry, server.config.Beacon.Enabled, cache, shaper)
+	feedHandler := handlers.NewFeedHandler(server.feeds, server.playlists, cache)
+	playlistHandler := handlers.NewSmartPlaylistHandler(server.playlists)
+	thumbnailHandler := handlers.NewThumbnailHandler(server.storage, server.images, server.config.Images)
+	profileImageHandler := handlers.NewProfileImageHandler(server.poolManager.GetMasterConnection(), server.storage,
+		server.images, server.config.Images, cache)
+	seriesHandler := handlers.NewSeriesHandler(server.poolManager.GetMasterConnection(), server.storage,
+		server.images, server.config.Images, cache)
+	pushDeviceHandler := handlers.NewPushDeviceHandler(server.push, server.config.Push.VAPIDPublicKey)
+	flagHandler := handlers.NewFlagHandler(server.flags)
+	flightRecorderHandler := handlers.NewFlightRecorderHandler(deps.Recorder)
+	maintenanceHandler := handlers.NewMaintenanceHandler(server.maintenance, server.config.Maintenance.RetryAfter)
+	regionHandler := handlers.NewRegionHandler(server.regions)
+	beaconHandler := handlers.NewBeaconHandler(server.poolManager.GetMasterConnection(), server.beacon, server.config.Beacon)
+	analyticsHandler := handlers.NewAnalyticsHandler(server.analytics, server.regions)
+	moderationHandler := handlers.NewModerationHandler(server.poolManager.GetMasterConnection(), server.moderator,
+		server.scanner, server.config.Playback, server.config.Moderation)
+	authHandler := handlers.NewAuthHandler(deps.Sessions)
+	ipAccessHandler := handlers.NewIPAccessHandler(deps.IPAccess)
+	encoderHandler := handlers.NewEncoderHandler(server.poolManager.GetMasterConnection(), server.config.Encoders)
+	transcodeHandler := handlers.NewTranscodeHandler(deps.Transcode)
+	egressHandler := handlers.NewEgressHandler(shaper)
+	jobHandler := handlers.NewJobHandler(server.poolManager.GetMasterConnection(), server.jobs)
+	scheduleHandler := handlers.NewScheduleHandler(deps.Scheduler)
+	teardownHandler := handlers.NewOrganizationTeardownHandler(deps.Teardowns)
+	downloadHandler := handlers.NewDownloadHandler(server.poolManager.GetMasterConnection(), server.storage, deps.Downloads,
+		server.beacon, server.jobs, server.config.Downloads, server.config.Playback, shaper, cache)
+	shareLinkHandler := handlers.NewShareLinkHandler(embedHandler, server.beacon)
+	reviewHandler := handlers.NewReviewHandler(server.jobs)
+
+	policies, err := newPolicyTable(deps.Policies, deps.Limiter, server.config.Admin.APIToken,
+		server.config.TLS.ClientCAFile != "")
+	if err != nil {
+		panic(err)
+	}
+
+	chain := []gin.HandlerFunc{
+		middleware.RequestID(),
+		deps.AccessLog.Middleware(),
+		middleware.Recovery(deps.Errors),
+		// Ahead of the middleware wrapping the response writer, whose deadlines it sets
+		policies.limits(),
+		middleware.SendFile(),
+		middleware.SecurityHeaders(server.config.Security),
+		middleware.Compress(server.config.Compression),
+		deps.Recorder.Middleware(),
+		middleware.QueryCount(gin.IsDebugging()),
 	}
+	if deps.Contract != nil {
+		chain = append(chain, middleware.ContractCheck(deps.Contract, server.config.Contract.Check))
+	}
+	chain = append(chain,
+		middleware.CORS(server.config.CORS, func(ctx context.Context) ([]string, error) {
+			return services.ListPlaybackDomains(ctx, server.poolManager.GetMasterConnection())
+		}),
+		server.maintenance.Guard(),
+	)
+	if deps.Faults != nil && deps.Faults.Enabled() {
+		chain = append(chain, deps.Faults.Middleware())
+	}
+	router.Use(chain...)
 
-	router.Use(middleware.Logger())
-	router.Use(middleware.Recovery())
-	router.Use(middleware.CORS())
+	// The admin API gets the same middleware on its own listener, the admin token included
+	adminRouter := router
+	if deps.AdminRouter != nil {
+		adminRouter = deps.AdminRouter
+		adminRouter.Use(chain...)
+	}
+	// Metrics are scraped from inside the network and need little of it
+	metricsRouter := router
+	if deps.MetricsRouter != nil {
+		metricsRouter = deps.MetricsRouter
+		metricsRouter.Use(middleware.RequestID(), middleware.Recovery(deps.Errors))
+		if server.config.Server.Pprof {
+			registerPprof(metricsRouter)
+		}
+	}
 
 	// Health check endpoints (no authentication required)
 	router.GET("/health", handlers.HealthCheck)
+	router.GET("/livez", healthHandler.Livez)
+	router.GET("/readyz", healthHandler.Readyz)
+	// Hook for Kubernetes preStop, which cannot send anything but GET
+	adminRouter.GET("/drainz", healthHandler.Drainz)
+	router.GET("/version", handlers.Version)
 	router.GET("/health/db", database.StatelessHealthCheckHandler(server.poolManager))
-	router.GET("/stats/db", database.StatelessMetricsHandler(server.poolManager))
+	metricsRouter.GET("/stats/db", database.StatelessMetricsHandler(server.poolManager))
+
+	// Prometheus metrics (no authentication required)
+	metricsRouter.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Embeddable player and oEmbed (access follows video visibility and playback tokens)
+	router.GET("/embed/:id", embedHandler.Embed)
+	router.GET("/embed/:id/media", embedHandler.Media)
+	router.GET("/embed/:id/thumbnail", embedHandler.Thumbnail)
+	router.GET("/embed/:id/chapters", embedHandler.Chapters)
+	router.GET("/embed/:id/ads", embedHandler.Ads)
+	router.GET("/oembed", embedHandler.OEmbed)
+	// Download files, through the signed links members are given
+	router.GET("/downloads/:id", downloadHandler.ServeDownload)
+	// Share links, playing their video whatever its visibility
+	router.GET("/s/:slug", shareLinkHandler.Share)
+	router.GET("/s/:slug/download", shareLinkHandler.ShareDownload)
+
+	// Catalog feeds of projects, listing only public videos
+	router.GET("/feeds/projects/:id/mrss.xml", feedHandler.MRSSFeed)
+	router.GET("/feeds/projects/:id/feed.json", feedHandler.JSONFeed)
+	router.GET("/feeds/projects/:id/podcast.xml", feedHandler.PodcastFeed)
+	// Feeds of smart playlists, listing their public videos
+	router.GET("/feeds/playlists/:id/mrss.xml", feedHandler.PlaylistMRSSFeed)
+	router.GET("/feeds/playlists/:id/feed.json", feedHandler.PlaylistJSONFeed)
+	router.GET("/feeds/playlists/:id/podcast.xml", feedHandler.PlaylistPodcastFeed)
+
+	// Avatars, banners and series artwork are public, so they can be shown without credentials
+	router.GET("/images/users/:id/avatar", profileImageHandler.Avatar)
+	router.GET("/images/organizations/:id/banner", profileImageHandler.Banner)
+	router.GET("/images/series/:id/artwork", seriesHandler.SeriesArtwork)
+
+	// Notifications from external encoders, authenticated by their integration's signature
+	router.POST("/hooks/encoders/:id", encoderHandler.ReceiveEncoderNotification)
+	// Job state changes from transcoders, authorized with the callback token
+	router.POST("/hooks/transcoders/:provider", transcodeHandler.TranscoderCallback)
+
+	// Operator endpoints, authorized with the admin token rather than as a user
+	admin := adminRouter.Group("/admin/v1")
+	{
+		admin.GET("/flags", flagHandler.ListFlags)
+		admin.PUT("/flags/:key", flagHandler.PutFlag)
+		admin.DELETE("/flags/:key", flagHandler.DeleteFlag)
+		admin.PUT("/flags/:key/organizations/:org_id", flagHandler.SetFlagOverride)
+		admin.DELETE("/flags/:key/organizations/:org_id", flagHandler.DeleteFlagOverride)
+		admin.GET("/maintenance", maintenanceHandler.GetMaintenance)
+		admin.PUT("/maintenance", maintenanceHandler.SetMaintenance)
+		admin.GET("/flight-recorder", flightRecorderHandler.GetFlightRecorder)
+		admin.PUT("/flight-recorder", flightRecorderHandler.SetFlightRecorder)
+		admin.GET("/flight-recorder/requests", flightRecorderHandler.ListFlightRecords)
+		admin.GET("/flight-recorder/requests/:id", flightRecorderHandler.GetFlightRecord)
+		admin.DELETE("/flight-recorder/requests", flightRecorderHandler.ClearFlightRecords)
+		admin.GET("/regions", regionHandler.ListRegions)
+		admin.PUT("/organizations/:id/region", regionHandler.SetOrganizationRegion)
+		admin.PUT("/organizations/:id/transcoder", transcodeHandler.SetOrganizationTranscoder)
+		admin.GET("/organizations/:id/egress", egressHandler.GetOrganizationEgress)
+		admin.PUT("/organizations/:id/egress", egressHandler.SetOrganizationEgress)
+		admin.GET("/jobs", jobHandler.ListJobs)
+		admin.POST("/jobs/retry", jobHandler.RetryFailedJobs)
+		admin.POST("/jobs/:id/retry", jobHandler.RetryJob)
+		admin.GET("/schedules", scheduleHandler.ListSchedules)
+		admin.PATCH("/schedules/:name", scheduleHandler.UpdateSchedule)
+		admin.POST("/schedules/:name/run", scheduleHandler.RunSchedule)
+		admin.GET("/moderation/queue", moderationHandler.GetModerationQueue)
+		admin.GET("/moderation/videos/:id", moderationHandler.GetModerationCase)
+		admin.POST("/moderation/videos/:id/dismiss", moderationHandler.DismissReports)
+		admin.POST("/moderation/videos/:id/takedown", moderationHandler.TakeDownVideo)
+		admin.POST("/moderation/videos/:id/scan", moderationHandler.ScanVideo)
+		admin.GET("/moderation/takedowns", moderationHandler.ListTakedowns)
+		admin.POST("/moderation/takedowns/:id/restore", moderationHandler.RestoreTakedown)
+		admin.POST("/moderation/takedowns/:id/uphold", moderationHandler.UpholdTakedown)
+	}
 
 	// Swagger documentation (no authentication required)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/openapi.json", func(c *gin.Context) {
+		doc, err := openapi.Document()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate the OpenAPI document"})
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", doc)
+	})
 
 	// API endpoints with tenant database access
-	api := router.Group("/api/v1")
-	{
+	registerAPI := func(api *gin.RouterGroup) {
+		// Error messages in the caller's language and timestamps in their time zone, for every
+		// route including the errors of authentication
+		api.Use(middleware.Localize())
+
+		// Player analytics; players report anonymously, so the beacon is registered ahead of
+		// the database middleware and authentication
+		api.POST("/beacon", beaconHandler.PostBeacon)
+
+		// Abuse reports come from viewers, who need no account
+		api.POST("/videos/:id/reports", moderationHandler.ReportVideo)
+
+		// Signing in opens the session later requests authenticate with
+		api.POST("/auth/login", authHandler.Login)
+
+		// Session tokens are checked on every request, so revoked ones fail at once
+		api.Use(deps.Sessions.Middleware())
 		// Apply database middleware only to API routes
 		api.Use(database.StatelessDatabaseMiddleware(server.poolManager))
+		// Organizations' IP access rules apply to the organization a request acts in
+		api.Use(deps.IPAccess.Middleware())
+		// Per-organization request counts for usage reports and billing
+		api.Use(server.usage.Middleware())
+		// Routes needing a user refuse anonymous requests and count against the user's rate limit
+		api.Use(policies.requireUser(), policies.limitUser())
 
 		// Organizations endpoints (require authentication)
 		orgs := api.Group("/organizations")
-		orgs.Use(database.StatelessRequireAuth())
+		orgs.Use(deps.IPAccess.PathMiddleware())
 		{
 			orgs.GET("", handlers.StatelessGetOrganizations)
 			orgs.POST("", handlers.StatelessCreateOrganization)
+			orgs.GET("/:id", handlers.StatelessGetOrganization)
+			orgs.PATCH("/:id", handlers.StatelessUpdateOrganization)
+			orgs.DELETE("/:id", teardownHandler.DeleteOrganization)
+			orgs.POST("/:id/deletion", teardownHandler.RequestOrganizationDeletion)
+			orgs.GET("/:id/teardown", teardownHandler.GetOrganizationTeardown)
+			orgs.PUT("/:id/banner", profileImageHandler.UploadBanner)
+			orgs.DELETE("/:id/banner", profileImageHandler.DeleteBanner)
+			orgs.POST("/:id/members", handlers.AddOrganizationMember)
+			orgs.GET("/:id/entitlements", handlers.ListEntitlements)
+			orgs.POST("/:id/entitlements", handlers.GrantEntitlement)
+			orgs.POST("/:id/entitlements/bulk", handlers.BulkGrantEntitlements)
+			orgs.DELETE("/:id/entitlements/:entitlement_id", handlers.RevokeEntitlement)
+			orgs.GET("/:id/webhooks", handlers.ListWebhooks)
+			orgs.POST("/:id/webhooks", handlers.CreateWebhook)
+			orgs.DELETE("/:id/webhooks/:webhook_id", handlers.DeleteWebhook)
+			orgs.GET("/:id/encoder-integrations", handlers.ListEncoderIntegrations)
+			orgs.POST("/:id/encoder-integrations", handlers.CreateEncoderIntegration)
+			orgs.DELETE("/:id/encoder-integrations/:integration_id", handlers.DeleteEncoderIntegration)
+			orgs.GET("/:id/custom-fields", handlers.ListCustomFields)
+			orgs.POST("/:id/custom-fields", handlers.CreateCustomField)
+			orgs.PATCH("/:id/custom-fields/:key", handlers.UpdateCustomField)
+			orgs.DELETE("/:id/custom-fields/:key", handlers.DeleteCustomField)
+			orgs.GET("/:id/api-usage", handlers.GetAPIUsage)
+			orgs.GET("/:id/ip-access", ipAccessHandler.GetIPAccess)
+			orgs.PUT("/:id/ip-access", ipAccessHandler.PutIPAccess)
+			orgs.GET("/:id/audit-log", ipAccessHandler.ListAuditLog)
+		}
+
+		// The authenticated user's own profile
+		me := api.Group("/me")
+		{
+			me.GET("", handlers.GetProfile)
+			me.PATCH("", handlers.UpdateProfile)
+			me.GET("/notification-preferences", handlers.GetNotificationPreferences)
+			me.PUT("/notification-preferences", handlers.UpdateNotificationPreferences)
+			me.PUT("/avatar", profileImageHandler.UploadAvatar)
+			me.DELETE("/avatar", profileImageHandler.DeleteAvatar)
+			me.GET("/devices", pushDeviceHandler.ListPushDevices)
+			me.POST("/devices", pushDeviceHandler.RegisterPushDevice)
+			me.DELETE("/devices/:id", pushDeviceHandler.DeletePushDevice)
+			me.GET("/sessions", authHandler.ListSessions)
+			me.DELETE("/sessions/:id", authHandler.RevokeSession)
 		}
 
 		// Session management endpoints (require authentication)
 		sessions := api.Group("/sessions")
-		sessions.Use(database.StatelessRequireAuth())
 		{
 			sessions.GET("", handlers.StatelessGetUserSession)
 			sessions.DELETE("", handlers.StatelessInvalidateSession)
+			sessions.PUT("/organization", handlers.SwitchOrganization)
+		}
+
+		// Direct-to-storage multipart uploads (require authentication, run in the organization's region)
+		uploads := api.Group("/uploads")
+		uploads.Use(server.regions.Middleware())
+		{
+			uploads.POST("/multipart", uploadHandler.CreateMultipartUpload)
+			uploads.GET("/multipart/:id/parts", uploadHandler.GetMultipartUploadParts)
+			uploads.POST("/multipart/:id/complete", uploadHandler.CompleteMultipartUpload)
+			uploads.DELETE("/multipart/:id", uploadHandler.AbortMultipartUpload)
+		}
+
+		// Video endpoints (require authentication, run in the organization's region)
+		videos := api.Group("/videos")
+		videos.Use(server.regions.Middleware())
+		{
+			videos.GET("", handlers.ListVideos)
+			videos.GET("/trending", handlers.ListTrendingVideos)
+			videos.POST("/import", importHandler.ImportVideo)
+			videos.POST("/import/bulk", importHandler.BulkImportVideos)
+			videos.PATCH("/bulk", handlers.BulkUpdateVideos)
+			videos.GET("/by-external-id/:external_id", handlers.GetVideoByExternalID)
+			videos.GET("/:id", handlers.GetVideo)
+			videos.PATCH("/:id", handlers.UpdateVideo)
+			videos.DELETE("/:id", handlers.DeleteVideo)
+			videos.GET("/:id/status-history", handlers.GetVideoStatusHistory)
+			videos.POST("/:id/replace", uploadHandler.ReplaceVideoSource)
+			videos.GET("/:id/source-versions", sourceVersionHandler.ListSourceVersions)
+			videos.POST("/:id/source-versions/:revision/rollback", sourceVersionHandler.RollbackVideoSource)
+			videos.POST("/:id/retry", jobHandler.RetryVideoProcessing)
+			videos.PUT("/:id/thumbnail", thumbnailHandler.UploadThumbnail)
+			videos.DELETE("/:id/thumbnail", thumbnailHandler.DeleteThumbnail)
+			videos.GET("/:id/chapters", handlers.GetVideoChapters)
+			videos.PUT("/:id/chapters", handlers.SetVideoChapters)
+			videos.GET("/:id/renditions", handlers.ListVideoRenditions)
+			videos.GET("/:id/transcodes", handlers.ListVideoTranscodes)
+			videos.GET("/:id/ad-breaks", handlers.GetVideoAdBreaks)
+			videos.PUT("/:id/ad-breaks", handlers.SetVideoAdBreaks)
+			videos.POST("/:id/playback-token", embedHandler.CreatePlaybackToken)
+			videos.POST("/:id/playback-sessions", embedHandler.CreatePlaybackSession)
+			videos.POST("/:id/downloads", downloadHandler.CreateDownload)
+			videos.GET("/:id/downloads/:download_id", downloadHandler.GetDownload)
+			videos.POST("/:id/share-links", shareLinkHandler.CreateShareLink)
+			videos.GET("/:id/share-links", shareLinkHandler.ListShareLinks)
+			videos.DELETE("/:id/share-links/:link_id", shareLinkHandler.RevokeShareLink)
+			videos.GET("/:id/reviews", reviewHandler.ListVideoReviews)
+			videos.POST("/:id/reviews", reviewHandler.SubmitVideoForReview)
+			videos.GET("/:id/storage", storageObjectHandler.GetVideoStorage)
+			videos.POST("/:id/storage/restore", storageObjectHandler.RestoreVideoStorage)
+			videos.POST("/:id/counter-notice", moderationHandler.FileCounterNotice)
+			videos.GET("/:id/scans", moderationHandler.ListVideoScans)
+		}
+
+		// Approval of the organization's videos (require authentication, run in the organization's region)
+		reviews := api.Group("/reviews")
+		reviews.Use(server.regions.Middleware())
+		{
+			reviews.GET("", reviewHandler.ListReviews)
+			reviews.GET("/:id", reviewHandler.GetReview)
+			reviews.POST("/:id/comments", reviewHandler.AddReviewComment)
+			reviews.POST("/:id/approve", reviewHandler.ApproveReview)
+			reviews.POST("/:id/reject", reviewHandler.RejectReview)
+		}
+
+		// Series of the organization's videos (require authentication, run in the organization's region)
+		seriesGroup := api.Group("/series")
+		seriesGroup.Use(server.regions.Middleware())
+		{
+			seriesGroup.GET("", seriesHandler.ListSeries)
+			seriesGroup.POST("", seriesHandler.CreateSeries)
+			seriesGroup.GET("/:id", seriesHandler.GetSeries)
+			seriesGroup.PATCH("/:id", seriesHandler.UpdateSeries)
+			seriesGroup.DELETE("/:id", seriesHandler.DeleteSeries)
+			seriesGroup.PUT("/:id/episodes", seriesHandler.SetSeriesEpisodes)
+			seriesGroup.PUT("/:id/artwork", seriesHandler.UploadSeriesArtwork)
+			seriesGroup.DELETE("/:id/artwork", seriesHandler.DeleteSeriesArtwork)
+		}
+
+		// Smart playlists, saved searches over the organization's videos (require authentication,
+		// run in the organization's region)
+		playlists := api.Group("/playlists")
+		playlists.Use(server.regions.Middleware())
+		{
+			playlists.GET("", playlistHandler.ListSmartPlaylists)
+			playlists.POST("", playlistHandler.CreateSmartPlaylist)
+			playlists.GET("/:id", playlistHandler.GetSmartPlaylist)
+			playlists.PATCH("/:id", playlistHandler.UpdateSmartPlaylist)
+			playlists.DELETE("/:id", playlistHandler.DeleteSmartPlaylist)
+		}
+
+		// Playback sessions of the organization's videos (require authentication, run in the
+		// organization's region)
+		playbackSessions := api.Group("/playback-sessions")
+		playbackSessions.Use(server.regions.Middleware())
+		{
+			playbackSessions.GET("", handlers.ListPlaybackSessions)
+			playbackSessions.POST("/:id/heartbeat", embedHandler.PlaybackSessionHeartbeat)
+			playbackSessions.DELETE("/:id", embedHandler.EndPlaybackSession)
 		}
+
+		// The caller's notifications (require authentication)
+		notifications := api.Group("/notifications")
+		{
+			notifications.GET("", handlers.ListNotifications)
+			notifications.POST("/read", handlers.MarkAllNotificationsRead)
+			notifications.POST("/:id/read", handlers.MarkNotificationRead)
+			notifications.GET("/preferences", handlers.GetNotificationPreferences)
+			notifications.PUT("/preferences", handlers.UpdateNotificationPreferences)
+		}
+
+		// Platforms devices can register for push on (no authentication required)
+		api.GET("/push/config", pushDeviceHandler.GetPushConfig)
+
+		// Feature flags as they apply to the caller's organization (require authentication)
+		api.GET("/flags", flagHandler.GetFlags)
+
+		// Background job status (require authentication, run in the organization's region)
+		jobsGroup := api.Group("/jobs")
+		jobsGroup.Use(server.regions.Middleware())
+		{
+			jobsGroup.GET("", handlers.ListFailedJobs)
+			jobsGroup.GET("/:id", handlers.GetJob)
+		}
+
+		// Playback quality aggregates of the caller's organization (require authentication)
+		analyticsGroup := api.Group("/analytics")
+		{
+			analyticsGroup.GET("/qoe", analyticsHandler.QoESummary)
+			analyticsGroup.GET("/qoe/timeseries", analyticsHandler.QoETimeseries)
+		}
+	}
+
+	// The API is served under /api/v1 in the shape handlers write it, and under /api/v2 with
+	// each JSON response wrapped in the envelope of pkg/response
+	registerAPI(router.Group("/api/v1"))
+	registerAPI(router.Group("/api/v2", middleware.EnvelopeV2()))
+
+	policies.resolve(router.Routes())
+	if adminRouter != router {
+		policies.resolve(adminRouter.Routes())
 	}
 }
+
+// registerPprof serves the runtime profiles of net/http/pprof under /debug/pprof
+func registerPprof(router *gin.Engine) {
+	debug := router.Group("/debug/pprof")
+	debug.GET("/", gin.WrapF(pprof.Index))
+	debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("/profile", gin.WrapF(pprof.Profile))
+	debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/trace", gin.WrapF(pprof.Trace))
+	// Named profiles, such as heap and goroutine
+	debug.GET("/:profile", gin.WrapF(pprof.Index))
+}
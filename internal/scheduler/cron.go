@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is when a task runs, parsed from a cron expression
+type Schedule interface {
+	// Next returns the first time after t the task runs
+	Next(t time.Time) time.Time
+}
+
+// everySchedule runs a task at a fixed interval from the previous run
+type everySchedule time.Duration
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s)).Truncate(time.Second)
+}
+
+// cronSchedule holds a bit per allowed value of each field
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = [5]cronField{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule parses a five-field cron expression (minute, hour, day of month, month, day of
+// week; with *, lists, ranges and steps), a descriptor such as @hourly or @daily, or
+// "@every <duration>" of at least a second. Expressions are evaluated in UTC. As in cron, a
+// day matches when either its day of month or its day of week does, if both are restricted.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("%w %q: @every needs a duration of at least 1s", ErrInvalidSchedule, spec)
+		}
+		return everySchedule(d), nil
+	}
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("%w %q: expected 5 fields, a descriptor or @every", ErrInvalidSchedule, spec)
+	}
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidSchedule, spec, err)
+		}
+		masks[i] = mask
+	}
+	// Sunday may be written as 7
+	if masks[4]&(1<<7) != 0 {
+		masks[4] = masks[4]&^(1<<7) | 1
+	}
+	schedule := &cronSchedule{minute: masks[0], hour: masks[1], dom: masks[2], month: masks[3], dow: masks[4]}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w %q: never matches", ErrInvalidSchedule, spec)
+	}
+	return schedule, nil
+}
+
+// parseCronField parses a comma-separated list of *, values and ranges, each with an optional
+// step
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	max := bounds.max
+	if bounds == cronFields[4] {
+		max = 7
+	}
+
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := bounds.min, max
+		switch {
+		case expr == "*":
+		case strings.Contains(expr, "-"):
+			a, b, _ := strings.Cut(expr, "-")
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(a)
+			hi, err2 = strconv.Atoi(b)
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", expr)
+			}
+		default:
+			n, err := strconv.Atoi(expr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", expr)
+			}
+			lo, hi = n, n
+			// 5/15 runs from 5 on, as in cron
+			if hasStep {
+				hi = max
+			}
+		}
+		if lo < bounds.min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, bounds.min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+// restricted reports whether a field allows less than its whole range
+func restricted(mask uint64, bounds cronField) bool {
+	return bits.OnesCount64(mask) < bounds.max-bounds.min+1
+}
+
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<t.Weekday()) != 0
+	if restricted(s.dom, cronFields[2]) && restricted(s.dow, cronFields[4]) {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// Next steps through the fields from the largest, so it skips whole months, days and hours
+// that do not match. Five years without a match means the expression never matches, such as
+// one for February 30.
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	t = t.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<t.Month()) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<t.Hour()) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
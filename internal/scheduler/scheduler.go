@@ -0,0 +1,480 @@
+// Package scheduler runs recurring maintenance tasks, such as deleting expired sign-in sessions,
+// on cron schedules. The schedule of each task and the outcome of its last run are kept in the
+// scheduled_tasks table, so runs keep to the schedule across restarts and changes of leader,
+// and operators can pause, reschedule and trigger tasks through the admin API.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/leader"
+	"openvdo/internal/metrics"
+	"openvdo/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Outcomes of a run
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var (
+	// ErrUnknownTask is returned for names no task is registered under
+	ErrUnknownTask = errors.New("unknown scheduled task")
+	// ErrInvalidSchedule is returned for schedules ParseSchedule rejects
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrTaskDisabled is returned when triggering a disabled task
+	ErrTaskDisabled = errors.New("scheduled task is disabled")
+)
+
+var (
+	runs = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "scheduler",
+		Name:      "runs_total",
+		Help:      "Runs of scheduled tasks by outcome",
+	}, []string{"task", "status"})
+	lastSuccess = promauto.With(metrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "scheduler",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "When each scheduled task last succeeded in this process",
+	}, []string{"task"})
+)
+
+// Func runs a task and returns a short summary of what it did, such as "deleted 12 sessions"
+type Func func(ctx context.Context) (string, error)
+
+// Task is a recurring task
+type Task struct {
+	Name        string
+	Description string
+	// Schedule is the default schedule, which operators may override; see ParseSchedule
+	Schedule string
+	// Timeout bounds a run; zero uses SCHEDULER_TASK_TIMEOUT
+	Timeout time.Duration
+	Run     Func
+}
+
+// State is a task with its schedule and the outcome of its last run
+type State struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Schedule is the schedule in effect, DefaultSchedule the one it has without an override
+	Schedule        string `json:"schedule"`
+	DefaultSchedule string `json:"default_schedule"`
+	Enabled         bool   `json:"enabled"`
+	// Running is true while a run holds the task's lock
+	Running        bool       `json:"running"`
+	NextRunAt      *time.Time `json:"next_run_at,omitempty"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	// LastStatus is running, succeeded or failed
+	LastStatus     *string `json:"last_status,omitempty"`
+	LastError      *string `json:"last_error,omitempty"`
+	LastResult     *string `json:"last_result,omitempty"`
+	LastDurationMs *int64  `json:"last_duration_ms,omitempty"`
+}
+
+type task struct {
+	Task
+	schedule Schedule
+}
+
+// Scheduler runs the due tasks in the leader's process. Every task has a row in
+// scheduled_tasks, which a run claims by moving its next_run_at on, so a task runs once per
+// due time even while leadership changes hands.
+type Scheduler struct {
+	db     *sql.DB
+	config config.Scheduler
+	leader *leader.Elector
+
+	mu      sync.Mutex
+	tasks   map[string]*task
+	running map[string]bool
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New creates a scheduler. The master connection is used because tasks act across all
+// organizations.
+func New(db *sql.DB, cfg config.Scheduler, elector *leader.Elector) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		db: db, config: cfg, leader: elector,
+		tasks: make(map[string]*task), running: make(map[string]bool),
+		ctx: ctx, cancel: cancel,
+	}
+}
+
+// Register adds a task. It panics when the default schedule does not parse, which is a
+// programming error.
+func (s *Scheduler) Register(t Task) {
+	schedule, err := ParseSchedule(t.Schedule)
+	if err != nil {
+		panic(fmt.Sprintf("scheduled task %s: %v", t.Name, err))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[t.Name] = &task{Task: t, schedule: schedule}
+}
+
+// Start creates the rows of new tasks and checks for due tasks every SCHEDULER_CHECK_INTERVAL
+// until Stop is called
+func (s *Scheduler) Start() {
+	for _, t := range s.sorted() {
+		if _, err := s.db.ExecContext(s.ctx, `INSERT INTO scheduled_tasks (name) VALUES ($1) ON CONFLICT DO NOTHING`,
+			t.Name); err != nil {
+			logger.Error("Failed to create scheduled task %s: %v", t.Name, err)
+		}
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.config.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if !s.leader.IsLeader() {
+				continue
+			}
+			if err := s.tick(); err != nil && s.ctx.Err() == nil {
+				logger.Error("Failed to check scheduled tasks: %v", err)
+			}
+		}
+	}()
+	logger.Info("Scheduler started with %d tasks (check interval %v)", len(s.tasks), s.config.CheckInterval)
+}
+
+// Stop cancels the runs under way and waits for them to record their outcome
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) sorted() []*task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (s *Scheduler) lookup(name string) (*task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTask, name)
+	}
+	return t, nil
+}
+
+// effective returns the schedule in effect: the override when it parses, the default otherwise
+func (t *task) effective(override sql.NullString) (Schedule, string) {
+	if override.Valid {
+		if schedule, err := ParseSchedule(override.String); err == nil {
+			return schedule, override.String
+		}
+	}
+	return t.schedule, t.Schedule
+}
+
+func (s *Scheduler) timeout(t *task) time.Duration {
+	if t.Timeout > 0 {
+		return t.Timeout
+	}
+	return s.config.TaskTimeout
+}
+
+// tick plans the next run of tasks without one and starts the due ones. Runs missed while no
+// worker was up are caught up with a single run.
+func (s *Scheduler) tick() error {
+	rows, err := s.db.QueryContext(s.ctx, `
+		SELECT name, schedule, next_run_at, COALESCE(locked_until > NOW(), false)
+		FROM scheduled_tasks WHERE enabled
+	`)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		name     string
+		override sql.NullString
+		next     sql.NullTime
+		locked   bool
+	}
+	var due []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.name, &r.override, &r.next, &r.locked); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, r := range due {
+		t, err := s.lookup(r.name)
+		if err != nil {
+			// Registered by another version of the server
+			continue
+		}
+		schedule, _ := t.effective(r.override)
+		next := schedule.Next(now)
+
+		if !r.next.Valid {
+			if _, err := s.db.ExecContext(s.ctx, `
+				UPDATE scheduled_tasks SET next_run_at = $2 WHERE name = $1 AND next_run_at IS NULL
+			`, r.name, nullTime(next)); err != nil {
+				return err
+			}
+			continue
+		}
+		if r.next.Time.After(now) || r.locked {
+			continue
+		}
+
+		s.mu.Lock()
+		busy := s.running[r.name]
+		s.mu.Unlock()
+		if busy {
+			continue
+		}
+		res, err := s.db.ExecContext(s.ctx, `
+			UPDATE scheduled_tasks
+			SET next_run_at = $2, locked_until = NOW() + make_interval(secs => $3), last_started_at = NOW(),
+				last_status = $4
+			WHERE name = $1 AND next_run_at = $5 AND (locked_until IS NULL OR locked_until < NOW())
+		`, r.name, nullTime(next), s.timeout(t).Seconds(), StatusRunning, r.next.Time)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			s.start(t)
+		}
+	}
+	return nil
+}
+
+// nullTime stores the zero time Next returns for schedules that never match as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func (s *Scheduler) start(t *task) {
+	s.mu.Lock()
+	s.running[t.Name] = true
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, t.Name)
+			s.mu.Unlock()
+		}()
+		s.run(t)
+	}()
+}
+
+// run runs a claimed task and records its outcome. A run interrupted by shutdown is due again
+// right away, so the next leader runs it.
+func (s *Scheduler) run(t *task) {
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout(t))
+	defer cancel()
+
+	start := time.Now()
+	result, err := func() (result string, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("task panicked: %v", r)
+			}
+		}()
+		return t.Run(ctx)
+	}()
+	duration := time.Since(start)
+
+	status := StatusSucceeded
+	var lastError sql.NullString
+	if err != nil {
+		status = StatusFailed
+		lastError = sql.NullString{String: err.Error(), Valid: true}
+		logger.Error("Scheduled task %s failed after %v: %v", t.Name, duration.Round(time.Millisecond), err)
+	} else {
+		lastSuccess.WithLabelValues(t.Name).SetToCurrentTime()
+		logger.Info("Scheduled task %s finished in %v: %s", t.Name, duration.Round(time.Millisecond), result)
+	}
+	runs.WithLabelValues(t.Name, status).Inc()
+
+	// The scheduler's context may be cancelled by now, and the outcome must still be written
+	recordCtx, recordCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer recordCancel()
+	_, dbErr := s.db.ExecContext(recordCtx, `
+		UPDATE scheduled_tasks
+		SET last_finished_at = NOW(), last_status = $2, last_error = $3, last_result = NULLIF($4, ''),
+			last_duration_ms = $5, locked_until = NULL,
+			next_run_at = CASE WHEN $6 THEN NOW() ELSE next_run_at END
+		WHERE name = $1
+	`, t.Name, status, lastError, result, duration.Milliseconds(), s.ctx.Err() != nil)
+	if dbErr != nil {
+		logger.Error("Failed to record outcome of scheduled task %s: %v", t.Name, dbErr)
+	}
+}
+
+// stateColumns is the column list matching scanState
+const stateColumns = `name, schedule, enabled, COALESCE(locked_until > NOW(), false), next_run_at, last_started_at,
+	last_finished_at, last_status, last_error, last_result, last_duration_ms`
+
+func (s *Scheduler) scanState(row interface{ Scan(...interface{}) error }) (*State, error) {
+	var st State
+	var override sql.NullString
+	err := row.Scan(&st.Name, &override, &st.Enabled, &st.Running, &st.NextRunAt, &st.LastStartedAt,
+		&st.LastFinishedAt, &st.LastStatus, &st.LastError, &st.LastResult, &st.LastDurationMs)
+	if err != nil {
+		return nil, err
+	}
+	t, err := s.lookup(st.Name)
+	if err != nil {
+		return nil, err
+	}
+	st.Description, st.DefaultSchedule = t.Description, t.Schedule
+	_, st.Schedule = t.effective(override)
+	return &st, nil
+}
+
+// List returns the registered tasks by name. Tasks no worker has started yet show with their
+// defaults.
+func (s *Scheduler) List(ctx context.Context) ([]State, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+stateColumns+` FROM scheduled_tasks`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := make(map[string]*State)
+	for rows.Next() {
+		st, err := s.scanState(rows)
+		if errors.Is(err, ErrUnknownTask) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stored[st.Name] = st
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := []State{}
+	for _, t := range s.sorted() {
+		st, ok := stored[t.Name]
+		if !ok {
+			st = &State{Name: t.Name, Description: t.Description, Schedule: t.Schedule, DefaultSchedule: t.Schedule,
+				Enabled: true}
+		}
+		states = append(states, *st)
+	}
+	return states, nil
+}
+
+// Get returns one task
+func (s *Scheduler) Get(ctx context.Context, name string) (*State, error) {
+	if _, err := s.lookup(name); err != nil {
+		return nil, err
+	}
+	st, err := s.scanState(s.db.QueryRowContext(ctx, `SELECT `+stateColumns+` FROM scheduled_tasks WHERE name = $1`, name))
+	if err == sql.ErrNoRows {
+		t, _ := s.lookup(name)
+		return &State{Name: t.Name, Description: t.Description, Schedule: t.Schedule, DefaultSchedule: t.Schedule,
+			Enabled: true}, nil
+	}
+	return st, err
+}
+
+// Update pauses or resumes a task and overrides its schedule; nil leaves a setting as it is,
+// and an empty schedule restores the default. The next run is planned afresh from now.
+func (s *Scheduler) Update(ctx context.Context, name string, enabled *bool, schedule *string) (*State, error) {
+	t, err := s.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var override sql.NullString
+	var current sql.NullString
+	err = s.db.QueryRowContext(ctx, `SELECT schedule FROM scheduled_tasks WHERE name = $1`, name).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	override = current
+	if schedule != nil {
+		override = sql.NullString{String: *schedule, Valid: *schedule != ""}
+		if override.Valid {
+			if _, err := ParseSchedule(*schedule); err != nil {
+				return nil, err
+			}
+		}
+	}
+	next, _ := t.effective(override)
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO scheduled_tasks (name, schedule, enabled, next_run_at)
+		VALUES ($1, $2, COALESCE($3, TRUE), $4)
+		ON CONFLICT (name) DO UPDATE
+		SET schedule = EXCLUDED.schedule, enabled = COALESCE($3, scheduled_tasks.enabled),
+			next_run_at = EXCLUDED.next_run_at
+	`, name, override, enabled, nullTime(next.Next(time.Now())))
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, name)
+}
+
+// Trigger makes a task due now; the leader runs it within SCHEDULER_CHECK_INTERVAL, after the
+// run under way if there is one
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*State, error) {
+	if _, err := s.lookup(name); err != nil {
+		return nil, err
+	}
+	var enabled bool
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO scheduled_tasks (name, next_run_at) VALUES ($1, NOW())
+		ON CONFLICT (name) DO UPDATE
+		SET next_run_at = CASE WHEN scheduled_tasks.enabled THEN NOW() ELSE scheduled_tasks.next_run_at END
+		RETURNING enabled
+	`, name).Scan(&enabled)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, fmt.Errorf("%w: %s", ErrTaskDisabled, name)
+	}
+	return s.Get(ctx, name)
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"openvdo/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxAdBreaks bounds the ad breaks of a video
+	MaxAdBreaks = 100
+	// maxAdBreakDuration bounds the avail of one break
+	maxAdBreakDuration = 600
+	maxVASTTagURL      = 2048
+)
+
+// ErrInvalidAdBreaks is returned for ad break lists that cannot be used
+var ErrInvalidAdBreaks = errors.New("invalid ad breaks")
+
+// NormalizeAdBreaks sorts ad breaks by offset. Offsets must be distinct and not negative, and
+// durations positive and at most ten minutes.
+func NormalizeAdBreaks(breaks []models.AdBreak) (models.AdBreaks, error) {
+	if len(breaks) > MaxAdBreaks {
+		return nil, fmt.Errorf("%w: a video has at most %d ad breaks", ErrInvalidAdBreaks, MaxAdBreaks)
+	}
+
+	out := make(models.AdBreaks, 0, len(breaks))
+	for _, b := range breaks {
+		if b.OffsetSeconds < 0 || math.IsNaN(b.OffsetSeconds) || math.IsInf(b.OffsetSeconds, 0) {
+			return nil, fmt.Errorf("%w: offsets must be zero or more seconds", ErrInvalidAdBreaks)
+		}
+		if !(b.DurationSeconds > 0 && b.DurationSeconds <= maxAdBreakDuration) {
+			return nil, fmt.Errorf("%w: durations must be more than 0 and at most %d seconds", ErrInvalidAdBreaks, maxAdBreakDuration)
+		}
+		b.OffsetSeconds = math.Round(b.OffsetSeconds*1000) / 1000
+		b.DurationSeconds = math.Round(b.DurationSeconds*1000) / 1000
+		out = append(out, b)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].OffsetSeconds < out[j].OffsetSeconds })
+	for i := 1; i < len(out); i++ {
+		if out[i].OffsetSeconds == out[i-1].OffsetSeconds {
+			return nil, fmt.Errorf("%w: two ad breaks at %g seconds", ErrInvalidAdBreaks, out[i].OffsetSeconds)
+		}
+	}
+	return out, nil
+}
+
+// NormalizeVASTTagURL checks an ad tag URL; empty clears the tag
+func NormalizeVASTTagURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if len(raw) > maxVASTTagURL {
+		return "", fmt.Errorf("must be at most %d characters", maxVASTTagURL)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("must be an http or https URL")
+	}
+	return raw, nil
+}
+
+// ExpandVASTTag fills the [VIDEO_ID] macro of an ad tag. Other macros, such as
+// [CACHEBUSTING], are left for the player or its ad SDK, which expand them per request.
+func ExpandVASTTag(tag string, videoID uuid.UUID) string {
+	return strings.ReplaceAll(tag, "[VIDEO_ID]", url.QueryEscape(videoID.String()))
+}
+
+// InsertHLSAdCues marks the ad breaks in an HLS media playlist. Each break becomes an
+// insertion point before the first segment starting at or after its offset: an
+// #EXT-X-CUE-OUT with the avail duration and the matching SCTE-35 splice_insert, immediately
+// followed by #EXT-X-CUE-IN, since no content is replaced. Breaks past the last segment are
+// marked at the end of a VOD playlist. It returns false, with the playlist
+// unchanged, for master playlists and playlists without segments.
+func InsertHLSAdCues(playlist []byte, breaks models.AdBreaks) ([]byte, bool) {
+	if len(breaks) == 0 || !bytes.HasPrefix(bytes.TrimPrefix(playlist, []byte("\xef\xbb\xbf")), []byte("#EXTM3U")) ||
+		bytes.Contains(playlist, []byte("#EXT-X-STREAM-INF")) || !bytes.Contains(playlist, []byte("#EXTINF")) {
+		return playlist, false
+	}
+
+	var out bytes.Buffer
+	next := 0
+	position := 0.0
+	// The tags of a segment precede its URI; cues go before the first of them
+	segmentStarted := false
+	writeCues := func() {
+		for next < len(breaks) && breaks[next].OffsetSeconds <= position+0.001 {
+			b := breaks[next]
+			fmt.Fprintf(&out, "#EXT-X-CUE-OUT:DURATION=%s\n", formatHLSSeconds(b.DurationSeconds))
+			fmt.Fprintf(&out, "#EXT-OATCLS-SCTE35:%s\n", SpliceInsert(uint32(next+1), b.OffsetSeconds, b.DurationSeconds))
+			out.WriteString("#EXT-X-CUE-IN\n")
+			next++
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(playlist))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		isSegmentTag := strings.HasPrefix(trimmed, "#EXTINF") || strings.HasPrefix(trimmed, "#EXT-X-DISCONTINUITY") ||
+			strings.HasPrefix(trimmed, "#EXT-X-PROGRAM-DATE-TIME") || strings.HasPrefix(trimmed, "#EXT-X-BYTERANGE")
+		isURI := trimmed != "" && !strings.HasPrefix(trimmed, "#")
+		if !segmentStarted && (isSegmentTag || isURI) {
+			writeCues()
+			segmentStarted = true
+		}
+		if trimmed == "#EXT-X-ENDLIST" {
+			// Breaks at or past the end are post-rolls
+			position = math.Inf(1)
+			writeCues()
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+
+		if strings.HasPrefix(trimmed, "#EXTINF:") {
+			duration, _, _ := strings.Cut(strings.TrimPrefix(trimmed, "#EXTINF:"), ",")
+			if d, err := strconv.ParseFloat(strings.TrimSpace(duration), 64); err == nil {
+				position += d
+			}
+		}
+		if isURI {
+			segmentStarted = false
+		}
+	}
+	return out.Bytes(), true
+}
+
+func formatHLSSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
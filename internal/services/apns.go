@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+)
+
+const (
+	apnsProductionURL = "https://api.push.apple.com"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com"
+	// Apple rejects provider tokens older than an hour and throttles providers that refresh
+	// them more often than every 20 minutes
+	apnsTokenLifetime = 45 * time.Minute
+)
+
+// APNsProvider sends notifications to iOS apps through the Apple Push Notification service,
+// authenticating with a token signing key
+type APNsProvider struct {
+	client  *http.Client
+	baseURL string
+	key     *ecdsa.PrivateKey
+	keyID   string
+	teamID  string
+	topic   string
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsProvider creates an APNs provider from the .p8 key file in cfg
+func NewAPNsProvider(cfg config.Push) (*APNsProvider, error) {
+	if cfg.APNsKeyID == "" || cfg.APNsTeamID == "" || cfg.APNsTopic == "" {
+		return nil, fmt.Errorf("PUSH_APNS_KEY_ID, PUSH_APNS_TEAM_ID and PUSH_APNS_TOPIC are required with PUSH_APNS_KEY_FILE")
+	}
+	data, err := os.ReadFile(cfg.APNsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PUSH_APNS_KEY_FILE: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("PUSH_APNS_KEY_FILE is not a PEM key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PUSH_APNS_KEY_FILE: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("PUSH_APNS_KEY_FILE is not an ECDSA key")
+	}
+
+	baseURL := apnsProductionURL
+	if cfg.APNsSandbox {
+		baseURL = apnsSandboxURL
+	}
+	return &APNsProvider{
+		// APNs only speaks HTTP/2
+		client:  &http.Client{Timeout: cfg.Timeout, Transport: &http.Transport{ForceAttemptHTTP2: true}},
+		baseURL: baseURL,
+		key:     key,
+		keyID:   cfg.APNsKeyID,
+		teamID:  cfg.APNsTeamID,
+		topic:   cfg.APNsTopic,
+	}, nil
+}
+
+// ValidAPNsToken reports whether token looks like a device token, which is hex encoded
+func ValidAPNsToken(token string) bool {
+	b, err := hex.DecodeString(token)
+	return err == nil && len(b) >= 32 && len(b) <= 100
+}
+
+// Platform implements PushProvider
+func (a *APNsProvider) Platform() string {
+	return models.PushPlatformAPNs
+}
+
+// Send implements PushProvider. The notification's ID, type and data are custom keys next to
+// the aps dictionary.
+func (a *APNsProvider) Send(ctx context.Context, device *models.PushDevice, msg *PushMessage) error {
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+		"notification_id": msg.ID,
+		"type":            msg.Type,
+	}
+	if len(msg.Data) > 0 {
+		payload["data"] = msg.Data
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	token, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/3/device/"+device.Token, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDeviceGone, err)
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("apns-expiration", strconv.FormatInt(time.Now().Add(msg.TTL).Unix(), 10))
+	req.Header.Set("apns-collapse-id", msg.ID.String())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var failure struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<12)).Decode(&failure)
+	err = fmt.Errorf("APNs returned %s: %s", resp.Status, failure.Reason)
+
+	switch {
+	case resp.StatusCode == http.StatusGone || failure.Reason == "BadDeviceToken" ||
+		failure.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("%w: %v", ErrDeviceGone, err)
+	case failure.Reason == "ExpiredProviderToken":
+		a.mu.Lock()
+		a.token = ""
+		a.mu.Unlock()
+		return err
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return err
+	default:
+		return jobs.Permanent(err)
+	}
+}
+
+// providerToken returns the signed provider token, reusing it until it nears its lifetime
+func (a *APNsProvider) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.issuedAt) < apnsTokenLifetime {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	token, err := signJWT(map[string]string{"alg": "ES256", "kid": a.keyID},
+		map[string]interface{}{"iss": a.teamID, "iat": now.Unix()}, a.key)
+	if err != nil {
+		return "", err
+	}
+	a.token, a.issuedAt = token, now
+	return token, nil
+}
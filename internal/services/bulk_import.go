@@ -0,0 +1,492 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/pkg/logger"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// JobKindBulkImport walks an S3 prefix or a CSV manifest and queues a video.import job for
+// every entry
+const JobKindBulkImport = "video.bulk_import"
+
+const (
+	// How long a bulk import waits when its imports in flight reach the limit
+	bulkImportPollInterval = 5 * time.Second
+	// Presigned S3 URLs must outlive the queueing and retries of the import they are given to
+	bulkImportPresignExpiry = 24 * time.Hour
+	// Entry errors kept in the checkpoint; the count of skipped entries is always complete
+	maxBulkImportErrors = 50
+	maxBulkImportTags   = 50
+	maxBulkImportTagLen = 64
+)
+
+// S3 error codes that retrying with the same bucket and credentials cannot fix
+var permanentS3Errors = map[string]bool{
+	"AccessDenied":          true,
+	"AllAccessDisabled":     true,
+	"InvalidAccessKeyId":    true,
+	"InvalidBucketName":     true,
+	"NoSuchBucket":          true,
+	"PermanentRedirect":     true,
+	"SignatureDoesNotMatch": true,
+}
+
+// BulkImportPayload is the payload of a video.bulk_import job. Exactly one of S3, Manifest
+// and ManifestURL is set.
+type BulkImportPayload struct {
+	S3 *BulkImportS3 `json:"s3,omitempty"`
+	// Manifest is CSV with the columns url, title and tags, with or without a header row.
+	// A manifest fetched from ManifestURL is stored here on the first run.
+	Manifest    string     `json:"manifest,omitempty"`
+	ManifestURL string     `json:"manifest_url,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	ProjectID   *uuid.UUID `json:"project_id,omitempty"`
+	// Secrets are removed from the payload once the walk is over
+	Secrets *BulkImportSecrets `json:"secrets,omitempty"`
+}
+
+// BulkImportS3 selects the objects under a prefix of an S3 compatible bucket
+type BulkImportS3 struct {
+	Bucket         string `json:"bucket"`
+	Prefix         string `json:"prefix,omitempty"`
+	Region         string `json:"region,omitempty"`
+	Endpoint       string `json:"endpoint,omitempty"`
+	ForcePathStyle bool   `json:"force_path_style,omitempty"`
+}
+
+// BulkImportSecrets are credentials for the source bucket. Without them the bucket is listed
+// anonymously; the server's own credentials are never used.
+type BulkImportSecrets struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+// BulkImportCheckpoint records how far a bulk import got, so a rescheduled or retried run
+// continues after the last entry it queued
+type BulkImportCheckpoint struct {
+	// StartAfter is the last S3 key handled
+	StartAfter string `json:"start_after,omitempty"`
+	// Row is the number of manifest records handled, out of Total
+	Row     int      `json:"row,omitempty"`
+	Total   int      `json:"total,omitempty"`
+	Queued  int      `json:"queued"`
+	Skipped int      `json:"skipped"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+func (cp *BulkImportCheckpoint) skip(format string, args ...interface{}) {
+	cp.Skipped++
+	if len(cp.Errors) < maxBulkImportErrors {
+		cp.Errors = append(cp.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// bulkImportEntry is one video to import
+type bulkImportEntry struct {
+	url   string
+	title string
+	tags  []string
+}
+
+// BulkImporter runs video.bulk_import jobs. Entries are queued in batches that keep at most
+// the configured number of imports queued or running, so a large bucket does not crowd out
+// other organizations' jobs.
+type BulkImporter struct {
+	db       *sql.DB
+	importer *Importer
+	config   config.Import
+}
+
+// NewBulkImporter creates a bulk importer queueing its entries for importer
+func NewBulkImporter(db *sql.DB, importer *Importer, cfg config.Import) *BulkImporter {
+	return &BulkImporter{db: db, importer: importer, config: cfg}
+}
+
+// Validate checks a payload before the job is queued and normalizes its tags
+func (b *BulkImporter) Validate(p *BulkImportPayload) error {
+	sources := 0
+	for _, set := range []bool{p.S3 != nil, p.Manifest != "", p.ManifestURL != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("exactly one of s3, manifest and manifest_url is required")
+	}
+
+	if p.S3 != nil {
+		if p.S3.Bucket == "" {
+			return fmt.Errorf("s3.bucket is required")
+		}
+		if p.S3.Endpoint != "" {
+			if _, err := b.importer.ParseURL(p.S3.Endpoint); err != nil {
+				return fmt.Errorf("invalid s3.endpoint: %w", err)
+			}
+		}
+	}
+	if p.Secrets != nil {
+		if p.S3 == nil {
+			return fmt.Errorf("secrets are only used with s3")
+		}
+		if p.Secrets.AccessKeyID == "" || p.Secrets.SecretAccessKey == "" {
+			return fmt.Errorf("secrets need both access_key_id and secret_access_key")
+		}
+	}
+	if p.ManifestURL != "" {
+		if _, err := b.importer.ParseURL(p.ManifestURL); err != nil {
+			return fmt.Errorf("invalid manifest_url: %w", err)
+		}
+	}
+	if int64(len(p.Manifest)) > b.config.BulkManifestMaxSize {
+		return fmt.Errorf("manifest is larger than %d bytes", b.config.BulkManifestMaxSize)
+	}
+
+	p.Tags = normalizeImportTags(p.Tags)
+	return nil
+}
+
+// Handle runs a video.bulk_import job. Each run queues one batch and reschedules the job
+// until the source is exhausted.
+func (b *BulkImporter) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload BulkImportPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	if job.OrganizationID == nil {
+		return jobs.Permanent(fmt.Errorf("bulk import has no organization"))
+	}
+
+	var cp BulkImportCheckpoint
+	if len(job.Checkpoint) > 0 {
+		if err := json.Unmarshal(job.Checkpoint, &cp); err != nil {
+			return jobs.Permanent(fmt.Errorf("invalid checkpoint: %w", err))
+		}
+	}
+
+	more, err := b.runBatch(ctx, job, &payload, &cp)
+	if err == nil && more {
+		if cp.Total > 0 {
+			progress(float64(cp.Row) / float64(cp.Total))
+		}
+		return jobs.Reschedule(bulkImportPollInterval)
+	}
+
+	if err == nil || (ctx.Err() == nil && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts)) {
+		// The walk is over either way, and credentials should not outlive it
+		if _, dbErr := b.db.ExecContext(context.Background(), `UPDATE jobs SET payload = payload - 'secrets' WHERE id = $1`,
+			job.ID); dbErr != nil {
+			logger.Error("Failed to remove secrets of job %s: %v", job.ID, dbErr)
+		}
+	}
+	if err == nil {
+		logger.Info("Bulk import %s queued %d videos, skipped %d entries", job.ID, cp.Queued, cp.Skipped)
+	}
+	return err
+}
+
+// runBatch queues the next entries up to the in-flight limit and reports whether any remain
+func (b *BulkImporter) runBatch(ctx context.Context, job *jobs.Job, payload *BulkImportPayload, cp *BulkImportCheckpoint) (bool, error) {
+	if payload.ManifestURL != "" && payload.Manifest == "" {
+		manifest, err := b.fetchManifest(ctx, payload.ManifestURL)
+		if err != nil {
+			return false, err
+		}
+		if _, err := b.db.ExecContext(ctx, `UPDATE jobs SET payload = jsonb_set(payload, '{manifest}', to_jsonb($1::text)) WHERE id = $2`,
+			manifest, job.ID); err != nil {
+			return false, fmt.Errorf("failed to save manifest: %w", err)
+		}
+		payload.Manifest = manifest
+	}
+
+	var inFlight int
+	if err := b.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE parent_id = $1 AND status IN ($2, $3)`,
+		job.ID, jobs.StatusQueued, jobs.StatusRunning).Scan(&inFlight); err != nil {
+		return false, fmt.Errorf("failed to count imports in flight: %w", err)
+	}
+	room := b.config.BulkMaxInFlight - inFlight
+	if room <= 0 {
+		return true, nil
+	}
+
+	var entries []bulkImportEntry
+	var more bool
+	var err error
+	if payload.S3 != nil {
+		entries, more, err = b.listS3(ctx, payload, cp, room)
+	} else {
+		entries, more, err = b.readManifest(payload.Manifest, cp, room)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return more, b.enqueue(ctx, job, payload, cp, entries)
+}
+
+// enqueue creates the videos and their import jobs together with the checkpoint, so a run
+// interrupted at any point neither loses nor repeats entries
+func (b *BulkImporter) enqueue(ctx context.Context, job *jobs.Job, payload *BulkImportPayload, cp *BulkImportCheckpoint,
+	entries []bulkImportEntry) error {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	videos := database.NewBulkInsert("videos", "id", "organization_id", "project_id", "title", "tags", "status",
+		"source_key", "created_by")
+	imports := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		u, _ := url.Parse(entry.url)
+		filename := path.Base(u.Path)
+		title := entry.title
+		if title == "" {
+			title = truncateRunes(filename, 255)
+			if title == "" || title == "." || title == "/" {
+				title = u.Hostname()
+			}
+		}
+		tags := normalizeImportTags(append(append([]string{}, payload.Tags...), entry.tags...))
+
+		videoID := uuid.New()
+		videos.Add(videoID, *job.OrganizationID, payload.ProjectID, title, pq.Array(tags), models.VideoStatusUploading,
+			SourceKey(*job.OrganizationID, videoID, filename), job.CreatedBy)
+		imports = append(imports, ImportPayload{VideoID: videoID, URL: entry.url})
+	}
+
+	events := make([]outbox.NewEvent, 0, len(entries))
+	if err := videos.Query(ctx, tx, VideoColumns, func(rows *sql.Rows) error {
+		v, err := ScanVideo(rows)
+		if err != nil {
+			return err
+		}
+		events = append(events, VideoEvent(outbox.EventVideoCreated, v))
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to insert videos: %w", err)
+	}
+	if err := outbox.WriteAll(ctx, tx, events); err != nil {
+		return err
+	}
+	if err := jobs.EnqueueAll(ctx, tx, JobKindVideoImport, imports,
+		jobs.Options{OrganizationID: job.OrganizationID, CreatedBy: job.CreatedBy, ParentID: &job.ID}); err != nil {
+		return err
+	}
+	cp.Queued += len(entries)
+
+	if err := jobs.SaveCheckpoint(ctx, tx, job.ID, cp); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (b *BulkImporter) fetchManifest(ctx context.Context, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", jobs.Permanent(err)
+	}
+	req.Header.Set("User-Agent", "OpenVDO-Importer/1.0")
+
+	resp, err := b.importer.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return "", jobs.Permanent(err)
+		}
+		return "", fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("manifest URL returned %s", resp.Status)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return "", jobs.Permanent(err)
+		}
+		return "", err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, b.config.BulkManifestMaxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if int64(len(data)) > b.config.BulkManifestMaxSize {
+		return "", jobs.Permanent(fmt.Errorf("manifest is larger than %d bytes", b.config.BulkManifestMaxSize))
+	}
+	if !utf8.Valid(data) {
+		return "", jobs.Permanent(fmt.Errorf("manifest is not UTF-8 text"))
+	}
+	return string(data), nil
+}
+
+// readManifest returns up to limit valid entries after the checkpoint's row. Invalid rows are
+// counted as skipped and do not use up the limit.
+func (b *BulkImporter) readManifest(manifest string, cp *BulkImportCheckpoint, limit int) ([]bulkImportEntry, bool, error) {
+	r := csv.NewReader(strings.NewReader(manifest))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, false, jobs.Permanent(fmt.Errorf("invalid manifest: %w", err))
+	}
+
+	// Columns are positional unless the first row names them
+	cols := map[string]int{"url": 0, "title": 1, "tags": 2}
+	start := 0
+	if len(records) > 0 {
+		header := make(map[string]int)
+		for i, name := range records[0] {
+			header[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		if _, ok := header["url"]; ok {
+			cols = header
+			start = 1
+		}
+	}
+	records = records[start:]
+	cp.Total = len(records)
+
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []bulkImportEntry
+	for cp.Row < len(records) && len(entries) < limit {
+		record := records[cp.Row]
+		cp.Row++
+
+		raw := field(record, "url")
+		if raw == "" {
+			if strings.TrimSpace(strings.Join(record, "")) != "" {
+				cp.skip("row %d: missing url", cp.Row+start)
+			}
+			continue
+		}
+		u, err := b.importer.ParseURL(raw)
+		if err != nil {
+			cp.skip("row %d: %v", cp.Row+start, err)
+			continue
+		}
+		entries = append(entries, bulkImportEntry{
+			url:   u.String(),
+			title: truncateRunes(field(record, "title"), 255),
+			tags: strings.FieldsFunc(field(record, "tags"), func(r rune) bool {
+				return r == ';' || r == '|' || r == ','
+			}),
+		})
+	}
+	return entries, cp.Row < len(records), nil
+}
+
+// listS3 returns up to limit objects after the checkpoint's key, as presigned URLs. Folder
+// markers and empty objects are passed over.
+func (b *BulkImporter) listS3(ctx context.Context, payload *BulkImportPayload, cp *BulkImportCheckpoint, limit int) ([]bulkImportEntry, bool, error) {
+	src := payload.S3
+	opts := s3.Options{
+		Region:       src.Region,
+		Credentials:  aws.AnonymousCredentials{},
+		HTTPClient:   b.importer.client,
+		UsePathStyle: src.ForcePathStyle,
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if payload.Secrets != nil {
+		opts.Credentials = credentials.NewStaticCredentialsProvider(payload.Secrets.AccessKeyID, payload.Secrets.SecretAccessKey, "")
+	}
+	if src.Endpoint != "" {
+		opts.BaseEndpoint = aws.String(src.Endpoint)
+	}
+	client := s3.New(opts)
+	presign := s3.NewPresignClient(client)
+
+	var entries []bulkImportEntry
+	for len(entries) < limit {
+		input := &s3.ListObjectsV2Input{
+			Bucket:  aws.String(src.Bucket),
+			MaxKeys: aws.Int32(int32(limit - len(entries))),
+		}
+		if src.Prefix != "" {
+			input.Prefix = aws.String(src.Prefix)
+		}
+		if cp.StartAfter != "" {
+			input.StartAfter = aws.String(cp.StartAfter)
+		}
+
+		out, err := client.ListObjectsV2(ctx, input)
+		if err != nil {
+			var apiErr smithy.APIError
+			if errors.As(err, &apiErr) && permanentS3Errors[apiErr.ErrorCode()] {
+				return nil, false, jobs.Permanent(fmt.Errorf("failed to list bucket: %w", err))
+			}
+			if errors.Is(err, ErrBlockedAddress) {
+				return nil, false, jobs.Permanent(err)
+			}
+			return nil, false, fmt.Errorf("failed to list bucket: %w", err)
+		}
+
+		for _, obj := range out.Contents {
+			key := aws.ToString(obj.Key)
+			cp.StartAfter = key
+			if strings.HasSuffix(key, "/") || aws.ToInt64(obj.Size) == 0 {
+				continue
+			}
+
+			req, err := presign.PresignGetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(src.Bucket), Key: aws.String(key)},
+				s3.WithPresignExpires(bulkImportPresignExpiry))
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to presign %s: %w", key, err)
+			}
+			entries = append(entries, bulkImportEntry{url: req.URL})
+		}
+
+		if !aws.ToBool(out.IsTruncated) {
+			return entries, false, nil
+		}
+	}
+	return entries, true, nil
+}
+
+// normalizeImportTags applies NormalizeTags and the limits UpdateVideo enforces on request tags
+func normalizeImportTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, tag := range NormalizeTags(tags) {
+		if utf8.RuneCountInString(tag) <= maxBulkImportTagLen && len(out) < maxBulkImportTags {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+
+	"github.com/google/uuid"
+)
+
+// JobKindCDNPurge asks the CDN to drop cached copies of a video's playback URLs or of feeds
+const JobKindCDNPurge = "cdn.purge"
+
+// PurgePayload is the payload of a cdn.purge job
+type PurgePayload struct {
+	// VideoID is the video whose playback is purged; nil for feeds
+	VideoID *uuid.UUID `json:"video_id,omitempty"`
+	Paths   []string   `json:"paths"`
+}
+
+// CachePurger forwards purge requests to the webhook configured with CDN_PURGE_URL. The
+// webhook receives {"paths": [...], "urls": [...]}, with URLs only when PUBLIC_URL is set,
+// and translates them to the CDN's own purge API.
+type CachePurger struct {
+	client    *http.Client
+	config    config.CDN
+	publicURL string
+}
+
+// NewCachePurger creates a purger; publicURL turns paths into absolute URLs
+func NewCachePurger(cfg config.CDN, publicURL string) *CachePurger {
+	return &CachePurger{
+		client:    &http.Client{Timeout: cfg.Timeout},
+		config:    cfg,
+		publicURL: publicURL,
+	}
+}
+
+// Enabled reports whether a purge webhook is configured
+func (p *CachePurger) Enabled() bool {
+	return p != nil && p.config.PurgeURL != ""
+}
+
+// PlaybackPaths lists the paths under which a video's playback is served and cached
+func PlaybackPaths(videoID uuid.UUID) []string {
+	return []string{
+		fmt.Sprintf("/embed/%s", videoID),
+		fmt.Sprintf("/embed/%s/media", videoID),
+	}
+}
+
+// Handle runs a cdn.purge job
+func (p *CachePurger) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload PurgePayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	if !p.Enabled() {
+		// Purging was turned off after the job was queued
+		return nil
+	}
+
+	body := map[string][]string{"paths": payload.Paths}
+	if p.publicURL != "" {
+		urls := make([]string, len(payload.Paths))
+		for i, path := range payload.Paths {
+			urls[i] = p.publicURL + path
+		}
+		body["urls"] = urls
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.PurgeURL, bytes.NewReader(data))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.config.PurgeToken != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.PurgeToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("purge request failed: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("purge webhook returned %s", resp.Status)
+	}
+	return nil
+}
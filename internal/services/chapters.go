@@ -0,0 +1,232 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"openvdo/internal/models"
+)
+
+const (
+	// MaxChapters bounds the chapters of a video
+	MaxChapters     = 500
+	maxChapterTitle = 200
+	// openChapterEnd ends the last cue of a WebVTT chapters track, whose end is the end of the
+	// video; players stop at the media's duration
+	openChapterEnd = "99:59:59.999"
+)
+
+// ErrInvalidChapters is returned for chapter lists and files that cannot be used
+var ErrInvalidChapters = errors.New("invalid chapters")
+
+// NormalizeChapters trims the titles and sorts the chapters by start. Starts must be distinct
+// and not negative, and titles not empty.
+func NormalizeChapters(chapters []models.Chapter) (models.Chapters, error) {
+	if len(chapters) > MaxChapters {
+		return nil, fmt.Errorf("%w: a video has at most %d chapters", ErrInvalidChapters, MaxChapters)
+	}
+
+	out := make(models.Chapters, 0, len(chapters))
+	for _, ch := range chapters {
+		ch.Title = strings.Join(strings.Fields(ch.Title), " ")
+		if ch.Title == "" {
+			return nil, fmt.Errorf("%w: chapter at %s has no title", ErrInvalidChapters, formatChapterTime(ch.StartSeconds))
+		}
+		if utf8.RuneCountInString(ch.Title) > maxChapterTitle {
+			return nil, fmt.Errorf("%w: chapter titles have at most %d characters", ErrInvalidChapters, maxChapterTitle)
+		}
+		if ch.StartSeconds < 0 || math.IsNaN(ch.StartSeconds) || math.IsInf(ch.StartSeconds, 0) {
+			return nil, fmt.Errorf("%w: chapter %q starts at an invalid time", ErrInvalidChapters, ch.Title)
+		}
+		// Millisecond precision, as in WebVTT
+		ch.StartSeconds = math.Round(ch.StartSeconds*1000) / 1000
+		out = append(out, ch)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].StartSeconds < out[j].StartSeconds })
+	for i := 1; i < len(out); i++ {
+		if out[i].StartSeconds == out[i-1].StartSeconds {
+			return nil, fmt.Errorf("%w: two chapters start at %s", ErrInvalidChapters, formatChapterTime(out[i].StartSeconds))
+		}
+	}
+	return out, nil
+}
+
+// ParseChapterFile extracts chapters from a WebVTT chapters track, an SRT file, an FFmpeg
+// metadata file or a plain-text list with a timestamp at the start of each line, as found in
+// video descriptions ("0:00 Intro", "12:34 - Q&A"). Lines of a list without a timestamp are
+// ignored. The chapters are normalized.
+func ParseChapterFile(data []byte) (models.Chapters, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("%w: file is not UTF-8 text", ErrInvalidChapters)
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	var chapters []models.Chapter
+	var err error
+	switch {
+	case strings.HasPrefix(text, ";FFMETADATA"):
+		chapters, err = parseFFMetadataChapters(text)
+	case strings.Contains(text, "-->"):
+		chapters, err = parseCueChapters(text)
+	default:
+		chapters = parseListChapters(text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("%w: no chapters found in file", ErrInvalidChapters)
+	}
+	return NormalizeChapters(chapters)
+}
+
+// parseCueChapters reads the cues of a WebVTT or SRT file; each cue's text is a chapter title
+func parseCueChapters(text string) ([]models.Chapter, error) {
+	var chapters []models.Chapter
+	for _, block := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		for i, line := range lines {
+			timing, _, found := strings.Cut(line, "-->")
+			if !found {
+				continue
+			}
+			start, ok := parseChapterTime(strings.TrimSpace(timing))
+			if !ok {
+				return nil, fmt.Errorf("%w: invalid cue timing %q", ErrInvalidChapters, strings.TrimSpace(line))
+			}
+			chapters = append(chapters, models.Chapter{StartSeconds: start, Title: html.UnescapeString(strings.Join(lines[i+1:], " "))})
+			break
+		}
+	}
+	return chapters, nil
+}
+
+// parseFFMetadataChapters reads the [CHAPTER] sections of an FFmpeg metadata file
+func parseFFMetadataChapters(text string) ([]models.Chapter, error) {
+	var chapters []models.Chapter
+	var current map[string]string
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		num, den := 1.0, 1000.0
+		if tb, ok := current["TIMEBASE"]; ok {
+			n, d, _ := strings.Cut(tb, "/")
+			var errN, errD error
+			num, errN = strconv.ParseFloat(n, 64)
+			den, errD = strconv.ParseFloat(d, 64)
+			if errN != nil || errD != nil || den == 0 {
+				return fmt.Errorf("%w: invalid TIMEBASE %q", ErrInvalidChapters, tb)
+			}
+		}
+		start, err := strconv.ParseFloat(current["START"], 64)
+		if err != nil {
+			return fmt.Errorf("%w: invalid chapter START %q", ErrInvalidChapters, current["START"])
+		}
+		chapters = append(chapters, models.Chapter{StartSeconds: start * num / den, Title: current["title"]})
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = nil
+			if line == "[CHAPTER]" {
+				current = map[string]string{}
+			}
+			continue
+		}
+		if current == nil || line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			current[key] = value
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return chapters, nil
+}
+
+// listChapterLine matches "0:00 Intro", "[01:02:03] Outro" and "12:34 - Q&A"
+var listChapterLine = regexp.MustCompile(`^[\[(]?(\d{1,2}(?::\d{1,2}){1,2}(?:[.,]\d{1,3})?)[\])]?\s*(?:[-–—:|]\s*)?(.+)$`)
+
+func parseListChapters(text string) []models.Chapter {
+	var chapters []models.Chapter
+	for _, line := range strings.Split(text, "\n") {
+		m := listChapterLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		if start, ok := parseChapterTime(m[1]); ok {
+			chapters = append(chapters, models.Chapter{StartSeconds: start, Title: m[2]})
+		}
+	}
+	return chapters
+}
+
+// parseChapterTime parses [hh:]mm:ss with optional fractions, separated by a dot or, as in
+// SRT, a comma
+func parseChapterTime(s string) (float64, bool) {
+	parts := strings.Split(strings.Replace(s, ",", ".", 1), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	var seconds float64
+	for i, part := range parts {
+		last := i == len(parts)-1
+		var value float64
+		var err error
+		if last {
+			value, err = strconv.ParseFloat(part, 64)
+		} else {
+			var n int
+			n, err = strconv.Atoi(part)
+			value = float64(n)
+		}
+		if err != nil || value < 0 || (i > 0 && value >= 60) {
+			return 0, false
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds, true
+}
+
+func formatChapterTime(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// ChaptersVTT renders chapters as a WebVTT chapters track, for <track kind="chapters">
+func ChaptersVTT(chapters models.Chapters) []byte {
+	var b bytes.Buffer
+	b.WriteString("WEBVTT\n")
+	for i, ch := range chapters {
+		end := openChapterEnd
+		if i+1 < len(chapters) {
+			end = formatChapterTime(chapters[i+1].StartSeconds)
+		}
+		// Cue text escapes like HTML, and "-->" would be read as a timing
+		title := vttEscaper.Replace(ch.Title)
+		fmt.Fprintf(&b, "\n%d\n%s --> %s\n%s\n", i+1, formatChapterTime(ch.StartSeconds), end, title)
+	}
+	return b.Bytes()
+}
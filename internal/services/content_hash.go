@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const pendingHashBatchSize = 500
+
+// HashResult is the outcome of hashing a video source
+type HashResult struct {
+	SHA256      string
+	SizeBytes   int64
+	DuplicateOf *uuid.UUID
+	Reused      bool
+}
+
+// ContentHasher computes SHA-256 digests of uploaded sources and applies the duplicate policy.
+// Multipart uploads go straight to storage, so sources are hashed by streaming them back
+// once the upload completes.
+type ContentHasher struct {
+	db      *sql.DB
+	storage storage.Storage
+	policy  string
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewContentHasher creates a content hasher. The master connection is used because hashing
+// runs in the background outside any request.
+func NewContentHasher(db *sql.DB, store storage.Storage, cfg config.Storage) *ContentHasher {
+	workers := cfg.HashWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ContentHasher{
+		db:      db,
+		storage: store,
+		policy:  cfg.DuplicatePolicy,
+		sem:     make(chan struct{}, workers),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Policy returns the configured duplicate policy
+func (h *ContentHasher) Policy() string {
+	return h.policy
+}
+
+// Start queues uploaded videos that were never hashed, e.g. because the server stopped mid-hash
+func (h *ContentHasher) Start() {
+	ids, err := queries.New(h.db).ListUnhashedVideoIDs(h.ctx, queries.ListUnhashedVideoIDsParams{
+		Statuses:  models.PlayableVideoStatuses,
+		MaxVideos: pendingHashBatchSize,
+	})
+	if err != nil {
+		logger.Error("Failed to query unhashed videos: %v", err)
+		return
+	}
+	for _, id := range ids {
+		h.Enqueue(id)
+	}
+
+	if len(ids) > 0 {
+		logger.Info("Queued %d unhashed videos for content hashing", len(ids))
+	}
+}
+
+// Stop cancels running hashes and waits for the workers to exit
+func (h *ContentHasher) Stop() {
+	h.cancel()
+	h.wg.Wait()
+}
+
+// Enqueue hashes the source of a video in the background
+func (h *ContentHasher) Enqueue(videoID uuid.UUID) {
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		select {
+		case h.sem <- struct{}{}:
+		case <-h.ctx.Done():
+			return
+		}
+		defer func() { <-h.sem }()
+
+		result, err := h.HashSource(h.ctx, videoID)
+		if err != nil {
+			logger.Error("Failed to hash source of video %s: %v", videoID, err)
+			return
+		}
+		if result.DuplicateOf != nil {
+			logger.Info("Video %s is a duplicate of %s (reused: %v)", videoID, *result.DuplicateOf, result.Reused)
+		}
+	}()
+}
+
+// HashSource streams a video's source from storage, records its SHA-256 and applies the
+// duplicate policy. With the reuse policy the new copy is deleted and the video points at
+// the source of the original.
+func (h *ContentHasher) HashSource(ctx context.Context, videoID uuid.UUID) (*HashResult, error) {
+	video, err := queries.New(h.db).GetVideoSource(ctx, videoID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load video: %w", err)
+	}
+	if video.SourceKey == nil {
+		return nil, fmt.Errorf("failed to load video: %s has no source", videoID)
+	}
+	orgID, key := video.OrganizationID, *video.SourceKey
+
+	r, err := h.storage.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open source: %w", err)
+	}
+	defer r.Close()
+
+	digest := sha256.New()
+	n, err := io.Copy(digest, r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source: %w", err)
+	}
+	result := &HashResult{SHA256: hex.EncodeToString(digest.Sum(nil)), SizeBytes: n}
+
+	var original *models.Video
+	if h.policy != models.DuplicatePolicyOff {
+		original, err = FindDuplicate(ctx, h.db, orgID, result.SHA256, videoID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up duplicates: %w", err)
+		}
+	}
+	if original == nil {
+		err := queries.New(h.db).SetVideoHash(ctx, queries.SetVideoHashParams{SHA256: result.SHA256, SizeBytes: n, ID: videoID})
+		return result, err
+	}
+
+	result.DuplicateOf = &original.ID
+	if h.policy != models.DuplicatePolicyReuse || original.SourceKey == key {
+		err := queries.New(h.db).SetVideoDuplicate(ctx, queries.SetVideoDuplicateParams{
+			SHA256: result.SHA256, SizeBytes: n, DuplicateOf: original.ID, ID: videoID,
+		})
+		return result, err
+	}
+
+	tx, err := h.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := queries.New(tx).ReuseVideoSource(ctx, queries.ReuseVideoSourceParams{
+		SHA256: result.SHA256, SizeBytes: n, DuplicateOf: original.ID, SourceKey: original.SourceKey, ID: videoID,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = $1`, key); err != nil {
+		return nil, err
+	}
+	if err := MarkAccessed(ctx, tx, original.SourceKey); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	// The database no longer references the copy; a failed delete only leaves an orphan behind
+	if err := h.storage.Delete(ctx, key); err != nil {
+		logger.Error("Failed to delete duplicate source %s: %v", key, err)
+	}
+
+	result.Reused = true
+	return result, nil
+}
+
+// FindDuplicate returns the earliest uploaded video of an organization with the given content
+// hash, ignoring excludeID. Videos that are themselves duplicates are skipped so every copy
+// points at the same original.
+func FindDuplicate(ctx context.Context, q database.Querier, orgID uuid.UUID, sum string, excludeID uuid.UUID) (*models.Video, error) {
+	video, err := VideoModel(queries.New(q).FindDuplicateVideo(ctx, queries.FindDuplicateVideoParams{
+		OrganizationID: orgID,
+		SHA256:         sum,
+		ExcludeID:      excludeID,
+		Statuses:       models.PlayableVideoStatuses,
+	}))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return video, err
+}
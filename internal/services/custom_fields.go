@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrInvalidCustomField is returned for values that do not match their field, and for keys no
+// field is defined for
+var ErrInvalidCustomField = errors.New("invalid custom field")
+
+// CustomFieldKeyPattern is what keys of custom fields look like
+var CustomFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// maxCustomStringLength bounds the values of string fields, in characters
+const maxCustomStringLength = 1000
+
+// CustomFieldColumns is the column list of custom_fields matching ScanCustomField
+const CustomFieldColumns = `id, organization_id, key, name, type, options, created_by, created_at, updated_at`
+
+// ScanCustomField scans a row selected with CustomFieldColumns
+func ScanCustomField(row interface{ Scan(...interface{}) error }) (*models.CustomField, error) {
+	f := models.CustomField{Options: []string{}}
+	if err := row.Scan(&f.ID, &f.OrganizationID, &f.Key, &f.Name, &f.Type, pq.Array(&f.Options), &f.CreatedBy,
+		&f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// CustomFields returns the custom fields of an organization by key
+func CustomFields(ctx context.Context, q database.Querier, orgID uuid.UUID) (map[string]models.CustomField, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+CustomFieldColumns+` FROM custom_fields WHERE organization_id = $1`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := map[string]models.CustomField{}
+	for rows.Next() {
+		f, err := ScanCustomField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields[f.Key] = *f
+	}
+	return fields, rows.Err()
+}
+
+// CheckCustomFields validates values against an organization's fields. Values are normalized
+// to what is stored: numbers as JSON numbers and dates as YYYY-MM-DD. A null value removes the
+// field from the video and is returned among the keys to unset.
+func CheckCustomFields(fields map[string]models.CustomField, values map[string]interface{}) (models.CustomFieldValues, []string, error) {
+	set := models.CustomFieldValues{}
+	unset := []string{}
+	for key, value := range values {
+		field, ok := fields[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: no field %q is defined", ErrInvalidCustomField, key)
+		}
+		if value == nil {
+			unset = append(unset, key)
+			continue
+		}
+		normalized, err := checkCustomValue(field, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		set[key] = normalized
+	}
+	slices.Sort(unset)
+	return set, unset, nil
+}
+
+func checkCustomValue(field models.CustomField, value interface{}) (interface{}, error) {
+	switch field.Type {
+	case models.CustomFieldNumber:
+		if n, ok := value.(float64); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidCustomField, field.Key)
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s must be a string", ErrInvalidCustomField, field.Key)
+	}
+	switch field.Type {
+	case models.CustomFieldDate:
+		t, err := time.Parse(models.CustomFieldDateLayout, s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a date like 2006-01-02", ErrInvalidCustomField, field.Key)
+		}
+		return t.Format(models.CustomFieldDateLayout), nil
+	case models.CustomFieldEnum:
+		if !slices.Contains(field.Options, s) {
+			return nil, fmt.Errorf("%w: %s must be one of %v", ErrInvalidCustomField, field.Key, field.Options)
+		}
+	default:
+		if utf8.RuneCountInString(s) > maxCustomStringLength {
+			return nil, fmt.Errorf("%w: %s must be at most %d characters", ErrInvalidCustomField, field.Key,
+				maxCustomStringLength)
+		}
+	}
+	return s, nil
+}
+
+// CustomFieldFilters turns filters on custom fields into conditions on videos, appending their
+// arguments to args. equal matches values exactly; atLeast and atMost bound number and date
+// fields.
+func CustomFieldFilters(fields map[string]models.CustomField, equal, atLeast, atMost map[string]string, args *[]interface{}) ([]string, error) {
+	var conditions []string
+	for _, key := range slices.Sorted(maps.Keys(equal)) {
+		field, ok := fields[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: no field %q is defined", ErrInvalidCustomField, key)
+		}
+		var value interface{} = equal[key]
+		if field.Type == models.CustomFieldNumber {
+			n, err := strconv.ParseFloat(equal[key], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidCustomField, key)
+			}
+			value = n
+		}
+		normalized, err := checkCustomValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		*args = append(*args, models.CustomFieldValues{key: normalized})
+		conditions = append(conditions, "custom_fields @> $"+strconv.Itoa(len(*args))+"::jsonb")
+	}
+
+	bounds := []struct {
+		values map[string]string
+		op     string
+	}{{atLeast, ">="}, {atMost, "<="}}
+	for _, b := range bounds {
+		for _, key := range slices.Sorted(maps.Keys(b.values)) {
+			field, ok := fields[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: no field %q is defined", ErrInvalidCustomField, key)
+			}
+			var cast string
+			var value interface{}
+			switch field.Type {
+			case models.CustomFieldNumber:
+				n, err := strconv.ParseFloat(b.values[key], 64)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidCustomField, key)
+				}
+				cast, value = "numeric", n
+			case models.CustomFieldDate:
+				t, err := time.Parse(models.CustomFieldDateLayout, b.values[key])
+				if err != nil {
+					return nil, fmt.Errorf("%w: %s must be a date like 2006-01-02", ErrInvalidCustomField, key)
+				}
+				cast, value = "date", t.Format(models.CustomFieldDateLayout)
+			default:
+				return nil, fmt.Errorf("%w: only number and date fields have ranges", ErrInvalidCustomField)
+			}
+			// Keys match CustomFieldKeyPattern, so they are safe to quote into the query
+			*args = append(*args, value)
+			conditions = append(conditions, fmt.Sprintf("(custom_fields->>'%s')::%s %s $%d::%s",
+				key, cast, b.op, len(*args), cast))
+		}
+	}
+	return conditions, nil
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// JobKindVideoDownload renders the file of a video download
+const JobKindVideoDownload = "video.download"
+
+// downloadBatchSize bounds the expired downloads deleted per statement
+const downloadBatchSize = 100
+
+var (
+	// ErrDownloadsDisabled is returned for videos that do not allow downloads, or cannot be
+	// downloaded in their current state
+	ErrDownloadsDisabled = errors.New("downloads are not enabled for this video")
+	// ErrRenditionNotDownloadable is returned for renditions that are not of the video, or are
+	// manifests of several qualities rather than one
+	ErrRenditionNotDownloadable = errors.New("rendition cannot be downloaded")
+)
+
+// DownloadColumns is the column list matching ScanDownload
+const DownloadColumns = `id, video_id, organization_id, requested_by, rendition_id, source_revision, watermark, status,
+	COALESCE(object_key, ''), size_bytes, error, expires_at, created_at, updated_at`
+
+// ScanDownload scans a row selected with DownloadColumns
+func ScanDownload(row interface{ Scan(...interface{}) error }) (*models.VideoDownload, error) {
+	var d models.VideoDownload
+	err := row.Scan(&d.ID, &d.VideoID, &d.OrganizationID, &d.RequestedBy, &d.RenditionID, &d.SourceRevision, &d.Watermark,
+		&d.Status, &d.ObjectKey, &d.SizeBytes, &d.Error, &d.ExpiresAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DownloadKey builds the storage key of a download's file
+func DownloadKey(orgID, videoID, downloadID uuid.UUID) string {
+	return fmt.Sprintf("orgs/%s/videos/%s/downloads/%s.mp4", orgID, videoID, downloadID)
+}
+
+// Downloader renders the files members download from videos as MP4, remuxing the source or a
+// rendition and burning in a watermark when one was asked for
+type Downloader struct {
+	db      *sql.DB
+	storage storage.Storage
+	ffmpeg  string
+	config  config.Downloads
+}
+
+// NewDownloader creates a downloader. It fails when ffmpeg cannot be found. db must not carry
+// a tenant context.
+func NewDownloader(db *sql.DB, store storage.Storage, cfg config.Downloads) (*Downloader, error) {
+	if cfg.FFmpegPath == "" {
+		return nil, fmt.Errorf("DOWNLOADS_FFMPEG_PATH is empty")
+	}
+	ffmpeg, err := exec.LookPath(cfg.FFmpegPath)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found at %s", cfg.FFmpegPath)
+	}
+	return &Downloader{db: db, storage: store, ffmpeg: ffmpeg, config: cfg}, nil
+}
+
+type downloadPayload struct {
+	DownloadID uuid.UUID `json:"download_id"`
+}
+
+// Request records a download of a video by a member in tx, which carries the member's tenant
+// context, and queues its rendering. A file of the same source revision, rendition and
+// watermark that is kept for at least another link's lifetime is returned instead, with
+// reused set.
+func (d *Downloader) Request(ctx context.Context, tx *sql.Tx, video *models.Video, renditionID *uuid.UUID, watermark bool, userID uuid.UUID) (*models.VideoDownload, bool, error) {
+	if !video.DownloadsEnabled || !models.VideoPlayable(video.Status) || video.ModerationStatus != models.ModerationActive ||
+		video.SourceKey == "" {
+		return nil, false, ErrDownloadsDisabled
+	}
+	if renditionID != nil {
+		var contentType string
+		var height *int
+		err := tx.QueryRowContext(ctx, `SELECT content_type, height FROM video_renditions WHERE id = $1 AND video_id = $2`,
+			*renditionID, video.ID).Scan(&contentType, &height)
+		if err == sql.ErrNoRows {
+			return nil, false, fmt.Errorf("%w: not a rendition of the video", ErrRenditionNotDownloadable)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		// Master playlists and DASH manifests list qualities; a variant or file holds one
+		if contentType == "application/dash+xml" || (contentType == "application/vnd.apple.mpegurl" && height == nil) {
+			return nil, false, fmt.Errorf("%w: it lists several qualities; choose one of its variants", ErrRenditionNotDownloadable)
+		}
+	}
+
+	var text *string
+	if watermark {
+		email, err := queries.New(d.db).GetUserEmail(ctx, userID)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to look up watermark: %w", err)
+		}
+		text = &email
+	}
+
+	existing, err := ScanDownload(tx.QueryRowContext(ctx, `
+		SELECT `+DownloadColumns+` FROM video_downloads
+		WHERE video_id = $1 AND source_revision = $2 AND rendition_id IS NOT DISTINCT FROM $3
+			AND watermark IS NOT DISTINCT FROM $4 AND status <> $5 AND expires_at > $6
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		video.ID, video.SourceRevision, renditionID, text, models.DownloadFailed, time.Now().Add(d.config.LinkTTL)))
+	if err == nil {
+		return existing, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+
+	download, err := ScanDownload(tx.QueryRowContext(ctx, `
+		INSERT INTO video_downloads (video_id, organization_id, requested_by, rendition_id, source_revision, watermark, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+DownloadColumns,
+		video.ID, video.OrganizationID, userID, renditionID, video.SourceRevision, text, time.Now().Add(d.config.Retention)))
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = jobs.Enqueue(ctx, tx, JobKindVideoDownload, downloadPayload{DownloadID: download.ID},
+		jobs.Options{OrganizationID: &video.OrganizationID, CreatedBy: &userID})
+	return download, false, err
+}
+
+// Handle runs a video.download job. Downloads whose rendering gives up are marked failed with
+// the reason.
+func (d *Downloader) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload downloadPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	err := d.render(ctx, payload.DownloadID, progress)
+	if err != nil && ctx.Err() == nil && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+		if _, dbErr := d.db.ExecContext(context.Background(), `
+			UPDATE video_downloads SET status = $2, error = $3 WHERE id = $1 AND status = $4`,
+			payload.DownloadID, models.DownloadFailed, err.Error(), models.DownloadPending); dbErr != nil {
+			logger.Error("Failed to mark download %s failed: %v", payload.DownloadID, dbErr)
+		}
+	}
+	return err
+}
+
+func (d *Downloader) render(ctx context.Context, downloadID uuid.UUID, progress func(float64)) error {
+	var orgID, videoID uuid.UUID
+	var status, sourceKey, renditionURL string
+	var watermark sql.NullString
+	err := d.db.QueryRowContext(ctx, `
+		SELECT dl.organization_id, dl.video_id, dl.status, dl.watermark, COALESCE(v.source_key, ''), COALESCE(r.url, '')
+		FROM video_downloads dl
+		JOIN videos v ON v.id = dl.video_id
+		LEFT JOIN video_renditions r ON r.id = dl.rendition_id
+		WHERE dl.id = $1`, downloadID).Scan(&orgID, &videoID, &status, &watermark, &sourceKey, &renditionURL)
+	if err == sql.ErrNoRows || (err == nil && status != models.DownloadPending) {
+		// Deleted since, or rendered by an earlier attempt
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if renditionURL == "" && sourceKey == "" {
+		return jobs.Permanent(fmt.Errorf("video %s has no source", videoID))
+	}
+
+	renderCtx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+	dir, err := os.MkdirTemp("", "openvdo-download-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var args []string
+	switch {
+	case renditionURL != "":
+		// Playlists must not send ffmpeg to local files
+		args = append(args, "-protocol_whitelist", "http,https,tcp,tls,crypto", "-i", renditionURL)
+	default:
+		input, err := d.input(renderCtx, dir, sourceKey)
+		if err != nil {
+			return err
+		}
+		args = append(args, "-i", input)
+	}
+	args = append(args, "-map", "0:v:0", "-map", "0:a:0?")
+	if watermark.Valid {
+		// Read from a file, so no character of the address needs escaping in the filter graph
+		textFile := filepath.Join(dir, "watermark.txt")
+		if err := os.WriteFile(textFile, []byte(watermark.String), 0o600); err != nil {
+			return err
+		}
+		args = append(args, "-vf", "drawtext=textfile="+textFile+
+			":expansion=none:fontcolor=white@0.6:fontsize=h/28:borderw=2:bordercolor=black@0.4:x=w-tw-h/28:y=h-th-h/28",
+			"-c:v", "libx264", "-preset", "veryfast", "-crf", "20", "-pix_fmt", "yuv420p")
+	} else {
+		args = append(args, "-c:v", "copy")
+	}
+	out := filepath.Join(dir, "download.mp4")
+	args = append(args, "-c:a", "aac", "-b:a", "192k", "-movflags", "+faststart", out)
+	if err := d.run(renderCtx, args...); err != nil {
+		return err
+	}
+	progress(0.9)
+
+	f, err := os.Open(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	key := DownloadKey(orgID, videoID, downloadID)
+	if err := d.storage.Put(ctx, key, f, info.Size(), "video/mp4"); err != nil {
+		return fmt.Errorf("failed to store download: %w", err)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO storage_objects (organization_id, video_id, object_key, kind, size_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (object_key) DO NOTHING
+	`, orgID, videoID, key, models.ObjectKindDownload, info.Size()); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE video_downloads SET status = $2, object_key = $3, size_bytes = $4 WHERE id = $1 AND status = $5`,
+		downloadID, models.DownloadReady, key, info.Size(), models.DownloadPending); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	progress(1)
+	logger.Info("Rendered download %s of video %s, %d bytes", downloadID, videoID, info.Size())
+	return nil
+}
+
+// input returns what ffmpeg reads a source from: a presigned URL where the backend has them,
+// so only the streams are fetched, or a downloaded copy
+func (d *Downloader) input(ctx context.Context, dir, key string) (string, error) {
+	if presigner, ok := storage.AsPresigner(d.storage); ok {
+		return presigner.PresignGet(ctx, key, d.config.Timeout+time.Minute)
+	}
+	r, err := d.storage.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	path := filepath.Join(dir, "source")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+func (d *Downloader) run(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, d.ffmpeg, append([]string{"-v", "error", "-y"}, args...)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// DeleteExpiredDownloads deletes downloads past their expiry and their files. It runs whether
+// or not downloads can be rendered, so files rendered before ffmpeg went away are removed too.
+func DeleteExpiredDownloads(ctx context.Context, db *sql.DB, store storage.Storage) (int, error) {
+	deleted := 0
+	for {
+		rows, err := db.QueryContext(ctx, `
+			SELECT id, COALESCE(object_key, '') FROM video_downloads
+			WHERE expires_at < NOW()
+			ORDER BY expires_at
+			LIMIT $1`, downloadBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+		var ids []uuid.UUID
+		var keys []string
+		for rows.Next() {
+			var id uuid.UUID
+			var key string
+			if err := rows.Scan(&id, &key); err != nil {
+				rows.Close()
+				return deleted, err
+			}
+			ids = append(ids, id)
+			if key != "" {
+				keys = append(keys, key)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(ids) == 0 {
+			return deleted, err
+		}
+
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				return deleted, fmt.Errorf("failed to delete %s: %w", key, err)
+			}
+		}
+		if _, err := db.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = ANY($1)`, pq.Array(keys)); err != nil {
+			return deleted, err
+		}
+		res, err := db.ExecContext(ctx, `DELETE FROM video_downloads WHERE id = ANY($1)`, pq.Array(ids))
+		if err != nil {
+			return deleted, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += int(n)
+	}
+}
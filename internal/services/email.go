@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+)
+
+// EmailNotifier delivers notifications as plain-text emails through an SMTP relay
+type EmailNotifier struct {
+	config config.Email
+	from   *mail.Address
+}
+
+// NewEmailNotifier creates an email notifier, or returns nil when no SMTP host is configured
+func NewEmailNotifier(cfg config.Email) (*EmailNotifier, error) {
+	if cfg.SMTPHost == "" {
+		return nil, nil
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_FROM: %w", err)
+	}
+	return &EmailNotifier{config: cfg, from: from}, nil
+}
+
+// Channel implements Notifier
+func (e *EmailNotifier) Channel() string {
+	return models.NotificationChannelEmail
+}
+
+// Notify implements Notifier
+func (e *EmailNotifier) Notify(ctx context.Context, to Recipient, n *models.Notification) error {
+	rcpt, err := mail.ParseAddress(to.Email)
+	if err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid recipient address: %w", err))
+	}
+	rcpt.Name = to.Name
+
+	msg, err := e.message(rcpt, n)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	return e.send(ctx, rcpt.Address, msg)
+}
+
+func (e *EmailNotifier) message(to *mail.Address, n *models.Notification) ([]byte, error) {
+	var buf bytes.Buffer
+	headers := [][2]string{
+		{"From", e.from.String()},
+		{"To", to.String()},
+		// Encoding the subject also keeps line breaks in a title from injecting headers
+		{"Subject", mime.QEncoding.Encode("utf-8", n.Title)},
+		{"Date", n.CreatedAt.Format(time.RFC1123Z)},
+		{"Message-ID", fmt.Sprintf("<%s@%s>", n.ID, e.config.SMTPHost)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, h := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := fmt.Fprintf(w, "%s\r\n", n.Body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// send delivers msg over one SMTP session, upgrading to TLS when the relay offers it. Unlike
+// smtp.SendMail it is bounded by the configured timeout and ctx.
+func (e *EmailNotifier) send(ctx context.Context, to string, msg []byte) error {
+	if e.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.Timeout)
+		defer cancel()
+	}
+
+	addr := net.JoinHostPort(e.config.SMTPHost, strconv.Itoa(e.config.SMTPPort))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP relay: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, e.config.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: e.config.SMTPHost}); err != nil {
+			return err
+		}
+	}
+	if e.config.SMTPUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.config.SMTPUsername, e.config.SMTPPassword, e.config.SMTPHost)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		// 5xx replies reject the address itself, so retrying would fail the same way
+		var tpErr *textproto.Error
+		if errors.As(err, &tpErr) && tpErr.Code >= 500 {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
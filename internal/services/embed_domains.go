@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// NormalizeEmbedDomain validates a domain allowed to embed videos and returns it as a CSP
+// host source: an optional http(s) scheme, a host that may start with "*." to cover its
+// subdomains, and an optional port, as in https://*.example.com or example.com:8443
+func NormalizeEmbedDomain(domain string) (string, error) {
+	s := strings.ToLower(strings.TrimSpace(domain))
+	scheme := ""
+	if i := strings.Index(s, "://"); i >= 0 {
+		scheme = s[:i]
+		if scheme != "https" && scheme != "http" {
+			return "", fmt.Errorf("%q must use http or https", domain)
+		}
+		s = s[i+3:]
+	}
+	s = strings.TrimSuffix(s, "/")
+	if strings.ContainsAny(s, "/?#@ ") {
+		return "", fmt.Errorf("%q must not contain a path, query or credentials", domain)
+	}
+
+	host, port := s, ""
+	if h, p, err := net.SplitHostPort(s); err == nil {
+		host, port = h, p
+		if n, err := strconv.Atoi(p); p != "*" && (err != nil || n < 1 || n > 65535) {
+			return "", fmt.Errorf("%q has an invalid port", domain)
+		}
+	}
+	name := strings.TrimPrefix(host, "*.")
+	if name == "" || strings.Contains(name, "*") || (!strings.Contains(name, ".") && name != "localhost") {
+		return "", fmt.Errorf("%q is not a domain", domain)
+	}
+
+	normalized := host
+	if scheme != "" {
+		normalized = scheme + "://" + host
+	}
+	if port != "" {
+		normalized += ":" + port
+	}
+	return normalized, nil
+}
+
+// EmbedDomainAllowed tells whether origin, a scheme and host as in the Origin header, matches
+// one of the domains. Domains without a scheme match http and https; domains without a port
+// match only the default ones.
+func EmbedDomainAllowed(domains []string, origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host, port := u.Hostname(), u.Port()
+	for _, domain := range domains {
+		scheme, rest, ok := strings.Cut(domain, "://")
+		if !ok {
+			scheme, rest = "", domain
+		}
+		if scheme != "" && scheme != u.Scheme {
+			continue
+		}
+		if scheme == "" && u.Scheme != "https" && u.Scheme != "http" {
+			continue
+		}
+		pattern, patternPort := rest, ""
+		if h, p, err := net.SplitHostPort(rest); err == nil {
+			pattern, patternPort = h, p
+		}
+		if patternPort != "*" && patternPort != port {
+			continue
+		}
+		if suffix, wildcard := strings.CutPrefix(pattern, "*."); wildcard {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestOrigin returns the origin a request came from: its Origin header, or the scheme and
+// host of its Referer. It is empty for requests that carry neither, such as server-to-server
+// calls.
+func RequestOrigin(origin, referer string) string {
+	if origin != "" && origin != "null" {
+		return origin
+	}
+	u, err := url.Parse(referer)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// EmbedDomains returns the domains an organization's videos may be embedded on; empty means
+// anywhere. db must not carry a tenant context, as players are not members.
+func EmbedDomains(ctx context.Context, db *sql.DB, orgID uuid.UUID) ([]string, error) {
+	domains, err := queries.New(db).GetEmbedDomains(ctx, orgID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return domains, err
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// EncoderRenditionComplete is the notification type announcing finished renditions
+const EncoderRenditionComplete = "rendition.complete"
+
+// MaxNotificationRenditions bounds the renditions of one notification
+const MaxNotificationRenditions = 50
+
+var (
+	// ErrEncoderIntegrationNotFound is returned for notifications to unknown integrations
+	ErrEncoderIntegrationNotFound = errors.New("encoder integration not found")
+	// ErrEncoderVideo is returned for notifications about videos outside the integration's organization
+	ErrEncoderVideo = errors.New("video not found in the organization")
+	// ErrInvalidRenditions is wrapped by errors describing invalid renditions
+	ErrInvalidRenditions = errors.New("invalid renditions")
+)
+
+// EncoderNotification is the body external encoders post, signed with their integration's
+// secret as webhooks are
+type EncoderNotification struct {
+	// Type is rendition.complete; other types are acknowledged and ignored
+	Type       string          `json:"type"`
+	VideoID    uuid.UUID       `json:"video_id"`
+	Renditions []RenditionSpec `json:"renditions"`
+}
+
+// RenditionSpec describes a finished rendition hosted by the encoder
+type RenditionSpec struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       *int   `json:"width"`
+	Height      *int   `json:"height"`
+	BitrateKbps *int   `json:"bitrate_kbps"`
+	Codec       string `json:"codec"`
+	// DynamicRange is sdr, hdr10, hlg or dolby_vision, or empty when unknown
+	DynamicRange string `json:"dynamic_range"`
+	ExternalID   string `json:"external_id"`
+}
+
+// EncoderSecret returns the organization and signing secret of an integration. db must not
+// carry a tenant context, as encoders are not members.
+func EncoderSecret(ctx context.Context, db *sql.DB, integrationID uuid.UUID) (uuid.UUID, string, error) {
+	var orgID uuid.UUID
+	var secret string
+	err := db.QueryRowContext(ctx,
+		`SELECT organization_id, secret FROM encoder_integrations WHERE id = $1`, integrationID).Scan(&orgID, &secret)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, "", ErrEncoderIntegrationNotFound
+	}
+	return orgID, secret, err
+}
+
+// ValidateRenditions checks renditions and returns them trimmed
+func ValidateRenditions(specs []RenditionSpec) ([]RenditionSpec, error) {
+	if len(specs) == 0 || len(specs) > MaxNotificationRenditions {
+		return nil, fmt.Errorf("%w: expected 1 to %d renditions", ErrInvalidRenditions, MaxNotificationRenditions)
+	}
+	out := make([]RenditionSpec, 0, len(specs))
+	for i, s := range specs {
+		s.URL = strings.TrimSpace(s.URL)
+		u, err := url.Parse(s.URL)
+		if err == nil {
+			err = CheckFetchURL(u, false)
+		}
+		if err != nil || len(s.URL) > 2048 {
+			return nil, fmt.Errorf("%w: rendition %d has an invalid URL", ErrInvalidRenditions, i)
+		}
+		s.URL = u.String()
+		for _, n := range []*int{s.Width, s.Height, s.BitrateKbps} {
+			if n != nil && *n <= 0 {
+				return nil, fmt.Errorf("%w: rendition %d has a width, height or bitrate that is not positive", ErrInvalidRenditions, i)
+			}
+		}
+		s.ContentType, s.Codec, s.ExternalID = strings.TrimSpace(s.ContentType), strings.TrimSpace(s.Codec), strings.TrimSpace(s.ExternalID)
+		if len(s.ContentType) > 100 || len(s.Codec) > 100 || len(s.ExternalID) > 255 {
+			return nil, fmt.Errorf("%w: rendition %d has a content type, codec or external ID that is too long", ErrInvalidRenditions, i)
+		}
+		s.DynamicRange = strings.ToLower(strings.TrimSpace(s.DynamicRange))
+		if s.DynamicRange != "" && !slices.Contains(models.DynamicRanges, s.DynamicRange) {
+			return nil, fmt.Errorf("%w: rendition %d has an unknown dynamic range; use %s", ErrInvalidRenditions, i,
+				strings.Join(models.DynamicRanges, ", "))
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// RenditionColumns is the column list matching ScanRendition
+const RenditionColumns = `id, video_id, integration_id, external_id, url, content_type, width, height, bitrate_kbps, codec,
+	COALESCE(dynamic_range, ''), created_at, updated_at`
+
+// ScanRendition scans a row selected with RenditionColumns
+func ScanRendition(row interface{ Scan(...interface{}) error }) (*models.VideoRendition, error) {
+	var r models.VideoRendition
+	if err := row.Scan(&r.ID, &r.VideoID, &r.IntegrationID, &r.ExternalID, &r.URL, &r.ContentType, &r.Width, &r.Height,
+		&r.BitrateKbps, &r.Codec, &r.DynamicRange, &r.CreatedAt, &r.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// PlaybackPlaylist returns the URL of a video's latest HLS master playlist hosted by a
+// transcoder, a streaming provider or an encoder, or "" if there is none. Master playlists are
+// the HLS renditions without a height.
+func PlaybackPlaylist(ctx context.Context, q database.Querier, videoID uuid.UUID) (string, error) {
+	var u string
+	err := q.QueryRowContext(ctx, `
+		SELECT url FROM video_renditions
+		WHERE video_id = $1 AND content_type = 'application/vnd.apple.mpegurl' AND height IS NULL
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, videoID).Scan(&u)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return u, err
+}
+
+// RegisterRenditions adds validated renditions to a video of the integration's organization,
+// replacing those with the same URL, and announces them with a video.renditions_registered
+// event. db must not carry a tenant context.
+func RegisterRenditions(ctx context.Context, db *sql.DB, integrationID, orgID, videoID uuid.UUID, specs []RenditionSpec) ([]models.VideoRendition, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	exists, err := queries.New(tx).VideoExistsInOrganization(ctx, queries.VideoExistsInOrganizationParams{
+		ID: videoID, OrganizationID: orgID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrEncoderVideo
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE encoder_integrations SET last_received_at = NOW() WHERE id = $1`, integrationID); err != nil {
+		return nil, err
+	}
+	renditions, err := AddRenditions(ctx, tx, orgID, videoID, &integrationID, specs)
+	if err != nil {
+		return nil, err
+	}
+	return renditions, tx.Commit()
+}
+
+// AddRenditions adds validated renditions to a video in tx, replacing those with the same URL,
+// and writes the video.renditions_registered event. integrationID is nil for renditions of the
+// organization's transcoder.
+func AddRenditions(ctx context.Context, tx *sql.Tx, orgID, videoID uuid.UUID, integrationID *uuid.UUID, specs []RenditionSpec) ([]models.VideoRendition, error) {
+	renditions := make([]models.VideoRendition, 0, len(specs))
+	for _, s := range specs {
+		r, err := ScanRendition(tx.QueryRowContext(ctx, `
+			INSERT INTO video_renditions (video_id, organization_id, integration_id, external_id, url, content_type,
+				width, height, bitrate_kbps, codec, dynamic_range)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
+			ON CONFLICT (video_id, url) DO UPDATE SET
+				integration_id = EXCLUDED.integration_id, external_id = EXCLUDED.external_id,
+				content_type = EXCLUDED.content_type, width = EXCLUDED.width, height = EXCLUDED.height,
+				bitrate_kbps = EXCLUDED.bitrate_kbps, codec = EXCLUDED.codec, dynamic_range = EXCLUDED.dynamic_range
+			RETURNING `+RenditionColumns,
+			videoID, orgID, integrationID, s.ExternalID, s.URL, s.ContentType, s.Width, s.Height, s.BitrateKbps, s.Codec, s.DynamicRange))
+		if err != nil {
+			return nil, err
+		}
+		renditions = append(renditions, *r)
+	}
+
+	err := outbox.Write(ctx, tx, outbox.NewEvent{
+		OrganizationID: orgID,
+		Type:           outbox.EventVideoRenditions,
+		SubjectID:      &videoID,
+		Data:           map[string]interface{}{"video_id": videoID, "integration_id": integrationID, "renditions": renditions},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return renditions, nil
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// MaxEntitlementRows bounds the rows of a bulk grant
+const MaxEntitlementRows = 10000
+
+var (
+	// ErrEntitlementTarget is returned when the video or project of a grant is not in the organization
+	ErrEntitlementTarget = errors.New("video or project not found in the organization")
+	// ErrEntitlementUser is returned when the user of a grant does not exist
+	ErrEntitlementUser = errors.New("user not found")
+)
+
+// EntitlementColumns is the column list matching ScanEntitlement
+const EntitlementColumns = `id, organization_id, user_id, video_id, project_id, expires_at, reference, granted_by,
+	expires_at IS NULL OR expires_at > NOW(), created_at, updated_at`
+
+// ScanEntitlement scans a row selected with EntitlementColumns
+func ScanEntitlement(row interface{ Scan(...interface{}) error }) (*models.Entitlement, error) {
+	var e models.Entitlement
+	err := row.Scan(&e.ID, &e.OrganizationID, &e.UserID, &e.VideoID, &e.ProjectID, &e.ExpiresAt, &e.Reference, &e.GrantedBy,
+		&e.Active, &e.CreatedAt, &e.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// EntitlementGrant describes a grant of one video or one project to a user, who is given by
+// ID or email address
+type EntitlementGrant struct {
+	UserID    uuid.UUID
+	Email     string
+	VideoID   *uuid.UUID
+	ProjectID *uuid.UUID
+	ExpiresAt *time.Time
+	Reference string
+	// Row is the CSV row of a bulk grant
+	Row int
+}
+
+// GrantEntitlement grants playback in the organization. A user's grant of the same video or
+// project is replaced, so granting again extends or shortens a rental.
+func GrantEntitlement(ctx context.Context, q database.Querier, orgID, grantedBy uuid.UUID, g EntitlementGrant) (*models.Entitlement, error) {
+	if (g.VideoID == nil) == (g.ProjectID == nil) {
+		return nil, fmt.Errorf("give either a video or a project")
+	}
+	userID := g.UserID
+	if userID == uuid.Nil {
+		var err error
+		userID, err = queries.New(q).FindUserByEmailFold(ctx, strings.TrimSpace(g.Email))
+		if err == sql.ErrNoRows {
+			return nil, ErrEntitlementUser
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conflict := "(user_id, video_id)"
+	if g.ProjectID != nil {
+		conflict = "(user_id, project_id)"
+	}
+	e, err := ScanEntitlement(q.QueryRowContext(ctx, `
+		INSERT INTO entitlements (organization_id, user_id, video_id, project_id, expires_at, reference, granted_by)
+		SELECT $1, u.id, $3, $4, $5, $6, $7
+		FROM users u
+		WHERE u.id = $2
+			AND (EXISTS (SELECT 1 FROM videos WHERE id = $3 AND organization_id = $1)
+				OR EXISTS (SELECT 1 FROM projects WHERE id = $4 AND organization_id = $1))
+		ON CONFLICT `+conflict+` DO UPDATE
+		SET expires_at = EXCLUDED.expires_at, reference = EXCLUDED.reference, granted_by = EXCLUDED.granted_by
+		RETURNING `+EntitlementColumns,
+		orgID, userID, g.VideoID, g.ProjectID, g.ExpiresAt, truncateRunes(g.Reference, 255), grantedBy))
+	if err == sql.ErrNoRows {
+		exists, err := queries.New(q).UserExists(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrEntitlementUser
+		}
+		return nil, ErrEntitlementTarget
+	}
+	return e, err
+}
+
+// Entitled reports whether a user may play a video. Videos that do not require an entitlement
+// play for every member; others need an unexpired grant of the video or its project, unless
+// the user manages the organization's content as an owner, admin or developer.
+func Entitled(ctx context.Context, q database.Querier, userID uuid.UUID, v *models.Video) (bool, error) {
+	if !v.RequiresEntitlement {
+		return true, nil
+	}
+	var entitled bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+				SELECT 1 FROM user_org_roles
+				WHERE user_id = $1 AND organization_id = $2 AND role IN ($5, $6, $7)
+			) OR EXISTS (
+				SELECT 1 FROM entitlements
+				WHERE user_id = $1 AND (video_id = $3 OR project_id = $4) AND (expires_at IS NULL OR expires_at > NOW())
+			)`,
+		userID, v.OrganizationID, v.ID, v.ProjectID, models.RoleOwner, models.RoleAdmin, models.RoleDeveloper).Scan(&entitled)
+	return entitled, err
+}
+
+// ParseEntitlementCSV reads bulk grants with the columns user (an ID or email address),
+// video_id, project_id, expires_at (RFC 3339, empty for none) and reference. Columns are
+// positional unless the first row names them. Rows that cannot be used are returned as
+// messages with their row number.
+func ParseEntitlementCSV(data string) ([]EntitlementGrant, []string, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	cols := map[string]int{"user": 0, "video_id": 1, "project_id": 2, "expires_at": 3, "reference": 4}
+	start := 0
+	if len(records) > 0 {
+		header := make(map[string]int)
+		for i, name := range records[0] {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "user_id" || name == "email" {
+				name = "user"
+			}
+			header[name] = i
+		}
+		if _, ok := header["user"]; ok {
+			cols = header
+			start = 1
+		}
+	}
+	if len(records)-start > MaxEntitlementRows {
+		return nil, nil, fmt.Errorf("at most %d rows can be granted at once", MaxEntitlementRows)
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := cols[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	parseID := func(s string) (*uuid.UUID, error) {
+		if s == "" {
+			return nil, nil
+		}
+		id, err := uuid.Parse(s)
+		return &id, err
+	}
+
+	var grants []EntitlementGrant
+	var skipped []string
+	for i, record := range records[start:] {
+		row := i + start + 1
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		g := EntitlementGrant{Row: row}
+		user := field(record, "user")
+		if id, err := uuid.Parse(user); err == nil {
+			g.UserID = id
+		} else if strings.Contains(user, "@") {
+			g.Email = user
+		} else {
+			skipped = append(skipped, fmt.Sprintf("row %d: user must be a user ID or email address", row))
+			continue
+		}
+		if g.VideoID, err = parseID(field(record, "video_id")); err != nil {
+			skipped = append(skipped, fmt.Sprintf("row %d: invalid video_id", row))
+			continue
+		}
+		if g.ProjectID, err = parseID(field(record, "project_id")); err != nil {
+			skipped = append(skipped, fmt.Sprintf("row %d: invalid project_id", row))
+			continue
+		}
+		if (g.VideoID == nil) == (g.ProjectID == nil) {
+			skipped = append(skipped, fmt.Sprintf("row %d: give either video_id or project_id", row))
+			continue
+		}
+		if s := field(record, "expires_at"); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("row %d: expires_at must be an RFC 3339 time", row))
+				continue
+			}
+			g.ExpiresAt = &t
+		}
+		g.Reference = field(record, "reference")
+		grants = append(grants, g)
+	}
+	return grants, skipped, nil
+}
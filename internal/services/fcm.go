@@ -0,0 +1,230 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+)
+
+const (
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+	fcmTokenURL = "https://oauth2.googleapis.com/token"
+)
+
+// FCMProvider sends notifications to Android and other Firebase Cloud Messaging clients through
+// the HTTP v1 API, authenticating as a service account
+type FCMProvider struct {
+	client      *http.Client
+	sendURL     string
+	clientEmail string
+	tokenURL    string
+	key         crypto.Signer
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+// NewFCMProvider creates an FCM provider from the service account key file in cfg
+func NewFCMProvider(cfg config.Push) (*FCMProvider, error) {
+	data, err := os.ReadFile(cfg.FCMCredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PUSH_FCM_CREDENTIALS_FILE: %w", err)
+	}
+	var creds struct {
+		Type        string `json:"type"`
+		ProjectID   string `json:"project_id"`
+		PrivateKey  string `json:"private_key"`
+		ClientEmail string `json:"client_email"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("invalid PUSH_FCM_CREDENTIALS_FILE: %w", err)
+	}
+	if creds.Type != "service_account" || creds.ClientEmail == "" {
+		return nil, fmt.Errorf("PUSH_FCM_CREDENTIALS_FILE is not a service account key")
+	}
+
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("PUSH_FCM_CREDENTIALS_FILE has no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key in PUSH_FCM_CREDENTIALS_FILE: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key in PUSH_FCM_CREDENTIALS_FILE")
+	}
+
+	project := cfg.FCMProjectID
+	if project == "" {
+		project = creds.ProjectID
+	}
+	if project == "" {
+		return nil, fmt.Errorf("PUSH_FCM_PROJECT_ID is required when the credentials name no project")
+	}
+	tokenURL := creds.TokenURI
+	if tokenURL == "" {
+		tokenURL = fcmTokenURL
+	}
+
+	return &FCMProvider{
+		client:      &http.Client{Timeout: cfg.Timeout},
+		sendURL:     "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(project) + "/messages:send",
+		clientEmail: creds.ClientEmail,
+		tokenURL:    tokenURL,
+		key:         key,
+	}, nil
+}
+
+// Platform implements PushProvider
+func (f *FCMProvider) Platform() string {
+	return models.PushPlatformFCM
+}
+
+// Send implements PushProvider. Besides the displayed notification, the message carries the
+// notification's ID, type and data for the app.
+func (f *FCMProvider) Send(ctx context.Context, device *models.PushDevice, msg *PushMessage) error {
+	data := map[string]string{"notification_id": msg.ID.String(), "type": msg.Type}
+	if len(msg.Data) > 0 {
+		data["data"] = string(msg.Data)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token":        device.Token,
+			"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+			"data":         data,
+			"android": map[string]interface{}{
+				"collapse_key": msg.ID.String(),
+				"ttl":          strconv.Itoa(int(msg.TTL.Seconds())) + "s",
+			},
+		},
+	})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.sendURL, bytes.NewReader(body))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var failure struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&failure)
+	code := failure.Error.Status
+	for _, detail := range failure.Error.Details {
+		if detail.ErrorCode != "" {
+			code = detail.ErrorCode
+		}
+	}
+	err = fmt.Errorf("FCM returned %s: %s %s", resp.Status, code, failure.Error.Message)
+
+	switch {
+	case code == "UNREGISTERED" || resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("%w: %v", ErrDeviceGone, err)
+	case resp.StatusCode == http.StatusUnauthorized:
+		// The access token was revoked or expired early; the retry fetches a new one
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+		return err
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return err
+	default:
+		return jobs.Permanent(err)
+	}
+}
+
+// token returns a cached OAuth access token, exchanging a signed assertion for a new one
+// shortly before the cached token expires
+func (f *FCMProvider) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Until(f.expires) > time.Minute {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := signJWT(map[string]string{"typ": "JWT", "alg": "RS256"}, map[string]interface{}{
+		"iss":   f.clientEmail,
+		"scope": fcmScope,
+		"aud":   f.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}, f.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get FCM access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("failed to get FCM access token: %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+
+	var out struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode FCM access token: %w", err)
+	}
+	f.accessToken = out.AccessToken
+	f.expires = now.Add(time.Duration(out.ExpiresIn) * time.Second)
+	return f.accessToken, nil
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// JobKindFeedGenerate regenerates the stored feeds of a project
+const JobKindFeedGenerate = "feed.generate"
+
+// Feed formats
+const (
+	FeedFormatMRSS    = "mrss"
+	FeedFormatJSON    = "json"
+	FeedFormatPodcast = "podcast"
+)
+
+// FeedFormats lists every feed format
+var FeedFormats = []string{FeedFormatMRSS, FeedFormatJSON, FeedFormatPodcast}
+
+// feedFiles are the file names feeds are served under, per format
+var feedFiles = map[string]string{
+	FeedFormatMRSS:    "mrss.xml",
+	FeedFormatJSON:    "feed.json",
+	FeedFormatPodcast: "podcast.xml",
+}
+
+// ErrFeedNotFound is returned for projects that do not exist or have nothing to list in a
+// feed, so that feeds never reveal projects without public videos
+var ErrFeedNotFound = errors.New("feed not found")
+
+// FeedPath is the path a project's feed is served under
+func FeedPath(projectID uuid.UUID, format string) string {
+	return fmt.Sprintf("/feeds/projects/%s/%s", projectID, feedFiles[format])
+}
+
+// PlaylistFeedPath is the path a smart playlist's feed is served under
+func PlaylistFeedPath(playlistID uuid.UUID, format string) string {
+	return fmt.Sprintf("/feeds/playlists/%s/%s", playlistID, feedFiles[format])
+}
+
+// FeedPaths lists the paths of every feed of a project, for purging
+func FeedPaths(projectID uuid.UUID) []string {
+	paths := make([]string, len(FeedFormats))
+	for i, format := range FeedFormats {
+		paths[i] = FeedPath(projectID, format)
+	}
+	return paths
+}
+
+// FeedContentType is the media type a feed format is served as
+func FeedContentType(format string) string {
+	if format == FeedFormatJSON {
+		return "application/feed+json; charset=utf-8"
+	}
+	return "application/rss+xml; charset=utf-8"
+}
+
+// Feed is a stored feed document
+type Feed struct {
+	Body        []byte
+	ETag        string
+	GeneratedAt time.Time
+}
+
+type feedPayload struct {
+	ProjectID uuid.UUID `json:"project_id"`
+}
+
+// FeedGenerator renders the public catalog feeds of projects: MRSS for connected TV
+// ingestion, JSON Feed, and podcast RSS with the sources as enclosures. A feed lists the
+// project's public, playable videos that need no entitlement. When PUBLIC_URL is set feeds are
+// stored, regenerated when a video of the project changes and purged from the CDN when their
+// content changes; without it links depend on the request's host, so feeds are rendered per
+// request.
+type FeedGenerator struct {
+	db        *sql.DB
+	config    config.Feeds
+	publicURL string
+	purger    *CachePurger
+}
+
+// NewFeedGenerator creates a feed generator
+func NewFeedGenerator(db *sql.DB, cfg config.Feeds, publicURL string, purger *CachePurger) *FeedGenerator {
+	return &FeedGenerator{db: db, config: cfg, publicURL: publicURL, purger: purger}
+}
+
+// Stored reports whether feeds are stored rather than rendered per request
+func (g *FeedGenerator) Stored() bool {
+	return g.publicURL != ""
+}
+
+// Name implements outbox.Publisher
+func (g *FeedGenerator) Name() string {
+	return "feeds"
+}
+
+// Publish implements outbox.Publisher, queueing the regeneration of the feeds of the project
+// of a changed video
+func (g *FeedGenerator) Publish(ctx context.Context, tx *sql.Tx, e *outbox.Event) error {
+	if !g.Stored() {
+		return nil
+	}
+	switch e.Type {
+	case outbox.EventVideoReady, outbox.EventVideoUpdated, outbox.EventVideoSourceReplaced, outbox.EventVideoSourceRolledBack,
+		outbox.EventVideoModerated, outbox.EventVideoApproved, outbox.EventVideoRejected:
+	default:
+		return nil
+	}
+	var video struct {
+		ProjectID *uuid.UUID `json:"project_id"`
+	}
+	if err := json.Unmarshal(e.Data, &video); err != nil || video.ProjectID == nil {
+		return nil
+	}
+	_, err := jobs.Enqueue(ctx, tx, JobKindFeedGenerate, feedPayload{ProjectID: *video.ProjectID},
+		jobs.Options{OrganizationID: &e.OrganizationID})
+	return err
+}
+
+// Handle runs a feed.generate job
+func (g *FeedGenerator) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload feedPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	return g.Generate(ctx, payload.ProjectID)
+}
+
+// Generate renders and stores every feed of a project, removing those with nothing to list,
+// and queues a CDN purge of the feeds when any of them changed
+func (g *FeedGenerator) Generate(ctx context.Context, projectID uuid.UUID) error {
+	var orgID uuid.UUID
+	err := g.db.QueryRowContext(ctx, `SELECT organization_id FROM projects WHERE id = $1`, projectID).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		// Deleted since the job was queued; its feeds went with it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, format := range FeedFormats {
+		body, err := g.Render(ctx, projectID, format, g.publicURL)
+		if errors.Is(err, ErrFeedNotFound) {
+			res, err := g.db.ExecContext(ctx, `DELETE FROM feeds WHERE project_id = $1 AND format = $2`, projectID, format)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				changed = true
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		res, err := g.db.ExecContext(ctx, `
+			INSERT INTO feeds (project_id, format, organization_id, body, etag)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (project_id, format) DO UPDATE
+			SET body = EXCLUDED.body, etag = EXCLUDED.etag, generated_at = NOW()
+			WHERE feeds.etag <> EXCLUDED.etag
+		`, projectID, format, orgID, body, feedETag(body))
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			changed = true
+		}
+	}
+
+	if changed && g.purger.Enabled() {
+		_, err := jobs.Enqueue(ctx, g.db, JobKindCDNPurge, PurgePayload{Paths: FeedPaths(projectID)},
+			jobs.Options{OrganizationID: &orgID})
+		return err
+	}
+	return nil
+}
+
+// Load returns a stored feed, generating the project's feeds first if they were never
+// generated
+func (g *FeedGenerator) Load(ctx context.Context, projectID uuid.UUID, format string) (*Feed, error) {
+	feed, err := g.load(ctx, projectID, format)
+	if err != sql.ErrNoRows {
+		return feed, err
+	}
+
+	var generated bool
+	if err := g.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM feeds WHERE project_id = $1)`, projectID).Scan(&generated); err != nil {
+		return nil, err
+	}
+	if !generated {
+		if err := g.Generate(ctx, projectID); err != nil {
+			return nil, err
+		}
+		feed, err = g.load(ctx, projectID, format)
+	}
+	if err == sql.ErrNoRows {
+		return nil, ErrFeedNotFound
+	}
+	return feed, err
+}
+
+func (g *FeedGenerator) load(ctx context.Context, projectID uuid.UUID, format string) (*Feed, error) {
+	var f Feed
+	err := g.db.QueryRowContext(ctx, `SELECT body, etag, generated_at FROM feeds WHERE project_id = $1 AND format = $2`,
+		projectID, format).Scan(&f.Body, &f.ETag, &f.GeneratedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Render renders a feed of a project with links under base
+func (g *FeedGenerator) Render(ctx context.Context, projectID uuid.UUID, format, base string) ([]byte, error) {
+	var project feedProject
+	err := g.db.QueryRowContext(ctx, `
+		SELECT p.id, p.name, COALESCE(p.description, ''), o.name
+		FROM projects p
+		JOIN organizations o ON o.id = p.organization_id
+		WHERE p.id = $1 AND o.deleted_at IS NULL
+	`, projectID).Scan(&project.ID, &project.Name, &project.Description, &project.Organization)
+	if err == sql.ErrNoRows {
+		return nil, ErrFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	videos, err := VideoModels(queries.New(g.db).ListPublicProjectVideos(ctx, queries.ListPublicProjectVideosParams{
+		ProjectID:        projectID,
+		Visibility:       models.VideoVisibilityPublic,
+		Statuses:         models.PlayableVideoStatuses,
+		ModerationStatus: models.ModerationActive,
+		ReviewStatus:     models.ReviewApproved,
+		MaxVideos:        int32(g.config.MaxItems),
+	}))
+	if err != nil {
+		return nil, err
+	}
+	return renderFeed(format, base, base+FeedPath(projectID, format), project, videos)
+}
+
+// renderFeed renders videos as a feed in format, served at self, leaving out those the format
+// cannot list. A feed without videos is not found.
+func renderFeed(format, base, self string, project feedProject, videos []*models.Video) ([]byte, error) {
+	// Podcast apps only play files, so only audio and video sources are enclosures there
+	if format == FeedFormatPodcast {
+		videos = slices.DeleteFunc(slices.Clone(videos), func(v *models.Video) bool {
+			return !isPodcastMedia(v.ContentType)
+		})
+	}
+	if len(videos) == 0 {
+		return nil, ErrFeedNotFound
+	}
+
+	f := feedRenderer{base: base, project: project, videos: videos, self: self}
+	switch format {
+	case FeedFormatMRSS:
+		return f.rss(false)
+	case FeedFormatPodcast:
+		return f.rss(true)
+	case FeedFormatJSON:
+		return f.jsonFeed()
+	}
+	return nil, ErrFeedNotFound
+}
+
+func feedETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
+
+func isPodcastMedia(contentType string) bool {
+	return strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/")
+}
+
+// mediaType is the type of a video's source; feeds need one even when the upload declared none
+func mediaType(v *models.Video) string {
+	if v.ContentType == "" {
+		return "application/octet-stream"
+	}
+	return v.ContentType
+}
+
+type feedProject struct {
+	ID           uuid.UUID
+	Name         string
+	Description  string
+	Organization string
+}
+
+type feedRenderer struct {
+	base    string
+	self    string
+	project feedProject
+	videos  []*models.Video
+}
+
+func (f *feedRenderer) videoURL(v *models.Video, suffix string) string {
+	return f.base + "/embed/" + v.ID.String() + suffix
+}
+
+func (f *feedRenderer) thumbnailURL(v *models.Video) string {
+	if v.Thumbnail == nil {
+		return ""
+	}
+	return f.videoURL(v, "/thumbnail?v="+url.QueryEscape(v.Thumbnail.ID))
+}
+
+func (f *feedRenderer) description() string {
+	if f.project.Description != "" {
+		return f.project.Description
+	}
+	return f.project.Name
+}
+
+// updated is when the newest change to a listed video was made. Feeds carry no generation
+// time, so rendering unchanged content gives the same bytes.
+func (f *feedRenderer) updated() time.Time {
+	var latest time.Time
+	for _, v := range f.videos {
+		if v.UpdatedAt.After(latest) {
+			latest = v.UpdatedAt
+		}
+	}
+	return latest
+}
+
+type rssDocument struct {
+	XMLName  xml.Name   `xml:"rss"`
+	Version  string     `xml:"version,attr"`
+	AtomNS   string     `xml:"xmlns:atom,attr"`
+	MediaNS  string     `xml:"xmlns:media,attr,omitempty"`
+	ITunesNS string     `xml:"xmlns:itunes,attr,omitempty"`
+	Channel  rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title          string       `xml:"title"`
+	Link           string       `xml:"link"`
+	Description    string       `xml:"description"`
+	AtomLink       rssAtomLink  `xml:"atom:link"`
+	LastBuildDate  string       `xml:"lastBuildDate"`
+	Generator      string       `xml:"generator"`
+	ITunesAuthor   string       `xml:"itunes:author,omitempty"`
+	ITunesSummary  string       `xml:"itunes:summary,omitempty"`
+	ITunesExplicit string       `xml:"itunes:explicit,omitempty"`
+	ITunesImage    *rssHrefNode `xml:"itunes:image"`
+	Items          []rssItem    `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssHrefNode struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title          string             `xml:"title"`
+	Link           string             `xml:"link"`
+	GUID           rssGUID            `xml:"guid"`
+	PubDate        string             `xml:"pubDate"`
+	Description    string             `xml:"description,omitempty"`
+	Categories     []string           `xml:"category"`
+	Enclosure      *rssEnclosure      `xml:"enclosure"`
+	MediaContent   *rssMediaContent   `xml:"media:content"`
+	MediaThumbnail *rssMediaThumbnail `xml:"media:thumbnail"`
+	MediaKeywords  string             `xml:"media:keywords,omitempty"`
+	ITunesImage    *rssHrefNode       `xml:"itunes:image"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssMediaContent struct {
+	URL         string `xml:"url,attr"`
+	Type        string `xml:"type,attr,omitempty"`
+	FileSize    int64  `xml:"fileSize,attr,omitempty"`
+	Medium      string `xml:"medium,attr,omitempty"`
+	Title       string `xml:"media:title"`
+	Description string `xml:"media:description,omitempty"`
+}
+
+type rssMediaThumbnail struct {
+	URL    string `xml:"url,attr"`
+	Width  int    `xml:"width,attr,omitempty"`
+	Height int    `xml:"height,attr,omitempty"`
+}
+
+// rss renders RSS 2.0, with Media RSS elements or, for podcasts, enclosures and iTunes tags
+func (f *feedRenderer) rss(podcast bool) ([]byte, error) {
+	doc := rssDocument{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         f.project.Name,
+			Link:          f.base,
+			Description:   f.description(),
+			AtomLink:      rssAtomLink{Href: f.self, Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: f.updated().UTC().Format(time.RFC1123Z),
+			Generator:     "OpenVDO",
+		},
+	}
+	if podcast {
+		doc.ITunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+		doc.Channel.ITunesAuthor = f.project.Organization
+		doc.Channel.ITunesSummary = f.description()
+		doc.Channel.ITunesExplicit = "false"
+		if thumbnail := f.thumbnailURL(f.videos[0]); thumbnail != "" {
+			doc.Channel.ITunesImage = &rssHrefNode{Href: thumbnail}
+		}
+	} else {
+		doc.MediaNS = "http://search.yahoo.com/mrss/"
+	}
+
+	for _, v := range f.videos {
+		item := rssItem{
+			Title:       v.Title,
+			Link:        f.videoURL(v, ""),
+			GUID:        rssGUID{Value: v.ID.String()},
+			PubDate:     v.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: v.Description,
+			Categories:  v.Tags,
+		}
+		media := f.videoURL(v, "/media")
+		thumbnail := f.thumbnailURL(v)
+		if podcast {
+			item.Enclosure = &rssEnclosure{URL: media, Length: v.SizeBytes, Type: mediaType(v)}
+			if thumbnail != "" {
+				item.ITunesImage = &rssHrefNode{Href: thumbnail}
+			}
+		} else {
+			medium := "video"
+			if strings.HasPrefix(v.ContentType, "audio/") {
+				medium = "audio"
+			}
+			item.MediaContent = &rssMediaContent{
+				URL: media, Type: mediaType(v), FileSize: v.SizeBytes, Medium: medium,
+				Title: v.Title, Description: v.Description,
+			}
+			if thumbnail != "" {
+				item.MediaThumbnail = &rssMediaThumbnail{URL: thumbnail, Width: v.Thumbnail.Width, Height: v.Thumbnail.Height}
+			}
+			item.MediaKeywords = strings.Join(v.Tags, ", ")
+		}
+		doc.Channel.Items = append(doc.Channel.Items, item)
+	}
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	enc := xml.NewEncoder(&b)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description,omitempty"`
+	Authors     []jsonFeedName `json:"authors,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedName struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string               `json:"id"`
+	URL           string               `json:"url"`
+	Title         string               `json:"title"`
+	ContentText   string               `json:"content_text"`
+	Image         string               `json:"image,omitempty"`
+	DatePublished time.Time            `json:"date_published"`
+	DateModified  time.Time            `json:"date_modified"`
+	Tags          []string             `json:"tags,omitempty"`
+	Attachments   []jsonFeedAttachment `json:"attachments"`
+}
+
+type jsonFeedAttachment struct {
+	URL         string `json:"url"`
+	MimeType    string `json:"mime_type"`
+	SizeInBytes int64  `json:"size_in_bytes,omitempty"`
+}
+
+// jsonFeed renders JSON Feed 1.1 with each video's source as an attachment
+func (f *feedRenderer) jsonFeed() ([]byte, error) {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.project.Name,
+		HomePageURL: f.base,
+		FeedURL:     f.self,
+		Description: f.project.Description,
+		Authors:     []jsonFeedName{{Name: f.project.Organization}},
+		Items:       []jsonFeedItem{},
+	}
+	for _, v := range f.videos {
+		doc.Items = append(doc.Items, jsonFeedItem{
+			ID:            v.ID.String(),
+			URL:           f.videoURL(v, ""),
+			Title:         v.Title,
+			ContentText:   v.Description,
+			Image:         f.thumbnailURL(v),
+			DatePublished: v.CreatedAt.UTC(),
+			DateModified:  v.UpdatedAt.UTC(),
+			Tags:          v.Tags,
+			Attachments: []jsonFeedAttachment{{
+				URL: f.videoURL(v, "/media"), MimeType: mediaType(v), SizeInBytes: v.SizeBytes,
+			}},
+		})
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
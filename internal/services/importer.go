@@ -0,0 +1,292 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// JobKindVideoImport downloads a video source from a URL
+const JobKindVideoImport = "video.import"
+
+// Share of the progress attributed to downloading; the rest is the copy into storage
+const importDownloadShare = 0.9
+
+// Declared types that can hold a video; anything else is rejected before downloading
+var importableTypes = []string{"video/", "application/mp4", "application/octet-stream", "binary/octet-stream"}
+
+// ImportPayload is the payload of a video.import job
+type ImportPayload struct {
+	VideoID uuid.UUID `json:"video_id"`
+	URL     string    `json:"url"`
+}
+
+// Importer pulls video sources from external URLs into storage and hands them to the same
+// hashing as multipart uploads
+type Importer struct {
+	db      *sql.DB
+	storage storage.Storage
+	hasher  *ContentHasher
+	client  *http.Client
+	config  config.Import
+	maxSize int64
+}
+
+// NewImporter creates an importer. The master connection is used because imports run as jobs
+// outside any request.
+func NewImporter(db *sql.DB, store storage.Storage, hasher *ContentHasher, cfg config.Import, maxSize int64) *Importer {
+	return &Importer{
+		db:      db,
+		storage: store,
+		hasher:  hasher,
+		client:  NewSafeHTTPClient(cfg.Timeout, cfg.MaxRedirects, cfg.AllowHTTP),
+		config:  cfg,
+		maxSize: maxSize,
+	}
+}
+
+// ParseURL validates a source URL before an import is queued
+func (im *Importer) ParseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := CheckFetchURL(u, im.config.AllowHTTP); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Handle runs a video.import job. When the last attempt fails the video is marked failed, and
+// it is uploading again once the job is retried by hand.
+func (im *Importer) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload ImportPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	if job.Retries > 0 {
+		if err := im.moveVideo(ctx, payload.VideoID, models.VideoStatusUploading, "import retried"); err != nil {
+			return err
+		}
+	}
+
+	// Videos of a bulk import are not announced one by one
+	err := im.importSource(ctx, &payload, job.ParentID == nil, progress)
+	if err != nil && ctx.Err() == nil && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+		if dbErr := im.moveVideo(context.Background(), payload.VideoID, models.VideoStatusFailed,
+			"import failed: "+err.Error()); dbErr != nil {
+			logger.Error("Failed to mark video %s failed: %v", payload.VideoID, dbErr)
+		}
+	}
+	return err
+}
+
+// moveVideo moves a video to status, such as a video whose import gave up to failed. Videos
+// that cannot make the move, such as those an earlier attempt imported, are left as they are.
+func (im *Importer) moveVideo(ctx context.Context, videoID uuid.UUID, status, reason string) error {
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = TransitionVideo(ctx, tx, videoID, status, reason)
+	if errors.Is(err, ErrInvalidTransition) || err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (im *Importer) importSource(ctx context.Context, payload *ImportPayload, notify bool, progress func(float64)) error {
+	video, err := queries.New(im.db).GetVideoImportState(ctx, payload.VideoID)
+	if err == sql.ErrNoRows {
+		return jobs.Permanent(fmt.Errorf("video %s no longer exists", payload.VideoID))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load video: %w", err)
+	}
+	status, key := video.Status, video.SourceKey
+	if status != models.VideoStatusUploading {
+		// Already imported by an earlier attempt whose outcome was not recorded
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, payload.URL, nil)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("User-Agent", "OpenVDO-Importer/1.0")
+
+	resp, err := im.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return jobs.Permanent(err)
+		}
+		return fmt.Errorf("failed to fetch source: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("source URL returned %s", resp.Status)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout &&
+			resp.StatusCode != http.StatusTooManyRequests {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	if im.maxSize > 0 && resp.ContentLength > im.maxSize {
+		return jobs.Permanent(fmt.Errorf("source is %d bytes, more than the maximum of %d", resp.ContentLength, im.maxSize))
+	}
+
+	body := bufio.NewReaderSize(resp.Body, 4096)
+	head, _ := body.Peek(512)
+	contentType, err := importContentType(resp.Header.Get("Content-Type"), head)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	// Spooling to disk gives storage a known size and lets a failed Put be retried cheaply
+	tmp, err := os.CreateTemp(im.config.TempDir, "openvdo-import-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var src io.Reader = body
+	if im.maxSize > 0 {
+		src = io.LimitReader(body, im.maxSize+1)
+	}
+	n, err := io.Copy(tmp, &progressReader{r: src, total: resp.ContentLength, report: func(p float64) {
+		progress(p * importDownloadShare)
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to download source: %w", err)
+	}
+	if im.maxSize > 0 && n > im.maxSize {
+		return jobs.Permanent(fmt.Errorf("source exceeds the maximum size of %d bytes", im.maxSize))
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return fmt.Errorf("download ended after %d of %d bytes", n, resp.ContentLength)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := im.storage.Put(ctx, key, tmp, n, contentType); err != nil {
+		return fmt.Errorf("failed to store source: %w", err)
+	}
+	progress(1)
+
+	tx, err := im.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := queries.New(tx).SetVideoImported(ctx, queries.SetVideoImportedParams{
+		ContentType: contentType, SizeBytes: n, ID: payload.VideoID,
+	}); err != nil {
+		return err
+	}
+	if err := TransitionVideo(ctx, tx, payload.VideoID, models.VideoStatusQueued, "import completed"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO storage_objects (organization_id, video_id, object_key, kind, size_bytes)
+		SELECT organization_id, id, source_key, $1, size_bytes FROM videos WHERE id = $2
+		ON CONFLICT (object_key) DO NOTHING
+	`, models.ObjectKindSource, payload.VideoID); err != nil {
+		return err
+	}
+	if notify {
+		if err := NotifyVideoReady(ctx, tx, payload.VideoID); err != nil {
+			return err
+		}
+	}
+	if err := RecordVideoEvent(ctx, tx, outbox.EventVideoReady, payload.VideoID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	im.hasher.Enqueue(payload.VideoID)
+	logger.Info("Imported %d bytes for video %s", n, payload.VideoID)
+	return nil
+}
+
+// importContentType checks the declared type and the sniffed first bytes, so web pages and
+// images behind a URL are rejected, and returns the type to store the source with
+func importContentType(declared string, head []byte) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(declared)
+	mediaType = strings.ToLower(mediaType)
+
+	if mediaType != "" {
+		ok := false
+		for _, prefix := range importableTypes {
+			if strings.HasPrefix(mediaType, prefix) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return "", fmt.Errorf("source has content type %s, not a video", mediaType)
+		}
+	}
+
+	sniffed := http.DetectContentType(head)
+	if strings.HasPrefix(sniffed, "text/") || strings.HasPrefix(sniffed, "image/") ||
+		strings.HasPrefix(sniffed, "application/pdf") || strings.HasPrefix(sniffed, "application/zip") ||
+		strings.HasPrefix(sniffed, "application/x-gzip") {
+		return "", fmt.Errorf("source looks like %s, not a video", sniffed)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "video/"):
+		return mediaType, nil
+	case strings.HasPrefix(sniffed, "video/"):
+		return sniffed, nil
+	case mediaType == "application/mp4":
+		return "video/mp4", nil
+	}
+	return "application/octet-stream", nil
+}
+
+// progressReader reports the share of total read so far, or nothing when total is unknown
+type progressReader struct {
+	r      io.Reader
+	total  int64
+	read   int64
+	report func(float64)
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.r.Read(p)
+	pr.read += int64(n)
+	if pr.total > 0 {
+		pr.report(float64(pr.read) / float64(pr.total))
+	}
+	return n, err
+}
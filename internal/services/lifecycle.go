@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const lifecycleBatchSize = 100
+
+// StorageObjectColumns is the column list matching ScanStorageObject
+const StorageObjectColumns = `id, organization_id, video_id, object_key, kind, COALESCE(size_bytes, 0), tier_status, storage_class,
+	last_accessed_at, archived_at, restore_requested_at, restore_expires_at, last_error, created_at, updated_at`
+
+// LifecycleManager moves inactive objects to cold storage and tracks on-demand restores
+type LifecycleManager struct {
+	db       *sql.DB
+	archiver storage.Archiver
+	config   config.Storage
+}
+
+// NewLifecycleManager creates a lifecycle manager. The master connection is used because
+// archival runs across all organizations. The scheduler runs the policies in the leader's
+// process every STORAGE_LIFECYCLE_INTERVAL.
+func NewLifecycleManager(db *sql.DB, store storage.Storage, cfg config.Storage) *LifecycleManager {
+	lm := &LifecycleManager{
+		db:     db,
+		config: cfg,
+	}
+
+	if archiver, err := storage.AsArchiver(store); err == nil {
+		lm.archiver = archiver
+	}
+
+	return lm
+}
+
+// Enabled reports whether the storage backend supports archival
+func (lm *LifecycleManager) Enabled() bool {
+	return lm.archiver != nil
+}
+
+// RunOnce applies the archival policies and refreshes restore states
+func (lm *LifecycleManager) RunOnce(ctx context.Context) error {
+	if err := lm.archiveInactive(ctx, models.ObjectKindSource, lm.config.ArchiveSourceAfter); err != nil {
+		return err
+	}
+	if err := lm.archiveInactive(ctx, models.ObjectKindRendition, lm.config.ArchiveRenditionsAfter); err != nil {
+		return err
+	}
+	return lm.refreshRestores(ctx)
+}
+
+// archiveInactive claims a batch of hot objects not accessed within the threshold and archives them
+func (lm *LifecycleManager) archiveInactive(ctx context.Context, kind string, after time.Duration) error {
+	if after <= 0 {
+		return nil
+	}
+
+	rows, err := lm.db.QueryContext(ctx, `
+		UPDATE storage_objects SET tier_status = $1
+		WHERE id IN (
+			SELECT id FROM storage_objects
+			WHERE kind = $2 AND tier_status = $3 AND last_accessed_at < $4
+			ORDER BY last_accessed_at
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, object_key
+	`, models.TierStatusArchiving, kind, models.TierStatusHot, time.Now().Add(-after), lifecycleBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to claim %s objects for archival: %w", kind, err)
+	}
+
+	type claimed struct {
+		id  uuid.UUID
+		key string
+	}
+	var batch []claimed
+	for rows.Next() {
+		var c claimed
+		if err := rows.Scan(&c.id, &c.key); err != nil {
+			rows.Close()
+			return err
+		}
+		batch = append(batch, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var archived int
+	for _, obj := range batch {
+		if err := lm.archiver.Archive(ctx, obj.key, lm.config.ArchiveStorageClass); err != nil {
+			logger.Error("Failed to archive object %s: %v", obj.key, err)
+			lm.db.ExecContext(ctx, `UPDATE storage_objects SET tier_status = $1, last_error = $2 WHERE id = $3`,
+				models.TierStatusHot, err.Error(), obj.id)
+			continue
+		}
+
+		_, err := lm.db.ExecContext(ctx, `
+			UPDATE storage_objects
+			SET tier_status = $1, storage_class = $2, archived_at = NOW(), last_error = NULL
+			WHERE id = $3
+		`, models.TierStatusArchived, lm.config.ArchiveStorageClass, obj.id)
+		if err != nil {
+			logger.Error("Failed to record archival of %s: %v", obj.key, err)
+			continue
+		}
+		archived++
+	}
+
+	if archived > 0 {
+		logger.Info("Archived %d inactive %s objects to %s", archived, kind, lm.config.ArchiveStorageClass)
+	}
+	return nil
+}
+
+// refreshRestores polls pending restores and re-archives restored copies that expired
+func (lm *LifecycleManager) refreshRestores(ctx context.Context) error {
+	rows, err := lm.db.QueryContext(ctx, `
+		SELECT `+StorageObjectColumns+`
+		FROM storage_objects
+		WHERE tier_status IN ($1, $2)
+		ORDER BY restore_requested_at
+		LIMIT $3
+	`, models.TierStatusRestoring, models.TierStatusRestored, lifecycleBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to query restoring objects: %w", err)
+	}
+
+	var objects []models.StorageObject
+	for rows.Next() {
+		obj, err := ScanStorageObject(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		objects = append(objects, *obj)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range objects {
+		obj := &objects[i]
+		if obj.TierStatus == models.TierStatusRestored {
+			if obj.RestoreExpiresAt != nil && time.Now().After(*obj.RestoreExpiresAt) {
+				lm.db.ExecContext(ctx, `UPDATE storage_objects SET tier_status = $1, restore_expires_at = NULL WHERE id = $2`,
+					models.TierStatusArchived, obj.ID)
+			}
+			continue
+		}
+
+		state, err := lm.archiver.RestoreStatus(ctx, obj.ObjectKey)
+		if err != nil {
+			logger.Error("Failed to check restore status of %s: %v", obj.ObjectKey, err)
+			continue
+		}
+		if !state.Ongoing && state.ExpiresAt != nil {
+			lm.db.ExecContext(ctx, `UPDATE storage_objects SET tier_status = $1, restore_expires_at = $2 WHERE id = $3`,
+				models.TierStatusRestored, *state.ExpiresAt, obj.ID)
+			logger.Debug("Restore of %s completed, available until %v", obj.ObjectKey, *state.ExpiresAt)
+		}
+	}
+
+	return nil
+}
+
+// RequestRestore starts an on-demand restore of an archived object. The querier is usually
+// the tenant connection so organization access is enforced by RLS.
+func (lm *LifecycleManager) RequestRestore(ctx context.Context, q database.Querier, obj *models.StorageObject) error {
+	if !lm.Enabled() {
+		return storage.ErrArchiveUnsupported
+	}
+	if obj.TierStatus != models.TierStatusArchived {
+		return nil
+	}
+
+	if err := lm.archiver.Restore(ctx, obj.ObjectKey, lm.config.RestoreDays, lm.config.RestoreTier); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err := q.ExecContext(ctx, `
+		UPDATE storage_objects SET tier_status = $1, restore_requested_at = $2, last_accessed_at = $2
+		WHERE id = $3
+	`, models.TierStatusRestoring, now, obj.ID)
+	if err != nil {
+		return fmt.Errorf("failed to record restore request: %w", err)
+	}
+
+	obj.TierStatus = models.TierStatusRestoring
+	obj.RestoreRequestedAt = &now
+	return nil
+}
+
+// MarkAccessed refreshes the inactivity clock of an object
+func MarkAccessed(ctx context.Context, q database.Querier, objectKey string) error {
+	_, err := q.ExecContext(ctx, `UPDATE storage_objects SET last_accessed_at = NOW() WHERE object_key = $1`, objectKey)
+	return err
+}
+
+// ScanStorageObject scans a row selected with StorageObjectColumns
+func ScanStorageObject(row interface{ Scan(...interface{}) error }) (*models.StorageObject, error) {
+	var obj models.StorageObject
+	err := row.Scan(
+		&obj.ID, &obj.OrganizationID, &obj.VideoID, &obj.ObjectKey, &obj.Kind, &obj.SizeBytes,
+		&obj.TierStatus, &obj.StorageClass, &obj.LastAccessedAt, &obj.ArchivedAt,
+		&obj.RestoreRequestedAt, &obj.RestoreExpiresAt, &obj.LastError, &obj.CreatedAt, &obj.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
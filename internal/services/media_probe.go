@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// JobKindVideoProbe records the color signaling of a video's source
+const JobKindVideoProbe = "video.probe"
+
+// MediaProber reads the color signaling of sources with ffprobe and records it on their videos.
+// As an outbox publisher it queues a video.probe job in the relay's transaction for video.ready
+// and video.source_replaced events.
+type MediaProber struct {
+	db      *sql.DB
+	storage storage.Storage
+	ffprobe string
+	config  config.Probe
+}
+
+// NewMediaProber creates a prober. It fails when ffprobe cannot be found. db must not carry a
+// tenant context.
+func NewMediaProber(db *sql.DB, store storage.Storage, cfg config.Probe) (*MediaProber, error) {
+	if cfg.FFprobePath == "" {
+		return nil, fmt.Errorf("PROBE_FFPROBE_PATH is empty")
+	}
+	ffprobe, err := exec.LookPath(cfg.FFprobePath)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found at %s", cfg.FFprobePath)
+	}
+	return &MediaProber{db: db, storage: store, ffprobe: ffprobe, config: cfg}, nil
+}
+
+type probePayload struct {
+	VideoID uuid.UUID `json:"video_id"`
+}
+
+// Name implements outbox.Publisher
+func (p *MediaProber) Name() string {
+	return "media-probe"
+}
+
+// Publish implements outbox.Publisher
+func (p *MediaProber) Publish(ctx context.Context, tx *sql.Tx, e *outbox.Event) error {
+	if (e.Type != outbox.EventVideoReady && e.Type != outbox.EventVideoSourceReplaced) || e.SubjectID == nil {
+		return nil
+	}
+	_, err := jobs.Enqueue(ctx, tx, JobKindVideoProbe, probePayload{VideoID: *e.SubjectID},
+		jobs.Options{OrganizationID: &e.OrganizationID})
+	return err
+}
+
+// Handle runs a video.probe job
+func (p *MediaProber) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload probePayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	_, err := p.Color(ctx, payload.VideoID)
+	if err == sql.ErrNoRows {
+		// Deleted since the probe was queued
+		return nil
+	}
+	return err
+}
+
+// Color returns the color signaling of a video's current source, probing the source unless it
+// was probed already. It returns sql.ErrNoRows for videos that are gone or have no source.
+func (p *MediaProber) Color(ctx context.Context, videoID uuid.UUID) (*models.ColorInfo, error) {
+	state, err := queries.New(p.db).GetVideoColorState(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	if state.SourceKey == nil || *state.SourceKey == "" {
+		return nil, sql.ErrNoRows
+	}
+	revision := state.SourceRevision
+	if state.Color != nil && state.Color.SourceRevision == revision {
+		return state.Color, nil
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+	color, err := p.probe(probeCtx, *state.SourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe source of video %s: %w", videoID, err)
+	}
+	color.SourceRevision = revision
+	color.ProbedAt = time.Now().UTC()
+
+	// A source replaced meanwhile is probed by its own job
+	if err := queries.New(p.db).SetVideoColor(ctx, queries.SetVideoColorParams{
+		ID: videoID, SourceRevision: revision, Color: color,
+	}); err != nil {
+		return nil, err
+	}
+	if color.HDR() {
+		logger.Info("Source revision %d of video %s is %s", revision, videoID, color.DynamicRange)
+	}
+	return color, nil
+}
+
+// probe runs ffprobe on a source. Backends that presign URLs let ffprobe read only the parts
+// it needs; the source is downloaded otherwise.
+func (p *MediaProber) probe(ctx context.Context, key string) (*models.ColorInfo, error) {
+	input := ""
+	if presigner, ok := storage.AsPresigner(p.storage); ok {
+		var err error
+		if input, err = presigner.PresignGet(ctx, key, p.config.Timeout+time.Minute); err != nil {
+			return nil, err
+		}
+	} else {
+		f, err := os.CreateTemp("", "openvdo-probe-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		r, err := p.storage.Get(ctx, key)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		_, err = io.Copy(f, r)
+		r.Close()
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		input = f.Name()
+	}
+
+	// Mastering display and light level metadata are stream side data in MP4 and Matroska, and
+	// frame side data in raw HEVC, so the first frame is read as well
+	output, err := exec.CommandContext(ctx, p.ffprobe, "-v", "error", "-select_streams", "v:0",
+		"-show_streams", "-show_frames", "-read_intervals", "%+#1", "-of", "json", input).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+	return parseProbe(output)
+}
+
+// ffprobeOutput is the part of ffprobe's JSON output color signaling is read from
+type ffprobeOutput struct {
+	Streams []struct {
+		PixFmt           string            `json:"pix_fmt"`
+		BitsPerRawSample string            `json:"bits_per_raw_sample"`
+		ColorSpace       string            `json:"color_space"`
+		ColorTransfer    string            `json:"color_transfer"`
+		ColorPrimaries   string            `json:"color_primaries"`
+		SideDataList     []ffprobeSideData `json:"side_data_list"`
+	} `json:"streams"`
+	Frames []struct {
+		SideDataList []ffprobeSideData `json:"side_data_list"`
+	} `json:"frames"`
+}
+
+type ffprobeSideData struct {
+	Type          string `json:"side_data_type"`
+	MaxContent    int    `json:"max_content"`
+	MaxAverage    int    `json:"max_average"`
+	DVProfile     int    `json:"dv_profile"`
+	DVLevel       int    `json:"dv_level"`
+	Compatibility int    `json:"dv_bl_signal_compatibility_id"`
+}
+
+// parseProbe classifies a source from ffprobe's output. A Dolby Vision configuration record
+// wins over the transfer function, which is PQ in HDR10-compatible Dolby Vision too.
+func parseProbe(output []byte) (*models.ColorInfo, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+	if len(out.Streams) == 0 {
+		return nil, fmt.Errorf("ffprobe: no video stream")
+	}
+	stream := out.Streams[0]
+	color := &models.ColorInfo{
+		DynamicRange:   models.DynamicRangeSDR,
+		ColorPrimaries: stream.ColorPrimaries,
+		Transfer:       stream.ColorTransfer,
+		Matrix:         stream.ColorSpace,
+		BitDepth:       bitDepth(stream.BitsPerRawSample, stream.PixFmt),
+	}
+
+	sideData := stream.SideDataList
+	for _, frame := range out.Frames {
+		sideData = append(sideData, frame.SideDataList...)
+	}
+	for _, d := range sideData {
+		switch d.Type {
+		case "DOVI configuration record":
+			color.DolbyVision = &models.DolbyVisionInfo{Profile: d.DVProfile, Level: d.DVLevel, Compatibility: d.Compatibility}
+		case "Mastering display metadata":
+			color.MasteringDisplay = true
+		case "Content light level metadata":
+			color.MaxCLL, color.MaxFALL = d.MaxContent, d.MaxAverage
+		}
+	}
+
+	switch {
+	case color.DolbyVision != nil:
+		color.DynamicRange = models.DynamicRangeDolbyVision
+	case stream.ColorTransfer == "smpte2084":
+		color.DynamicRange = models.DynamicRangeHDR10
+	case stream.ColorTransfer == "arib-std-b67":
+		color.DynamicRange = models.DynamicRangeHLG
+	}
+	return color, nil
+}
+
+// bitDepth reads the bit depth ffprobe reports, or infers it from the pixel format, such as 10
+// for yuv420p10le
+func bitDepth(bits, pixFmt string) int {
+	if n, err := strconv.Atoi(bits); err == nil && n > 0 {
+		return n
+	}
+	for _, depth := range []string{"16", "12", "10"} {
+		if strings.Contains(pixFmt, "p"+depth) {
+			n, _ := strconv.Atoi(depth)
+			return n
+		}
+	}
+	if pixFmt == "" {
+		return 0
+	}
+	return 8
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// JobKindNotificationDeliver sends a recorded notification over the recipient's channels
+const JobKindNotificationDeliver = "notification.deliver"
+
+// NotificationTypes lists every notification type, in the order preferences are shown
+var NotificationTypes = []string{
+	models.NotificationVideoReady,
+	models.NotificationCommentReply,
+	models.NotificationInviteReceived,
+	models.NotificationAccountLocked,
+	models.NotificationVideoApproved,
+	models.NotificationVideoRejected,
+}
+
+// defaultNotificationChannels apply to the types a user has not set preferences for
+var defaultNotificationChannels = map[string]models.NotificationPreference{
+	models.NotificationVideoReady:     {Email: true, Push: true},
+	models.NotificationCommentReply:   {Email: true, Push: true},
+	models.NotificationInviteReceived: {Email: true, Push: false},
+	models.NotificationAccountLocked:  {Email: true, Push: true},
+	models.NotificationVideoApproved:  {Email: true, Push: true},
+	models.NotificationVideoRejected:  {Email: true, Push: true},
+}
+
+// DefaultNotificationPreference returns the channels a type is delivered on until the user
+// chooses otherwise
+func DefaultNotificationPreference(notificationType string) models.NotificationPreference {
+	pref := defaultNotificationChannels[notificationType]
+	pref.Type = notificationType
+	return pref
+}
+
+// IsNotificationType reports whether t is a known notification type
+func IsNotificationType(t string) bool {
+	_, ok := defaultNotificationChannels[t]
+	return ok
+}
+
+// Recipient is the user a notification is delivered to
+type Recipient struct {
+	UserID uuid.UUID
+	Email  string
+	Name   string
+}
+
+// Notifier delivers notifications over one channel, such as email
+type Notifier interface {
+	Channel() string
+	Notify(ctx context.Context, to Recipient, n *models.Notification) error
+}
+
+// NewNotification describes a notification to record
+type NewNotification struct {
+	UserID         uuid.UUID
+	OrganizationID *uuid.UUID
+	Type           string
+	Title          string
+	Body           string
+	// Data is marshaled to JSON for clients, e.g. the ID of the video a notification is about
+	Data interface{}
+}
+
+// deliveryPayload is the payload of a notification.deliver job
+type deliveryPayload struct {
+	NotificationID uuid.UUID `json:"notification_id"`
+}
+
+// deliveryCheckpoint records the channels a notification already went out on, so a retry
+// after one channel failed does not repeat the others
+type deliveryCheckpoint struct {
+	Delivered []string `json:"delivered"`
+}
+
+// Notify records a notification and queues its delivery. Passing the transaction of the change
+// it reports keeps the two consistent. Through a tenant connection OrganizationID must be one
+// of the caller's organizations.
+func Notify(ctx context.Context, q database.Querier, n NewNotification) (uuid.UUID, error) {
+	data := []byte("{}")
+	if n.Data != nil {
+		var err error
+		if data, err = json.Marshal(n.Data); err != nil {
+			return uuid.Nil, fmt.Errorf("failed to encode notification data: %w", err)
+		}
+	}
+
+	// Generated here rather than returned, since the caller may not see notifications of others
+	id := uuid.New()
+	if _, err := q.ExecContext(ctx, `
+		INSERT INTO notifications (id, user_id, organization_id, type, title, body, data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, id, n.UserID, n.OrganizationID, n.Type, n.Title, n.Body, data); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to record notification: %w", err)
+	}
+
+	if _, err := jobs.Enqueue(ctx, q, JobKindNotificationDeliver, deliveryPayload{NotificationID: id},
+		jobs.Options{OrganizationID: n.OrganizationID, MaxAttempts: 5}); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// NotifyVideoReady tells the creator of a video that it can be played
+func NotifyVideoReady(ctx context.Context, q database.Querier, videoID uuid.UUID) error {
+	video, err := queries.New(q).GetVideoOwner(ctx, videoID)
+	if err != nil {
+		return err
+	}
+	if video.CreatedBy == nil {
+		return nil
+	}
+
+	_, err = Notify(ctx, q, NewNotification{
+		UserID:         *video.CreatedBy,
+		OrganizationID: &video.OrganizationID,
+		Type:           models.NotificationVideoReady,
+		Title:          "Your video is ready",
+		Body:           fmt.Sprintf("%q has finished uploading and can be played.", video.Title),
+		Data:           map[string]uuid.UUID{"video_id": videoID},
+	})
+	return err
+}
+
+// NotificationColumns is the column list matching ScanNotification
+const NotificationColumns = `id, user_id, organization_id, type, title, body, data, read_at, created_at`
+
+// ScanNotification scans a row selected with NotificationColumns
+func ScanNotification(row interface{ Scan(...interface{}) error }) (*models.Notification, error) {
+	var n models.Notification
+	var data []byte
+	if err := row.Scan(&n.ID, &n.UserID, &n.OrganizationID, &n.Type, &n.Title, &n.Body, &data, &n.ReadAt,
+		&n.CreatedAt); err != nil {
+		return nil, err
+	}
+	n.Data = data
+	return &n, nil
+}
+
+// NotificationPreferences returns the user's preference for every notification type, filling
+// in the defaults for types the user has not set
+func NotificationPreferences(ctx context.Context, q database.Querier, userID uuid.UUID) ([]models.NotificationPreference, error) {
+	rows, err := q.QueryContext(ctx, `SELECT type, email, push FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := make(map[string]models.NotificationPreference)
+	for rows.Next() {
+		var pref models.NotificationPreference
+		if err := rows.Scan(&pref.Type, &pref.Email, &pref.Push); err != nil {
+			return nil, err
+		}
+		set[pref.Type] = pref
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prefs := make([]models.NotificationPreference, 0, len(NotificationTypes))
+	for _, t := range NotificationTypes {
+		pref, ok := set[t]
+		if !ok {
+			pref = DefaultNotificationPreference(t)
+		}
+		prefs = append(prefs, pref)
+	}
+	return prefs, nil
+}
+
+// NotificationDispatcher runs notification.deliver jobs, sending each notification over the
+// channels the recipient enabled for its type
+type NotificationDispatcher struct {
+	db        *sql.DB
+	notifiers map[string][]Notifier
+}
+
+// NewNotificationDispatcher creates a dispatcher. db must not carry a tenant context.
+func NewNotificationDispatcher(db *sql.DB) *NotificationDispatcher {
+	return &NotificationDispatcher{db: db, notifiers: make(map[string][]Notifier)}
+}
+
+// Register adds a notifier for its channel. A channel may have several notifiers; each gets
+// every notification sent on it.
+func (d *NotificationDispatcher) Register(n Notifier) {
+	d.notifiers[n.Channel()] = append(d.notifiers[n.Channel()], n)
+}
+
+// Handle runs a notification.deliver job
+func (d *NotificationDispatcher) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload deliveryPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	n, err := ScanNotification(d.db.QueryRowContext(ctx,
+		`SELECT `+NotificationColumns+` FROM notifications WHERE id = $1`, payload.NotificationID))
+	if err == sql.ErrNoRows {
+		// Deleted along with its user or organization
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	contact, err := queries.New(d.db).GetUserContact(ctx, n.UserID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	to := Recipient{UserID: n.UserID, Email: contact.Email, Name: contact.Name}
+
+	pref := DefaultNotificationPreference(n.Type)
+	err = d.db.QueryRowContext(ctx, `SELECT email, push FROM notification_preferences WHERE user_id = $1 AND type = $2`,
+		n.UserID, n.Type).Scan(&pref.Email, &pref.Push)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	var checkpoint deliveryCheckpoint
+	if len(job.Checkpoint) > 0 {
+		if err := json.Unmarshal(job.Checkpoint, &checkpoint); err != nil {
+			return jobs.Permanent(fmt.Errorf("invalid checkpoint: %w", err))
+		}
+	}
+	delivered := make(map[string]bool)
+	for _, key := range checkpoint.Delivered {
+		delivered[key] = true
+	}
+
+	channels := map[string]bool{
+		models.NotificationChannelEmail: pref.Email,
+		models.NotificationChannelPush:  pref.Push,
+	}
+	var errs []error
+	for _, channel := range []string{models.NotificationChannelEmail, models.NotificationChannelPush} {
+		if !channels[channel] {
+			continue
+		}
+		for i, notifier := range d.notifiers[channel] {
+			key := fmt.Sprintf("%s/%d", channel, i)
+			if delivered[key] {
+				continue
+			}
+			if err := notifier.Notify(ctx, to, n); err != nil {
+				if !jobs.IsPermanent(err) {
+					errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+					continue
+				}
+				// Retrying cannot help, e.g. for a rejected address, and must not hold up the rest
+				logger.Error("Failed to deliver notification %s by %s: %v", n.ID, channel, err)
+			}
+			checkpoint.Delivered = append(checkpoint.Delivered, key)
+			if err := jobs.SaveCheckpoint(ctx, d.db, job.ID, checkpoint); err != nil {
+				logger.Error("Failed to save delivery checkpoint of notification %s: %v", n.ID, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
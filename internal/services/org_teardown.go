@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"openvdo/internal/analytics"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// JobKindOrganizationTeardown removes the data of a deleted organization
+const JobKindOrganizationTeardown = "organization.teardown"
+
+// DeletionConfirmationTTL is how long the owner has to confirm a deletion they asked for
+const DeletionConfirmationTTL = 15 * time.Minute
+
+const teardownBatchSize = 100
+
+// Steps of a teardown, in order. Stored files go first, as their rows are deleted with the
+// videos they belong to.
+const (
+	teardownStepStorage   = "storage"
+	teardownStepVideos    = "videos"
+	teardownStepAnalytics = "analytics"
+)
+
+var (
+	// ErrOrganizationDeleted is returned when asking to delete an organization already deleted
+	ErrOrganizationDeleted = errors.New("organization is already deleted")
+	// ErrInvalidConfirmation is returned for confirmation tokens that do not match the last one
+	// issued to the user, or that expired
+	ErrInvalidConfirmation = errors.New("invalid or expired confirmation token")
+)
+
+// TeardownPayload is the payload of an organization.teardown job
+type TeardownPayload struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+}
+
+// teardownColumns is the column list matching scanTeardown
+const teardownColumns = `organization_id, status, job_id, step, objects_total, objects_deleted, bytes_deleted,
+	videos_total, videos_deleted, last_error, confirmed_at, completed_at, updated_at`
+
+func scanTeardown(row interface{ Scan(...interface{}) error }) (*models.OrganizationTeardown, error) {
+	var t models.OrganizationTeardown
+	err := row.Scan(&t.OrganizationID, &t.Status, &t.JobID, &t.Step, &t.ObjectsTotal, &t.ObjectsDeleted,
+		&t.BytesDeleted, &t.VideosTotal, &t.VideosDeleted, &t.LastError, &t.ConfirmedAt, &t.CompletedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// OrganizationTeardowns deletes organizations in two steps: their owner asks for a
+// confirmation token, then confirms with it. Confirming cuts off access at once and queues a
+// job removing the organization's stored files, videos and playback events in batches.
+type OrganizationTeardowns struct {
+	db        *sql.DB
+	storage   storage.Storage
+	analytics analytics.Store
+}
+
+// NewOrganizationTeardowns works through the master connection, as members lose access to the
+// organization's rows when the deletion is confirmed
+func NewOrganizationTeardowns(db *sql.DB, store storage.Storage, analyticsStore analytics.Store) *OrganizationTeardowns {
+	return &OrganizationTeardowns{db: db, storage: store, analytics: analyticsStore}
+}
+
+func hashConfirmation(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// RequestDeletion issues a token the user confirms the deletion of an organization with,
+// replacing any issued before. The caller checks that the user owns the organization.
+func (t *OrganizationTeardowns) RequestDeletion(ctx context.Context, orgID, userID uuid.UUID) (string, time.Time, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	expiresAt := time.Now().Add(DeletionConfirmationTTL)
+
+	result, err := t.db.ExecContext(ctx, `
+		INSERT INTO organization_teardowns (organization_id, requested_by, status, confirmation_hash, confirmation_expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (organization_id) DO UPDATE
+		SET requested_by = EXCLUDED.requested_by, confirmation_hash = EXCLUDED.confirmation_hash,
+			confirmation_expires_at = EXCLUDED.confirmation_expires_at
+		WHERE organization_teardowns.status = $3
+	`, orgID, userID, models.TeardownConfirming, hashConfirmation(token), expiresAt)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", time.Time{}, ErrOrganizationDeleted
+	}
+	return token, expiresAt, nil
+}
+
+// ConfirmDeletion deletes an organization with the token RequestDeletion issued to the user:
+// it marks the organization deleted, revokes its memberships and API keys and queues the
+// teardown. It returns the teardown and the members whose cached sessions must be dropped.
+func (t *OrganizationTeardowns) ConfirmDeletion(ctx context.Context, orgID, userID uuid.UUID, token string) (*models.OrganizationTeardown, []uuid.UUID, error) {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	var requestedBy *uuid.UUID
+	var hash []byte
+	var expiresAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, requested_by, confirmation_hash, confirmation_expires_at
+		FROM organization_teardowns WHERE organization_id = $1 FOR UPDATE
+	`, orgID).Scan(&status, &requestedBy, &hash, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrInvalidConfirmation
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if status != models.TeardownConfirming {
+		return nil, nil, ErrOrganizationDeleted
+	}
+	if requestedBy == nil || *requestedBy != userID || !expiresAt.Valid || time.Now().After(expiresAt.Time) ||
+		subtle.ConstantTimeCompare(hash, hashConfirmation(token)) != 1 {
+		return nil, nil, ErrInvalidConfirmation
+	}
+
+	qtx := queries.New(tx)
+	if err := qtx.MarkOrganizationDeleted(ctx, orgID); err != nil {
+		return nil, nil, err
+	}
+	members, err := qtx.DeleteOrganizationMembers(ctx, orgID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := qtx.ClearDefaultOrganization(ctx, orgID); err != nil {
+		return nil, nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM api_keys WHERE project_id IN (SELECT id FROM projects WHERE organization_id = $1)`,
+		orgID); err != nil {
+		return nil, nil, err
+	}
+
+	job, err := jobs.Enqueue(ctx, tx, JobKindOrganizationTeardown, TeardownPayload{OrganizationID: orgID},
+		jobs.Options{OrganizationID: &orgID, CreatedBy: &userID, MaxAttempts: 5})
+	if err != nil {
+		return nil, nil, err
+	}
+	teardown, err := scanTeardown(tx.QueryRowContext(ctx, `
+		UPDATE organization_teardowns
+		SET status = $2, job_id = $3, confirmation_hash = NULL, confirmation_expires_at = NULL, confirmed_at = NOW(),
+			objects_total = (SELECT COUNT(*) FROM storage_objects WHERE organization_id = $1),
+			videos_total = (SELECT COUNT(*) FROM videos WHERE organization_id = $1)
+		WHERE organization_id = $1
+		RETURNING `+teardownColumns,
+		orgID, models.TeardownPending, job.ID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return teardown, members, nil
+}
+
+// Get returns the deletion of an organization requested by the user, sql.ErrNoRows when there
+// is none. It is read without the organization's tenant context, which the requester has lost.
+func (t *OrganizationTeardowns) Get(ctx context.Context, orgID, userID uuid.UUID) (*models.OrganizationTeardown, error) {
+	return scanTeardown(t.db.QueryRowContext(ctx, `
+		SELECT `+teardownColumns+` FROM organization_teardowns WHERE organization_id = $1 AND requested_by = $2
+	`, orgID, userID))
+}
+
+// Handle runs an organization.teardown job, one batch per turn. Every step removes what is
+// left, so a retried teardown picks up where the failed one stopped.
+func (t *OrganizationTeardowns) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload TeardownPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	err := t.handle(ctx, payload.OrganizationID, progress)
+	if err != nil && ctx.Err() == nil && !jobs.IsReschedule(err) && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+		if _, dbErr := t.db.ExecContext(context.Background(), `
+			UPDATE organization_teardowns SET status = $2, last_error = $3 WHERE organization_id = $1
+		`, payload.OrganizationID, models.TeardownFailed, err.Error()); dbErr != nil {
+			logger.Error("Failed to record failed teardown of organization %s: %v", payload.OrganizationID, dbErr)
+		}
+	}
+	return err
+}
+
+func (t *OrganizationTeardowns) handle(ctx context.Context, orgID uuid.UUID, progress func(float64)) error {
+	var status string
+	var step sql.NullString
+	err := t.db.QueryRowContext(ctx, `
+		UPDATE organization_teardowns
+		SET status = $2, step = COALESCE(step, $3), last_error = NULL
+		WHERE organization_id = $1 AND status <> $4
+		RETURNING status, step
+	`, orgID, models.TeardownRunning, teardownStepStorage, models.TeardownConfirming).Scan(&status, &step)
+	if err == sql.ErrNoRows {
+		return jobs.Permanent(fmt.Errorf("organization %s has no confirmed deletion", orgID))
+	}
+	if err != nil {
+		return err
+	}
+
+	var more bool
+	next := ""
+	switch step.String {
+	case teardownStepStorage:
+		more, err = t.deleteObjects(ctx, orgID)
+		next = teardownStepVideos
+	case teardownStepVideos:
+		more, err = t.deleteVideos(ctx, orgID)
+		next = teardownStepAnalytics
+	case teardownStepAnalytics:
+		err = t.analytics.DeleteOrganization(ctx, orgID)
+	default:
+		// Completed before; a retried job has nothing left to do
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if more {
+		t.reportProgress(ctx, orgID, progress)
+		return jobs.Reschedule(0)
+	}
+
+	if next != "" {
+		if _, err := t.db.ExecContext(ctx, `UPDATE organization_teardowns SET step = $2 WHERE organization_id = $1`,
+			orgID, next); err != nil {
+			return err
+		}
+		t.reportProgress(ctx, orgID, progress)
+		return jobs.Reschedule(0)
+	}
+	if _, err := t.db.ExecContext(ctx, `
+		UPDATE organization_teardowns SET status = $2, step = NULL, completed_at = NOW() WHERE organization_id = $1
+	`, orgID, models.TeardownCompleted); err != nil {
+		return err
+	}
+	logger.Info("Teardown of organization %s completed", orgID)
+	return nil
+}
+
+// deleteObjects deletes a batch of the organization's stored files and reports whether any
+// remain
+func (t *OrganizationTeardowns) deleteObjects(ctx context.Context, orgID uuid.UUID) (bool, error) {
+	rows, err := t.db.QueryContext(ctx, `
+		SELECT id, object_key, COALESCE(size_bytes, 0) FROM storage_objects
+		WHERE organization_id = $1
+		ORDER BY id
+		LIMIT $2
+	`, orgID, teardownBatchSize)
+	if err != nil {
+		return false, err
+	}
+	var ids []uuid.UUID
+	var keys []string
+	var bytes int64
+	for rows.Next() {
+		var id uuid.UUID
+		var key string
+		var size int64
+		if err := rows.Scan(&id, &key, &size); err != nil {
+			rows.Close()
+			return false, err
+		}
+		ids = append(ids, id)
+		keys = append(keys, key)
+		bytes += size
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, key := range keys {
+		if err := t.storage.Delete(ctx, key); err != nil {
+			return false, fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+	if len(ids) > 0 {
+		if _, err := t.db.ExecContext(ctx, `DELETE FROM storage_objects WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return false, err
+		}
+		if _, err := t.db.ExecContext(ctx, `
+			UPDATE organization_teardowns
+			SET objects_deleted = objects_deleted + $2, bytes_deleted = bytes_deleted + $3
+			WHERE organization_id = $1
+		`, orgID, len(ids), bytes); err != nil {
+			return false, err
+		}
+	}
+	return len(ids) == teardownBatchSize, nil
+}
+
+// deleteVideos deletes a batch of the organization's videos, with their sources in case they
+// were stored without being tracked, and reports whether any remain. Their status history,
+// renditions and other rows go with them.
+func (t *OrganizationTeardowns) deleteVideos(ctx context.Context, orgID uuid.UUID) (bool, error) {
+	videos, err := queries.New(t.db).ListVideoSourcesInOrganization(ctx, queries.ListVideoSourcesInOrganizationParams{
+		OrganizationID: orgID, Limit: teardownBatchSize,
+	})
+	if err != nil {
+		return false, err
+	}
+	ids := make([]uuid.UUID, len(videos))
+	var keys []string
+	for i, v := range videos {
+		ids[i] = v.ID
+		if v.SourceKey != "" {
+			keys = append(keys, v.SourceKey)
+		}
+	}
+
+	for _, key := range keys {
+		if err := t.storage.Delete(ctx, key); err != nil {
+			return false, fmt.Errorf("failed to delete %s: %w", key, err)
+		}
+	}
+	if len(ids) > 0 {
+		if err := queries.New(t.db).DeleteVideos(ctx, ids); err != nil {
+			return false, err
+		}
+		if _, err := t.db.ExecContext(ctx, `
+			UPDATE organization_teardowns SET videos_deleted = videos_deleted + $2 WHERE organization_id = $1
+		`, orgID, len(ids)); err != nil {
+			return false, err
+		}
+	}
+	return len(ids) == teardownBatchSize, nil
+}
+
+// reportProgress sets the job's progress from the objects and videos deleted so far
+func (t *OrganizationTeardowns) reportProgress(ctx context.Context, orgID uuid.UUID, progress func(float64)) {
+	var done, total int64
+	err := t.db.QueryRowContext(ctx, `
+		SELECT objects_deleted + videos_deleted, objects_total + videos_total FROM organization_teardowns
+		WHERE organization_id = $1
+	`, orgID).Scan(&done, &total)
+	if err == nil && total > 0 {
+		progress(min(float64(done)/float64(total), 1))
+	}
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"encoding/json"
+
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+)
+
+// OrganizationModel converts an organization row, passing its error through
+func OrganizationModel(o queries.Organization, err error) (*models.Organization, error) {
+	if err != nil {
+		return nil, err
+	}
+	org := &models.Organization{
+		ID:              o.ID,
+		Name:            o.Name,
+		Description:     valueOf(o.Description),
+		Settings:        o.Settings,
+		ExternalID:      o.ExternalID,
+		PlaybackDomains: o.PlaybackDomains,
+		EmbedDomains:    o.EmbedDomains,
+		Region:          o.Region,
+		VASTTagURL:      o.VASTTagURL,
+		RequireReview:   o.RequireReview,
+		Banner:          o.Banner,
+		Version:         o.Version,
+		CreatedAt:       o.CreatedAt,
+		UpdatedAt:       o.UpdatedAt,
+	}
+	if org.Settings == nil {
+		org.Settings = json.RawMessage("{}")
+	}
+	if org.PlaybackDomains == nil {
+		org.PlaybackDomains = []string{}
+	}
+	if org.EmbedDomains == nil {
+		org.EmbedDomains = []string{}
+	}
+	return org, nil
+}
+
+// OrganizationModels converts organization rows, passing their error through
+func OrganizationModels(rows []queries.Organization, err error) ([]*models.Organization, error) {
+	if err != nil {
+		return nil, err
+	}
+	orgs := make([]*models.Organization, len(rows))
+	for i, row := range rows {
+		orgs[i], _ = OrganizationModel(row, nil)
+	}
+	return orgs, nil
+}
+
+// MembershipModel converts a user_org_roles row, passing its error through
+func MembershipModel(m queries.UserOrgRole, err error) (*models.Membership, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &models.Membership{
+		UserID:         m.UserID,
+		OrganizationID: m.OrganizationID,
+		Role:           m.Role,
+		InvitedBy:      m.InvitedBy,
+		CreatedAt:      m.CreatedAt,
+	}, nil
+}
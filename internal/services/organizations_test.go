@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// rowDriver serves one row of fixed values to every query, so scanners can be tested with the
+// conversions database/sql applies to real driver values. It is its own connector, so each
+// test opens a database with its values without registering a driver.
+type rowDriver struct{ values []driver.Value }
+
+func (d rowDriver) Open(string) (driver.Conn, error)             { return rowConn(d), nil }
+func (d rowDriver) Connect(context.Context) (driver.Conn, error) { return rowConn(d), nil }
+func (d rowDriver) Driver() driver.Driver                        { return d }
+
+type rowConn rowDriver
+
+func (c rowConn) Prepare(string) (driver.Stmt, error) { return rowStmt(c), nil }
+func (rowConn) Close() error                          { return nil }
+func (rowConn) Begin() (driver.Tx, error)             { return nil, driver.ErrSkip }
+
+type rowStmt rowConn
+
+func (rowStmt) Close() error                               { return nil }
+func (rowStmt) NumInput() int                              { return -1 }
+func (rowStmt) Exec([]driver.Value) (driver.Result, error) { return driver.ResultNoRows, nil }
+func (s rowStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fixedRows{values: s.values}, nil
+}
+
+type fixedRows struct {
+	values []driver.Value
+	done   bool
+}
+
+func (r *fixedRows) Columns() []string {
+	columns := make([]string, len(r.values))
+	for i := range columns {
+		columns[i] = "c"
+	}
+	return columns
+}
+func (*fixedRows) Close() error { return nil }
+func (r *fixedRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
+
+// rowDB opens a database answering every query with values
+func rowDB(t *testing.T, values ...driver.Value) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(rowDriver{values: values})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// getOrganization reads an organization the way the API does from a database answering with values
+func getOrganization(t *testing.T, values ...driver.Value) (*models.Organization, error) {
+	t.Helper()
+	return OrganizationModel(queries.New(rowDB(t, values...)).GetOrganization(context.Background(), uuid.New()))
+}
+
+// organizationRow returns the values of an organization row, in the order of its columns, as
+// lib/pq hands them over
+func organizationRow(createdAt, updatedAt driver.Value) []driver.Value {
+	return []driver.Value{
+		[]byte("0b9f7e0c-4f2e-4c55-9d2b-3c6a1f0e8a11"), "Acme", "", []byte(`{}`), createdAt, updatedAt,
+		int64(3), []byte("{https://www.example.com}"), nil, nil, nil, []byte("{}"), nil, nil, nil, nil,
+		false, nil,
+	}
+}
+
+func TestGetOrganizationTimestampsRoundTrip(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	createdAt := time.Date(2026, 5, 1, 9, 30, 0, 123456000, paris)
+	updatedAt := time.Date(2026, 10, 16, 13, 0, 5, 0, time.UTC)
+
+	org, err := getOrganization(t, organizationRow(createdAt, updatedAt)...)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if !org.CreatedAt.Equal(createdAt) || !org.UpdatedAt.Equal(updatedAt) {
+		t.Fatalf("scanned created_at %v and updated_at %v, want %v and %v", org.CreatedAt, org.UpdatedAt, createdAt, updatedAt)
+	}
+
+	body, err := json.Marshal(org)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		CreatedAt string `json:"created_at"`
+		UpdatedAt string `json:"updated_at"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if want := "2026-05-01T09:30:00.123456+02:00"; decoded.CreatedAt != want {
+		t.Errorf("created_at = %q, want %q", decoded.CreatedAt, want)
+	}
+	if want := "2026-10-16T13:00:05Z"; decoded.UpdatedAt != want {
+		t.Errorf("updated_at = %q, want %q", decoded.UpdatedAt, want)
+	}
+}
+
+func TestGetOrganizationRejectsNullTimestamps(t *testing.T) {
+	now := time.Now()
+	for name, values := range map[string][]driver.Value{
+		"created_at": organizationRow(nil, now),
+		"updated_at": organizationRow(now, nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if org, err := getOrganization(t, values...); err == nil {
+				t.Fatalf("scanning a NULL %s gave %+v, want an error", name, org)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"openvdo/internal/queries"
+)
+
+// NormalizeOrigin validates a playback domain and returns it in the form browsers send in
+// the Origin header: a lowercase scheme and host, without path or trailing slash
+func NormalizeOrigin(origin string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http(s) origin", origin)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", fmt.Errorf("%q must not contain a path, query or credentials", origin)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// ListPlaybackDomains returns the playback domains of all organizations. It needs a
+// connection without tenant context, since origins are checked before authentication.
+func ListPlaybackDomains(ctx context.Context, db *sql.DB) ([]string, error) {
+	return queries.New(db).ListPlaybackDomains(ctx)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrConcurrentStreamLimit is returned when a viewer already has as many active sessions as allowed
+	ErrConcurrentStreamLimit = errors.New("concurrent stream limit reached")
+	// ErrPlaybackSessionEnded is returned for heartbeats of sessions that were ended or expired
+	ErrPlaybackSessionEnded = errors.New("playback session ended")
+)
+
+// PlaybackSessionColumns is the column list matching ScanPlaybackSession
+const PlaybackSessionColumns = `id, organization_id, video_id, user_id, client_ip, user_agent, position_seconds, watched_seconds,
+	heartbeats, ended_at IS NULL AND expires_at > NOW(), started_at, last_heartbeat_at, expires_at, ended_at`
+
+// ScanPlaybackSession scans a row selected with PlaybackSessionColumns
+func ScanPlaybackSession(row interface{ Scan(...interface{}) error }) (*models.PlaybackSession, error) {
+	var s models.PlaybackSession
+	err := row.Scan(&s.ID, &s.OrganizationID, &s.VideoID, &s.UserID, &s.ClientIP, &s.UserAgent, &s.PositionSeconds, &s.WatchedSeconds,
+		&s.Heartbeats, &s.Active, &s.StartedAt, &s.LastHeartbeatAt, &s.ExpiresAt, &s.EndedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// NewPlaybackSession describes a session to start
+type NewPlaybackSession struct {
+	Video     *models.Video
+	UserID    uuid.UUID
+	ClientIP  string
+	UserAgent string
+	// Timeout is how long the session stays active without a heartbeat
+	Timeout time.Duration
+	// MaxStreams bounds the viewer's active sessions in the video's organization; 0 is unlimited
+	MaxStreams int
+}
+
+// StartPlaybackSession opens a playback session. Expired sessions of the viewer are ended
+// first, and with MaxStreams set it fails with ErrConcurrentStreamLimit when the viewer has
+// that many active sessions left. The viewer's sessions are locked for the transaction, so
+// concurrent starts cannot both take the last stream.
+func StartPlaybackSession(ctx context.Context, tx *sql.Tx, n NewPlaybackSession) (*models.PlaybackSession, error) {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('playback_sessions:' || $1::text, 0))`, n.UserID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE playback_sessions SET ended_at = expires_at
+		WHERE user_id = $1 AND ended_at IS NULL AND expires_at <= NOW()`, n.UserID); err != nil {
+		return nil, err
+	}
+
+	if n.MaxStreams > 0 {
+		var active int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM playback_sessions
+			WHERE user_id = $1 AND organization_id = $2 AND ended_at IS NULL`,
+			n.UserID, n.Video.OrganizationID).Scan(&active)
+		if err != nil {
+			return nil, err
+		}
+		if active >= n.MaxStreams {
+			return nil, ErrConcurrentStreamLimit
+		}
+	}
+
+	return ScanPlaybackSession(tx.QueryRowContext(ctx, `
+		INSERT INTO playback_sessions (organization_id, video_id, user_id, client_ip, user_agent, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + make_interval(secs => $6))
+		RETURNING `+PlaybackSessionColumns,
+		n.Video.OrganizationID, n.Video.ID, n.UserID, n.ClientIP, truncateRunes(n.UserAgent, 512), n.Timeout.Seconds()))
+}
+
+// HeartbeatPlaybackSession keeps a viewer's session active for another timeout and records
+// the position, when it is given. The watch time grows by the progress since the last heartbeat, at most the
+// time that passed, so seeking ahead does not count. Sessions that ended or expired give
+// ErrPlaybackSessionEnded, and sessions of other viewers sql.ErrNoRows.
+func HeartbeatPlaybackSession(ctx context.Context, q database.Querier, id, userID uuid.UUID, position *float64, timeout time.Duration) (*models.PlaybackSession, error) {
+	s, err := ScanPlaybackSession(q.QueryRowContext(ctx, `
+		UPDATE playback_sessions
+		SET watched_seconds = watched_seconds +
+				LEAST(GREATEST(COALESCE($3, position_seconds) - position_seconds, 0), EXTRACT(EPOCH FROM NOW() - last_heartbeat_at)),
+			position_seconds = COALESCE($3, position_seconds), heartbeats = heartbeats + 1, last_heartbeat_at = NOW(),
+			expires_at = NOW() + make_interval(secs => $4)
+		WHERE id = $1 AND user_id = $2 AND ended_at IS NULL AND expires_at > NOW()
+		RETURNING `+PlaybackSessionColumns,
+		id, userID, position, timeout.Seconds()))
+	if err == sql.ErrNoRows {
+		return nil, sessionGone(ctx, q, id, userID)
+	}
+	return s, err
+}
+
+// EndPlaybackSession ends a viewer's session, recording the final position when it is given
+func EndPlaybackSession(ctx context.Context, q database.Querier, id, userID uuid.UUID, position *float64) (*models.PlaybackSession, error) {
+	s, err := ScanPlaybackSession(q.QueryRowContext(ctx, `
+		UPDATE playback_sessions
+		SET position_seconds = COALESCE($3, position_seconds), ended_at = LEAST(NOW(), expires_at)
+		WHERE id = $1 AND user_id = $2 AND ended_at IS NULL
+		RETURNING `+PlaybackSessionColumns,
+		id, userID, position))
+	if err == sql.ErrNoRows {
+		return nil, sessionGone(ctx, q, id, userID)
+	}
+	return s, err
+}
+
+// sessionGone tells a session that ended apart from one that does not exist
+func sessionGone(ctx context.Context, q database.Querier, id, userID uuid.UUID) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT true FROM playback_sessions WHERE id = $1 AND user_id = $2`, id, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	return ErrPlaybackSessionEnded
+}
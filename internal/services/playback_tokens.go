@@ -0,0 +1,71 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPlaybackToken is returned for tokens that are malformed, expired or signed for another video
+var ErrInvalidPlaybackToken = errors.New("invalid playback token")
+
+// ErrInvalidDownloadToken is returned for download tokens that are malformed, expired or
+// signed for another download
+var ErrInvalidDownloadToken = errors.New("invalid download token")
+
+// SignPlaybackToken returns a token that grants playback of the video until expires. Tokens
+// are "<unix expiry>.<signature>" so they fit in a query parameter without escaping.
+func SignPlaybackToken(key string, videoID uuid.UUID, expires time.Time) string {
+	return signToken(key, videoID.String(), expires)
+}
+
+// VerifyPlaybackToken checks that token was signed with key for the video and has not expired
+func VerifyPlaybackToken(key string, videoID uuid.UUID, token string) error {
+	if !verifyToken(key, videoID.String(), token) {
+		return ErrInvalidPlaybackToken
+	}
+	return nil
+}
+
+// SignDownloadToken returns a token that grants fetching the file of a download until expires.
+// The prefix keeps download and playback tokens apart, as both are signed with the same key.
+func SignDownloadToken(key string, downloadID uuid.UUID, expires time.Time) string {
+	return signToken(key, "download:"+downloadID.String(), expires)
+}
+
+// VerifyDownloadToken checks that token was signed with key for the download and has not expired
+func VerifyDownloadToken(key string, downloadID uuid.UUID, token string) error {
+	if !verifyToken(key, "download:"+downloadID.String(), token) {
+		return ErrInvalidDownloadToken
+	}
+	return nil
+}
+
+func signToken(key, subject string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + tokenSignature(key, subject, exp)
+}
+
+func verifyToken(key, subject, token string) bool {
+	exp, sig, ok := strings.Cut(token, ".")
+	if key == "" || !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(tokenSignature(key, subject, exp)))
+}
+
+func tokenSignature(key, subject, exp string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(subject + "." + exp))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func BenchmarkSignPlaybackToken(b *testing.B) {
+	videoID := uuid.New()
+	expires := time.Now().Add(time.Hour)
+	b.ReportAllocs()
+	for b.Loop() {
+		SignPlaybackToken("benchmark-signing-key", videoID, expires)
+	}
+}
+
+func BenchmarkVerifyPlaybackToken(b *testing.B) {
+	videoID := uuid.New()
+	token := SignPlaybackToken("benchmark-signing-key", videoID, time.Now().Add(time.Hour))
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := VerifyPlaybackToken("benchmark-signing-key", videoID, token); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// ErrDeviceGone is returned by push providers when a device token or subscription is no
+// longer valid, e.g. because the app was uninstalled; the device is then removed
+var ErrDeviceGone = errors.New("push device is no longer registered")
+
+// PushMessage is a notification as handed to push providers
+type PushMessage struct {
+	// ID doubles as the collapse key, so a retried delivery replaces the earlier one on the device
+	ID    uuid.UUID
+	Type  string
+	Title string
+	Body  string
+	Data  json.RawMessage
+	TTL   time.Duration
+}
+
+// PushProvider sends push messages to the devices of one platform
+type PushProvider interface {
+	Platform() string
+	Send(ctx context.Context, device *models.PushDevice, msg *PushMessage) error
+}
+
+// PushNotifier delivers notifications to every registered device of the recipient through the
+// provider of its platform
+type PushNotifier struct {
+	db        *sql.DB
+	ttl       time.Duration
+	providers map[string]PushProvider
+}
+
+// NewPushNotifier creates a push notifier. db must not carry a tenant context.
+func NewPushNotifier(db *sql.DB, ttl time.Duration, providers ...PushProvider) *PushNotifier {
+	p := &PushNotifier{db: db, ttl: ttl, providers: make(map[string]PushProvider)}
+	for _, provider := range providers {
+		p.providers[provider.Platform()] = provider
+	}
+	return p
+}
+
+// NewPushProviders creates a provider for every platform with credentials in cfg
+func NewPushProviders(cfg config.Push) ([]PushProvider, error) {
+	var providers []PushProvider
+	if cfg.VAPIDPublicKey != "" || cfg.VAPIDPrivateKey != "" {
+		p, err := NewWebPushProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if cfg.FCMCredentialsFile != "" {
+		p, err := NewFCMProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	if cfg.APNsKeyFile != "" {
+		p, err := NewAPNsProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
+// Platforms lists the platforms devices can register for
+func (p *PushNotifier) Platforms() []string {
+	platforms := make([]string, 0, len(p.providers))
+	for platform := range p.providers {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+	return platforms
+}
+
+// Supports reports whether devices of the platform can receive notifications
+func (p *PushNotifier) Supports(platform string) bool {
+	_, ok := p.providers[platform]
+	return ok
+}
+
+// Channel implements Notifier
+func (p *PushNotifier) Channel() string {
+	return models.NotificationChannelPush
+}
+
+// Notify implements Notifier. Devices that fail permanently are skipped; a transient failure
+// of any device fails the delivery, and the retry sends to all devices again under the same
+// collapse key.
+func (p *PushNotifier) Notify(ctx context.Context, to Recipient, n *models.Notification) error {
+	devices, err := p.devices(ctx, to.UserID)
+	if err != nil {
+		return err
+	}
+
+	msg := &PushMessage{ID: n.ID, Type: n.Type, Title: n.Title, Body: n.Body, Data: n.Data, TTL: p.ttl}
+	var errs []error
+	for _, device := range devices {
+		err := p.providers[device.Platform].Send(ctx, device, msg)
+		switch {
+		case errors.Is(err, ErrDeviceGone):
+			logger.Info("Removing %s push device %s of user %s: %v", device.Platform, device.ID, device.UserID, err)
+			if _, err := p.db.ExecContext(ctx, `DELETE FROM push_devices WHERE id = $1`, device.ID); err != nil {
+				logger.Error("Failed to remove push device %s: %v", device.ID, err)
+			}
+		case jobs.IsPermanent(err):
+			logger.Error("Failed to push notification %s to device %s: %v", n.ID, device.ID, err)
+		case err != nil:
+			errs = append(errs, fmt.Errorf("device %s: %w", device.ID, err))
+		default:
+			if _, err := p.db.ExecContext(ctx, `UPDATE push_devices SET last_used_at = NOW() WHERE id = $1`,
+				device.ID); err != nil {
+				logger.Error("Failed to update push device %s: %v", device.ID, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// devices returns the user's devices on platforms with a provider
+func (p *PushNotifier) devices(ctx context.Context, userID uuid.UUID) ([]*models.PushDevice, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT `+PushDeviceColumns+` FROM push_devices WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []*models.PushDevice
+	for rows.Next() {
+		device, err := ScanPushDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		if p.Supports(device.Platform) {
+			devices = append(devices, device)
+		}
+	}
+	return devices, rows.Err()
+}
+
+// PushDeviceColumns is the column list matching ScanPushDevice
+const PushDeviceColumns = `id, user_id, platform, token, COALESCE(p256dh, ''), COALESCE(auth, ''), name, last_used_at, created_at, updated_at`
+
+// ScanPushDevice scans a row selected with PushDeviceColumns
+func ScanPushDevice(row interface{ Scan(...interface{}) error }) (*models.PushDevice, error) {
+	var d models.PushDevice
+	if err := row.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.P256DH, &d.Auth, &d.Name, &d.LastUsedAt,
+		&d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// signJWT returns a compact JWS of the header and claims. Web Push and APNs sign with ES256,
+// which encodes the signature as the fixed-length r and s rather than ASN.1; FCM's service
+// account grant uses RS256.
+func signJWT(header, claims interface{}, key crypto.Signer) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	if ec, ok := key.(*ecdsa.PrivateKey); ok {
+		r, s, err := ecdsa.Sign(rand.Reader, ec, digest[:])
+		if err != nil {
+			return "", err
+		}
+		size := (ec.Curve.Params().BitSize + 7) / 8
+		sig = make([]byte, 2*size)
+		r.FillBytes(sig[:size])
+		s.FillBytes(sig[size:])
+	} else if sig, err = key.Sign(rand.Reader, digest[:], crypto.SHA256); err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrReviewPending is returned when submitting a video that already waits for review
+	ErrReviewPending = errors.New("video is already waiting for review")
+	// ErrReviewDecided is returned when deciding a review that was approved or rejected already
+	ErrReviewDecided = errors.New("review was already decided")
+	// ErrNotRejected is returned when submitting a video that was not rejected; approved videos
+	// need no review
+	ErrNotRejected = errors.New("only rejected videos can be submitted for review")
+)
+
+// ReviewColumns is the column list of video_reviews matching ScanReview, with the title of
+// the video
+const ReviewColumns = `id, video_id, organization_id, submitted_by, status, reviewed_by, decision_note, decided_at,
+	created_at, updated_at, (SELECT title FROM videos WHERE videos.id = video_reviews.video_id)`
+
+// ScanReview scans a row selected with ReviewColumns
+func ScanReview(row interface{ Scan(...interface{}) error }) (*models.VideoReview, error) {
+	var r models.VideoReview
+	err := row.Scan(&r.ID, &r.VideoID, &r.OrganizationID, &r.SubmittedBy, &r.Status, &r.ReviewedBy, &r.DecisionNote,
+		&r.DecidedAt, &r.CreatedAt, &r.UpdatedAt, &r.VideoTitle)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ReviewCommentColumns is the column list matching ScanReviewComment
+const ReviewCommentColumns = `id, review_id, author_id, body, position_seconds, created_at`
+
+// ScanReviewComment scans a row selected with ReviewCommentColumns
+func ScanReviewComment(row interface{ Scan(...interface{}) error }) (*models.ReviewComment, error) {
+	var c models.ReviewComment
+	if err := row.Scan(&c.ID, &c.ReviewID, &c.AuthorID, &c.Body, &c.PositionSeconds, &c.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// HoldForReview opens a review of a video just created by a user with role when its
+// organization requires review, so the video only plays for members until it is approved.
+// Videos of owners and admins are never held. It reports whether the video was held.
+func HoldForReview(ctx context.Context, q database.Querier, orgID, videoID, userID uuid.UUID, role string) (bool, error) {
+	if role == models.RoleOwner || role == models.RoleAdmin {
+		return false, nil
+	}
+	required, err := queries.New(q).RequiresReview(ctx, orgID)
+	if err != nil {
+		return false, err
+	}
+	if !required {
+		return false, nil
+	}
+	if _, err := openReview(ctx, q, videoID, userID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SubmitForReview submits a rejected video for review again. q should be a transaction, as
+// the video's row is locked until it ends.
+func SubmitForReview(ctx context.Context, q database.Querier, videoID, userID uuid.UUID) (*models.VideoReview, error) {
+	status, err := queries.New(q).LockVideoReviewStatus(ctx, queries.LockVideoReviewStatusParams{
+		ID: videoID, ExcludedStatus: models.VideoStatusDeleted,
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case models.ReviewPending:
+		return nil, ErrReviewPending
+	case models.ReviewApproved:
+		return nil, ErrNotRejected
+	}
+	return openReview(ctx, q, videoID, userID)
+}
+
+func openReview(ctx context.Context, q database.Querier, videoID, userID uuid.UUID) (*models.VideoReview, error) {
+	if err := queries.New(q).SetVideoReviewStatus(ctx, queries.SetVideoReviewStatusParams{
+		ID: videoID, ReviewStatus: models.ReviewPending,
+	}); err != nil {
+		return nil, err
+	}
+	review, err := ScanReview(q.QueryRowContext(ctx, `
+		INSERT INTO video_reviews (video_id, organization_id, submitted_by)
+		SELECT id, organization_id, $2 FROM videos WHERE id = $1
+		RETURNING `+ReviewColumns, videoID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open review: %w", err)
+	}
+	return review, nil
+}
+
+// DecideReview approves or rejects a pending review, publishing or keeping back its video. The
+// decision is written to the outbox as a video.approved or video.rejected event, and the member
+// who submitted the video is notified. q should be a transaction. A missing review returns
+// sql.ErrNoRows, and one decided already ErrReviewDecided.
+func DecideReview(ctx context.Context, q database.Querier, reviewID, reviewerID uuid.UUID, approve bool, note string) (*models.VideoReview, error) {
+	status, eventType, notification := models.ReviewRejected, outbox.EventVideoRejected, models.NotificationVideoRejected
+	if approve {
+		status, eventType, notification = models.ReviewApproved, outbox.EventVideoApproved, models.NotificationVideoApproved
+	}
+
+	review, err := ScanReview(q.QueryRowContext(ctx, `
+		UPDATE video_reviews SET status = $2, reviewed_by = $3, decision_note = $4, decided_at = NOW()
+		WHERE id = $1 AND status = $5
+		RETURNING `+ReviewColumns, reviewID, status, reviewerID, note, models.ReviewPending))
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM video_reviews WHERE id = $1)`,
+			reviewID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrReviewDecided
+		}
+		return nil, sql.ErrNoRows
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	video, err := VideoModel(queries.New(q).DecideVideoReview(ctx, queries.DecideVideoReviewParams{
+		ID: review.VideoID, ReviewStatus: status,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	// The video's fields stay at the top of the data, like the other video events
+	event := VideoEvent(eventType, video)
+	event.Data = struct {
+		*models.Video
+		Review *models.VideoReview `json:"review"`
+	}{video, review}
+	if err := outbox.Write(ctx, q, event); err != nil {
+		return nil, err
+	}
+
+	if review.SubmittedBy == nil || *review.SubmittedBy == reviewerID {
+		return review, nil
+	}
+	title, body := "Your video was approved", fmt.Sprintf("%q was approved and is now published.", video.Title)
+	if !approve {
+		title, body = "Your video was rejected", fmt.Sprintf("%q was not approved: %s", video.Title, note)
+	}
+	if _, err := Notify(ctx, q, NewNotification{
+		UserID:         *review.SubmittedBy,
+		OrganizationID: &video.OrganizationID,
+		Type:           notification,
+		Title:          title,
+		Body:           body,
+		Data:           map[string]uuid.UUID{"video_id": video.ID, "review_id": review.ID},
+	}); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
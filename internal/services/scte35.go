@@ -0,0 +1,103 @@
+package services
+
+import (
+	"encoding/base64"
+	"math"
+)
+
+// SCTE-35 splice_info_section constants
+const (
+	scte35TableID      = 0xFC
+	scte35SpliceInsert = 0x05
+	// scte35Clock is the 90 kHz clock of PTS and durations
+	scte35Clock = 90000
+	pts33Mask   = 1<<33 - 1
+)
+
+// bitWriter appends big-endian bit fields
+type bitWriter struct {
+	buf  []byte
+	nbit uint
+}
+
+func (w *bitWriter) write(value uint64, bits uint) {
+	for i := int(bits) - 1; i >= 0; i-- {
+		if w.nbit%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if value>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 1 << (7 - w.nbit%8)
+		}
+		w.nbit++
+	}
+}
+
+// SpliceInsert encodes an SCTE-35 splice_insert that leaves the network at offset seconds of
+// the presentation for an avail of duration seconds and returns automatically, in base64 as
+// carried by #EXT-OATCLS-SCTE35 and #EXT-X-DATERANGE.
+func SpliceInsert(eventID uint32, offset, duration float64) string {
+	var cmd bitWriter
+	cmd.write(uint64(eventID), 32)
+	cmd.write(0, 1)    // splice_event_cancel_indicator
+	cmd.write(0x7F, 7) // reserved
+	cmd.write(1, 1)    // out_of_network_indicator
+	cmd.write(1, 1)    // program_splice_flag
+	cmd.write(1, 1)    // duration_flag
+	cmd.write(0, 1)    // splice_immediate_flag
+	cmd.write(1, 1)    // event_id_compliance_flag
+	cmd.write(0x7, 3)  // reserved
+	// splice_time()
+	cmd.write(1, 1) // time_specified_flag
+	cmd.write(0x3F, 6)
+	cmd.write(uint64(math.Round(offset*scte35Clock))&pts33Mask, 33)
+	// break_duration()
+	cmd.write(1, 1) // auto_return
+	cmd.write(0x3F, 6)
+	cmd.write(uint64(math.Round(duration*scte35Clock))&pts33Mask, 33)
+	cmd.write(0, 16) // unique_program_id
+	cmd.write(0, 8)  // avail_num
+	cmd.write(0, 8)  // avails_expected
+
+	// Everything after section_length: the fixed header fields, the command, an empty
+	// descriptor loop and the CRC
+	sectionLength := 11 + len(cmd.buf) + 2 + 4
+
+	var w bitWriter
+	w.write(scte35TableID, 8)
+	w.write(0, 1)   // section_syntax_indicator
+	w.write(0, 1)   // private_indicator
+	w.write(0x3, 2) // sap_type: not specified
+	w.write(uint64(sectionLength), 12)
+	w.write(0, 8)      // protocol_version
+	w.write(0, 1)      // encrypted_packet
+	w.write(0, 6)      // encryption_algorithm
+	w.write(0, 33)     // pts_adjustment
+	w.write(0, 8)      // cw_index
+	w.write(0xFFF, 12) // tier
+	w.write(uint64(len(cmd.buf)), 12)
+	w.write(scte35SpliceInsert, 8)
+	w.buf = append(w.buf, cmd.buf...)
+	w.nbit += uint(len(cmd.buf)) * 8
+	w.write(0, 16) // descriptor_loop_length
+
+	crc := crc32MPEG2(w.buf)
+	w.buf = append(w.buf, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+	return base64.StdEncoding.EncodeToString(w.buf)
+}
+
+// crc32MPEG2 is the CRC of MPEG-2 sections: polynomial 0x04C11DB7, not reflected, initial
+// value 0xFFFFFFFF and no final XOR
+func crc32MPEG2(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
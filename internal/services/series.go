@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// MaxEpisodes bounds the episodes of a series
+const MaxEpisodes = 1000
+
+var (
+	// ErrInvalidEpisodes is returned for episode lists that cannot be used
+	ErrInvalidEpisodes = errors.New("invalid episodes")
+	// ErrSeriesProject is returned when the project of a series is not in its organization
+	ErrSeriesProject = errors.New("project not found in the organization")
+)
+
+// SeriesColumns is the column list matching ScanSeries
+const SeriesColumns = `id, organization_id, project_id, title, description, artwork, created_by, version, created_at, updated_at`
+
+// ScanSeries scans a row selected with SeriesColumns
+func ScanSeries(row interface{ Scan(...interface{}) error }) (*models.Series, error) {
+	var s models.Series
+	err := row.Scan(&s.ID, &s.OrganizationID, &s.ProjectID, &s.Title, &s.Description, &s.Artwork, &s.CreatedBy,
+		&s.Version, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CheckSeriesProject checks that projectID, when given, belongs to the organization
+func CheckSeriesProject(ctx context.Context, q database.Querier, orgID uuid.UUID, projectID *uuid.UUID) error {
+	if projectID == nil {
+		return nil
+	}
+	var found bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND organization_id = $2)`,
+		*projectID, orgID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrSeriesProject
+	}
+	return nil
+}
+
+// EpisodeSpec places a video in a series. A missing season continues the season of the
+// previous episode in the list, or season 1, and a missing episode number follows the
+// previous episode of the same season.
+type EpisodeSpec struct {
+	VideoID       uuid.UUID
+	SeasonNumber  *int
+	EpisodeNumber *int
+}
+
+// NumberEpisodes fills in the missing season and episode numbers of an ordered episode list
+// and sorts it by season and episode. Videos and episode numbers must be distinct.
+func NumberEpisodes(specs []EpisodeSpec) ([]models.Episode, error) {
+	if len(specs) > MaxEpisodes {
+		return nil, fmt.Errorf("%w: a series has at most %d episodes", ErrInvalidEpisodes, MaxEpisodes)
+	}
+
+	episodes := make([]models.Episode, 0, len(specs))
+	videos := map[uuid.UUID]bool{}
+	last := map[int]int{}
+	season := 1
+	for _, spec := range specs {
+		if spec.VideoID == uuid.Nil {
+			return nil, fmt.Errorf("%w: every episode needs a video_id", ErrInvalidEpisodes)
+		}
+		if videos[spec.VideoID] {
+			return nil, fmt.Errorf("%w: video %s is listed twice", ErrInvalidEpisodes, spec.VideoID)
+		}
+		videos[spec.VideoID] = true
+
+		if spec.SeasonNumber != nil {
+			season = *spec.SeasonNumber
+		}
+		if season < 0 {
+			return nil, fmt.Errorf("%w: season numbers must be zero or more", ErrInvalidEpisodes)
+		}
+		number := last[season] + 1
+		if spec.EpisodeNumber != nil {
+			number = *spec.EpisodeNumber
+		}
+		if number <= 0 {
+			return nil, fmt.Errorf("%w: episode numbers must be positive", ErrInvalidEpisodes)
+		}
+		last[season] = number
+		episodes = append(episodes, models.Episode{VideoID: spec.VideoID, SeasonNumber: season, EpisodeNumber: number})
+	}
+
+	sort.SliceStable(episodes, func(i, j int) bool {
+		if episodes[i].SeasonNumber != episodes[j].SeasonNumber {
+			return episodes[i].SeasonNumber < episodes[j].SeasonNumber
+		}
+		return episodes[i].EpisodeNumber < episodes[j].EpisodeNumber
+	})
+	for i := 1; i < len(episodes); i++ {
+		if episodes[i].SeasonNumber == episodes[i-1].SeasonNumber && episodes[i].EpisodeNumber == episodes[i-1].EpisodeNumber {
+			return nil, fmt.Errorf("%w: two episodes are numbered S%dE%d", ErrInvalidEpisodes,
+				episodes[i].SeasonNumber, episodes[i].EpisodeNumber)
+		}
+	}
+	return episodes, nil
+}
+
+const episodeColumns = `e.series_id, e.video_id, e.season_number, e.episode_number, v.title, v.status`
+
+func scanEpisode(row interface{ Scan(...interface{}) error }) (*models.Episode, error) {
+	var e models.Episode
+	if err := row.Scan(&e.SeriesID, &e.VideoID, &e.SeasonNumber, &e.EpisodeNumber, &e.Title, &e.Status); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ListEpisodes returns the episodes of a series in order
+func ListEpisodes(ctx context.Context, q database.Querier, seriesID uuid.UUID) ([]models.Episode, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+episodeColumns+`
+		FROM series_episodes e
+		JOIN videos v ON v.id = e.video_id
+		WHERE e.series_id = $1
+		ORDER BY e.season_number, e.episode_number
+	`, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	episodes := []models.Episode{}
+	for rows.Next() {
+		e, err := scanEpisode(rows)
+		if err != nil {
+			return nil, err
+		}
+		episodes = append(episodes, *e)
+	}
+	return episodes, rows.Err()
+}
+
+// SetEpisodes replaces the episodes of a series, which must be locked by the caller's
+// transaction. Each video must belong to the series' organization and may be an episode of
+// no other series.
+func SetEpisodes(ctx context.Context, tx *sql.Tx, series *models.Series, episodes []models.Episode) error {
+	ids := make([]uuid.UUID, len(episodes))
+	for i, e := range episodes {
+		ids[i] = e.VideoID
+	}
+
+	found, err := queries.New(tx).CountVideosInOrganization(ctx, queries.CountVideosInOrganizationParams{
+		Ids: ids, OrganizationID: series.OrganizationID,
+	})
+	if err != nil {
+		return err
+	}
+	if found != int64(len(ids)) {
+		return fmt.Errorf("%w: videos not found in the organization", ErrInvalidEpisodes)
+	}
+	var taken uuid.UUID
+	err = tx.QueryRowContext(ctx, `SELECT video_id FROM series_episodes WHERE video_id = ANY($1) AND series_id <> $2 LIMIT 1`,
+		pq.Array(ids), series.ID).Scan(&taken)
+	if err == nil {
+		return fmt.Errorf("%w: video %s is an episode of another series", ErrInvalidEpisodes, taken)
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM series_episodes WHERE series_id = $1`, series.ID); err != nil {
+		return err
+	}
+	for _, e := range episodes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO series_episodes (series_id, video_id, organization_id, season_number, episode_number)
+			VALUES ($1, $2, $3, $4, $5)
+		`, series.ID, e.VideoID, series.OrganizationID, e.SeasonNumber, e.EpisodeNumber); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VideoEpisode returns the series a video is an episode of with its place in it, or nils
+// when it is in none
+func VideoEpisode(ctx context.Context, q database.Querier, videoID uuid.UUID) (*models.Series, *models.Episode, error) {
+	var seriesID uuid.UUID
+	err := q.QueryRowContext(ctx, `SELECT series_id FROM series_episodes WHERE video_id = $1`, videoID).Scan(&seriesID)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	series, err := ScanSeries(q.QueryRowContext(ctx, `SELECT `+SeriesColumns+` FROM series WHERE id = $1`, seriesID))
+	if err != nil {
+		return nil, nil, err
+	}
+	episode, err := scanEpisode(q.QueryRowContext(ctx, `
+		SELECT `+episodeColumns+`
+		FROM series_episodes e
+		JOIN videos v ON v.id = e.video_id
+		WHERE e.video_id = $1
+	`, videoID))
+	if err != nil {
+		return nil, nil, err
+	}
+	return series, episode, nil
+}
+
+// NextEpisode returns the episode after a video's in its series, skipping episodes that
+// cannot be played yet, were taken down or wait for approval, or nil at the end of the series
+func NextEpisode(ctx context.Context, q database.Querier, videoID uuid.UUID) (*models.Episode, error) {
+	e, err := scanEpisode(q.QueryRowContext(ctx, `
+		SELECT `+episodeColumns+`
+		FROM series_episodes cur
+		JOIN series_episodes e ON e.series_id = cur.series_id
+			AND (e.season_number, e.episode_number) > (cur.season_number, cur.episode_number)
+		JOIN videos v ON v.id = e.video_id
+		WHERE cur.video_id = $1 AND v.status = ANY($2) AND v.moderation_status = $3 AND v.review_status = $4
+			AND COALESCE(v.source_key, '') <> ''
+		ORDER BY e.season_number, e.episode_number
+		LIMIT 1
+	`, videoID, pq.Array(models.PlayableVideoStatuses), models.ModerationActive, models.ReviewApproved))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return e, err
+}
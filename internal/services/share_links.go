@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+)
+
+// ErrShareLinkUnavailable is returned for share links that were revoked, have expired or have
+// used up their views
+var ErrShareLinkUnavailable = errors.New("share link is no longer available")
+
+// ShareLinkColumns is the column list matching ScanShareLink
+const ShareLinkColumns = `id, video_id, organization_id, created_by, slug, expires_at, max_views, view_count,
+	allow_download, allow_comments, revoked_at, last_viewed_at, created_at, updated_at`
+
+// ScanShareLink scans a row selected with ShareLinkColumns
+func ScanShareLink(row interface{ Scan(...interface{}) error }) (*models.ShareLink, error) {
+	var l models.ShareLink
+	err := row.Scan(&l.ID, &l.VideoID, &l.OrganizationID, &l.CreatedBy, &l.Slug, &l.ExpiresAt, &l.MaxViews, &l.ViewCount,
+		&l.AllowDownload, &l.AllowComments, &l.RevokedAt, &l.LastViewedAt, &l.CreatedAt, &l.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// NewShareSlug returns a random slug for a share link, 128 bits in URL-safe base64
+func NewShareSlug() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ActiveShareLink looks up a share link by its slug. It returns sql.ErrNoRows for unknown
+// slugs and ErrShareLinkUnavailable for links that no longer work.
+func ActiveShareLink(ctx context.Context, q database.Querier, slug string) (*models.ShareLink, error) {
+	link, err := ScanShareLink(q.QueryRowContext(ctx, `SELECT `+ShareLinkColumns+` FROM share_links WHERE slug = $1`, slug))
+	if err != nil {
+		return nil, err
+	}
+	if !link.Active(time.Now()) {
+		return nil, ErrShareLinkUnavailable
+	}
+	return link, nil
+}
+
+// ViewShareLink counts a view of a share link and returns the link. The check and the count are
+// one statement, so concurrent viewers cannot exceed the link's views. It returns the errors of
+// ActiveShareLink.
+func ViewShareLink(ctx context.Context, q database.Querier, slug string) (*models.ShareLink, error) {
+	link, err := ScanShareLink(q.QueryRowContext(ctx, `
+		UPDATE share_links SET view_count = view_count + 1, last_viewed_at = NOW()
+		WHERE slug = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+			AND (max_views IS NULL OR view_count < max_views)
+		RETURNING `+ShareLinkColumns, slug))
+	if err != sql.ErrNoRows {
+		return link, err
+	}
+	// Tell unknown slugs from links that stopped working
+	if _, err := ActiveShareLink(ctx, q, slug); err != nil {
+		return nil, err
+	}
+	return nil, ErrShareLinkUnavailable
+}
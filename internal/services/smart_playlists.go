@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrInvalidPlaylistCriteria is returned for criteria a smart playlist cannot use
+var ErrInvalidPlaylistCriteria = errors.New("invalid playlist criteria")
+
+// SmartPlaylistColumns is the column list of smart_playlists matching ScanSmartPlaylist
+const SmartPlaylistColumns = `id, organization_id, title, description, criteria, sort, max_items, created_by, version, created_at, updated_at`
+
+// ScanSmartPlaylist scans a row selected with SmartPlaylistColumns
+func ScanSmartPlaylist(row interface{ Scan(...interface{}) error }) (*models.SmartPlaylist, error) {
+	var p models.SmartPlaylist
+	err := row.Scan(&p.ID, &p.OrganizationID, &p.Title, &p.Description, &p.Criteria, &p.Sort, &p.MaxItems, &p.CreatedBy,
+		&p.Version, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// playlistOrders are the ORDER BY clauses of the sorts of smart playlists
+var playlistOrders = map[string]string{
+	models.PlaylistSortNewest: "created_at DESC, id",
+	models.PlaylistSortOldest: "created_at, id",
+	models.PlaylistSortTitle:  "lower(title), id",
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// CheckPlaylistCriteria normalizes the criteria of a smart playlist of an organization and
+// checks them against its projects and custom fields
+func CheckPlaylistCriteria(ctx context.Context, q database.Querier, orgID uuid.UUID, c *models.PlaylistCriteria) error {
+	c.Query = strings.TrimSpace(c.Query)
+	c.Tags = NormalizeTags(c.Tags)
+	switch c.Visibility {
+	case "", models.VideoVisibilityPublic, models.VideoVisibilityUnlisted, models.VideoVisibilityPrivate:
+	default:
+		return fmt.Errorf("%w: visibility must be public, unlisted or private", ErrInvalidPlaylistCriteria)
+	}
+	if c.CreatedAfter != nil && c.CreatedBefore != nil && !c.CreatedAfter.Before(*c.CreatedBefore) {
+		return fmt.Errorf("%w: created_after must be before created_before", ErrInvalidPlaylistCriteria)
+	}
+
+	if err := CheckSeriesProject(ctx, q, orgID, c.ProjectID); errors.Is(err, ErrSeriesProject) {
+		return fmt.Errorf("%w: %v", ErrInvalidPlaylistCriteria, err)
+	} else if err != nil {
+		return err
+	}
+	if len(c.Fields)+len(c.FieldsMin)+len(c.FieldsMax) == 0 {
+		return nil
+	}
+	fields, err := CustomFields(ctx, q, orgID)
+	if err != nil {
+		return err
+	}
+	var args []interface{}
+	if _, err := CustomFieldFilters(fields, c.Fields, c.FieldsMin, c.FieldsMax, &args); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPlaylistCriteria, err)
+	}
+	return nil
+}
+
+// playlistConditions turns criteria into conditions on videos, appending their arguments to
+// args
+func playlistConditions(fields map[string]models.CustomField, c models.PlaylistCriteria, args *[]interface{}) ([]string, error) {
+	var conditions []string
+	add := func(condition string, value interface{}) {
+		*args = append(*args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(*args)))
+	}
+	if c.Query != "" {
+		add(`(title ILIKE $%[1]d OR description ILIKE $%[1]d)`, "%"+likeEscaper.Replace(c.Query)+"%")
+	}
+	if c.ProjectID != nil {
+		add(`project_id = $%d`, *c.ProjectID)
+	}
+	if len(c.Tags) > 0 {
+		add(`tags @> $%d::text[]`, pq.Array(c.Tags))
+	}
+	if c.Visibility != "" {
+		add(`visibility = $%d`, c.Visibility)
+	}
+	if c.CreatedAfter != nil {
+		add(`created_at >= $%d`, *c.CreatedAfter)
+	}
+	if c.CreatedBefore != nil {
+		add(`created_at < $%d`, *c.CreatedBefore)
+	}
+	fieldConditions, err := CustomFieldFilters(fields, c.Fields, c.FieldsMin, c.FieldsMax, args)
+	if err != nil {
+		return nil, err
+	}
+	return append(conditions, fieldConditions...), nil
+}
+
+// SmartPlaylists computes the videos of smart playlists and renders their feeds. Which videos
+// a playlist lists is cached per instance for FEEDS_PLAYLIST_CACHE_TTL; the videos themselves
+// are read, and checked to still be listable, on every read.
+type SmartPlaylists struct {
+	db        *sql.DB
+	config    config.Feeds
+	publicURL string
+
+	mu    sync.Mutex
+	cache map[playlistKey]cachedPlaylist
+}
+
+// playlistKey names the videos of a version of a playlist, those of its feeds apart, so an
+// update of the playlist is seen at once
+type playlistKey struct {
+	id      uuid.UUID
+	version int64
+	feed    bool
+}
+
+type cachedPlaylist struct {
+	ids     []uuid.UUID
+	expires time.Time
+}
+
+// NewSmartPlaylists creates the smart playlist service; db serves feeds outside any tenant
+func NewSmartPlaylists(db *sql.DB, cfg config.Feeds, publicURL string) *SmartPlaylists {
+	return &SmartPlaylists{db: db, config: cfg, publicURL: publicURL, cache: make(map[playlistKey]cachedPlaylist)}
+}
+
+// Videos returns the videos of a playlist that have a source to play, as q sees them
+func (s *SmartPlaylists) Videos(ctx context.Context, q database.Querier, p *models.SmartPlaylist) ([]models.Video, error) {
+	listed, err := s.videos(ctx, q, p, false)
+	if err != nil {
+		return nil, err
+	}
+	videos := make([]models.Video, len(listed))
+	for i, v := range listed {
+		videos[i] = *v
+	}
+	return videos, nil
+}
+
+// RenderFeed renders a feed of a smart playlist with links under PUBLIC_URL, or under base
+// when it is not set. Like project feeds it lists only public, playable videos that need no
+// entitlement, and playlists without such videos have no feed.
+func (s *SmartPlaylists) RenderFeed(ctx context.Context, playlistID uuid.UUID, format, base string) ([]byte, error) {
+	if s.publicURL != "" {
+		base = s.publicURL
+	}
+	p, err := ScanSmartPlaylist(s.db.QueryRowContext(ctx, `
+		SELECT `+SmartPlaylistColumns+` FROM smart_playlists WHERE id = $1
+	`, playlistID))
+	if err == sql.ErrNoRows {
+		return nil, ErrFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	organization, err := queries.New(s.db).GetOrganizationName(ctx, p.OrganizationID)
+	if err == sql.ErrNoRows {
+		return nil, ErrFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	videos, err := s.videos(ctx, s.db, p, true)
+	if err != nil {
+		return nil, err
+	}
+	project := feedProject{ID: p.ID, Name: p.Title, Description: p.Description, Organization: organization}
+	return renderFeed(format, base, base+PlaylistFeedPath(p.ID, format), project, videos)
+}
+
+// videos reads the listed videos of a playlist in its order. Feeds list only the videos
+// project feeds would.
+func (s *SmartPlaylists) videos(ctx context.Context, q database.Querier, p *models.SmartPlaylist, feed bool) ([]*models.Video, error) {
+	listable := `organization_id = $1 AND status = ANY($2)`
+	args := []interface{}{p.OrganizationID, pq.Array(models.PlayableVideoStatuses)}
+	if feed {
+		listable += ` AND visibility = $3 AND moderation_status = $4 AND review_status = $5 AND NOT requires_entitlement
+			AND COALESCE(source_key, '') <> ''`
+		args = append(args, models.VideoVisibilityPublic, models.ModerationActive, models.ReviewApproved)
+	}
+
+	ids, err := s.videoIDs(ctx, q, p, feed, listable, args)
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	n := strconv.Itoa(len(args) + 1)
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+VideoColumns+` FROM videos
+		WHERE `+listable+` AND id = ANY($`+n+`::uuid[])
+		ORDER BY array_position($`+n+`::uuid[], id)
+	`, append(args, pq.Array(ids))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var videos []*models.Video
+	for rows.Next() {
+		v, err := ScanVideo(rows)
+		if err != nil {
+			return nil, err
+		}
+		videos = append(videos, v)
+	}
+	return videos, rows.Err()
+}
+
+// videoIDs evaluates the criteria of a playlist on the listable videos, or returns the IDs
+// cached from the last evaluation
+func (s *SmartPlaylists) videoIDs(ctx context.Context, q database.Querier, p *models.SmartPlaylist, feed bool, listable string, args []interface{}) ([]uuid.UUID, error) {
+	key := playlistKey{id: p.ID, version: p.Version, feed: feed}
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.ids, nil
+	}
+
+	var fields map[string]models.CustomField
+	if len(p.Criteria.Fields)+len(p.Criteria.FieldsMin)+len(p.Criteria.FieldsMax) > 0 {
+		var err error
+		if fields, err = CustomFields(ctx, q, p.OrganizationID); err != nil {
+			return nil, err
+		}
+	}
+	conditions, err := playlistConditions(fields, p.Criteria, &args)
+	if errors.Is(err, ErrInvalidCustomField) {
+		// A field the criteria use was deleted or changed since, so no video matches them
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	limit := p.MaxItems
+	if feed && s.config.MaxItems < limit {
+		limit = s.config.MaxItems
+	}
+	order, ok := playlistOrders[p.Sort]
+	if !ok {
+		order = playlistOrders[models.PlaylistSortNewest]
+	}
+
+	where := listable
+	if len(conditions) > 0 {
+		where += " AND " + strings.Join(conditions, " AND ")
+	}
+	rows, err := q.QueryContext(ctx, `SELECT id FROM videos WHERE `+where+` ORDER BY `+order+
+		` LIMIT $`+strconv.Itoa(len(args)+1), append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if s.config.PlaylistCacheTTL > 0 {
+		now := time.Now()
+		s.mu.Lock()
+		for k, c := range s.cache {
+			if now.After(c.expires) {
+				delete(s.cache, k)
+			}
+		}
+		s.cache[key] = cachedPlaylist{ids: ids, expires: now.Add(s.config.PlaylistCacheTTL)}
+		s.mu.Unlock()
+	}
+	return ids, nil
+}
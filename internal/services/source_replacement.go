@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// ErrSourceChanged is returned by ReplaceSource when the video's source changed after the
+// replacement upload started, typically because another replacement completed first
+var ErrSourceChanged = errors.New("video source changed since the replacement started")
+
+// SourceReplacement is a completed upload that becomes a video's source
+type SourceReplacement struct {
+	VideoID     uuid.UUID
+	OldKey      string
+	NewKey      string
+	ContentType string
+	SizeBytes   int64
+	// KeepFor is how long the old source and its renditions are kept as a version the video
+	// can be rolled back to; zero deletes them
+	KeepFor    time.Duration
+	ReplacedBy *uuid.UUID
+}
+
+// ReplaceSource points a video at a new source within tx. The video keeps its ID, so playback
+// URLs and everything recorded against it stay valid; its hash is cleared for the hasher to
+// recompute and its source revision goes up. Unless the old source is kept as a version, the
+// returned keys are the objects no longer referenced, the old source unless duplicates share it
+// and the renditions derived from it, which the caller deletes from storage once tx has
+// committed.
+func ReplaceSource(ctx context.Context, tx *sql.Tx, r SourceReplacement) (*models.Video, []string, error) {
+	// The new source waits for its transcoder like a first one
+	err := TransitionVideo(ctx, tx, r.VideoID, models.VideoStatusQueued, "source replaced")
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrSourceChanged
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keep := r.KeepFor > 0 && r.OldKey != ""
+	if keep {
+		if err := keepSourceVersion(ctx, tx, r.VideoID, r.ReplacedBy, r.KeepFor); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	qtx := queries.New(tx)
+	video, err := VideoModel(qtx.ReplaceVideoSource(ctx, queries.ReplaceVideoSourceParams{
+		NewKey: r.NewKey, ContentType: r.ContentType, SizeBytes: r.SizeBytes, ID: r.VideoID, OldKey: r.OldKey,
+	}))
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrSourceChanged
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Copies of the old content are no longer duplicates of this video
+	if err := qtx.ClearDuplicatesOf(ctx, r.VideoID); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO storage_objects (organization_id, video_id, object_key, kind, size_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (object_key) DO NOTHING
+	`, video.OrganizationID, r.VideoID, r.NewKey, models.ObjectKindSource, r.SizeBytes); err != nil {
+		return nil, nil, err
+	}
+
+	var obsolete []string
+	if r.OldKey != "" {
+		sharer, err := qtx.FirstVideoWithSource(ctx, r.OldKey)
+		switch {
+		case err == sql.ErrNoRows:
+			if keep {
+				// The version keeps the object under the video
+				break
+			}
+			if _, err := tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = $1`, r.OldKey); err != nil {
+				return nil, nil, err
+			}
+			obsolete = append(obsolete, r.OldKey)
+		case err != nil:
+			return nil, nil, err
+		default:
+			// Deduplicated videos still play the old source, so it stays and moves to one of them
+			if _, err := tx.ExecContext(ctx, `UPDATE storage_objects SET video_id = $1 WHERE object_key = $2`,
+				sharer, r.OldKey); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if keep {
+		return video, obsolete, nil
+	}
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM storage_objects o
+		WHERE video_id = $1 AND kind = $2
+			AND NOT EXISTS (SELECT 1 FROM video_source_versions s WHERE o.object_key = ANY(s.object_keys))
+		RETURNING object_key
+	`, r.VideoID, models.ObjectKindRendition)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, nil, err
+		}
+		obsolete = append(obsolete, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return video, obsolete, nil
+}
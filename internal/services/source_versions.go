@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+	"openvdo/internal/storage"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrSourceVersionNotFound is returned when rolling back to a revision that was not kept or
+	// has expired
+	ErrSourceVersionNotFound = errors.New("source version not found")
+	// ErrSourceVersionArchived is returned when rolling back to a version whose objects are in
+	// cold storage; they are restored through the video's storage first
+	ErrSourceVersionArchived = errors.New("source version is archived")
+)
+
+// SourceVersionColumns is the column list of video_source_versions matching ScanSourceVersion,
+// with the tier status of the version's source
+const SourceVersionColumns = `id, video_id, revision, source_key, COALESCE(content_type, ''), COALESCE(size_bytes, 0),
+	sha256, color, renditions, replaced_by,
+	COALESCE((SELECT tier_status FROM storage_objects WHERE object_key = video_source_versions.source_key), 'hot'),
+	expires_at, created_at`
+
+// ScanSourceVersion scans a row selected with SourceVersionColumns
+func ScanSourceVersion(row interface{ Scan(...interface{}) error }) (*models.SourceVersion, error) {
+	var v models.SourceVersion
+	var renditions []byte
+	err := row.Scan(&v.ID, &v.VideoID, &v.Revision, &v.SourceKey, &v.ContentType, &v.SizeBytes, &v.SHA256, &v.Color,
+		&renditions, &v.ReplacedBy, &v.TierStatus, &v.ExpiresAt, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(renditions, &v.Renditions); err != nil {
+		return nil, fmt.Errorf("invalid renditions of source version %s: %w", v.ID, err)
+	}
+	return &v, nil
+}
+
+// keepSourceVersion records a video's current source as a version kept for keepFor, with its
+// renditions and the rendition objects no other version claims. It does nothing for videos
+// without a source.
+func keepSourceVersion(ctx context.Context, tx *sql.Tx, videoID uuid.UUID, userID *uuid.UUID, keepFor time.Duration) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO video_source_versions (video_id, organization_id, revision, source_key, content_type, size_bytes,
+			sha256, color, renditions, object_keys, replaced_by, expires_at)
+		SELECT v.id, v.organization_id, v.source_revision, v.source_key, v.content_type, v.size_bytes, v.sha256, v.color,
+			COALESCE((SELECT jsonb_agg(to_jsonb(r) ORDER BY r.created_at) FROM video_renditions r WHERE r.video_id = v.id), '[]'),
+			ARRAY(
+				SELECT o.object_key FROM storage_objects o
+				WHERE o.video_id = v.id AND o.kind = $2
+					AND NOT EXISTS (SELECT 1 FROM video_source_versions s WHERE o.object_key = ANY(s.object_keys))
+			),
+			$3, NOW() + make_interval(secs => $4)
+		FROM videos v
+		WHERE v.id = $1 AND COALESCE(v.source_key, '') <> ''
+		ON CONFLICT (video_id, revision) DO NOTHING
+	`, videoID, models.ObjectKindRendition, userID, keepFor.Seconds())
+	return err
+}
+
+// ListSourceVersions returns the kept source versions of a video, newest first
+func ListSourceVersions(ctx context.Context, q database.Querier, videoID uuid.UUID) ([]models.SourceVersion, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+SourceVersionColumns+` FROM video_source_versions
+		WHERE video_id = $1
+		ORDER BY revision DESC
+	`, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []models.SourceVersion{}
+	for rows.Next() {
+		v, err := ScanSourceVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *v)
+	}
+	return versions, rows.Err()
+}
+
+// RollbackSource makes a kept version the source of a video again within tx. The version's
+// renditions replace the video's, so it is ready without transcoding, and its source revision
+// goes up like on a replacement. The source it had is kept as a version for keepFor, so a
+// rollback can be undone. A missing video returns sql.ErrNoRows.
+func RollbackSource(ctx context.Context, tx *sql.Tx, videoID uuid.UUID, revision int, userID *uuid.UUID, keepFor time.Duration) (*models.Video, error) {
+	qtx := queries.New(tx)
+	locked, err := qtx.LockVideoStatus(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	status := locked.Status
+	if !CanTransitionVideo(status, models.VideoStatusReady) && status != models.VideoStatusReady {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidTransition, status, models.VideoStatusReady)
+	}
+
+	version, err := ScanSourceVersion(tx.QueryRowContext(ctx, `
+		SELECT `+SourceVersionColumns+` FROM video_source_versions
+		WHERE video_id = $1 AND revision = $2
+	`, videoID, revision))
+	if err == sql.ErrNoRows {
+		return nil, ErrSourceVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var archived bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM storage_objects o, video_source_versions s
+			WHERE s.id = $1 AND (o.object_key = s.source_key OR o.object_key = ANY(s.object_keys))
+				AND o.tier_status IN ($2, $3)
+		)
+	`, version.ID, models.TierStatusArchived, models.TierStatusRestoring).Scan(&archived); err != nil {
+		return nil, err
+	}
+	if archived {
+		return nil, ErrSourceVersionArchived
+	}
+
+	if err := keepSourceVersion(ctx, tx, videoID, userID, keepFor); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM video_renditions WHERE video_id = $1`, videoID); err != nil {
+		return nil, err
+	}
+	// Renditions of encoders deleted since keep their URLs but no longer name the encoder
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO video_renditions (video_id, organization_id, integration_id, external_id, url, content_type,
+			width, height, bitrate_kbps, codec, dynamic_range, created_at)
+		SELECT v.id, v.organization_id, (SELECT e.id FROM encoder_integrations e WHERE e.id = r.integration_id),
+			r.external_id, r.url, r.content_type, r.width, r.height, r.bitrate_kbps, r.codec, r.dynamic_range, r.created_at
+		FROM video_source_versions s, jsonb_populate_recordset(NULL::video_renditions, s.renditions) r, videos v
+		WHERE s.id = $2 AND v.id = $1
+		ON CONFLICT (video_id, url) DO NOTHING
+	`, videoID, version.ID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM video_source_versions WHERE id = $1`, version.ID); err != nil {
+		return nil, err
+	}
+
+	if err := TransitionVideo(ctx, tx, videoID, models.VideoStatusReady,
+		fmt.Sprintf("rolled back to source revision %d", revision)); err != nil {
+		return nil, err
+	}
+	video, err := VideoModel(qtx.RollbackVideoSource(ctx, queries.RollbackVideoSourceParams{
+		SourceKey: version.SourceKey, ContentType: version.ContentType, SizeBytes: version.SizeBytes,
+		SHA256: version.SHA256, Color: version.Color, ID: videoID,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	// Copies of the replaced content are no longer duplicates of this video
+	if err := qtx.ClearDuplicatesOf(ctx, videoID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO storage_objects (organization_id, video_id, object_key, kind, size_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (object_key) DO NOTHING
+	`, video.OrganizationID, videoID, version.SourceKey, models.ObjectKindSource, version.SizeBytes); err != nil {
+		return nil, err
+	}
+	if err := MarkAccessed(ctx, tx, version.SourceKey); err != nil {
+		return nil, err
+	}
+	return video, nil
+}
+
+// sourceVersionBatchSize bounds the versions DeleteExpiredSourceVersions selects per query
+const sourceVersionBatchSize = 100
+
+// DeleteExpiredSourceVersions deletes the source versions past their retention, and beyond
+// the newest max of each video when max is positive, with their objects. A source another
+// video or version still uses stays in storage.
+func DeleteExpiredSourceVersions(ctx context.Context, db *sql.DB, store storage.Storage, max int) (int, error) {
+	deleted := 0
+	for {
+		rows, err := db.QueryContext(ctx, `
+			SELECT id FROM (
+				SELECT id, expires_at, created_at,
+					ROW_NUMBER() OVER (PARTITION BY video_id ORDER BY revision DESC) AS newest
+				FROM video_source_versions
+			) v
+			WHERE expires_at < NOW() OR ($2 > 0 AND newest > $2)
+			ORDER BY created_at
+			LIMIT $1`, sourceVersionBatchSize, max)
+		if err != nil {
+			return deleted, err
+		}
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return deleted, err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(ids) == 0 {
+			return deleted, err
+		}
+
+		for _, id := range ids {
+			keys, err := deleteSourceVersion(ctx, db, id)
+			if err != nil {
+				return deleted, err
+			}
+			// The database no longer references the objects; a failed delete only leaves an orphan behind
+			for _, key := range keys {
+				if err := store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+					return deleted, fmt.Errorf("failed to delete %s: %w", key, err)
+				}
+			}
+			deleted++
+		}
+	}
+}
+
+// deleteSourceVersion deletes a version and the storage objects only it uses, returning their
+// keys
+func deleteSourceVersion(ctx context.Context, db *sql.DB, id uuid.UUID) ([]string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var sourceKey string
+	var keys []string
+	err = tx.QueryRowContext(ctx, `DELETE FROM video_source_versions WHERE id = $1 RETURNING source_key, object_keys`,
+		id).Scan(&sourceKey, pq.Array(&keys))
+	if err == sql.ErrNoRows {
+		// Rolled back to or deleted with its video meanwhile
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := queries.New(tx).SourceKeyInUse(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		keys = append(keys, sourceKey)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM storage_objects WHERE object_key = ANY($1)`, pq.Array(keys)); err != nil {
+		return nil, err
+	}
+	return keys, tx.Commit()
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"time"
+
+	"openvdo/internal/analytics"
+
+	"github.com/lib/pq"
+)
+
+// RecomputeTrending replaces the trending scores of videos with ones from the plays of the last
+// day and week: plays of the last day count fully and those of the week by their daily
+// average, so videos rise quickly and fade over the week. Videos without plays in the week
+// drop out. It returns how many videos have a score.
+func RecomputeTrending(ctx context.Context, db *sql.DB, store analytics.Store) (int, error) {
+	now := time.Now()
+	day, err := store.Plays(ctx, now.Add(-24*time.Hour), now)
+	if err != nil {
+		return 0, err
+	}
+	week, err := store.Plays(ctx, now.Add(-7*24*time.Hour), now)
+	if err != nil {
+		return 0, err
+	}
+
+	daily := make(map[string]float64, len(day))
+	for _, p := range day {
+		daily[p.VideoID.String()] = p.Sessions
+	}
+	ids := make([]string, 0, len(week))
+	plays24h := make([]int64, 0, len(week))
+	plays7d := make([]int64, 0, len(week))
+	scores := make([]float64, 0, len(week))
+	for _, p := range week {
+		id := p.VideoID.String()
+		ids = append(ids, id)
+		plays24h = append(plays24h, int64(math.Round(daily[id])))
+		plays7d = append(plays7d, int64(math.Round(p.Sessions)))
+		scores = append(scores, daily[id]+p.Sessions/7)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM video_trending`); err != nil {
+		return 0, err
+	}
+	// Plays of videos deleted since, or stored in another region's database, are left out by the join
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO video_trending (video_id, organization_id, plays_24h, plays_7d, score, computed_at)
+		SELECT v.id, v.organization_id, t.plays_24h, t.plays_7d, t.score, $5
+		FROM unnest($1::uuid[], $2::bigint[], $3::bigint[], $4::float8[]) AS t(video_id, plays_24h, plays_7d, score)
+		JOIN videos v ON v.id = t.video_id
+	`, pq.Array(ids), pq.Array(plays24h), pq.Array(plays7d), pq.Array(scores), now)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
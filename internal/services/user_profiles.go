@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+)
+
+var (
+	// ErrInvalidLocale is returned for locales that are not BCP 47 language tags
+	ErrInvalidLocale = errors.New("locale must be a BCP 47 language tag such as en or pt-BR")
+	// ErrInvalidTimezone is returned for names that are not IANA time zones
+	ErrInvalidTimezone = errors.New("timezone must be an IANA time zone such as Europe/Paris")
+)
+
+// UserProfileModel converts a profile row, passing its error through
+func UserProfileModel(p queries.GetUserProfileRow, err error) (*models.UserProfile, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &models.UserProfile{
+		ID:       p.ID,
+		Email:    p.Email,
+		Name:     p.Name,
+		Avatar:   p.Avatar,
+		Locale:   p.Locale,
+		Timezone: p.Timezone,
+		Playback: models.PlaybackPreferences{
+			Autoplay:       p.PlaybackAutoplay,
+			DefaultQuality: p.PlaybackQuality,
+			CaptionsOn:     p.PlaybackCaptions,
+		},
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}, nil
+}
+
+// CanonicalLocale returns the canonical form of a BCP 47 language tag, e.g. pt-BR for pt_br
+func CanonicalLocale(locale string) (string, error) {
+	tag, err := language.Parse(locale)
+	if err != nil || tag == language.Und {
+		return "", ErrInvalidLocale
+	}
+	return tag.String(), nil
+}
+
+// CheckTimezone checks that name is an IANA time zone. Local is refused, since it depends on
+// the server.
+func CheckTimezone(name string) error {
+	if name == "" || name == "Local" {
+		return ErrInvalidTimezone
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return ErrInvalidTimezone
+	}
+	return nil
+}
+
+// UserPlaybackPreferences returns how players start videos for a user, or the defaults for
+// users that no longer exist
+func UserPlaybackPreferences(ctx context.Context, q database.Querier, userID uuid.UUID) (models.PlaybackPreferences, error) {
+	row, err := queries.New(q).GetPlaybackPreferences(ctx, userID)
+	if err == sql.ErrNoRows {
+		return models.PlaybackPreferences{DefaultQuality: models.PlaybackQualityAuto}, nil
+	}
+	return models.PlaybackPreferences{
+		Autoplay:       row.PlaybackAutoplay,
+		DefaultQuality: row.PlaybackQuality,
+		CaptionsOn:     row.PlaybackCaptions,
+	}, err
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidTransition is returned for status changes the video lifecycle does not allow
+var ErrInvalidTransition = errors.New("invalid video status transition")
+
+// videoTransitions are the statuses a video may move to from each status. A replaced source
+// queues the video again, a retried import has it uploading again, rolling back to an earlier
+// source makes it ready with that source's renditions, and any video but a deleted one may be
+// deleted.
+var videoTransitions = map[string][]string{
+	models.VideoStatusUploading:  {models.VideoStatusQueued, models.VideoStatusFailed, models.VideoStatusDeleted},
+	models.VideoStatusQueued:     {models.VideoStatusProcessing, models.VideoStatusReady, models.VideoStatusDeleted},
+	models.VideoStatusProcessing: {models.VideoStatusReady, models.VideoStatusQueued, models.VideoStatusDeleted},
+	models.VideoStatusReady:      {models.VideoStatusQueued, models.VideoStatusDeleted},
+	models.VideoStatusFailed:     {models.VideoStatusUploading, models.VideoStatusQueued, models.VideoStatusReady, models.VideoStatusDeleted},
+	models.VideoStatusDeleted:    {},
+}
+
+// CanTransitionVideo reports whether a video may move from one status to another
+func CanTransitionVideo(from, to string) bool {
+	return slices.Contains(videoTransitions[from], to)
+}
+
+// TransitionVideo moves a video to a status and records the change with its reason, which
+// may be empty. q should be a transaction, as the video's row is locked until it ends. Moving
+// a video to the status it is in does nothing; a move the lifecycle does not allow returns
+// ErrInvalidTransition, and a missing video sql.ErrNoRows.
+func TransitionVideo(ctx context.Context, q database.Querier, videoID uuid.UUID, to, reason string) error {
+	qv := queries.New(q)
+	locked, err := qv.LockVideoStatus(ctx, videoID)
+	if err != nil {
+		return err
+	}
+	from, orgID := locked.Status, locked.OrganizationID
+	if from == to {
+		return nil
+	}
+	if !CanTransitionVideo(from, to) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	if err := qv.SetVideoStatus(ctx, queries.SetVideoStatusParams{ID: videoID, Status: to}); err != nil {
+		return err
+	}
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO video_status_changes (video_id, organization_id, from_status, to_status, reason)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+	`, videoID, orgID, from, to, reason)
+	return err
+}
+
+// VideoStatusHistory returns the status changes of a video, oldest first
+func VideoStatusHistory(ctx context.Context, q database.Querier, videoID uuid.UUID) ([]models.VideoStatusChange, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT from_status, to_status, COALESCE(reason, ''), created_at FROM video_status_changes
+		WHERE video_id = $1
+		ORDER BY id
+	`, videoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.VideoStatusChange{}
+	for rows.Next() {
+		var change models.VideoStatusChange
+		if err := rows.Scan(&change.From, &change.To, &change.Reason, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
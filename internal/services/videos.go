@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"openvdo/internal/database"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+
+	"github.com/google/uuid"
+)
+
+// VideoColumns is the column list of videos matching ScanVideo, for the listings that build
+// their filters at run time. Fixed statements are generated in internal/queries.
+var VideoColumns = queries.Columns[queries.Video]()
+
+// ScanVideo scans a row selected with VideoColumns
+func ScanVideo(row interface{ Scan(...interface{}) error }) (*models.Video, error) {
+	return VideoModel(queries.ScanRow[queries.Video](row))
+}
+
+// VideoModel converts a video row read by a generated query, passing its error through
+func VideoModel(v queries.Video, err error) (*models.Video, error) {
+	if err != nil {
+		return nil, err
+	}
+	video := &models.Video{
+		ID:                  v.ID,
+		OrganizationID:      v.OrganizationID,
+		ProjectID:           v.ProjectID,
+		ExternalID:          v.ExternalID,
+		Title:               v.Title,
+		Description:         valueOf(v.Description),
+		Status:              v.Status,
+		StatusChangedAt:     v.StatusChangedAt,
+		Visibility:          v.Visibility,
+		RequiresEntitlement: v.RequiresEntitlement,
+		DownloadsEnabled:    v.DownloadsEnabled,
+		ModerationStatus:    v.ModerationStatus,
+		ReviewStatus:        v.ReviewStatus,
+		Tags:                v.Tags,
+		CustomFields:        v.CustomFields,
+		Thumbnail:           v.Thumbnail,
+		Chapters:            v.Chapters,
+		AdBreaks:            v.AdBreaks,
+		SourceKey:           valueOf(v.SourceKey),
+		ContentType:         valueOf(v.ContentType),
+		SizeBytes:           valueOf(v.SizeBytes),
+		SHA256:              v.SHA256,
+		DuplicateOf:         v.DuplicateOf,
+		SourceRevision:      v.SourceRevision,
+		Color:               v.Color,
+		ReplacedAt:          v.ReplacedAt,
+		CreatedBy:           v.CreatedBy,
+		Version:             v.Version,
+		CreatedAt:           valueOf(v.CreatedAt),
+		UpdatedAt:           valueOf(v.UpdatedAt),
+	}
+	if video.Tags == nil {
+		video.Tags = []string{}
+	}
+	return video, nil
+}
+
+// VideoModels converts the video rows read by a generated query, passing its error through
+func VideoModels(rows []queries.Video, err error) ([]*models.Video, error) {
+	if err != nil {
+		return nil, err
+	}
+	videos := make([]*models.Video, len(rows))
+	for i, row := range rows {
+		videos[i], _ = VideoModel(row, nil)
+	}
+	return videos, nil
+}
+
+// valueOf returns what p points to, or the zero value for nil; nullable columns read by the
+// generated queries map to plain fields of the models this way
+func valueOf[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
+
+// SourceKey builds the storage key for a video's source file
+func SourceKey(orgID, videoID uuid.UUID, filename string) string {
+	return fmt.Sprintf("orgs/%s/videos/%s/source/%s", orgID, videoID, sourceName(filename))
+}
+
+// ReplacementSourceKey builds the storage key for a source uploaded to replace the current
+// one. The upload ID keeps it apart from the current source even when the names match.
+func ReplacementSourceKey(orgID, videoID, uploadID uuid.UUID, filename string) string {
+	return fmt.Sprintf("orgs/%s/videos/%s/source/%s/%s", orgID, videoID, uploadID, sourceName(filename))
+}
+
+func sourceName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if name == "" || name == "." || name == "/" {
+		name = "source"
+	}
+	return name
+}
+
+// NormalizeTags trims and lowercases tags, dropping empty and repeated ones
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// VideoEvent describes an outbox event about a video, carrying the video as its data
+func VideoEvent(eventType string, v *models.Video) outbox.NewEvent {
+	return outbox.NewEvent{OrganizationID: v.OrganizationID, Type: eventType, SubjectID: &v.ID, Data: v}
+}
+
+// RecordVideoEvent writes an outbox event with the video as q sees it, for callers that
+// changed the video without reading it back
+func RecordVideoEvent(ctx context.Context, q database.Querier, eventType string, videoID uuid.UUID) error {
+	v, err := VideoModel(queries.New(q).GetVideo(ctx, videoID))
+	if err != nil {
+		return err
+	}
+	return outbox.Write(ctx, q, VideoEvent(eventType, v))
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/outbox"
+
+	"github.com/google/uuid"
+)
+
+// JobKindWebhookDeliver posts one event to one webhook endpoint
+const JobKindWebhookDeliver = "webhook.deliver"
+
+// WebhookSignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">", keyed
+// with the endpoint's secret
+const WebhookSignatureHeader = "X-OpenVDO-Signature"
+
+// webhookPayload is the payload of a webhook.deliver job. The event is copied in, so
+// deliveries outlive the event's retention in the outbox.
+type webhookPayload struct {
+	EndpointID uuid.UUID       `json:"endpoint_id"`
+	Event      json.RawMessage `json:"event" swaggertype:"object"`
+}
+
+// WebhookDispatcher fans events out to the webhook endpoints of their organization. As an
+// outbox publisher it queues a delivery job per endpoint in the relay's transaction, so a slow
+// or failing endpoint is retried on its own without holding up the outbox or other endpoints.
+type WebhookDispatcher struct {
+	db     *sql.DB
+	client *http.Client
+	config config.Events
+}
+
+// NewWebhookDispatcher creates a dispatcher. db must not carry a tenant context.
+func NewWebhookDispatcher(db *sql.DB, cfg config.Events) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		db:     db,
+		client: NewSafeHTTPClient(cfg.WebhookTimeout, 0, false),
+		config: cfg,
+	}
+}
+
+// NewWebhookSecret returns a random signing secret for an endpoint
+func NewWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// SignWebhook returns the signature header value for a body sent at t
+func SignWebhook(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(webhookMAC(secret, ts, body))
+}
+
+func webhookMAC(secret, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// ErrInvalidWebhookSignature is returned for webhooks whose signature does not match their
+// body, or that were signed too long ago to rule out a replay
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// VerifyWebhook checks a signature header value as SignWebhook writes it, for a body received
+// at now. Any of several v1 signatures may match, so senders can sign with an old and a new
+// secret while rotating.
+func VerifyWebhook(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrInvalidWebhookSignature
+	}
+	if now.Sub(time.Unix(unix, 0)).Abs() > tolerance {
+		return ErrInvalidWebhookSignature
+	}
+
+	expected := webhookMAC(secret, ts, body)
+	for _, signature := range signatures {
+		if mac, err := hex.DecodeString(signature); err == nil && hmac.Equal(mac, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
+
+// Name implements outbox.Publisher
+func (d *WebhookDispatcher) Name() string {
+	return "webhooks"
+}
+
+// Publish implements outbox.Publisher. Endpoints with no event types receive every event.
+func (d *WebhookDispatcher) Publish(ctx context.Context, tx *sql.Tx, e *outbox.Event) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM webhook_endpoints
+		WHERE organization_id = $1 AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+	`, e.OrganizationID, e.Type)
+	if err != nil {
+		return err
+	}
+	var endpoints []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		endpoints = append(endpoints, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	event, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	for _, id := range endpoints {
+		if _, err := jobs.Enqueue(ctx, tx, JobKindWebhookDeliver, webhookPayload{EndpointID: id, Event: event},
+			jobs.Options{OrganizationID: &e.OrganizationID, MaxAttempts: d.config.WebhookMaxAttempts}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handle runs a webhook.deliver job. Any 2xx response counts as delivered; 410 Gone stops
+// the retries.
+func (d *WebhookDispatcher) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload webhookPayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	var event struct {
+		ID   uuid.UUID `json:"id"`
+		Type string    `json:"type"`
+	}
+	if err := json.Unmarshal(payload.Event, &event); err != nil {
+		return jobs.Permanent(fmt.Errorf("invalid event: %w", err))
+	}
+
+	var endpoint, secret string
+	err := d.db.QueryRowContext(ctx, `SELECT url, secret FROM webhook_endpoints WHERE id = $1`,
+		payload.EndpointID).Scan(&endpoint, &secret)
+	if err == sql.ErrNoRows {
+		// Deleted since the event was published
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload.Event))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OpenVDO-Webhooks/1.0")
+	req.Header.Set("X-OpenVDO-Event", event.Type)
+	req.Header.Set("X-OpenVDO-Event-ID", event.ID.String())
+	req.Header.Set("X-OpenVDO-Delivery", job.ID.String())
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, time.Now(), payload.Event))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return jobs.Permanent(err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusGone:
+		return jobs.Permanent(fmt.Errorf("endpoint returned %s", resp.Status))
+	default:
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+)
+
+// Largest plaintext that fits the single 4096-byte record push services accept, less the
+// 16-byte tag and the padding delimiter
+const webPushMaxPayload = 4096 - 16 - 1
+
+// WebPushProvider sends notifications to browser push subscriptions, encrypted as in RFC 8291
+// and authenticated to the push service with VAPID (RFC 8292)
+type WebPushProvider struct {
+	client    *http.Client
+	key       *ecdsa.PrivateKey
+	publicKey string
+	subject   string
+}
+
+// NewWebPushProvider creates a Web Push provider from the VAPID key pair in cfg
+func NewWebPushProvider(cfg config.Push) (*WebPushProvider, error) {
+	if !strings.HasPrefix(cfg.VAPIDSubject, "mailto:") && !strings.HasPrefix(cfg.VAPIDSubject, "https:") {
+		return nil, fmt.Errorf("PUSH_VAPID_SUBJECT must be a mailto: or https: URL")
+	}
+	raw, err := decodeBase64URL(cfg.VAPIDPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PUSH_VAPID_PRIVATE_KEY: %w", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PUSH_VAPID_PRIVATE_KEY: %w", err)
+	}
+	public, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if configured, err := decodeBase64URL(cfg.VAPIDPublicKey); err != nil || !bytes.Equal(configured, public) {
+		return nil, fmt.Errorf("PUSH_VAPID_PUBLIC_KEY does not belong to PUSH_VAPID_PRIVATE_KEY")
+	}
+
+	return &WebPushProvider{
+		// Subscription endpoints come from browsers, so they are fetched like any user-supplied URL
+		client:    NewSafeHTTPClient(cfg.Timeout, 0, false),
+		key:       key,
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		subject:   cfg.VAPIDSubject,
+	}, nil
+}
+
+// GenerateVAPIDKeys returns a new base64url-encoded VAPID key pair
+func GenerateVAPIDKeys() (public, private string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	pub, err := key.PublicKey.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+	priv, err := key.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(pub), base64.RawURLEncoding.EncodeToString(priv), nil
+}
+
+// ValidateWebPushSubscription checks a subscription before it is stored
+func ValidateWebPushSubscription(endpoint, p256dh, auth string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if err := CheckFetchURL(u, false); err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	key, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	if _, err := ecdh.P256().NewPublicKey(key); err != nil {
+		return fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	if secret, err := decodeBase64URL(auth); err != nil || len(secret) != 16 {
+		return fmt.Errorf("invalid auth secret")
+	}
+	return nil
+}
+
+// PublicKey is the application server key browsers subscribe with
+func (w *WebPushProvider) PublicKey() string {
+	return w.publicKey
+}
+
+// Platform implements PushProvider
+func (w *WebPushProvider) Platform() string {
+	return models.PushPlatformWeb
+}
+
+// Send implements PushProvider. The payload is the notification as JSON for the service
+// worker to display.
+func (w *WebPushProvider) Send(ctx context.Context, device *models.PushDevice, msg *PushMessage) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":    msg.ID,
+		"type":  msg.Type,
+		"title": msg.Title,
+		"body":  msg.Body,
+		"data":  msg.Data,
+	})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	if len(payload) > webPushMaxPayload {
+		return jobs.Permanent(fmt.Errorf("payload of %d bytes exceeds the Web Push limit", len(payload)))
+	}
+	body, err := encryptWebPush(device, payload)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+
+	endpoint, err := url.Parse(device.Token)
+	if err != nil {
+		return fmt.Errorf("%w: invalid endpoint", ErrDeviceGone)
+	}
+	token, err := signJWT(map[string]string{"typ": "JWT", "alg": "ES256"}, map[string]interface{}{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": w.subject,
+	}, w.key)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, device.Token, bytes.NewReader(body))
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	req.Header.Set("Authorization", "vapid t="+token+", k="+w.publicKey)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(msg.TTL.Seconds())))
+	req.Header.Set("Urgency", "normal")
+	// A UUID without dashes is within the 32 URL-safe characters a topic may have
+	req.Header.Set("Topic", strings.ReplaceAll(msg.ID.String(), "-", ""))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrBlockedAddress) {
+			return fmt.Errorf("%w: %v", ErrDeviceGone, err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("%w: push service returned %s", ErrDeviceGone, resp.Status)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("push service returned %s", resp.Status)
+	default:
+		return jobs.Permanent(fmt.Errorf("push service returned %s: %s", resp.Status, bytes.TrimSpace(detail)))
+	}
+}
+
+// encryptWebPush encrypts the payload for a subscription with the aes128gcm content coding,
+// as a single record whose header carries the sender's ephemeral public key
+func encryptWebPush(device *models.PushDevice, payload []byte) ([]byte, error) {
+	uaPublic, err := decodeBase64URL(device.P256DH)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := decodeBase64URL(device.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+	uaKey, err := ecdh.P256().NewPublicKey(uaPublic)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	asKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := asKey.ECDH(uaKey)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asKey.PublicKey().Bytes()
+
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, shared, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// Header: salt, record size, key ID length and the key ID, which is the sender's public key
+	out := make([]byte, 0, 16+4+1+len(asPublic)+len(payload)+1+gcm.Overhead())
+	out = append(out, salt...)
+	out = binary.BigEndian.AppendUint32(out, 4096)
+	out = append(out, byte(len(asPublic)))
+	out = append(out, asPublic...)
+	// 0x02 marks the last record
+	return gcm.Seal(out, nonce, append(payload, 0x02), nil), nil
+}
+
+// decodeBase64URL decodes base64url with or without padding, as browsers and key tools differ
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
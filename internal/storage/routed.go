@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegionResolver returns the region an organization's objects are stored in
+type RegionResolver func(ctx context.Context, orgID uuid.UUID) (string, error)
+
+// routed sends each object to the backend of the region its organization is pinned to. Keys
+// are routed by the organization in their orgs/<id>/ prefix; other keys, such as user
+// avatars, stay in the home backend.
+type routed struct {
+	home     Storage
+	backends map[string]Storage
+	regionOf RegionResolver
+}
+
+// routedFull adds the multipart, presigning and archival capabilities, for when every
+// backend has them
+type routedFull struct {
+	*routed
+}
+
+// NewRouted creates a backend that routes objects between the home backend and the backends
+// of other regions. The result only offers the optional capabilities every backend has.
+func NewRouted(home Storage, regional map[string]Storage, regionOf RegionResolver) Storage {
+	r := &routed{home: home, backends: regional, regionOf: regionOf}
+
+	all := append([]Storage{home}, mapValues(regional)...)
+	for _, s := range all {
+		_, multipart := s.(MultipartUploader)
+		_, presign := s.(Presigner)
+		_, archive := s.(Archiver)
+		if !multipart || !presign || !archive {
+			return r
+		}
+	}
+	return routedFull{r}
+}
+
+func mapValues(m map[string]Storage) []Storage {
+	values := make([]Storage, 0, len(m))
+	for _, s := range m {
+		values = append(values, s)
+	}
+	return values
+}
+
+// backend returns the backend a key belongs in
+func (r *routed) backend(ctx context.Context, key string) (Storage, error) {
+	rest, ok := strings.CutPrefix(key, "orgs/")
+	if !ok {
+		return r.home, nil
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	orgID, err := uuid.Parse(id)
+	if err != nil {
+		return r.home, nil
+	}
+
+	region, err := r.regionOf(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve region of organization %s: %w", orgID, err)
+	}
+	if s, ok := r.backends[region]; ok {
+		return s, nil
+	}
+	return r.home, nil
+}
+
+func (r *routed) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, key, body, size, contentType)
+}
+
+func (r *routed) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, key)
+}
+
+func (r *routed) Delete(ctx context.Context, key string) error {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.Delete(ctx, key)
+}
+
+func (r *routed) Exists(ctx context.Context, key string) (bool, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return s.Exists(ctx, key)
+}
+
+// Stat and GetRange answer ErrRangeUnsupported for objects of a backend without ranged reads,
+// so the routed backend offers them even when only some regions can
+func (r *routed) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	rr, ok := s.(RangeReader)
+	if !ok {
+		return ObjectInfo{}, ErrRangeUnsupported
+	}
+	return rr.Stat(ctx, key)
+}
+
+func (r *routed) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	rr, ok := s.(RangeReader)
+	if !ok {
+		return nil, ErrRangeUnsupported
+	}
+	return rr.GetRange(ctx, key, offset, length)
+}
+
+func (r routedFull) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return s.(MultipartUploader).CreateMultipartUpload(ctx, key, contentType)
+}
+
+func (r routedFull) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, expiry time.Duration) (string, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return s.(MultipartUploader).PresignUploadPart(ctx, key, uploadID, partNumber, expiry)
+}
+
+func (r routedFull) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.(MultipartUploader).CompleteMultipartUpload(ctx, key, uploadID, parts)
+}
+
+func (r routedFull) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.(MultipartUploader).AbortMultipartUpload(ctx, key, uploadID)
+}
+
+func (r routedFull) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	return s.(Presigner).PresignGet(ctx, key, expiry)
+}
+
+func (r routedFull) Archive(ctx context.Context, key, storageClass string) error {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.(Archiver).Archive(ctx, key, storageClass)
+}
+
+func (r routedFull) Restore(ctx context.Context, key string, days int, tier string) error {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return err
+	}
+	return s.(Archiver).Restore(ctx, key, days, tier)
+}
+
+func (r routedFull) RestoreStatus(ctx context.Context, key string) (RestoreState, error) {
+	s, err := r.backend(ctx, key)
+	if err != nil {
+		return RestoreState{}, err
+	}
+	return s.(Archiver).RestoreStatus(ctx, key)
+}
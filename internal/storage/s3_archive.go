@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// CopyObject is limited to 5 GiB; larger objects are copied part by part
+	maxSingleCopySize = 5 << 30
+	copyPartSize      = 1 << 30
+)
+
+var restoreExpiryPattern = regexp.MustCompile(`expiry-date="([^"]+)"`)
+
+// Archive rewrites the object in place with a cold storage class
+func (s *S3Storage) Archive(ctx context.Context, key, storageClass string) error {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to head object %s: %w", key, err)
+	}
+
+	if string(head.StorageClass) == storageClass {
+		return nil
+	}
+
+	source := url.PathEscape(s.bucket + "/" + key)
+	size := aws.ToInt64(head.ContentLength)
+	if size <= maxSingleCopySize {
+		_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:            aws.String(s.bucket),
+			Key:               aws.String(key),
+			CopySource:        aws.String(source),
+			StorageClass:      types.StorageClass(storageClass),
+			MetadataDirective: types.MetadataDirectiveCopy,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to archive object %s: %w", key, err)
+		}
+		return nil
+	}
+
+	return s.archiveMultipart(ctx, key, source, size, head.ContentType, storageClass)
+}
+
+// archiveMultipart copies objects larger than 5 GiB into the cold tier using UploadPartCopy
+func (s *S3Storage) archiveMultipart(ctx context.Context, key, source string, size int64, contentType *string, storageClass string) error {
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		ContentType:  contentType,
+		StorageClass: types.StorageClass(storageClass),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start archive copy for %s: %w", key, err)
+	}
+
+	var parts []CompletedPart
+	for offset, n := int64(0), int32(1); offset < size; offset, n = offset+copyPartSize, n+1 {
+		end := offset + copyPartSize - 1
+		if end >= size {
+			end = size - 1
+		}
+
+		out, err := s.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(s.bucket),
+			Key:             aws.String(key),
+			CopySource:      aws.String(source),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", offset, end)),
+			PartNumber:      aws.Int32(n),
+			UploadId:        created.UploadId,
+		})
+		if err != nil {
+			s.AbortMultipartUpload(ctx, key, aws.ToString(created.UploadId))
+			return fmt.Errorf("failed to copy part %d of %s: %w", n, key, err)
+		}
+		parts = append(parts, CompletedPart{PartNumber: n, ETag: aws.ToString(out.CopyPartResult.ETag)})
+	}
+
+	if err := s.CompleteMultipartUpload(ctx, key, aws.ToString(created.UploadId), parts); err != nil {
+		s.AbortMultipartUpload(ctx, key, aws.ToString(created.UploadId))
+		return err
+	}
+	return nil
+}
+
+// Restore requests a temporary readable copy of an archived object
+func (s *S3Storage) Restore(ctx context.Context, key string, days int, tier string) error {
+	_, err := s.client.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		RestoreRequest: &types.RestoreRequest{
+			Days: aws.Int32(int32(days)),
+			GlacierJobParameters: &types.GlacierJobParameters{
+				Tier: types.Tier(tier),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore object %s: %w", key, err)
+	}
+	return nil
+}
+
+// RestoreStatus reports the storage tier and restore progress of an object
+func (s *S3Storage) RestoreStatus(ctx context.Context, key string) (RestoreState, error) {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return RestoreState{}, fmt.Errorf("failed to head object %s: %w", key, err)
+	}
+
+	state := RestoreState{
+		Archived: head.StorageClass == types.StorageClassGlacier ||
+			head.StorageClass == types.StorageClassDeepArchive ||
+			head.ArchiveStatus != "",
+	}
+
+	// Restore header looks like: ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"
+	if restore := aws.ToString(head.Restore); restore != "" {
+		state.Ongoing = strings.Contains(restore, `ongoing-request="true"`)
+		if m := restoreExpiryPattern.FindStringSubmatch(restore); m != nil {
+			if t, err := time.Parse(time.RFC1123, m[1]); err == nil {
+				state.ExpiresAt = &t
+			}
+		}
+	}
+
+	return state, nil
+}
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"openvdo/internal/config"
+)
+
+// ErrNotFound is returned when the requested object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ErrMultipartUnsupported is returned when the configured backend cannot issue presigned multipart uploads
+var ErrMultipartUnsupported = errors.New("storage backend does not support multipart uploads")
+
+// ErrArchiveUnsupported is returned when the configured backend has no cold storage tier
+var ErrArchiveUnsupported = errors.New("storage backend does not support archival")
+
+// ErrRangeUnsupported is returned when the backend holding an object cannot read part of it
+var ErrRangeUnsupported = errors.New("storage backend does not support ranged reads")
+
+// Storage is the minimal object storage abstraction used for sources, renditions and images
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// CompletedPart identifies a part uploaded by the client directly to the backend
+type CompletedPart struct {
+	PartNumber int32  `json:"part_number" binding:"required,min=1,max=10000"`
+	ETag       string `json:"etag" binding:"required"`
+}
+
+// MultipartUploader is implemented by backends that let clients upload parts directly
+// through presigned URLs, so large sources never transit the API server
+type MultipartUploader interface {
+	CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error)
+	PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, expiry time.Duration) (string, error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// Presigner is implemented by backends that can hand out time-limited download URLs, so
+// playback reads objects directly from the backend
+type Presigner interface {
+	PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+}
+
+// RangeReader is implemented by backends that can read part of an object, so proxying a range
+// of a large object fetches only the bytes asked for
+type RangeReader interface {
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// GetRange streams length bytes of the object from offset; a negative length reads to its end
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// RestoreState describes where an archived object is in the restore cycle
+type RestoreState struct {
+	Archived  bool       `json:"archived"`
+	Ongoing   bool       `json:"ongoing"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Archiver is implemented by backends with a cold storage tier (S3 Glacier, GCS Archive).
+// Archived objects must be restored before they can be read again.
+type Archiver interface {
+	Archive(ctx context.Context, key, storageClass string) error
+	Restore(ctx context.Context, key string, days int, tier string) error
+	RestoreStatus(ctx context.Context, key string) (RestoreState, error)
+}
+
+// New creates the storage backend selected by configuration
+func New(cfg config.Storage) (Storage, error) {
+	switch cfg.Backend {
+	case "s3":
+		return NewS3Storage(cfg)
+	case "local", "":
+		return NewLocalStorage(cfg.LocalPath)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// AsMultipartUploader returns the backend's multipart capability if it has one
+func AsMultipartUploader(s Storage) (MultipartUploader, error) {
+	if mu, ok := s.(MultipartUploader); ok {
+		return mu, nil
+	}
+	return nil, ErrMultipartUnsupported
+}
+
+// AsPresigner returns the backend's download URL capability if it has one
+func AsPresigner(s Storage) (Presigner, bool) {
+	p, ok := s.(Presigner)
+	return p, ok
+}
+
+// AsRangeReader returns the backend's ranged read capability if it has one. Objects of a routed
+// backend may still answer ErrRangeUnsupported when the backend holding them has none.
+func AsRangeReader(s Storage) (RangeReader, bool) {
+	rr, ok := s.(RangeReader)
+	return rr, ok
+}
+
+// AsArchiver returns the backend's archival capability if it has one
+func AsArchiver(s Storage) (Archiver, error) {
+	if a, ok := s.(Archiver); ok {
+		return a, nil
+	}
+	return nil, ErrArchiveUnsupported
+}
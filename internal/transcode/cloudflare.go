@@ -0,0 +1,188 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+)
+
+// CloudflareStream hands sources to Cloudflare Stream, which stores and streams them, as Mux
+// does. Each video's Stream video is its transcode job.
+type CloudflareStream struct {
+	client   *http.Client
+	endpoint string
+	config   config.Transcode
+}
+
+// NewCloudflareStream creates the Cloudflare Stream provider
+func NewCloudflareStream(cfg config.Transcode) (*CloudflareStream, error) {
+	if cfg.CloudflareStreamAccountID == "" || cfg.CloudflareStreamAPIToken == "" {
+		return nil, fmt.Errorf("CLOUDFLARE_STREAM_ACCOUNT_ID and CLOUDFLARE_STREAM_API_TOKEN are required for the cloudflare transcoder")
+	}
+	return &CloudflareStream{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		endpoint: "https://api.cloudflare.com/client/v4/accounts/" + url.PathEscape(cfg.CloudflareStreamAccountID) + "/stream",
+		config:   cfg,
+	}, nil
+}
+
+// Name implements Transcoder
+func (cf *CloudflareStream) Name() string {
+	return "cloudflare"
+}
+
+// Submit implements Transcoder by having Stream copy the source from its storage URL
+func (cf *CloudflareStream) Submit(ctx context.Context, req *Request) (string, error) {
+	if req.SourceURL == "" {
+		return "", jobs.Permanent(errors.New("cloudflare stream fetches sources over HTTP, which needs a storage backend that presigns URLs"))
+	}
+	body := map[string]interface{}{
+		"url":  req.SourceURL,
+		"meta": map[string]string{"name": req.VideoID.String(), metadataVideoID: req.VideoID.String()},
+	}
+	var video cloudflareVideo
+	if err := cf.do(ctx, http.MethodPost, "/copy", body, &video); err != nil {
+		return "", err
+	}
+	if video.UID == "" {
+		return "", fmt.Errorf("cloudflare stream returned no video ID")
+	}
+	return video.UID, nil
+}
+
+// Status implements Transcoder
+func (cf *CloudflareStream) Status(ctx context.Context, externalID string) (*Result, error) {
+	var video cloudflareVideo
+	if err := cf.do(ctx, http.MethodGet, "/"+url.PathEscape(externalID), nil, &video); err != nil {
+		return nil, err
+	}
+	return video.result(), nil
+}
+
+// ParseCallback implements CallbackParser for Stream webhooks, which post the video once it is
+// ready or failed, signed in the Webhook-Signature header
+func (cf *CloudflareStream) ParseCallback(header http.Header, body []byte) (string, *Result, error) {
+	if cf.config.CloudflareStreamWebhookSecret == "" {
+		return "", nil, ErrCallbacksUnsupported
+	}
+	// Stream signs as OpenVDO does but names the parts differently: time=<unix time>,sig1=<HMAC>
+	signature := strings.NewReplacer("time=", "t=", "sig1=", "v1=").Replace(header.Get("Webhook-Signature"))
+	if services.VerifyWebhook(cf.config.CloudflareStreamWebhookSecret, signature, body, time.Now(), callbackTolerance) != nil {
+		return "", nil, ErrUnauthorizedCallback
+	}
+
+	var video cloudflareVideo
+	if err := json.Unmarshal(body, &video); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+	if video.UID == "" {
+		return "", nil, fmt.Errorf("%w: not a Stream video", ErrInvalidCallback)
+	}
+	return video.UID, video.result(), nil
+}
+
+// cloudflareVideo is a Stream video as the API and webhooks describe it
+type cloudflareVideo struct {
+	UID    string `json:"uid"`
+	Status struct {
+		State           string `json:"state"`
+		PctComplete     string `json:"pctComplete"`
+		ErrorReasonCode string `json:"errorReasonCode"`
+		ErrorReasonText string `json:"errorReasonText"`
+	} `json:"status"`
+	Playback struct {
+		HLS  string `json:"hls"`
+		Dash string `json:"dash"`
+	} `json:"playback"`
+}
+
+func (v *cloudflareVideo) result() *Result {
+	switch v.Status.State {
+	case "ready":
+		return &Result{Status: models.TranscodeComplete, Progress: 1, Outputs: v.outputs()}
+	case "error":
+		message := v.Status.ErrorReasonText
+		if message == "" {
+			message = "video failed"
+		}
+		if v.Status.ErrorReasonCode != "" {
+			message = v.Status.ErrorReasonCode + ": " + message
+		}
+		return &Result{Status: models.TranscodeFailed, Error: message}
+	case "inprogress":
+		percent, _ := strconv.ParseFloat(v.Status.PctComplete, 64)
+		return &Result{Status: models.TranscodeProgressing, Progress: percent / 100}
+	default:
+		// pendingupload, downloading and queued
+		return &Result{Status: models.TranscodeSubmitted}
+	}
+}
+
+// outputs are the HLS and DASH manifests of the video
+func (v *cloudflareVideo) outputs() []services.RenditionSpec {
+	var outputs []services.RenditionSpec
+	if v.Playback.HLS != "" {
+		outputs = append(outputs, services.RenditionSpec{
+			URL: v.Playback.HLS, ContentType: "application/vnd.apple.mpegurl", ExternalID: v.UID,
+		})
+	}
+	if v.Playback.Dash != "" {
+		outputs = append(outputs, services.RenditionSpec{
+			URL: v.Playback.Dash, ContentType: "application/dash+xml", ExternalID: v.UID,
+		})
+	}
+	return outputs
+}
+
+// do sends a request authenticated with the API token and unwraps the result
+func (cf *CloudflareStream) do(ctx context.Context, method, path string, body, out interface{}) error {
+	payload := []byte{}
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, cf.endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cf.config.CloudflareStreamAPIToken)
+
+	respBody, err := sendJSON(cf.client, req, func(body []byte) string {
+		var apiErr struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		messages := make([]string, len(apiErr.Errors))
+		for i, e := range apiErr.Errors {
+			messages[i] = e.Message
+		}
+		return strings.Join(messages, "; ")
+	})
+	if err != nil {
+		return fmt.Errorf("cloudflare stream: %w", err)
+	}
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return err
+	}
+	return json.Unmarshal(envelope.Result, out)
+}
@@ -0,0 +1,517 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// mediaConvertAPI is the version prefix of MediaConvert's REST API
+const mediaConvertAPI = "/2017-08-29"
+
+// User metadata keys MediaConvert echoes in job descriptions and events, from which outputs
+// are found again without depending on the configuration at submission
+const (
+	metadataVideoID = "openvdo_video_id"
+	metadataOutput  = "openvdo_output"
+	metadataHeights = "openvdo_heights"
+	metadataCodecs  = "openvdo_codecs"
+	// metadataRanges lists the dynamic range of each codec's ladder when the source was probed
+	metadataRanges = "openvdo_ranges"
+)
+
+// MediaConvert transcodes sources with AWS Elemental MediaConvert into an HLS ladder written to
+// the output bucket. It calls the REST API directly, signing requests with SigV4.
+type MediaConvert struct {
+	client      *http.Client
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	endpoint    string
+	config      config.Transcode
+}
+
+// NewMediaConvert creates the MediaConvert transcoder
+func NewMediaConvert(cfg config.Transcode) (*MediaConvert, error) {
+	switch {
+	case cfg.MediaConvertRoleARN == "":
+		return nil, fmt.Errorf("MEDIACONVERT_ROLE_ARN is required for the mediaconvert transcoder")
+	case cfg.MediaConvertInputBucket == "":
+		return nil, fmt.Errorf("MEDIACONVERT_INPUT_BUCKET or S3_BUCKET is required for the mediaconvert transcoder")
+	case cfg.MediaConvertOutputBucket == "":
+		return nil, fmt.Errorf("MEDIACONVERT_OUTPUT_BUCKET is required for the mediaconvert transcoder")
+	case cfg.MediaConvertOutputBaseURL == "":
+		return nil, fmt.Errorf("MEDIACONVERT_OUTPUT_BASE_URL is required for the mediaconvert transcoder")
+	case len(cfg.MediaConvertHeights) == 0:
+		return nil, fmt.Errorf("MEDIACONVERT_HEIGHTS must list at least one height")
+	}
+	for _, h := range cfg.MediaConvertHeights {
+		if h < 32 || h > 4320 || h%2 != 0 {
+			return nil, fmt.Errorf("MEDIACONVERT_HEIGHTS: %d is not an even height between 32 and 4320", h)
+		}
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(cfg.MediaConvertRegion),
+	}
+	if cfg.MediaConvertAccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.MediaConvertAccessKeyID, cfg.MediaConvertSecretAccessKey, ""),
+		))
+	}
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	endpoint := cfg.MediaConvertEndpoint
+	if endpoint == "" {
+		endpoint = "https://mediaconvert." + cfg.MediaConvertRegion + ".amazonaws.com"
+	}
+	return &MediaConvert{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		signer:      v4.NewSigner(),
+		credentials: awsCfg.Credentials,
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		config:      cfg,
+	}, nil
+}
+
+// Name implements Transcoder
+func (mc *MediaConvert) Name() string {
+	return "mediaconvert"
+}
+
+func (mc *MediaConvert) outputURL(key string) string {
+	return strings.TrimSuffix(mc.config.MediaConvertOutputBaseURL, "/") + "/" + key
+}
+
+// maxBitrate is the QVBR ceiling of a rung of the ladder, about 5 bits per pixel row squared:
+// 5.8 Mbps at 1080p, 2.6 Mbps at 720p
+func maxBitrate(height int) int {
+	return max(height*height*5, 400_000)
+}
+
+// codecBitrate is the QVBR ceiling of a rung in a codec. HEVC reaches the quality of H.264 in
+// about 60% of the bits and AV1 in about half.
+func codecBitrate(codec string, height int) int {
+	switch codec {
+	case CodecHEVC:
+		return maxBitrate(height) * 6 / 10
+	case CodecAV1:
+		return maxBitrate(height) / 2
+	}
+	return maxBitrate(height)
+}
+
+// variantName is the suffix of a rung's playlist. H.264-only ladders keep the names they had
+// before other codecs were supported.
+func variantName(codecs []string, codec string, height int) string {
+	if len(codecs) == 1 {
+		return fmt.Sprintf("_%dp", height)
+	}
+	return fmt.Sprintf("_%s_%dp", codec, height)
+}
+
+// outputRange is the dynamic range of a codec's ladder. H.264 is always SDR, as that ladder is
+// the fallback for players without HDR support. HEVC keeps the source's range, Dolby Vision
+// included; AV1 carries a Dolby Vision source's HDR10 or HLG base instead.
+func outputRange(codec string, color *models.ColorInfo) string {
+	if !color.HDR() || codec == CodecH264 {
+		return models.DynamicRangeSDR
+	}
+	if color.DynamicRange == models.DynamicRangeDolbyVision && codec == CodecAV1 {
+		if color.DolbyVision != nil && color.DolbyVision.Compatibility == 4 {
+			return models.DynamicRangeHLG
+		}
+		return models.DynamicRangeHDR10
+	}
+	return color.DynamicRange
+}
+
+// preprocessors convert a source to a rung's dynamic range: HDR sources are tone mapped for SDR
+// rungs, HDR10 and HLG rungs are signaled as such with the source's metadata passed through,
+// and Dolby Vision rungs are written as profile 8.1, whose base layer HDR10 players decode.
+func preprocessors(rangeOut string, color *models.ColorInfo) map[string]interface{} {
+	switch rangeOut {
+	case models.DynamicRangeHDR10:
+		return map[string]interface{}{"colorCorrector": map[string]interface{}{"colorSpaceConversion": "FORCE_HDR10"}}
+	case models.DynamicRangeHLG:
+		return map[string]interface{}{"colorCorrector": map[string]interface{}{"colorSpaceConversion": "FORCE_HLG_2020"}}
+	case models.DynamicRangeDolbyVision:
+		return map[string]interface{}{"dolbyVision": map[string]interface{}{"profile": "PROFILE_8_1", "l6Mode": "PASSTHROUGH"}}
+	}
+	if color.HDR() {
+		return map[string]interface{}{"colorCorrector": map[string]interface{}{
+			"colorSpaceConversion": "FORCE_709",
+			"hdrToSdrToneMapper":   "PRESERVE_DETAILS",
+		}}
+	}
+	return nil
+}
+
+// codecSettings are the QVBR settings of a rung in a codec. HEVC is tagged hvc1, which Apple
+// players require in fragmented MP4, and HDR rungs are encoded with 10 bits.
+func codecSettings(codec string, height int, rangeOut string) map[string]interface{} {
+	settings := map[string]interface{}{
+		"rateControlMode": "QVBR",
+		"maxBitrate":      codecBitrate(codec, height),
+		"qvbrSettings":    map[string]interface{}{"qvbrQualityLevel": 7},
+	}
+	hdr := rangeOut != models.DynamicRangeSDR
+	switch codec {
+	case CodecHEVC:
+		settings["sceneChangeDetect"] = "TRANSITION_DETECTION"
+		settings["writeMp4PackagingType"] = "HVC1"
+		if hdr {
+			settings["codecProfile"] = "MAIN10_MAIN"
+		}
+		return map[string]interface{}{"codec": "H_265", "h265Settings": settings}
+	case CodecAV1:
+		if hdr {
+			settings["bitDepth"] = "BIT_10"
+		}
+		return map[string]interface{}{"codec": "AV1", "av1Settings": settings}
+	}
+	settings["sceneChangeDetect"] = "TRANSITION_DETECTION"
+	return map[string]interface{}{"codec": "H_264", "h264Settings": settings}
+}
+
+var aacAudio = []map[string]interface{}{{
+	"codecSettings": map[string]interface{}{
+		"codec": "AAC",
+		"aacSettings": map[string]interface{}{
+			"bitrate":    128000,
+			"codingMode": "CODING_MODE_2_0",
+			"sampleRate": 48000,
+		},
+	},
+}}
+
+// Submit implements Transcoder. An H.264 ladder is written as an HLS group of MPEG-TS segments.
+// With HEVC or AV1, which HLS only carries in fragmented MP4, every ladder goes to a CMAF group
+// instead, with the audio in a rendition of its own and a DASH manifest besides the HLS one.
+// Both manifests declare each variant's codecs and video range, so players skip those they
+// cannot decode or display and fall back to H.264.
+func (mc *MediaConvert) Submit(ctx context.Context, req *Request) (string, error) {
+	// Outputs of each source revision go to their own directory, so a replaced source never
+	// overwrites segments players may still be reading
+	output := fmt.Sprintf("%s%s/%d/index", mc.config.MediaConvertOutputPrefix, req.VideoID, req.SourceRevision)
+	destination := "s3://" + mc.config.MediaConvertOutputBucket + "/" + output
+	codecs := req.Codecs
+	if len(codecs) == 0 {
+		codecs = []string{CodecH264}
+	}
+	heights := make([]string, len(mc.config.MediaConvertHeights))
+	for i, h := range mc.config.MediaConvertHeights {
+		heights[i] = strconv.Itoa(h)
+	}
+	ranges := make([]string, len(codecs))
+
+	var outputs []map[string]interface{}
+	for c, codec := range codecs {
+		ranges[c] = outputRange(codec, req.Color)
+		for _, h := range mc.config.MediaConvertHeights {
+			video := map[string]interface{}{
+				"height":        h,
+				"codecSettings": codecSettings(codec, h, ranges[c]),
+				"colorMetadata": "INSERT",
+			}
+			if p := preprocessors(ranges[c], req.Color); p != nil {
+				video["videoPreprocessors"] = p
+			}
+			out := map[string]interface{}{
+				"nameModifier":      variantName(codecs, codec, h),
+				"containerSettings": map[string]interface{}{"container": "M3U8"},
+				"videoDescription":  video,
+			}
+			if len(codecs) == 1 {
+				out["audioDescriptions"] = aacAudio
+			} else {
+				out["containerSettings"] = map[string]interface{}{"container": "CMFC"}
+			}
+			outputs = append(outputs, out)
+		}
+	}
+
+	var group map[string]interface{}
+	if len(codecs) == 1 {
+		group = map[string]interface{}{
+			"name": "HLS",
+			"outputGroupSettings": map[string]interface{}{
+				"type": "HLS_GROUP_SETTINGS",
+				"hlsGroupSettings": map[string]interface{}{
+					"destination":        destination,
+					"segmentLength":      6,
+					"minSegmentLength":   0,
+					"codecSpecification": "RFC_6381",
+				},
+			},
+			"outputs": outputs,
+		}
+	} else {
+		outputs = append(outputs, map[string]interface{}{
+			"nameModifier":      "_audio",
+			"containerSettings": map[string]interface{}{"container": "CMFC"},
+			"audioDescriptions": aacAudio,
+		})
+		group = map[string]interface{}{
+			"name": "CMAF",
+			"outputGroupSettings": map[string]interface{}{
+				"type": "CMAF_GROUP_SETTINGS",
+				"cmafGroupSettings": map[string]interface{}{
+					"destination":        destination,
+					"segmentLength":      6,
+					"fragmentLength":     2,
+					"segmentControl":     "SEGMENTED_FILES",
+					"writeHlsManifest":   "ENABLED",
+					"writeDashManifest":  "ENABLED",
+					"codecSpecification": "RFC_6381",
+				},
+			},
+			"outputs": outputs,
+		}
+	}
+
+	metadata := map[string]string{
+		metadataVideoID: req.VideoID.String(),
+		metadataOutput:  output,
+		metadataHeights: strings.Join(heights, ","),
+		metadataCodecs:  strings.Join(codecs, ","),
+	}
+	if req.Color != nil {
+		metadata[metadataRanges] = strings.Join(ranges, ",")
+	}
+	body := map[string]interface{}{
+		"role":               mc.config.MediaConvertRoleARN,
+		"clientRequestToken": req.Token,
+		"userMetadata":       metadata,
+		"settings": map[string]interface{}{
+			"inputs": []map[string]interface{}{{
+				"fileInput": "s3://" + mc.config.MediaConvertInputBucket + "/" + req.SourceKey,
+				"audioSelectors": map[string]interface{}{
+					"Audio Selector 1": map[string]interface{}{"defaultSelection": "DEFAULT"},
+				},
+				"videoSelector":  map[string]interface{}{},
+				"timecodeSource": "ZEROBASED",
+			}},
+			"outputGroups": []map[string]interface{}{group},
+		},
+	}
+	if mc.config.MediaConvertQueue != "" {
+		body["queue"] = mc.config.MediaConvertQueue
+	}
+
+	var resp struct {
+		Job mediaConvertJob `json:"job"`
+	}
+	if err := mc.do(ctx, http.MethodPost, mediaConvertAPI+"/jobs", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Job.ID == "" {
+		return "", fmt.Errorf("mediaconvert returned no job ID")
+	}
+	return resp.Job.ID, nil
+}
+
+// Status implements Transcoder
+func (mc *MediaConvert) Status(ctx context.Context, externalID string) (*Result, error) {
+	var resp struct {
+		Job mediaConvertJob `json:"job"`
+	}
+	if err := mc.do(ctx, http.MethodGet, mediaConvertAPI+"/jobs/"+url.PathEscape(externalID), nil, &resp); err != nil {
+		return nil, err
+	}
+	return mc.result(&resp.Job), nil
+}
+
+// ParseCallback implements CallbackParser for "MediaConvert Job State Change" events that an
+// EventBridge rule sends to an API destination, authorized with the callback token
+func (mc *MediaConvert) ParseCallback(header http.Header, body []byte) (string, *Result, error) {
+	if mc.config.CallbackToken == "" {
+		return "", nil, ErrCallbacksUnsupported
+	}
+	token := strings.TrimPrefix(header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(mc.config.CallbackToken)) != 1 {
+		return "", nil, ErrUnauthorizedCallback
+	}
+
+	var event struct {
+		Source string          `json:"source"`
+		Detail mediaConvertJob `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+	if event.Source != "aws.mediaconvert" || event.Detail.JobID == "" {
+		return "", nil, fmt.Errorf("%w: not a MediaConvert job event", ErrInvalidCallback)
+	}
+	return event.Detail.JobID, mc.result(&event.Detail), nil
+}
+
+// mediaConvertJob is a job as the API describes it, or the detail of a job event, which names
+// some fields differently
+type mediaConvertJob struct {
+	ID                 string `json:"id"`
+	JobID              string `json:"jobId"`
+	Status             string `json:"status"`
+	JobPercentComplete int    `json:"jobPercentComplete"`
+	JobProgress        *struct {
+		Percent int `json:"jobPercentComplete"`
+	} `json:"jobProgress"`
+	ErrorCode          int               `json:"errorCode"`
+	ErrorMessage       string            `json:"errorMessage"`
+	UserMetadata       map[string]string `json:"userMetadata"`
+	OutputGroupDetails []struct {
+		OutputDetails []struct {
+			VideoDetails *struct {
+				WidthInPx  int `json:"widthInPx"`
+				HeightInPx int `json:"heightInPx"`
+			} `json:"videoDetails"`
+		} `json:"outputDetails"`
+	} `json:"outputGroupDetails"`
+}
+
+func (mc *MediaConvert) result(job *mediaConvertJob) *Result {
+	switch job.Status {
+	case "SUBMITTED":
+		return &Result{Status: models.TranscodeSubmitted}
+	case "COMPLETE":
+		return &Result{Status: models.TranscodeComplete, Progress: 1, Outputs: mc.outputs(job)}
+	case "ERROR", "CANCELED":
+		message := job.ErrorMessage
+		if message == "" {
+			message = "job " + strings.ToLower(job.Status)
+		}
+		if job.ErrorCode != 0 {
+			message = fmt.Sprintf("%d: %s", job.ErrorCode, message)
+		}
+		return &Result{Status: models.TranscodeFailed, Error: message}
+	default:
+		// PROGRESSING, and STATUS_UPDATE events
+		percent := job.JobPercentComplete
+		if job.JobProgress != nil {
+			percent = job.JobProgress.Percent
+		}
+		return &Result{Status: models.TranscodeProgressing, Progress: float64(percent) / 100}
+	}
+}
+
+// outputs lists the master playlist, the DASH manifest of a CMAF group, and a variant playlist
+// per codec and height, as laid out by Submit. Jobs submitted before codecs were recorded are
+// H.264 alone, and variants of sources that were not probed have no dynamic range.
+func (mc *MediaConvert) outputs(job *mediaConvertJob) []services.RenditionSpec {
+	output := job.UserMetadata[metadataOutput]
+	if output == "" {
+		return nil
+	}
+	externalID := job.ID
+	if externalID == "" {
+		externalID = job.JobID
+	}
+	codecs := []string{CodecH264}
+	if list := job.UserMetadata[metadataCodecs]; list != "" {
+		codecs = strings.Split(list, ",")
+	}
+	var ranges []string
+	if list := job.UserMetadata[metadataRanges]; list != "" {
+		ranges = strings.Split(list, ",")
+	}
+	const hls = "application/vnd.apple.mpegurl"
+	outputs := []services.RenditionSpec{{URL: mc.outputURL(output + ".m3u8"), ContentType: hls, ExternalID: externalID}}
+	if len(codecs) > 1 {
+		outputs = append(outputs, services.RenditionSpec{
+			URL: mc.outputURL(output + ".mpd"), ContentType: "application/dash+xml", ExternalID: externalID,
+		})
+	}
+
+	heights := strings.Split(job.UserMetadata[metadataHeights], ",")
+	for c, codec := range codecs {
+		for i, s := range heights {
+			h, err := strconv.Atoi(s)
+			if err != nil {
+				continue
+			}
+			// Outputs are in the order Submit added them, codec by codec
+			index := c*len(heights) + i
+			width, height, bitrate := 0, h, codecBitrate(codec, h)/1000
+			if len(job.OutputGroupDetails) > 0 && index < len(job.OutputGroupDetails[0].OutputDetails) {
+				if d := job.OutputGroupDetails[0].OutputDetails[index].VideoDetails; d != nil && d.HeightInPx > 0 {
+					width, height = d.WidthInPx, d.HeightInPx
+				}
+			}
+			spec := services.RenditionSpec{
+				URL:         mc.outputURL(output + variantName(codecs, codec, h) + ".m3u8"),
+				ContentType: hls,
+				Height:      &height,
+				BitrateKbps: &bitrate,
+				Codec:       codec,
+				ExternalID:  externalID,
+			}
+			if c < len(ranges) {
+				spec.DynamicRange = ranges[c]
+			}
+			if width > 0 {
+				spec.Width = &width
+			}
+			outputs = append(outputs, spec)
+		}
+	}
+	return outputs
+}
+
+// do sends a signed request. Requests MediaConvert rejects as invalid fail permanently, as
+// retrying them cannot succeed.
+func (mc *MediaConvert) do(ctx context.Context, method, path string, body, out interface{}) error {
+	payload := []byte{}
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, mc.endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	creds, err := mc.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if err := mc.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(sum[:]), "mediaconvert",
+		mc.config.MediaConvertRegion, time.Now()); err != nil {
+		return err
+	}
+
+	respBody, err := sendJSON(mc.client, req, func(body []byte) string {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		return apiErr.Message
+	})
+	if err != nil {
+		return fmt.Errorf("mediaconvert: %w", err)
+	}
+	return json.Unmarshal(respBody, out)
+}
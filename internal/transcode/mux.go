@@ -0,0 +1,185 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/jobs"
+	"openvdo/internal/models"
+	"openvdo/internal/services"
+)
+
+// muxStreamURL serves the HLS playlists of Mux playback IDs
+const muxStreamURL = "https://stream.mux.com/"
+
+// Mux hands sources to Mux Video, which stores and streams them. OpenVDO keeps the video
+// records, access control and analytics; each video's Mux asset is its transcode job, and the
+// asset's playlist becomes the video's master rendition once the asset is ready.
+type Mux struct {
+	client   *http.Client
+	endpoint string
+	config   config.Transcode
+}
+
+// NewMux creates the Mux provider
+func NewMux(cfg config.Transcode) (*Mux, error) {
+	if cfg.MuxTokenID == "" || cfg.MuxTokenSecret == "" {
+		return nil, fmt.Errorf("MUX_TOKEN_ID and MUX_TOKEN_SECRET are required for the mux transcoder")
+	}
+	return &Mux{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		endpoint: "https://api.mux.com",
+		config:   cfg,
+	}, nil
+}
+
+// Name implements Transcoder
+func (m *Mux) Name() string {
+	return "mux"
+}
+
+// Submit implements Transcoder. Mux fetches the source from its storage URL; the video ID is
+// passed through, so assets can be traced back from the Mux dashboard.
+func (m *Mux) Submit(ctx context.Context, req *Request) (string, error) {
+	if req.SourceURL == "" {
+		return "", jobs.Permanent(errors.New("mux fetches sources over HTTP, which needs a storage backend that presigns URLs"))
+	}
+	body := map[string]interface{}{
+		"input":           []map[string]string{{"url": req.SourceURL}},
+		"playback_policy": []string{"public"},
+		"passthrough":     req.VideoID.String(),
+	}
+	var asset muxAsset
+	if err := m.do(ctx, http.MethodPost, "/video/v1/assets", body, &asset); err != nil {
+		return "", err
+	}
+	if asset.ID == "" {
+		return "", fmt.Errorf("mux returned no asset ID")
+	}
+	return asset.ID, nil
+}
+
+// Status implements Transcoder
+func (m *Mux) Status(ctx context.Context, externalID string) (*Result, error) {
+	var asset muxAsset
+	if err := m.do(ctx, http.MethodGet, "/video/v1/assets/"+url.PathEscape(externalID), nil, &asset); err != nil {
+		return nil, err
+	}
+	return asset.result(), nil
+}
+
+// ParseCallback implements CallbackParser for Mux webhooks, signed in the Mux-Signature header.
+// Only video.asset.ready and video.asset.errored events are applied.
+func (m *Mux) ParseCallback(header http.Header, body []byte) (string, *Result, error) {
+	if m.config.MuxWebhookSecret == "" {
+		return "", nil, ErrCallbacksUnsupported
+	}
+	// Mux signs as OpenVDO does: t=<unix time>,v1=<HMAC-SHA256 of "t.body">
+	if services.VerifyWebhook(m.config.MuxWebhookSecret, header.Get("Mux-Signature"), body, time.Now(), callbackTolerance) != nil {
+		return "", nil, ErrUnauthorizedCallback
+	}
+
+	var event struct {
+		Type string   `json:"type"`
+		Data muxAsset `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+	if event.Type != "video.asset.ready" && event.Type != "video.asset.errored" {
+		return "", nil, nil
+	}
+	if event.Data.ID == "" {
+		return "", nil, fmt.Errorf("%w: event has no asset ID", ErrInvalidCallback)
+	}
+	return event.Data.ID, event.Data.result(), nil
+}
+
+// muxAsset is an asset as the API and webhooks describe it
+type muxAsset struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	PlaybackIDs []struct {
+		ID     string `json:"id"`
+		Policy string `json:"policy"`
+	} `json:"playback_ids"`
+	Errors *struct {
+		Type     string   `json:"type"`
+		Messages []string `json:"messages"`
+	} `json:"errors"`
+}
+
+func (a *muxAsset) result() *Result {
+	switch a.Status {
+	case "ready":
+		return &Result{Status: models.TranscodeComplete, Progress: 1, Outputs: a.outputs()}
+	case "errored":
+		message := "asset errored"
+		if a.Errors != nil && len(a.Errors.Messages) > 0 {
+			message = strings.Join(a.Errors.Messages, "; ")
+		}
+		return &Result{Status: models.TranscodeFailed, Error: message}
+	default:
+		// preparing; Mux does not report progress
+		return &Result{Status: models.TranscodeProgressing}
+	}
+}
+
+// outputs is the master playlist of the asset's public playback ID
+func (a *muxAsset) outputs() []services.RenditionSpec {
+	for _, p := range a.PlaybackIDs {
+		if p.Policy == "public" {
+			return []services.RenditionSpec{{
+				URL:         muxStreamURL + p.ID + ".m3u8",
+				ContentType: "application/vnd.apple.mpegurl",
+				ExternalID:  a.ID,
+			}}
+		}
+	}
+	return nil
+}
+
+// do sends a request authenticated with the access token
+func (m *Mux) do(ctx context.Context, method, path string, body, out interface{}) error {
+	payload := []byte{}
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(m.config.MuxTokenID, m.config.MuxTokenSecret)
+
+	respBody, err := sendJSON(m.client, req, func(body []byte) string {
+		var apiErr struct {
+			Error struct {
+				Messages []string `json:"messages"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		return strings.Join(apiErr.Error.Messages, "; ")
+	})
+	if err != nil {
+		return fmt.Errorf("mux: %w", err)
+	}
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return err
+	}
+	return json.Unmarshal(envelope.Data, out)
+}
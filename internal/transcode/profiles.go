@@ -0,0 +1,67 @@
+package transcode
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"openvdo/internal/config"
+)
+
+// Codecs of transcoded renditions, as recorded on them
+const (
+	CodecH264 = "h264"
+	CodecHEVC = "hevc"
+	CodecAV1  = "av1"
+)
+
+// ProfileStandard is the profile every deployment has, transcoding to H.264 alone
+const ProfileStandard = "standard"
+
+// ErrUnknownProfile is returned for transcode profiles that are not configured
+var ErrUnknownProfile = errors.New("unknown transcode profile")
+
+// ParseProfiles parses TRANSCODE_PROFILES, a list of name=codec+codec entries, into the codecs
+// of each profile. H.264 comes first in every profile, whether listed or not, so players
+// without HEVC or AV1 support always find a ladder they can decode. The default profile must be
+// among them.
+func ParseProfiles(cfg config.Transcode) (map[string][]string, error) {
+	profiles := map[string][]string{ProfileStandard: {CodecH264}}
+	for _, entry := range cfg.Profiles {
+		name, list, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || len(name) > 50 {
+			return nil, fmt.Errorf("TRANSCODE_PROFILES: %q is not name=codec+codec", entry)
+		}
+		codecs := []string{CodecH264}
+		for _, codec := range strings.Split(list, "+") {
+			codec = strings.ToLower(strings.TrimSpace(codec))
+			switch codec {
+			case CodecH264:
+			case CodecHEVC, CodecAV1:
+				if !slices.Contains(codecs, codec) {
+					codecs = append(codecs, codec)
+				}
+			default:
+				return nil, fmt.Errorf("TRANSCODE_PROFILES: profile %s: unknown codec %q; use h264, hevc or av1", name, codec)
+			}
+		}
+		profiles[name] = codecs
+	}
+	if profiles[cfg.Profile] == nil {
+		return nil, fmt.Errorf("TRANSCODE_PROFILE: %w %q", ErrUnknownProfile, cfg.Profile)
+	}
+	return profiles, nil
+}
+
+// profileNames lists the profiles by name
+func profileNames(profiles map[string][]string) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
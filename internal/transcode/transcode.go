@@ -0,0 +1,593 @@
+// Package transcode sends video sources to external transcoders, such as AWS MediaConvert, or
+// to streaming providers, such as Mux, and registers their outputs as renditions of the videos
+package transcode
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/internal/jobs"
+	"openvdo/internal/metrics"
+	"openvdo/internal/models"
+	"openvdo/internal/outbox"
+	"openvdo/internal/queries"
+	"openvdo/internal/services"
+	"openvdo/internal/storage"
+	"openvdo/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// JobKindTranscode submits a video's source to its organization's transcoder and follows the
+// transcoder's job until it finishes
+const JobKindTranscode = "video.transcode"
+
+// ProviderNone turns transcoding off for an organization whatever the default provider is
+const ProviderNone = "none"
+
+var (
+	// ErrUnknownProvider is returned for transcoders that are not configured
+	ErrUnknownProvider = errors.New("unknown transcoder")
+	// ErrJobNotFound is returned for transcoder jobs OpenVDO did not submit
+	ErrJobNotFound = errors.New("transcode job not found")
+	// ErrCallbacksUnsupported is returned for transcoders that do not send callbacks
+	ErrCallbacksUnsupported = errors.New("transcoder does not send callbacks")
+	// ErrInvalidCallback is wrapped by errors describing callbacks that cannot be parsed
+	ErrInvalidCallback = errors.New("invalid callback")
+	// ErrUnauthorizedCallback is returned for callbacks without a valid token or signature
+	ErrUnauthorizedCallback = errors.New("callback not authorized")
+)
+
+// callbackTolerance is how far a signed callback's time may be from now
+const callbackTolerance = 5 * time.Minute
+
+var finished = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "transcode",
+	Name:      "jobs_finished_total",
+	Help:      "Transcoder jobs that completed or failed, by provider and status",
+}, []string{"provider", "status"})
+
+// Request describes a source to transcode
+type Request struct {
+	VideoID        uuid.UUID
+	OrganizationID uuid.UUID
+	SourceKey      string
+	// SourceURL is a time-limited storage URL of the source, for providers that fetch it over
+	// HTTP. It is empty when the storage backend does not presign URLs.
+	SourceURL      string
+	SourceRevision int
+	// Codecs are the codecs to encode to, H.264 first. Transcoders that choose the encoding
+	// themselves, such as Mux and Cloudflare Stream, ignore them.
+	Codecs []string
+	// Color is the source's color signaling, or nil when sources are not probed. Transcoders
+	// that encode themselves keep HDR sources HDR in the codecs that carry it.
+	Color *models.ColorInfo
+	// Token identifies the submission, so a retried Submit does not start a second job on
+	// transcoders that deduplicate requests
+	Token string
+}
+
+// Result is the state of a transcoder job
+type Result struct {
+	// Status is one of the models.Transcode* statuses
+	Status   string
+	Progress float64
+	// Outputs are the renditions of a complete job
+	Outputs []services.RenditionSpec
+	Error   string
+}
+
+// Transcoder submits sources to an external transcoding service
+type Transcoder interface {
+	Name() string
+	// Submit starts a job and returns the transcoder's ID of it
+	Submit(ctx context.Context, req *Request) (string, error)
+	Status(ctx context.Context, externalID string) (*Result, error)
+}
+
+// CallbackParser is implemented by transcoders that announce job changes to OpenVDO, such as
+// MediaConvert through EventBridge and Mux through webhooks
+type CallbackParser interface {
+	// ParseCallback authenticates a callback and returns the job it is about and its state.
+	// It returns an empty job ID for callbacks about something else, which are ignored.
+	ParseCallback(header http.Header, body []byte) (string, *Result, error)
+}
+
+// NewTranscoders returns the configured transcoders by name. The default provider must be
+// one of them.
+func NewTranscoders(cfg config.Transcode) (map[string]Transcoder, error) {
+	transcoders := make(map[string]Transcoder)
+	if cfg.MediaConvertRoleARN != "" || cfg.Provider == "mediaconvert" {
+		mc, err := NewMediaConvert(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transcoders[mc.Name()] = mc
+	}
+	if cfg.MuxTokenID != "" || cfg.Provider == "mux" {
+		mux, err := NewMux(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transcoders[mux.Name()] = mux
+	}
+	if cfg.CloudflareStreamAccountID != "" || cfg.Provider == "cloudflare" {
+		cf, err := NewCloudflareStream(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transcoders[cf.Name()] = cf
+	}
+	if cfg.Provider != "" && cfg.Provider != ProviderNone && transcoders[cfg.Provider] == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProvider, cfg.Provider)
+	}
+	return transcoders, nil
+}
+
+// Manager queues transcodes of new sources and records their outcome. As an outbox publisher
+// it queues a video.transcode job in the relay's transaction for video.ready and
+// video.source_replaced events of organizations with a transcoder.
+type Manager struct {
+	db          *sql.DB
+	storage     storage.Storage
+	transcoders map[string]Transcoder
+	// profiles are the codecs of each transcode profile, from ParseProfiles
+	profiles map[string][]string
+	// prober reads the color signaling of sources; nil when ffprobe is not available
+	prober *services.MediaProber
+	config config.Transcode
+}
+
+// NewManager creates a manager. db must not carry a tenant context, and prober may be nil.
+func NewManager(db *sql.DB, store storage.Storage, transcoders map[string]Transcoder, profiles map[string][]string,
+	prober *services.MediaProber, cfg config.Transcode) *Manager {
+	return &Manager{db: db, storage: store, transcoders: transcoders, profiles: profiles, prober: prober, config: cfg}
+}
+
+// Providers lists the configured transcoders
+func (m *Manager) Providers() []string {
+	names := make([]string, 0, len(m.transcoders))
+	for name := range m.transcoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profiles lists the configured transcode profiles
+func (m *Manager) Profiles() []string {
+	return profileNames(m.profiles)
+}
+
+// SetTranscoder sets an organization's transcoder, a configured one, ProviderNone, or empty to
+// follow the default; and its transcode profile, a configured one or empty to follow the
+// default
+func (m *Manager) SetTranscoder(ctx context.Context, orgID uuid.UUID, provider, profile string) error {
+	if provider != "" && provider != ProviderNone && m.transcoders[provider] == nil {
+		return ErrUnknownProvider
+	}
+	if profile != "" && m.profiles[profile] == nil {
+		return ErrUnknownProfile
+	}
+	n, err := queries.New(m.db).SetTranscoder(ctx, queries.SetTranscoderParams{
+		Transcoder: provider, TranscodeProfile: profile, ID: orgID,
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type transcodePayload struct {
+	VideoID  uuid.UUID `json:"video_id"`
+	Provider string    `json:"provider"`
+	// SourceRevision is the revision queued; jobs queued before it was recorded have none
+	SourceRevision int `json:"source_revision,omitempty"`
+	// Codecs are those of the organization's profile when the job was queued; jobs queued
+	// before profiles existed have none and encode to H.264 alone
+	Codecs []string `json:"codecs,omitempty"`
+}
+
+type transcodeCheckpoint struct {
+	TranscodeJobID uuid.UUID `json:"transcode_job_id"`
+	// Retries is the job's count of manual retries when the checkpoint was saved
+	Retries int `json:"retries,omitempty"`
+}
+
+// Name implements outbox.Publisher
+func (m *Manager) Name() string {
+	return "transcode"
+}
+
+// Publish implements outbox.Publisher. Videos of organizations without a transcoder are ready
+// once their source is stored; the others stay queued for the video.transcode job.
+func (m *Manager) Publish(ctx context.Context, tx *sql.Tx, e *outbox.Event) error {
+	if (e.Type != outbox.EventVideoReady && e.Type != outbox.EventVideoSourceReplaced) || e.SubjectID == nil {
+		return nil
+	}
+	qtx := queries.New(tx)
+	org, err := qtx.GetOrganizationTranscoder(ctx, e.OrganizationID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	provider := m.config.Provider
+	if org.Transcoder != nil {
+		provider = *org.Transcoder
+	}
+	if provider == "" || provider == ProviderNone {
+		return moveVideo(ctx, tx, *e.SubjectID, 0, models.VideoStatusReady, "no transcoder")
+	}
+	if m.transcoders[provider] == nil {
+		log.Printf("WARN: Organization %s uses transcoder %q, which is not configured", e.OrganizationID, provider)
+		return moveVideo(ctx, tx, *e.SubjectID, 0, models.VideoStatusReady,
+			fmt.Sprintf("transcoder %q is not configured", provider))
+	}
+
+	revision, err := qtx.GetVideoSourceRevision(ctx, *e.SubjectID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = jobs.Enqueue(ctx, tx, JobKindTranscode,
+		transcodePayload{VideoID: *e.SubjectID, Provider: provider, SourceRevision: revision, Codecs: m.codecs(e.OrganizationID, org.TranscodeProfile)},
+		jobs.Options{OrganizationID: &e.OrganizationID})
+	return err
+}
+
+// codecs returns the codecs of an organization's profile, or of the default profile for
+// organizations without one. A profile since removed from the configuration falls back to the
+// default as well.
+func (m *Manager) codecs(orgID uuid.UUID, profile *string) []string {
+	if profile == nil {
+		return m.profiles[m.config.Profile]
+	}
+	if codecs := m.profiles[*profile]; codecs != nil {
+		return codecs
+	}
+	log.Printf("WARN: Organization %s uses transcode profile %q, which is not configured", orgID, *profile)
+	return m.profiles[m.config.Profile]
+}
+
+// moveVideo moves a video to status within q while its source is still at revision, of which 0
+// matches any. Videos deleted or moved on meanwhile are left as they are.
+func moveVideo(ctx context.Context, q database.Querier, videoID uuid.UUID, revision int, status, reason string) error {
+	if revision > 0 {
+		current, err := queries.New(q).LockVideoSourceRevision(ctx, videoID)
+		if err == sql.ErrNoRows || (err == nil && current != revision) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err := services.TransitionVideo(ctx, q, videoID, status, reason)
+	if err == sql.ErrNoRows || errors.Is(err, services.ErrInvalidTransition) {
+		return nil
+	}
+	return err
+}
+
+// moveVideoNow is moveVideo in a transaction of its own
+func (m *Manager) moveVideoNow(ctx context.Context, videoID uuid.UUID, revision int, status, reason string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := moveVideo(ctx, tx, videoID, revision, status, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Handle runs a video.transcode job. The first run submits the source; later runs poll the
+// transcoder every PollInterval until the job finishes, here or through a callback. A video
+// whose transcode fails for good is ready all the same, playing its source as uploaded.
+func (m *Manager) Handle(ctx context.Context, job *jobs.Job, progress func(float64)) error {
+	var payload transcodePayload
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+	err := m.handle(ctx, job, &payload, progress)
+	if err != nil && ctx.Err() == nil && !jobs.IsReschedule(err) && (jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+		reason := "transcode failed, playing the source as uploaded: " + err.Error()
+		if moveErr := m.moveVideoNow(context.Background(), payload.VideoID, payload.SourceRevision,
+			models.VideoStatusReady, reason); moveErr != nil {
+			logger.Error("Failed to mark video %s ready: %v", payload.VideoID, moveErr)
+		}
+	}
+	return err
+}
+
+func (m *Manager) handle(ctx context.Context, job *jobs.Job, payload *transcodePayload, progress func(float64)) error {
+	t := m.transcoders[payload.Provider]
+	if t == nil {
+		return jobs.Permanent(fmt.Errorf("%w %q", ErrUnknownProvider, payload.Provider))
+	}
+
+	var checkpoint transcodeCheckpoint
+	if len(job.Checkpoint) > 0 {
+		if err := json.Unmarshal(job.Checkpoint, &checkpoint); err != nil {
+			return err
+		}
+	}
+	if job.Retries > checkpoint.Retries {
+		done, err := m.retry(ctx, job, payload, &checkpoint)
+		if done || err != nil {
+			return err
+		}
+	}
+	if checkpoint.TranscodeJobID == uuid.Nil {
+		return m.submit(ctx, t, job, payload.VideoID, payload.Codecs)
+	}
+
+	var externalID, status string
+	var jobError sql.NullString
+	err := m.db.QueryRowContext(ctx, `SELECT external_id, status, error FROM transcode_jobs WHERE id = $1`,
+		checkpoint.TranscodeJobID).Scan(&externalID, &status, &jobError)
+	if err == sql.ErrNoRows {
+		// The video was deleted, and its transcode jobs with it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if status == models.TranscodeComplete {
+		return nil
+	}
+	if status == models.TranscodeFailed {
+		return jobs.Permanent(fmt.Errorf("%s job %s failed: %s", t.Name(), externalID, jobError.String))
+	}
+
+	result, err := t.Status(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if err := m.Apply(ctx, t.Name(), externalID, result); err != nil && !errors.Is(err, ErrJobNotFound) {
+		return err
+	}
+	switch result.Status {
+	case models.TranscodeComplete:
+		return nil
+	case models.TranscodeFailed:
+		return jobs.Permanent(fmt.Errorf("%s job %s failed: %s", t.Name(), externalID, result.Error))
+	}
+	progress(result.Progress)
+	return jobs.Reschedule(m.config.PollInterval)
+}
+
+// retry picks a job requeued by hand back up. The video waits for the transcoder again; a
+// source the transcoder failed on is submitted anew, and one it is still working on is polled
+// on. It reports whether a completed transcode leaves nothing to do.
+func (m *Manager) retry(ctx context.Context, job *jobs.Job, payload *transcodePayload, checkpoint *transcodeCheckpoint) (bool, error) {
+	var status string
+	err := m.db.QueryRowContext(ctx, `SELECT status FROM transcode_jobs WHERE id = $1`,
+		checkpoint.TranscodeJobID).Scan(&status)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if status == models.TranscodeComplete {
+		return true, nil
+	}
+	if err := m.moveVideoNow(ctx, payload.VideoID, payload.SourceRevision, models.VideoStatusQueued,
+		"transcode retried"); err != nil {
+		return false, err
+	}
+
+	if status == "" || status == models.TranscodeFailed {
+		*checkpoint = transcodeCheckpoint{}
+		return false, nil
+	}
+	checkpoint.Retries = job.Retries
+	return false, jobs.SaveCheckpoint(ctx, m.db, job.ID, checkpoint)
+}
+
+// submit sends the video's current source to the transcoder and records the transcoder's job
+func (m *Manager) submit(ctx context.Context, t Transcoder, job *jobs.Job, videoID uuid.UUID, codecs []string) error {
+	video, err := queries.New(m.db).GetVideoTranscodeSource(ctx, videoID)
+	if err == sql.ErrNoRows {
+		// Deleted since the transcode was queued
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if video.SourceKey == nil || *video.SourceKey == "" {
+		return jobs.Permanent(fmt.Errorf("video %s has no source", videoID))
+	}
+
+	req := &Request{
+		VideoID:        videoID,
+		OrganizationID: video.OrganizationID,
+		SourceKey:      *video.SourceKey,
+		SourceRevision: video.SourceRevision,
+		Codecs:         codecs,
+		Token:          job.ID.String(),
+	}
+	if presigner, ok := storage.AsPresigner(m.storage); ok {
+		if req.SourceURL, err = presigner.PresignGet(ctx, req.SourceKey, m.config.SourceURLExpiry); err != nil {
+			return fmt.Errorf("failed to presign source of video %s: %w", videoID, err)
+		}
+	}
+	if m.prober != nil {
+		// A source that cannot be probed is transcoded as SDR rather than not at all
+		if req.Color, err = m.prober.Color(ctx, videoID); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Error("Transcoding video %s without color information: %v", videoID, err)
+		}
+	}
+	externalID, err := t.Submit(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to submit to %s: %w", t.Name(), err)
+	}
+
+	var id uuid.UUID
+	err = m.db.QueryRowContext(ctx, `
+		INSERT INTO transcode_jobs (video_id, organization_id, provider, external_id, source_revision)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, external_id) DO UPDATE SET updated_at = NOW()
+		RETURNING id
+	`, videoID, video.OrganizationID, t.Name(), externalID, video.SourceRevision).Scan(&id)
+	if err != nil {
+		return err
+	}
+	if err := jobs.SaveCheckpoint(ctx, m.db, job.ID, transcodeCheckpoint{TranscodeJobID: id, Retries: job.Retries}); err != nil {
+		return err
+	}
+	if err := m.moveVideoNow(ctx, videoID, video.SourceRevision, models.VideoStatusProcessing,
+		fmt.Sprintf("submitted to %s as job %s", t.Name(), externalID)); err != nil {
+		return err
+	}
+	logger.Info("Submitted source revision %d of video %s to %s as job %s", video.SourceRevision, videoID, t.Name(), externalID)
+	return jobs.Reschedule(m.config.PollInterval)
+}
+
+// Callback applies a transcoder's callback. Callbacks about jobs OpenVDO did not submit, such
+// as other jobs of the same AWS account, are ignored.
+func (m *Manager) Callback(ctx context.Context, provider string, header http.Header, body []byte) error {
+	t := m.transcoders[provider]
+	if t == nil {
+		return ErrUnknownProvider
+	}
+	parser, ok := t.(CallbackParser)
+	if !ok {
+		return ErrCallbacksUnsupported
+	}
+	externalID, result, err := parser.ParseCallback(header, body)
+	if err != nil || externalID == "" {
+		return err
+	}
+	if err := m.Apply(ctx, provider, externalID, result); err != nil && !errors.Is(err, ErrJobNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Apply records the state of a transcoder job. Once the job completes its outputs replace the
+// video's earlier transcoder renditions, unless the source was replaced since it was submitted.
+// Jobs that already finished are left as they are, so a late poll or a repeated callback does
+// not register outputs twice.
+func (m *Manager) Apply(ctx context.Context, provider, externalID string, result *Result) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id, videoID, orgID uuid.UUID
+	var status string
+	var revision int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, video_id, organization_id, status, source_revision FROM transcode_jobs
+		WHERE provider = $1 AND external_id = $2
+		FOR UPDATE
+	`, provider, externalID).Scan(&id, &videoID, &orgID, &status, &revision)
+	if err == sql.ErrNoRows {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if status == models.TranscodeComplete || status == models.TranscodeFailed {
+		return nil
+	}
+
+	switch result.Status {
+	case models.TranscodeComplete:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE transcode_jobs SET status = $2, progress = 1, error = NULL, completed_at = NOW() WHERE id = $1
+		`, id, models.TranscodeComplete)
+		if err != nil {
+			return err
+		}
+		var current bool
+		current, err = queries.New(tx).VideoAtRevision(ctx, queries.VideoAtRevisionParams{
+			ID: videoID, SourceRevision: revision,
+		})
+		if err != nil {
+			return err
+		}
+		if current && len(result.Outputs) > 0 {
+			// Outputs carry the ID of the job that made them, whichever transcoder ran it
+			if _, err := tx.ExecContext(ctx, `
+				DELETE FROM video_renditions
+				WHERE video_id = $1 AND integration_id IS NULL
+					AND external_id IN (SELECT external_id FROM transcode_jobs WHERE video_id = $1)
+			`, videoID); err != nil {
+				return err
+			}
+			if _, err := services.AddRenditions(ctx, tx, orgID, videoID, nil, result.Outputs); err != nil {
+				return err
+			}
+		}
+		err = moveVideo(ctx, tx, videoID, revision, models.VideoStatusReady,
+			fmt.Sprintf("%s job %s completed", provider, externalID))
+	case models.TranscodeFailed:
+		_, err = tx.ExecContext(ctx, `
+			UPDATE transcode_jobs SET status = $2, error = $3, completed_at = NOW() WHERE id = $1
+		`, id, models.TranscodeFailed, result.Error)
+		if err == nil {
+			err = moveVideo(ctx, tx, videoID, revision, models.VideoStatusReady,
+				fmt.Sprintf("%s job %s failed, playing the source as uploaded: %s", provider, externalID, result.Error))
+		}
+	default:
+		_, err = tx.ExecContext(ctx, `UPDATE transcode_jobs SET status = $2, progress = $3 WHERE id = $1`,
+			id, result.Status, result.Progress)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if result.Status == models.TranscodeComplete || result.Status == models.TranscodeFailed {
+		finished.WithLabelValues(provider, result.Status).Inc()
+	}
+	return nil
+}
+
+// sendJSON sends a provider API request and returns the response body. Requests the provider
+// rejects as invalid fail permanently, as retrying them cannot succeed; describe extracts the
+// provider's error message.
+func sendJSON(client *http.Client, req *http.Request, describe func([]byte) string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		err := fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, describe(body))
+		if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusNotFound {
+			return nil, jobs.Permanent(err)
+		}
+		return nil, err
+	}
+	return body, nil
+}
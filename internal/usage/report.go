@@ -0,0 +1,113 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"openvdo/internal/database"
+
+	"github.com/google/uuid"
+)
+
+// Granularities of a report
+const (
+	GranularityDay   = "day"
+	GranularityMonth = "month"
+)
+
+// MaxReportDays bounds the range of a report
+const MaxReportDays = 400
+
+// ErrInvalidReport is returned for unknown granularities and ranges outside the limits
+var ErrInvalidReport = errors.New("invalid usage report")
+
+// ReportQuery selects the usage of an organization in the UTC days [From, To]
+type ReportQuery struct {
+	OrganizationID uuid.UUID
+	From           time.Time
+	To             time.Time
+	Granularity    string
+	// ByAPIKey splits each period by API key
+	ByAPIKey bool
+}
+
+// Validate checks the granularity and range
+func (q ReportQuery) Validate() error {
+	if q.Granularity != GranularityDay && q.Granularity != GranularityMonth {
+		return fmt.Errorf("%w: granularity must be day or month", ErrInvalidReport)
+	}
+	if q.To.Before(q.From) {
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidReport)
+	}
+	if q.To.Sub(q.From) > MaxReportDays*24*time.Hour {
+		return fmt.Errorf("%w: range must not exceed %d days", ErrInvalidReport, MaxReportDays)
+	}
+	return nil
+}
+
+// Period is the usage of one day or month, of one API key when the report is split by key.
+// APIKeyID is left out for requests made with a user's credentials.
+type Period struct {
+	Start    string     `json:"start"`
+	APIKeyID *uuid.UUID `json:"api_key_id,omitempty"`
+	Counts
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// Report is an organization's usage per period, and its total
+type Report struct {
+	Periods []Period `json:"periods"`
+	Total   Counts   `json:"total"`
+	// ErrorRate is the share of requests that failed with a 4xx or 5xx status
+	ErrorRate float64 `json:"error_rate"`
+}
+
+func errorRate(c Counts) float64 {
+	if c.Requests == 0 {
+		return 0
+	}
+	return float64(c.ClientErrors+c.ServerErrors) / float64(c.Requests)
+}
+
+// Load reads a report from api_usage_daily. Counters not flushed yet, up to
+// USAGE_FLUSH_INTERVAL old, are not included.
+func Load(ctx context.Context, q database.Querier, rq ReportQuery) (*Report, error) {
+	keyColumn := `NULL::uuid`
+	if rq.ByAPIKey {
+		keyColumn = `api_key_id`
+	}
+	rows, err := q.QueryContext(ctx, `
+		SELECT to_char(date_trunc($4, day::timestamp), 'YYYY-MM-DD') AS period, `+keyColumn+` AS api_key,
+			SUM(requests), SUM(client_errors), SUM(server_errors), SUM(bytes_in), SUM(bytes_out)
+		FROM api_usage_daily
+		WHERE organization_id = $1 AND day BETWEEN $2::date AND $3::date
+		GROUP BY period, api_key
+		ORDER BY period, api_key
+	`, rq.OrganizationID, rq.From.Format(dayLayout), rq.To.Format(dayLayout), rq.Granularity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &Report{Periods: []Period{}}
+	for rows.Next() {
+		var p Period
+		if err := rows.Scan(&p.Start, &p.APIKeyID, &p.Requests, &p.ClientErrors, &p.ServerErrors,
+			&p.BytesIn, &p.BytesOut); err != nil {
+			return nil, err
+		}
+		if p.APIKeyID != nil && *p.APIKeyID == uuid.Nil {
+			p.APIKeyID = nil
+		}
+		p.ErrorRate = errorRate(p.Counts)
+		report.Total.add(p.Counts)
+		report.Periods = append(report.Periods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	report.ErrorRate = errorRate(report.Total)
+	return report, nil
+}
@@ -0,0 +1,363 @@
+// Package usage counts the API requests, errors and bytes of each organization and API key per
+// UTC day, for customer dashboards and billing. Requests increment counters in Redis, shared by
+// every instance, and a flusher moves them into the api_usage_daily table; monthly figures are
+// rolled up from the daily rows when read.
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+	"openvdo/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	// pendingKey is the set of counter keys not yet flushed
+	pendingKey = "usage:pending"
+	keyPrefix  = "usage:"
+	// counterTTL lets Redis drop counters no flusher picked up, such as after the feature was
+	// turned off
+	counterTTL = 7 * 24 * time.Hour
+	// flushBatch is how many counters one flush statement writes
+	flushBatch = 500
+	dayLayout  = "2006-01-02"
+)
+
+// Counts are the usage of one organization and API key over a period
+type Counts struct {
+	Requests int64 `json:"requests"`
+	// ClientErrors are responses with a 4xx status, ServerErrors those with a 5xx status
+	ClientErrors int64 `json:"client_errors"`
+	ServerErrors int64 `json:"server_errors"`
+	BytesIn      int64 `json:"bytes_in"`
+	BytesOut     int64 `json:"bytes_out"`
+}
+
+func (c *Counts) add(o Counts) {
+	c.Requests += o.Requests
+	c.ClientErrors += o.ClientErrors
+	c.ServerErrors += o.ServerErrors
+	c.BytesIn += o.BytesIn
+	c.BytesOut += o.BytesOut
+}
+
+// fields maps the counts to the hash fields of a Redis counter
+func (c Counts) fields() map[string]int64 {
+	return map[string]int64{
+		"requests":      c.Requests,
+		"client_errors": c.ClientErrors,
+		"server_errors": c.ServerErrors,
+		"bytes_in":      c.BytesIn,
+		"bytes_out":     c.BytesOut,
+	}
+}
+
+func countsFromFields(fields map[string]string) Counts {
+	get := func(name string) int64 {
+		n, _ := strconv.ParseInt(fields[name], 10, 64)
+		return n
+	}
+	return Counts{
+		Requests:     get("requests"),
+		ClientErrors: get("client_errors"),
+		ServerErrors: get("server_errors"),
+		BytesIn:      get("bytes_in"),
+		BytesOut:     get("bytes_out"),
+	}
+}
+
+// counter identifies one row of api_usage_daily. APIKeyID is uuid.Nil for requests made with
+// a user's credentials.
+type counter struct {
+	OrganizationID uuid.UUID
+	APIKeyID       uuid.UUID
+	Day            string
+}
+
+func (k counter) redisKey() string {
+	return keyPrefix + k.Day + ":" + k.OrganizationID.String() + ":" + k.APIKeyID.String()
+}
+
+func parseRedisKey(key string) (counter, error) {
+	parts := strings.Split(strings.TrimPrefix(key, keyPrefix), ":")
+	if len(parts) != 3 {
+		return counter{}, fmt.Errorf("malformed usage key %q", key)
+	}
+	orgID, err := uuid.Parse(parts[1])
+	if err != nil {
+		return counter{}, err
+	}
+	apiKeyID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return counter{}, err
+	}
+	return counter{OrganizationID: orgID, APIKeyID: apiKeyID, Day: parts[0]}, nil
+}
+
+// Recorder counts requests and flushes the counts to Postgres. Without Redis, or while it is
+// unreachable, counts are kept in memory and each instance flushes its own.
+type Recorder struct {
+	db     *sql.DB
+	redis  *redis.Client
+	config config.Usage
+
+	mu    sync.Mutex
+	local map[counter]*Counts
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRecorder creates a recorder. db must not carry a tenant context; redis may be nil.
+func NewRecorder(db *sql.DB, redisClient *redis.Client, cfg config.Usage) *Recorder {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Recorder{
+		db:     db,
+		redis:  redisClient,
+		config: cfg,
+		local:  make(map[counter]*Counts),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Middleware counts each request of an organization once it has been handled. It runs after
+// StatelessDatabaseMiddleware, which resolves the organization; requests without one are not
+// counted.
+func (r *Recorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !r.config.Enabled {
+			c.Next()
+			return
+		}
+		c.Next()
+
+		value, _ := c.Get(string(database.OrgIDKey))
+		orgID, ok := value.(uuid.UUID)
+		if !ok || orgID == uuid.Nil {
+			return
+		}
+		value, _ = c.Get(string(database.APIKeyIDKey))
+		apiKeyID, _ := value.(uuid.UUID)
+
+		counts := Counts{Requests: 1}
+		if status := c.Writer.Status(); status >= 500 {
+			counts.ServerErrors = 1
+		} else if status >= 400 {
+			counts.ClientErrors = 1
+		}
+		if c.Request.ContentLength > 0 {
+			counts.BytesIn = c.Request.ContentLength
+		}
+		if size := c.Writer.Size(); size > 0 {
+			counts.BytesOut = int64(size)
+		}
+
+		r.record(c.Request.Context(), counter{
+			OrganizationID: orgID,
+			APIKeyID:       apiKeyID,
+			Day:            time.Now().UTC().Format(dayLayout),
+		}, counts)
+	}
+}
+
+func (r *Recorder) record(ctx context.Context, k counter, counts Counts) {
+	if r.redis != nil {
+		_, err := r.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for field, n := range counts.fields() {
+				if n != 0 {
+					pipe.HIncrBy(ctx, k.redisKey(), field, n)
+				}
+			}
+			pipe.Expire(ctx, k.redisKey(), counterTTL)
+			pipe.SAdd(ctx, pendingKey, k.redisKey())
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		logger.Error("Failed to count API usage in Redis, keeping it in memory: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.local[k] == nil {
+		r.local[k] = &Counts{}
+	}
+	r.local[k].add(counts)
+}
+
+// Start flushes the counters every FlushInterval until Stop is called
+func (r *Recorder) Start() {
+	if !r.config.Enabled {
+		return
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.config.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.ctx.Done():
+				r.flush()
+				return
+			case <-ticker.C:
+				r.flush()
+			}
+		}
+	}()
+}
+
+// Stop flushes the counters a last time and stops the flusher
+func (r *Recorder) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Recorder) flush() {
+	// The recorder's own context is already cancelled for the last flush on shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.FlushInterval)
+	defer cancel()
+
+	r.mu.Lock()
+	local := r.local
+	r.local = make(map[counter]*Counts)
+	r.mu.Unlock()
+	if len(local) > 0 {
+		if err := r.write(ctx, local); err != nil {
+			logger.Error("Failed to flush API usage: %v", err)
+			r.restoreLocal(local)
+			return
+		}
+	}
+
+	if r.redis != nil {
+		if err := r.flushRedis(ctx); err != nil {
+			logger.Error("Failed to flush API usage from Redis: %v", err)
+		}
+	}
+}
+
+// flushRedis moves counters from Redis to Postgres, flushBatch at a time. Each counter is read
+// and deleted atomically, so increments made meanwhile land in a new counter and are flushed
+// next time. Counters whose write fails are added back.
+func (r *Recorder) flushRedis(ctx context.Context) error {
+	for {
+		keys, err := r.redis.SPopN(ctx, pendingKey, flushBatch).Result()
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		reads := make([]*redis.StringStringMapCmd, len(keys))
+		if _, err := r.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				reads[i] = pipe.HGetAll(ctx, key)
+				pipe.Del(ctx, key)
+			}
+			return nil
+		}); err != nil {
+			r.redis.SAdd(ctx, pendingKey, stringsToArgs(keys)...)
+			return err
+		}
+
+		batch := make(map[counter]*Counts, len(keys))
+		for i, key := range keys {
+			k, err := parseRedisKey(key)
+			if err != nil {
+				logger.Error("Dropping API usage counter: %v", err)
+				continue
+			}
+			counts := countsFromFields(reads[i].Val())
+			if counts == (Counts{}) {
+				continue
+			}
+			batch[k] = &counts
+		}
+
+		if err := r.write(ctx, batch); err != nil {
+			for k, counts := range batch {
+				r.record(ctx, k, *counts)
+			}
+			return err
+		}
+		if len(keys) < flushBatch {
+			return nil
+		}
+	}
+}
+
+func (r *Recorder) restoreLocal(counts map[counter]*Counts) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, c := range counts {
+		if r.local[k] == nil {
+			r.local[k] = &Counts{}
+		}
+		r.local[k].add(*c)
+	}
+}
+
+// write adds the counts to api_usage_daily. Counts of organizations deleted in the meantime are
+// left out rather than failing the batch.
+func (r *Recorder) write(ctx context.Context, batch map[counter]*Counts) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var orgIDs, apiKeyIDs, days []string
+	var requests, clientErrors, serverErrors, bytesIn, bytesOut []int64
+	for k, c := range batch {
+		orgIDs = append(orgIDs, k.OrganizationID.String())
+		apiKeyIDs = append(apiKeyIDs, k.APIKeyID.String())
+		days = append(days, k.Day)
+		requests = append(requests, c.Requests)
+		clientErrors = append(clientErrors, c.ClientErrors)
+		serverErrors = append(serverErrors, c.ServerErrors)
+		bytesIn = append(bytesIn, c.BytesIn)
+		bytesOut = append(bytesOut, c.BytesOut)
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_usage_daily (organization_id, api_key_id, day, requests, client_errors, server_errors,
+			bytes_in, bytes_out)
+		SELECT u.* FROM unnest($1::uuid[], $2::uuid[], $3::date[], $4::bigint[], $5::bigint[], $6::bigint[],
+			$7::bigint[], $8::bigint[])
+			AS u (organization_id, api_key_id, day, requests, client_errors, server_errors, bytes_in, bytes_out)
+		WHERE EXISTS (SELECT 1 FROM organizations o WHERE o.id = u.organization_id)
+		ON CONFLICT (organization_id, day, api_key_id) DO UPDATE SET
+			requests = api_usage_daily.requests + EXCLUDED.requests,
+			client_errors = api_usage_daily.client_errors + EXCLUDED.client_errors,
+			server_errors = api_usage_daily.server_errors + EXCLUDED.server_errors,
+			bytes_in = api_usage_daily.bytes_in + EXCLUDED.bytes_in,
+			bytes_out = api_usage_daily.bytes_out + EXCLUDED.bytes_out,
+			updated_at = NOW()
+	`, pq.Array(orgIDs), pq.Array(apiKeyIDs), pq.Array(days), pq.Array(requests), pq.Array(clientErrors),
+		pq.Array(serverErrors), pq.Array(bytesIn), pq.Array(bytesOut))
+	return err
+}
+
+func stringsToArgs(s []string) []interface{} {
+	args := make([]interface{}, len(s))
+	for i, v := range s {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,18 @@
+-- Drop RLS policies
+DROP POLICY IF EXISTS video_upload_org_access ON video_uploads;
+DROP POLICY IF EXISTS video_org_access ON videos;
+
+-- Drop triggers
+DROP TRIGGER IF EXISTS update_video_uploads_updated_at ON video_uploads;
+DROP TRIGGER IF EXISTS update_videos_updated_at ON videos;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_video_uploads_pending;
+DROP INDEX IF EXISTS idx_video_uploads_video_id;
+DROP INDEX IF EXISTS idx_videos_org_created_at;
+DROP INDEX IF EXISTS idx_videos_project_id;
+DROP INDEX IF EXISTS idx_videos_org_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS video_uploads;
+DROP TABLE IF EXISTS videos;
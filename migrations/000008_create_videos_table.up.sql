@@ -0,0 +1,73 @@
+-- Create videos table for uploaded media within organizations
+CREATE TABLE videos (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    status VARCHAR(50) NOT NULL DEFAULT 'uploading'
+        CHECK (status IN ('uploading', 'uploaded', 'failed')),
+    source_key VARCHAR(1024),
+    content_type VARCHAR(255),
+    size_bytes BIGINT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create video_uploads table to track multipart uploads handed to clients
+CREATE TABLE video_uploads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    storage_key VARCHAR(1024) NOT NULL,
+    upload_id VARCHAR(1024) NOT NULL,
+    part_size BIGINT NOT NULL,
+    part_count INTEGER NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'completed', 'aborted')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes for videos and video_uploads tables
+CREATE INDEX idx_videos_org_id ON videos(organization_id);
+CREATE INDEX idx_videos_project_id ON videos(project_id);
+CREATE INDEX idx_videos_org_created_at ON videos(organization_id, created_at DESC);
+CREATE INDEX idx_video_uploads_video_id ON video_uploads(video_id);
+CREATE INDEX idx_video_uploads_pending ON video_uploads(expires_at) WHERE status = 'pending';
+
+-- Add triggers for updated_at timestamp
+CREATE TRIGGER update_videos_updated_at
+    BEFORE UPDATE ON videos
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_video_uploads_updated_at
+    BEFORE UPDATE ON video_uploads
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Enable Row Level Security so videos follow organization membership
+ALTER TABLE videos ENABLE ROW LEVEL SECURITY;
+ALTER TABLE video_uploads ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY video_org_access ON videos
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+CREATE POLICY video_upload_org_access ON video_uploads
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
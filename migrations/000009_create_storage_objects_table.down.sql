@@ -0,0 +1,14 @@
+-- Drop RLS policy
+DROP POLICY IF EXISTS storage_object_org_access ON storage_objects;
+
+-- Drop trigger
+DROP TRIGGER IF EXISTS update_storage_objects_updated_at ON storage_objects;
+
+-- Drop indexes
+DROP INDEX IF EXISTS idx_storage_objects_restoring;
+DROP INDEX IF EXISTS idx_storage_objects_hot_accessed;
+DROP INDEX IF EXISTS idx_storage_objects_video_id;
+DROP INDEX IF EXISTS idx_storage_objects_org_id;
+
+-- Drop storage_objects table
+DROP TABLE IF EXISTS storage_objects;
@@ -0,0 +1,43 @@
+-- Create storage_objects table to track the storage tier of every stored file
+CREATE TABLE storage_objects (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    video_id UUID REFERENCES videos(id) ON DELETE CASCADE,
+    object_key VARCHAR(1024) NOT NULL UNIQUE,
+    kind VARCHAR(50) NOT NULL CHECK (kind IN ('source', 'rendition', 'thumbnail', 'image')),
+    size_bytes BIGINT,
+    tier_status VARCHAR(50) NOT NULL DEFAULT 'hot'
+        CHECK (tier_status IN ('hot', 'archiving', 'archived', 'restoring', 'restored')),
+    storage_class VARCHAR(50) NOT NULL DEFAULT 'STANDARD',
+    last_accessed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE,
+    restore_requested_at TIMESTAMP WITH TIME ZONE,
+    restore_expires_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes for storage_objects table
+CREATE INDEX idx_storage_objects_org_id ON storage_objects(organization_id);
+CREATE INDEX idx_storage_objects_video_id ON storage_objects(video_id);
+CREATE INDEX idx_storage_objects_hot_accessed ON storage_objects(kind, last_accessed_at) WHERE tier_status = 'hot';
+CREATE INDEX idx_storage_objects_restoring ON storage_objects(restore_requested_at) WHERE tier_status IN ('restoring', 'restored');
+
+-- Add trigger for updated_at timestamp
+CREATE TRIGGER update_storage_objects_updated_at
+    BEFORE UPDATE ON storage_objects
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Enable Row Level Security so storage objects follow organization membership
+ALTER TABLE storage_objects ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY storage_object_org_access ON storage_objects
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,6 @@
+-- Drop content hash index
+DROP INDEX IF EXISTS idx_videos_org_sha256;
+
+-- Drop content hash columns
+ALTER TABLE videos DROP COLUMN IF EXISTS duplicate_of;
+ALTER TABLE videos DROP COLUMN IF EXISTS sha256;
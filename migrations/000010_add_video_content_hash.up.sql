@@ -0,0 +1,6 @@
+-- Add content hash columns for integrity checks and duplicate detection
+ALTER TABLE videos ADD COLUMN sha256 CHAR(64);
+ALTER TABLE videos ADD COLUMN duplicate_of UUID REFERENCES videos(id) ON DELETE SET NULL;
+
+-- For finding identical uploads within an organization
+CREATE INDEX idx_videos_org_sha256 ON videos(organization_id, sha256) WHERE sha256 IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Drop edit versions
+ALTER TABLE organizations DROP COLUMN IF EXISTS version;
+ALTER TABLE videos DROP COLUMN IF EXISTS version;
@@ -0,0 +1,3 @@
+-- Add edit versions for optimistic concurrency: updates must name the version they are based on
+ALTER TABLE videos ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+ALTER TABLE organizations ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
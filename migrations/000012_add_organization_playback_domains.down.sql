@@ -0,0 +1,3 @@
+-- Drop playback domains
+DROP INDEX IF EXISTS idx_organizations_playback_domains;
+ALTER TABLE organizations DROP COLUMN IF EXISTS playback_domains;
@@ -0,0 +1,4 @@
+-- Add the origins each organization embeds its videos on; they are allowed read-only cross-origin access
+ALTER TABLE organizations ADD COLUMN playback_domains TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_organizations_playback_domains ON organizations USING GIN (playback_domains);
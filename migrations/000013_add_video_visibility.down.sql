@@ -0,0 +1,2 @@
+-- Drop video visibility
+ALTER TABLE videos DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,3 @@
+-- Add visibility to videos: public and unlisted videos play without a token, private ones need a signed playback token
+ALTER TABLE videos ADD COLUMN visibility VARCHAR(20) NOT NULL DEFAULT 'private'
+    CHECK (visibility IN ('public', 'unlisted', 'private'));
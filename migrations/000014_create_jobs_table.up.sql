@@ -0,0 +1,42 @@
+-- Create jobs table: a Postgres-backed queue for background work such as URL imports.
+-- Workers claim jobs with FOR UPDATE SKIP LOCKED and hold a lease they renew while running.
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+    progress REAL NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    last_error TEXT,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_by VARCHAR(255),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_jobs_ready ON jobs(run_at) WHERE status = 'queued';
+CREATE INDEX idx_jobs_expired_leases ON jobs(locked_until) WHERE status = 'running';
+CREATE INDEX idx_jobs_org_created_at ON jobs(organization_id, created_at DESC);
+
+CREATE TRIGGER update_jobs_updated_at
+    BEFORE UPDATE ON jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members see the jobs of their organizations; workers use the master connection
+ALTER TABLE jobs ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY job_org_access ON jobs
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
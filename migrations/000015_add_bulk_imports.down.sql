@@ -0,0 +1,7 @@
+-- Drop video tags and job checkpoints
+DROP INDEX IF EXISTS idx_videos_tags;
+ALTER TABLE videos DROP COLUMN IF EXISTS tags;
+
+DROP INDEX IF EXISTS idx_jobs_parent_id;
+ALTER TABLE jobs DROP COLUMN IF EXISTS checkpoint;
+ALTER TABLE jobs DROP COLUMN IF EXISTS parent_id;
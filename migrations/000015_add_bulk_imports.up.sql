@@ -0,0 +1,10 @@
+-- Let jobs spawn child jobs and keep a checkpoint to resume from, for bulk imports
+ALTER TABLE jobs ADD COLUMN parent_id UUID REFERENCES jobs(id) ON DELETE SET NULL;
+ALTER TABLE jobs ADD COLUMN checkpoint JSONB;
+
+CREATE INDEX idx_jobs_parent_id ON jobs(parent_id) WHERE parent_id IS NOT NULL;
+
+-- Add free-form tags to videos, set by bulk import manifests and editable through the API
+ALTER TABLE videos ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_videos_tags ON videos USING GIN (tags);
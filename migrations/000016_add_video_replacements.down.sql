@@ -0,0 +1,7 @@
+-- Drop source replacement tracking
+ALTER TABLE videos DROP COLUMN IF EXISTS replaced_at;
+ALTER TABLE videos DROP COLUMN IF EXISTS source_revision;
+
+ALTER TABLE video_uploads DROP COLUMN IF EXISTS size_bytes;
+ALTER TABLE video_uploads DROP COLUMN IF EXISTS content_type;
+ALTER TABLE video_uploads DROP COLUMN IF EXISTS replaces_key;
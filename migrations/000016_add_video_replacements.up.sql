@@ -0,0 +1,9 @@
+-- Let a multipart upload replace the source of an existing video. The new size and type are
+-- kept on the upload until it completes, so the video plays its current source meanwhile.
+ALTER TABLE video_uploads ADD COLUMN replaces_key VARCHAR(1024);
+ALTER TABLE video_uploads ADD COLUMN content_type VARCHAR(255);
+ALTER TABLE video_uploads ADD COLUMN size_bytes BIGINT;
+
+-- Count source replacements, so caches and range requests can tell sources apart
+ALTER TABLE videos ADD COLUMN source_revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE videos ADD COLUMN replaced_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,2 @@
+-- Drop video thumbnails
+ALTER TABLE videos DROP COLUMN IF EXISTS thumbnail;
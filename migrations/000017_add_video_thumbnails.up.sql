@@ -0,0 +1,2 @@
+-- Store each video's thumbnail, with the keys of its resized variants, as JSON
+ALTER TABLE videos ADD COLUMN thumbnail JSONB;
@@ -0,0 +1,3 @@
+-- Drop user avatars and organization banners
+ALTER TABLE organizations DROP COLUMN IF EXISTS banner;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar;
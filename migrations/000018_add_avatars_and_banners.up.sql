@@ -0,0 +1,3 @@
+-- Store user avatars and organization banners, with the keys of their resized variants, as JSON
+ALTER TABLE users ADD COLUMN avatar JSONB;
+ALTER TABLE organizations ADD COLUMN banner JSONB;
@@ -0,0 +1,3 @@
+-- Drop notifications
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
@@ -0,0 +1,52 @@
+-- Per-user notifications, shown in-app and delivered by email or push in the background
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    data JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_created_at ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+
+-- Which channels each user wants per notification type; missing rows use the defaults
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    email BOOLEAN NOT NULL,
+    push BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, type)
+);
+
+CREATE TRIGGER update_notification_preferences_updated_at
+    BEFORE UPDATE ON notification_preferences
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Users see only their own notifications and preferences. Members may also create notifications
+-- for others in their organizations, e.g. when inviting them; delivery uses the master connection.
+ALTER TABLE notifications ENABLE ROW LEVEL SECURITY;
+ALTER TABLE notification_preferences ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY notification_owner_access ON notifications
+  FOR ALL
+  USING (user_id = current_setting('app.current_user_id', true)::uuid);
+
+CREATE POLICY notification_org_insert ON notifications
+  FOR INSERT
+  WITH CHECK (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+CREATE POLICY notification_preference_owner_access ON notification_preferences
+  FOR ALL
+  USING (user_id = current_setting('app.current_user_id', true)::uuid);
@@ -0,0 +1,2 @@
+-- Drop push devices
+DROP TABLE IF EXISTS push_devices;
@@ -0,0 +1,30 @@
+-- Devices and browsers that receive push notifications. The token is the FCM registration
+-- token, the APNs device token, or the Web Push subscription endpoint, which also needs the
+-- subscription's encryption keys.
+CREATE TABLE push_devices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform VARCHAR(10) NOT NULL CHECK (platform IN ('web', 'fcm', 'apns')),
+    token TEXT NOT NULL,
+    p256dh TEXT,
+    auth TEXT,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (user_id, platform, token),
+    CHECK (platform <> 'web' OR (p256dh IS NOT NULL AND auth IS NOT NULL))
+);
+
+CREATE INDEX idx_push_devices_user_id ON push_devices(user_id);
+
+CREATE TRIGGER update_push_devices_updated_at
+    BEFORE UPDATE ON push_devices
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Users manage only their own devices; delivery uses the master connection
+ALTER TABLE push_devices ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY push_device_owner_access ON push_devices
+  FOR ALL
+  USING (user_id = current_setting('app.current_user_id', true)::uuid);
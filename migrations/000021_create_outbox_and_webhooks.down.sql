@@ -0,0 +1,3 @@
+-- Drop the outbox and webhook endpoints
+DROP TABLE IF EXISTS webhook_endpoints;
+DROP TABLE IF EXISTS outbox_events;
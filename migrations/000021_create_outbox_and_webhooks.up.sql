@@ -0,0 +1,62 @@
+-- Domain events, written in the same transaction as the change they describe and published
+-- to Redis and webhooks by the relay afterwards
+CREATE TABLE outbox_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    sequence BIGSERIAL NOT NULL UNIQUE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    type VARCHAR(100) NOT NULL,
+    subject_id UUID,
+    data JSONB NOT NULL DEFAULT '{}',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_outbox_events_pending ON outbox_events(sequence) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_events_published_at ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+-- Endpoints organizations receive events on, signed with their secret
+CREATE TABLE webhook_endpoints (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_endpoints_organization_id ON webhook_endpoints(organization_id);
+
+CREATE TRIGGER update_webhook_endpoints_updated_at
+    BEFORE UPDATE ON webhook_endpoints
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members write events for their organizations but cannot read the outbox; the relay uses the
+-- master connection
+ALTER TABLE outbox_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE webhook_endpoints ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY outbox_event_org_insert ON outbox_events
+  FOR INSERT
+  WITH CHECK (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+CREATE POLICY webhook_endpoint_org_access ON webhook_endpoints
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,3 @@
+-- Drop feature flags and their overrides
+DROP TABLE IF EXISTS feature_flag_overrides;
+DROP TABLE IF EXISTS feature_flags;
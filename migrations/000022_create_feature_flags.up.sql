@@ -0,0 +1,30 @@
+-- Platform-wide feature flags. A disabled flag is off everywhere; an enabled one is on for the
+-- organizations it is overridden for and for percentage of the rest.
+CREATE TABLE feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    percentage INTEGER NOT NULL DEFAULT 0 CHECK (percentage BETWEEN 0 AND 100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Organizations a flag is forced on or off for, regardless of the rollout percentage
+CREATE TABLE feature_flag_overrides (
+    flag_key VARCHAR(100) NOT NULL REFERENCES feature_flags(key) ON DELETE CASCADE ON UPDATE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (flag_key, organization_id)
+);
+
+CREATE INDEX idx_feature_flag_overrides_organization_id ON feature_flag_overrides(organization_id);
+
+CREATE TRIGGER update_feature_flags_updated_at
+    BEFORE UPDATE ON feature_flags
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Flags are managed through the admin API and evaluated on the master connection; tenants have
+-- no access
+ALTER TABLE feature_flags ENABLE ROW LEVEL SECURITY;
+ALTER TABLE feature_flag_overrides ENABLE ROW LEVEL SECURITY;
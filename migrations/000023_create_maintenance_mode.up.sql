@@ -0,0 +1,18 @@
+-- Fleet-wide maintenance mode; the single row is polled by every API instance
+CREATE TABLE maintenance_mode (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    message VARCHAR(500) NOT NULL DEFAULT '',
+    retry_after_seconds INTEGER NOT NULL DEFAULT 60 CHECK (retry_after_seconds >= 0),
+    started_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO maintenance_mode DEFAULT VALUES;
+
+CREATE TRIGGER update_maintenance_mode_updated_at
+    BEFORE UPDATE ON maintenance_mode
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Read and changed on the master connection only; tenants have no access
+ALTER TABLE maintenance_mode ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,48 @@
+-- Scope content by membership again and drop the organization context
+DROP POLICY job_org_access ON jobs;
+CREATE POLICY job_org_access ON jobs
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+DROP POLICY project_org_access ON projects;
+CREATE POLICY project_org_access ON projects
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+DROP POLICY video_upload_org_access ON video_uploads;
+CREATE POLICY video_upload_org_access ON video_uploads
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+DROP POLICY video_org_access ON videos;
+CREATE POLICY video_org_access ON videos
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+DROP FUNCTION IF EXISTS current_org_role();
+DROP FUNCTION IF EXISTS current_org_id();
+ALTER TABLE users DROP COLUMN IF EXISTS default_organization_id;
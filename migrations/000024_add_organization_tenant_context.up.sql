@@ -0,0 +1,63 @@
+-- The organization a user's requests act in when they do not name one with X-Org-ID
+ALTER TABLE users ADD COLUMN default_organization_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+-- The application sets app.current_org_id and app.current_role next to app.current_user_id.
+-- Both are empty for users without an organization.
+CREATE OR REPLACE FUNCTION current_org_id() RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.current_org_id', true), '')::uuid
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION current_org_role() RETURNS TEXT AS $$
+    SELECT NULLIF(current_setting('app.current_role', true), '')
+$$ LANGUAGE sql STABLE;
+
+-- Content is scoped to the organization a request acts in, so a user in several organizations
+-- only sees one at a time. Organizations, memberships, webhooks and storage objects stay
+-- scoped by membership, since they are managed through the organization in the URL.
+DROP POLICY video_org_access ON videos;
+CREATE POLICY video_org_access ON videos
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+DROP POLICY video_upload_org_access ON video_uploads;
+CREATE POLICY video_upload_org_access ON video_uploads
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+DROP POLICY project_org_access ON projects;
+CREATE POLICY project_org_access ON projects
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+DROP POLICY job_org_access ON jobs;
+CREATE POLICY job_org_access ON jobs
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,3 @@
+-- Drop organization regions
+DROP INDEX IF EXISTS idx_organizations_region;
+ALTER TABLE organizations DROP COLUMN IF EXISTS region;
@@ -0,0 +1,5 @@
+-- Pin organizations to a regional database and storage cluster; NULL keeps them in the home region
+ALTER TABLE organizations ADD COLUMN region VARCHAR(50)
+    CHECK (region ~ '^[a-z0-9][a-z0-9-]*$');
+
+CREATE INDEX idx_organizations_region ON organizations(region) WHERE region IS NOT NULL;
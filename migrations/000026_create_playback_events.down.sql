@@ -0,0 +1,2 @@
+-- Drop playback events
+DROP TABLE IF EXISTS playback_events;
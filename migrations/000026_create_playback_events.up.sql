@@ -0,0 +1,42 @@
+-- Quality-of-experience events reported by players through the beacon endpoint. The table is
+-- wide and append-only: one row per event with the measurements of every event type, so
+-- dashboards aggregate it without joins.
+CREATE TABLE playback_events (
+    id BIGSERIAL PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- No foreign key: a video deleted while its events are buffered must not fail their batch
+    video_id UUID NOT NULL,
+    -- session_id is generated by the player and groups the events of one playback
+    session_id VARCHAR(64) NOT NULL,
+    type VARCHAR(20) NOT NULL
+        CHECK (type IN ('startup', 'rebuffer', 'bitrate_switch', 'error', 'heartbeat', 'end')),
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    position_seconds DOUBLE PRECISION,
+    startup_ms INTEGER,
+    rebuffer_count INTEGER,
+    rebuffer_ms INTEGER,
+    bitrate_kbps INTEGER,
+    previous_bitrate_kbps INTEGER,
+    error_code VARCHAR(100),
+    error_message VARCHAR(500),
+    player VARCHAR(100),
+    user_agent VARCHAR(500),
+    sample_rate REAL NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_playback_events_org_occurred_at ON playback_events(organization_id, occurred_at);
+CREATE INDEX idx_playback_events_video_occurred_at ON playback_events(video_id, occurred_at);
+
+-- Members read their organizations' events; the beacon writes them through the master connection
+ALTER TABLE playback_events ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY playback_event_org_read ON playback_events
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,2 @@
+-- Drop API usage counts
+DROP TABLE IF EXISTS api_usage_daily;
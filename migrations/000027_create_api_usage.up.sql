@@ -0,0 +1,30 @@
+-- API requests, errors and bytes per organization, API key and UTC day, flushed from the
+-- Redis counters API instances increment. Monthly figures are rolled up from these rows.
+CREATE TABLE api_usage_daily (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- The nil UUID counts requests made with a user's credentials rather than an API key; API
+    -- keys are not referenced, so their usage outlives them for billing
+    api_key_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000',
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    client_errors BIGINT NOT NULL DEFAULT 0,
+    server_errors BIGINT NOT NULL DEFAULT 0,
+    bytes_in BIGINT NOT NULL DEFAULT 0,
+    bytes_out BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (organization_id, day, api_key_id)
+);
+
+-- Members read their organizations' usage; the flusher writes it through the master connection
+ALTER TABLE api_usage_daily ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY api_usage_org_read ON api_usage_daily
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,4 @@
+-- Drop takedowns, abuse reports and the moderation state of videos
+DROP TABLE IF EXISTS takedowns;
+DROP TABLE IF EXISTS abuse_reports;
+ALTER TABLE videos DROP COLUMN IF EXISTS moderation_status;
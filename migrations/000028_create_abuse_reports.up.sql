@@ -0,0 +1,89 @@
+-- Moderation state of videos: restricted videos were unpublished automatically after enough
+-- reports and wait for review, taken down ones were removed by a moderator. Neither plays.
+ALTER TABLE videos ADD COLUMN moderation_status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (moderation_status IN ('active', 'restricted', 'taken_down'));
+
+-- Viewers' reports of videos. reporter_key identifies the reporter, as the user ID or a hash of
+-- the client address, so one reporter cannot push a video over the threshold alone.
+CREATE TABLE abuse_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    reason VARCHAR(20) NOT NULL
+        CHECK (reason IN ('copyright', 'sexual', 'violence', 'harassment', 'hateful', 'spam', 'other')),
+    details VARCHAR(2000) NOT NULL DEFAULT '',
+    reporter_key VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'actioned')),
+    resolution_note VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_abuse_reports_open_reporter ON abuse_reports(video_id, reporter_key) WHERE status = 'open';
+CREATE INDEX idx_abuse_reports_open ON abuse_reports(created_at) WHERE status = 'open';
+
+-- Takedowns and, for DMCA takedowns, the uploader's counter-notice. A counter-noticed takedown
+-- may be restored once restore_after has passed unless the claimant took legal action.
+CREATE TABLE takedowns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('dmca', 'policy')),
+    reason VARCHAR(1000) NOT NULL DEFAULT '',
+    claimant VARCHAR(500) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'counter_noticed', 'restored', 'upheld')),
+    counter_notice_statement VARCHAR(5000),
+    counter_notice_contact VARCHAR(500),
+    counter_noticed_at TIMESTAMP WITH TIME ZONE,
+    restore_after TIMESTAMP WITH TIME ZONE,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- A video has at most one takedown in effect
+CREATE UNIQUE INDEX idx_takedowns_video_current ON takedowns(video_id) WHERE status IN ('active', 'counter_noticed');
+CREATE INDEX idx_takedowns_status ON takedowns(status, created_at);
+
+CREATE TRIGGER update_takedowns_updated_at
+    BEFORE UPDATE ON takedowns
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members see the reports and takedowns of their organizations' videos; reports are filed and
+-- resolved through the master connection
+ALTER TABLE abuse_reports ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY abuse_report_org_read ON abuse_reports
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+ALTER TABLE takedowns ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY takedown_org_read ON takedowns
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+-- Owners and admins file counter-notices on their organizations' takedowns
+CREATE POLICY takedown_org_counter_notice ON takedowns
+  FOR UPDATE
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+  );
@@ -0,0 +1,2 @@
+-- Drop the results of automatic content scans
+DROP TABLE IF EXISTS moderation_scans;
@@ -0,0 +1,31 @@
+-- Results of automatic content scans. scores holds the highest score of each category the
+-- provider returned over the sampled frames and audio; flagged lists the categories at or above
+-- the threshold, which filed a report for review.
+CREATE TABLE moderation_scans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    source_key VARCHAR(1024) NOT NULL,
+    frames INTEGER NOT NULL DEFAULT 0,
+    audio_seconds INTEGER NOT NULL DEFAULT 0,
+    scores JSONB NOT NULL DEFAULT '{}',
+    flagged TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_moderation_scans_video ON moderation_scans(video_id, created_at);
+
+-- Members see the scans of their organizations' videos; scans are written through the master
+-- connection
+ALTER TABLE moderation_scans ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY moderation_scan_org_read ON moderation_scans
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
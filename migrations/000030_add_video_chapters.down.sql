@@ -0,0 +1,2 @@
+-- Drop video chapters
+ALTER TABLE videos DROP COLUMN IF EXISTS chapters;
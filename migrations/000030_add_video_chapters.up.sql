@@ -0,0 +1,2 @@
+-- Chapters of each video's timeline as a JSON array of {start_seconds, title}, ordered by start
+ALTER TABLE videos ADD COLUMN chapters JSONB NOT NULL DEFAULT '[]';
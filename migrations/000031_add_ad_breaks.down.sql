@@ -0,0 +1,3 @@
+-- Drop ad breaks and VAST ad tags
+ALTER TABLE organizations DROP COLUMN IF EXISTS vast_tag_url;
+ALTER TABLE videos DROP COLUMN IF EXISTS ad_breaks;
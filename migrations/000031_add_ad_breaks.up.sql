@@ -0,0 +1,4 @@
+-- Ad break cue points of each video as a JSON array of {offset_seconds, duration_seconds},
+-- ordered by offset, and the VAST ad tag players of an organization's videos request ads from
+ALTER TABLE videos ADD COLUMN ad_breaks JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE organizations ADD COLUMN vast_tag_url VARCHAR(2048);
@@ -0,0 +1,2 @@
+-- Drop playback sessions
+DROP TABLE IF EXISTS playback_sessions;
@@ -0,0 +1,36 @@
+-- Playback sessions of signed-in viewers. A session stays active until expires_at, which each
+-- heartbeat of the player pushes back; active sessions count against concurrent-stream limits,
+-- and the position and watch time reported by heartbeats feed analytics.
+CREATE TABLE playback_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    position_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    watched_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+    heartbeats INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_heartbeat_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_playback_sessions_user_open ON playback_sessions(user_id, expires_at) WHERE ended_at IS NULL;
+CREATE INDEX idx_playback_sessions_video ON playback_sessions(video_id, started_at);
+CREATE INDEX idx_playback_sessions_org ON playback_sessions(organization_id, started_at);
+
+-- Members see the sessions of their organizations; heartbeats are limited to the session's
+-- viewer by the API
+ALTER TABLE playback_sessions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY playback_session_org_access ON playback_sessions
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,3 @@
+-- Drop entitlements
+DROP TABLE IF EXISTS entitlements;
+ALTER TABLE videos DROP COLUMN IF EXISTS requires_entitlement;
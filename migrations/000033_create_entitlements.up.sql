@@ -0,0 +1,42 @@
+-- Entitlements grant a user playback of one video, or of every video of a project, until
+-- expires_at (NULL never expires). Videos that require an entitlement only play for viewers
+-- holding one; owners, admins and developers of the organization always have access.
+ALTER TABLE videos ADD COLUMN requires_entitlement BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE entitlements (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    video_id UUID REFERENCES videos(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    -- reference ties the grant to an order, rental or enrollment in the caller's system
+    reference VARCHAR(255) NOT NULL DEFAULT '',
+    granted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((video_id IS NULL) <> (project_id IS NULL)),
+    UNIQUE (user_id, video_id),
+    UNIQUE (user_id, project_id)
+);
+
+CREATE INDEX idx_entitlements_org ON entitlements(organization_id, created_at);
+CREATE INDEX idx_entitlements_video ON entitlements(video_id) WHERE video_id IS NOT NULL;
+CREATE INDEX idx_entitlements_project ON entitlements(project_id) WHERE project_id IS NOT NULL;
+
+CREATE TRIGGER update_entitlements_updated_at
+    BEFORE UPDATE ON entitlements
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members of the organization see its grants, which the API lets owners and admins change
+ALTER TABLE entitlements ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY entitlement_org_access ON entitlements
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
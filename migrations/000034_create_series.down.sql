@@ -0,0 +1,3 @@
+-- Drop series and their episodes
+DROP TABLE IF EXISTS series_episodes;
+DROP TABLE IF EXISTS series;
@@ -0,0 +1,57 @@
+-- Series group videos into ordered episodes, numbered within seasons, for courses and
+-- episodic shows. A video is an episode of at most one series, so playback can name the next
+-- episode without ambiguity.
+CREATE TABLE series (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    project_id UUID REFERENCES projects(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    artwork JSONB,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    version BIGINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_series_org ON series(organization_id, created_at);
+
+CREATE TRIGGER update_series_updated_at
+    BEFORE UPDATE ON series
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE series_episodes (
+    series_id UUID NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    video_id UUID NOT NULL UNIQUE REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    season_number INTEGER NOT NULL DEFAULT 1 CHECK (season_number >= 0),
+    episode_number INTEGER NOT NULL CHECK (episode_number > 0),
+    PRIMARY KEY (series_id, video_id),
+    UNIQUE (series_id, season_number, episode_number)
+);
+
+-- Series are content, scoped to the organization a request acts in like videos
+ALTER TABLE series ENABLE ROW LEVEL SECURITY;
+ALTER TABLE series_episodes ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY series_org_access ON series
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+CREATE POLICY series_episode_org_access ON series_episodes
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,2 @@
+-- Drop the stored project feeds
+DROP TABLE IF EXISTS feeds;
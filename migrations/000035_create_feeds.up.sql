@@ -0,0 +1,26 @@
+-- Feeds are the rendered public catalog documents of a project: MRSS, JSON Feed and podcast
+-- RSS. They are regenerated when a video of the project changes and served as stored, so feed
+-- readers and CDNs never cause a query over the project's videos.
+CREATE TABLE feeds (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    format VARCHAR(16) NOT NULL CHECK (format IN ('mrss', 'json', 'podcast')),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    body BYTEA NOT NULL,
+    -- etag is derived from the body, so regenerating an unchanged feed keeps caches valid
+    etag VARCHAR(64) NOT NULL,
+    generated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (project_id, format)
+);
+
+-- Feeds are served publicly through the master connection; tenants only see their own
+ALTER TABLE feeds ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY feed_org_access ON feeds
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
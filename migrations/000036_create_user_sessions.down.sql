@@ -0,0 +1,2 @@
+-- Drop user sessions
+DROP TABLE IF EXISTS user_sessions;
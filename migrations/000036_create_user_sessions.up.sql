@@ -0,0 +1,22 @@
+-- Sign-in sessions of users. Clients hold an opaque bearer token, of which only the SHA-256 is
+-- stored, so the table cannot be used to sign in as anyone. Revoking a session deletes it;
+-- expired sessions are deleted when their user signs in again.
+CREATE TABLE user_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id, last_seen_at DESC);
+
+-- Users list their own sessions; sign-in and token checks use the master connection
+ALTER TABLE user_sessions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY user_session_owner_access ON user_sessions
+  FOR ALL
+  USING (user_id = current_setting('app.current_user_id', true)::uuid);
@@ -0,0 +1,39 @@
+-- Security-relevant actions and refusals per organization, such as changes to its IP access
+-- rules and the requests they denied. Entries are only ever added.
+CREATE TABLE audit_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(100) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_log_organization_created_at ON audit_log(organization_id, created_at DESC);
+
+-- Owners and admins read their organizations' entries; denials are written with the master
+-- connection, as the requests they refuse never get a tenant one
+ALTER TABLE audit_log ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY audit_log_admin_read ON audit_log
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+  );
+
+CREATE POLICY audit_log_admin_insert ON audit_log
+  FOR INSERT
+  WITH CHECK (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+  );
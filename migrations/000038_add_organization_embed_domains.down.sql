@@ -0,0 +1,2 @@
+-- Drop embed domains
+ALTER TABLE organizations DROP COLUMN IF EXISTS embed_domains;
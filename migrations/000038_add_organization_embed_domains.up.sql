@@ -0,0 +1,3 @@
+-- Add the domains each organization allows to embed its videos, as CSP host sources such as
+-- https://*.example.com; empty allows embedding anywhere
+ALTER TABLE organizations ADD COLUMN embed_domains TEXT[] NOT NULL DEFAULT '{}';
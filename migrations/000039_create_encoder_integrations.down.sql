@@ -0,0 +1,3 @@
+-- Drop encoder integrations and external renditions
+DROP TABLE IF EXISTS video_renditions;
+DROP TABLE IF EXISTS encoder_integrations;
@@ -0,0 +1,66 @@
+-- External encoders, such as AWS MediaConvert or Mux, that notify organizations of finished
+-- renditions. Notifications are signed with the integration's secret.
+CREATE TABLE encoder_integrations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    secret TEXT NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    last_received_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_encoder_integrations_organization_id ON encoder_integrations(organization_id);
+
+CREATE TRIGGER update_encoder_integrations_updated_at
+    BEFORE UPDATE ON encoder_integrations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Renditions of videos hosted outside OpenVDO's storage, one per URL
+CREATE TABLE video_renditions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    integration_id UUID REFERENCES encoder_integrations(id) ON DELETE SET NULL,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    url TEXT NOT NULL,
+    content_type VARCHAR(100) NOT NULL DEFAULT '',
+    width INTEGER CHECK (width > 0),
+    height INTEGER CHECK (height > 0),
+    bitrate_kbps INTEGER CHECK (bitrate_kbps > 0),
+    codec VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (video_id, url)
+);
+
+CREATE TRIGGER update_video_renditions_updated_at
+    BEFORE UPDATE ON video_renditions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members see their organizations' integrations and renditions; notifications are stored
+-- through the master connection, as encoders are not users
+ALTER TABLE encoder_integrations ENABLE ROW LEVEL SECURITY;
+ALTER TABLE video_renditions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY encoder_integration_org_access ON encoder_integrations
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+CREATE POLICY video_rendition_org_access ON video_renditions
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,3 @@
+-- Drop transcode jobs and the organization transcoder
+DROP TABLE IF EXISTS transcode_jobs;
+ALTER TABLE organizations DROP COLUMN IF EXISTS transcoder;
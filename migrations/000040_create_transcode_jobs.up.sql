@@ -0,0 +1,40 @@
+-- Transcoder each organization's sources go through; NULL follows TRANSCODE_PROVIDER
+ALTER TABLE organizations ADD COLUMN transcoder VARCHAR(50);
+
+-- Jobs submitted to external transcoders, updated by polling and by the transcoders' callbacks
+CREATE TABLE transcode_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    external_id VARCHAR(255) NOT NULL,
+    source_revision INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'submitted'
+        CHECK (status IN ('submitted', 'progressing', 'complete', 'failed')),
+    progress DOUBLE PRECISION NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (provider, external_id)
+);
+
+CREATE INDEX idx_transcode_jobs_video_id ON transcode_jobs(video_id, created_at DESC);
+
+CREATE TRIGGER update_transcode_jobs_updated_at
+    BEFORE UPDATE ON transcode_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members read their organizations' jobs; workers write them through the master connection
+ALTER TABLE transcode_jobs ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY transcode_job_org_read ON transcode_jobs
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
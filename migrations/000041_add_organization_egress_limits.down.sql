@@ -0,0 +1,2 @@
+-- Drop egress limits
+ALTER TABLE organizations DROP COLUMN IF EXISTS egress_limit;
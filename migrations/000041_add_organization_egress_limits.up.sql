@@ -0,0 +1,3 @@
+-- Bytes per second each organization's media is served at, per instance; NULL follows
+-- EGRESS_DEFAULT_LIMIT and 0 is unlimited
+ALTER TABLE organizations ADD COLUMN egress_limit BIGINT CHECK (egress_limit >= 0);
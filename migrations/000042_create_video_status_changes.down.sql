@@ -0,0 +1,12 @@
+-- Drop status history and fold the new states back into uploaded
+DROP TABLE IF EXISTS video_status_changes;
+DROP INDEX IF EXISTS idx_videos_status_changed_at;
+
+ALTER TABLE videos DISABLE TRIGGER update_videos_updated_at;
+ALTER TABLE videos DROP COLUMN IF EXISTS status_changed_at;
+ALTER TABLE videos DROP CONSTRAINT IF EXISTS videos_status_check;
+-- Deleted videos become failed, which do not play either
+UPDATE videos SET status = 'failed' WHERE status = 'deleted';
+UPDATE videos SET status = 'uploaded' WHERE status IN ('queued', 'processing', 'ready');
+ALTER TABLE videos ADD CONSTRAINT videos_status_check CHECK (status IN ('uploading', 'uploaded', 'failed'));
+ALTER TABLE videos ENABLE TRIGGER update_videos_updated_at;
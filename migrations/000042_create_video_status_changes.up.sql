@@ -0,0 +1,45 @@
+-- Videos move through explicit states: uploaded becomes ready, and videos wait as queued and
+-- processing while a transcoder works on them. Existing rows keep their updated_at.
+ALTER TABLE videos DISABLE TRIGGER update_videos_updated_at;
+
+ALTER TABLE videos DROP CONSTRAINT IF EXISTS videos_status_check;
+UPDATE videos SET status = 'ready' WHERE status = 'uploaded';
+ALTER TABLE videos ADD CONSTRAINT videos_status_check
+    CHECK (status IN ('uploading', 'queued', 'processing', 'ready', 'failed', 'deleted'));
+
+-- When each video entered its status, which shows videos stuck in one
+ALTER TABLE videos ADD COLUMN status_changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+UPDATE videos SET status_changed_at = updated_at;
+
+ALTER TABLE videos ENABLE TRIGGER update_videos_updated_at;
+
+CREATE INDEX idx_videos_status_changed_at ON videos(status, status_changed_at)
+    WHERE status IN ('uploading', 'queued', 'processing');
+
+-- Every status change of a video, for debugging videos that did not become ready
+CREATE TABLE video_status_changes (
+    id BIGSERIAL PRIMARY KEY,
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    from_status VARCHAR(50) NOT NULL,
+    to_status VARCHAR(50) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_video_status_changes_video_id ON video_status_changes(video_id, id);
+
+-- Members change and read their organizations' videos; workers record changes through the
+-- master connection
+ALTER TABLE video_status_changes ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY video_status_change_org_access ON video_status_changes
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
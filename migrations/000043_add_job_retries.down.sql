@@ -0,0 +1,10 @@
+-- Drop job failure counts; quarantined jobs become failed
+DROP INDEX IF EXISTS idx_jobs_video_id;
+DROP INDEX IF EXISTS idx_jobs_failed;
+
+UPDATE jobs SET status = 'failed' WHERE status = 'quarantined';
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check CHECK (status IN ('queued', 'running', 'succeeded', 'failed'));
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS retries;
+ALTER TABLE jobs DROP COLUMN IF EXISTS failures;
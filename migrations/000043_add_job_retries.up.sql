@@ -0,0 +1,17 @@
+-- Count the failed runs and manual retries of jobs. Jobs that keep failing are quarantined, so
+-- a poison job stops taking down workers until an administrator looks at it.
+ALTER TABLE jobs ADD COLUMN failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN retries INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jobs DROP CONSTRAINT IF EXISTS jobs_status_check;
+ALTER TABLE jobs ADD CONSTRAINT jobs_status_check
+    CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'quarantined'));
+
+-- Failed jobs used up their attempts; existing rows keep their updated_at
+ALTER TABLE jobs DISABLE TRIGGER update_jobs_updated_at;
+UPDATE jobs SET failures = attempts WHERE status = 'failed';
+ALTER TABLE jobs ENABLE TRIGGER update_jobs_updated_at;
+
+CREATE INDEX idx_jobs_failed ON jobs(kind, finished_at DESC) WHERE status IN ('failed', 'quarantined');
+-- The jobs of a video, found by the video_id of their payload
+CREATE INDEX idx_jobs_video_id ON jobs((payload->>'video_id'), created_at DESC) WHERE payload ? 'video_id';
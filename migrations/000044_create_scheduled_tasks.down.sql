@@ -0,0 +1,4 @@
+-- Drop the scheduler's state and the trending scores
+DROP TABLE IF EXISTS video_trending;
+DROP INDEX IF EXISTS idx_playback_events_startup_occurred_at;
+DROP TABLE IF EXISTS scheduled_tasks;
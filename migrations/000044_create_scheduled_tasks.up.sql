@@ -0,0 +1,55 @@
+-- Recurring maintenance tasks the leader runs on cron schedules, with their schedule overrides
+-- and the outcome of their last run. Rows are created by the scheduler for the tasks it knows.
+CREATE TABLE scheduled_tasks (
+    name VARCHAR(100) PRIMARY KEY,
+    -- NULL follows the task's built-in schedule
+    schedule VARCHAR(100),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP WITH TIME ZONE,
+    last_started_at TIMESTAMP WITH TIME ZONE,
+    last_finished_at TIMESTAMP WITH TIME ZONE,
+    last_status VARCHAR(20) CHECK (last_status IN ('running', 'succeeded', 'failed')),
+    last_error TEXT,
+    last_result TEXT,
+    last_duration_ms BIGINT,
+    -- Held by the run under way, so no other process starts the task until it ends or times out
+    locked_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_scheduled_tasks_updated_at
+    BEFORE UPDATE ON scheduled_tasks
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Trending scores of videos, recomputed by the trending task from the plays of the last day
+-- and week
+CREATE TABLE video_trending (
+    video_id UUID PRIMARY KEY REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    plays_24h BIGINT NOT NULL DEFAULT 0,
+    plays_7d BIGINT NOT NULL DEFAULT 0,
+    score DOUBLE PRECISION NOT NULL DEFAULT 0,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_video_trending_org_score ON video_trending(organization_id, score DESC);
+
+-- The plays of every organization in a window, which the trending task sums per video
+CREATE INDEX idx_playback_events_startup_occurred_at ON playback_events(occurred_at) WHERE type = 'startup';
+
+-- Members read their organizations' trending videos; the scheduler writes the scores through
+-- the master connection
+ALTER TABLE video_trending ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY video_trending_org_access ON video_trending
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
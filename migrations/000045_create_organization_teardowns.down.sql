@@ -0,0 +1,3 @@
+-- Drop teardown records; organizations deleted in the meantime lose their deletion mark
+DROP TABLE IF EXISTS organization_teardowns;
+ALTER TABLE organizations DROP COLUMN IF EXISTS deleted_at;
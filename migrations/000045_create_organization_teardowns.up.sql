@@ -0,0 +1,31 @@
+-- Deleted organizations are kept as a record once their data is gone
+ALTER TABLE organizations ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- The deletion of an organization: the confirmation its owner asked for, then the progress of
+-- the teardown job removing its stored files, videos and playback events
+CREATE TABLE organization_teardowns (
+    organization_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'confirming'
+        CHECK (status IN ('confirming', 'pending', 'running', 'completed', 'failed')),
+    -- SHA-256 of the confirmation token, cleared once the deletion is confirmed
+    confirmation_hash BYTEA,
+    confirmation_expires_at TIMESTAMP WITH TIME ZONE,
+    job_id UUID REFERENCES jobs(id) ON DELETE SET NULL,
+    step VARCHAR(20),
+    objects_total BIGINT NOT NULL DEFAULT 0,
+    objects_deleted BIGINT NOT NULL DEFAULT 0,
+    bytes_deleted BIGINT NOT NULL DEFAULT 0,
+    videos_total BIGINT NOT NULL DEFAULT 0,
+    videos_deleted BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TRIGGER update_organization_teardowns_updated_at
+    BEFORE UPDATE ON organization_teardowns
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,2 @@
+-- Drop the organization transcode profile
+ALTER TABLE organizations DROP COLUMN IF EXISTS transcode_profile;
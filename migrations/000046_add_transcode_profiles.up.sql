@@ -0,0 +1,3 @@
+-- Transcode profile of each organization, naming the codecs its sources are encoded to besides
+-- H.264; NULL follows TRANSCODE_PROFILE
+ALTER TABLE organizations ADD COLUMN transcode_profile VARCHAR(50);
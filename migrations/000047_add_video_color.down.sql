@@ -0,0 +1,3 @@
+-- Drop source color signaling and rendition dynamic ranges
+ALTER TABLE video_renditions DROP COLUMN IF EXISTS dynamic_range;
+ALTER TABLE videos DROP COLUMN IF EXISTS color;
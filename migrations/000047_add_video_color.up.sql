@@ -0,0 +1,6 @@
+-- Color signaling of each video's source as ffprobe reports it, telling HDR10, HLG and Dolby
+-- Vision sources apart from SDR ones; NULL until the source is probed
+ALTER TABLE videos ADD COLUMN color JSONB;
+
+-- Dynamic range of each rendition: sdr, hdr10, hlg or dolby_vision; NULL when unknown
+ALTER TABLE video_renditions ADD COLUMN dynamic_range VARCHAR(20);
@@ -0,0 +1,13 @@
+-- Drop downloads; their events and tracked files are removed first so the old checks hold
+DELETE FROM playback_events WHERE type = 'download';
+ALTER TABLE playback_events DROP CONSTRAINT IF EXISTS playback_events_type_check;
+ALTER TABLE playback_events ADD CONSTRAINT playback_events_type_check
+    CHECK (type IN ('startup', 'rebuffer', 'bitrate_switch', 'error', 'heartbeat', 'end'));
+
+DELETE FROM storage_objects WHERE kind = 'download';
+ALTER TABLE storage_objects DROP CONSTRAINT IF EXISTS storage_objects_kind_check;
+ALTER TABLE storage_objects ADD CONSTRAINT storage_objects_kind_check
+    CHECK (kind IN ('source', 'rendition', 'thumbnail', 'image'));
+
+DROP TABLE IF EXISTS video_downloads;
+ALTER TABLE videos DROP COLUMN IF EXISTS downloads_enabled;
@@ -0,0 +1,55 @@
+-- Videos members may download as files; off until enabled per video
+ALTER TABLE videos ADD COLUMN downloads_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Files rendered for download from a source or rendition, optionally with the downloader's
+-- email burned in
+CREATE TABLE video_downloads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- NULL downloads the source; a file already rendered survives its rendition
+    rendition_id UUID REFERENCES video_renditions(id) ON DELETE SET NULL,
+    source_revision INTEGER NOT NULL,
+    watermark TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'ready', 'failed')),
+    object_key TEXT,
+    size_bytes BIGINT,
+    error TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_video_downloads_video_id ON video_downloads(video_id, created_at DESC);
+CREATE INDEX idx_video_downloads_expires_at ON video_downloads(expires_at);
+
+CREATE TRIGGER update_video_downloads_updated_at
+    BEFORE UPDATE ON video_downloads
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members request and read their organizations' downloads; workers render them through the
+-- master connection
+ALTER TABLE video_downloads ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY video_download_org_access ON video_downloads
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+-- Rendered files are tracked like other objects, so teardowns and deletions find them
+ALTER TABLE storage_objects DROP CONSTRAINT IF EXISTS storage_objects_kind_check;
+ALTER TABLE storage_objects ADD CONSTRAINT storage_objects_kind_check
+    CHECK (kind IN ('source', 'rendition', 'thumbnail', 'image', 'download'));
+
+-- Downloads are recorded by the server next to the events players report
+ALTER TABLE playback_events DROP CONSTRAINT IF EXISTS playback_events_type_check;
+ALTER TABLE playback_events ADD CONSTRAINT playback_events_type_check
+    CHECK (type IN ('startup', 'rebuffer', 'bitrate_switch', 'error', 'heartbeat', 'end', 'download'));
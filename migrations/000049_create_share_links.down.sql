@@ -0,0 +1,3 @@
+-- Drop share links and the link of playback events
+ALTER TABLE playback_events DROP COLUMN IF EXISTS share_link_id;
+DROP TABLE IF EXISTS share_links;
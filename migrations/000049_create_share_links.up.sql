@@ -0,0 +1,46 @@
+-- Public links that open one video under their own slug, with limits on how long and how often
+-- they work and what viewers may do with the video
+CREATE TABLE share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    -- The random part of the link's URL; the video ID is not exposed
+    slug VARCHAR(32) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    max_views INTEGER CHECK (max_views > 0),
+    view_count INTEGER NOT NULL DEFAULT 0,
+    allow_download BOOLEAN NOT NULL DEFAULT FALSE,
+    allow_comments BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_viewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_share_links_video_id ON share_links(video_id, created_at DESC);
+
+CREATE TRIGGER update_share_links_updated_at
+    BEFORE UPDATE ON share_links
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Members create and revoke their organizations' links; viewers open them through the master
+-- connection
+ALTER TABLE share_links ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY share_link_org_access ON share_links
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+-- Playbacks through a share link are told apart from other playbacks of the video. No foreign
+-- key, like video_id: a link deleted while its events are buffered must not fail their batch.
+ALTER TABLE playback_events ADD COLUMN share_link_id UUID;
+CREATE INDEX idx_playback_events_share_link_occurred_at ON playback_events(share_link_id, occurred_at)
+    WHERE share_link_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Drop reviews and the approval state of videos
+DROP TABLE IF EXISTS review_comments;
+DROP TABLE IF EXISTS video_reviews;
+ALTER TABLE videos DROP COLUMN IF EXISTS review_status;
+ALTER TABLE organizations DROP COLUMN IF EXISTS require_review;
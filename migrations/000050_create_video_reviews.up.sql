@@ -0,0 +1,67 @@
+-- Organizations may require owner or admin approval of the videos other members create.
+-- Existing videos are approved; videos waiting for review or rejected only play for members.
+ALTER TABLE organizations ADD COLUMN require_review BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE videos ADD COLUMN review_status VARCHAR(20) NOT NULL DEFAULT 'approved'
+    CHECK (review_status IN ('pending', 'approved', 'rejected'));
+
+-- Each submission of a video for approval and its decision. A rejected video may be submitted
+-- again, opening a new review.
+CREATE TABLE video_reviews (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    submitted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decision_note VARCHAR(2000) NOT NULL DEFAULT '',
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- A video waits in at most one review
+CREATE UNIQUE INDEX idx_video_reviews_video_pending ON video_reviews(video_id) WHERE status = 'pending';
+CREATE INDEX idx_video_reviews_video_id ON video_reviews(video_id, created_at DESC);
+CREATE INDEX idx_video_reviews_queue ON video_reviews(organization_id, created_at) WHERE status = 'pending';
+
+CREATE TRIGGER update_video_reviews_updated_at
+    BEFORE UPDATE ON video_reviews
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Comments of reviewers and submitters on a review, optionally pinned to a position of the video
+CREATE TABLE review_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    review_id UUID NOT NULL REFERENCES video_reviews(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    author_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body VARCHAR(5000) NOT NULL,
+    position_seconds DOUBLE PRECISION CHECK (position_seconds >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_review_comments_review_id ON review_comments(review_id, created_at);
+
+ALTER TABLE video_reviews ENABLE ROW LEVEL SECURITY;
+ALTER TABLE review_comments ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY video_review_org_access ON video_reviews
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+CREATE POLICY review_comment_org_access ON review_comments
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
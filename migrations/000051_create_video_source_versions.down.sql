@@ -0,0 +1,2 @@
+-- Drop the replaced source versions of videos
+DROP TABLE IF EXISTS video_source_versions;
@@ -0,0 +1,37 @@
+-- Sources a video had before it was replaced, with the renditions made from them, so the
+-- video can be rolled back. The objects stay in storage_objects under the video and follow
+-- the archival lifecycle until the version expires.
+CREATE TABLE video_source_versions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id UUID NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    source_key TEXT NOT NULL,
+    content_type VARCHAR(100),
+    size_bytes BIGINT,
+    sha256 CHAR(64),
+    color JSONB,
+    -- The video_renditions rows of the version, restored on rollback
+    renditions JSONB NOT NULL DEFAULT '[]',
+    -- Rendition objects in storage that belong to the version
+    object_keys TEXT[] NOT NULL DEFAULT '{}',
+    replaced_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (video_id, revision)
+);
+
+CREATE INDEX idx_video_source_versions_expires_at ON video_source_versions(expires_at);
+
+ALTER TABLE video_source_versions ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY video_source_version_org_access ON video_source_versions
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
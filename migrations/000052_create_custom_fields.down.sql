@@ -0,0 +1,4 @@
+-- Drop custom fields and their values on videos
+DROP INDEX IF EXISTS idx_videos_custom_fields;
+ALTER TABLE videos DROP COLUMN IF EXISTS custom_fields;
+DROP TABLE IF EXISTS custom_fields;
@@ -0,0 +1,36 @@
+-- Typed fields organizations define for their videos, such as their own IDs and attributes
+CREATE TABLE custom_fields (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    key VARCHAR(64) NOT NULL CHECK (key ~ '^[a-z][a-z0-9_]*$'),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('string', 'number', 'date', 'enum')),
+    -- The allowed values of enum fields
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (organization_id, key)
+);
+
+CREATE TRIGGER update_custom_fields_updated_at
+    BEFORE UPDATE ON custom_fields
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Values of the fields by key; numbers are JSON numbers and dates YYYY-MM-DD strings
+ALTER TABLE videos ADD COLUMN custom_fields JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_videos_custom_fields ON videos USING GIN (custom_fields jsonb_path_ops);
+
+ALTER TABLE custom_fields ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY custom_field_org_access ON custom_fields
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,5 @@
+-- Drop external IDs of videos and organizations
+DROP INDEX IF EXISTS idx_organizations_external_id;
+DROP INDEX IF EXISTS idx_videos_external_id;
+ALTER TABLE organizations DROP COLUMN IF EXISTS external_id;
+ALTER TABLE videos DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,7 @@
+-- IDs videos and organizations have in external systems, such as the CMS they are synced from
+ALTER TABLE videos ADD COLUMN external_id VARCHAR(255);
+ALTER TABLE organizations ADD COLUMN external_id VARCHAR(255);
+
+-- External IDs of videos are unique within their organization, those of organizations overall
+CREATE UNIQUE INDEX idx_videos_external_id ON videos(organization_id, external_id) WHERE external_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_organizations_external_id ON organizations(external_id) WHERE external_id IS NOT NULL;
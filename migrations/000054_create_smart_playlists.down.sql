@@ -0,0 +1,2 @@
+-- Drop smart playlists
+DROP TABLE IF EXISTS smart_playlists;
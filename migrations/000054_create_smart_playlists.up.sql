@@ -0,0 +1,34 @@
+-- Smart playlists are saved searches over an organization's videos. Their videos are not
+-- stored but computed from the criteria when the playlist or its feeds are read.
+CREATE TABLE smart_playlists (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    criteria JSONB NOT NULL DEFAULT '{}',
+    sort VARCHAR(20) NOT NULL DEFAULT 'newest' CHECK (sort IN ('newest', 'oldest', 'title')),
+    max_items INTEGER NOT NULL DEFAULT 100 CHECK (max_items BETWEEN 1 AND 500),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    version BIGINT NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_smart_playlists_org ON smart_playlists(organization_id, created_at);
+
+CREATE TRIGGER update_smart_playlists_updated_at
+    BEFORE UPDATE ON smart_playlists
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE smart_playlists ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY smart_playlist_org_access ON smart_playlists
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
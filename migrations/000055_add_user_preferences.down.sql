@@ -0,0 +1,6 @@
+-- Drop profile settings and playback preferences of users
+ALTER TABLE users DROP COLUMN IF EXISTS playback_captions;
+ALTER TABLE users DROP COLUMN IF EXISTS playback_quality;
+ALTER TABLE users DROP COLUMN IF EXISTS playback_autoplay;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
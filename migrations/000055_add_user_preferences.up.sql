@@ -0,0 +1,6 @@
+-- Profile settings of users and how players start their videos
+ALTER TABLE users ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'en';
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN playback_autoplay BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN playback_quality VARCHAR(16) NOT NULL DEFAULT 'auto';
+ALTER TABLE users ADD COLUMN playback_captions BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,13 @@
+-- Timestamps of users, organizations and memberships are always set, so they scan into
+-- non-nullable times
+UPDATE users SET created_at = COALESCE(created_at, NOW()), updated_at = COALESCE(updated_at, created_at, NOW())
+WHERE created_at IS NULL OR updated_at IS NULL;
+ALTER TABLE users ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
+
+UPDATE organizations SET created_at = COALESCE(created_at, NOW()), updated_at = COALESCE(updated_at, created_at, NOW())
+WHERE created_at IS NULL OR updated_at IS NULL;
+ALTER TABLE organizations ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
+
+UPDATE user_org_roles SET created_at = COALESCE(created_at, NOW()), updated_at = COALESCE(updated_at, created_at, NOW())
+WHERE created_at IS NULL OR updated_at IS NULL;
+ALTER TABLE user_org_roles ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
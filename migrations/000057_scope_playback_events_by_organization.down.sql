@@ -0,0 +1,11 @@
+-- Scope playback_events by membership only again
+DROP POLICY playback_event_org_read ON playback_events;
+CREATE POLICY playback_event_org_read ON playback_events
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
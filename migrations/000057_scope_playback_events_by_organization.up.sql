@@ -0,0 +1,13 @@
+-- Members read the playback events of the organization their requests act in, like its videos,
+-- rather than of every organization they belong to
+DROP POLICY playback_event_org_read ON playback_events;
+CREATE POLICY playback_event_org_read ON playback_events
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
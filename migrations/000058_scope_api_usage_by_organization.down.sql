@@ -0,0 +1,11 @@
+-- Scope api_usage_daily by membership only again
+DROP POLICY api_usage_org_read ON api_usage_daily;
+CREATE POLICY api_usage_org_read ON api_usage_daily
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,13 @@
+-- Members read the API usage of the organization their requests act in, which is the one in the
+-- URL of the usage report
+DROP POLICY api_usage_org_read ON api_usage_daily;
+CREATE POLICY api_usage_org_read ON api_usage_daily
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
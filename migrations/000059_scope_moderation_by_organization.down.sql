@@ -0,0 +1,34 @@
+-- Scope abuse_reports and takedowns by membership only again
+DROP POLICY takedown_org_counter_notice ON takedowns;
+CREATE POLICY takedown_org_counter_notice ON takedowns
+  FOR UPDATE
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+  );
+
+DROP POLICY takedown_org_read ON takedowns;
+CREATE POLICY takedown_org_read ON takedowns
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
+
+DROP POLICY abuse_report_org_read ON abuse_reports;
+CREATE POLICY abuse_report_org_read ON abuse_reports
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,11 @@
+-- Scope moderation_scans by membership only again
+DROP POLICY moderation_scan_org_read ON moderation_scans;
+CREATE POLICY moderation_scan_org_read ON moderation_scans
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
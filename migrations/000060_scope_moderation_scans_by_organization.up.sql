@@ -0,0 +1,13 @@
+-- Members see the scans of the videos of the organization their requests act in, like the videos
+-- themselves
+DROP POLICY moderation_scan_org_read ON moderation_scans;
+CREATE POLICY moderation_scan_org_read ON moderation_scans
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
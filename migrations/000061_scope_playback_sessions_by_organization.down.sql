@@ -0,0 +1,11 @@
+-- Scope playback_sessions by membership only again
+DROP POLICY playback_session_org_access ON playback_sessions;
+CREATE POLICY playback_session_org_access ON playback_sessions
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
@@ -0,0 +1,13 @@
+-- Members see the playback sessions of the organization their requests act in, like its videos,
+-- rather than of every organization they belong to
+DROP POLICY playback_session_org_access ON playback_sessions;
+CREATE POLICY playback_session_org_access ON playback_sessions
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,11 @@
+-- Scope entitlements by membership only again
+DROP POLICY entitlement_org_access ON entitlements;
+CREATE POLICY entitlement_org_access ON entitlements
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
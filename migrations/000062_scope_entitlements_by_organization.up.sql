@@ -0,0 +1,13 @@
+-- Members see the entitlements of the organization their requests act in, which is the one in
+-- the URL when they are managed, like its videos
+DROP POLICY entitlement_org_access ON entitlements;
+CREATE POLICY entitlement_org_access ON entitlements
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,11 @@
+-- Scope feeds by membership only again
+DROP POLICY feed_org_access ON feeds;
+CREATE POLICY feed_org_access ON feeds
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+  );
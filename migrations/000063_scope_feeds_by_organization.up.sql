@@ -0,0 +1,13 @@
+-- Members see the generated feeds of the organization their requests act in, like its projects;
+-- feeds are still served publicly through the master connection
+DROP POLICY feed_org_access ON feeds;
+CREATE POLICY feed_org_access ON feeds
+  FOR ALL
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,24 @@
+-- Scope audit_log by membership only again
+DROP POLICY audit_log_admin_insert ON audit_log;
+CREATE POLICY audit_log_admin_insert ON audit_log
+  FOR INSERT
+  WITH CHECK (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+  );
+
+DROP POLICY audit_log_admin_read ON audit_log;
+CREATE POLICY audit_log_admin_read ON audit_log
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+  );
@@ -0,0 +1,27 @@
+-- Owners and admins read and write the audit log of the organization their requests act in,
+-- which is the one in the URL of the audit log and of the IP access rules
+DROP POLICY audit_log_admin_read ON audit_log;
+CREATE POLICY audit_log_admin_read ON audit_log
+  FOR SELECT
+  USING (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
+
+DROP POLICY audit_log_admin_insert ON audit_log;
+CREATE POLICY audit_log_admin_insert ON audit_log
+  FOR INSERT
+  WITH CHECK (
+    organization_id IN (
+      SELECT organization_id
+      FROM user_org_roles
+      WHERE user_id = current_setting('app.current_user_id', true)::uuid
+        AND role IN ('owner', 'admin')
+    )
+    AND organization_id = COALESCE(current_org_id(), organization_id)
+  );
@@ -0,0 +1,35 @@
+// Package migrations embeds the SQL migrations so the server can tell which schema
+// version it was built for.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// FS contains the up and down migration files
+//
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion returns the highest migration version in FS
+func LatestVersion() uint {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		prefix, _, ok := strings.Cut(entry.Name(), "_")
+		if !ok {
+			continue
+		}
+		if version, err := strconv.ParseUint(prefix, 10, 64); err == nil && uint(version) > latest {
+			latest = uint(version)
+		}
+	}
+	return latest
+}
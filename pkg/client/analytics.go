@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// QoEOptions selects the range, video and share link of playback analytics. Zero values query the last
+// 24 hours of every video.
+type QoEOptions struct {
+	From    time.Time
+	To      time.Time
+	VideoID string
+	// ShareLinkID limits the events to playbacks and downloads through one share link
+	ShareLinkID string
+	// Interval is "hour" or "day" for GetQoETimeseries
+	Interval string
+}
+
+func (o QoEOptions) values() url.Values {
+	q := url.Values{}
+	if !o.From.IsZero() {
+		q.Set("from", o.From.Format(time.RFC3339))
+	}
+	if !o.To.IsZero() {
+		q.Set("to", o.To.Format(time.RFC3339))
+	}
+	if o.VideoID != "" {
+		q.Set("video_id", o.VideoID)
+	}
+	if o.ShareLinkID != "" {
+		q.Set("share_link_id", o.ShareLinkID)
+	}
+	if o.Interval != "" {
+		q.Set("interval", o.Interval)
+	}
+	return q
+}
+
+// QoESummary aggregates the playback quality of a range. Sessions is estimated from the
+// sampled sessions; the percentiles and averages are null without events to compute them.
+type QoESummary struct {
+	Sessions                  int64    `json:"sessions"`
+	StartupP50Ms              *float64 `json:"startup_p50_ms"`
+	StartupP95Ms              *float64 `json:"startup_p95_ms"`
+	RebufferRate              float64  `json:"rebuffer_rate"`
+	RebufferMsPerSession      float64  `json:"rebuffer_ms_per_session"`
+	Errors                    int64    `json:"errors"`
+	ErrorRate                 float64  `json:"error_rate"`
+	AvgBitrateKbps            *float64 `json:"avg_bitrate_kbps"`
+	BitrateSwitchesPerSession float64  `json:"bitrate_switches_per_session"`
+	// Downloads counts the fetches of download files
+	Downloads int64 `json:"downloads"`
+}
+
+// QoEReport is the summary of the current organization's playback quality
+type QoEReport struct {
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	VideoID     *string    `json:"video_id"`
+	ShareLinkID *string    `json:"share_link_id"`
+	Backend     string     `json:"backend"`
+	Summary     QoESummary `json:"summary"`
+}
+
+// QoEBucket is the summary of one interval
+type QoEBucket struct {
+	Start time.Time `json:"start"`
+	QoESummary
+}
+
+// QoETimeseries is the playback quality per interval; intervals without events are left out
+type QoETimeseries struct {
+	From        time.Time   `json:"from"`
+	To          time.Time   `json:"to"`
+	VideoID     *string     `json:"video_id"`
+	ShareLinkID *string     `json:"share_link_id"`
+	Interval    string      `json:"interval"`
+	Backend     string      `json:"backend"`
+	Buckets     []QoEBucket `json:"buckets"`
+}
+
+// GetQoESummary aggregates the beacon events of the current organization
+func (c *Client) GetQoESummary(ctx context.Context, opts QoEOptions) (*QoEReport, error) {
+	var out QoEReport
+	if err := do(ctx, c, http.MethodGet, "/api/v1/analytics/qoe", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetQoETimeseries aggregates the beacon events of the current organization per hour or day
+func (c *Client) GetQoETimeseries(ctx context.Context, opts QoEOptions) (*QoETimeseries, error) {
+	var out QoETimeseries
+	if err := do(ctx, c, http.MethodGet, "/api/v1/analytics/qoe/timeseries", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// PlaybackEvent is one quality-of-experience event reported by a player. Type is startup,
+// rebuffer, bitrate_switch, error, heartbeat or end; the measurements that apply depend on it.
+type PlaybackEvent struct {
+	VideoID             string     `json:"video_id"`
+	SessionID           string     `json:"session_id"`
+	Type                string     `json:"type"`
+	OccurredAt          *time.Time `json:"occurred_at,omitempty"`
+	Position            *float64   `json:"position,omitempty"`
+	StartupMs           *int       `json:"startup_ms,omitempty"`
+	RebufferCount       *int       `json:"rebuffer_count,omitempty"`
+	RebufferMs          *int       `json:"rebuffer_ms,omitempty"`
+	BitrateKbps         *int       `json:"bitrate_kbps,omitempty"`
+	PreviousBitrateKbps *int       `json:"previous_bitrate_kbps,omitempty"`
+	ErrorCode           string     `json:"error_code,omitempty"`
+	ErrorMessage        string     `json:"error_message,omitempty"`
+}
+
+// BeaconResult tells which events were refused, by their index in the batch, and the share
+// of sessions the server keeps
+type BeaconResult struct {
+	Accepted int `json:"accepted"`
+	Rejected []struct {
+		Index int    `json:"index"`
+		Error string `json:"error"`
+	} `json:"rejected"`
+	SampleRate float64 `json:"sample_rate"`
+}
+
+// SendBeacon reports a batch of player events; it needs no credentials
+func (c *Client) SendBeacon(ctx context.Context, player string, events []PlaybackEvent) (*BeaconResult, error) {
+	body := struct {
+		Player string          `json:"player,omitempty"`
+		Events []PlaybackEvent `json:"events"`
+	}{player, events}
+	var out BeaconResult
+	if err := do(ctx, c, http.MethodPost, "/api/v1/beacon", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
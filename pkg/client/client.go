@@ -0,0 +1,326 @@
+// Package client is a typed Go client for the OpenVDO API. It sets the authentication
+// headers on every request, retries idempotent requests on transient failures and offers
+// iterators over paginated listings.
+//
+//	c := client.New("https://api.example.com", client.WithUserID(userID))
+//	for video, err := range c.Videos(ctx, client.ListVideosOptions{}) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(video.Title)
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the OpenVDO API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userID     string
+	orgID      string
+	token      string
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	userAgent  string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient replaces the default HTTP client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithUserID identifies the caller with the X-User-ID header
+func WithUserID(userID string) Option {
+	return func(c *Client) { c.userID = userID }
+}
+
+// WithOrgID makes requests act in the organization with the X-Org-ID header, for callers
+// belonging to several organizations
+func WithOrgID(orgID string) Option {
+	return func(c *Client) { c.orgID = orgID }
+}
+
+// WithToken sends a bearer token in the Authorization header
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithRetries sets how often idempotent requests are retried and the backoff bounds.
+// maxRetries 0 disables retries.
+func WithRetries(maxRetries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithUserAgent sets the User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New creates a client for the API at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		maxRetries: 3,
+		minBackoff: 200 * time.Millisecond,
+		maxBackoff: 5 * time.Second,
+		userAgent:  "openvdo-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ErrNotModified is returned by conditional reads when the resource still matches the ETag
+var ErrNotModified = errors.New("openvdo: not modified")
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Code tells some failures of the same status apart, such as already_exists for a name or
+	// external ID another resource has
+	Code string
+	// Data holds the current state of the resource on version conflicts, and the results of
+	// bulk updates that were rolled back
+	Data json.RawMessage
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("openvdo: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsPreconditionFailed reports whether err is an API 412, returned when a conditional
+// update lost against a concurrent change
+func IsPreconditionFailed(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// IsConflict reports whether err is an API 409. For version conflicts the current state of
+// the resource can be decoded from the error's Data.
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// IsAlreadyExists reports whether err is an API 409 for a value, such as an organization name,
+// another resource already has
+func IsAlreadyExists(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == "already_exists"
+}
+
+// IsNotFound reports whether err is an API 404
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// envelope is the success response format of the API
+type envelope[T any] struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+}
+
+// rawBody is a request body sent as is rather than encoded as JSON
+type rawBody struct {
+	contentType string
+	data        []byte
+}
+
+// do sends a request and decodes the data field of the response into out (if not nil)
+func do[T any](ctx context.Context, c *Client, method, path string, query url.Values, body any, out *T) error {
+	_, err := doWithHeaders(ctx, c, method, path, query, body, nil, out)
+	return err
+}
+
+// doWithHeaders is do with extra request headers, returning the response headers
+func doWithHeaders[T any](ctx context.Context, c *Client, method, path string, query url.Values, body any, header http.Header, out *T) (http.Header, error) {
+	var payload []byte
+	if raw, ok := body.(rawBody); ok {
+		payload = raw.data
+		header = header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		header.Set("Content-Type", raw.contentType)
+	} else if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("openvdo: failed to encode request: %w", err)
+		}
+	}
+
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	retries := 0
+	if idempotent(method) {
+		retries = c.maxRetries
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u, payload, header)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			defer resp.Body.Close()
+			return resp.Header, decode(resp, out)
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			if attempt >= retries {
+				defer resp.Body.Close()
+				return resp.Header, decode(resp, out)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		} else if attempt >= retries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		wait := c.backoff(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, payload []byte, header http.Header) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.userID != "" {
+		req.Header.Set("X-User-ID", c.userID)
+	}
+	if c.orgID != "" {
+		req.Header.Set("X-Org-ID", c.orgID)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+func decode[T any](resp *http.Response, out *T) error {
+	if resp.StatusCode == http.StatusNotModified {
+		return ErrNotModified
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("openvdo: failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error   string          `json:"error"`
+			Code    string          `json:"code"`
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(data, &apiErr)
+		message := apiErr.Error
+		if message == "" {
+			message = apiErr.Message
+		}
+		if message == "" {
+			message = strings.TrimSpace(string(data))
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: message, Code: apiErr.Code, Data: apiErr.Data}
+	}
+
+	if out == nil {
+		return nil
+	}
+	var env envelope[T]
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("openvdo: failed to decode response: %w", err)
+	}
+	*out = env.Data
+	return nil
+}
+
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.minBackoff << attempt
+	if d > c.maxBackoff || d <= 0 {
+		d = c.maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// CustomField is a typed field an organization defines for its videos
+type CustomField struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organization_id"`
+	Key            string `json:"key"`
+	Name           string `json:"name"`
+	// Type is "string", "number", "date" or "enum"
+	Type string `json:"type"`
+	// Options are the values an enum field accepts
+	Options   []string  `json:"options"`
+	CreatedBy *string   `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateCustomFieldRequest defines a custom field; Options are required for enum fields
+type CreateCustomFieldRequest struct {
+	Key     string   `json:"key"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Options []string `json:"options,omitempty"`
+}
+
+// UpdateCustomFieldRequest holds the changes to a custom field; nil fields are left as they are
+type UpdateCustomFieldRequest struct {
+	Name *string `json:"name,omitempty"`
+	// Options replaces the options of an enum field
+	Options *[]string `json:"options,omitempty"`
+}
+
+// ListCustomFields returns the custom fields of an organization
+func (c *Client) ListCustomFields(ctx context.Context, orgID string) ([]CustomField, error) {
+	var out struct {
+		CustomFields []CustomField `json:"custom_fields"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/organizations/"+url.PathEscape(orgID)+"/custom-fields", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.CustomFields, nil
+}
+
+// CreateCustomField defines a custom field for an organization's videos
+func (c *Client) CreateCustomField(ctx context.Context, orgID string, req CreateCustomFieldRequest) (*CustomField, error) {
+	var out CustomField
+	if err := do(ctx, c, http.MethodPost, "/api/v1/organizations/"+url.PathEscape(orgID)+"/custom-fields", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCustomField renames a custom field or replaces the options of an enum field
+func (c *Client) UpdateCustomField(ctx context.Context, orgID, key string, req UpdateCustomFieldRequest) (*CustomField, error) {
+	var out CustomField
+	path := "/api/v1/organizations/" + url.PathEscape(orgID) + "/custom-fields/" + url.PathEscape(key)
+	if err := do(ctx, c, http.MethodPatch, path, nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteCustomField removes a custom field and its values from every video
+func (c *Client) DeleteCustomField(ctx context.Context, orgID, key string) error {
+	path := "/api/v1/organizations/" + url.PathEscape(orgID) + "/custom-fields/" + url.PathEscape(key)
+	return do[struct{}](ctx, c, http.MethodDelete, path, nil, nil, nil)
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Entitlement grants a user playback of a video, or of every video of a project
+type Entitlement struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	UserID         string     `json:"user_id"`
+	VideoID        *string    `json:"video_id,omitempty"`
+	ProjectID      *string    `json:"project_id,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Reference      string     `json:"reference"`
+	GrantedBy      *string    `json:"granted_by,omitempty"`
+	// Active is false once the grant has expired
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GrantEntitlementRequest is the body of GrantEntitlement. Give one of UserID and Email, and
+// one of VideoID and ProjectID; without ExpiresAt or DurationSeconds the grant does not expire.
+type GrantEntitlementRequest struct {
+	UserID          string     `json:"user_id,omitempty"`
+	Email           string     `json:"email,omitempty"`
+	VideoID         string     `json:"video_id,omitempty"`
+	ProjectID       string     `json:"project_id,omitempty"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds,omitempty"`
+	Reference       string     `json:"reference,omitempty"`
+}
+
+// ListEntitlementsOptions filters and pages ListEntitlements
+type ListEntitlementsOptions struct {
+	PageOptions
+	UserID    string
+	VideoID   string
+	ProjectID string
+	// Active only returns grants that have not expired
+	Active bool
+}
+
+func (o ListEntitlementsOptions) values() url.Values {
+	q := o.PageOptions.values()
+	for name, value := range map[string]string{"user_id": o.UserID, "video_id": o.VideoID, "project_id": o.ProjectID} {
+		if value != "" {
+			q.Set(name, value)
+		}
+	}
+	if o.Active {
+		q.Set("active", "true")
+	}
+	return q
+}
+
+// EntitlementList is one page of entitlements
+type EntitlementList struct {
+	Entitlements []Entitlement `json:"entitlements"`
+	Pagination   Pagination    `json:"pagination"`
+}
+
+// BulkGrantResult counts the grants of BulkGrantEntitlements and explains the skipped rows
+type BulkGrantResult struct {
+	Granted int      `json:"granted"`
+	Skipped []string `json:"skipped"`
+}
+
+// ListEntitlements lists the organization's entitlements, newest first; it needs the owner or
+// admin role
+func (c *Client) ListEntitlements(ctx context.Context, orgID string, opts ListEntitlementsOptions) (*EntitlementList, error) {
+	var out EntitlementList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/organizations/"+url.PathEscape(orgID)+"/entitlements", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GrantEntitlement grants a user playback of a video or project, replacing the user's previous
+// grant of it
+func (c *Client) GrantEntitlement(ctx context.Context, orgID string, req GrantEntitlementRequest) (*Entitlement, error) {
+	var out Entitlement
+	if err := do(ctx, c, http.MethodPost, "/api/v1/organizations/"+url.PathEscape(orgID)+"/entitlements", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BulkGrantEntitlements grants the rows of a CSV with the columns user (an ID or email),
+// video_id, project_id, expires_at and reference
+func (c *Client) BulkGrantEntitlements(ctx context.Context, orgID string, csv []byte) (*BulkGrantResult, error) {
+	var out BulkGrantResult
+	body := rawBody{contentType: "text/csv", data: csv}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/organizations/"+url.PathEscape(orgID)+"/entitlements/bulk", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeEntitlement removes an entitlement
+func (c *Client) RevokeEntitlement(ctx context.Context, orgID, entitlementID string) error {
+	return do[struct{}](ctx, c, http.MethodDelete,
+		"/api/v1/organizations/"+url.PathEscape(orgID)+"/entitlements/"+url.PathEscape(entitlementID), nil, nil, nil)
+}
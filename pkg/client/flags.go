@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Flag is a feature flag as the admin API returns it. Overrides maps organization IDs to the
+// value the flag is forced to for them.
+type Flag struct {
+	Key         string          `json:"key"`
+	Description string          `json:"description"`
+	Enabled     bool            `json:"enabled"`
+	Percentage  int             `json:"percentage"`
+	Overrides   map[string]bool `json:"overrides"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// PutFlagRequest is the body of PutFlag
+type PutFlagRequest struct {
+	Enabled     bool   `json:"enabled"`
+	Percentage  int    `json:"percentage"`
+	Description string `json:"description,omitempty"`
+}
+
+// GetFlags returns whether each feature flag is on for the caller's organization
+func (c *Client) GetFlags(ctx context.Context) (map[string]bool, error) {
+	var out struct {
+		Flags map[string]bool `json:"flags"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/flags", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Flags, nil
+}
+
+// ListFlags returns every feature flag. It needs a client created WithToken and the admin token.
+func (c *Client) ListFlags(ctx context.Context) ([]Flag, error) {
+	var out struct {
+		Flags []Flag `json:"flags"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/flags", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Flags, nil
+}
+
+// PutFlag creates a feature flag or replaces its settings; it needs the admin token
+func (c *Client) PutFlag(ctx context.Context, key string, req PutFlagRequest) (*Flag, error) {
+	var out Flag
+	if err := do(ctx, c, http.MethodPut, "/admin/v1/flags/"+url.PathEscape(key), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteFlag removes a feature flag; it needs the admin token
+func (c *Client) DeleteFlag(ctx context.Context, key string) error {
+	return do[struct{}](ctx, c, http.MethodDelete, "/admin/v1/flags/"+url.PathEscape(key), nil, nil, nil)
+}
+
+// SetFlagOverride forces a feature flag on or off for an organization; it needs the admin token
+func (c *Client) SetFlagOverride(ctx context.Context, key, orgID string, enabled bool) error {
+	body := struct {
+		Enabled bool `json:"enabled"`
+	}{enabled}
+	return do[struct{}](ctx, c, http.MethodPut,
+		"/admin/v1/flags/"+url.PathEscape(key)+"/organizations/"+url.PathEscape(orgID), nil, body, nil)
+}
+
+// DeleteFlagOverride returns an organization to a feature flag's percentage rollout; it needs
+// the admin token
+func (c *Client) DeleteFlagOverride(ctx context.Context, key, orgID string) error {
+	return do[struct{}](ctx, c, http.MethodDelete,
+		"/admin/v1/flags/"+url.PathEscape(key)+"/organizations/"+url.PathEscape(orgID), nil, nil, nil)
+}
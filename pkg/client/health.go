@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Live reports whether the server process is up
+func (c *Client) Live(ctx context.Context) error {
+	return do[struct{}](ctx, c, http.MethodGet, "/livez", nil, nil, nil)
+}
+
+// Version returns the build of the server
+func (c *Client) Version(ctx context.Context) (*BuildInfo, error) {
+	var out BuildInfo
+	if err := do(ctx, c, http.MethodGet, "/version", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Ready returns the readiness report. Unlike other methods a 503 is not an error: the
+// report says which dependency is not ready.
+func (c *Client) Ready(ctx context.Context) (*Readiness, error) {
+	resp, err := c.send(ctx, http.MethodGet, c.baseURL+"/readyz", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, decode[struct{}](resp, nil)
+	}
+
+	var env envelope[Readiness]
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("openvdo: failed to read response: %w", err)
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("openvdo: failed to decode response: %w", err)
+	}
+	return &env.Data, nil
+}
+
+// DatabaseHealth returns the database pool health details
+func (c *Client) DatabaseHealth(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if err := do(ctx, c, http.MethodGet, "/health/db", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DatabaseStats returns the database pool statistics
+func (c *Client) DatabaseStats(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if err := do(ctx, c, http.MethodGet, "/stats/db", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
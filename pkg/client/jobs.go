@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// FailedJobsOptions filters ListFailedJobs and RetryFailedJobs
+type FailedJobsOptions struct {
+	// Status is "failed" or "quarantined"; empty lists both. Bulk retries skip quarantined jobs.
+	Status string
+	Kinds  []string
+	// OrganizationID is only honored by the admin endpoints
+	OrganizationID string
+	VideoID        string
+	FailedSince    time.Time
+	Limit          int
+}
+
+func (o FailedJobsOptions) values() url.Values {
+	q := url.Values{}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	for _, kind := range o.Kinds {
+		q.Add("kind", kind)
+	}
+	if o.OrganizationID != "" {
+		q.Set("organization_id", o.OrganizationID)
+	}
+	if o.VideoID != "" {
+		q.Set("video_id", o.VideoID)
+	}
+	if !o.FailedSince.IsZero() {
+		q.Set("failed_since", o.FailedSince.Format(time.RFC3339))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// ListFailedJobs returns the failed and quarantined jobs of the user's organizations, the
+// latest to give up first
+func (c *Client) ListFailedJobs(ctx context.Context, opts FailedJobsOptions) ([]Job, error) {
+	var out struct {
+		Jobs []Job `json:"jobs"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/jobs", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Jobs, nil
+}
+
+// RetryVideo requeues the latest import or transcode job of a video when it failed. It fails
+// with a conflict when that job has not failed or is quarantined.
+func (c *Client) RetryVideo(ctx context.Context, videoID string) (*Job, error) {
+	var out Job
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(videoID)+"/retry", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAllFailedJobs returns the failed and quarantined jobs of every organization; it needs the
+// admin token
+func (c *Client) ListAllFailedJobs(ctx context.Context, opts FailedJobsOptions) ([]Job, error) {
+	var out struct {
+		Jobs []Job `json:"jobs"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/jobs", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Jobs, nil
+}
+
+// RetryJob requeues a failed job; release also requeues a quarantined one. It needs the admin
+// token.
+func (c *Client) RetryJob(ctx context.Context, id string, release bool) (*Job, error) {
+	var q url.Values
+	if release {
+		q = url.Values{"release": {"true"}}
+	}
+	var out Job
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/jobs/"+url.PathEscape(id)+"/retry", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RetryFailedJobs requeues the failed jobs opts selects and returns how many; it needs the
+// admin token
+func (c *Client) RetryFailedJobs(ctx context.Context, opts FailedJobsOptions) (int, error) {
+	var out struct {
+		Retried int `json:"retried"`
+	}
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/jobs/retry", opts.values(), nil, &out); err != nil {
+		return 0, err
+	}
+	return out.Retried, nil
+}
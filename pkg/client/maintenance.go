@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Maintenance is the maintenance mode in effect. While it is on, requests that change data
+// fail with a 503 APIError.
+type Maintenance struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+	// RetryAfter is in seconds
+	RetryAfter int        `json:"retry_after"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Forced     bool       `json:"forced,omitempty"`
+}
+
+// SetMaintenanceRequest is the body of SetMaintenance. Without RetryAfter the server default
+// applies.
+type SetMaintenanceRequest struct {
+	Enabled    bool   `json:"enabled"`
+	Message    string `json:"message,omitempty"`
+	RetryAfter *int   `json:"retry_after,omitempty"`
+}
+
+// GetMaintenance returns the maintenance mode; it needs the admin token
+func (c *Client) GetMaintenance(ctx context.Context) (*Maintenance, error) {
+	var out Maintenance
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/maintenance", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetMaintenance turns the maintenance mode on or off; it needs the admin token
+func (c *Client) SetMaintenance(ctx context.Context, req SetMaintenanceRequest) (*Maintenance, error) {
+	var out Maintenance
+	if err := do(ctx, c, http.MethodPut, "/admin/v1/maintenance", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AbuseReport is a viewer's report of a video
+type AbuseReport struct {
+	ID             string     `json:"id"`
+	VideoID        string     `json:"video_id"`
+	OrganizationID string     `json:"organization_id"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Takedown removes a video from playback. Status is "active", "counter_noticed", "restored"
+// or "upheld".
+type Takedown struct {
+	ID                     string     `json:"id"`
+	VideoID                string     `json:"video_id"`
+	OrganizationID         string     `json:"organization_id"`
+	Kind                   string     `json:"kind"`
+	Reason                 string     `json:"reason"`
+	Claimant               string     `json:"claimant,omitempty"`
+	Status                 string     `json:"status"`
+	CounterNoticeStatement *string    `json:"counter_notice_statement,omitempty"`
+	CounterNoticeContact   *string    `json:"counter_notice_contact,omitempty"`
+	CounterNoticedAt       *time.Time `json:"counter_noticed_at,omitempty"`
+	RestoreAfter           *time.Time `json:"restore_after,omitempty"`
+	ResolvedAt             *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+}
+
+// ModerationQueueItem is a video with open reports
+type ModerationQueueItem struct {
+	VideoID          string    `json:"video_id"`
+	OrganizationID   string    `json:"organization_id"`
+	Title            string    `json:"title"`
+	ModerationStatus string    `json:"moderation_status"`
+	OpenReports      int       `json:"open_reports"`
+	Reasons          []string  `json:"reasons"`
+	FirstReportedAt  time.Time `json:"first_reported_at"`
+	LastReportedAt   time.Time `json:"last_reported_at"`
+}
+
+// ModerationScan is the result of an automatic content scan of a video's source. Scores are
+// the highest score of each category, from 0 to 1; Flagged lists the categories at or above
+// the threshold.
+type ModerationScan struct {
+	ID             string             `json:"id"`
+	VideoID        string             `json:"video_id"`
+	OrganizationID string             `json:"organization_id"`
+	Provider       string             `json:"provider"`
+	SourceKey      string             `json:"source_key"`
+	Frames         int                `json:"frames"`
+	AudioSeconds   int                `json:"audio_seconds"`
+	Scores         map[string]float64 `json:"scores"`
+	Flagged        []string           `json:"flagged"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+// ModerationCase is a video with all its reports, takedowns and scans
+type ModerationCase struct {
+	Video     Video            `json:"video"`
+	Reports   []AbuseReport    `json:"reports"`
+	Takedowns []Takedown       `json:"takedowns"`
+	Scans     []ModerationScan `json:"scans"`
+}
+
+// ReportVideo flags a video for review; it needs no credentials. token is the playback token
+// of a private video, or empty.
+func (c *Client) ReportVideo(ctx context.Context, videoID, reason, details, token string) error {
+	body := struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details,omitempty"`
+		Token   string `json:"token,omitempty"`
+	}{reason, details, token}
+	return do[struct{}](ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(videoID)+"/reports", nil, body, nil)
+}
+
+// FileCounterNotice disputes the DMCA takedown of one of the organization's videos; it needs
+// the owner or admin role
+func (c *Client) FileCounterNotice(ctx context.Context, videoID, statement, contact string) (*Takedown, error) {
+	body := struct {
+		Statement string `json:"statement"`
+		Contact   string `json:"contact"`
+	}{statement, contact}
+	var out Takedown
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(videoID)+"/counter-notice", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetModerationQueue lists videos with open reports, at most limit of them; it needs the admin
+// token
+func (c *Client) GetModerationQueue(ctx context.Context, limit int) ([]ModerationQueueItem, error) {
+	q := url.Values{}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out struct {
+		Videos []ModerationQueueItem `json:"videos"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/moderation/queue", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Videos, nil
+}
+
+// GetModerationCase returns a video with its reports and takedowns; it needs the admin token
+func (c *Client) GetModerationCase(ctx context.Context, videoID string) (*ModerationCase, error) {
+	var out ModerationCase
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/moderation/videos/"+url.PathEscape(videoID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DismissReports closes a video's open reports without action; it needs the admin token
+func (c *Client) DismissReports(ctx context.Context, videoID, note string) (*Video, error) {
+	body := struct {
+		Note string `json:"note,omitempty"`
+	}{note}
+	var out Video
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/moderation/videos/"+url.PathEscape(videoID)+"/dismiss", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TakeDownVideo stops a video from playing; kind is "dmca", which needs the claimant, or
+// "policy". It needs the admin token.
+func (c *Client) TakeDownVideo(ctx context.Context, videoID, kind, reason, claimant string) (*Takedown, error) {
+	body := struct {
+		Kind     string `json:"kind"`
+		Reason   string `json:"reason"`
+		Claimant string `json:"claimant,omitempty"`
+	}{kind, reason, claimant}
+	var out Takedown
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/moderation/videos/"+url.PathEscape(videoID)+"/takedown", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListTakedowns lists takedowns with a status, or all when status is empty; it needs the admin
+// token
+func (c *Client) ListTakedowns(ctx context.Context, status string) ([]Takedown, error) {
+	q := url.Values{}
+	if status != "" {
+		q.Set("status", status)
+	}
+	var out struct {
+		Takedowns []Takedown `json:"takedowns"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/moderation/takedowns", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Takedowns, nil
+}
+
+// RestoreTakedown ends a takedown and lets the video play again; it needs the admin token
+func (c *Client) RestoreTakedown(ctx context.Context, takedownID string) (*Takedown, error) {
+	var out Takedown
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/moderation/takedowns/"+url.PathEscape(takedownID)+"/restore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpholdTakedown keeps a counter-noticed video down; it needs the admin token
+func (c *Client) UpholdTakedown(ctx context.Context, takedownID string) (*Takedown, error) {
+	var out Takedown
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/moderation/takedowns/"+url.PathEscape(takedownID)+"/uphold", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ScanVideo queues an automatic content scan of a video's current source; it needs the admin
+// token
+func (c *Client) ScanVideo(ctx context.Context, videoID string) (*Job, error) {
+	var out Job
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/moderation/videos/"+url.PathEscape(videoID)+"/scan", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListVideoScans lists the automatic content scans of a video, newest first
+func (c *Client) ListVideoScans(ctx context.Context, videoID string) ([]ModerationScan, error) {
+	var out struct {
+		Scans []ModerationScan `json:"scans"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(videoID)+"/scans", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Scans, nil
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Notification is a message to the caller, e.g. that a video is ready
+type Notification struct {
+	ID             string          `json:"id"`
+	UserID         string          `json:"user_id"`
+	OrganizationID *string         `json:"organization_id,omitempty"`
+	Type           string          `json:"type"`
+	Title          string          `json:"title"`
+	Body           string          `json:"body"`
+	Data           json.RawMessage `json:"data"`
+	ReadAt         *time.Time      `json:"read_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// NotificationPreference selects whether one type of notification is sent by email and push
+type NotificationPreference struct {
+	Type  string `json:"type"`
+	Email bool   `json:"email"`
+	Push  bool   `json:"push"`
+}
+
+// ListNotificationsOptions filters and pages ListNotifications
+type ListNotificationsOptions struct {
+	PageOptions
+	// Unread only returns notifications that were not marked read
+	Unread bool
+}
+
+func (o ListNotificationsOptions) values() url.Values {
+	q := o.PageOptions.values()
+	if o.Unread {
+		q.Set("unread", "true")
+	}
+	return q
+}
+
+// NotificationList is one page of notifications
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unread_count"`
+	Pagination    Pagination     `json:"pagination"`
+}
+
+// ListNotifications returns one page of the caller's notifications, newest first
+func (c *Client) ListNotifications(ctx context.Context, opts ListNotificationsOptions) (*NotificationList, error) {
+	var out NotificationList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/notifications", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkNotificationRead marks one notification as read
+func (c *Client) MarkNotificationRead(ctx context.Context, id string) (*Notification, error) {
+	var out Notification
+	if err := do(ctx, c, http.MethodPost, "/api/v1/notifications/"+url.PathEscape(id)+"/read", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkAllNotificationsRead marks every unread notification as read and returns how many there were
+func (c *Client) MarkAllNotificationsRead(ctx context.Context) (int, error) {
+	var out struct {
+		Updated int `json:"updated"`
+	}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/notifications/read", nil, nil, &out); err != nil {
+		return 0, err
+	}
+	return out.Updated, nil
+}
+
+// GetNotificationPreferences returns the caller's channels for every notification type
+func (c *Client) GetNotificationPreferences(ctx context.Context) ([]NotificationPreference, error) {
+	var out struct {
+		Preferences []NotificationPreference `json:"preferences"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/notifications/preferences", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Preferences, nil
+}
+
+// UpdateNotificationPreferences sets the channels of the given types and returns all preferences
+func (c *Client) UpdateNotificationPreferences(ctx context.Context, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	var out struct {
+		Preferences []NotificationPreference `json:"preferences"`
+	}
+	body := map[string][]NotificationPreference{"preferences": prefs}
+	if err := do(ctx, c, http.MethodPut, "/api/v1/notifications/preferences", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out.Preferences, nil
+}
+
+// PushDevice is a browser or app installation registered for push notifications
+type PushDevice struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Platform   string     `json:"platform"`
+	Token      string     `json:"token"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// PushConfig lists the platforms the server sends push notifications on
+type PushConfig struct {
+	Platforms []string `json:"platforms"`
+	// VAPIDPublicKey is the applicationServerKey for browser subscriptions when Web Push is enabled
+	VAPIDPublicKey string `json:"vapid_public_key,omitempty"`
+}
+
+// WebPushSubscription is the JSON form of a browser PushSubscription
+type WebPushSubscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256DH string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// RegisterPushDeviceRequest is the body of RegisterPushDevice. Platform is web, fcm or apns;
+// web devices set Subscription and the others Token.
+type RegisterPushDeviceRequest struct {
+	Platform     string               `json:"platform"`
+	Token        string               `json:"token,omitempty"`
+	Subscription *WebPushSubscription `json:"subscription,omitempty"`
+	Name         string               `json:"name,omitempty"`
+}
+
+// GetPushConfig returns the platforms devices can register for
+func (c *Client) GetPushConfig(ctx context.Context) (*PushConfig, error) {
+	var out PushConfig
+	if err := do(ctx, c, http.MethodGet, "/api/v1/push/config", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPushDevices returns the caller's registered push devices
+func (c *Client) ListPushDevices(ctx context.Context) ([]PushDevice, error) {
+	var out struct {
+		Devices []PushDevice `json:"devices"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/me/devices", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Devices, nil
+}
+
+// RegisterPushDevice registers a device for push notifications; registering it again updates it
+func (c *Client) RegisterPushDevice(ctx context.Context, req RegisterPushDeviceRequest) (*PushDevice, error) {
+	var out PushDevice
+	if err := do(ctx, c, http.MethodPost, "/api/v1/me/devices", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePushDevice stops push notifications to a device
+func (c *Client) DeletePushDevice(ctx context.Context, id string) error {
+	return do[struct{}](ctx, c, http.MethodDelete, "/api/v1/me/devices/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,356 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// PageOptions selects a page of a listing. Zero values use the server defaults.
+type PageOptions struct {
+	Page  int
+	Limit int
+}
+
+func (o PageOptions) values() url.Values {
+	q := url.Values{}
+	if o.Page > 0 {
+		q.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	return q
+}
+
+// OrganizationList is one page of organizations
+type OrganizationList struct {
+	Organizations []Organization `json:"organizations"`
+	Pagination    Pagination     `json:"pagination"`
+}
+
+// ListOrganizations returns one page of the caller's organizations
+func (c *Client) ListOrganizations(ctx context.Context, opts PageOptions) (*OrganizationList, error) {
+	var out OrganizationList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/organizations", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Organizations iterates over all of the caller's organizations, fetching pages as needed
+func (c *Client) Organizations(ctx context.Context, opts PageOptions) iter.Seq2[Organization, error] {
+	return paginate(opts, func(opts PageOptions) ([]Organization, Pagination, error) {
+		list, err := c.ListOrganizations(ctx, opts)
+		if err != nil {
+			return nil, Pagination{}, err
+		}
+		return list.Organizations, list.Pagination, nil
+	})
+}
+
+// CreateOrganizationRequest is the body of CreateOrganization
+type CreateOrganizationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// ExternalID is the organization's ID in an external system, unique among organizations
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// CreateOrganization creates an organization
+func (c *Client) CreateOrganization(ctx context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	var out Organization
+	if err := do(ctx, c, http.MethodPost, "/api/v1/organizations", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOrganization returns an organization
+func (c *Client) GetOrganization(ctx context.Context, id string) (*Organization, error) {
+	return c.GetOrganizationIfChanged(ctx, id, "")
+}
+
+// GetOrganizationIfChanged returns the organization unless it still matches etag, in which
+// case it returns ErrNotModified
+func (c *Client) GetOrganizationIfChanged(ctx context.Context, id, etag string) (*Organization, error) {
+	var out Organization
+	header, err := doWithHeaders(ctx, c, http.MethodGet, "/api/v1/organizations/"+url.PathEscape(id), nil, nil, conditional("If-None-Match", etag), &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// UpdateOrganizationRequest holds the fields to change; nil fields are left as they are
+type UpdateOrganizationRequest struct {
+	// Version is the version of the organization the change is based on
+	Version     int64           `json:"version"`
+	Name        *string         `json:"name,omitempty"`
+	Description *string         `json:"description,omitempty"`
+	Settings    json.RawMessage `json:"settings,omitempty"`
+	// PlaybackDomains replaces the organization's playback domains when set
+	PlaybackDomains *[]string `json:"playback_domains,omitempty"`
+	// VASTTagURL replaces the organization's ad tag when set; "" removes it
+	VASTTagURL *string `json:"vast_tag_url,omitempty"`
+	// RequireReview turns the approval of new videos on or off when set
+	RequireReview *bool `json:"require_review,omitempty"`
+	// ExternalID replaces the organization's external ID when set; "" removes it
+	ExternalID *string `json:"external_id,omitempty"`
+}
+
+// UpdateOrganization changes an organization. When the organization is no longer at
+// req.Version the update fails with a conflict; see IsConflict.
+func (c *Client) UpdateOrganization(ctx context.Context, id string, req UpdateOrganizationRequest, ifMatch string) (*Organization, error) {
+	var out Organization
+	header, err := doWithHeaders(ctx, c, http.MethodPatch, "/api/v1/organizations/"+url.PathEscape(id), nil, req, conditional("If-Match", ifMatch), &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// DeletionConfirmation is the token that confirms the deletion of an organization
+type DeletionConfirmation struct {
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// OrganizationTeardown is the progress of removing a deleted organization's data
+type OrganizationTeardown struct {
+	OrganizationID string     `json:"organization_id"`
+	Status         string     `json:"status"`
+	JobID          string     `json:"job_id,omitempty"`
+	Step           string     `json:"step,omitempty"`
+	ObjectsTotal   int64      `json:"objects_total"`
+	ObjectsDeleted int64      `json:"objects_deleted"`
+	BytesDeleted   int64      `json:"bytes_deleted"`
+	VideosTotal    int64      `json:"videos_total"`
+	VideosDeleted  int64      `json:"videos_deleted"`
+	LastError      string     `json:"last_error,omitempty"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// RequestOrganizationDeletion returns the token DeleteOrganization needs. Only an owner may
+// ask for it, and it expires after 15 minutes.
+func (c *Client) RequestOrganizationDeletion(ctx context.Context, id string) (*DeletionConfirmation, error) {
+	var out DeletionConfirmation
+	if err := do(ctx, c, http.MethodPost, "/api/v1/organizations/"+url.PathEscape(id)+"/deletion", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteOrganization deletes the organization for good. Its members and API keys lose access
+// at once; its files, videos and playback events are removed in the background, which
+// GetOrganizationTeardown reports on.
+func (c *Client) DeleteOrganization(ctx context.Context, id, confirmationToken string) (*OrganizationTeardown, error) {
+	var out OrganizationTeardown
+	body := map[string]string{"confirmation_token": confirmationToken}
+	if err := do(ctx, c, http.MethodDelete, "/api/v1/organizations/"+url.PathEscape(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOrganizationTeardown returns the progress of deleting an organization the caller
+// deleted
+func (c *Client) GetOrganizationTeardown(ctx context.Context, id string) (*OrganizationTeardown, error) {
+	var out OrganizationTeardown
+	if err := do(ctx, c, http.MethodGet, "/api/v1/organizations/"+url.PathEscape(id)+"/teardown", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Member is a user's role in an organization
+type Member struct {
+	UserID         string    `json:"user_id"`
+	OrganizationID string    `json:"organization_id"`
+	Role           string    `json:"role"`
+	InvitedBy      string    `json:"invited_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// AddMember gives the existing user with email a role (owner, admin, developer or viewer) in
+// the organization; the user is notified of the invitation
+func (c *Client) AddMember(ctx context.Context, orgID, email, role string) (*Member, error) {
+	var out Member
+	body := map[string]string{"email": email, "role": role}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/organizations/"+url.PathEscape(orgID)+"/members", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetBanner uploads a JPEG, PNG or WebP image as the organization's banner. It is cropped to
+// 4:1, so wide images keep the most content.
+func (c *Client) SetBanner(ctx context.Context, id, contentType string, data []byte) (*Organization, error) {
+	var out Organization
+	body := rawBody{contentType: contentType, data: data}
+	header, err := doWithHeaders(ctx, c, http.MethodPut, "/api/v1/organizations/"+url.PathEscape(id)+"/banner", nil, body, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// DeleteBanner removes the organization's banner
+func (c *Client) DeleteBanner(ctx context.Context, id string) (*Organization, error) {
+	var out Organization
+	header, err := doWithHeaders(ctx, c, http.MethodDelete, "/api/v1/organizations/"+url.PathEscape(id)+"/banner", nil, nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// Profile is the caller's own account
+type Profile struct {
+	ID        string `json:"id"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	Avatar    *Image `json:"avatar,omitempty"`
+	AvatarURL string `json:"avatar_url,omitempty"`
+	// Locale is a BCP 47 language tag and Timezone an IANA time zone name
+	Locale    string              `json:"locale"`
+	Timezone  string              `json:"timezone"`
+	Playback  PlaybackPreferences `json:"playback"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// PlaybackPreferences are how players start videos for a user
+type PlaybackPreferences struct {
+	Autoplay bool `json:"autoplay"`
+	// DefaultQuality is auto or a rendition height such as 720p
+	DefaultQuality string `json:"default_quality"`
+	CaptionsOn     bool   `json:"captions_on"`
+}
+
+// UpdateProfileRequest is the body of UpdateProfile; nil fields are left unchanged
+type UpdateProfileRequest struct {
+	// Name is the display name; an empty string removes it
+	Name     *string                    `json:"name,omitempty"`
+	Locale   *string                    `json:"locale,omitempty"`
+	Timezone *string                    `json:"timezone,omitempty"`
+	Playback *UpdatePlaybackPreferences `json:"playback,omitempty"`
+}
+
+// UpdatePlaybackPreferences changes playback preferences; nil fields are left unchanged
+type UpdatePlaybackPreferences struct {
+	Autoplay       *bool   `json:"autoplay,omitempty"`
+	DefaultQuality *string `json:"default_quality,omitempty"`
+	CaptionsOn     *bool   `json:"captions_on,omitempty"`
+}
+
+// GetProfile returns the caller's profile
+func (c *Client) GetProfile(ctx context.Context) (*Profile, error) {
+	var out Profile
+	if err := do(ctx, c, http.MethodGet, "/api/v1/me", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateProfile changes the caller's profile and playback preferences
+func (c *Client) UpdateProfile(ctx context.Context, req UpdateProfileRequest) (*Profile, error) {
+	var out Profile
+	if err := do(ctx, c, http.MethodPatch, "/api/v1/me", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Avatar is the caller's avatar and the public URL it is served at
+type Avatar struct {
+	Avatar Image  `json:"avatar"`
+	URL    string `json:"url"`
+}
+
+// SetAvatar uploads a JPEG, PNG or WebP image as the caller's avatar. It is cropped to a square.
+func (c *Client) SetAvatar(ctx context.Context, contentType string, data []byte) (*Avatar, error) {
+	var out Avatar
+	if err := do(ctx, c, http.MethodPut, "/api/v1/me/avatar", nil, rawBody{contentType: contentType, data: data}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAvatar removes the caller's avatar
+func (c *Client) DeleteAvatar(ctx context.Context) error {
+	return do[struct{}](ctx, c, http.MethodDelete, "/api/v1/me/avatar", nil, nil, nil)
+}
+
+// GetSession returns the caller's session
+func (c *Client) GetSession(ctx context.Context) (*Session, error) {
+	var out Session
+	if err := do(ctx, c, http.MethodGet, "/api/v1/sessions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// InvalidateSession drops the caller's cached session, e.g. after a role change
+func (c *Client) InvalidateSession(ctx context.Context) error {
+	return do[struct{}](ctx, c, http.MethodDelete, "/api/v1/sessions", nil, nil, nil)
+}
+
+// SwitchOrganization makes orgID the organization the caller's requests act in when the
+// client is not created WithOrgID, and returns the updated session
+func (c *Client) SwitchOrganization(ctx context.Context, orgID string) (*Session, error) {
+	body := struct {
+		OrganizationID string `json:"organization_id"`
+	}{orgID}
+	var out Session
+	if err := do(ctx, c, http.MethodPut, "/api/v1/sessions/organization", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// conditional returns a header with key set to etag, or nil for an empty etag
+func conditional(key, etag string) http.Header {
+	if etag == "" {
+		return nil
+	}
+	return http.Header{key: []string{etag}}
+}
+
+// paginate yields the items of consecutive pages until the total is reached or a page is empty
+func paginate[T any](opts PageOptions, fetch func(PageOptions) ([]T, Pagination, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		if opts.Page < 1 {
+			opts.Page = 1
+		}
+		seen := 0
+		for {
+			items, page, err := fetch(opts)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			seen += len(items)
+			if len(items) == 0 || seen >= page.Total {
+				return
+			}
+			opts.Page++
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"openvdo/internal/models"
+	"openvdo/pkg/client"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// organizationServer answers every request with data in the envelope the API writes
+func organizationServer(t *testing.T, status int, data interface{}) *client.Client {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.NoRoute(func(c *gin.Context) {
+		c.JSON(status, gin.H{"status": "success", "message": "Organization retrieved", "data": data})
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return client.New(server.URL)
+}
+
+func TestOrganizationTimestampsRoundTrip(t *testing.T) {
+	org := models.Organization{
+		ID:              uuid.New(),
+		Name:            "Acme",
+		Settings:        []byte(`{}`),
+		PlaybackDomains: []string{},
+		EmbedDomains:    []string{},
+		Version:         2,
+		CreatedAt:       time.Date(2026, 5, 1, 9, 30, 0, 123456000, time.FixedZone("CEST", 2*60*60)),
+		UpdatedAt:       time.Date(2026, 10, 16, 13, 0, 5, 0, time.UTC),
+	}
+	c := organizationServer(t, http.StatusOK, org)
+
+	got, err := c.GetOrganization(context.Background(), org.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(org.CreatedAt) {
+		t.Errorf("CreatedAt = %v, want %v", got.CreatedAt, org.CreatedAt)
+	}
+	if !got.UpdatedAt.Equal(org.UpdatedAt) {
+		t.Errorf("UpdatedAt = %v, want %v", got.UpdatedAt, org.UpdatedAt)
+	}
+}
+
+func TestCreateOrganizationReturnsTimestamps(t *testing.T) {
+	createdAt := time.Date(2026, 10, 16, 13, 0, 5, 0, time.UTC)
+	c := organizationServer(t, http.StatusCreated, gin.H{
+		"id":         uuid.New(),
+		"name":       "Acme",
+		"created_at": createdAt,
+		"updated_at": createdAt,
+		"pool_type":  "stateless",
+	})
+
+	got, err := c.CreateOrganization(context.Background(), client.CreateOrganizationRequest{Name: "Acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(createdAt) || !got.UpdatedAt.Equal(createdAt) {
+		t.Errorf("CreatedAt = %v and UpdatedAt = %v, want both %v", got.CreatedAt, got.UpdatedAt, createdAt)
+	}
+}
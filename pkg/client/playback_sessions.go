@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// PlaybackSession is a viewer's playback of a video, active while the player sends heartbeats
+type PlaybackSession struct {
+	ID              string     `json:"id"`
+	OrganizationID  string     `json:"organization_id"`
+	VideoID         string     `json:"video_id"`
+	UserID          string     `json:"user_id"`
+	ClientIP        string     `json:"client_ip"`
+	UserAgent       string     `json:"user_agent"`
+	PositionSeconds float64    `json:"position_seconds"`
+	WatchedSeconds  float64    `json:"watched_seconds"`
+	Heartbeats      int        `json:"heartbeats"`
+	Active          bool       `json:"active"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastHeartbeatAt time.Time  `json:"last_heartbeat_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+}
+
+// SessionPlayback is the short-lived token a playback session plays with. Heartbeats return a
+// fresh one; the heartbeat fields, Player, Ads and the series fields are only set when the
+// session starts, the latter for episodes of a series. NextEpisode is nil after the last episode.
+type SessionPlayback struct {
+	Session     PlaybackSession `json:"session"`
+	Token       string          `json:"token"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	ManifestURL string          `json:"manifest_url"`
+	EmbedURL    string          `json:"embed_url"`
+	// HeartbeatIntervalSeconds is how often the player must call HeartbeatPlaybackSession
+	HeartbeatIntervalSeconds int    `json:"heartbeat_interval_seconds,omitempty"`
+	HeartbeatURL             string `json:"heartbeat_url,omitempty"`
+	// Player is how the caller prefers videos to start
+	Player      *PlaybackPreferences `json:"player,omitempty"`
+	Ads         *PlaybackAds         `json:"ads,omitempty"`
+	Series      *PlaybackSeries      `json:"series,omitempty"`
+	Episode     *Episode             `json:"episode,omitempty"`
+	NextEpisode *NextEpisode         `json:"next_episode,omitempty"`
+}
+
+// ListPlaybackSessionsOptions filters and pages ListPlaybackSessions
+type ListPlaybackSessionsOptions struct {
+	PageOptions
+	VideoID string
+	UserID  string
+	// Active only returns sessions that are still playing
+	Active bool
+}
+
+func (o ListPlaybackSessionsOptions) values() url.Values {
+	q := o.PageOptions.values()
+	if o.VideoID != "" {
+		q.Set("video_id", o.VideoID)
+	}
+	if o.UserID != "" {
+		q.Set("user_id", o.UserID)
+	}
+	if o.Active {
+		q.Set("active", "true")
+	}
+	return q
+}
+
+// PlaybackSessionList is one page of playback sessions
+type PlaybackSessionList struct {
+	Sessions   []PlaybackSession `json:"sessions"`
+	Pagination Pagination        `json:"pagination"`
+}
+
+type sessionPosition struct {
+	PositionSeconds *float64 `json:"position_seconds,omitempty"`
+}
+
+// CreatePlaybackSession starts a playback session of a video. It fails with 429 when the
+// caller has reached the server's concurrent stream limit.
+func (c *Client) CreatePlaybackSession(ctx context.Context, videoID string) (*SessionPlayback, error) {
+	var out SessionPlayback
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(videoID)+"/playback-sessions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// HeartbeatPlaybackSession keeps a session active and records the position, if not nil. It
+// fails with 410 once the session has ended or expired.
+func (c *Client) HeartbeatPlaybackSession(ctx context.Context, id string, position *float64) (*SessionPlayback, error) {
+	var out SessionPlayback
+	body := sessionPosition{PositionSeconds: position}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/playback-sessions/"+url.PathEscape(id)+"/heartbeat", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EndPlaybackSession ends a session, recording the final position if not nil
+func (c *Client) EndPlaybackSession(ctx context.Context, id string, position *float64) (*PlaybackSession, error) {
+	var out PlaybackSession
+	body := sessionPosition{PositionSeconds: position}
+	if err := do(ctx, c, http.MethodDelete, "/api/v1/playback-sessions/"+url.PathEscape(id), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPlaybackSessions lists the playback sessions of the organization's videos, newest first
+func (c *Client) ListPlaybackSessions(ctx context.Context, opts ListPlaybackSessionsOptions) (*PlaybackSessionList, error) {
+	var out PlaybackSessionList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/playback-sessions", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
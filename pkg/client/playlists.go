@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SmartPlaylist is a saved search whose videos are computed from its criteria when it is read
+type SmartPlaylist struct {
+	ID             string           `json:"id"`
+	OrganizationID string           `json:"organization_id"`
+	Title          string           `json:"title"`
+	Description    string           `json:"description"`
+	Criteria       PlaylistCriteria `json:"criteria"`
+	// Sort is newest, oldest or title
+	Sort      string    `json:"sort"`
+	MaxItems  int       `json:"max_items"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	Version   int64     `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Videos are only returned by GetSmartPlaylist
+	Videos []Video `json:"videos,omitempty"`
+	// ETag is set by GetSmartPlaylist and UpdateSmartPlaylist for conditional requests
+	ETag string `json:"-"`
+}
+
+// PlaylistCriteria select the videos of a smart playlist; a video must match all that are set
+type PlaylistCriteria struct {
+	// Query matches text in the title or description
+	Query     string   `json:"query,omitempty"`
+	ProjectID *string  `json:"project_id,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	// Visibility is public, unlisted or private
+	Visibility string `json:"visibility,omitempty"`
+	// Fields match custom field values exactly; FieldsMin and FieldsMax bound number and date fields
+	Fields        map[string]string `json:"fields,omitempty"`
+	FieldsMin     map[string]string `json:"fields_min,omitempty"`
+	FieldsMax     map[string]string `json:"fields_max,omitempty"`
+	CreatedAfter  *time.Time        `json:"created_after,omitempty"`
+	CreatedBefore *time.Time        `json:"created_before,omitempty"`
+}
+
+// CreateSmartPlaylistRequest is the body of CreateSmartPlaylist
+type CreateSmartPlaylistRequest struct {
+	Title       string           `json:"title"`
+	Description string           `json:"description,omitempty"`
+	Criteria    PlaylistCriteria `json:"criteria"`
+	// Sort is newest (the default), oldest or title
+	Sort string `json:"sort,omitempty"`
+	// MaxItems bounds the videos listed, 100 by default and at most 500
+	MaxItems int `json:"max_items,omitempty"`
+}
+
+// UpdateSmartPlaylistRequest is the body of UpdateSmartPlaylist; nil fields are left unchanged
+type UpdateSmartPlaylistRequest struct {
+	// Version is the version of the playlist the change is based on
+	Version     int64   `json:"version"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	// Criteria replaces all criteria of the playlist
+	Criteria *PlaylistCriteria `json:"criteria,omitempty"`
+	Sort     *string           `json:"sort,omitempty"`
+	MaxItems *int              `json:"max_items,omitempty"`
+}
+
+// SmartPlaylistList is one page of smart playlists
+type SmartPlaylistList struct {
+	Playlists  []SmartPlaylist `json:"playlists"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+// ListSmartPlaylists lists the smart playlists of the current organization, newest first
+func (c *Client) ListSmartPlaylists(ctx context.Context, opts PageOptions) (*SmartPlaylistList, error) {
+	var out SmartPlaylistList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/playlists", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateSmartPlaylist saves criteria as a smart playlist of the current organization
+func (c *Client) CreateSmartPlaylist(ctx context.Context, req CreateSmartPlaylistRequest) (*SmartPlaylist, error) {
+	var out SmartPlaylist
+	if err := do(ctx, c, http.MethodPost, "/api/v1/playlists", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSmartPlaylist returns a smart playlist with the videos its criteria currently select
+func (c *Client) GetSmartPlaylist(ctx context.Context, id string) (*SmartPlaylist, error) {
+	var out SmartPlaylist
+	header, err := doWithHeaders(ctx, c, http.MethodGet, "/api/v1/playlists/"+url.PathEscape(id), nil, nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// UpdateSmartPlaylist changes a smart playlist. When it is no longer at req.Version the update
+// fails with a conflict; see IsConflict. A non-empty ifMatch is sent as an additional precondition.
+func (c *Client) UpdateSmartPlaylist(ctx context.Context, id string, req UpdateSmartPlaylistRequest, ifMatch string) (*SmartPlaylist, error) {
+	var out SmartPlaylist
+	header, err := doWithHeaders(ctx, c, http.MethodPatch, "/api/v1/playlists/"+url.PathEscape(id), nil, req, conditional("If-Match", ifMatch), &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// DeleteSmartPlaylist deletes a smart playlist; its videos are kept
+func (c *Client) DeleteSmartPlaylist(ctx context.Context, id string) error {
+	return do[struct{}](ctx, c, http.MethodDelete, "/api/v1/playlists/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Region is a region with the content it holds, as the admin API reports it
+type Region struct {
+	Name          string `json:"name"`
+	Home          bool   `json:"home"`
+	Organizations int64  `json:"organizations"`
+	Videos        int64  `json:"videos"`
+	Bytes         int64  `json:"bytes"`
+	QueuedJobs    int64  `json:"queued_jobs"`
+}
+
+// ListRegions returns every configured region; it needs the admin token
+func (c *Client) ListRegions(ctx context.Context) ([]Region, error) {
+	var out struct {
+		Regions []Region `json:"regions"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/regions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Regions, nil
+}
+
+// SetOrganizationRegion pins an organization without videos to a region; it needs the admin
+// token
+func (c *Client) SetOrganizationRegion(ctx context.Context, orgID, region string) error {
+	body := struct {
+		Region string `json:"region"`
+	}{region}
+	return do[struct{}](ctx, c, http.MethodPut, "/admin/v1/organizations/"+url.PathEscape(orgID)+"/region", nil, body, nil)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Review is one submission of a video for approval
+type Review struct {
+	ID          string  `json:"id"`
+	VideoID     string  `json:"video_id"`
+	VideoTitle  string  `json:"video_title"`
+	SubmittedBy *string `json:"submitted_by"`
+	// Status is "pending", "approved" or "rejected"
+	Status       string     `json:"status"`
+	ReviewedBy   *string    `json:"reviewed_by"`
+	DecisionNote string     `json:"decision_note"`
+	DecidedAt    *time.Time `json:"decided_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ReviewComment is a comment on a review; PositionSeconds pins it to a moment of the video
+type ReviewComment struct {
+	ID              string    `json:"id"`
+	ReviewID        string    `json:"review_id"`
+	AuthorID        *string   `json:"author_id"`
+	Body            string    `json:"body"`
+	PositionSeconds *float64  `json:"position_seconds"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ListReviewsOptions selects a page of reviews in a status
+type ListReviewsOptions struct {
+	PageOptions
+	// Status is "pending" (the default), "approved" or "rejected"
+	Status string
+}
+
+func (o ListReviewsOptions) values() url.Values {
+	q := o.PageOptions.values()
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	return q
+}
+
+// ReviewList is one page of reviews
+type ReviewList struct {
+	Reviews    []Review   `json:"reviews"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ListReviews returns one page of the current organization's reviews; pending reviews, the
+// review queue, come oldest first
+func (c *Client) ListReviews(ctx context.Context, opts ListReviewsOptions) (*ReviewList, error) {
+	var out ReviewList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/reviews", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReviewDetail is a review with its comments
+type ReviewDetail struct {
+	Review   Review          `json:"review"`
+	Comments []ReviewComment `json:"comments"`
+}
+
+// GetReview returns a review with its comments
+func (c *Client) GetReview(ctx context.Context, reviewID string) (*ReviewDetail, error) {
+	var out ReviewDetail
+	if err := do(ctx, c, http.MethodGet, "/api/v1/reviews/"+url.PathEscape(reviewID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AddReviewComment comments on a review; positionSeconds may be nil for a comment on the whole
+// video
+func (c *Client) AddReviewComment(ctx context.Context, reviewID, body string, positionSeconds *float64) (*ReviewComment, error) {
+	req := struct {
+		Body            string   `json:"body"`
+		PositionSeconds *float64 `json:"position_seconds,omitempty"`
+	}{body, positionSeconds}
+	var out ReviewComment
+	if err := do(ctx, c, http.MethodPost, "/api/v1/reviews/"+url.PathEscape(reviewID)+"/comments", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ApproveReview approves a pending review, publishing its video; note may be empty
+func (c *Client) ApproveReview(ctx context.Context, reviewID, note string) (*Review, error) {
+	return c.decideReview(ctx, reviewID, "approve", note)
+}
+
+// RejectReview rejects a pending review; note tells the submitter why and is required
+func (c *Client) RejectReview(ctx context.Context, reviewID, note string) (*Review, error) {
+	return c.decideReview(ctx, reviewID, "reject", note)
+}
+
+func (c *Client) decideReview(ctx context.Context, reviewID, decision, note string) (*Review, error) {
+	body := struct {
+		Note string `json:"note,omitempty"`
+	}{note}
+	var out Review
+	if err := do(ctx, c, http.MethodPost, "/api/v1/reviews/"+url.PathEscape(reviewID)+"/"+decision, nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SubmitVideoForReview submits a rejected video for approval again
+func (c *Client) SubmitVideoForReview(ctx context.Context, videoID string) (*Review, error) {
+	var out Review
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(videoID)+"/reviews", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListVideoReviews returns every review of a video, newest first
+func (c *Client) ListVideoReviews(ctx context.Context, videoID string) ([]Review, error) {
+	var out struct {
+		Reviews []Review `json:"reviews"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(videoID)+"/reviews", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Reviews, nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ScheduledTask is a recurring maintenance task with the outcome of its last run
+type ScheduledTask struct {
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Schedule        string     `json:"schedule"`
+	DefaultSchedule string     `json:"default_schedule"`
+	Enabled         bool       `json:"enabled"`
+	Running         bool       `json:"running"`
+	NextRunAt       *time.Time `json:"next_run_at,omitempty"`
+	LastStartedAt   *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt  *time.Time `json:"last_finished_at,omitempty"`
+	// LastStatus is "running", "succeeded" or "failed"
+	LastStatus     *string `json:"last_status,omitempty"`
+	LastError      *string `json:"last_error,omitempty"`
+	LastResult     *string `json:"last_result,omitempty"`
+	LastDurationMs *int64  `json:"last_duration_ms,omitempty"`
+}
+
+// UpdateScheduleRequest changes a scheduled task; nil fields are left as they are, and an
+// empty Schedule restores the default
+type UpdateScheduleRequest struct {
+	Enabled  *bool   `json:"enabled,omitempty"`
+	Schedule *string `json:"schedule,omitempty"`
+}
+
+// ListSchedules returns the scheduled tasks; it needs the admin token
+func (c *Client) ListSchedules(ctx context.Context) ([]ScheduledTask, error) {
+	var out []ScheduledTask
+	if err := do(ctx, c, http.MethodGet, "/admin/v1/schedules", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateSchedule pauses, resumes or reschedules a task; it needs the admin token
+func (c *Client) UpdateSchedule(ctx context.Context, name string, req UpdateScheduleRequest) (*ScheduledTask, error) {
+	var out ScheduledTask
+	if err := do(ctx, c, http.MethodPatch, "/admin/v1/schedules/"+url.PathEscape(name), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RunSchedule makes a task due now, for the leader to start shortly; it needs the admin token
+func (c *Client) RunSchedule(ctx context.Context, name string) (*ScheduledTask, error) {
+	var out ScheduledTask
+	if err := do(ctx, c, http.MethodPost, "/admin/v1/schedules/"+url.PathEscape(name)+"/run", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
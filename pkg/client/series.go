@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Series groups videos into episodes numbered within seasons
+type Series struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	ProjectID      *string   `json:"project_id,omitempty"`
+	Title          string    `json:"title"`
+	Description    string    `json:"description"`
+	Artwork        *Image    `json:"artwork,omitempty"`
+	CreatedBy      *string   `json:"created_by,omitempty"`
+	Version        int64     `json:"version"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// Episodes are only returned by GetSeries and SetSeriesEpisodes
+	Episodes []Episode `json:"episodes,omitempty"`
+	// ETag is set by GetSeries and UpdateSeries for conditional requests
+	ETag string `json:"-"`
+}
+
+// Episode is a video's place in a series
+type Episode struct {
+	SeriesID      string `json:"series_id"`
+	VideoID       string `json:"video_id"`
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	Title         string `json:"title"`
+	Status        string `json:"status"`
+}
+
+// EpisodeSpec places a video in a series. A nil SeasonNumber continues the season of the
+// previous entry, or season 1, and a nil EpisodeNumber follows the previous episode of the season.
+type EpisodeSpec struct {
+	VideoID       string `json:"video_id"`
+	SeasonNumber  *int   `json:"season_number,omitempty"`
+	EpisodeNumber *int   `json:"episode_number,omitempty"`
+}
+
+// CreateSeriesRequest is the body of CreateSeries
+type CreateSeriesRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ProjectID   string `json:"project_id,omitempty"`
+}
+
+// UpdateSeriesRequest is the body of UpdateSeries; nil fields are left unchanged
+type UpdateSeriesRequest struct {
+	// Version is the version of the series the change is based on
+	Version     int64   `json:"version"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	// ProjectID moves the series to a project; an empty string removes it from its project
+	ProjectID *string `json:"project_id,omitempty"`
+}
+
+// ListSeriesOptions filters and pages ListSeries
+type ListSeriesOptions struct {
+	PageOptions
+	ProjectID string
+}
+
+func (o ListSeriesOptions) values() url.Values {
+	q := o.PageOptions.values()
+	if o.ProjectID != "" {
+		q.Set("project_id", o.ProjectID)
+	}
+	return q
+}
+
+// SeriesList is one page of series
+type SeriesList struct {
+	Series     []Series   `json:"series"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ListSeries lists the series of the current organization, newest first
+func (c *Client) ListSeries(ctx context.Context, opts ListSeriesOptions) (*SeriesList, error) {
+	var out SeriesList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/series", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateSeries creates a series in the current organization
+func (c *Client) CreateSeries(ctx context.Context, req CreateSeriesRequest) (*Series, error) {
+	var out Series
+	if err := do(ctx, c, http.MethodPost, "/api/v1/series", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSeries returns a series with its episodes in order
+func (c *Client) GetSeries(ctx context.Context, id string) (*Series, error) {
+	var out Series
+	header, err := doWithHeaders(ctx, c, http.MethodGet, "/api/v1/series/"+url.PathEscape(id), nil, nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// UpdateSeries changes a series. When the series is no longer at req.Version the update fails
+// with a conflict; see IsConflict. A non-empty ifMatch is sent as an additional precondition.
+func (c *Client) UpdateSeries(ctx context.Context, id string, req UpdateSeriesRequest, ifMatch string) (*Series, error) {
+	var out Series
+	header, err := doWithHeaders(ctx, c, http.MethodPatch, "/api/v1/series/"+url.PathEscape(id), nil, req, conditional("If-Match", ifMatch), &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// DeleteSeries deletes a series; the videos of its episodes are kept
+func (c *Client) DeleteSeries(ctx context.Context, id string) error {
+	return do[struct{}](ctx, c, http.MethodDelete, "/api/v1/series/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// SetSeriesEpisodes replaces the episodes of a series and returns it with the new episodes
+func (c *Client) SetSeriesEpisodes(ctx context.Context, id string, episodes []EpisodeSpec) (*Series, error) {
+	var out Series
+	body := map[string][]EpisodeSpec{"episodes": episodes}
+	if err := do(ctx, c, http.MethodPut, "/api/v1/series/"+url.PathEscape(id)+"/episodes", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetSeriesArtwork uploads a JPEG, PNG or WebP image as the series' artwork, cropped to 16:9.
+// contentType is the image's MIME type, e.g. "image/jpeg".
+func (c *Client) SetSeriesArtwork(ctx context.Context, id, contentType string, data []byte) (*Series, error) {
+	var out Series
+	body := rawBody{contentType: contentType, data: data}
+	if err := do(ctx, c, http.MethodPut, "/api/v1/series/"+url.PathEscape(id)+"/artwork", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSeriesArtwork removes the series' artwork
+func (c *Client) DeleteSeriesArtwork(ctx context.Context, id string) (*Series, error) {
+	var out Series
+	if err := do(ctx, c, http.MethodDelete, "/api/v1/series/"+url.PathEscape(id)+"/artwork", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PlaybackSeries is the series of the episode a playback session plays
+type PlaybackSeries struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	ArtworkURL string `json:"artwork_url,omitempty"`
+}
+
+// NextEpisode is the episode after the one playing that can be played, with the URL to start
+// its playback session
+type NextEpisode struct {
+	Episode
+	PlaybackSessionURL string `json:"playback_session_url"`
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ShareLink opens a video to anyone holding URL, whatever the video's visibility, until it is
+// revoked, expires or runs out of views
+type ShareLink struct {
+	ID        string     `json:"id"`
+	VideoID   string     `json:"video_id"`
+	CreatedBy *string    `json:"created_by"`
+	Slug      string     `json:"slug"`
+	URL       string     `json:"url"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// MaxViews is nil for links with unlimited views
+	MaxViews      *int       `json:"max_views"`
+	ViewCount     int        `json:"view_count"`
+	AllowDownload bool       `json:"allow_download"`
+	AllowComments bool       `json:"allow_comments"`
+	Active        bool       `json:"active"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	LastViewedAt  *time.Time `json:"last_viewed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CreateShareLinkRequest sets the limits and permissions of a share link; the zero value never
+// expires and only plays the video
+type CreateShareLinkRequest struct {
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	MaxViews      *int       `json:"max_views,omitempty"`
+	AllowDownload bool       `json:"allow_download,omitempty"`
+	AllowComments bool       `json:"allow_comments,omitempty"`
+}
+
+// CreateShareLink creates a public link to a video
+func (c *Client) CreateShareLink(ctx context.Context, videoID string, req CreateShareLinkRequest) (*ShareLink, error) {
+	var out ShareLink
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(videoID)+"/share-links", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListShareLinks returns the share links of a video, newest first, including inactive ones
+func (c *Client) ListShareLinks(ctx context.Context, videoID string) ([]ShareLink, error) {
+	var out struct {
+		ShareLinks []ShareLink `json:"share_links"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(videoID)+"/share-links", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.ShareLinks, nil
+}
+
+// RevokeShareLink stops a share link from working
+func (c *Client) RevokeShareLink(ctx context.Context, videoID, linkID string) (*ShareLink, error) {
+	var out ShareLink
+	path := "/api/v1/videos/" + url.PathEscape(videoID) + "/share-links/" + url.PathEscape(linkID)
+	if err := do(ctx, c, http.MethodDelete, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Rendition is a rendition of a video hosted by a transcoder or an encoder
+type Rendition struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ContentType  string `json:"content_type"`
+	Width        *int   `json:"width"`
+	Height       *int   `json:"height"`
+	BitrateKbps  *int   `json:"bitrate_kbps"`
+	Codec        string `json:"codec"`
+	DynamicRange string `json:"dynamic_range,omitempty"`
+}
+
+// SourceVersion is a source a video had before it was replaced, kept until ExpiresAt so the
+// video can be rolled back to it
+type SourceVersion struct {
+	ID          string      `json:"id"`
+	VideoID     string      `json:"video_id"`
+	Revision    int         `json:"revision"`
+	SourceKey   string      `json:"source_key"`
+	ContentType string      `json:"content_type,omitempty"`
+	SizeBytes   int64       `json:"size_bytes"`
+	SHA256      *string     `json:"sha256,omitempty"`
+	Color       *ColorInfo  `json:"color,omitempty"`
+	Renditions  []Rendition `json:"renditions"`
+	// TierStatus is "archived" or "restoring" for versions that must be restored before a rollback
+	TierStatus string    `json:"tier_status"`
+	ReplacedBy *string   `json:"replaced_by"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SourceVersions is a video's current source revision and its kept versions, newest first
+type SourceVersions struct {
+	SourceRevision int             `json:"source_revision"`
+	Versions       []SourceVersion `json:"versions"`
+}
+
+// ListSourceVersions returns the source versions kept for a video
+func (c *Client) ListSourceVersions(ctx context.Context, videoID string) (*SourceVersions, error) {
+	var out SourceVersions
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(videoID)+"/source-versions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RollbackSource makes the version kept for revision the video's source again and returns the
+// video, ready with the version's renditions
+func (c *Client) RollbackSource(ctx context.Context, videoID string, revision int) (*Video, error) {
+	var out Video
+	path := "/api/v1/videos/" + url.PathEscape(videoID) + "/source-versions/" + strconv.Itoa(revision) + "/rollback"
+	if err := do(ctx, c, http.MethodPost, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,288 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Pagination describes the page of a listing
+type Pagination struct {
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Total int `json:"total"`
+	// TotalEstimated tells that Total is the query planner's estimate for a large listing
+	TotalEstimated bool `json:"total_estimated"`
+}
+
+// Organization is an organization the caller is a member of
+type Organization struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Settings    json.RawMessage `json:"settings,omitempty"`
+	// ExternalID is the organization's ID in an external system
+	ExternalID *string `json:"external_id,omitempty"`
+	// PlaybackDomains are the origins allowed to embed the organization's videos
+	PlaybackDomains []string `json:"playback_domains,omitempty"`
+	// VASTTagURL is the ad tag players of the organization's videos request ads from
+	VASTTagURL *string `json:"vast_tag_url,omitempty"`
+	// RequireReview holds videos created by developers until an owner or admin approves them
+	RequireReview bool `json:"require_review"`
+	// Region is the region the organization's data is pinned to; nil means the home region
+	Region    *string   `json:"region,omitempty"`
+	Banner    *Image    `json:"banner,omitempty"`
+	Version   int64     `json:"version,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// ETag is set by GetOrganization and UpdateOrganization for conditional requests
+	ETag string `json:"-"`
+}
+
+// Session is the caller's cached organization membership
+type Session struct {
+	UserID string `json:"user_id"`
+	// OrgID and Role are those of the organization requests act in by default
+	OrgID string `json:"org_id"`
+	Role  string `json:"role"`
+	// Organizations maps every organization the caller belongs to to their role in it
+	Organizations map[string]string `json:"organizations"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+}
+
+// Video is an uploaded video
+type Video struct {
+	ID             string  `json:"id"`
+	OrganizationID string  `json:"organization_id"`
+	ProjectID      *string `json:"project_id,omitempty"`
+	// ExternalID is the video's ID in an external system, unique within the organization
+	ExternalID  *string `json:"external_id,omitempty"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	// Status is "uploading", "queued", "processing", "ready", "failed" or "deleted"
+	Status string `json:"status"`
+	// StatusChangedAt is when the video entered its status
+	StatusChangedAt time.Time `json:"status_changed_at"`
+	Visibility      string    `json:"visibility"`
+	// RequiresEntitlement limits playback sessions to viewers with an entitlement
+	RequiresEntitlement bool `json:"requires_entitlement"`
+	// DownloadsEnabled lets members request files of the video to download
+	DownloadsEnabled bool `json:"downloads_enabled"`
+	// ModerationStatus is "active", "restricted" while reports wait for review, or "taken_down"
+	ModerationStatus string `json:"moderation_status"`
+	// ReviewStatus is "approved", or "pending" or "rejected" for videos that need approval
+	ReviewStatus string   `json:"review_status"`
+	Tags         []string `json:"tags"`
+	// CustomFields are the values of the organization's custom fields by key: strings, float64
+	// numbers, and dates as YYYY-MM-DD strings
+	CustomFields   map[string]interface{} `json:"custom_fields"`
+	Thumbnail      *Image                 `json:"thumbnail,omitempty"`
+	Chapters       []Chapter              `json:"chapters"`
+	AdBreaks       []AdBreak              `json:"ad_breaks"`
+	SourceKey      string                 `json:"source_key,omitempty"`
+	ContentType    string                 `json:"content_type,omitempty"`
+	SizeBytes      int64                  `json:"size_bytes"`
+	SHA256         *string                `json:"sha256,omitempty"`
+	DuplicateOf    *string                `json:"duplicate_of,omitempty"`
+	SourceRevision int                    `json:"source_revision"`
+	// Color is the source's color signaling; nil until the source is probed
+	Color      *ColorInfo `json:"color,omitempty"`
+	ReplacedAt *time.Time `json:"replaced_at,omitempty"`
+	CreatedBy  *string    `json:"created_by,omitempty"`
+	Version    int64      `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	// ETag is set by GetVideo and UpdateVideo for conditional requests
+	ETag string `json:"-"`
+}
+
+// ColorInfo is the color signaling of a video's source
+type ColorInfo struct {
+	// DynamicRange is "sdr", "hdr10", "hlg" or "dolby_vision"
+	DynamicRange     string `json:"dynamic_range"`
+	ColorPrimaries   string `json:"color_primaries,omitempty"`
+	Transfer         string `json:"transfer,omitempty"`
+	Matrix           string `json:"matrix,omitempty"`
+	BitDepth         int    `json:"bit_depth,omitempty"`
+	MaxCLL           int    `json:"max_cll,omitempty"`
+	MaxFALL          int    `json:"max_fall,omitempty"`
+	MasteringDisplay bool   `json:"mastering_display"`
+	DolbyVision      *struct {
+		Profile       int `json:"profile"`
+		Level         int `json:"level"`
+		Compatibility int `json:"compatibility"`
+	} `json:"dolby_vision,omitempty"`
+	SourceRevision int       `json:"source_revision"`
+	ProbedAt       time.Time `json:"probed_at"`
+}
+
+// HDR reports whether the source has a high dynamic range
+func (c *ColorInfo) HDR() bool {
+	return c != nil && c.DynamicRange != "" && c.DynamicRange != "sdr"
+}
+
+// Chapter is a titled section of a video's timeline. EndSeconds is only returned by
+// GetChapters, and not for the last chapter, which runs to the end of the video.
+type Chapter struct {
+	StartSeconds float64  `json:"start_seconds"`
+	EndSeconds   *float64 `json:"end_seconds,omitempty"`
+	Title        string   `json:"title"`
+}
+
+// AdBreak is a cue point where players insert ads for at most DurationSeconds
+type AdBreak struct {
+	OffsetSeconds   float64 `json:"offset_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// PlaybackAds are a video's ad breaks and the VAST tag to request their ads from
+type PlaybackAds struct {
+	VASTTagURL string    `json:"vast_tag_url,omitempty"`
+	Breaks     []AdBreak `json:"breaks"`
+}
+
+// Image is an uploaded image stored as resized variants in several formats
+type Image struct {
+	ID        string         `json:"id"`
+	Source    string         `json:"source"`
+	Width     int            `json:"width"`
+	Height    int            `json:"height"`
+	Variants  []ImageVariant `json:"variants"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// ImageVariant is one size and format of an Image
+type ImageVariant struct {
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Format      string `json:"format"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+}
+
+// Job is a background job such as a URL import
+type Job struct {
+	ID          string          `json:"id"`
+	ParentID    *string         `json:"parent_id,omitempty"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Checkpoint  json.RawMessage `json:"checkpoint,omitempty"`
+	Status      string          `json:"status"`
+	Progress    float64         `json:"progress"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	// Failures counts failed runs across retries; Retries counts manual retries
+	Failures   int        `json:"failures"`
+	Retries    int        `json:"retries"`
+	LastError  *string    `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Children counts the jobs spawned by this one, such as the imports of a bulk import, by
+	// status. Only GetJob fills it in.
+	Children map[string]int `json:"children,omitempty"`
+}
+
+// Done reports whether the job succeeded or failed for good
+func (j *Job) Done() bool {
+	return j.Status == "succeeded" || j.Status == "failed" || j.Status == "quarantined"
+}
+
+// PlaybackToken lets the embedded player play a video until it expires
+type PlaybackToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	EmbedURL  string    `json:"embed_url"`
+	MediaURL  string    `json:"media_url"`
+	// Ads is nil when talking to servers without ad support
+	Ads *PlaybackAds `json:"ads,omitempty"`
+}
+
+// VideoDownload is a file rendered from a video for download. URL is set once Status is
+// "ready", and stops working at URLExpiresAt.
+type VideoDownload struct {
+	ID      string `json:"id"`
+	VideoID string `json:"video_id"`
+	// RenditionID is the rendition the file was made from; nil for the source
+	RenditionID *string `json:"rendition_id"`
+	// Watermark is the email burned into the picture, if any
+	Watermark *string `json:"watermark,omitempty"`
+	// Status is "pending", "ready" or "failed"
+	Status       string     `json:"status"`
+	SizeBytes    *int64     `json:"size_bytes,omitempty"`
+	Error        *string    `json:"error,omitempty"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	URL          string     `json:"url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Upload is a multipart upload whose parts go directly to object storage
+type Upload struct {
+	ID             string    `json:"id"`
+	VideoID        string    `json:"video_id"`
+	OrganizationID string    `json:"organization_id"`
+	StorageKey     string    `json:"storage_key"`
+	PartSize       int64     `json:"part_size"`
+	PartCount      int       `json:"part_count"`
+	Status         string    `json:"status"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Replacement is set for uploads that replace the source of an existing video
+	Replacement bool `json:"replacement,omitempty"`
+}
+
+// PresignedPart is a URL to PUT one part of an upload to
+type PresignedPart struct {
+	PartNumber int32  `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+// CompletedPart is an uploaded part with the ETag storage returned for it
+type CompletedPart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+}
+
+// StorageObject is a stored file of a video and its storage tier state
+type StorageObject struct {
+	ID                 string     `json:"id"`
+	OrganizationID     string     `json:"organization_id"`
+	VideoID            *string    `json:"video_id,omitempty"`
+	ObjectKey          string     `json:"object_key"`
+	Kind               string     `json:"kind"`
+	SizeBytes          int64      `json:"size_bytes"`
+	TierStatus         string     `json:"tier_status"`
+	StorageClass       string     `json:"storage_class"`
+	LastAccessedAt     time.Time  `json:"last_accessed_at"`
+	ArchivedAt         *time.Time `json:"archived_at,omitempty"`
+	RestoreRequestedAt *time.Time `json:"restore_requested_at,omitempty"`
+	RestoreExpiresAt   *time.Time `json:"restore_expires_at,omitempty"`
+	LastError          *string    `json:"last_error,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// BuildInfo is the build of a server
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// CheckResult is the outcome of one readiness check
+type CheckResult struct {
+	Healthy    bool      `json:"healthy"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"duration_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Readiness is the aggregated readiness of the server's dependencies
+type Readiness struct {
+	Ready     bool                   `json:"ready"`
+	Version   string                 `json:"version"`
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
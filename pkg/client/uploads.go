@@ -0,0 +1,215 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CreateUploadRequest is the body of CreateUpload
+type CreateUploadRequest struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description,omitempty"`
+	Filename    string  `json:"filename,omitempty"`
+	ContentType string  `json:"content_type,omitempty"`
+	SizeBytes   int64   `json:"size_bytes"`
+	ProjectID   *string `json:"project_id,omitempty"`
+	// SHA256 lets the server detect content the organization already has
+	SHA256 string `json:"sha256,omitempty"`
+	// ExternalID is the video's ID in an external system, unique within the organization
+	ExternalID string `json:"external_id,omitempty"`
+	// CustomFields are values of the organization's custom fields by key
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// CreatedUpload is the result of CreateUpload. With the reuse duplicate policy Deduplicated
+// is set, Video holds the new video and there is nothing to upload.
+type CreatedUpload struct {
+	Upload       *Upload         `json:"upload,omitempty"`
+	Parts        []PresignedPart `json:"parts,omitempty"`
+	DuplicateOf  *string         `json:"duplicate_of,omitempty"`
+	Warning      string          `json:"warning,omitempty"`
+	Video        *Video          `json:"video,omitempty"`
+	Deduplicated bool            `json:"deduplicated,omitempty"`
+}
+
+// UploadParts is a batch of presigned part URLs
+type UploadParts struct {
+	Upload Upload          `json:"upload"`
+	Parts  []PresignedPart `json:"parts"`
+}
+
+// CreateUpload starts a multipart upload and returns presigned URLs for the first parts
+func (c *Client) CreateUpload(ctx context.Context, req CreateUploadRequest) (*CreatedUpload, error) {
+	var out CreatedUpload
+	if err := do(ctx, c, http.MethodPost, "/api/v1/uploads/multipart", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUploadParts presigns count parts starting at part number start
+func (c *Client) GetUploadParts(ctx context.Context, uploadID string, start, count int) (*UploadParts, error) {
+	q := url.Values{}
+	q.Set("start", strconv.Itoa(start))
+	q.Set("count", strconv.Itoa(count))
+
+	var out UploadParts
+	if err := do(ctx, c, http.MethodGet, "/api/v1/uploads/multipart/"+url.PathEscape(uploadID)+"/parts", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CompleteUpload assembles the uploaded parts. The returned upload's VideoID is the new video.
+func (c *Client) CompleteUpload(ctx context.Context, uploadID string, parts []CompletedPart) (*Upload, error) {
+	var out struct {
+		Upload Upload `json:"upload"`
+	}
+	body := map[string]any{"parts": parts}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/uploads/multipart/"+url.PathEscape(uploadID)+"/complete", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Upload, nil
+}
+
+// AbortUpload discards the uploaded parts and marks the video as failed
+func (c *Client) AbortUpload(ctx context.Context, uploadID string) error {
+	return do[struct{}](ctx, c, http.MethodDelete, "/api/v1/uploads/multipart/"+url.PathEscape(uploadID), nil, nil, nil)
+}
+
+// Upload runs the whole multipart flow for the req.SizeBytes bytes of r: it starts the
+// upload, PUTs every part to its presigned URL and completes it. The upload is aborted if a
+// part fails. It returns the ID of the new (or, when deduplicated, reused) video.
+func (c *Client) Upload(ctx context.Context, req CreateUploadRequest, r io.ReaderAt) (string, error) {
+	created, err := c.CreateUpload(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if created.Deduplicated && created.Video != nil {
+		return created.Video.ID, nil
+	}
+	upload := created.Upload
+
+	completed, err := c.uploadParts(ctx, upload, created.Parts, req.SizeBytes, r)
+	if err != nil {
+		c.AbortUpload(context.WithoutCancel(ctx), upload.ID)
+		return "", err
+	}
+
+	done, err := c.CompleteUpload(ctx, upload.ID, completed)
+	if err != nil {
+		return "", err
+	}
+	return done.VideoID, nil
+}
+
+// ReplaceSourceRequest is the body of CreateReplacement
+type ReplaceSourceRequest struct {
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	SizeBytes   int64  `json:"size_bytes"`
+	// Version, when set, makes the request fail with a conflict if the video changed since
+	Version *int64 `json:"version,omitempty"`
+}
+
+// CreateReplacement starts a multipart upload of a new source for an existing video. Upload
+// the parts and complete it like any other upload; the video switches sources on completion.
+func (c *Client) CreateReplacement(ctx context.Context, videoID string, req ReplaceSourceRequest) (*CreatedUpload, error) {
+	var out CreatedUpload
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(videoID)+"/replace", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplaceSource runs the whole replacement flow for the req.SizeBytes bytes of r and returns
+// the video on its new source. The video keeps its ID and playback URLs.
+func (c *Client) ReplaceSource(ctx context.Context, videoID string, req ReplaceSourceRequest, r io.ReaderAt) (*Video, error) {
+	created, err := c.CreateReplacement(ctx, videoID, req)
+	if err != nil {
+		return nil, err
+	}
+	upload := created.Upload
+
+	completed, err := c.uploadParts(ctx, upload, created.Parts, req.SizeBytes, r)
+	if err != nil {
+		c.AbortUpload(context.WithoutCancel(ctx), upload.ID)
+		return nil, err
+	}
+
+	var out struct {
+		Video Video `json:"video"`
+	}
+	body := map[string]any{"parts": completed}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/uploads/multipart/"+url.PathEscape(upload.ID)+"/complete", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out.Video, nil
+}
+
+func (c *Client) uploadParts(ctx context.Context, upload *Upload, parts []PresignedPart, size int64, r io.ReaderAt) ([]CompletedPart, error) {
+	completed := make([]CompletedPart, 0, upload.PartCount)
+	for next := 1; next <= upload.PartCount; {
+		if len(parts) == 0 {
+			batch, err := c.GetUploadParts(ctx, upload.ID, next, 100)
+			if err != nil {
+				return nil, err
+			}
+			parts = batch.Parts
+		}
+
+		for _, part := range parts {
+			offset := int64(part.PartNumber-1) * upload.PartSize
+			length := min(upload.PartSize, size-offset)
+
+			etag, err := c.putPart(ctx, part.URL, io.NewSectionReader(r, offset, length), length)
+			if err != nil {
+				return nil, fmt.Errorf("openvdo: part %d: %w", part.PartNumber, err)
+			}
+			completed = append(completed, CompletedPart{PartNumber: part.PartNumber, ETag: etag})
+			next = int(part.PartNumber) + 1
+		}
+		parts = nil
+	}
+	return completed, nil
+}
+
+// putPart uploads one part to storage, retrying transient failures
+func (c *Client) putPart(ctx context.Context, partURL string, body *io.SectionReader, length int64) (string, error) {
+	for attempt := 0; ; attempt++ {
+		body.Seek(0, io.SeekStart)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, partURL, body)
+		if err != nil {
+			return "", err
+		}
+		req.ContentLength = length
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+				return strings.TrimSpace(resp.Header.Get("ETag")), nil
+			}
+			if !retryableStatus(resp.StatusCode) && resp.StatusCode != http.StatusInternalServerError {
+				return "", &APIError{StatusCode: resp.StatusCode, Message: "storage rejected the part"}
+			}
+			err = &APIError{StatusCode: resp.StatusCode, Message: "storage rejected the part"}
+		}
+		if attempt >= c.maxRetries || ctx.Err() != nil {
+			return "", err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(c.backoff(attempt)):
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// APIUsageOptions selects the periods of GetAPIUsage. Zero values report the last 30 days, or
+// the last 12 months with Granularity "month".
+type APIUsageOptions struct {
+	// Granularity is "day" or "month"
+	Granularity string
+	From        time.Time
+	To          time.Time
+	// ByAPIKey splits each period by API key
+	ByAPIKey bool
+}
+
+func (o APIUsageOptions) values() url.Values {
+	q := url.Values{}
+	if o.Granularity != "" {
+		q.Set("granularity", o.Granularity)
+	}
+	if !o.From.IsZero() {
+		q.Set("from", o.From.Format(time.DateOnly))
+	}
+	if !o.To.IsZero() {
+		q.Set("to", o.To.Format(time.DateOnly))
+	}
+	if o.ByAPIKey {
+		q.Set("group_by", "api_key")
+	}
+	return q
+}
+
+// UsageCounts are the API requests, 4xx and 5xx errors and bytes of a period
+type UsageCounts struct {
+	Requests     int64 `json:"requests"`
+	ClientErrors int64 `json:"client_errors"`
+	ServerErrors int64 `json:"server_errors"`
+	BytesIn      int64 `json:"bytes_in"`
+	BytesOut     int64 `json:"bytes_out"`
+}
+
+// UsagePeriod is the usage of one day or month. APIKeyID is set when the report is split by
+// API key and the requests used one.
+type UsagePeriod struct {
+	Start    string  `json:"start"`
+	APIKeyID *string `json:"api_key_id,omitempty"`
+	UsageCounts
+	ErrorRate float64 `json:"error_rate"`
+}
+
+// APIUsage is an organization's usage per period and in total
+type APIUsage struct {
+	Granularity string `json:"granularity"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Usage       struct {
+		Periods   []UsagePeriod `json:"periods"`
+		Total     UsageCounts   `json:"total"`
+		ErrorRate float64       `json:"error_rate"`
+	} `json:"usage"`
+}
+
+// GetAPIUsage reports an organization's API usage; it needs the owner or admin role
+func (c *Client) GetAPIUsage(ctx context.Context, orgID string, opts APIUsageOptions) (*APIUsage, error) {
+	var out APIUsage
+	if err := do(ctx, c, http.MethodGet, "/api/v1/organizations/"+url.PathEscape(orgID)+"/api-usage", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
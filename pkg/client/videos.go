@@ -0,0 +1,446 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListVideosOptions filters and pages ListVideos
+type ListVideosOptions struct {
+	PageOptions
+	// SHA256 only returns videos with this content hash
+	SHA256 string
+	// Status only returns videos in this status
+	Status string
+	// Fields only returns videos whose custom fields have these values, by key
+	Fields map[string]string
+	// FieldsMin and FieldsMax bound number and date custom fields, by key
+	FieldsMin map[string]string
+	FieldsMax map[string]string
+}
+
+func (o ListVideosOptions) values() url.Values {
+	q := o.PageOptions.values()
+	if o.SHA256 != "" {
+		q.Set("sha256", o.SHA256)
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	for param, fields := range map[string]map[string]string{"field": o.Fields, "field_min": o.FieldsMin, "field_max": o.FieldsMax} {
+		for key, value := range fields {
+			q.Set(param+"["+key+"]", value)
+		}
+	}
+	return q
+}
+
+// VideoList is one page of videos
+type VideoList struct {
+	Videos     []Video    `json:"videos"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// ListVideos returns one page of the organization's videos
+func (c *Client) ListVideos(ctx context.Context, opts ListVideosOptions) (*VideoList, error) {
+	var out VideoList
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos", opts.values(), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Videos iterates over all matching videos, fetching pages as needed
+func (c *Client) Videos(ctx context.Context, opts ListVideosOptions) iter.Seq2[Video, error] {
+	return paginate(opts.PageOptions, func(page PageOptions) ([]Video, Pagination, error) {
+		opts.PageOptions = page
+		list, err := c.ListVideos(ctx, opts)
+		if err != nil {
+			return nil, Pagination{}, err
+		}
+		return list.Videos, list.Pagination, nil
+	})
+}
+
+// GetVideo returns a video
+func (c *Client) GetVideo(ctx context.Context, id string) (*Video, error) {
+	return c.GetVideoIfChanged(ctx, id, "")
+}
+
+// GetVideoByExternalID returns the video of the current organization with an external ID
+func (c *Client) GetVideoByExternalID(ctx context.Context, externalID string) (*Video, error) {
+	var out Video
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/by-external-id/"+url.PathEscape(externalID), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetVideoIfChanged returns the video unless it still matches etag, in which case it
+// returns ErrNotModified
+func (c *Client) GetVideoIfChanged(ctx context.Context, id, etag string) (*Video, error) {
+	var out Video
+	header, err := doWithHeaders(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(id), nil, nil, conditional("If-None-Match", etag), &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// UpdateVideoRequest holds the fields to change; nil fields are left as they are
+type UpdateVideoRequest struct {
+	// Version is the version of the video the change is based on
+	Version     int64   `json:"version"`
+	Title       *string `json:"title,omitempty"`
+	Description *string `json:"description,omitempty"`
+	// Visibility is "public", "unlisted" or "private"
+	Visibility *string `json:"visibility,omitempty"`
+	// Tags replaces the video's tags when not nil
+	Tags *[]string `json:"tags,omitempty"`
+	// RequiresEntitlement makes playback sessions of the video need an entitlement
+	RequiresEntitlement *bool `json:"requires_entitlement,omitempty"`
+	// DownloadsEnabled lets members request files of the video to download
+	DownloadsEnabled *bool `json:"downloads_enabled,omitempty"`
+	// ExternalID replaces the video's external ID when set; "" removes it
+	ExternalID *string `json:"external_id,omitempty"`
+	// CustomFields sets custom field values by key; a nil value removes the field's value
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// UpdateVideo changes a video. When the video is no longer at req.Version the update fails
+// with a conflict; see IsConflict. A non-empty ifMatch (the ETag of a previous read) is sent
+// as an additional precondition.
+func (c *Client) UpdateVideo(ctx context.Context, id string, req UpdateVideoRequest, ifMatch string) (*Video, error) {
+	var out Video
+	header, err := doWithHeaders(ctx, c, http.MethodPatch, "/api/v1/videos/"+url.PathEscape(id), nil, req, conditional("If-Match", ifMatch), &out)
+	if err != nil {
+		return nil, err
+	}
+	out.ETag = header.Get("ETag")
+	return &out, nil
+}
+
+// BulkUpdateVideosRequest changes up to 500 videos at once; nil and empty fields are left as
+// they are
+type BulkUpdateVideosRequest struct {
+	VideoIDs []string `json:"video_ids"`
+	// Visibility is "public", "unlisted" or "private"
+	Visibility *string `json:"visibility,omitempty"`
+	// Tags replaces the tags of every video when not nil
+	Tags *[]string `json:"tags,omitempty"`
+	// AddTags and RemoveTags change the tags of every video, after Tags
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	// CustomFields sets custom field values by key; a nil value removes the field's value
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// BulkVideoResult is the outcome of a bulk update for one video
+type BulkVideoResult struct {
+	VideoID string `json:"video_id"`
+	// Status is "updated", or "not_found" or "too_many_tags" for a video that could not be
+	// updated, in which case the others are "rolled_back"
+	Status string `json:"status"`
+	Video  *Video `json:"video,omitempty"`
+}
+
+// BulkUpdateVideos changes several videos in one transaction. When some cannot be updated
+// nothing changes and the error is an *APIError whose Data holds the results.
+func (c *Client) BulkUpdateVideos(ctx context.Context, req BulkUpdateVideosRequest) ([]BulkVideoResult, error) {
+	var out struct {
+		Results []BulkVideoResult `json:"results"`
+	}
+	if err := do(ctx, c, http.MethodPatch, "/api/v1/videos/bulk", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return out.Results, nil
+}
+
+// DeleteVideo deletes a video, which stays listed with status "deleted" but no longer plays
+func (c *Client) DeleteVideo(ctx context.Context, id string) (*Video, error) {
+	var out Video
+	if err := do(ctx, c, http.MethodDelete, "/api/v1/videos/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// VideoStatusHistory is the current status of a video and the changes that led to it
+type VideoStatusHistory struct {
+	VideoID         string              `json:"video_id"`
+	Status          string              `json:"status"`
+	StatusChangedAt time.Time           `json:"status_changed_at"`
+	Transitions     []VideoStatusChange `json:"transitions"`
+}
+
+// VideoStatusChange is a move of a video from one status to another
+type VideoStatusChange struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetVideoStatusHistory returns the status changes of a video, oldest first
+func (c *Client) GetVideoStatusHistory(ctx context.Context, id string) (*VideoStatusHistory, error) {
+	var out VideoStatusHistory
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(id)+"/status-history", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// TrendingVideo is a video with its estimated plays of the last day and week
+type TrendingVideo struct {
+	Video      Video     `json:"video"`
+	Plays24h   int64     `json:"plays_24h"`
+	Plays7d    int64     `json:"plays_7d"`
+	Score      float64   `json:"score"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// ListTrendingVideos returns the ready videos with the highest trending scores; limit is at
+// most 100, and 0 uses the server's default
+func (c *Client) ListTrendingVideos(ctx context.Context, limit int) ([]TrendingVideo, error) {
+	var q url.Values
+	if limit > 0 {
+		q = url.Values{"limit": {strconv.Itoa(limit)}}
+	}
+	var out struct {
+		Videos []TrendingVideo `json:"videos"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/trending", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Videos, nil
+}
+
+// VideoStorage lists the stored objects of a video
+type VideoStorage struct {
+	Objects          []StorageObject `json:"objects"`
+	LifecycleEnabled bool            `json:"lifecycle_enabled"`
+}
+
+// GetVideoStorage returns the storage tier status of every object of a video
+func (c *Client) GetVideoStorage(ctx context.Context, id string) (*VideoStorage, error) {
+	var out VideoStorage
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(id)+"/storage", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestoreVideoStorage requests a restore of the archived objects of a video. kind limits the
+// restore to "source" or "rendition" objects; empty restores all.
+func (c *Client) RestoreVideoStorage(ctx context.Context, id, kind string) ([]StorageObject, error) {
+	var out struct {
+		Objects []StorageObject `json:"objects"`
+	}
+	body := map[string]string{}
+	if kind != "" {
+		body["kind"] = kind
+	}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(id)+"/storage/restore", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return out.Objects, nil
+}
+
+// CreateDownloadRequest picks what a download holds
+type CreateDownloadRequest struct {
+	// RenditionID downloads a rendition instead of the source
+	RenditionID string `json:"rendition_id,omitempty"`
+	// Watermark burns the caller's email into the picture
+	Watermark bool `json:"watermark,omitempty"`
+}
+
+// CreateDownload requests a file of a video to download. The file is rendered in the
+// background unless one was rendered already; poll GetDownload until its status is "ready".
+func (c *Client) CreateDownload(ctx context.Context, id string, req CreateDownloadRequest) (*VideoDownload, error) {
+	var out VideoDownload
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(id)+"/downloads", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDownload returns a download of a video, with a freshly signed link once it is ready
+func (c *Client) GetDownload(ctx context.Context, id, downloadID string) (*VideoDownload, error) {
+	var out VideoDownload
+	path := "/api/v1/videos/" + url.PathEscape(id) + "/downloads/" + url.PathEscape(downloadID)
+	if err := do(ctx, c, http.MethodGet, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreatePlaybackToken signs a time-limited token for embedding a video, including private ones
+func (c *Client) CreatePlaybackToken(ctx context.Context, id string) (*PlaybackToken, error) {
+	var out PlaybackToken
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/"+url.PathEscape(id)+"/playback-token", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetThumbnail uploads a JPEG, PNG or WebP image as the video's custom thumbnail, replacing
+// any previous one. contentType is the image's MIME type, e.g. "image/jpeg".
+func (c *Client) SetThumbnail(ctx context.Context, id, contentType string, data []byte) (*Video, error) {
+	var out Video
+	body := rawBody{contentType: contentType, data: data}
+	if err := do(ctx, c, http.MethodPut, "/api/v1/videos/"+url.PathEscape(id)+"/thumbnail", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteThumbnail removes the video's custom thumbnail
+func (c *Client) DeleteThumbnail(ctx context.Context, id string) (*Video, error) {
+	var out Video
+	if err := do(ctx, c, http.MethodDelete, "/api/v1/videos/"+url.PathEscape(id)+"/thumbnail", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetChapters returns the video's chapters in order
+func (c *Client) GetChapters(ctx context.Context, id string) ([]Chapter, error) {
+	var out struct {
+		Chapters []Chapter `json:"chapters"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(id)+"/chapters", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Chapters, nil
+}
+
+// SetChapters replaces the video's chapters; an empty list removes them. Only StartSeconds
+// and Title are used.
+func (c *Client) SetChapters(ctx context.Context, id string, chapters []Chapter) (*Video, error) {
+	body := struct {
+		Chapters []Chapter `json:"chapters"`
+	}{chapters}
+	if body.Chapters == nil {
+		body.Chapters = []Chapter{}
+	}
+	var out Video
+	if err := do(ctx, c, http.MethodPut, "/api/v1/videos/"+url.PathEscape(id)+"/chapters", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UploadChapters replaces the video's chapters with those of a chapter file: a WebVTT
+// chapters track, an SRT or FFmpeg metadata file, or text with a timestamp at the start of
+// each line
+func (c *Client) UploadChapters(ctx context.Context, id, contentType string, data []byte) (*Video, error) {
+	var out Video
+	body := rawBody{contentType: contentType, data: data}
+	if err := do(ctx, c, http.MethodPut, "/api/v1/videos/"+url.PathEscape(id)+"/chapters", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAdBreaks returns the video's ad breaks in order of their offset
+func (c *Client) GetAdBreaks(ctx context.Context, id string) ([]AdBreak, error) {
+	var out struct {
+		AdBreaks []AdBreak `json:"ad_breaks"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/videos/"+url.PathEscape(id)+"/ad-breaks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.AdBreaks, nil
+}
+
+// SetAdBreaks replaces the video's ad breaks; an empty list removes them
+func (c *Client) SetAdBreaks(ctx context.Context, id string, breaks []AdBreak) (*Video, error) {
+	body := struct {
+		AdBreaks []AdBreak `json:"ad_breaks"`
+	}{breaks}
+	if body.AdBreaks == nil {
+		body.AdBreaks = []AdBreak{}
+	}
+	var out Video
+	if err := do(ctx, c, http.MethodPut, "/api/v1/videos/"+url.PathEscape(id)+"/ad-breaks", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportVideoRequest is the body of ImportVideo
+type ImportVideoRequest struct {
+	// URL is the https URL the server downloads the source from
+	URL         string   `json:"url"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ProjectID   *string  `json:"project_id,omitempty"`
+	// ExternalID is the video's ID in an external system, unique within the organization
+	ExternalID string `json:"external_id,omitempty"`
+	// CustomFields are values of the organization's custom fields by key
+	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+}
+
+// ImportVideo creates a video whose source the server downloads in the background. Poll the
+// returned job with GetJob until it is done.
+func (c *Client) ImportVideo(ctx context.Context, req ImportVideoRequest) (*Video, *Job, error) {
+	var out struct {
+		Video Video `json:"video"`
+		Job   Job   `json:"job"`
+	}
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/import", nil, req, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out.Video, &out.Job, nil
+}
+
+// BulkImportS3 selects the objects under a prefix of a bucket
+type BulkImportS3 struct {
+	Bucket         string `json:"bucket"`
+	Prefix         string `json:"prefix,omitempty"`
+	Region         string `json:"region,omitempty"`
+	Endpoint       string `json:"endpoint,omitempty"`
+	ForcePathStyle bool   `json:"force_path_style,omitempty"`
+}
+
+// BulkImportSecrets are credentials for listing and reading a private bucket
+type BulkImportSecrets struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+// BulkImportRequest is the body of BulkImport. Set exactly one of S3, Manifest and ManifestURL.
+type BulkImportRequest struct {
+	S3 *BulkImportS3 `json:"s3,omitempty"`
+	// Manifest is CSV with the columns url, title and tags
+	Manifest    string             `json:"manifest,omitempty"`
+	ManifestURL string             `json:"manifest_url,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
+	ProjectID   *string            `json:"project_id,omitempty"`
+	Secrets     *BulkImportSecrets `json:"secrets,omitempty"`
+}
+
+// BulkImport queues a job that imports every object of an S3 prefix or entry of a manifest.
+// GetJob on the returned job reports the imports it queued in Children.
+func (c *Client) BulkImport(ctx context.Context, req BulkImportRequest) (*Job, error) {
+	var out Job
+	if err := do(ctx, c, http.MethodPost, "/api/v1/videos/import/bulk", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetJob returns the status and progress of a background job
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	var out Job
+	if err := do(ctx, c, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
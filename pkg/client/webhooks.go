@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader is the header deliveries are signed in
+const WebhookSignatureHeader = "X-OpenVDO-Signature"
+
+// ErrInvalidSignature is returned by VerifyWebhook for deliveries that were not signed with the
+// secret or were signed too long ago
+var ErrInvalidSignature = errors.New("openvdo: invalid webhook signature")
+
+// Webhook is an endpoint an organization receives events on
+type Webhook struct {
+	ID             string   `json:"id"`
+	OrganizationID string   `json:"organization_id"`
+	URL            string   `json:"url"`
+	EventTypes     []string `json:"event_types"`
+	Description    string   `json:"description"`
+	// Secret is only set on the webhook returned by CreateWebhook
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy *string   `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateWebhookRequest is the body of CreateWebhook. No EventTypes subscribes to every event.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
+
+// Event is the body of a webhook delivery, e.g. a video.ready event with the video as Data
+type Event struct {
+	ID             string          `json:"id"`
+	Sequence       int64           `json:"sequence"`
+	OrganizationID string          `json:"organization_id"`
+	Type           string          `json:"type"`
+	SubjectID      *string         `json:"subject_id,omitempty"`
+	Data           json.RawMessage `json:"data"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// ListWebhooks returns the organization's webhooks
+func (c *Client) ListWebhooks(ctx context.Context, orgID string) ([]Webhook, error) {
+	var out struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	if err := do(ctx, c, http.MethodGet, "/api/v1/organizations/"+url.PathEscape(orgID)+"/webhooks", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Webhooks, nil
+}
+
+// CreateWebhook adds a webhook; the returned Secret is needed to verify deliveries
+func (c *Client) CreateWebhook(ctx context.Context, orgID string, req CreateWebhookRequest) (*Webhook, error) {
+	var out Webhook
+	if err := do(ctx, c, http.MethodPost, "/api/v1/organizations/"+url.PathEscape(orgID)+"/webhooks", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteWebhook removes a webhook
+func (c *Client) DeleteWebhook(ctx context.Context, orgID, webhookID string) error {
+	return do[struct{}](ctx, c, http.MethodDelete,
+		"/api/v1/organizations/"+url.PathEscape(orgID)+"/webhooks/"+url.PathEscape(webhookID), nil, nil, nil)
+}
+
+// VerifyWebhook checks the signature header of a delivery against the raw body and decodes
+// the event. Signatures older than tolerance are rejected, so captured deliveries cannot be
+// replayed later; zero skips that check.
+func VerifyWebhook(secret, signature string, body []byte, tolerance time.Duration) (*Event, error) {
+	var ts, sig string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			sig = value
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return nil, ErrInvalidSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)).Abs() > tolerance {
+		return nil, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
@@ -4,14 +4,43 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync/atomic"
 )
 
+// Levels passed to a Hook
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// Hook receives the messages logged by Error and Fatal, with their format string for
+// grouping. Fatal waits for the hook to return before exiting.
+type Hook func(level, format string, args []interface{})
+
+var hook atomic.Pointer[Hook]
+
+// SetHook installs h, replacing any previous hook; nil removes it
+func SetHook(h Hook) {
+	if h == nil {
+		hook.Store(nil)
+		return
+	}
+	hook.Store(&h)
+}
+
+func callHook(level, format string, args []interface{}) {
+	if h := hook.Load(); h != nil {
+		(*h)(level, format, args)
+	}
+}
+
 func Info(format string, args ...interface{}) {
 	log.Printf("[INFO] "+format+"\n", args...)
 }
 
 func Error(format string, args ...interface{}) {
 	log.Printf("[ERROR] "+format+"\n", args...)
+	callHook(LevelError, format, args)
 }
 
 func Debug(format string, args ...interface{}) {
@@ -22,6 +51,7 @@ func Debug(format string, args ...interface{}) {
 
 func Fatal(format string, args ...interface{}) {
 	log.Printf("[FATAL] "+format+"\n", args...)
+	callHook(LevelFatal, format, args)
 	os.Exit(1)
 }
 
@@ -0,0 +1,174 @@
+// Package openvdo embeds the OpenVDO API in other Go programs. A Server is an http.Handler,
+// so it can be mounted in an existing mux or served on its own, and its router accepts
+// additional routes that reuse the tenant database middleware.
+//
+//	cfg := openvdo.LoadConfig()
+//	srv, err := openvdo.New(cfg)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	ext := srv.Router().Group("/ext/v1", srv.TenantMiddleware(), srv.RequireAuth())
+//	ext.GET("/hello", func(c *gin.Context) {
+//		tenantDB, _ := openvdo.TenantDBFromContext(c)
+//		...
+//	})
+//	go srv.ListenAndServe(":8080")
+//	...
+//	srv.Shutdown(ctx)
+package openvdo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"openvdo/internal/app"
+	"openvdo/internal/bootstrap"
+	"openvdo/internal/config"
+	"openvdo/internal/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Config is the complete server configuration
+type Config = config.Config
+
+// TenantDB is a request-scoped database handle with the RLS context of the authenticated user
+type TenantDB = database.StatelessTenantDB
+
+// LoadConfig reads the configuration from config.yaml and the environment, like the openvdo binary
+func LoadConfig() *Config {
+	return config.Load()
+}
+
+// Option customizes a Server
+type Option func(*options)
+
+type options struct {
+	bootstrap bool
+	workers   bool
+}
+
+// WithoutBootstrap skips waiting for dependencies and the schema checks in New
+func WithoutBootstrap() Option {
+	return func(o *options) { o.bootstrap = false }
+}
+
+// WithoutWorkers leaves the storage lifecycle policies and the unhashed upload scan to
+// another process
+func WithoutWorkers() Option {
+	return func(o *options) { o.workers = false }
+}
+
+// Server is an embeddable OpenVDO API instance
+type Server struct {
+	app     *app.App
+	options options
+
+	mu      sync.Mutex
+	started bool
+	http    *http.Server
+}
+
+// New connects to the configured dependencies and builds the API. No background work runs
+// and nothing listens until Start or ListenAndServe is called.
+func New(cfg *Config, opts ...Option) (*Server, error) {
+	o := options{bootstrap: true, workers: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.bootstrap {
+		if err := bootstrap.Run(cfg); err != nil {
+			return nil, fmt.Errorf("bootstrap failed: %w", err)
+		}
+	}
+
+	a, err := app.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{app: a, options: o}, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.app.Router.ServeHTTP(w, r)
+}
+
+// Router returns the Gin engine so callers can register their own routes and middleware.
+// Routes must be added before the server handles its first request.
+func (s *Server) Router() *gin.Engine {
+	return s.app.Router
+}
+
+// TenantMiddleware provides a TenantDB for the identified user to the handlers after it
+func (s *Server) TenantMiddleware() gin.HandlerFunc {
+	return database.StatelessDatabaseMiddleware(s.app.Pools)
+}
+
+// RequireAuth rejects requests without an identified user. Use it after TenantMiddleware.
+func (s *Server) RequireAuth() gin.HandlerFunc {
+	return database.StatelessRequireAuth()
+}
+
+// TenantDBFromContext returns the TenantDB set by TenantMiddleware
+func TenantDBFromContext(c *gin.Context) (*TenantDB, bool) {
+	return database.GetStatelessTenantDBFromContext(c)
+}
+
+// Start begins the readiness checks and, unless disabled, the background workers. It does
+// not block and is a no-op when the server was already started.
+func (s *Server) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	if s.options.workers {
+		s.app.StartWorkers()
+	}
+	s.app.Start()
+}
+
+// ListenAndServe starts the server and serves HTTP on addr until Shutdown is called
+func (s *Server) ListenAndServe(addr string) error {
+	s.Start()
+
+	s.mu.Lock()
+	if s.http != nil {
+		s.mu.Unlock()
+		return fmt.Errorf("server is already listening")
+	}
+	s.http = &http.Server{Addr: addr, Handler: s}
+	srv := s.http
+	s.mu.Unlock()
+
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting requests, waits for in-flight requests until ctx is done, then
+// stops background work and closes the database connections
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.http
+	s.mu.Unlock()
+
+	var shutdownErr error
+	if srv != nil {
+		shutdownErr = srv.Shutdown(ctx)
+	}
+
+	if err := s.app.Close(); err != nil {
+		return err
+	}
+	return shutdownErr
+}
@@ -0,0 +1,140 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// APIVersion is the version of the API answering with Envelope
+const APIVersion = "2"
+
+// Envelope is the body of every JSON response under /api/v2. Successful responses carry
+// data and, for listings, pagination; failed ones carry error. Meta is always present.
+type Envelope struct {
+	Data       interface{} `json:"data,omitempty"`
+	Error      *ErrorBody  `json:"error,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Meta       Meta        `json:"meta"`
+}
+
+// ErrorBody describes why a request failed
+type ErrorBody struct {
+	// Code is the code the failure names, such as already_exists, or else is derived from the
+	// HTTP status, such as not_found or conflict
+	Code    string `json:"code" example:"not_found"`
+	Message string `json:"message" example:"Video not found"`
+	// Details holds what else the failure carries, such as the current state on a conflict
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Pagination describes the page of a listing
+type Pagination struct {
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Total int64 `json:"total"`
+	// TotalEstimated tells that Total is the query planner's estimate for a large listing
+	TotalEstimated bool `json:"total_estimated,omitempty"`
+}
+
+// Meta describes the response itself
+type Meta struct {
+	RequestID  string `json:"request_id,omitempty"`
+	Message    string `json:"message,omitempty"`
+	APIVersion string `json:"api_version" example:"2"`
+}
+
+// errorCodes names the error statuses handlers answer with
+var errorCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusPaymentRequired:       "payment_required",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusNotAcceptable:         "not_acceptable",
+	http.StatusConflict:              "conflict",
+	http.StatusGone:                  "gone",
+	http.StatusPreconditionFailed:    "precondition_failed",
+	http.StatusRequestEntityTooLarge: "payload_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusUnprocessableEntity:   "unprocessable_entity",
+	http.StatusPreconditionRequired:  "precondition_required",
+	http.StatusTooManyRequests:       "too_many_requests",
+	http.StatusInternalServerError:   "internal_error",
+	http.StatusBadGateway:            "bad_gateway",
+	http.StatusServiceUnavailable:    "unavailable",
+	http.StatusGatewayTimeout:        "timeout",
+}
+
+// ErrorCode returns the code of an error status
+func ErrorCode(status int) string {
+	if code, ok := errorCodes[status]; ok {
+		return code
+	}
+	if status >= 500 {
+		return "internal_error"
+	}
+	return "error"
+}
+
+// FromV1 converts a JSON body in the shape of /api/v1, {"status": "success", "message": ...,
+// "data": ...} or {"error": ..., "code": ...}, into an envelope. Pagination found in data is lifted out of
+// it. Bodies of another shape become the envelope's data as they are.
+func FromV1(status int, body []byte, requestID string) Envelope {
+	env := Envelope{Meta: Meta{RequestID: requestID, APIVersion: APIVersion}}
+
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil || fields == nil {
+		env.Data = json.RawMessage(body)
+		if status >= 400 {
+			env.Data = nil
+			env.Error = &ErrorBody{Code: ErrorCode(status), Message: http.StatusText(status)}
+		}
+		return env
+	}
+
+	if message, isError := fields["error"].(string); isError || status >= 400 {
+		if !isError {
+			message = http.StatusText(status)
+		}
+		env.Error = &ErrorBody{Code: ErrorCode(status), Message: message}
+		if code, ok := fields["code"].(string); ok && code != "" {
+			env.Error.Code = code
+		}
+		delete(fields, "error")
+		delete(fields, "status")
+		delete(fields, "code")
+		if len(fields) > 0 {
+			env.Error.Details = fields
+		}
+		return env
+	}
+
+	if _, wrapped := fields["status"]; !wrapped {
+		env.Data = fields
+		return env
+	}
+	env.Meta.Message, _ = fields["message"].(string)
+	env.Data = fields["data"]
+	if data, ok := env.Data.(map[string]interface{}); ok {
+		if pagination, ok := data["pagination"].(map[string]interface{}); ok {
+			env.Pagination = paginationFromV1(pagination)
+			delete(data, "pagination")
+		}
+	}
+	return env
+}
+
+func paginationFromV1(fields map[string]interface{}) *Pagination {
+	number := func(name string) int64 {
+		n, _ := fields[name].(json.Number)
+		v, _ := n.Int64()
+		return v
+	}
+	p := &Pagination{Page: int(number("page")), Limit: int(number("limit")), Total: number("total")}
+	p.TotalEstimated, _ = fields["total_estimated"].(bool)
+	return p
+}
@@ -0,0 +1,53 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// listBody is a /api/v1 listing of n videos, as handlers write it
+func listBody(n int) []byte {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"id":"6f1c2a9e-4b7d-4c3e-9a51-%012d","title":"Video %d","status":"ready",`+
+			`"duration":%d,"tags":["news","sports"],"created_at":"2026-10-16T13:00:05Z"}`, i, i, 60+i)
+	}
+	return []byte(`{"status":"success","message":"Videos retrieved successfully","data":{"videos":[` +
+		strings.Join(items, ",") + `],"pagination":{"page":1,"limit":50,"total":1234}}}`)
+}
+
+func BenchmarkFromV1(b *testing.B) {
+	bodies := []struct {
+		name   string
+		status int
+		body   []byte
+	}{
+		{"error", 404, []byte(`{"error":"Video not found","code":"not_found"}`)},
+		{"object", 200, listBody(1)},
+		{"list", 200, listBody(50)},
+	}
+	for _, bb := range bodies {
+		b.Run(bb.name, func(b *testing.B) {
+			b.SetBytes(int64(len(bb.body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				FromV1(bb.status, bb.body, "req-1")
+			}
+		})
+	}
+}
+
+// BenchmarkEnvelopeEncoding measures converting and encoding an envelope together, as
+// EnvelopeV2 does for every JSON response
+func BenchmarkEnvelopeEncoding(b *testing.B) {
+	body := listBody(50)
+	b.SetBytes(int64(len(body)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := json.Marshal(FromV1(200, body, "req-1")); err != nil {
+			b.Fatal(err)
+		}
+	}
+}